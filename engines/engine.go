@@ -2,7 +2,9 @@ package engines
 
 import (
 	"encoding/binary"
+	"fmt"
 	"image"
+	"math"
 	"reflect"
 
 	log "github.com/suiqirui1987/fly3d/tools/logrus"
@@ -14,7 +16,7 @@ import (
 	"github.com/suiqirui1987/fly3d/math32"
 	"github.com/suiqirui1987/fly3d/tools"
 	"github.com/suiqirui1987/fly3d/tools/resize"
-	"github.com/suiqirui1987/fly3d/windows"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 
 	"golang.org/x/mobile/exp/f32"
 )
@@ -33,8 +35,14 @@ type EngineCaps struct {
 	MaxTextureSize        int
 	MaxCubemapTextureSize int
 	MaxRenderTextureSize  int
+	MaxVertexAttribs      int
 
 	StandardDerivatives bool
+	// TextureLOD is true when fragment shaders can pick the mip level
+	TextureLOD      bool
+	InstancedArrays bool
+	// Uint32Indices is false when index buffers are limited to 16 bits
+	Uint32Indices bool
 }
 
 type Engine struct {
@@ -56,7 +64,7 @@ type Engine struct {
 
 	//get Properties
 	_aspectRatio          float32
-	_renderingCanvas      windows.IWindow
+	_renderingCanvas      canvas.IWindow
 	_hardwareScalingLevel float32
 	_alphaTest            bool
 	_runningLoop          bool
@@ -68,9 +76,9 @@ type Engine struct {
 	_renderFunction func()
 }
 
-func NewEngine(canvas windows.IWindow, antialias bool) *Engine {
+func NewEngine(window canvas.IWindow, antialias bool) *Engine {
 	this := &Engine{}
-	this._renderingCanvas = canvas
+	this._renderingCanvas = window
 	this._alphaTest = false
 
 	// Options
@@ -78,7 +86,7 @@ func NewEngine(canvas windows.IWindow, antialias bool) *Engine {
 	this.CullBackFaces = true
 
 	//Viewport
-	this._hardwareScalingLevel = 1.0 / canvas.GetWindowDevicePixelRatio()
+	this._hardwareScalingLevel = 1.0 / window.GetWindowDevicePixelRatio()
 
 	// Caps
 	this._caps = &EngineCaps{}
@@ -86,10 +94,13 @@ func NewEngine(canvas windows.IWindow, antialias bool) *Engine {
 	this._caps.MaxTextureSize = gl.GetInteger(gl.MAX_TEXTURE_SIZE)
 	this._caps.MaxCubemapTextureSize = gl.GetInteger(gl.MAX_CUBE_MAP_TEXTURE_SIZE)
 	this._caps.MaxRenderTextureSize = gl.GetInteger(gl.MAX_RENDERBUFFER_SIZE)
+	this._caps.MaxVertexAttribs = gl.GetInteger(gl.MAX_VERTEX_ATTRIBS)
 
 	// Extensions
-	//derivatives := gl.GetExtension("OES_standard_derivatives")
-	this._caps.StandardDerivatives = true
+	this._caps.StandardDerivatives = gl.StandardDerivativesSupported()
+	this._caps.TextureLOD = gl.TextureLodSupported()
+	this._caps.InstancedArrays = gl.InstancingSupported()
+	this._caps.Uint32Indices = gl.ElementIndexUintSupported()
 
 	// Cache
 	this._loadedTexturesCache = make([]*gl.GLTextureBuffer, 0)
@@ -107,11 +118,11 @@ func NewEngine(canvas windows.IWindow, antialias bool) *Engine {
 	this.IsFullscreen = false
 
 	that := this
-	this._renderingCanvas.On(windows.FullScreenChange, func() error {
+	this._renderingCanvas.On(canvas.FullScreenChange, func() error {
 		that.IsFullscreen = that._renderingCanvas.GetFullscreen()
 		return nil
 	})
-	this._renderingCanvas.On(windows.Resize, func(evt *windows.ResizeEvent) error {
+	this._renderingCanvas.On(canvas.Resize, func(evt *canvas.ResizeEvent) error {
 		this._aspectRatio = (float32)(this._renderingCanvas.GetRenderWidth()) / (float32)(this._renderingCanvas.GetRenderHeight())
 		return nil
 	})
@@ -124,6 +135,11 @@ func (this *Engine) GetAspectRatio() float32 {
 	return this._aspectRatio
 }
 
+// SetAspectRatio overrides the ratio used by cameras until the next resize
+func (this *Engine) SetAspectRatio(ratio float32) {
+	this._aspectRatio = ratio
+}
+
 func (this *Engine) GetRenderWidth() int {
 	return this._renderingCanvas.GetRenderWidth()
 }
@@ -132,7 +148,7 @@ func (this *Engine) GetRenderHeight() int {
 	return this._renderingCanvas.GetRenderHeight()
 }
 
-func (this *Engine) GetRenderingCanvas() windows.IWindow {
+func (this *Engine) GetRenderingCanvas() canvas.IWindow {
 	return this._renderingCanvas
 }
 
@@ -219,6 +235,7 @@ func (this *Engine) Clear(color *math32.Color3, backBuffer, depthStencil bool) {
 }
 
 func (this *Engine) BeginFrame() {
+	// keeps the deprecated tools.GetFps running, scenes measure their own clock
 	tools.MeasureFps()
 
 	gl.Viewport(0, 0, this._renderingCanvas.GetRenderWidth(), this._renderingCanvas.GetRenderHeight())
@@ -248,6 +265,21 @@ func (this *Engine) FlushFramebuffer() {
 	gl.Flush()
 }
 
+// ReadPixels reads a rectangle of the bound framebuffer, rows are returned top to bottom
+func (this *Engine) ReadPixels(x, y, width, height int) *image.RGBA {
+	pixels := make([]byte, width*height*4)
+	gl.ReadPixels(pixels, x, y, width, height, gl.RGBA, gl.UNSIGNED_BYTE)
+
+	// gl rows start at the bottom
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	stride := width * 4
+	for row := 0; row < height; row++ {
+		copy(img.Pix[row*img.Stride:row*img.Stride+stride], pixels[(height-1-row)*stride:(height-row)*stride])
+	}
+
+	return img
+}
+
 func (this *Engine) RestoreDefaultFramebuffer() {
 
 	gl.BindFramebuffer(gl.FRAMEBUFFER, gl.Framebuffer{})
@@ -295,8 +327,29 @@ func (this *Engine) UpdateDynamicVertexBuffer(vertexBuffer *gl.GLVertexBuffer, v
 
 }
 
-func (this *Engine) CreateIndexBuffer(indices []uint16, is32Bits bool) *gl.GLIndexBuffer {
-	indices_data := tools.BytesUint16(binary.LittleEndian, indices...)
+// CreateIndexBuffer switches to 32 bits indices when an index does not fit in
+// 16 bits, it fails when the engine has no 32 bits index buffers
+func (this *Engine) CreateIndexBuffer(indices []uint32, is32Bits bool) (*gl.GLIndexBuffer, error) {
+	for _, index := range indices {
+		if index > math.MaxUint16 {
+			is32Bits = true
+			break
+		}
+	}
+	if is32Bits && !this._caps.Uint32Indices {
+		return nil, fmt.Errorf("indices above %d need 32 bits, OES_element_index_uint is not supported", math.MaxUint16)
+	}
+
+	var indices_data []byte
+	if is32Bits {
+		indices_data = tools.BytesUint32(binary.LittleEndian, indices...)
+	} else {
+		indices16 := make([]uint16, len(indices))
+		for i, index := range indices {
+			indices16[i] = uint16(index)
+		}
+		indices_data = tools.BytesUint16(binary.LittleEndian, indices16...)
+	}
 
 	vbo := gl.CreateBuffer()
 	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, vbo)
@@ -307,7 +360,7 @@ func (this *Engine) CreateIndexBuffer(indices []uint16, is32Bits bool) *gl.GLInd
 	vbobuf.Vbo = vbo
 	vbobuf.References = 1
 	vbobuf.Is32Bits = is32Bits
-	return vbobuf
+	return vbobuf, nil
 }
 
 func (this *Engine) BindBuffers(vertexBuffer *gl.GLVertexBuffer, indexBuffer *gl.GLIndexBuffer, vertexDeclaration []int, vertexStrideSize int, effect IEffect) {
@@ -350,8 +403,9 @@ func (this *Engine) BindMultiBuffers(vertexBuffers map[string]*gl.GLVertexBuffer
 		for index := 0; index < len(attributes); index++ {
 			order := effect.GetAttribute(index)
 
-			if order.Valid() {
-				vertexBuffer := vertexBuffers[attributes[index]]
+			// Instanced attributes are bound by BindInstancesBuffer
+			vertexBuffer, ok := vertexBuffers[attributes[index]]
+			if order.Valid() && ok {
 				stride := vertexBuffer.StrideSize
 				gl.BindBuffer(gl.ARRAY_BUFFER, vertexBuffer.Vbo)
 				gl.VertexAttribPointer(order, stride, gl.FLOAT, false, stride*4, 0)
@@ -392,14 +446,66 @@ func (this *Engine) Draw(useTriangles bool, indexStart, indexCount int) {
 	} else {
 		gltype = gl.LINES
 	}
-	gl.DrawElements(gltype, indexCount, gl.UNSIGNED_SHORT, indexStart*2)
+	indexBuffer := this._buffersCache._cachedIndexBuffer
+	if indexBuffer != nil && indexBuffer.Is32Bits {
+		gl.DrawElements(gltype, indexCount, gl.UNSIGNED_INT, indexStart*4)
+	} else {
+		gl.DrawElements(gltype, indexCount, gl.UNSIGNED_SHORT, indexStart*2)
+	}
 
 	if err := gl.GetError(); err != 0 {
 		log.Printf("Draw gl error: %v \r\n", err)
 	}
 }
 
-func (this *Engine) CreateShaderProgram(vertexCode, fragmentCode, defines string) gl.Program {
+// BindInstancesBuffer binds the world matrices of the instances to the four
+// vec4 attributes locations, one matrix per instance
+func (this *Engine) BindInstancesBuffer(instancesBuffer *gl.GLVertexBuffer, locations []gl.Attrib) {
+	gl.BindBuffer(gl.ARRAY_BUFFER, instancesBuffer.Vbo)
+	this._buffersCache._cachedVertexBuffer = nil
+
+	for index, location := range locations {
+		if !location.Valid() {
+			continue
+		}
+		gl.EnableVertexAttribArray(location)
+		gl.VertexAttribPointer(location, 4, gl.FLOAT, false, 64, index*16)
+		gl.VertexAttribDivisor(location, 1)
+	}
+}
+
+func (this *Engine) UnBindInstancesBuffer(locations []gl.Attrib) {
+	for _, location := range locations {
+		if !location.Valid() {
+			continue
+		}
+		gl.VertexAttribDivisor(location, 0)
+		gl.DisableVertexAttribArray(location)
+	}
+}
+
+func (this *Engine) DrawInstanced(useTriangles bool, indexStart, indexCount, instancesCount int) {
+	var gltype gl.Enum
+	if useTriangles {
+		gltype = gl.TRIANGLES
+	} else {
+		gltype = gl.LINES
+	}
+
+	indexBuffer := this._buffersCache._cachedIndexBuffer
+	if indexBuffer != nil && indexBuffer.Is32Bits {
+		gl.DrawElementsInstanced(gltype, indexCount, gl.UNSIGNED_INT, indexStart*4, instancesCount)
+	} else {
+		gl.DrawElementsInstanced(gltype, indexCount, gl.UNSIGNED_SHORT, indexStart*2, instancesCount)
+	}
+
+	if err := gl.GetError(); err != 0 {
+		log.Printf("DrawInstanced gl error: %v \r\n", err)
+	}
+}
+
+// CreateShaderProgram compiles and links the sources, defines lead both of them
+func (this *Engine) CreateShaderProgram(vertexCode, fragmentCode, defines string) (gl.Program, error) {
 
 	if defines != "" {
 		defines = defines + "\n"
@@ -411,11 +517,10 @@ func (this *Engine) CreateShaderProgram(vertexCode, fragmentCode, defines string
 	shaderProgram, err := glutil.CreateProgram(vertexCode_str, fragmentCode_str)
 
 	if err != nil {
-		log.Print(err)
-		return gl.Program{}
+		return gl.Program{}, err
 	}
 
-	return shaderProgram
+	return shaderProgram, nil
 
 }
 
@@ -471,6 +576,48 @@ func (this *Engine) SetMatrix(uniform gl.Uniform, m *math32.Matrix4) {
 	gl.UniformMatrix4fv(uniform, m.ToArray32())
 }
 
+func (this *Engine) SetMatrices(uniform gl.Uniform, matrices []float32) {
+	if !uniform.Valid() {
+		log.Println("SetMatrices uniform.Valid Failed")
+		return
+	}
+	gl.UniformMatrix4fv(uniform, matrices)
+}
+
+func (this *Engine) SetFloatArray(uniform gl.Uniform, array []float32) {
+	if !uniform.Valid() {
+		log.Println("SetFloatArray uniform.Valid Failed")
+		return
+	}
+	gl.Uniform1fv(uniform, array)
+}
+
+// SetArray3 sets a vec3 array uniform, array holds 3 floats per element
+func (this *Engine) SetArray3(uniform gl.Uniform, array []float32) {
+	if !uniform.Valid() {
+		log.Println("SetArray3 uniform.Valid Failed")
+		return
+	}
+	gl.Uniform3fv(uniform, array)
+}
+
+// SetArray4 sets a vec4 array uniform, array holds 4 floats per element
+func (this *Engine) SetArray4(uniform gl.Uniform, array []float32) {
+	if !uniform.Valid() {
+		log.Println("SetArray4 uniform.Valid Failed")
+		return
+	}
+	gl.Uniform4fv(uniform, array)
+}
+
+func (this *Engine) SetFloat(uniform gl.Uniform, value float32) {
+	if !uniform.Valid() {
+		log.Println("SetFloat uniform.Valid Failed")
+		return
+	}
+	gl.Uniform1f(uniform, value)
+}
+
 func (this *Engine) SetVector2(uniform gl.Uniform, v *math32.Vector2) {
 	if !uniform.Valid() {
 		log.Println("SetVector2 uniform.Valid Failed")
@@ -639,37 +786,7 @@ func (this *Engine) CreateTexture(url string, noMipmap bool, invertY int, scene
 	texture.References = 1
 
 	onload := func(img *image.RGBA) {
-		width := img.Bounds().Dx()
-		height := img.Bounds().Dy()
-		canvas_width := this.GetExponantOfTwo(width, int(this._caps.MaxTextureSize))
-		canvas_height := this.GetExponantOfTwo(height, int(this._caps.MaxTextureSize))
-		pixelData := img.Pix
-
-		isPot := (width == canvas_width && height == canvas_height)
-		if !isPot {
-
-			img = this.GetScaled(img, canvas_width, canvas_height)
-			pixelData = img.Pix
-		}
-
-		gl.BindTexture(gl.TEXTURE_2D, texture.Tex)
-
-		gl.TexImage2D(gl.TEXTURE_2D, 0, canvas_width, canvas_height, gl.RGBA, gl.UNSIGNED_BYTE, pixelData)
-		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-
-		if noMipmap == true {
-			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-		} else {
-			gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
-			gl.GenerateMipmap(gl.TEXTURE_2D)
-		}
-
-		this._activeTexturesCache = make([]*gl.GLTextureBuffer, 0)
-		texture.BaseWidth = (int)(width)
-		texture.BaseHeight = (int)(height)
-		texture.Width = (int)(canvas_width)
-		texture.Height = (int)(canvas_height)
-		texture.IsReady = true
+		this._uploadTexture(texture, img, noMipmap)
 		scene.RemovePendingData(url)
 
 	}
@@ -685,6 +802,62 @@ func (this *Engine) CreateTexture(url string, noMipmap bool, invertY int, scene
 
 }
 
+// CreateTextureFromData creates a texture from encoded image content, name is used as the cache url
+func (this *Engine) CreateTextureFromData(name string, content []byte, noMipmap bool, invertY int, scene *Scene) *gl.GLTextureBuffer {
+
+	texture := gl.NewGLTextureBuffer()
+	texture.Tex = gl.CreateTexture()
+	texture.IsReady = false
+	texture.Url = name
+	texture.NoMipmap = noMipmap
+	texture.References = 1
+
+	img, err := tools.DecodeImage(content)
+	if err != nil {
+		log.Printf("CreateTextureFromData DecodeImage %s Failed %s", name, err)
+	} else {
+		this._uploadTexture(texture, img, noMipmap)
+	}
+
+	this._loadedTexturesCache = append(this._loadedTexturesCache, texture)
+
+	return texture
+}
+
+func (this *Engine) _uploadTexture(texture *gl.GLTextureBuffer, img *image.RGBA, noMipmap bool) {
+	width := img.Bounds().Dx()
+	height := img.Bounds().Dy()
+	canvas_width := this.GetExponantOfTwo(width, int(this._caps.MaxTextureSize))
+	canvas_height := this.GetExponantOfTwo(height, int(this._caps.MaxTextureSize))
+	pixelData := img.Pix
+
+	isPot := (width == canvas_width && height == canvas_height)
+	if !isPot {
+
+		img = this.GetScaled(img, canvas_width, canvas_height)
+		pixelData = img.Pix
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, texture.Tex)
+
+	gl.TexImage2D(gl.TEXTURE_2D, 0, canvas_width, canvas_height, gl.RGBA, gl.UNSIGNED_BYTE, pixelData)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+
+	if noMipmap == true {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	} else {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	this._activeTexturesCache = make([]*gl.GLTextureBuffer, 0)
+	texture.BaseWidth = (int)(width)
+	texture.BaseHeight = (int)(height)
+	texture.Width = (int)(canvas_width)
+	texture.Height = (int)(canvas_height)
+	texture.IsReady = true
+}
+
 func (this *Engine) CreateDynamicTexture(size int, generateMipMaps bool) *gl.GLTextureBuffer {
 
 	texture := gl.NewGLTextureBuffer()
@@ -740,7 +913,11 @@ func (this *Engine) UpdateVideoTexture(texture *gl.GLTextureBuffer, img *image.R
 }
 
 func (this *Engine) CreateRenderTargetTexture(size int, generateMipMaps bool) *gl.GLTextureBuffer {
-	log.Debugf("CreateRenderTargetTexture size %d ", size)
+	return this.CreateRenderTargetTextureWithSize(size, size, generateMipMaps)
+}
+
+func (this *Engine) CreateRenderTargetTextureWithSize(width int, height int, generateMipMaps bool) *gl.GLTextureBuffer {
+	log.Debugf("CreateRenderTargetTexture size %dx%d ", width, height)
 	texture := gl.NewGLTextureBuffer()
 	texture.Tex = gl.CreateTexture()
 
@@ -756,12 +933,12 @@ func (this *Engine) CreateRenderTargetTexture(size int, generateMipMaps bool) *g
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, size, size, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, nil)
 
 	// Create the depth buffer
 	depthBuffer := gl.CreateRenderbuffer()
 	gl.BindRenderbuffer(gl.RENDERBUFFER, depthBuffer)
-	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT16, size, size)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT16, width, height)
 
 	// Create the framebuffer
 	framebuffer := gl.CreateFramebuffer()
@@ -776,8 +953,8 @@ func (this *Engine) CreateRenderTargetTexture(size int, generateMipMaps bool) *g
 
 	texture.FrameBuf = framebuffer
 	texture.DepthBuf = depthBuffer
-	texture.Width = size
-	texture.Height = size
+	texture.Width = width
+	texture.Height = height
 	texture.IsReady = true
 	texture.GenerateMipMaps = generateMipMaps
 	texture.References = 1
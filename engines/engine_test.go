@@ -0,0 +1,55 @@
+// +build glnull glsoft
+
+package engines_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/gl"
+)
+
+func TestCreateIndexBuffer(t *testing.T) {
+	defer gl.SetElementIndexUintSupported(true)
+
+	tests := []struct {
+		name     string
+		indices  []uint32
+		is32Bits bool
+		uint32   bool
+		want32   bool
+		err      bool
+	}{
+		{"16 bits", []uint32{0, 1, math.MaxUint16}, false, true, false, false},
+		{"forced", []uint32{0, 1, 2}, true, true, true, false},
+		{"switched", []uint32{0, 1, math.MaxUint16 + 1}, false, true, true, false},
+		{"16 bits only", []uint32{0, 1, math.MaxUint16}, false, false, false, false},
+		{"unsupported", []uint32{0, 1, math.MaxUint16 + 1}, false, false, false, true},
+	}
+
+	for _, test := range tests {
+		gl.SetElementIndexUintSupported(test.uint32)
+		engine := enginetest.NewEngine(t)
+
+		buffer, err := engine.CreateIndexBuffer(test.indices, test.is32Bits)
+		if test.err {
+			if err == nil || buffer != nil {
+				t.Errorf("%s: got buffer %v error %v, want an error", test.name, buffer, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		size := 2
+		if test.want32 {
+			size = 4
+		}
+		if buffer.Is32Bits != test.want32 || len(gl.BufferContents(buffer.Vbo)) != len(test.indices)*size {
+			t.Errorf("%s: 32 bits %v with %d bytes, want %v", test.name, buffer.Is32Bits, len(gl.BufferContents(buffer.Vbo)), test.want32)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package enginetest creates engines on a headless window for the tests of
+// the engine and its modules. It needs a gl backend that runs without a
+// display, build the tests with -tags glnull or glsoft.
+package enginetest
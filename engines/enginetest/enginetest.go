@@ -0,0 +1,33 @@
+// +build glnull glsoft
+
+package enginetest
+
+import (
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/gl"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
+	"github.com/suiqirui1987/fly3d/windows/headless"
+)
+
+// NewApp drops the gl objects of the previous test and opens a headless window
+func NewApp(t testing.TB, width int, height int) *headless.App {
+	gl.ResetContext()
+
+	app, err := headless.NewApp(&canvas.AppOption{Width: width, Height: height})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// NewEngine returns an engine drawing into a 64x64 headless window
+func NewEngine(t testing.TB) *engines.Engine {
+	return engines.NewEngine(NewApp(t, 64, 64), false)
+}
+
+// NewScene returns an empty scene on a new engine
+func NewScene(t testing.TB) *engines.Scene {
+	return engines.NewScene(NewEngine(t))
+}
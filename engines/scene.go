@@ -1,6 +1,7 @@
 package engines
 
 import (
+	"fmt"
 	"math"
 
 	"github.com/suiqirui1987/fly3d/core"
@@ -14,6 +15,13 @@ type ISceneDisposed interface {
 	Dispose()
 }
 
+// InstancesBatch is what a mesh draws in the current frame: itself when it is
+// active and its active instances
+type InstancesBatch struct {
+	RenderSelf bool
+	Instances  []IInstancedMesh
+}
+
 type Scene struct {
 
 	//
@@ -41,8 +49,12 @@ type Scene struct {
 	_spritesDuration              int
 
 	_animationRatio float32
+	_clock          IClock
 	_pendingData    []string
 
+	// Run by LoadScene once every object is parsed
+	_afterParse []func() error
+
 	//callback
 	BeforeRender func()
 	AfterRender  func()
@@ -73,6 +85,8 @@ type Scene struct {
 	_opaqueSubMeshes      []ISubMesh
 	_transparentSubMeshes []ISubMesh
 	_alphaTestSubMeshes   []ISubMesh
+	_instancesBatches     map[IMesh]*InstancesBatch
+	_dispatchedSubMeshes  map[ISubMesh]bool
 
 	//target
 	_renderTargets []ITexture
@@ -85,6 +99,9 @@ type Scene struct {
 	// Textures
 	Textures []ITexture
 
+	// Skeletons
+	Skeletons []ISkeleton
+
 	// Particles
 	ParticlesEnabled       bool
 	ParticleSystems        []IParticleSystem
@@ -148,11 +165,16 @@ func NewScene(engine *Engine) *Scene {
 	//Mesh
 	this.Meshes = make([]IMesh, 0)
 	this._activeMeshes = make([]IMesh, 0)
+	this._instancesBatches = map[IMesh]*InstancesBatch{}
+	this._dispatchedSubMeshes = map[ISubMesh]bool{}
 
 	// Materials
 	this.Materials = make([]IMaterial, 0)
 	this.MultiMaterials = make([]IMultiMaterial, 0)
 
+	// Skeletons
+	this.Skeletons = make([]ISkeleton, 0)
+
 	// Textures
 	this.Textures = make([]ITexture, 0)
 
@@ -172,6 +194,7 @@ func NewScene(engine *Engine) *Scene {
 
 	// Animations
 	this.ActiveAnimatables = make([]IAnimatable, 0)
+	this._clock = tools.NewRealTimeClock()
 
 	// Matrices
 	this._transformMatrix = math32.NewMatrix4().Zero()
@@ -218,12 +241,25 @@ func (this *Scene) GetAnimationRatio() float32 {
 	return this._animationRatio
 }
 
+// GetRenderId counts the frames rendered by the scene
+func (this *Scene) GetRenderId() int {
+	return this._renderId
+}
+
+// SetClock replaces the time source of animations and particles
+func (this *Scene) SetClock(clock IClock) {
+	this._clock = clock
+}
+func (this *Scene) GetClock() IClock {
+	return this._clock
+}
+
 //ready
 
 func (this *Scene) IsReady() bool {
 
 	for index := 0; index < len(this.Materials); index++ {
-		if !this.Materials[index].IsReady(nil) {
+		if !this.Materials[index].IsReady(nil, false) {
 			return false
 		}
 	}
@@ -323,6 +359,24 @@ func (this *Scene) GetMaterialByID(id string) IMaterial {
 	return nil
 }
 
+func (this *Scene) GetSkeletonByID(id string) ISkeleton {
+	for index := 0; index < len(this.Skeletons); index++ {
+		if this.Skeletons[index].GetId() == id {
+			return this.Skeletons[index]
+		}
+	}
+	return nil
+}
+
+func (this *Scene) GetLightByID(id string) ILight {
+	for index := 0; index < len(this.Lights); index++ {
+		if this.Lights[index].GetId() == id {
+			return this.Lights[index]
+		}
+	}
+	return nil
+}
+
 func (this *Scene) GetMeshByID(id string) IMesh {
 	for index := 0; index < len(this.Meshes); index++ {
 		if this.Meshes[index].GetId() == id {
@@ -358,31 +412,56 @@ func (this *Scene) IsActiveMesh(mesh IMesh) bool {
 	return index != -1
 }
 
+// GetInstancesBatch returns what the mesh draws in the current frame, nil when
+// the mesh was not evaluated
+func (this *Scene) GetInstancesBatch(mesh IMesh) *InstancesBatch {
+	return this._instancesBatches[mesh]
+}
+
+func (this *Scene) _getInstancesBatch(mesh IMesh) *InstancesBatch {
+	batch, ok := this._instancesBatches[mesh]
+	if !ok {
+		batch = &InstancesBatch{}
+		this._instancesBatches[mesh] = batch
+	}
+	return batch
+}
+
 func (this *Scene) _evaluateSubMesh(subMesh ISubMesh, mesh IMesh) {
 	if len(mesh.GetSubMeshes()) == 1 || subMesh.IsInFrustrum(this._frustumPlanes) {
-		material := subMesh.GetMaterial()
-
-		if material != nil {
-			// Render targets
-			rendertargets := material.GetRenderTargetTextures()
-			if rendertargets != nil {
-				if tools.IndexOf(material, this._processedMaterials) == -1 {
-					this._processedMaterials = append(this._processedMaterials, material)
-					this._renderTargets = append(this._renderTargets, rendertargets...)
-				}
+		this._dispatchSubMesh(subMesh, mesh)
+	}
+}
+
+func (this *Scene) _dispatchSubMesh(subMesh ISubMesh, mesh IMesh) {
+	// A submesh shared by instances is dispatched once
+	if this._dispatchedSubMeshes[subMesh] {
+		return
+	}
+	this._dispatchedSubMeshes[subMesh] = true
 
+	material := subMesh.GetMaterial()
+
+	if material != nil {
+		// Render targets
+		rendertargets := material.GetRenderTargetTextures()
+		if rendertargets != nil {
+			if tools.IndexOf(material, this._processedMaterials) == -1 {
+				this._processedMaterials = append(this._processedMaterials, material)
+				this._renderTargets = append(this._renderTargets, rendertargets...)
 			}
 
-			// Dispatch
-			if material.NeedAlphaBlending() || mesh.GetVisibility() < 1.0 { // Transparent
-				if material.GetAlpha() > 0 || mesh.GetVisibility() < 1.0 {
-					this._transparentSubMeshes = append(this._transparentSubMeshes, subMesh) // Opaque
-				}
-			} else if material.NeedAlphaBlending() { // Alpha test
-				this._alphaTestSubMeshes = append(this._alphaTestSubMeshes, subMesh)
-			} else {
-				this._opaqueSubMeshes = append(this._opaqueSubMeshes, subMesh)
+		}
+
+		// Dispatch
+		if material.NeedAlphaBlending() || mesh.GetVisibility() < 1.0 { // Transparent
+			if material.GetAlpha() > 0 || mesh.GetVisibility() < 1.0 {
+				this._transparentSubMeshes = append(this._transparentSubMeshes, subMesh) // Opaque
 			}
+		} else if material.NeedAlphaTesting() { // Alpha test
+			this._alphaTestSubMeshes = append(this._alphaTestSubMeshes, subMesh)
+		} else {
+			this._opaqueSubMeshes = append(this._opaqueSubMeshes, subMesh)
 		}
 	}
 }
@@ -396,6 +475,8 @@ func (this *Scene) _evaluateActiveMeshes() {
 	this._processedMaterials = make([]IMaterial, 0)
 	this._renderTargets = make([]ITexture, 0)
 	this._activeParticleSystems = make([]IParticleSystem, 0)
+	this._instancesBatches = map[IMesh]*InstancesBatch{}
+	this._dispatchedSubMeshes = map[ISubMesh]bool{}
 
 	if this._frustumPlanes == nil || len(this._frustumPlanes) == 0 {
 		this._frustumPlanes = math32.NewFrustum().GetPlanes(this._transformMatrix)
@@ -416,11 +497,32 @@ func (this *Scene) _evaluateActiveMeshes() {
 
 		mesh.ComputeWorldMatrix()
 
+		// The level of detail drawn for the mesh at the distance of the camera
+		meshLOD := mesh.GetLOD(this.ActiveCamera)
+		if meshLOD == nil {
+			continue
+		}
+		if meshLOD != mesh {
+			meshLOD.ComputeWorldMatrix()
+		}
+
 		if mesh.IsEnabled() && mesh.IsVisible() && mesh.GetVisibility() > 0.0 && mesh.IsInFrustrum(this._frustumPlanes) {
 			this._activeMeshes = append(this._activeMeshes, mesh)
 
-			for _, subMesh := range mesh.GetSubMeshes() {
-				this._evaluateSubMesh(subMesh, mesh)
+			// Instances are drawn by their source mesh, or its level of detail
+			if instance, ok := mesh.(IInstancedMesh); ok {
+				batch := this._getInstancesBatch(meshLOD)
+				batch.Instances = append(batch.Instances, instance)
+
+				for _, subMesh := range meshLOD.GetSubMeshes() {
+					this._dispatchSubMesh(subMesh, meshLOD)
+				}
+				continue
+			}
+			this._getInstancesBatch(meshLOD).RenderSelf = true
+
+			for _, subMesh := range meshLOD.GetSubMeshes() {
+				this._evaluateSubMesh(subMesh, meshLOD)
 			}
 
 		}
@@ -510,6 +612,7 @@ func (this *Scene) Render() {
 	}
 
 	startDate := tools.GetCurrentTimeMs()
+	this._renderId++
 	this._particlesDuration = 0
 	this._activeParticles = 0
 	engine := this._engine
@@ -526,9 +629,15 @@ func (this *Scene) Render() {
 	this.SetTransformMatrix(this.ActiveCamera.GetViewMatrix(), this.ActiveCamera.GetProjectionMatrix())
 
 	// Animations
-	this._animationRatio = tools.GetDeltaTime() * (60.0 / 1000.0)
+	this._clock.Tick()
+	this._animationRatio = this._clock.GetDeltaTime() * (60.0 / 1000.0)
 	this._animate()
 
+	// Skeletons
+	for skeletonIndex := 0; skeletonIndex < len(this.Skeletons); skeletonIndex++ {
+		this.Skeletons[skeletonIndex].Prepare()
+	}
+
 	// Meshes
 	beforeEvaluateActiveMeshesDate := tools.GetCurrentTimeMs()
 	this._evaluateActiveMeshes()
@@ -611,6 +720,37 @@ func (this *Scene) Render() {
 	this._lastFrameDuration = tools.GetCurrentTimeMs() - startDate
 }
 
+// RecordFrames renders count frames with a fixed timestep of frameTime
+// milliseconds and writes every frame as a PNG. pathFormat receives the frame
+// index, e.g. "capture/frame%04d.png"
+func (this *Scene) RecordFrames(count int, frameTime float32, pathFormat string) error {
+	engine := this._engine
+
+	// Continue from the current time so running animations carry on
+	previousClock := this._clock
+	clock := tools.NewFixedStepClock(frameTime)
+	clock.SetTime(previousClock.Now())
+	clock.SetScale(previousClock.GetScale())
+	this._clock = clock
+	defer func() {
+		this._clock = previousClock
+	}()
+
+	for index := 0; index < count; index++ {
+		engine.BeginFrame()
+		this.Render()
+		img := engine.ReadPixels(0, 0, engine.GetRenderWidth(), engine.GetRenderHeight())
+		engine.EndFrame()
+
+		err := tools.SavePNG(fmt.Sprintf(pathFormat, index), img)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (this *Scene) Dispose() {
 
 	this.BeforeRender = nil
@@ -0,0 +1,64 @@
+// +build glnull glsoft
+
+package engines_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/gl"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cameras"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+)
+
+const leavesVertex = `
+attribute vec3 position;
+uniform mat4 worldViewProjection;
+void main(void) {
+	gl_Position = worldViewProjection * vec4(position, 1.0);
+}
+`
+
+const leavesFragment = `
+precision mediump float;
+void main(void) {
+	gl_FragColor = vec4(0.2, 0.8, 0.2, 1.0);
+}
+`
+
+func TestRenderAlphaTestedAfterOpaque(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	camera := cameras.NewFreeCamera("camera", math32.NewVector3(0, 0, -10), scene)
+	camera.SetTarget(math32.NewVector3(0, 0, 0))
+
+	// The alpha tested mesh comes first, so it is drawn first unless it is sorted
+	leaves := materials.NewShaderMaterialFromSource("leaves", scene, leavesVertex, leavesFragment, &materials.ShaderMaterialOptions{
+		Attributes:       []string{"position"},
+		Uniforms:         []string{"worldViewProjection"},
+		NeedAlphaTesting: true,
+	})
+	meshs.CreateBox("leaves", 1, scene, false).Material = leaves
+	meshs.CreateBox("wall", 1, scene, false).Material = materials.NewStandardMaterial("wall", scene)
+
+	// The effects compile during the first frames
+	for frame := 0; frame < 3; frame++ {
+		scene.Render()
+	}
+	gl.ResetCalls()
+	scene.Render()
+
+	order := make([]string, 0)
+	for _, draw := range gl.DrawCalls() {
+		if strings.Contains(gl.ProgramSource(draw.Program, gl.FRAGMENT_SHADER), "vec4(0.2, 0.8, 0.2, 1.0)") {
+			order = append(order, "leaves")
+		} else {
+			order = append(order, "wall")
+		}
+	}
+	if strings.Join(order, " ") != "wall leaves" {
+		t.Errorf("draw order %v, want [wall leaves]", order)
+	}
+}
@@ -0,0 +1,282 @@
+package engines
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// Scene files store every object as the JSON value returned by its Serialize
+// method. LoadScene creates them again with the parser registered for their
+// "type" field, packages register their parsers when they are imported. A
+// program loading scenes without using every package imports the missing ones
+// for their side effect:
+//
+//	import (
+//		_ "github.com/suiqirui1987/fly3d/module/animations"
+//		_ "github.com/suiqirui1987/fly3d/module/bones"
+//		_ "github.com/suiqirui1987/fly3d/module/cameras"
+//		_ "github.com/suiqirui1987/fly3d/module/layers"
+//		_ "github.com/suiqirui1987/fly3d/module/lights"
+//		_ "github.com/suiqirui1987/fly3d/module/materials"
+//		_ "github.com/suiqirui1987/fly3d/module/meshs"
+//		_ "github.com/suiqirui1987/fly3d/module/particles"
+//		_ "github.com/suiqirui1987/fly3d/module/textures"
+//	)
+
+const sceneFileVersion = 1
+
+// ParseFunc creates an object of scene from its serialized form
+type ParseFunc func(data []byte, scene *Scene) (interface{}, error)
+
+var _parsers = map[string]ParseFunc{}
+
+// RegisterParser sets the parser of the objects serialized with typeName, the
+// packages of module call it from init for the types they define
+func RegisterParser(typeName string, parser ParseFunc) {
+	_parsers[typeName] = parser
+}
+
+// SerializeObject encodes object, nil when it cannot be stored
+func SerializeObject(object interface{}) json.RawMessage {
+	if object == nil {
+		return nil
+	}
+
+	serializable, ok := object.(ISerializable)
+	if !ok {
+		log.Printf("serializer: %T is not serializable", object)
+		return nil
+	}
+
+	value := serializable.Serialize()
+	if value == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("serializer: %T: %s", object, err)
+		return nil
+	}
+	return data
+}
+
+// ParseObject creates the object encoded by SerializeObject
+func ParseObject(data []byte, scene *Scene) (interface{}, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	header := struct {
+		Type string `json:"type"`
+	}{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	parser, ok := _parsers[header.Type]
+	if !ok {
+		return nil, fmt.Errorf("no parser registered for type %q, the package defining it is not imported", header.Type)
+	}
+	return parser(data, scene)
+}
+
+// AfterParse makes LoadScene run fn once every object of the file is parsed,
+// for the references of a parser to objects found further in the file
+func (this *Scene) AfterParse(fn func() error) {
+	this._afterParse = append(this._afterParse, fn)
+}
+
+type sceneFile struct {
+	Version int `json:"version"`
+
+	AutoClear         bool            `json:"autoClear"`
+	ClearColor        *math32.Color3  `json:"clearColor"`
+	AmbientColor      *math32.Color3  `json:"ambientColor"`
+	CollisionsEnabled bool            `json:"collisionsEnabled"`
+	Gravity           *math32.Vector3 `json:"gravity"`
+	ParticlesEnabled  bool            `json:"particlesEnabled"`
+
+	FogMode    int            `json:"fogMode"`
+	FogColor   *math32.Color3 `json:"fogColor"`
+	FogDensity float32        `json:"fogDensity"`
+	FogStart   float32        `json:"fogStart"`
+	FogEnd     float32        `json:"fogEnd"`
+
+	// Sections are parsed in this order, objects only reference the previous ones
+	Lights           []json.RawMessage `json:"lights"`
+	Materials        []json.RawMessage `json:"materials"`
+	MultiMaterials   []json.RawMessage `json:"multiMaterials"`
+	Skeletons        []json.RawMessage `json:"skeletons"`
+	Meshes           []json.RawMessage `json:"meshes"`
+	Cameras          []json.RawMessage `json:"cameras"`
+	ActiveCameraID   string            `json:"activeCameraID"`
+	ShadowGenerators []json.RawMessage `json:"shadowGenerators"`
+	ParticleSystems  []json.RawMessage `json:"particleSystems"`
+	Layers           []json.RawMessage `json:"layers"`
+	Animatables      []json.RawMessage `json:"animatables"`
+}
+
+func appendObject(list []json.RawMessage, object interface{}) []json.RawMessage {
+	if data := SerializeObject(object); data != nil {
+		return append(list, data)
+	}
+	return list
+}
+
+// Serialize encodes the scene graph, LoadScene creates it again
+func (this *Scene) Serialize() ([]byte, error) {
+	file := &sceneFile{
+		Version:           sceneFileVersion,
+		AutoClear:         this.AutoClear,
+		ClearColor:        this.ClearColor,
+		AmbientColor:      this.AmbientColor,
+		CollisionsEnabled: this.CollisionsEnabled,
+		Gravity:           this.Gravity,
+		ParticlesEnabled:  this.ParticlesEnabled,
+		FogMode:           this.FogMode,
+		FogColor:          this.FogColor,
+		FogDensity:        this.FogDensity,
+		FogStart:          this.FogStart,
+		FogEnd:            this.FogEnd,
+	}
+
+	for _, light := range this.Lights {
+		file.Lights = appendObject(file.Lights, light)
+		if generator := light.GetShadowGenerator(); generator != nil {
+			file.ShadowGenerators = appendObject(file.ShadowGenerators, generator)
+		}
+	}
+	for _, material := range this.Materials {
+		file.Materials = appendObject(file.Materials, material)
+	}
+	for _, multiMaterial := range this.MultiMaterials {
+		file.MultiMaterials = appendObject(file.MultiMaterials, multiMaterial)
+	}
+	for _, skeleton := range this.Skeletons {
+		file.Skeletons = appendObject(file.Skeletons, skeleton)
+	}
+	for _, mesh := range this._sortedMeshes() {
+		file.Meshes = appendObject(file.Meshes, mesh)
+	}
+	for _, camera := range this.Cameras {
+		file.Cameras = appendObject(file.Cameras, camera)
+	}
+	if this.ActiveCamera != nil {
+		file.ActiveCameraID = this.ActiveCamera.GetId()
+	}
+	for _, system := range this.ParticleSystems {
+		file.ParticleSystems = appendObject(file.ParticleSystems, system)
+	}
+	for _, layer := range this.Layers {
+		file.Layers = appendObject(file.Layers, layer)
+	}
+	for _, animatable := range this.ActiveAnimatables {
+		file.Animatables = appendObject(file.Animatables, animatable)
+	}
+
+	return json.Marshal(file)
+}
+
+// _sortedMeshes returns the meshes with the parents before their children
+func (this *Scene) _sortedMeshes() []IMesh {
+	type parented interface {
+		GetParent() IMesh
+	}
+
+	inScene := map[IMesh]bool{}
+	for _, mesh := range this.Meshes {
+		inScene[mesh] = true
+	}
+
+	result := make([]IMesh, 0, len(this.Meshes))
+	visited := map[IMesh]bool{}
+	var visit func(mesh IMesh)
+	visit = func(mesh IMesh) {
+		if visited[mesh] || !inScene[mesh] {
+			return
+		}
+		visited[mesh] = true
+		if child, ok := mesh.(parented); ok && child.GetParent() != nil {
+			visit(child.GetParent())
+		}
+		result = append(result, mesh)
+	}
+	for _, mesh := range this.Meshes {
+		visit(mesh)
+	}
+
+	return result
+}
+
+// LoadScene creates a scene from the output of Scene.Serialize. It fails on
+// the objects whose package is not imported, see RegisterParser
+func LoadScene(data []byte, engine *Engine) (*Scene, error) {
+	file := &sceneFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("scene: %s", err)
+	}
+	if file.Version > sceneFileVersion {
+		return nil, fmt.Errorf("scene: unsupported version %d", file.Version)
+	}
+
+	scene := NewScene(engine)
+	scene.AutoClear = file.AutoClear
+	if file.ClearColor != nil {
+		scene.ClearColor = file.ClearColor
+	}
+	if file.AmbientColor != nil {
+		scene.AmbientColor = file.AmbientColor
+	}
+	scene.CollisionsEnabled = file.CollisionsEnabled
+	if file.Gravity != nil {
+		scene.Gravity = file.Gravity
+	}
+	scene.ParticlesEnabled = file.ParticlesEnabled
+
+	scene.FogMode = file.FogMode
+	if file.FogColor != nil {
+		scene.FogColor = file.FogColor
+	}
+	scene.FogDensity = file.FogDensity
+	scene.FogStart = file.FogStart
+	scene.FogEnd = file.FogEnd
+
+	sections := [][]json.RawMessage{
+		file.Lights,
+		file.Materials,
+		file.MultiMaterials,
+		file.Skeletons,
+		file.Meshes,
+		file.Cameras,
+		file.ShadowGenerators,
+		file.ParticleSystems,
+		file.Layers,
+		file.Animatables,
+	}
+	for _, section := range sections {
+		for _, object := range section {
+			if _, err := ParseObject(object, scene); err != nil {
+				return nil, fmt.Errorf("scene: %s", err)
+			}
+		}
+	}
+
+	afterParse := scene._afterParse
+	scene._afterParse = nil
+	for _, fn := range afterParse {
+		if err := fn(); err != nil {
+			return nil, fmt.Errorf("scene: %s", err)
+		}
+	}
+
+	if file.ActiveCameraID != "" {
+		scene.ActiveCameraByID(file.ActiveCameraID)
+	}
+
+	return scene, nil
+}
@@ -0,0 +1,29 @@
+package engines
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseObjectWithoutParser(t *testing.T) {
+	parsers := _parsers
+	_parsers = map[string]ParseFunc{}
+	defer func() { _parsers = parsers }()
+
+	// A mesh of a program that does not import module/meshs
+	object, err := ParseObject([]byte(`{"type": "Mesh", "name": "box", "id": "box"}`), nil)
+	if object != nil || err == nil || !strings.Contains(err.Error(), `type "Mesh"`) {
+		t.Errorf("got %v, %v, want the missing parser of Mesh", object, err)
+	}
+
+	RegisterParser("Mesh", func(data []byte, scene *Scene) (interface{}, error) {
+		return "box", nil
+	})
+	if object, err := ParseObject([]byte(`{"type": "Mesh"}`), nil); object != "box" || err != nil {
+		t.Errorf("registered parser returned %v, %v", object, err)
+	}
+
+	if object, err := ParseObject([]byte("null"), nil); object != nil || err != nil {
+		t.Errorf("null object parsed to %v, %v", object, err)
+	}
+}
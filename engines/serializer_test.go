@@ -0,0 +1,142 @@
+// +build glnull glsoft
+
+package engines_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/core"
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cameras"
+	"github.com/suiqirui1987/fly3d/module/lights"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+)
+
+// buildScene uses every section of the scene files
+func buildScene(engine *engines.Engine) *engines.Scene {
+	scene := engines.NewScene(engine)
+	scene.ClearColor = math32.NewColor3(0.1, 0.2, 0.3)
+	scene.FogMode = core.FOGMODE_LINEAR
+	scene.FogStart = 10
+	scene.FogEnd = 50
+
+	lights.NewPointLight("point", math32.NewVector3(0, 4, -4), scene)
+	sun := lights.NewDirectionalLight("sun", math32.NewVector3(0.5, -1, 1), scene)
+
+	red := materials.NewStandardMaterial("red", scene)
+	red.DiffuseColor = math32.NewColor3(1, 0, 0)
+	blue := materials.NewStandardMaterial("blue", scene)
+	blue.DiffuseColor = math32.NewColor3(0, 0, 1)
+	shader := materials.NewShaderMaterialFromSource("waves", scene, "void main() {}", "void main() {}", nil)
+	shader.SetFloat("time", 2)
+	multi := materials.NewMultiMaterial("multi", scene)
+	multi.SubMaterials = append(multi.SubMaterials, red, blue)
+
+	box := meshs.CreateBox("box", 1, scene, false)
+	box.Position = math32.NewVector3(1, 2, 3)
+	box.Material = red
+
+	child := meshs.CreateSphere("child", 8, 1, scene, false)
+	child.Parent = box
+	child.Material = shader
+
+	ground := meshs.CreateGround("ground", 10, 10, 1, scene, false)
+	ground.MutilMaterial = multi
+
+	low := meshs.CreateBox("low", 1, scene, false)
+	box.AddLODLevel(30, low)
+
+	instance := box.CreateInstance("instance")
+	instance.Position = math32.NewVector3(-1, 0, 0)
+
+	generator := lights.NewShadowGenerator("shadows", 256, sun, scene)
+	generator.GetShadowMap().AddRenderList(box)
+
+	camera := cameras.NewFreeCamera("camera", math32.NewVector3(0, 3, -8), scene)
+	camera.SetTarget(math32.NewVector3(0, 0, 0))
+	scene.ActiveCamera = camera
+
+	return scene
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	engine := enginetest.NewEngine(t)
+	data, err := buildScene(engine).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scene, err := engines.LoadScene(data, engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A loaded scene serializes to the same file
+	again, err := scene.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("the loaded scene serializes differently\n%s\n%s", data, again)
+	}
+
+	if !scene.ClearColor.Equals(math32.NewColor3(0.1, 0.2, 0.3)) || scene.FogMode != core.FOGMODE_LINEAR || scene.FogEnd != 50 {
+		t.Errorf("scene settings not loaded")
+	}
+
+	box, _ := scene.GetMeshByID("box").(*meshs.Mesh)
+	child, _ := scene.GetMeshByID("child").(*meshs.Mesh)
+	ground, _ := scene.GetMeshByID("ground").(*meshs.Mesh)
+	instance, _ := scene.GetMeshByID("instance").(*meshs.InstancedMesh)
+	low, _ := scene.GetMeshByID("low").(*meshs.Mesh)
+	if box == nil || child == nil || ground == nil || low == nil {
+		t.Fatalf("meshes not loaded")
+	}
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"position", box.Position.Equals(math32.NewVector3(1, 2, 3))},
+		{"vertices", box.GetTotalVertices() == 24 && len(box.GetIndices()) == 36},
+		{"parent", child.Parent == box},
+		{"material", box.Material == scene.GetMaterialByID("red")},
+		{"shader material", child.Material == scene.GetMaterialByID("waves")},
+		{"multi material", ground.MutilMaterial != nil && ground.MutilMaterial.GetId() == "multi"},
+		{"level of detail", len(box.GetLODLevels()) == 1 && box.GetLODLevels()[0].Mesh == low && box.GetLODLevels()[0].Distance == 30},
+		{"instance", instance != nil && instance.GetSourceMesh() == box && instance.Position.Equals(math32.NewVector3(-1, 0, 0))},
+		{"shadows", scene.GetLightByID("sun").GetShadowGenerator() != nil},
+		{"active camera", scene.ActiveCamera != nil && scene.ActiveCamera.GetId() == "camera"},
+	}
+	for _, test := range tests {
+		if !test.ok {
+			t.Errorf("%s not restored", test.name)
+		}
+	}
+}
+
+func TestLoadSceneErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"invalid json", `{"version": `, "scene: unexpected end of JSON input"},
+		{"version", `{"version": 2}`, "scene: unsupported version 2"},
+		{"unknown type", `{"version": 1, "meshes": [{"type": "Teapot"}]}`, `no parser registered for type "Teapot"`},
+		{"missing material", `{"version": 1, "meshes": [{"type": "Mesh", "name": "m", "id": "m", "materialId": "paint"}]}`, "mesh m: material paint not found"},
+		{"missing level of detail", `{"version": 1, "meshes": [{"type": "Mesh", "name": "m", "id": "m", "lodLevels": [{"distance": 10, "meshId": "far"}]}]}`, "mesh m: level of detail far not found"},
+	}
+
+	for _, test := range tests {
+		_, err := engines.LoadScene([]byte(test.data), enginetest.NewEngine(t))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
@@ -11,7 +11,14 @@ It supports:
 
 - iOS and Android via OpenGL ES 2.0 backend,
 
-- Modern Browsers (desktop and mobile) via WebGL 1.0 backend.
+- Modern Browsers (desktop and mobile) via WebGL 1.0 backend,
+
+- GPU-less testing via an in-memory null backend (build with -tags glnull), which records
+every call and keeps object state; see Calls, DrawCalls and ResetContext.
+
+- CPU rendering via a software rasterizer (build with -tags glsoft), which extends the null backend and
+draws into its framebuffers so ReadPixels returns real pixels. Shaders are run by Go code registered
+with RegisterSoftShader; the built-in effects are provided by module/effects.
 
 This is a fork of golang.org/x/mobile/gl package with [CL 8793](https://go-review.googlesource.com/8793)
 merged in and Windows support added. This package is fully functional, but may eventually become superceded by
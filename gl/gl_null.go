@@ -0,0 +1,2534 @@
+// +build glnull glsoft
+
+package gl
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// The null backend implements the gl api in memory. Every call is recorded
+// and gl objects keep their state (buffer contents, texture images, shader
+// sources, uniform values...), so the engine can run and be inspected
+// without a gpu or a display. Build with -tags glnull, or -tags glsoft to
+// also rasterize draws into the framebuffers.
+
+// ContextWatcher is this library's context watcher, satisfying glfw.ContextWatcher interface.
+// The null backend has no context, notifications are ignored.
+var ContextWatcher = new(contextWatcher)
+
+type contextWatcher struct{}
+
+func (contextWatcher) OnMakeCurrent(context interface{}) {}
+func (contextWatcher) OnDetach()                         {}
+
+// Call is a gl function call recorded by the null backend
+type Call struct {
+	Name string
+	Args []interface{}
+}
+
+func (this Call) String() string {
+	args := make([]string, len(this.Args))
+	for index, arg := range this.Args {
+		args[index] = fmt.Sprintf("%v", arg)
+	}
+	return this.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// DrawCall is a DrawArrays, DrawElements or DrawElementsInstanced call with
+// the state it was issued with. Instances is 0 for the draws that are not instanced
+type DrawCall struct {
+	Mode        Enum
+	First       int
+	Count       int
+	Type        Enum
+	Offset      int
+	Indexed     bool
+	Instances   int
+	Program     Program
+	Framebuffer Framebuffer
+	Viewport    [4]int
+}
+
+type nullBuffer struct {
+	data  []byte
+	usage Enum
+}
+
+type nullImage struct {
+	width  int
+	height int
+	format Enum
+	ty     Enum
+	data   []byte
+}
+
+type nullTexture struct {
+	target Enum
+	images map[Enum][]*nullImage
+	params map[Enum]float32
+}
+
+type nullRenderbuffer struct {
+	format Enum
+	width  int
+	height int
+	depth  []float32
+}
+
+type nullAttachment struct {
+	texture      Texture
+	texTarget    Enum
+	level        int
+	renderbuffer Renderbuffer
+}
+
+type nullFramebuffer struct {
+	attachments map[Enum]*nullAttachment
+}
+
+type nullShader struct {
+	ty         Enum
+	source     string
+	compiled   bool
+	deleted    bool
+	infoLog    string
+	attributes []nullVariable
+	uniforms   []nullVariable
+	defines    map[string]string
+}
+
+type nullProgram struct {
+	shaders       []Shader
+	sources       map[Enum]string
+	linked        bool
+	validated     bool
+	deleted       bool
+	infoLog       string
+	boundAttribs  map[string]int
+	attributes    []nullVariable
+	attribLocs    map[string]int
+	uniforms      []nullVariable
+	uniformLocs   map[string]int32
+	uniformValues map[int32][]float32
+	defines       map[string]string
+}
+
+type nullVertexAttrib struct {
+	enabled    bool
+	buffer     Buffer
+	size       int
+	ty         Enum
+	normalized bool
+	stride     int
+	offset     int
+	divisor    int
+	value      [4]float32
+}
+
+type nullContext struct {
+	nextName uint32
+	err      Enum
+
+	calls []Call
+	draws []DrawCall
+
+	buffers       map[uint32]*nullBuffer
+	textures      map[uint32]*nullTexture
+	renderbuffers map[uint32]*nullRenderbuffer
+	framebuffers  map[uint32]*nullFramebuffer
+	shaders       map[uint32]*nullShader
+	programs      map[uint32]*nullProgram
+
+	boundBuffers      map[Enum]Buffer
+	boundTextures     map[int]map[Enum]Texture
+	boundFramebuffer  Framebuffer
+	boundRenderbuffer Renderbuffer
+	activeTexture     int
+	program           Program
+	attribs           []nullVertexAttrib
+
+	defaultColor *nullImage
+	defaultDepth []float32
+
+	caps       map[Enum]bool
+	hints      map[Enum]Enum
+	pixelStore map[Enum]int32
+	viewport   [4]int
+	scissor    [4]int32
+
+	clearColor   [4]float32
+	clearDepth   float32
+	clearStencil int
+	colorMask    [4]bool
+	depthMask    bool
+	depthFunc    Enum
+	depthRange   [2]float32
+	cullFace     Enum
+	frontFace    Enum
+	lineWidth    float32
+
+	blendColor    [4]float32
+	blendEquation [2]Enum
+	blendFunc     [4]Enum
+
+	polygonOffset [2]float32
+	stencilFunc   [3]int
+	stencilMask   uint32
+	stencilOp     [3]Enum
+}
+
+const (
+	nullMaxVertexAttribs = 16
+	nullMaxTextureUnits  = 16
+	nullMaxTextureSize   = 4096
+)
+
+var nullCtx = newNullContext()
+
+// rasterize is installed by the software backend to turn draws into pixels
+var rasterize func(call DrawCall)
+
+// shaderValidator lets tests reject shader sources, a non empty result is the compile log
+var shaderValidator func(ty Enum, source string) string
+
+// elementIndexUint reports the OES_element_index_uint extension, tests can turn it off
+var elementIndexUint = true
+
+func newNullContext() *nullContext {
+	this := &nullContext{}
+	this.nextName = 1
+	this.buffers = map[uint32]*nullBuffer{}
+	this.textures = map[uint32]*nullTexture{}
+	this.renderbuffers = map[uint32]*nullRenderbuffer{}
+	this.framebuffers = map[uint32]*nullFramebuffer{}
+	this.shaders = map[uint32]*nullShader{}
+	this.programs = map[uint32]*nullProgram{}
+
+	this.boundBuffers = map[Enum]Buffer{}
+	this.boundTextures = map[int]map[Enum]Texture{}
+	this.attribs = make([]nullVertexAttrib, nullMaxVertexAttribs)
+	for index := range this.attribs {
+		this.attribs[index].value = [4]float32{0, 0, 0, 1}
+	}
+
+	this.caps = map[Enum]bool{DITHER: true}
+	this.hints = map[Enum]Enum{}
+	this.pixelStore = map[Enum]int32{PACK_ALIGNMENT: 4, UNPACK_ALIGNMENT: 4}
+
+	this.clearDepth = 1
+	this.colorMask = [4]bool{true, true, true, true}
+	this.depthMask = true
+	this.depthFunc = LESS
+	this.depthRange = [2]float32{0, 1}
+	this.cullFace = BACK
+	this.frontFace = CCW
+	this.lineWidth = 1
+	this.blendEquation = [2]Enum{FUNC_ADD, FUNC_ADD}
+	this.blendFunc = [4]Enum{ONE, ZERO, ONE, ZERO}
+	this.stencilFunc = [3]int{ALWAYS, 0, -1}
+	this.stencilMask = 0xffffffff
+	this.stencilOp = [3]Enum{KEEP, KEEP, KEEP}
+
+	return this
+}
+
+func (this *nullContext) record(name string, args ...interface{}) {
+	this.calls = append(this.calls, Call{Name: name, Args: args})
+}
+
+func (this *nullContext) setError(err Enum) {
+	if this.err == NO_ERROR {
+		this.err = err
+	}
+}
+
+func (this *nullContext) genName() uint32 {
+	name := this.nextName
+	this.nextName++
+	return name
+}
+
+func (this *nullContext) boundBuffer(target Enum) *nullBuffer {
+	buffer := this.buffers[this.boundBuffers[target].Value]
+	if buffer == nil {
+		this.setError(INVALID_OPERATION)
+	}
+	return buffer
+}
+
+// textureBindingTarget maps a cube map face to the cube map binding point
+func textureBindingTarget(target Enum) Enum {
+	if target >= TEXTURE_CUBE_MAP_POSITIVE_X && target <= TEXTURE_CUBE_MAP_NEGATIVE_Z {
+		return TEXTURE_CUBE_MAP
+	}
+	return target
+}
+
+func (this *nullContext) boundTexture(target Enum) *nullTexture {
+	units := this.boundTextures[this.activeTexture]
+	if units == nil {
+		this.setError(INVALID_OPERATION)
+		return nil
+	}
+	texture := this.textures[units[textureBindingTarget(target)].Value]
+	if texture == nil {
+		this.setError(INVALID_OPERATION)
+	}
+	return texture
+}
+
+func (this *nullTexture) setImage(target Enum, level int, image *nullImage) {
+	levels := this.images[target]
+	for len(levels) <= level {
+		levels = append(levels, nil)
+	}
+	levels[level] = image
+	this.images[target] = levels
+}
+
+func (this *nullTexture) image(target Enum, level int) *nullImage {
+	levels := this.images[target]
+	if level < len(levels) {
+		return levels[level]
+	}
+	return nil
+}
+
+func (this *nullContext) currentProgram() *nullProgram {
+	program := this.programs[this.program.Value]
+	if program == nil || !program.linked {
+		this.setError(INVALID_OPERATION)
+		return nil
+	}
+	return program
+}
+
+// colorImage returns the image the bound framebuffer renders to
+func (this *nullContext) colorImage() *nullImage {
+	if !this.boundFramebuffer.Valid() {
+		return this.defaultColor
+	}
+	framebuffer := this.framebuffers[this.boundFramebuffer.Value]
+	if framebuffer == nil {
+		return nil
+	}
+	attachment := framebuffer.attachments[COLOR_ATTACHMENT0]
+	if attachment == nil || !attachment.texture.Valid() {
+		return nil
+	}
+	texture := this.textures[attachment.texture.Value]
+	if texture == nil {
+		return nil
+	}
+	return texture.image(attachment.texTarget, attachment.level)
+}
+
+// depthBuffer returns the depth values of the bound framebuffer, nil if it has no depth attachment
+func (this *nullContext) depthBuffer() []float32 {
+	if !this.boundFramebuffer.Valid() {
+		if this.defaultDepth == nil && this.defaultColor != nil {
+			this.defaultDepth = make([]float32, this.defaultColor.width*this.defaultColor.height)
+			for index := range this.defaultDepth {
+				this.defaultDepth[index] = 1
+			}
+		}
+		return this.defaultDepth
+	}
+	framebuffer := this.framebuffers[this.boundFramebuffer.Value]
+	if framebuffer == nil {
+		return nil
+	}
+	attachment := framebuffer.attachments[DEPTH_ATTACHMENT]
+	if attachment == nil || !attachment.renderbuffer.Valid() {
+		return nil
+	}
+	renderbuffer := this.renderbuffers[attachment.renderbuffer.Value]
+	if renderbuffer == nil {
+		return nil
+	}
+	if len(renderbuffer.depth) != renderbuffer.width*renderbuffer.height {
+		renderbuffer.depth = make([]float32, renderbuffer.width*renderbuffer.height)
+		for index := range renderbuffer.depth {
+			renderbuffer.depth[index] = 1
+		}
+	}
+	return renderbuffer.depth
+}
+
+// growDefaultFramebuffer makes the default framebuffer at least width x height pixels
+func (this *nullContext) growDefaultFramebuffer(width, height int) {
+	if this.defaultColor != nil && this.defaultColor.width >= width && this.defaultColor.height >= height {
+		return
+	}
+	if this.defaultColor != nil {
+		if this.defaultColor.width > width {
+			width = this.defaultColor.width
+		}
+		if this.defaultColor.height > height {
+			height = this.defaultColor.height
+		}
+	}
+	this.defaultColor = &nullImage{width: width, height: height, format: RGBA, ty: UNSIGNED_BYTE, data: make([]byte, width*height*4)}
+	this.defaultDepth = nil
+}
+
+func (this *nullContext) setUniform(name string, dst Uniform, values []float32) {
+	this.record(name, dst, values)
+	program := this.currentProgram()
+	if program == nil {
+		return
+	}
+	if dst.Value == -1 {
+		return
+	}
+	program.uniformValues[dst.Value] = values
+}
+
+func (this *nullContext) setVertexAttrib(dst Attrib, values ...float32) {
+	if int(dst.Value) < 0 || int(dst.Value) >= len(this.attribs) {
+		this.setError(INVALID_VALUE)
+		return
+	}
+	value := [4]float32{0, 0, 0, 1}
+	copy(value[:], values)
+	this.attribs[dst.Value].value = value
+}
+
+func copyFloats(src []float32) []float32 {
+	values := make([]float32, len(src))
+	copy(values, src)
+	return values
+}
+
+func intsToFloats(src ...int32) []float32 {
+	values := make([]float32, len(src))
+	for index, value := range src {
+		values[index] = float32(value)
+	}
+	return values
+}
+
+// Calls returns the gl calls recorded since the last ResetCalls
+func Calls() []Call {
+	return nullCtx.calls
+}
+
+// CountCalls returns how many times the named gl function was called
+func CountCalls(name string) int {
+	count := 0
+	for _, call := range nullCtx.calls {
+		if call.Name == name {
+			count++
+		}
+	}
+	return count
+}
+
+// DrawCalls returns the draws recorded since the last ResetCalls
+func DrawCalls() []DrawCall {
+	return nullCtx.draws
+}
+
+// ResetCalls clears the recorded calls and draws, gl objects are kept
+func ResetCalls() {
+	nullCtx.calls = nil
+	nullCtx.draws = nil
+}
+
+// ResetContext drops every gl object and restores the default state
+func ResetContext() {
+	nullCtx = newNullContext()
+}
+
+// SetShaderValidator installs a function that can reject shader sources,
+// a non empty result makes CompileShader fail with that log
+func SetShaderValidator(fn func(ty Enum, source string) string) {
+	shaderValidator = fn
+}
+
+// SetElementIndexUintSupported turns the OES_element_index_uint extension on
+// or off, without it DrawElements rejects UNSIGNED_INT indices
+func SetElementIndexUintSupported(supported bool) {
+	elementIndexUint = supported
+}
+
+// BufferContents returns the data stored in buffer b
+func BufferContents(b Buffer) []byte {
+	buffer := nullCtx.buffers[b.Value]
+	if buffer == nil {
+		return nil
+	}
+	return buffer.data
+}
+
+// TextureImage returns the size and pixels of a texture level, target is TEXTURE_2D or a cube map face
+func TextureImage(t Texture, target Enum, level int) (width, height int, data []byte) {
+	texture := nullCtx.textures[t.Value]
+	if texture == nil {
+		return 0, 0, nil
+	}
+	image := texture.image(target, level)
+	if image == nil {
+		return 0, 0, nil
+	}
+	return image.width, image.height, image.data
+}
+
+// FramebufferAttachment returns what is attached to fb at attachment point
+func FramebufferAttachment(fb Framebuffer, attachment Enum) (Texture, Renderbuffer) {
+	framebuffer := nullCtx.framebuffers[fb.Value]
+	if framebuffer == nil || framebuffer.attachments[attachment] == nil {
+		return Texture{}, Renderbuffer{}
+	}
+	return framebuffer.attachments[attachment].texture, framebuffer.attachments[attachment].renderbuffer
+}
+
+// ProgramSource returns the source a linked program was built from, ty is VERTEX_SHADER or FRAGMENT_SHADER
+func ProgramSource(p Program, ty Enum) string {
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		return ""
+	}
+	return program.sources[ty]
+}
+
+// UniformValue returns the last value set for the named uniform of program p
+func UniformValue(p Program, name string) []float32 {
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		return nil
+	}
+	location, ok := program.uniformLocs[name]
+	if !ok {
+		return nil
+	}
+	return program.uniformValues[location]
+}
+
+// ActiveTexture sets the active texture unit.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glActiveTexture.xhtml
+func ActiveTexture(texture Enum) {
+	nullCtx.record("ActiveTexture", texture)
+	unit := int(texture) - TEXTURE0
+	if unit < 0 || unit >= nullMaxTextureUnits {
+		nullCtx.setError(INVALID_ENUM)
+		return
+	}
+	nullCtx.activeTexture = unit
+}
+
+// AttachShader attaches a shader to a program.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glAttachShader.xhtml
+func AttachShader(p Program, s Shader) {
+	nullCtx.record("AttachShader", p, s)
+	program := nullCtx.programs[p.Value]
+	if program == nil || nullCtx.shaders[s.Value] == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	program.shaders = append(program.shaders, s)
+}
+
+// BindAttribLocation binds a vertex attribute index with a named
+// variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBindAttribLocation.xhtml
+func BindAttribLocation(p Program, a Attrib, name string) {
+	nullCtx.record("BindAttribLocation", p, a, name)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	program.boundAttribs[name] = int(a.Value)
+}
+
+// BindBuffer binds a buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBindBuffer.xhtml
+func BindBuffer(target Enum, b Buffer) {
+	nullCtx.record("BindBuffer", target, b)
+	if b.Valid() && nullCtx.buffers[b.Value] == nil {
+		nullCtx.buffers[b.Value] = &nullBuffer{}
+	}
+	nullCtx.boundBuffers[target] = b
+}
+
+// BindFramebuffer binds a framebuffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBindFramebuffer.xhtml
+func BindFramebuffer(target Enum, fb Framebuffer) {
+	nullCtx.record("BindFramebuffer", target, fb)
+	if fb.Valid() && nullCtx.framebuffers[fb.Value] == nil {
+		nullCtx.framebuffers[fb.Value] = &nullFramebuffer{attachments: map[Enum]*nullAttachment{}}
+	}
+	nullCtx.boundFramebuffer = fb
+}
+
+// BindRenderbuffer binds a render buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBindRenderbuffer.xhtml
+func BindRenderbuffer(target Enum, rb Renderbuffer) {
+	nullCtx.record("BindRenderbuffer", target, rb)
+	if rb.Valid() && nullCtx.renderbuffers[rb.Value] == nil {
+		nullCtx.renderbuffers[rb.Value] = &nullRenderbuffer{}
+	}
+	nullCtx.boundRenderbuffer = rb
+}
+
+// BindTexture binds a texture.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBindTexture.xhtml
+func BindTexture(target Enum, t Texture) {
+	nullCtx.record("BindTexture", target, t)
+	if t.Valid() {
+		texture := nullCtx.textures[t.Value]
+		if texture == nil {
+			texture = &nullTexture{images: map[Enum][]*nullImage{}, params: map[Enum]float32{}}
+			nullCtx.textures[t.Value] = texture
+		}
+		if texture.target == 0 {
+			texture.target = target
+		} else if texture.target != target {
+			nullCtx.setError(INVALID_OPERATION)
+			return
+		}
+	}
+	units := nullCtx.boundTextures[nullCtx.activeTexture]
+	if units == nil {
+		units = map[Enum]Texture{}
+		nullCtx.boundTextures[nullCtx.activeTexture] = units
+	}
+	units[target] = t
+}
+
+// BlendColor sets the blend color.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBlendColor.xhtml
+func BlendColor(red, green, blue, alpha float32) {
+	nullCtx.record("BlendColor", red, green, blue, alpha)
+	nullCtx.blendColor = [4]float32{red, green, blue, alpha}
+}
+
+// BlendEquation sets both RGB and alpha blend equations.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBlendEquation.xhtml
+func BlendEquation(mode Enum) {
+	nullCtx.record("BlendEquation", mode)
+	nullCtx.blendEquation = [2]Enum{mode, mode}
+}
+
+// BlendEquationSeparate sets RGB and alpha blend equations separatly.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBlendEquationSeparate.xhtml
+func BlendEquationSeparate(modeRGB, modeAlpha Enum) {
+	nullCtx.record("BlendEquationSeparate", modeRGB, modeAlpha)
+	nullCtx.blendEquation = [2]Enum{modeRGB, modeAlpha}
+}
+
+// BlendFunc sets the pixel blending factors.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBlendFunc.xhtml
+func BlendFunc(sfactor, dfactor Enum) {
+	nullCtx.record("BlendFunc", sfactor, dfactor)
+	nullCtx.blendFunc = [4]Enum{sfactor, dfactor, sfactor, dfactor}
+}
+
+// BlendFunc sets the pixel RGB and alpha blending factors separately.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBlendFuncSeparate.xhtml
+func BlendFuncSeparate(sfactorRGB, dfactorRGB, sfactorAlpha, dfactorAlpha Enum) {
+	nullCtx.record("BlendFuncSeparate", sfactorRGB, dfactorRGB, sfactorAlpha, dfactorAlpha)
+	nullCtx.blendFunc = [4]Enum{sfactorRGB, dfactorRGB, sfactorAlpha, dfactorAlpha}
+}
+
+// BufferData creates a new data store for the bound buffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBufferData.xhtml
+func BufferData(target Enum, src []byte, usage Enum) {
+	nullCtx.record("BufferData", target, len(src), usage)
+	buffer := nullCtx.boundBuffer(target)
+	if buffer == nil {
+		return
+	}
+	buffer.data = make([]byte, len(src))
+	copy(buffer.data, src)
+	buffer.usage = usage
+}
+
+// BufferInit creates a new unitialized data store for the bound buffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBufferData.xhtml
+func BufferInit(target Enum, size int, usage Enum) {
+	nullCtx.record("BufferInit", target, size, usage)
+	buffer := nullCtx.boundBuffer(target)
+	if buffer == nil {
+		return
+	}
+	buffer.data = make([]byte, size)
+	buffer.usage = usage
+}
+
+// BufferSubData sets some of data in the bound buffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glBufferSubData.xhtml
+func BufferSubData(target Enum, offset int, data []byte) {
+	nullCtx.record("BufferSubData", target, offset, len(data))
+	buffer := nullCtx.boundBuffer(target)
+	if buffer == nil {
+		return
+	}
+	if offset < 0 || offset+len(data) > len(buffer.data) {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	copy(buffer.data[offset:], data)
+}
+
+// CheckFramebufferStatus reports the completeness status of the
+// active framebuffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCheckFramebufferStatus.xhtml
+func CheckFramebufferStatus(target Enum) Enum {
+	nullCtx.record("CheckFramebufferStatus", target)
+	framebuffer := nullCtx.framebuffers[nullCtx.boundFramebuffer.Value]
+	if framebuffer != nil && len(framebuffer.attachments) == 0 {
+		return FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT
+	}
+	return FRAMEBUFFER_COMPLETE
+}
+
+// Clear clears the window.
+//
+// The behavior of Clear is influenced by the pixel ownership test,
+// the scissor test, dithering, and the buffer writemasks.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glClear.xhtml
+func Clear(mask Enum) {
+	nullCtx.record("Clear", mask)
+	if mask&DEPTH_BUFFER_BIT != 0 && nullCtx.depthMask {
+		depth := nullCtx.depthBuffer()
+		for index := range depth {
+			depth[index] = nullCtx.clearDepth
+		}
+	}
+	if mask&COLOR_BUFFER_BIT == 0 {
+		return
+	}
+	image := nullCtx.colorImage()
+	if image == nil || image.data == nil {
+		return
+	}
+	var color [4]byte
+	for index, value := range nullCtx.clearColor {
+		color[index] = byte(math.Max(0, math.Min(1, float64(value)))*255 + 0.5)
+	}
+	for pos := 0; pos+4 <= len(image.data); pos += 4 {
+		for channel := 0; channel < 4; channel++ {
+			if nullCtx.colorMask[channel] {
+				image.data[pos+channel] = color[channel]
+			}
+		}
+	}
+}
+
+// ClearColor specifies the RGBA values used to clear color buffers.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glClearColor.xhtml
+func ClearColor(red, green, blue, alpha float32) {
+	nullCtx.record("ClearColor", red, green, blue, alpha)
+	nullCtx.clearColor = [4]float32{red, green, blue, alpha}
+}
+
+// ClearDepthf sets the depth value used to clear the depth buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glClearDepthf.xhtml
+func ClearDepthf(d float32) {
+	nullCtx.record("ClearDepthf", d)
+	nullCtx.clearDepth = d
+}
+
+// ClearStencil sets the index used to clear the stencil buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glClearStencil.xhtml
+func ClearStencil(s int) {
+	nullCtx.record("ClearStencil", s)
+	nullCtx.clearStencil = s
+}
+
+// ColorMask specifies whether color components in the framebuffer
+// can be written.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glColorMask.xhtml
+func ColorMask(red, green, blue, alpha bool) {
+	nullCtx.record("ColorMask", red, green, blue, alpha)
+	nullCtx.colorMask = [4]bool{red, green, blue, alpha}
+}
+
+// CompileShader compiles the source code of s.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCompileShader.xhtml
+func CompileShader(s Shader) {
+	nullCtx.record("CompileShader", s)
+	shader := nullCtx.shaders[s.Value]
+	if shader == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	_, attributes, uniforms, defines, err := preprocessShader(shader.source)
+	if err == "" && shaderValidator != nil {
+		err = shaderValidator(shader.ty, shader.source)
+	}
+	shader.compiled = err == ""
+	shader.infoLog = err
+	shader.attributes = attributes
+	shader.uniforms = uniforms
+	shader.defines = defines
+}
+
+// CompressedTexImage2D writes a compressed 2D texture.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCompressedTexImage2D.xhtml
+func CompressedTexImage2D(target Enum, level int, internalformat Enum, width, height, border int, data []byte) {
+	nullCtx.record("CompressedTexImage2D", target, level, internalformat, width, height, border, len(data))
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	image := &nullImage{width: width, height: height, format: internalformat, data: make([]byte, len(data))}
+	copy(image.data, data)
+	texture.setImage(target, level, image)
+}
+
+// CompressedTexSubImage2D writes a subregion of a compressed 2D texture.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCompressedTexSubImage2D.xhtml
+func CompressedTexSubImage2D(target Enum, level, xoffset, yoffset, width, height int, format Enum, data []byte) {
+	nullCtx.record("CompressedTexSubImage2D", target, level, xoffset, yoffset, width, height, format, len(data))
+	nullCtx.boundTexture(target)
+}
+
+// CopyTexImage2D writes a 2D texture from the current framebuffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCopyTexImage2D.xhtml
+func CopyTexImage2D(target Enum, level int, internalformat Enum, x, y, width, height, border int) {
+	nullCtx.record("CopyTexImage2D", target, level, internalformat, x, y, width, height, border)
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	image := &nullImage{width: width, height: height, format: internalformat, ty: UNSIGNED_BYTE, data: make([]byte, width*height*4)}
+	readPixels(image.data, x, y, width, height)
+	texture.setImage(target, level, image)
+}
+
+// CopyTexSubImage2D writes a 2D texture subregion from the
+// current framebuffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCopyTexSubImage2D.xhtml
+func CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	nullCtx.record("CopyTexSubImage2D", target, level, xoffset, yoffset, x, y, width, height)
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	data := make([]byte, width*height*4)
+	readPixels(data, x, y, width, height)
+	writeSubImage(texture.image(target, level), xoffset, yoffset, width, height, data)
+}
+
+// CreateBuffer creates a buffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGenBuffers.xhtml
+func CreateBuffer() Buffer {
+	b := Buffer{Value: nullCtx.genName()}
+	nullCtx.record("CreateBuffer", b)
+	nullCtx.buffers[b.Value] = &nullBuffer{}
+	return b
+}
+
+// CreateFramebuffer creates a framebuffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGenFramebuffers.xhtml
+func CreateFramebuffer() Framebuffer {
+	fb := Framebuffer{Value: nullCtx.genName()}
+	nullCtx.record("CreateFramebuffer", fb)
+	nullCtx.framebuffers[fb.Value] = &nullFramebuffer{attachments: map[Enum]*nullAttachment{}}
+	return fb
+}
+
+// CreateProgram creates a new empty program object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCreateProgram.xhtml
+func CreateProgram() Program {
+	p := Program{Value: nullCtx.genName()}
+	nullCtx.record("CreateProgram", p)
+	nullCtx.programs[p.Value] = &nullProgram{
+		sources:       map[Enum]string{},
+		boundAttribs:  map[string]int{},
+		attribLocs:    map[string]int{},
+		uniformLocs:   map[string]int32{},
+		uniformValues: map[int32][]float32{},
+		defines:       map[string]string{},
+	}
+	return p
+}
+
+// CreateRenderbuffer create a renderbuffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGenRenderbuffers.xhtml
+func CreateRenderbuffer() Renderbuffer {
+	rb := Renderbuffer{Value: nullCtx.genName()}
+	nullCtx.record("CreateRenderbuffer", rb)
+	nullCtx.renderbuffers[rb.Value] = &nullRenderbuffer{}
+	return rb
+}
+
+// CreateShader creates a new empty shader object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCreateShader.xhtml
+func CreateShader(ty Enum) Shader {
+	s := Shader{Value: nullCtx.genName()}
+	nullCtx.record("CreateShader", ty, s)
+	nullCtx.shaders[s.Value] = &nullShader{ty: ty}
+	return s
+}
+
+// CreateTexture creates a texture object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGenTextures.xhtml
+func CreateTexture() Texture {
+	t := Texture{Value: nullCtx.genName()}
+	nullCtx.record("CreateTexture", t)
+	nullCtx.textures[t.Value] = &nullTexture{images: map[Enum][]*nullImage{}, params: map[Enum]float32{}}
+	return t
+}
+
+// CullFace specifies which polygons are candidates for culling.
+//
+// Valid modes: FRONT, BACK, FRONT_AND_BACK.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glCullFace.xhtml
+func CullFace(mode Enum) {
+	nullCtx.record("CullFace", mode)
+	nullCtx.cullFace = mode
+}
+
+// DeleteBuffer deletes the given buffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDeleteBuffers.xhtml
+func DeleteBuffer(v Buffer) {
+	nullCtx.record("DeleteBuffer", v)
+	delete(nullCtx.buffers, v.Value)
+	for target, b := range nullCtx.boundBuffers {
+		if b == v {
+			nullCtx.boundBuffers[target] = Buffer{}
+		}
+	}
+}
+
+// DeleteFramebuffer deletes the given framebuffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDeleteFramebuffers.xhtml
+func DeleteFramebuffer(v Framebuffer) {
+	nullCtx.record("DeleteFramebuffer", v)
+	delete(nullCtx.framebuffers, v.Value)
+	if nullCtx.boundFramebuffer == v {
+		nullCtx.boundFramebuffer = Framebuffer{}
+	}
+}
+
+// DeleteProgram deletes the given program object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDeleteProgram.xhtml
+func DeleteProgram(p Program) {
+	nullCtx.record("DeleteProgram", p)
+	if nullCtx.program == p {
+		// a program in use is only flagged for deletion
+		if program := nullCtx.programs[p.Value]; program != nil {
+			program.deleted = true
+		}
+		return
+	}
+	delete(nullCtx.programs, p.Value)
+}
+
+// DeleteRenderbuffer deletes the given render buffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDeleteRenderbuffers.xhtml
+func DeleteRenderbuffer(v Renderbuffer) {
+	nullCtx.record("DeleteRenderbuffer", v)
+	delete(nullCtx.renderbuffers, v.Value)
+	if nullCtx.boundRenderbuffer == v {
+		nullCtx.boundRenderbuffer = Renderbuffer{}
+	}
+}
+
+// DeleteShader deletes shader s.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDeleteShader.xhtml
+func DeleteShader(s Shader) {
+	nullCtx.record("DeleteShader", s)
+	// attached shaders are only flagged for deletion
+	for _, program := range nullCtx.programs {
+		for _, attached := range program.shaders {
+			if attached == s {
+				if shader := nullCtx.shaders[s.Value]; shader != nil {
+					shader.deleted = true
+				}
+				return
+			}
+		}
+	}
+	delete(nullCtx.shaders, s.Value)
+}
+
+// DeleteTexture deletes the given texture object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDeleteTextures.xhtml
+func DeleteTexture(v Texture) {
+	nullCtx.record("DeleteTexture", v)
+	delete(nullCtx.textures, v.Value)
+	for _, units := range nullCtx.boundTextures {
+		for target, t := range units {
+			if t == v {
+				units[target] = Texture{}
+			}
+		}
+	}
+}
+
+// DepthFunc sets the function used for depth buffer comparisons.
+//
+// Valid fn values:
+//	NEVER
+//	LESS
+//	EQUAL
+//	LEQUAL
+//	GREATER
+//	NOTEQUAL
+//	GEQUAL
+//	ALWAYS
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDepthFunc.xhtml
+func DepthFunc(fn Enum) {
+	nullCtx.record("DepthFunc", fn)
+	nullCtx.depthFunc = fn
+}
+
+// DepthMask sets the depth buffer enabled for writing.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDepthMask.xhtml
+func DepthMask(flag bool) {
+	nullCtx.record("DepthMask", flag)
+	nullCtx.depthMask = flag
+}
+
+// DepthRangef sets the mapping from normalized device coordinates to
+// window coordinates.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDepthRangef.xhtml
+func DepthRangef(n, f float32) {
+	nullCtx.record("DepthRangef", n, f)
+	nullCtx.depthRange = [2]float32{n, f}
+}
+
+// DetachShader detaches the shader s from the program p.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDetachShader.xhtml
+func DetachShader(p Program, s Shader) {
+	nullCtx.record("DetachShader", p, s)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	for index, attached := range program.shaders {
+		if attached == s {
+			program.shaders = append(program.shaders[:index], program.shaders[index+1:]...)
+			break
+		}
+	}
+	if shader := nullCtx.shaders[s.Value]; shader != nil && shader.deleted {
+		delete(nullCtx.shaders, s.Value)
+	}
+}
+
+// Disable disables various GL capabilities.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDisable.xhtml
+func Disable(cap Enum) {
+	nullCtx.record("Disable", cap)
+	nullCtx.caps[cap] = false
+}
+
+// DisableVertexAttribArray disables a vertex attribute array.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDisableVertexAttribArray.xhtml
+func DisableVertexAttribArray(a Attrib) {
+	nullCtx.record("DisableVertexAttribArray", a)
+	if int(a.Value) < 0 || int(a.Value) >= len(nullCtx.attribs) {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	nullCtx.attribs[a.Value].enabled = false
+}
+
+func (this *nullContext) draw(call DrawCall) {
+	if this.currentProgram() == nil {
+		return
+	}
+	if call.Indexed && this.buffers[this.boundBuffers[ELEMENT_ARRAY_BUFFER].Value] == nil {
+		this.setError(INVALID_OPERATION)
+		return
+	}
+	if call.Indexed && call.Type == UNSIGNED_INT && !elementIndexUint {
+		this.setError(INVALID_ENUM)
+		return
+	}
+	call.Program = this.program
+	call.Framebuffer = this.boundFramebuffer
+	call.Viewport = this.viewport
+	this.draws = append(this.draws, call)
+
+	if rasterize != nil {
+		rasterize(call)
+	}
+}
+
+// DrawArrays renders geometric primitives from the bound data.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDrawArrays.xhtml
+func DrawArrays(mode Enum, first, count int) {
+	nullCtx.record("DrawArrays", mode, first, count)
+	nullCtx.draw(DrawCall{Mode: mode, First: first, Count: count})
+}
+
+// DrawElements renders primitives from a bound buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDrawElements.xhtml
+func DrawElements(mode Enum, count int, ty Enum, offset int) {
+	nullCtx.record("DrawElements", mode, count, ty, offset)
+	nullCtx.draw(DrawCall{Mode: mode, Count: count, Type: ty, Offset: offset, Indexed: true})
+}
+
+// DrawElementsInstanced renders instanceCount instances of primitives from a bound buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDrawElementsInstanced.xhtml
+func DrawElementsInstanced(mode Enum, count int, ty Enum, offset, instanceCount int) {
+	nullCtx.record("DrawElementsInstanced", mode, count, ty, offset, instanceCount)
+	if instanceCount < 0 {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	if instanceCount == 0 {
+		return
+	}
+	nullCtx.draw(DrawCall{Mode: mode, Count: count, Type: ty, Offset: offset, Indexed: true, Instances: instanceCount})
+}
+
+// Enable enables various GL capabilities.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glEnable.xhtml
+func Enable(cap Enum) {
+	nullCtx.record("Enable", cap)
+	nullCtx.caps[cap] = true
+}
+
+// EnableVertexAttribArray enables a vertex attribute array.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glEnableVertexAttribArray.xhtml
+func EnableVertexAttribArray(a Attrib) {
+	nullCtx.record("EnableVertexAttribArray", a)
+	if int(a.Value) < 0 || int(a.Value) >= len(nullCtx.attribs) {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	nullCtx.attribs[a.Value].enabled = true
+}
+
+// Finish blocks until the effects of all previously called GL
+// commands are complete.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glFinish.xhtml
+func Finish() {
+	nullCtx.record("Finish")
+}
+
+// Flush empties all buffers. It does not block.
+//
+// An OpenGL implementation may buffer network communication,
+// the command stream, or data inside the graphics accelerator.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glFlush.xhtml
+func Flush() {
+	nullCtx.record("Flush")
+}
+
+// FramebufferRenderbuffer attaches rb to the current frame buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glFramebufferRenderbuffer.xhtml
+func FramebufferRenderbuffer(target, attachment, rbTarget Enum, rb Renderbuffer) {
+	nullCtx.record("FramebufferRenderbuffer", target, attachment, rbTarget, rb)
+	framebuffer := nullCtx.framebuffers[nullCtx.boundFramebuffer.Value]
+	if framebuffer == nil {
+		nullCtx.setError(INVALID_OPERATION)
+		return
+	}
+	if !rb.Valid() {
+		delete(framebuffer.attachments, attachment)
+		return
+	}
+	framebuffer.attachments[attachment] = &nullAttachment{renderbuffer: rb}
+}
+
+// FramebufferTexture2D attaches the t to the current frame buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glFramebufferTexture2D.xhtml
+func FramebufferTexture2D(target, attachment, texTarget Enum, t Texture, level int) {
+	nullCtx.record("FramebufferTexture2D", target, attachment, texTarget, t, level)
+	framebuffer := nullCtx.framebuffers[nullCtx.boundFramebuffer.Value]
+	if framebuffer == nil {
+		nullCtx.setError(INVALID_OPERATION)
+		return
+	}
+	if !t.Valid() {
+		delete(framebuffer.attachments, attachment)
+		return
+	}
+	framebuffer.attachments[attachment] = &nullAttachment{texture: t, texTarget: texTarget, level: level}
+}
+
+// FrontFace defines which polygons are front-facing.
+//
+// Valid modes: CW, CCW.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glFrontFace.xhtml
+func FrontFace(mode Enum) {
+	nullCtx.record("FrontFace", mode)
+	nullCtx.frontFace = mode
+}
+
+// GenerateMipmap generates mipmaps for the current texture.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGenerateMipmap.xhtml
+func GenerateMipmap(target Enum) {
+	nullCtx.record("GenerateMipmap", target)
+	nullCtx.boundTexture(target)
+}
+
+// GetActiveAttrib returns details about an active attribute variable.
+// A value of 0 for index selects the first active attribute variable.
+// Permissible values for index range from 0 to the number of active
+// attribute variables minus 1.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetActiveAttrib.xhtml
+func GetActiveAttrib(p Program, index uint32) (name string, size int, ty Enum) {
+	nullCtx.record("GetActiveAttrib", p, index)
+	program := nullCtx.programs[p.Value]
+	if program == nil || int(index) >= len(program.attributes) {
+		nullCtx.setError(INVALID_VALUE)
+		return "", 0, 0
+	}
+	variable := program.attributes[index]
+	return variable.name, variable.size, variable.ty
+}
+
+// GetActiveUniform returns details about an active uniform variable.
+// A value of 0 for index selects the first active uniform variable.
+// Permissible values for index range from 0 to the number of active
+// uniform variables minus 1.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetActiveUniform.xhtml
+func GetActiveUniform(p Program, index uint32) (name string, size int, ty Enum) {
+	nullCtx.record("GetActiveUniform", p, index)
+	program := nullCtx.programs[p.Value]
+	if program == nil || int(index) >= len(program.uniforms) {
+		nullCtx.setError(INVALID_VALUE)
+		return "", 0, 0
+	}
+	variable := program.uniforms[index]
+	return variable.name, variable.size, variable.ty
+}
+
+// GetAttachedShaders returns the shader objects attached to program p.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetAttachedShaders.xhtml
+func GetAttachedShaders(p Program) []Shader {
+	nullCtx.record("GetAttachedShaders", p)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return nil
+	}
+	shaders := make([]Shader, len(program.shaders))
+	copy(shaders, program.shaders)
+	return shaders
+}
+
+// GetAttribLocation returns the location of an attribute variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetAttribLocation.xhtml
+func GetAttribLocation(p Program, name string) Attrib {
+	nullCtx.record("GetAttribLocation", p, name)
+	program := nullCtx.programs[p.Value]
+	if program == nil || !program.linked {
+		nullCtx.setError(INVALID_OPERATION)
+		return Attrib{Value: uint(math.MaxUint64)}
+	}
+	location, ok := program.attribLocs[name]
+	if !ok {
+		return Attrib{Value: uint(math.MaxUint64)}
+	}
+	return Attrib{Value: uint(location)}
+}
+
+// GetBooleanv returns the boolean values of parameter pname.
+//
+// Many boolean parameters can be queried more easily using IsEnabled.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGet.xhtml
+func GetBooleanv(dst []bool, pname Enum) {
+	nullCtx.record("GetBooleanv", pname)
+	switch pname {
+	case DEPTH_WRITEMASK:
+		dst[0] = nullCtx.depthMask
+	case COLOR_WRITEMASK:
+		copy(dst, nullCtx.colorMask[:])
+	default:
+		dst[0] = nullCtx.caps[pname]
+	}
+}
+
+// GetFloatv returns the float values of parameter pname.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGet.xhtml
+func GetFloatv(dst []float32, pname Enum) {
+	nullCtx.record("GetFloatv", pname)
+	switch pname {
+	case COLOR_CLEAR_VALUE:
+		copy(dst, nullCtx.clearColor[:])
+	case BLEND_COLOR:
+		copy(dst, nullCtx.blendColor[:])
+	case DEPTH_CLEAR_VALUE:
+		dst[0] = nullCtx.clearDepth
+	case DEPTH_RANGE:
+		copy(dst, nullCtx.depthRange[:])
+	case LINE_WIDTH:
+		dst[0] = nullCtx.lineWidth
+	case ALIASED_LINE_WIDTH_RANGE, ALIASED_POINT_SIZE_RANGE:
+		copy(dst, []float32{1, 1})
+	default:
+		values := make([]int32, len(dst))
+		GetIntegerv(pname, values)
+		for index, value := range values {
+			dst[index] = float32(value)
+		}
+	}
+}
+
+// GetIntegerv returns the int values of parameter pname.
+//
+// Single values may be queried more easily using GetInteger.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGet.xhtml
+func GetIntegerv(pname Enum, data []int32) {
+	nullCtx.record("GetIntegerv", pname)
+	switch pname {
+	case VIEWPORT:
+		for index, value := range nullCtx.viewport {
+			data[index] = int32(value)
+		}
+	case SCISSOR_BOX:
+		copy(data, nullCtx.scissor[:])
+	case MAX_VIEWPORT_DIMS:
+		copy(data, []int32{nullMaxTextureSize, nullMaxTextureSize})
+	default:
+		data[0] = int32(nullInteger(pname))
+	}
+}
+
+func nullInteger(pname Enum) int {
+	switch pname {
+	case MAX_TEXTURE_IMAGE_UNITS, MAX_COMBINED_TEXTURE_IMAGE_UNITS, MAX_VERTEX_TEXTURE_IMAGE_UNITS:
+		return nullMaxTextureUnits
+	case MAX_TEXTURE_SIZE, MAX_CUBE_MAP_TEXTURE_SIZE, MAX_RENDERBUFFER_SIZE:
+		return nullMaxTextureSize
+	case MAX_VERTEX_ATTRIBS:
+		return nullMaxVertexAttribs
+	case MAX_VERTEX_UNIFORM_VECTORS, MAX_FRAGMENT_UNIFORM_VECTORS:
+		return 1024
+	case MAX_VARYING_VECTORS:
+		return 16
+	case FRAMEBUFFER_BINDING:
+		return int(nullCtx.boundFramebuffer.Value)
+	case RENDERBUFFER_BINDING:
+		return int(nullCtx.boundRenderbuffer.Value)
+	case ARRAY_BUFFER_BINDING:
+		return int(nullCtx.boundBuffers[ARRAY_BUFFER].Value)
+	case ELEMENT_ARRAY_BUFFER_BINDING:
+		return int(nullCtx.boundBuffers[ELEMENT_ARRAY_BUFFER].Value)
+	case CURRENT_PROGRAM:
+		return int(nullCtx.program.Value)
+	case ACTIVE_TEXTURE:
+		return TEXTURE0 + nullCtx.activeTexture
+	case TEXTURE_BINDING_2D:
+		return int(nullCtx.boundTextures[nullCtx.activeTexture][TEXTURE_2D].Value)
+	case TEXTURE_BINDING_CUBE_MAP:
+		return int(nullCtx.boundTextures[nullCtx.activeTexture][TEXTURE_CUBE_MAP].Value)
+	case PACK_ALIGNMENT, UNPACK_ALIGNMENT:
+		return int(nullCtx.pixelStore[pname])
+	case DEPTH_FUNC:
+		return int(nullCtx.depthFunc)
+	case CULL_FACE_MODE:
+		return int(nullCtx.cullFace)
+	case FRONT_FACE:
+		return int(nullCtx.frontFace)
+	case BLEND_SRC_RGB:
+		return int(nullCtx.blendFunc[0])
+	case BLEND_DST_RGB:
+		return int(nullCtx.blendFunc[1])
+	case BLEND_SRC_ALPHA:
+		return int(nullCtx.blendFunc[2])
+	case BLEND_DST_ALPHA:
+		return int(nullCtx.blendFunc[3])
+	case BLEND_EQUATION_RGB:
+		return int(nullCtx.blendEquation[0])
+	case BLEND_EQUATION_ALPHA:
+		return int(nullCtx.blendEquation[1])
+	case STENCIL_FUNC:
+		return nullCtx.stencilFunc[0]
+	}
+	return 0
+}
+
+// GetInteger returns the int value of parameter pname.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGet.xhtml
+func GetInteger(pname Enum) int {
+	nullCtx.record("GetInteger", pname)
+	return nullInteger(pname)
+}
+
+// GetBufferParameteri returns a parameter for the active buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetBufferParameteriv.xhtml
+func GetBufferParameteri(target, pname Enum) int {
+	nullCtx.record("GetBufferParameteri", target, pname)
+	buffer := nullCtx.boundBuffer(target)
+	if buffer == nil {
+		return 0
+	}
+	switch pname {
+	case BUFFER_SIZE:
+		return len(buffer.data)
+	case BUFFER_USAGE:
+		return int(buffer.usage)
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return 0
+}
+
+// GetError returns the next error.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetError.xhtml
+func GetError() Enum {
+	nullCtx.record("GetError")
+	err := nullCtx.err
+	nullCtx.err = NO_ERROR
+	return err
+}
+
+// GetBoundFramebuffer returns the currently bound framebuffer.
+// Use this method instead of gl.GetInteger(gl.FRAMEBUFFER_BINDING) to
+// enable support on all platforms
+func GetBoundFramebuffer() Framebuffer {
+	nullCtx.record("GetBoundFramebuffer")
+	return nullCtx.boundFramebuffer
+}
+
+// GetFramebufferAttachmentParameteri returns attachment parameters
+// for the active framebuffer object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetFramebufferAttachmentParameteriv.xhtml
+func GetFramebufferAttachmentParameteri(target, attachment, pname Enum) int {
+	nullCtx.record("GetFramebufferAttachmentParameteri", target, attachment, pname)
+	framebuffer := nullCtx.framebuffers[nullCtx.boundFramebuffer.Value]
+	if framebuffer == nil {
+		nullCtx.setError(INVALID_OPERATION)
+		return 0
+	}
+	value := framebuffer.attachments[attachment]
+	if value == nil {
+		return 0
+	}
+	switch pname {
+	case FRAMEBUFFER_ATTACHMENT_OBJECT_TYPE:
+		if value.texture.Valid() {
+			return TEXTURE
+		}
+		return RENDERBUFFER
+	case FRAMEBUFFER_ATTACHMENT_OBJECT_NAME:
+		if value.texture.Valid() {
+			return int(value.texture.Value)
+		}
+		return int(value.renderbuffer.Value)
+	case FRAMEBUFFER_ATTACHMENT_TEXTURE_LEVEL:
+		return value.level
+	case FRAMEBUFFER_ATTACHMENT_TEXTURE_CUBE_MAP_FACE:
+		return int(value.texTarget)
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return 0
+}
+
+// GetProgrami returns a parameter value for a program.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetProgramiv.xhtml
+func GetProgrami(p Program, pname Enum) int {
+	nullCtx.record("GetProgrami", p, pname)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return 0
+	}
+	switch pname {
+	case DELETE_STATUS:
+		return nullBool(program.deleted)
+	case LINK_STATUS:
+		return nullBool(program.linked)
+	case VALIDATE_STATUS:
+		return nullBool(program.validated)
+	case INFO_LOG_LENGTH:
+		if program.infoLog == "" {
+			return 0
+		}
+		return len(program.infoLog) + 1
+	case ATTACHED_SHADERS:
+		return len(program.shaders)
+	case ACTIVE_ATTRIBUTES:
+		return len(program.attributes)
+	case ACTIVE_UNIFORMS:
+		return len(program.uniforms)
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return 0
+}
+
+func nullBool(value bool) int {
+	if value {
+		return TRUE
+	}
+	return FALSE
+}
+
+// GetProgramInfoLog returns the information log for a program.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetProgramInfoLog.xhtml
+func GetProgramInfoLog(p Program) string {
+	nullCtx.record("GetProgramInfoLog", p)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return ""
+	}
+	return program.infoLog
+}
+
+// GetRenderbufferParameteri returns a parameter value for a render buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetRenderbufferParameteriv.xhtml
+func GetRenderbufferParameteri(target, pname Enum) int {
+	nullCtx.record("GetRenderbufferParameteri", target, pname)
+	renderbuffer := nullCtx.renderbuffers[nullCtx.boundRenderbuffer.Value]
+	if renderbuffer == nil {
+		nullCtx.setError(INVALID_OPERATION)
+		return 0
+	}
+	switch pname {
+	case RENDERBUFFER_WIDTH:
+		return renderbuffer.width
+	case RENDERBUFFER_HEIGHT:
+		return renderbuffer.height
+	case RENDERBUFFER_INTERNAL_FORMAT:
+		return int(renderbuffer.format)
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return 0
+}
+
+// GetShaderi returns a parameter value for a shader.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetShaderiv.xhtml
+func GetShaderi(s Shader, pname Enum) int {
+	nullCtx.record("GetShaderi", s, pname)
+	shader := nullCtx.shaders[s.Value]
+	if shader == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return 0
+	}
+	switch pname {
+	case SHADER_TYPE:
+		return int(shader.ty)
+	case DELETE_STATUS:
+		return nullBool(shader.deleted)
+	case COMPILE_STATUS:
+		return nullBool(shader.compiled)
+	case INFO_LOG_LENGTH:
+		if shader.infoLog == "" {
+			return 0
+		}
+		return len(shader.infoLog) + 1
+	case SHADER_SOURCE_LENGTH:
+		if shader.source == "" {
+			return 0
+		}
+		return len(shader.source) + 1
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return 0
+}
+
+// GetShaderInfoLog returns the information log for a shader.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetShaderInfoLog.xhtml
+func GetShaderInfoLog(s Shader) string {
+	nullCtx.record("GetShaderInfoLog", s)
+	shader := nullCtx.shaders[s.Value]
+	if shader == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return ""
+	}
+	return shader.infoLog
+}
+
+// GetShaderPrecisionFormat returns range and precision limits for
+// shader types.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetShaderPrecisionFormat.xhtml
+func GetShaderPrecisionFormat(shadertype, precisiontype Enum) (rangeLow, rangeHigh, precision int) {
+	nullCtx.record("GetShaderPrecisionFormat", shadertype, precisiontype)
+	switch precisiontype {
+	case LOW_FLOAT, MEDIUM_FLOAT, HIGH_FLOAT:
+		return 127, 127, 23
+	}
+	return 31, 30, 0
+}
+
+// GetShaderSource returns source code of shader s.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetShaderSource.xhtml
+func GetShaderSource(s Shader) string {
+	nullCtx.record("GetShaderSource", s)
+	shader := nullCtx.shaders[s.Value]
+	if shader == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return ""
+	}
+	return shader.source
+}
+
+// GetString reports current GL state.
+//
+// Valid name values:
+//	EXTENSIONS
+//	RENDERER
+//	SHADING_LANGUAGE_VERSION
+//	VENDOR
+//	VERSION
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetString.xhtml
+func GetString(pname Enum) string {
+	nullCtx.record("GetString", pname)
+	switch pname {
+	case VENDOR:
+		return "fly3d"
+	case RENDERER:
+		return "null"
+	case VERSION:
+		return "OpenGL ES 2.0 null"
+	case SHADING_LANGUAGE_VERSION:
+		return "OpenGL ES GLSL ES 1.00"
+	case EXTENSIONS:
+		if elementIndexUint {
+			return "GL_OES_standard_derivatives GL_OES_element_index_uint"
+		}
+		return "GL_OES_standard_derivatives"
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return ""
+}
+
+// GetTexParameterfv returns the float values of a texture parameter.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetTexParameter.xhtml
+func GetTexParameterfv(dst []float32, target, pname Enum) {
+	nullCtx.record("GetTexParameterfv", target, pname)
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	dst[0] = texture.params[pname]
+}
+
+// GetTexParameteriv returns the int values of a texture parameter.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetTexParameter.xhtml
+func GetTexParameteriv(dst []int32, target, pname Enum) {
+	nullCtx.record("GetTexParameteriv", target, pname)
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	dst[0] = int32(texture.params[pname])
+}
+
+// GetUniformfv returns the float values of a uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetUniform.xhtml
+func GetUniformfv(dst []float32, src Uniform, p Program) {
+	nullCtx.record("GetUniformfv", src, p)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	copy(dst, program.uniformValues[src.Value])
+}
+
+// GetUniformiv returns the float values of a uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetUniform.xhtml
+func GetUniformiv(dst []int32, src Uniform, p Program) {
+	nullCtx.record("GetUniformiv", src, p)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	for index, value := range program.uniformValues[src.Value] {
+		if index < len(dst) {
+			dst[index] = int32(value)
+		}
+	}
+}
+
+// GetUniformLocation returns the location of a uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetUniformLocation.xhtml
+func GetUniformLocation(p Program, name string) Uniform {
+	nullCtx.record("GetUniformLocation", p, name)
+	program := nullCtx.programs[p.Value]
+	if program == nil || !program.linked {
+		nullCtx.setError(INVALID_OPERATION)
+		return Uniform{Value: -1}
+	}
+	location, ok := program.uniformLocs[name]
+	if !ok {
+		return Uniform{Value: -1}
+	}
+	return Uniform{Value: location}
+}
+
+// GetVertexAttribf reads the float value of a vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetVertexAttrib.xhtml
+func GetVertexAttribf(src Attrib, pname Enum) float32 {
+	values := make([]float32, 4)
+	GetVertexAttribfv(values, src, pname)
+	return values[0]
+}
+
+// GetVertexAttribfv reads float values of a vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetVertexAttrib.xhtml
+func GetVertexAttribfv(dst []float32, src Attrib, pname Enum) {
+	nullCtx.record("GetVertexAttribfv", src, pname)
+	if pname == CURRENT_VERTEX_ATTRIB {
+		if int(src.Value) < 0 || int(src.Value) >= len(nullCtx.attribs) {
+			nullCtx.setError(INVALID_VALUE)
+			return
+		}
+		copy(dst, nullCtx.attribs[src.Value].value[:])
+		return
+	}
+	dst[0] = float32(nullVertexAttribi(src, pname))
+}
+
+// GetVertexAttribi reads the int value of a vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetVertexAttrib.xhtml
+func GetVertexAttribi(src Attrib, pname Enum) int32 {
+	nullCtx.record("GetVertexAttribi", src, pname)
+	return nullVertexAttribi(src, pname)
+}
+
+// GetVertexAttribiv reads int values of a vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glGetVertexAttrib.xhtml
+func GetVertexAttribiv(dst []int32, src Attrib, pname Enum) {
+	nullCtx.record("GetVertexAttribiv", src, pname)
+	dst[0] = nullVertexAttribi(src, pname)
+}
+
+func nullVertexAttribi(src Attrib, pname Enum) int32 {
+	if int(src.Value) < 0 || int(src.Value) >= len(nullCtx.attribs) {
+		nullCtx.setError(INVALID_VALUE)
+		return 0
+	}
+	attrib := nullCtx.attribs[src.Value]
+	switch pname {
+	case VERTEX_ATTRIB_ARRAY_ENABLED:
+		return int32(nullBool(attrib.enabled))
+	case VERTEX_ATTRIB_ARRAY_SIZE:
+		return int32(attrib.size)
+	case VERTEX_ATTRIB_ARRAY_STRIDE:
+		return int32(attrib.stride)
+	case VERTEX_ATTRIB_ARRAY_TYPE:
+		return int32(attrib.ty)
+	case VERTEX_ATTRIB_ARRAY_NORMALIZED:
+		return int32(nullBool(attrib.normalized))
+	case VERTEX_ATTRIB_ARRAY_BUFFER_BINDING:
+		return int32(attrib.buffer.Value)
+	}
+	nullCtx.setError(INVALID_ENUM)
+	return 0
+}
+
+// Hint sets implementation-specific modes.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glHint.xhtml
+func Hint(target, mode Enum) {
+	nullCtx.record("Hint", target, mode)
+	nullCtx.hints[target] = mode
+}
+
+// IsBuffer reports if b is a valid buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsBuffer.xhtml
+func IsBuffer(b Buffer) bool {
+	nullCtx.record("IsBuffer", b)
+	return nullCtx.buffers[b.Value] != nil
+}
+
+// IsEnabled reports if cap is an enabled capability.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsEnabled.xhtml
+func IsEnabled(cap Enum) bool {
+	nullCtx.record("IsEnabled", cap)
+	return nullCtx.caps[cap]
+}
+
+// IsFramebuffer reports if fb is a valid frame buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsFramebuffer.xhtml
+func IsFramebuffer(fb Framebuffer) bool {
+	nullCtx.record("IsFramebuffer", fb)
+	return nullCtx.framebuffers[fb.Value] != nil
+}
+
+// IsProgram reports if p is a valid program object.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsProgram.xhtml
+func IsProgram(p Program) bool {
+	nullCtx.record("IsProgram", p)
+	return nullCtx.programs[p.Value] != nil
+}
+
+// IsRenderbuffer reports if rb is a valid render buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsRenderbuffer.xhtml
+func IsRenderbuffer(rb Renderbuffer) bool {
+	nullCtx.record("IsRenderbuffer", rb)
+	return nullCtx.renderbuffers[rb.Value] != nil
+}
+
+// IsShader reports if s is valid shader.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsShader.xhtml
+func IsShader(s Shader) bool {
+	nullCtx.record("IsShader", s)
+	return nullCtx.shaders[s.Value] != nil
+}
+
+// IsTexture reports if t is a valid texture.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glIsTexture.xhtml
+func IsTexture(t Texture) bool {
+	nullCtx.record("IsTexture", t)
+	return nullCtx.textures[t.Value] != nil
+}
+
+// LineWidth specifies the width of lines.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glLineWidth.xhtml
+func LineWidth(width float32) {
+	nullCtx.record("LineWidth", width)
+	nullCtx.lineWidth = width
+}
+
+// LinkProgram links the specified program.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glLinkProgram.xhtml
+func LinkProgram(p Program) {
+	nullCtx.record("LinkProgram", p)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+
+	program.linked = false
+	program.infoLog = ""
+	program.sources = map[Enum]string{}
+	program.attributes = nil
+	program.uniforms = nil
+	program.attribLocs = map[string]int{}
+	program.uniformLocs = map[string]int32{}
+	program.uniformValues = map[int32][]float32{}
+	program.defines = map[string]string{}
+
+	uniformSeen := map[string]bool{}
+	for _, s := range program.shaders {
+		shader := nullCtx.shaders[s.Value]
+		if shader == nil || !shader.compiled {
+			program.infoLog = "ERROR: attached shader is not compiled"
+			return
+		}
+		program.sources[shader.ty] = shader.source
+		for name, value := range shader.defines {
+			program.defines[name] = value
+		}
+		if shader.ty == VERTEX_SHADER {
+			program.attributes = append(program.attributes, shader.attributes...)
+		}
+		for _, uniform := range shader.uniforms {
+			if !uniformSeen[uniform.name] {
+				uniformSeen[uniform.name] = true
+				program.uniforms = append(program.uniforms, uniform)
+			}
+		}
+	}
+	if program.sources[VERTEX_SHADER] == "" || program.sources[FRAGMENT_SHADER] == "" {
+		program.infoLog = "ERROR: missing vertex or fragment shader"
+		return
+	}
+
+	// explicit bindings first, then the remaining attributes in declaration order
+	used := map[int]bool{}
+	for _, attribute := range program.attributes {
+		if location, ok := program.boundAttribs[attribute.name]; ok {
+			program.attribLocs[attribute.name] = location
+			used[location] = true
+		}
+	}
+	next := 0
+	for _, attribute := range program.attributes {
+		if _, ok := program.attribLocs[attribute.name]; ok {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		if next >= nullMaxVertexAttribs {
+			program.infoLog = "ERROR: too many attributes"
+			return
+		}
+		program.attribLocs[attribute.name] = next
+		used[next] = true
+	}
+
+	// arrays get one location per element
+	location := int32(0)
+	for _, uniform := range program.uniforms {
+		program.uniformLocs[uniform.name] = location
+		if uniform.size > 1 {
+			for index := 0; index < uniform.size; index++ {
+				program.uniformLocs[fmt.Sprintf("%s[%d]", uniform.name, index)] = location + int32(index)
+			}
+		}
+		location += int32(uniform.size)
+	}
+
+	program.linked = true
+}
+
+// PixelStorei sets pixel storage parameters.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glPixelStorei.xhtml
+func PixelStorei(pname Enum, param int32) {
+	nullCtx.record("PixelStorei", pname, param)
+	nullCtx.pixelStore[pname] = param
+}
+
+// PolygonOffset sets the scaling factors for depth offsets.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glPolygonOffset.xhtml
+func PolygonOffset(factor, units float32) {
+	nullCtx.record("PolygonOffset", factor, units)
+	nullCtx.polygonOffset = [2]float32{factor, units}
+}
+
+// readPixels copies RGBA pixels of the bound framebuffer, pixels never rendered read as black
+func readPixels(dst []byte, x, y, width, height int) {
+	for index := range dst {
+		dst[index] = 0
+	}
+	image := nullCtx.colorImage()
+	if image == nil || image.data == nil {
+		return
+	}
+	for row := 0; row < height; row++ {
+		srcY := y + row
+		if srcY < 0 || srcY >= image.height {
+			continue
+		}
+		for col := 0; col < width; col++ {
+			srcX := x + col
+			if srcX < 0 || srcX >= image.width {
+				continue
+			}
+			src := (srcY*image.width + srcX) * 4
+			dstPos := (row*width + col) * 4
+			if dstPos+4 <= len(dst) && src+4 <= len(image.data) {
+				copy(dst[dstPos:dstPos+4], image.data[src:src+4])
+			}
+		}
+	}
+}
+
+// writeSubImage copies RGBA pixels into a region of image
+func writeSubImage(image *nullImage, x, y, width, height int, data []byte) {
+	if image == nil {
+		nullCtx.setError(INVALID_OPERATION)
+		return
+	}
+	if image.data == nil {
+		image.data = make([]byte, image.width*image.height*4)
+	}
+	for row := 0; row < height; row++ {
+		dstY := y + row
+		if dstY < 0 || dstY >= image.height {
+			continue
+		}
+		for col := 0; col < width; col++ {
+			dstX := x + col
+			if dstX < 0 || dstX >= image.width {
+				continue
+			}
+			src := (row*width + col) * 4
+			dst := (dstY*image.width + dstX) * 4
+			if src+4 <= len(data) && dst+4 <= len(image.data) {
+				copy(image.data[dst:dst+4], data[src:src+4])
+			}
+		}
+	}
+}
+
+// ReadPixels returns pixel data from a buffer.
+//
+// In GLES 3, the source buffer is controlled with ReadBuffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glReadPixels.xhtml
+func ReadPixels(dst []byte, x, y, width, height int, format, ty Enum) {
+	nullCtx.record("ReadPixels", x, y, width, height, format, ty)
+	readPixels(dst, x, y, width, height)
+}
+
+// ReleaseShaderCompiler frees resources allocated by the shader compiler.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glReleaseShaderCompiler.xhtml
+func ReleaseShaderCompiler() {
+	nullCtx.record("ReleaseShaderCompiler")
+}
+
+// RenderbufferStorage establishes the data storage, format, and
+// dimensions of a renderbuffer object's image.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glRenderbufferStorage.xhtml
+func RenderbufferStorage(target, internalFormat Enum, width, height int) {
+	nullCtx.record("RenderbufferStorage", target, internalFormat, width, height)
+	renderbuffer := nullCtx.renderbuffers[nullCtx.boundRenderbuffer.Value]
+	if renderbuffer == nil {
+		nullCtx.setError(INVALID_OPERATION)
+		return
+	}
+	renderbuffer.format = internalFormat
+	renderbuffer.width = width
+	renderbuffer.height = height
+}
+
+// SampleCoverage sets multisample coverage parameters.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glSampleCoverage.xhtml
+func SampleCoverage(value float32, invert bool) {
+	nullCtx.record("SampleCoverage", value, invert)
+}
+
+// Scissor defines the scissor box rectangle, in window coordinates.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glScissor.xhtml
+func Scissor(x, y, width, height int32) {
+	nullCtx.record("Scissor", x, y, width, height)
+	nullCtx.scissor = [4]int32{x, y, width, height}
+}
+
+// ShaderSource sets the source code of s to the given source code.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glShaderSource.xhtml
+func ShaderSource(s Shader, src string) {
+	nullCtx.record("ShaderSource", s, len(src))
+	shader := nullCtx.shaders[s.Value]
+	if shader == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	shader.source = src
+}
+
+// StencilFunc sets the front and back stencil test reference value.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glStencilFunc.xhtml
+func StencilFunc(fn Enum, ref int, mask uint32) {
+	nullCtx.record("StencilFunc", fn, ref, mask)
+	nullCtx.stencilFunc = [3]int{int(fn), ref, int(mask)}
+}
+
+// StencilFunc sets the front or back stencil test reference value.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glStencilFuncSeparate.xhtml
+func StencilFuncSeparate(face, fn Enum, ref int, mask uint32) {
+	nullCtx.record("StencilFuncSeparate", face, fn, ref, mask)
+	nullCtx.stencilFunc = [3]int{int(fn), ref, int(mask)}
+}
+
+// StencilMask controls the writing of bits in the stencil planes.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glStencilMask.xhtml
+func StencilMask(mask uint32) {
+	nullCtx.record("StencilMask", mask)
+	nullCtx.stencilMask = mask
+}
+
+// StencilMaskSeparate controls the writing of bits in the stencil planes.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glStencilMaskSeparate.xhtml
+func StencilMaskSeparate(face Enum, mask uint32) {
+	nullCtx.record("StencilMaskSeparate", face, mask)
+	nullCtx.stencilMask = mask
+}
+
+// StencilOp sets front and back stencil test actions.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glStencilOp.xhtml
+func StencilOp(fail, zfail, zpass Enum) {
+	nullCtx.record("StencilOp", fail, zfail, zpass)
+	nullCtx.stencilOp = [3]Enum{fail, zfail, zpass}
+}
+
+// StencilOpSeparate sets front or back stencil tests.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glStencilOpSeparate.xhtml
+func StencilOpSeparate(face, sfail, dpfail, dppass Enum) {
+	nullCtx.record("StencilOpSeparate", face, sfail, dpfail, dppass)
+	nullCtx.stencilOp = [3]Enum{sfail, dpfail, dppass}
+}
+
+// TexImage2D writes a 2D texture image.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glTexImage2D.xhtml
+func TexImage2D(target Enum, level int, width, height int, format Enum, ty Enum, data []byte) {
+	nullCtx.record("TexImage2D", target, level, width, height, format, ty, len(data))
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	if width < 0 || height < 0 || width > nullMaxTextureSize || height > nullMaxTextureSize {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	image := &nullImage{width: width, height: height, format: format, ty: ty}
+	if data != nil {
+		image.data = make([]byte, len(data))
+		copy(image.data, data)
+	} else if format == RGBA && ty == UNSIGNED_BYTE {
+		image.data = make([]byte, width*height*4)
+	}
+	texture.setImage(target, level, image)
+}
+
+// TexSubImage2D writes a subregion of a 2D texture image.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glTexSubImage2D.xhtml
+func TexSubImage2D(target Enum, level int, x, y, width, height int, format, ty Enum, data []byte) {
+	nullCtx.record("TexSubImage2D", target, level, x, y, width, height, format, ty, len(data))
+	texture := nullCtx.boundTexture(target)
+	if texture == nil {
+		return
+	}
+	writeSubImage(texture.image(target, level), x, y, width, height, data)
+}
+
+// TexParameterf sets a float texture parameter.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glTexParameter.xhtml
+func TexParameterf(target, pname Enum, param float32) {
+	nullCtx.record("TexParameterf", target, pname, param)
+	if texture := nullCtx.boundTexture(target); texture != nil {
+		texture.params[pname] = param
+	}
+}
+
+// TexParameterfv sets a float texture parameter array.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glTexParameter.xhtml
+func TexParameterfv(target, pname Enum, params []float32) {
+	nullCtx.record("TexParameterfv", target, pname, copyFloats(params))
+	if texture := nullCtx.boundTexture(target); texture != nil && len(params) > 0 {
+		texture.params[pname] = params[0]
+	}
+}
+
+// TexParameteri sets an integer texture parameter.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glTexParameter.xhtml
+func TexParameteri(target, pname Enum, param int) {
+	nullCtx.record("TexParameteri", target, pname, param)
+	if texture := nullCtx.boundTexture(target); texture != nil {
+		texture.params[pname] = float32(param)
+	}
+}
+
+// TexParameteriv sets an integer texture parameter array.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glTexParameter.xhtml
+func TexParameteriv(target, pname Enum, params []int32) {
+	nullCtx.record("TexParameteriv", target, pname, params)
+	if texture := nullCtx.boundTexture(target); texture != nil && len(params) > 0 {
+		texture.params[pname] = float32(params[0])
+	}
+}
+
+// Uniform1f writes a float uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform1f(dst Uniform, v float32) {
+	nullCtx.setUniform("Uniform1f", dst, []float32{v})
+}
+
+// Uniform1fv writes a [len(src)]float uniform array.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform1fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("Uniform1fv", dst, copyFloats(src))
+}
+
+// Uniform1i writes an int uniform variable.
+//
+// Uniform1i and Uniform1iv are the only two functions that may be used
+// to load uniform variables defined as sampler types. Loading samplers
+// with any other function will result in a INVALID_OPERATION error.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform1i(dst Uniform, v int) {
+	nullCtx.setUniform("Uniform1i", dst, intsToFloats(int32(v)))
+}
+
+// Uniform1iv writes a int uniform array of len(src) elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform1iv(dst Uniform, src []int32) {
+	nullCtx.setUniform("Uniform1iv", dst, intsToFloats(src...))
+}
+
+// Uniform2f writes a vec2 uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform2f(dst Uniform, v0, v1 float32) {
+	nullCtx.setUniform("Uniform2f", dst, []float32{v0, v1})
+}
+
+// Uniform2fv writes a vec2 uniform array of len(src)/2 elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform2fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("Uniform2fv", dst, copyFloats(src))
+}
+
+// Uniform2i writes an ivec2 uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform2i(dst Uniform, v0, v1 int) {
+	nullCtx.setUniform("Uniform2i", dst, intsToFloats(int32(v0), int32(v1)))
+}
+
+// Uniform2iv writes an ivec2 uniform array of len(src)/2 elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform2iv(dst Uniform, src []int32) {
+	nullCtx.setUniform("Uniform2iv", dst, intsToFloats(src...))
+}
+
+// Uniform3f writes a vec3 uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform3f(dst Uniform, v0, v1, v2 float32) {
+	nullCtx.setUniform("Uniform3f", dst, []float32{v0, v1, v2})
+}
+
+// Uniform3fv writes a vec3 uniform array of len(src)/3 elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform3fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("Uniform3fv", dst, copyFloats(src))
+}
+
+// Uniform3i writes an ivec3 uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform3i(dst Uniform, v0, v1, v2 int32) {
+	nullCtx.setUniform("Uniform3i", dst, intsToFloats(v0, v1, v2))
+}
+
+// Uniform3iv writes an ivec3 uniform array of len(src)/3 elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform3iv(dst Uniform, src []int32) {
+	nullCtx.setUniform("Uniform3iv", dst, intsToFloats(src...))
+}
+
+// Uniform4f writes a vec4 uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform4f(dst Uniform, v0, v1, v2, v3 float32) {
+	nullCtx.setUniform("Uniform4f", dst, []float32{v0, v1, v2, v3})
+}
+
+// Uniform4fv writes a vec4 uniform array of len(src)/4 elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform4fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("Uniform4fv", dst, copyFloats(src))
+}
+
+// Uniform4i writes an ivec4 uniform variable.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform4i(dst Uniform, v0, v1, v2, v3 int32) {
+	nullCtx.setUniform("Uniform4i", dst, intsToFloats(v0, v1, v2, v3))
+}
+
+// Uniform4i writes an ivec4 uniform array of len(src)/4 elements.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func Uniform4iv(dst Uniform, src []int32) {
+	nullCtx.setUniform("Uniform4iv", dst, intsToFloats(src...))
+}
+
+// UniformMatrix2fv writes 2x2 matrices. Each matrix uses four
+// float32 values, so the number of matrices written is len(src)/4.
+//
+// Each matrix must be supplied in column major order.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func UniformMatrix2fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("UniformMatrix2fv", dst, copyFloats(src))
+}
+
+// UniformMatrix3fv writes 3x3 matrices. Each matrix uses nine
+// float32 values, so the number of matrices written is len(src)/9.
+//
+// Each matrix must be supplied in column major order.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func UniformMatrix3fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("UniformMatrix3fv", dst, copyFloats(src))
+}
+
+// UniformMatrix4fv writes 4x4 matrices. Each matrix uses 16
+// float32 values, so the number of matrices written is len(src)/16.
+//
+// Each matrix must be supplied in column major order.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUniform.xhtml
+func UniformMatrix4fv(dst Uniform, src []float32) {
+	nullCtx.setUniform("UniformMatrix4fv", dst, copyFloats(src))
+}
+
+// UseProgram sets the active program.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glUseProgram.xhtml
+func UseProgram(p Program) {
+	nullCtx.record("UseProgram", p)
+	if p.Valid() {
+		program := nullCtx.programs[p.Value]
+		if program == nil || !program.linked {
+			nullCtx.setError(INVALID_OPERATION)
+			return
+		}
+	}
+	if previous := nullCtx.programs[nullCtx.program.Value]; previous != nil && previous.deleted && nullCtx.program != p {
+		delete(nullCtx.programs, nullCtx.program.Value)
+	}
+	nullCtx.program = p
+}
+
+// ValidateProgram checks to see whether the executables contained in
+// program can execute given the current OpenGL state.
+//
+// Typically only used for debugging.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glValidateProgram.xhtml
+func ValidateProgram(p Program) {
+	nullCtx.record("ValidateProgram", p)
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	program.validated = program.linked
+}
+
+// VertexAttrib1f writes a float vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib1f(dst Attrib, x float32) {
+	nullCtx.record("VertexAttrib1f", dst, x)
+	nullCtx.setVertexAttrib(dst, x)
+}
+
+// VertexAttrib1fv writes a float vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib1fv(dst Attrib, src []float32) {
+	nullCtx.record("VertexAttrib1fv", dst, copyFloats(src))
+	nullCtx.setVertexAttrib(dst, src[:1]...)
+}
+
+// VertexAttrib2f writes a vec2 vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib2f(dst Attrib, x, y float32) {
+	nullCtx.record("VertexAttrib2f", dst, x, y)
+	nullCtx.setVertexAttrib(dst, x, y)
+}
+
+// VertexAttrib2fv writes a vec2 vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib2fv(dst Attrib, src []float32) {
+	nullCtx.record("VertexAttrib2fv", dst, copyFloats(src))
+	nullCtx.setVertexAttrib(dst, src[:2]...)
+}
+
+// VertexAttrib3f writes a vec3 vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib3f(dst Attrib, x, y, z float32) {
+	nullCtx.record("VertexAttrib3f", dst, x, y, z)
+	nullCtx.setVertexAttrib(dst, x, y, z)
+}
+
+// VertexAttrib3fv writes a vec3 vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib3fv(dst Attrib, src []float32) {
+	nullCtx.record("VertexAttrib3fv", dst, copyFloats(src))
+	nullCtx.setVertexAttrib(dst, src[:3]...)
+}
+
+// VertexAttrib4f writes a vec4 vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib4f(dst Attrib, x, y, z, w float32) {
+	nullCtx.record("VertexAttrib4f", dst, x, y, z, w)
+	nullCtx.setVertexAttrib(dst, x, y, z, w)
+}
+
+// VertexAttrib4fv writes a vec4 vertex attribute.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttrib.xhtml
+func VertexAttrib4fv(dst Attrib, src []float32) {
+	nullCtx.record("VertexAttrib4fv", dst, copyFloats(src))
+	nullCtx.setVertexAttrib(dst, src[:4]...)
+}
+
+// InstancingSupported reports if DrawElementsInstanced and VertexAttribDivisor are available.
+func InstancingSupported() bool {
+	return true
+}
+
+// StandardDerivativesSupported reports if fragment shaders can use dFdx, dFdy
+// and fwidth. The software rasterizer shades pixels one at a time and has
+// none.
+func StandardDerivativesSupported() bool {
+	return false
+}
+
+// TextureLodSupported reports if fragment shaders can pick the mip level with
+// textureCubeLodEXT. The software rasterizer only reads the first level.
+func TextureLodSupported() bool {
+	return false
+}
+
+// ElementIndexUintSupported reports if DrawElements accepts UNSIGNED_INT
+// indices, see SetElementIndexUintSupported.
+func ElementIndexUintSupported() bool {
+	return elementIndexUint
+}
+
+// VertexAttribDivisor sets how many instances are drawn before the attribute
+// dst advances, 0 advances it for every vertex.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttribDivisor.xhtml
+func VertexAttribDivisor(dst Attrib, divisor int) {
+	nullCtx.record("VertexAttribDivisor", dst, divisor)
+	if int(dst.Value) < 0 || int(dst.Value) >= len(nullCtx.attribs) || divisor < 0 {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	nullCtx.attribs[dst.Value].divisor = divisor
+}
+
+// VertexAttribPointer uses a bound buffer to define vertex attribute data.
+//
+// Direct use of VertexAttribPointer to load data into OpenGL is not
+// supported via the Go bindings. Instead, use BindBuffer with an
+// ARRAY_BUFFER and then fill it using BufferData.
+//
+// The size argument specifies the number of components per attribute,
+// between 1-4. The stride argument specifies the byte offset between
+// consecutive vertex attributes.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttribPointer.xhtml
+func VertexAttribPointer(dst Attrib, size int, ty Enum, normalized bool, stride, offset int) {
+	nullCtx.record("VertexAttribPointer", dst, size, ty, normalized, stride, offset)
+	if int(dst.Value) < 0 || int(dst.Value) >= len(nullCtx.attribs) || size < 1 || size > 4 {
+		nullCtx.setError(INVALID_VALUE)
+		return
+	}
+	attrib := &nullCtx.attribs[dst.Value]
+	attrib.buffer = nullCtx.boundBuffers[ARRAY_BUFFER]
+	attrib.size = size
+	attrib.ty = ty
+	attrib.normalized = normalized
+	attrib.stride = stride
+	attrib.offset = offset
+}
+
+// Viewport sets the viewport, an affine transformation that
+// normalizes device coordinates to window coordinates.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glViewport.xhtml
+func Viewport(x, y, width, height int) {
+	nullCtx.record("Viewport", x, y, width, height)
+	nullCtx.viewport = [4]int{x, y, width, height}
+	if !nullCtx.boundFramebuffer.Valid() {
+		nullCtx.growDefaultFramebuffer(x+width, y+height)
+	}
+}
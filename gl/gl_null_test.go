@@ -0,0 +1,206 @@
+// +build glnull glsoft
+
+package gl
+
+import (
+	"strings"
+	"testing"
+)
+
+const testVertexShader = `
+attribute vec3 position;
+uniform mat4 world;
+void main(void) {
+	gl_Position = world * vec4(position, 1.0);
+}
+`
+
+const testFragmentShader = `
+precision mediump float;
+uniform vec4 color;
+void main(void) {
+	gl_FragColor = color;
+}
+`
+
+func newTestProgram(t *testing.T) Program {
+	program := CreateProgram()
+	for ty, source := range map[Enum]string{VERTEX_SHADER: testVertexShader, FRAGMENT_SHADER: testFragmentShader} {
+		shader := CreateShader(ty)
+		ShaderSource(shader, source)
+		CompileShader(shader)
+		if GetShaderi(shader, COMPILE_STATUS) != TRUE {
+			t.Fatalf("compile: %s", GetShaderInfoLog(shader))
+		}
+		AttachShader(program, shader)
+	}
+	LinkProgram(program)
+	if GetProgrami(program, LINK_STATUS) != TRUE {
+		t.Fatalf("link: %s", GetProgramInfoLog(program))
+	}
+	return program
+}
+
+func TestRecordCalls(t *testing.T) {
+	ResetContext()
+
+	ClearColor(0, 0, 0, 1)
+	Enable(DEPTH_TEST)
+	Enable(CULL_FACE)
+	Viewport(0, 0, 32, 16)
+
+	calls := Calls()
+	if len(calls) != 4 {
+		t.Fatalf("recorded %d calls, want 4: %v", len(calls), calls)
+	}
+	if calls[0].Name != "ClearColor" || len(calls[0].Args) != 4 {
+		t.Errorf("first call is %v, want ClearColor(0, 0, 0, 1)", calls[0])
+	}
+	if calls[3].String() != "Viewport(0, 0, 32, 16)" {
+		t.Errorf("last call is %v, want Viewport(0, 0, 32, 16)", calls[3])
+	}
+	if count := CountCalls("Enable"); count != 2 {
+		t.Errorf("CountCalls(Enable) = %d, want 2", count)
+	}
+	if count := CountCalls("Disable"); count != 0 {
+		t.Errorf("CountCalls(Disable) = %d, want 0", count)
+	}
+
+	ResetCalls()
+	if len(Calls()) != 0 {
+		t.Errorf("ResetCalls kept %d calls", len(Calls()))
+	}
+	if !IsEnabled(DEPTH_TEST) {
+		t.Errorf("ResetCalls dropped the gl state")
+	}
+}
+
+func TestBufferContents(t *testing.T) {
+	ResetContext()
+
+	buffer := CreateBuffer()
+	BindBuffer(ARRAY_BUFFER, buffer)
+	BufferData(ARRAY_BUFFER, []byte{1, 2, 3, 4, 5, 6}, STATIC_DRAW)
+	if data := BufferContents(buffer); string(data) != string([]byte{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("BufferData stored %v", data)
+	}
+
+	BufferSubData(ARRAY_BUFFER, 2, []byte{9, 9})
+	if data := BufferContents(buffer); string(data) != string([]byte{1, 2, 9, 9, 5, 6}) {
+		t.Errorf("BufferSubData stored %v", data)
+	}
+
+	BufferSubData(ARRAY_BUFFER, 5, []byte{7, 7})
+	if err := GetError(); err != INVALID_VALUE {
+		t.Errorf("BufferSubData past the end: error %v, want INVALID_VALUE", err)
+	}
+	if data := BufferContents(buffer); data[5] != 6 {
+		t.Errorf("BufferSubData past the end wrote %v", data)
+	}
+
+	BufferInit(ARRAY_BUFFER, 3, DYNAMIC_DRAW)
+	if data := BufferContents(buffer); string(data) != string([]byte{0, 0, 0}) {
+		t.Errorf("BufferInit stored %v", data)
+	}
+
+	if data := BufferContents(Buffer{Value: 1000}); data != nil {
+		t.Errorf("unknown buffer has contents %v", data)
+	}
+}
+
+func TestUniformValue(t *testing.T) {
+	ResetContext()
+
+	program := newTestProgram(t)
+	UseProgram(program)
+
+	Uniform4f(GetUniformLocation(program, "color"), 1, 0.5, 0.25, 1)
+	if value := UniformValue(program, "color"); len(value) != 4 || value[1] != 0.5 || value[2] != 0.25 {
+		t.Errorf("color = %v, want [1 0.5 0.25 1]", value)
+	}
+
+	world := make([]float32, 16)
+	world[0], world[5], world[10], world[15] = 2, 2, 2, 1
+	UniformMatrix4fv(GetUniformLocation(program, "world"), world)
+	world[0] = 3
+	if value := UniformValue(program, "world"); len(value) != 16 || value[0] != 2 {
+		t.Errorf("world = %v, want the matrix at the time of the call", value)
+	}
+
+	if value := UniformValue(program, "missing"); value != nil {
+		t.Errorf("missing uniform = %v, want nil", value)
+	}
+	Uniform1f(GetUniformLocation(program, "missing"), 1)
+	if err := GetError(); err != NO_ERROR {
+		t.Errorf("setting an inactive uniform: error %v", err)
+	}
+}
+
+func TestDrawCalls(t *testing.T) {
+	ResetContext()
+
+	DrawArrays(TRIANGLES, 0, 3)
+	if len(DrawCalls()) != 0 {
+		t.Errorf("draw without a program was recorded")
+	}
+
+	program := newTestProgram(t)
+	UseProgram(program)
+	Viewport(0, 0, 8, 8)
+
+	DrawElements(TRIANGLES, 6, UNSIGNED_SHORT, 0)
+	if err := GetError(); err != INVALID_OPERATION {
+		t.Errorf("DrawElements without an index buffer: error %v, want INVALID_OPERATION", err)
+	}
+
+	indices := CreateBuffer()
+	BindBuffer(ELEMENT_ARRAY_BUFFER, indices)
+	BufferData(ELEMENT_ARRAY_BUFFER, make([]byte, 12), STATIC_DRAW)
+
+	DrawArrays(LINES, 2, 4)
+	DrawElements(TRIANGLES, 6, UNSIGNED_SHORT, 4)
+
+	draws := DrawCalls()
+	if len(draws) != 2 {
+		t.Fatalf("recorded %d draws, want 2", len(draws))
+	}
+	if draw := draws[0]; draw.Indexed || draw.Mode != LINES || draw.First != 2 || draw.Count != 4 {
+		t.Errorf("DrawArrays recorded %+v", draw)
+	}
+	draw := draws[1]
+	if !draw.Indexed || draw.Count != 6 || draw.Type != UNSIGNED_SHORT || draw.Offset != 4 {
+		t.Errorf("DrawElements recorded %+v", draw)
+	}
+	if draw.Program != program || draw.Viewport != [4]int{0, 0, 8, 8} {
+		t.Errorf("draw state is program %v viewport %v", draw.Program, draw.Viewport)
+	}
+	if count := CountCalls("DrawElements"); count != 2 {
+		t.Errorf("CountCalls(DrawElements) = %d, want 2", count)
+	}
+}
+
+func TestElementIndexUint(t *testing.T) {
+	ResetContext()
+	defer SetElementIndexUintSupported(true)
+
+	program := newTestProgram(t)
+	UseProgram(program)
+	BindBuffer(ELEMENT_ARRAY_BUFFER, CreateBuffer())
+	BufferData(ELEMENT_ARRAY_BUFFER, make([]byte, 12), STATIC_DRAW)
+
+	for _, supported := range []bool{true, false} {
+		SetElementIndexUintSupported(supported)
+		ResetCalls()
+
+		DrawElements(TRIANGLES, 3, UNSIGNED_INT, 0)
+		if drawn := len(DrawCalls()) == 1; drawn != supported {
+			t.Errorf("extension %v: UNSIGNED_INT indices drawn %v", supported, drawn)
+		}
+		if supported == (GetError() == INVALID_ENUM) {
+			t.Errorf("extension %v: unexpected error state", supported)
+		}
+		if ElementIndexUintSupported() != supported || strings.Contains(GetString(EXTENSIONS), "GL_OES_element_index_uint") != supported {
+			t.Errorf("extension %v: reported %v in %q", supported, ElementIndexUintSupported(), GetString(EXTENSIONS))
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// +build !js
+// +build !js,!glnull,!glsoft
 
 package gl
 
@@ -387,6 +387,13 @@ func DrawElements(mode Enum, count int, ty Enum, offset int) {
 	gl.DrawElements(uint32(mode), int32(count), uint32(ty), gl.PtrOffset(offset))
 }
 
+// DrawElementsInstanced renders instanceCount instances of primitives from a bound buffer.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glDrawElementsInstanced.xhtml
+func DrawElementsInstanced(mode Enum, count int, ty Enum, offset, instanceCount int) {
+	gl.DrawElementsInstanced(uint32(mode), int32(count), uint32(ty), gl.PtrOffset(offset), int32(instanceCount))
+}
+
 // Enable enables various GL capabilities.
 //
 // http://www.khronos.org/opengles/sdk/docs/man3/html/glEnable.xhtml
@@ -1183,6 +1190,37 @@ func VertexAttrib4fv(dst Attrib, src []float32) {
 	gl.VertexAttrib4fv(uint32(dst.Value), &src[0])
 }
 
+// InstancingSupported reports if DrawElementsInstanced and VertexAttribDivisor are available.
+func InstancingSupported() bool {
+	return true
+}
+
+// StandardDerivativesSupported reports if fragment shaders can use dFdx, dFdy
+// and fwidth, they are core in desktop OpenGL.
+func StandardDerivativesSupported() bool {
+	return true
+}
+
+// TextureLodSupported reports if the ARB_shader_texture_lod extension lets
+// fragment shaders pick the mip level with textureCubeLod.
+func TextureLodSupported() bool {
+	return strings.Contains(GetString(EXTENSIONS), "GL_ARB_shader_texture_lod")
+}
+
+// ElementIndexUintSupported reports if DrawElements accepts UNSIGNED_INT
+// indices, they are core in desktop OpenGL.
+func ElementIndexUintSupported() bool {
+	return true
+}
+
+// VertexAttribDivisor sets how many instances are drawn before the attribute
+// dst advances, 0 advances it for every vertex.
+//
+// http://www.khronos.org/opengles/sdk/docs/man3/html/glVertexAttribDivisor.xhtml
+func VertexAttribDivisor(dst Attrib, divisor int) {
+	gl.VertexAttribDivisor(uint32(dst.Value), uint32(divisor))
+}
+
 // VertexAttribPointer uses a bound buffer to define vertex attribute data.
 //
 // Direct use of VertexAttribPointer to load data into OpenGL is not
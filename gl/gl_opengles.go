@@ -4,6 +4,7 @@
 
 // +build darwin linux
 // +build arm arm64
+// +build !glnull,!glsoft
 
 package gl
 
@@ -19,7 +20,10 @@ package gl
 */
 import "C"
 
-import "unsafe"
+import (
+	"strings"
+	"unsafe"
+)
 
 var ContextWatcher contextWatcher
 
@@ -226,6 +230,10 @@ func DrawElements(mode Enum, count int, ty Enum, offset int) {
 	C.glDrawElements(mode.c(), C.GLsizei(count), ty.c(), unsafe.Pointer(uintptr(offset)))
 }
 
+// DrawElementsInstanced is not part of OpenGL ES 2, InstancingSupported reports false
+func DrawElementsInstanced(mode Enum, count int, ty Enum, offset, instanceCount int) {
+}
+
 func Enable(cap Enum) {
 	C.glEnable(cap.c())
 }
@@ -694,6 +702,32 @@ func VertexAttrib4fv(dst Attrib, src []float32) {
 	C.glVertexAttrib4fv(dst.c(), (*C.GLfloat)(&src[0]))
 }
 
+func InstancingSupported() bool {
+	return false
+}
+
+// StandardDerivativesSupported reports if the OES_standard_derivatives
+// extension gives fragment shaders dFdx, dFdy and fwidth.
+func StandardDerivativesSupported() bool {
+	return strings.Contains(GetString(EXTENSIONS), "GL_OES_standard_derivatives")
+}
+
+// TextureLodSupported reports if the EXT_shader_texture_lod extension lets
+// fragment shaders pick the mip level with textureCubeLodEXT.
+func TextureLodSupported() bool {
+	return strings.Contains(GetString(EXTENSIONS), "GL_EXT_shader_texture_lod")
+}
+
+// ElementIndexUintSupported reports if the OES_element_index_uint extension
+// lets DrawElements use UNSIGNED_INT indices.
+func ElementIndexUintSupported() bool {
+	return strings.Contains(GetString(EXTENSIONS), "GL_OES_element_index_uint")
+}
+
+// VertexAttribDivisor is not part of OpenGL ES 2, InstancingSupported reports false
+func VertexAttribDivisor(dst Attrib, divisor int) {
+}
+
 func VertexAttribPointer(dst Attrib, size int, ty Enum, normalized bool, stride, offset int) {
 	n := glBoolean(normalized)
 	s := C.GLsizei(stride)
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build js
+// +build js,!glnull,!glsoft
 
 package gl
 
@@ -216,6 +216,15 @@ func DrawElements(mode Enum, count int, ty Enum, offset int) {
 	c.Call("drawElements", mode, count, ty, offset)
 }
 
+// instancedArrays returns the ANGLE_instanced_arrays extension, nil when the browser lacks it
+func instancedArrays() *js.Object {
+	return c.Call("getExtension", "ANGLE_instanced_arrays")
+}
+
+func DrawElementsInstanced(mode Enum, count int, ty Enum, offset, instanceCount int) {
+	instancedArrays().Call("drawElementsInstancedANGLE", mode, count, ty, offset, instanceCount)
+}
+
 func Enable(cap Enum) {
 	c.Call("enable", cap)
 }
@@ -688,6 +697,28 @@ func VertexAttrib4fv(dst Attrib, src []float32) {
 	c.Call("vertexAttrib4fv", dst.Value, src)
 }
 
+func InstancingSupported() bool {
+	return instancedArrays() != nil
+}
+
+func StandardDerivativesSupported() bool {
+	return c.Call("getExtension", "OES_standard_derivatives") != nil
+}
+
+func TextureLodSupported() bool {
+	return c.Call("getExtension", "EXT_shader_texture_lod") != nil
+}
+
+// ElementIndexUintSupported enables OES_element_index_uint, WebGL 1 only
+// accepts UNSIGNED_INT indices once the extension is requested
+func ElementIndexUintSupported() bool {
+	return c.Call("getExtension", "OES_element_index_uint") != nil
+}
+
+func VertexAttribDivisor(dst Attrib, divisor int) {
+	instancedArrays().Call("vertexAttribDivisorANGLE", dst.Value, divisor)
+}
+
 func VertexAttribPointer(dst Attrib, size int, ty Enum, normalized bool, stride, offset int) {
 	c.Call("vertexAttribPointer", dst.Value, size, ty, normalized, stride, offset)
 }
@@ -0,0 +1,259 @@
+// +build glnull glsoft
+
+package gl
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The null backend does not compile GLSL, it only runs the preprocessor so
+// that attributes and uniforms hidden behind #ifdef are reported as inactive,
+// like a real driver would do.
+
+type nullVariable struct {
+	name string
+	ty   Enum
+	size int
+}
+
+var nullDeclRegexp = regexp.MustCompile(`^\s*(attribute|uniform)\s+(?:(?:lowp|mediump|highp)\s+)?(\w+)\s+(\w+)\s*(?:\[\s*(\w+)\s*\])?\s*;`)
+
+var nullTypes = map[string]Enum{
+	"float":       FLOAT,
+	"vec2":        FLOAT_VEC2,
+	"vec3":        FLOAT_VEC3,
+	"vec4":        FLOAT_VEC4,
+	"int":         INT,
+	"ivec2":       INT_VEC2,
+	"ivec3":       INT_VEC3,
+	"ivec4":       INT_VEC4,
+	"bool":        BOOL,
+	"bvec2":       BOOL_VEC2,
+	"bvec3":       BOOL_VEC3,
+	"bvec4":       BOOL_VEC4,
+	"mat2":        FLOAT_MAT2,
+	"mat3":        FLOAT_MAT3,
+	"mat4":        FLOAT_MAT4,
+	"sampler2D":   SAMPLER_2D,
+	"samplerCube": SAMPLER_CUBE,
+}
+
+// preprocessShader returns the active lines of src, the declared attributes and uniforms and the macros defined at the end
+func preprocessShader(src string) (lines []string, attributes []nullVariable, uniforms []nullVariable, defines map[string]string, err string) {
+	defines = map[string]string{}
+
+	// every entry tells if the enclosing block is active, and if a branch was taken
+	type block struct {
+		active bool
+		taken  bool
+	}
+	stack := []block{}
+	active := func() bool {
+		return len(stack) == 0 || stack[len(stack)-1].active
+	}
+
+	for lineIndex, line := range strings.Split(strings.Replace(src, "\r", "", -1), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "#") {
+			directive := strings.TrimSpace(trimmed[1:])
+			name := directive
+			rest := ""
+			if pos := strings.IndexAny(directive, " \t"); pos >= 0 {
+				name = directive[:pos]
+				rest = strings.TrimSpace(directive[pos:])
+			}
+			if pos := strings.Index(rest, "//"); pos >= 0 {
+				rest = strings.TrimSpace(rest[:pos])
+			}
+
+			parent := active()
+			switch name {
+			case "ifdef":
+				_, ok := defines[rest]
+				stack = append(stack, block{active: parent && ok, taken: ok})
+			case "ifndef":
+				_, ok := defines[rest]
+				stack = append(stack, block{active: parent && !ok, taken: !ok})
+			case "if":
+				ok := evalShaderCondition(rest, defines)
+				stack = append(stack, block{active: parent && ok, taken: ok})
+			case "elif", "else", "endif":
+				if len(stack) == 0 {
+					return nil, nil, nil, nil, "ERROR: 0:" + strconv.Itoa(lineIndex+1) + ": '#" + name + "' without '#if'"
+				}
+				top := &stack[len(stack)-1]
+				parent = len(stack) == 1 || stack[len(stack)-2].active
+
+				switch name {
+				case "elif":
+					ok := !top.taken && evalShaderCondition(rest, defines)
+					top.active = parent && ok
+					top.taken = top.taken || ok
+				case "else":
+					top.active = parent && !top.taken
+					top.taken = true
+				case "endif":
+					stack = stack[:len(stack)-1]
+				}
+			case "define":
+				if parent {
+					macro := rest
+					value := ""
+					if pos := strings.IndexAny(rest, " \t"); pos >= 0 {
+						macro = rest[:pos]
+						value = strings.TrimSpace(rest[pos:])
+					}
+					defines[macro] = value
+				}
+			case "undef":
+				if parent {
+					delete(defines, rest)
+				}
+			}
+			continue
+		}
+
+		if !active() {
+			continue
+		}
+		lines = append(lines, line)
+
+		match := nullDeclRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		size := 1
+		if match[4] != "" {
+			sizeStr := match[4]
+			if value, ok := defines[sizeStr]; ok {
+				sizeStr = value
+			}
+			size, _ = strconv.Atoi(sizeStr)
+		}
+		variable := nullVariable{name: match[3], ty: nullTypes[match[2]], size: size}
+		if match[1] == "attribute" {
+			attributes = append(attributes, variable)
+		} else {
+			uniforms = append(uniforms, variable)
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, nil, nil, nil, "ERROR: unterminated '#if'"
+	}
+
+	return lines, attributes, uniforms, defines, ""
+}
+
+var nullTokenRegexp = regexp.MustCompile(`defined\s*\(\s*\w+\s*\)|defined\s+\w+|\w+|&&|\|\||==|!=|<=|>=|[()!<>]`)
+
+// evalShaderCondition evaluates a #if expression with defined(), integers, comparisons, !, && and ||
+func evalShaderCondition(expr string, defines map[string]string) bool {
+	tokens := nullTokenRegexp.FindAllString(expr, -1)
+	pos := 0
+
+	var parseOr func() int
+	value := func(token string) int {
+		if strings.HasPrefix(token, "defined") {
+			name := strings.Trim(strings.TrimSpace(token[len("defined"):]), "() \t")
+			if _, ok := defines[name]; ok {
+				return 1
+			}
+			return 0
+		}
+		if define, ok := defines[token]; ok {
+			token = define
+		}
+		number, err := strconv.ParseFloat(strings.TrimSuffix(token, "."), 64)
+		if err != nil {
+			return 0
+		}
+		return int(number)
+	}
+	var parseUnary func() int
+	parseUnary = func() int {
+		if pos >= len(tokens) {
+			return 0
+		}
+		token := tokens[pos]
+		pos++
+		switch token {
+		case "!":
+			if parseUnary() == 0 {
+				return 1
+			}
+			return 0
+		case "(":
+			result := parseOr()
+			if pos < len(tokens) && tokens[pos] == ")" {
+				pos++
+			}
+			return result
+		}
+		return value(token)
+	}
+	parseCompare := func() int {
+		left := parseUnary()
+		for pos < len(tokens) {
+			op := tokens[pos]
+			var result bool
+			switch op {
+			case "==", "!=", "<", ">", "<=", ">=":
+				pos++
+				right := parseUnary()
+				switch op {
+				case "==":
+					result = left == right
+				case "!=":
+					result = left != right
+				case "<":
+					result = left < right
+				case ">":
+					result = left > right
+				case "<=":
+					result = left <= right
+				case ">=":
+					result = left >= right
+				}
+			default:
+				return left
+			}
+			left = 0
+			if result {
+				left = 1
+			}
+		}
+		return left
+	}
+	parseAnd := func() int {
+		left := parseCompare()
+		for pos < len(tokens) && tokens[pos] == "&&" {
+			pos++
+			right := parseCompare()
+			if left != 0 && right != 0 {
+				left = 1
+			} else {
+				left = 0
+			}
+		}
+		return left
+	}
+	parseOr = func() int {
+		left := parseAnd()
+		for pos < len(tokens) && tokens[pos] == "||" {
+			pos++
+			right := parseAnd()
+			if left != 0 || right != 0 {
+				left = 1
+			} else {
+				left = 0
+			}
+		}
+		return left
+	}
+
+	return parseOr() != 0
+}
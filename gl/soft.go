@@ -0,0 +1,897 @@
+// +build glsoft
+
+package gl
+
+import (
+	"log"
+	"math"
+)
+
+// The software backend extends the null backend with a cpu rasterizer:
+// draws are clipped, culled, depth tested, blended and written into the
+// framebuffer images, so ReadPixels returns what a gpu would have drawn.
+// GLSL is not interpreted, every program needs a SoftShader registered
+// with RegisterSoftShader that does the same work in Go.
+
+// SoftShader runs a program on the cpu. A shader is created for every draw,
+// so uniforms and samplers can be read once when it is created.
+type SoftShader interface {
+	// Attributes returns the names of the attributes passed to Vertex, in order
+	Attributes() []string
+	// Varyings returns how many floats Vertex writes for Fragment
+	Varyings() int
+	// Vertex returns the clip space position of one vertex and fills its varyings
+	Vertex(attributes [][4]float32, varyings []float32) [4]float32
+	// Fragment returns the color of one pixel from the interpolated varyings, discard drops the pixel
+	Fragment(fragment *SoftFragment, varyings []float32) (color [4]float32, discard bool)
+}
+
+// SoftFragment holds gl_FragCoord and gl_FrontFacing of the pixel being shaded
+type SoftFragment struct {
+	X           float32
+	Y           float32
+	Z           float32
+	W           float32
+	FrontFacing bool
+}
+
+type softShaderEntry struct {
+	match  func(vertexSource, fragmentSource string) bool
+	create func(program *SoftProgram) SoftShader
+}
+
+var softShaders []softShaderEntry
+var softMissing = map[uint32]bool{}
+
+// RegisterSoftShader adds a cpu implementation for the programs whose sources
+// are accepted by match, later registrations take precedence
+func RegisterSoftShader(match func(vertexSource, fragmentSource string) bool, create func(program *SoftProgram) SoftShader) {
+	softShaders = append(softShaders, softShaderEntry{match: match, create: create})
+}
+
+// SoftProgram gives a SoftShader access to the state of the program being drawn
+type SoftProgram struct {
+	Program Program
+
+	_program *nullProgram
+	// _reads collects the uniforms and macros read, for SoftShaderReads
+	_reads map[string]bool
+}
+
+// Defined reports if the macro name is defined in the program sources
+func (this *SoftProgram) Defined(name string) bool {
+	this.read(name)
+	_, ok := this._program.defines[name]
+	return ok
+}
+
+// Define returns the value of the macro name, empty if it is not defined
+func (this *SoftProgram) Define(name string) string {
+	this.read(name)
+	return this._program.defines[name]
+}
+
+// Uniform returns the last value set for the named uniform, padded with zeros to size floats
+func (this *SoftProgram) Uniform(name string, size int) []float32 {
+	this.read(name)
+	values := make([]float32, size)
+	location, ok := this._program.uniformLocs[name]
+	if ok {
+		copy(values, this._program.uniformValues[location])
+	}
+	return values
+}
+
+func (this *SoftProgram) Float(name string) float32 {
+	return this.Uniform(name, 1)[0]
+}
+
+func (this *SoftProgram) Vec4(name string) [4]float32 {
+	var result [4]float32
+	copy(result[:], this.Uniform(name, 4))
+	return result
+}
+
+func (this *SoftProgram) Matrix(name string) [16]float32 {
+	var result [16]float32
+	copy(result[:], this.Uniform(name, 16))
+	return result
+}
+
+// Sampler returns the texture bound to the unit of the named sampler uniform,
+// target is TEXTURE_2D or TEXTURE_CUBE_MAP
+func (this *SoftProgram) Sampler(name string, target Enum) *SoftSampler {
+	unit := int(this.Float(name))
+	units := nullCtx.boundTextures[unit]
+	if units == nil {
+		return nil
+	}
+	texture := nullCtx.textures[units[target].Value]
+	if texture == nil {
+		return nil
+	}
+	return &SoftSampler{_texture: texture}
+}
+
+func (this *SoftProgram) read(name string) {
+	if this._reads != nil {
+		this._reads[name] = true
+	}
+}
+
+// SoftShaderReads creates the SoftShader of p like a draw does, and returns
+// its attributes and the uniforms and macros it read. Tests compare them with
+// the GLSL of the program, ok is false when no SoftShader accepts p.
+func SoftShaderReads(p Program) (attributes []string, reads map[string]bool, ok bool) {
+	program := nullCtx.programs[p.Value]
+	if program == nil {
+		return nil, nil, false
+	}
+	create := findSoftShader(program)
+	if create == nil {
+		return nil, nil, false
+	}
+
+	softProgram := &SoftProgram{Program: p, _program: program, _reads: map[string]bool{}}
+	return create(softProgram).Attributes(), softProgram._reads, true
+}
+
+// SoftSampler reads a texture like texture2D and textureCube, a nil sampler reads opaque black
+type SoftSampler struct {
+	_texture *nullTexture
+}
+
+// Sample reads a 2D texture at u, v
+func (this *SoftSampler) Sample(u, v float32) [4]float32 {
+	if this == nil {
+		return [4]float32{0, 0, 0, 1}
+	}
+	return this.sampleImage(this._texture.image(TEXTURE_2D, 0), u, v, this.wrap(TEXTURE_WRAP_S), this.wrap(TEXTURE_WRAP_T))
+}
+
+// SampleCube reads a cube texture in direction x, y, z
+func (this *SoftSampler) SampleCube(x, y, z float32) [4]float32 {
+	if this == nil {
+		return [4]float32{0, 0, 0, 1}
+	}
+
+	// face selection from the OpenGL ES specification, table 3.21
+	ax, ay, az := abs32(x), abs32(y), abs32(z)
+	var face Enum
+	var sc, tc, ma float32
+	switch {
+	case ax >= ay && ax >= az:
+		ma = ax
+		if x > 0 {
+			face, sc, tc = TEXTURE_CUBE_MAP_POSITIVE_X, -z, -y
+		} else {
+			face, sc, tc = TEXTURE_CUBE_MAP_NEGATIVE_X, z, -y
+		}
+	case ay >= az:
+		ma = ay
+		if y > 0 {
+			face, sc, tc = TEXTURE_CUBE_MAP_POSITIVE_Y, x, z
+		} else {
+			face, sc, tc = TEXTURE_CUBE_MAP_NEGATIVE_Y, x, -z
+		}
+	default:
+		ma = az
+		if z > 0 {
+			face, sc, tc = TEXTURE_CUBE_MAP_POSITIVE_Z, x, -y
+		} else {
+			face, sc, tc = TEXTURE_CUBE_MAP_NEGATIVE_Z, -x, -y
+		}
+	}
+	if ma == 0 {
+		return [4]float32{0, 0, 0, 1}
+	}
+
+	return this.sampleImage(this._texture.image(face, 0), (sc/ma+1)/2, (tc/ma+1)/2, CLAMP_TO_EDGE, CLAMP_TO_EDGE)
+}
+
+func (this *SoftSampler) wrap(pname Enum) Enum {
+	if value, ok := this._texture.params[pname]; ok {
+		return Enum(value)
+	}
+	return REPEAT
+}
+
+func (this *SoftSampler) sampleImage(image *nullImage, u, v float32, wrapS, wrapT Enum) [4]float32 {
+	if image == nil || image.data == nil || image.width == 0 || image.height == 0 {
+		return [4]float32{0, 0, 0, 1}
+	}
+
+	if filter, ok := this._texture.params[TEXTURE_MAG_FILTER]; ok && Enum(filter) == NEAREST {
+		x := softWrap(int(math.Floor(float64(u*float32(image.width)))), image.width, wrapS)
+		y := softWrap(int(math.Floor(float64(v*float32(image.height)))), image.height, wrapT)
+		return softTexel(image, x, y)
+	}
+
+	fx := u*float32(image.width) - 0.5
+	fy := v*float32(image.height) - 0.5
+	x0 := int(math.Floor(float64(fx)))
+	y0 := int(math.Floor(float64(fy)))
+	tx := fx - float32(x0)
+	ty := fy - float32(y0)
+
+	x1 := softWrap(x0+1, image.width, wrapS)
+	y1 := softWrap(y0+1, image.height, wrapT)
+	x0 = softWrap(x0, image.width, wrapS)
+	y0 = softWrap(y0, image.height, wrapT)
+
+	c00 := softTexel(image, x0, y0)
+	c10 := softTexel(image, x1, y0)
+	c01 := softTexel(image, x0, y1)
+	c11 := softTexel(image, x1, y1)
+
+	var result [4]float32
+	for index := 0; index < 4; index++ {
+		top := c00[index]*(1-tx) + c10[index]*tx
+		bottom := c01[index]*(1-tx) + c11[index]*tx
+		result[index] = top*(1-ty) + bottom*ty
+	}
+	return result
+}
+
+func softWrap(coord, size int, mode Enum) int {
+	switch mode {
+	case CLAMP_TO_EDGE:
+		if coord < 0 {
+			return 0
+		}
+		if coord >= size {
+			return size - 1
+		}
+		return coord
+	case MIRRORED_REPEAT:
+		period := size * 2
+		coord = ((coord % period) + period) % period
+		if coord >= size {
+			coord = period - 1 - coord
+		}
+		return coord
+	}
+	return ((coord % size) + size) % size
+}
+
+func softTexel(image *nullImage, x, y int) [4]float32 {
+	var components int
+	switch image.format {
+	case RGB:
+		components = 3
+	case LUMINANCE_ALPHA:
+		components = 2
+	case LUMINANCE, ALPHA:
+		components = 1
+	default:
+		components = 4
+	}
+
+	pos := (y*image.width + x) * components
+	if pos+components > len(image.data) {
+		return [4]float32{0, 0, 0, 1}
+	}
+	texel := image.data[pos : pos+components]
+
+	switch image.format {
+	case RGB:
+		return [4]float32{float32(texel[0]) / 255, float32(texel[1]) / 255, float32(texel[2]) / 255, 1}
+	case LUMINANCE_ALPHA:
+		l := float32(texel[0]) / 255
+		return [4]float32{l, l, l, float32(texel[1]) / 255}
+	case LUMINANCE:
+		l := float32(texel[0]) / 255
+		return [4]float32{l, l, l, 1}
+	case ALPHA:
+		return [4]float32{0, 0, 0, float32(texel[0]) / 255}
+	}
+	return [4]float32{float32(texel[0]) / 255, float32(texel[1]) / 255, float32(texel[2]) / 255, float32(texel[3]) / 255}
+}
+
+func abs32(value float32) float32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func clamp01(value float32) float32 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
+
+func init() {
+	rasterize = softDraw
+}
+
+// softVertex is a shaded vertex, in clip space before the viewport transform and in window space after
+type softVertex struct {
+	position [4]float32
+	varyings []float32
+
+	x, y, z float32
+	invW    float32
+}
+
+type softRasterizer struct {
+	shader   SoftShader
+	target   *nullImage
+	depth    []float32
+	varyings int
+
+	// pixels outside [minX, maxX) x [minY, maxY) are not drawn
+	minX, minY, maxX, maxY int
+}
+
+func findSoftShader(program *nullProgram) func(program *SoftProgram) SoftShader {
+	for index := len(softShaders) - 1; index >= 0; index-- {
+		entry := softShaders[index]
+		if entry.match(program.sources[VERTEX_SHADER], program.sources[FRAGMENT_SHADER]) {
+			return entry.create
+		}
+	}
+	return nil
+}
+
+func softDraw(call DrawCall) {
+	program := nullCtx.programs[call.Program.Value]
+	create := findSoftShader(program)
+	if create == nil {
+		if !softMissing[call.Program.Value] {
+			softMissing[call.Program.Value] = true
+			log.Printf("glsoft: no SoftShader registered for program %d, draws are skipped", call.Program.Value)
+		}
+		return
+	}
+
+	target := nullCtx.colorImage()
+	if target == nil || target.data == nil {
+		return
+	}
+
+	this := &softRasterizer{}
+	this.shader = create(&SoftProgram{Program: call.Program, _program: program})
+	this.target = target
+	this.depth = nullCtx.depthBuffer()
+	this.varyings = this.shader.Varyings()
+
+	viewport := nullCtx.viewport
+	this.minX, this.minY = viewport[0], viewport[1]
+	this.maxX, this.maxY = viewport[0]+viewport[2], viewport[1]+viewport[3]
+	if nullCtx.caps[SCISSOR_TEST] {
+		scissor := nullCtx.scissor
+		this.minX = maxInt(this.minX, int(scissor[0]))
+		this.minY = maxInt(this.minY, int(scissor[1]))
+		this.maxX = minInt(this.maxX, int(scissor[0]+scissor[2]))
+		this.maxY = minInt(this.maxY, int(scissor[1]+scissor[3]))
+	}
+	this.minX, this.minY = maxInt(this.minX, 0), maxInt(this.minY, 0)
+	this.maxX, this.maxY = minInt(this.maxX, target.width), minInt(this.maxY, target.height)
+
+	indices := softIndices(call)
+	if call.Instances == 0 {
+		this.primitives(call.Mode, this.shadeVertices(program, indices, 0))
+		return
+	}
+	for instance := 0; instance < call.Instances; instance++ {
+		this.primitives(call.Mode, this.shadeVertices(program, indices, instance))
+	}
+}
+
+// primitives assembles shaded vertices into primitives and rasterizes them
+func (this *softRasterizer) primitives(mode Enum, vertices []*softVertex) {
+	switch mode {
+	case TRIANGLES:
+		for index := 0; index+2 < len(vertices); index += 3 {
+			this.triangle(vertices[index], vertices[index+1], vertices[index+2])
+		}
+	case TRIANGLE_STRIP:
+		for index := 0; index+2 < len(vertices); index++ {
+			if index%2 == 0 {
+				this.triangle(vertices[index], vertices[index+1], vertices[index+2])
+			} else {
+				this.triangle(vertices[index+1], vertices[index], vertices[index+2])
+			}
+		}
+	case TRIANGLE_FAN:
+		for index := 1; index+1 < len(vertices); index++ {
+			this.triangle(vertices[0], vertices[index], vertices[index+1])
+		}
+	case LINES:
+		for index := 0; index+1 < len(vertices); index += 2 {
+			this.line(vertices[index], vertices[index+1])
+		}
+	case LINE_STRIP, LINE_LOOP:
+		for index := 0; index+1 < len(vertices); index++ {
+			this.line(vertices[index], vertices[index+1])
+		}
+		if mode == LINE_LOOP && len(vertices) > 2 {
+			this.line(vertices[len(vertices)-1], vertices[0])
+		}
+	case POINTS:
+		for _, vertex := range vertices {
+			this.point(vertex)
+		}
+	}
+}
+
+// softIndices returns the vertex indices used by a draw
+func softIndices(call DrawCall) []int {
+	if !call.Indexed {
+		indices := make([]int, call.Count)
+		for index := range indices {
+			indices[index] = call.First + index
+		}
+		return indices
+	}
+
+	buffer := nullCtx.buffers[nullCtx.boundBuffers[ELEMENT_ARRAY_BUFFER].Value]
+	if buffer == nil {
+		return nil
+	}
+	data := buffer.data
+
+	var size int
+	switch call.Type {
+	case UNSIGNED_BYTE:
+		size = 1
+	case UNSIGNED_INT:
+		size = 4
+	default:
+		size = 2
+	}
+
+	indices := make([]int, 0, call.Count)
+	for index := 0; index < call.Count; index++ {
+		pos := call.Offset + index*size
+		if pos+size > len(data) {
+			break
+		}
+		switch size {
+		case 1:
+			indices = append(indices, int(data[pos]))
+		case 2:
+			indices = append(indices, int(uint16(data[pos])|uint16(data[pos+1])<<8))
+		case 4:
+			indices = append(indices, int(uint32(data[pos])|uint32(data[pos+1])<<8|uint32(data[pos+2])<<16|uint32(data[pos+3])<<24))
+		}
+	}
+	return indices
+}
+
+// shadeVertices runs the vertex shader once per distinct index of an instance
+func (this *softRasterizer) shadeVertices(program *nullProgram, indices []int, instance int) []*softVertex {
+	names := this.shader.Attributes()
+	locations := make([]int, len(names))
+	for index, name := range names {
+		location, ok := program.attribLocs[name]
+		if !ok {
+			location = -1
+		}
+		locations[index] = location
+	}
+
+	cache := map[int]*softVertex{}
+	vertices := make([]*softVertex, len(indices))
+	attributes := make([][4]float32, len(names))
+
+	for position, vertexIndex := range indices {
+		if vertex, ok := cache[vertexIndex]; ok {
+			vertices[position] = vertex
+			continue
+		}
+
+		for index, location := range locations {
+			attributes[index] = softFetch(location, vertexIndex, instance)
+		}
+
+		vertex := &softVertex{varyings: make([]float32, this.varyings)}
+		vertex.position = this.shader.Vertex(attributes, vertex.varyings)
+		cache[vertexIndex] = vertex
+		vertices[position] = vertex
+	}
+	return vertices
+}
+
+// softFetch reads one attribute of one vertex, instanced attributes are read for the instance
+func softFetch(location int, vertexIndex int, instance int) [4]float32 {
+	if location < 0 || location >= len(nullCtx.attribs) {
+		return [4]float32{0, 0, 0, 1}
+	}
+	attrib := nullCtx.attribs[location]
+	if !attrib.enabled {
+		return attrib.value
+	}
+	buffer := nullCtx.buffers[attrib.buffer.Value]
+	if buffer == nil {
+		return [4]float32{0, 0, 0, 1}
+	}
+
+	componentSize := 4
+	if attrib.ty == UNSIGNED_BYTE || attrib.ty == BYTE {
+		componentSize = 1
+	} else if attrib.ty == UNSIGNED_SHORT || attrib.ty == SHORT {
+		componentSize = 2
+	}
+	stride := attrib.stride
+	if stride == 0 {
+		stride = attrib.size * componentSize
+	}
+
+	if attrib.divisor > 0 {
+		vertexIndex = instance / attrib.divisor
+	}
+
+	result := [4]float32{0, 0, 0, 1}
+	pos := attrib.offset + vertexIndex*stride
+	for component := 0; component < attrib.size; component++ {
+		at := pos + component*componentSize
+		if at+componentSize > len(buffer.data) {
+			break
+		}
+		switch attrib.ty {
+		case UNSIGNED_BYTE:
+			result[component] = float32(buffer.data[at])
+			if attrib.normalized {
+				result[component] /= 255
+			}
+		case BYTE:
+			result[component] = float32(int8(buffer.data[at]))
+			if attrib.normalized {
+				result[component] /= 127
+			}
+		case UNSIGNED_SHORT:
+			result[component] = float32(uint16(buffer.data[at]) | uint16(buffer.data[at+1])<<8)
+			if attrib.normalized {
+				result[component] /= 65535
+			}
+		case SHORT:
+			result[component] = float32(int16(uint16(buffer.data[at]) | uint16(buffer.data[at+1])<<8))
+			if attrib.normalized {
+				result[component] /= 32767
+			}
+		default:
+			bits := uint32(buffer.data[at]) | uint32(buffer.data[at+1])<<8 | uint32(buffer.data[at+2])<<16 | uint32(buffer.data[at+3])<<24
+			result[component] = math.Float32frombits(bits)
+		}
+	}
+	return result
+}
+
+// lerpVertex interpolates two clip space vertices
+func (this *softRasterizer) lerpVertex(a, b *softVertex, t float32) *softVertex {
+	result := &softVertex{varyings: make([]float32, this.varyings)}
+	for index := 0; index < 4; index++ {
+		result.position[index] = a.position[index] + (b.position[index]-a.position[index])*t
+	}
+	for index := range result.varyings {
+		result.varyings[index] = a.varyings[index] + (b.varyings[index]-a.varyings[index])*t
+	}
+	return result
+}
+
+// clipNear cuts a polygon with the near plane z = -w, the far plane is handled per pixel
+func (this *softRasterizer) clipNear(polygon []*softVertex) []*softVertex {
+	distance := func(vertex *softVertex) float32 {
+		return vertex.position[2] + vertex.position[3]
+	}
+
+	result := make([]*softVertex, 0, len(polygon)+1)
+	for index, current := range polygon {
+		next := polygon[(index+1)%len(polygon)]
+		dc, dn := distance(current), distance(next)
+		if dc >= 0 {
+			result = append(result, current)
+		}
+		if (dc >= 0) != (dn >= 0) {
+			result = append(result, this.lerpVertex(current, next, dc/(dc-dn)))
+		}
+	}
+	return result
+}
+
+// toWindow applies the perspective divide and the viewport transform
+func (this *softRasterizer) toWindow(vertex *softVertex) *softVertex {
+	w := vertex.position[3]
+	if w == 0 {
+		w = 1e-6
+	}
+	viewport := nullCtx.viewport
+	result := &softVertex{position: vertex.position, varyings: make([]float32, len(vertex.varyings))}
+	result.invW = 1 / w
+	result.x = float32(viewport[0]) + (vertex.position[0]*result.invW+1)*0.5*float32(viewport[2])
+	result.y = float32(viewport[1]) + (vertex.position[1]*result.invW+1)*0.5*float32(viewport[3])
+	result.z = vertex.position[2] * result.invW
+	for index, value := range vertex.varyings {
+		result.varyings[index] = value * result.invW
+	}
+	return result
+}
+
+func (this *softRasterizer) triangle(a, b, c *softVertex) {
+	polygon := this.clipNear([]*softVertex{a, b, c})
+	if len(polygon) < 3 {
+		return
+	}
+	window := make([]*softVertex, len(polygon))
+	for index, vertex := range polygon {
+		window[index] = this.toWindow(vertex)
+	}
+	for index := 1; index+1 < len(window); index++ {
+		this.rasterTriangle(window[0], window[index], window[index+1])
+	}
+}
+
+func (this *softRasterizer) rasterTriangle(v0, v1, v2 *softVertex) {
+	area := (v1.x-v0.x)*(v2.y-v0.y) - (v2.x-v0.x)*(v1.y-v0.y)
+	if area == 0 {
+		return
+	}
+
+	front := area > 0
+	if nullCtx.frontFace == CW {
+		front = !front
+	}
+	if nullCtx.caps[CULL_FACE] {
+		switch nullCtx.cullFace {
+		case FRONT_AND_BACK:
+			return
+		case FRONT:
+			if front {
+				return
+			}
+		default:
+			if !front {
+				return
+			}
+		}
+	}
+
+	// counter clockwise order makes all edge functions positive inside
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+
+	minX := maxInt(this.minX, int(math.Floor(float64(min3(v0.x, v1.x, v2.x)))))
+	maxX := minInt(this.maxX, int(math.Ceil(float64(max3(v0.x, v1.x, v2.x)))))
+	minY := maxInt(this.minY, int(math.Floor(float64(min3(v0.y, v1.y, v2.y)))))
+	maxY := minInt(this.maxY, int(math.Ceil(float64(max3(v0.y, v1.y, v2.y)))))
+
+	// top-left rule so that pixels on shared edges are drawn once
+	topLeft := func(a, b *softVertex) bool {
+		dx, dy := b.x-a.x, b.y-a.y
+		return dy < 0 || (dy == 0 && dx < 0)
+	}
+	tl0, tl1, tl2 := topLeft(v1, v2), topLeft(v2, v0), topLeft(v0, v1)
+	edge := func(a, b *softVertex, x, y float32) float32 {
+		return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+	}
+
+	varyings := make([]float32, this.varyings)
+	for py := minY; py < maxY; py++ {
+		cy := float32(py) + 0.5
+		for px := minX; px < maxX; px++ {
+			cx := float32(px) + 0.5
+
+			e0 := edge(v1, v2, cx, cy)
+			e1 := edge(v2, v0, cx, cy)
+			e2 := edge(v0, v1, cx, cy)
+			if e0 < 0 || e1 < 0 || e2 < 0 {
+				continue
+			}
+			if (e0 == 0 && !tl0) || (e1 == 0 && !tl1) || (e2 == 0 && !tl2) {
+				continue
+			}
+
+			b0, b1, b2 := e0/area, e1/area, e2/area
+			z := b0*v0.z + b1*v1.z + b2*v2.z
+			invW := b0*v0.invW + b1*v1.invW + b2*v2.invW
+			for index := range varyings {
+				varyings[index] = (b0*v0.varyings[index] + b1*v1.varyings[index] + b2*v2.varyings[index]) / invW
+			}
+
+			this.fragment(px, py, z, invW, front, varyings)
+		}
+	}
+}
+
+func (this *softRasterizer) line(a, b *softVertex) {
+	polygon := this.clipNear([]*softVertex{a, b})
+	if len(polygon) < 2 {
+		return
+	}
+	// a segment clipped by the near plane comes back as current, intersection, ...
+	v0 := this.toWindow(polygon[0])
+	v1 := this.toWindow(polygon[1])
+
+	dx, dy := v1.x-v0.x, v1.y-v0.y
+	steps := int(math.Ceil(float64(math.Max(math.Abs(float64(dx)), math.Abs(float64(dy))))))
+	if steps == 0 {
+		steps = 1
+	}
+
+	varyings := make([]float32, this.varyings)
+	for step := 0; step <= steps; step++ {
+		t := float32(step) / float32(steps)
+		x := v0.x + dx*t
+		y := v0.y + dy*t
+		px, py := int(math.Floor(float64(x))), int(math.Floor(float64(y)))
+		if px < this.minX || px >= this.maxX || py < this.minY || py >= this.maxY {
+			continue
+		}
+		z := v0.z + (v1.z-v0.z)*t
+		invW := v0.invW + (v1.invW-v0.invW)*t
+		for index := range varyings {
+			varyings[index] = (v0.varyings[index] + (v1.varyings[index]-v0.varyings[index])*t) / invW
+		}
+		this.fragment(px, py, z, invW, true, varyings)
+	}
+}
+
+func (this *softRasterizer) point(vertex *softVertex) {
+	if vertex.position[2] < -vertex.position[3] {
+		return
+	}
+	window := this.toWindow(vertex)
+	px, py := int(math.Floor(float64(window.x))), int(math.Floor(float64(window.y)))
+	if px < this.minX || px >= this.maxX || py < this.minY || py >= this.maxY {
+		return
+	}
+	varyings := make([]float32, this.varyings)
+	for index := range varyings {
+		varyings[index] = window.varyings[index] / window.invW
+	}
+	this.fragment(px, py, window.z, window.invW, true, varyings)
+}
+
+// fragment shades one pixel, z is in normalized device coordinates
+func (this *softRasterizer) fragment(px, py int, z, invW float32, front bool, varyings []float32) {
+	if z < -1 || z > 1 {
+		return
+	}
+	depthRange := nullCtx.depthRange
+	depth := depthRange[0] + (z+1)*0.5*(depthRange[1]-depthRange[0])
+
+	pos := py*this.target.width + px
+	depthTest := nullCtx.caps[DEPTH_TEST] && this.depth != nil && pos < len(this.depth)
+	if depthTest && !softDepthPass(depth, this.depth[pos]) {
+		return
+	}
+
+	fragment := &SoftFragment{X: float32(px) + 0.5, Y: float32(py) + 0.5, Z: depth, W: invW, FrontFacing: front}
+	color, discard := this.shader.Fragment(fragment, varyings)
+	if discard {
+		return
+	}
+
+	if depthTest && nullCtx.depthMask {
+		this.depth[pos] = depth
+	}
+
+	pixel := this.target.data[pos*4 : pos*4+4]
+	for index := range color {
+		color[index] = clamp01(color[index])
+	}
+	if nullCtx.caps[BLEND] {
+		color = softBlend(color, pixel)
+	}
+	for index := 0; index < 4; index++ {
+		if nullCtx.colorMask[index] {
+			pixel[index] = byte(clamp01(color[index])*255 + 0.5)
+		}
+	}
+}
+
+func softDepthPass(depth, stored float32) bool {
+	switch nullCtx.depthFunc {
+	case NEVER:
+		return false
+	case LESS:
+		return depth < stored
+	case EQUAL:
+		return depth == stored
+	case LEQUAL:
+		return depth <= stored
+	case GREATER:
+		return depth > stored
+	case NOTEQUAL:
+		return depth != stored
+	case GEQUAL:
+		return depth >= stored
+	}
+	return true
+}
+
+func softBlend(src [4]float32, pixel []byte) [4]float32 {
+	dst := [4]float32{float32(pixel[0]) / 255, float32(pixel[1]) / 255, float32(pixel[2]) / 255, float32(pixel[3]) / 255}
+	blendColor := nullCtx.blendColor
+
+	factor := func(mode Enum, channel int) float32 {
+		switch mode {
+		case ZERO:
+			return 0
+		case ONE:
+			return 1
+		case SRC_COLOR:
+			return src[channel]
+		case ONE_MINUS_SRC_COLOR:
+			return 1 - src[channel]
+		case DST_COLOR:
+			return dst[channel]
+		case ONE_MINUS_DST_COLOR:
+			return 1 - dst[channel]
+		case SRC_ALPHA:
+			return src[3]
+		case ONE_MINUS_SRC_ALPHA:
+			return 1 - src[3]
+		case DST_ALPHA:
+			return dst[3]
+		case ONE_MINUS_DST_ALPHA:
+			return 1 - dst[3]
+		case CONSTANT_COLOR:
+			return blendColor[channel]
+		case ONE_MINUS_CONSTANT_COLOR:
+			return 1 - blendColor[channel]
+		case CONSTANT_ALPHA:
+			return blendColor[3]
+		case ONE_MINUS_CONSTANT_ALPHA:
+			return 1 - blendColor[3]
+		case SRC_ALPHA_SATURATE:
+			if channel == 3 {
+				return 1
+			}
+			if src[3] < 1-dst[3] {
+				return src[3]
+			}
+			return 1 - dst[3]
+		}
+		return 1
+	}
+
+	var result [4]float32
+	for channel := 0; channel < 4; channel++ {
+		srcMode, dstMode, equation := nullCtx.blendFunc[0], nullCtx.blendFunc[1], nullCtx.blendEquation[0]
+		if channel == 3 {
+			srcMode, dstMode, equation = nullCtx.blendFunc[2], nullCtx.blendFunc[3], nullCtx.blendEquation[1]
+		}
+		s := src[channel] * factor(srcMode, channel)
+		d := dst[channel] * factor(dstMode, channel)
+		switch equation {
+		case FUNC_SUBTRACT:
+			result[channel] = s - d
+		case FUNC_REVERSE_SUBTRACT:
+			result[channel] = d - s
+		default:
+			result[channel] = s + d
+		}
+	}
+	return result
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min3(a, b, c float32) float32 {
+	return float32(math.Min(float64(a), math.Min(float64(b), float64(c))))
+}
+
+func max3(a, b, c float32) float32 {
+	return float32(math.Max(float64(a), math.Max(float64(b), float64(c))))
+}
@@ -4,6 +4,7 @@
 
 // +build darwin linux
 // +build arm arm64
+// +build !glnull,!glsoft
 
 package gl
 
@@ -24,6 +24,11 @@ echo "func init() { \n">> $shaderfile
 for element in `ls $currPWD/fly3d/shaders`
     do  
 
+        # the includes are stored below under their bare names
+        if [ -d $currPWD/fly3d/shaders/$element ]; then
+            continue
+        fi
+
         file_name=`echo $element|awk -F"." '{print $1}'`  
         type_name=`echo $element|awk -F"." '{print $2}'`  
        
@@ -35,4 +40,15 @@ for element in `ls $currPWD/fly3d/shaders`
 
     done
 
+for element in `ls $currPWD/fly3d/shaders/ShadersInclude`
+    do
+
+        file_name=`echo $element|awk -F"." '{print $1}'`
+
+        data_content=`cat $currPWD/fly3d/shaders/ShadersInclude/$element`
+
+        echo "ShadersStore[\"${file_name}\"] = \`$data_content\` \n" >>  $shaderfile
+
+    done
+
 echo "} \n" >> $shaderfile
\ No newline at end of file
@@ -11,5 +11,6 @@ type IAnimation interface {
 
 type IAnimationTarget interface {
 	GetAnimations() []IAnimation
-	GetAnimatables() []IAnimatable
+	// GetAnimatables returns the children animated with the target
+	GetAnimatables() []IAnimationTarget
 }
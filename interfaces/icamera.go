@@ -2,7 +2,7 @@ package interfaces
 
 import (
 	"github.com/suiqirui1987/fly3d/math32"
-	"github.com/suiqirui1987/fly3d/windows"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 )
 
 type ICamera interface {
@@ -11,8 +11,8 @@ type ICamera interface {
 	GetMinZ() float32
 	GetMaxZ() float32
 
-	AttachControl(win windows.IWindow)
-	DetachControl(win windows.IWindow)
+	AttachControl(win canvas.IWindow)
+	DetachControl(win canvas.IWindow)
 
 	GetViewMatrix() *math32.Matrix4
 	GetProjectionMatrix() *math32.Matrix4
@@ -0,0 +1,18 @@
+package interfaces
+
+// IClock is the time source of a scene, all times are in milliseconds
+type IClock interface {
+	// Tick advances the clock by one frame and returns the scaled frame time
+	Tick() float32
+	Now() float64
+	GetDeltaTime() float32
+	// GetFps averages the unscaled frame time of the last frames
+	GetFps() float32
+
+	SetScale(scale float32)
+	GetScale() float32
+
+	Pause()
+	Resume()
+	IsPaused() bool
+}
@@ -5,7 +5,7 @@ import "github.com/suiqirui1987/fly3d/math32"
 type ICollider interface {
 	GetRadius() *math32.Vector3
 	CanDoCollision(sphereCenter *math32.Vector3, sphereRadius float32, vecMin *math32.Vector3, vecMax *math32.Vector3) bool
-	Collide(subMesh ISubMesh, pts []*math32.Vector3, indices []uint16, indexStart int, indexEnd int, decal int)
+	Collide(subMesh ISubMesh, pts []*math32.Vector3, indices []uint32, indexStart int, indexEnd int, decal int)
 	SetMesh(IMesh)
 	GetMesh() IMesh
 	HasCollisionFound() bool
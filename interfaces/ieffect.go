@@ -14,8 +14,14 @@ type IEffect interface {
 	GetUniformIndex(uniformName string) int
 	GetUniform(uniformName string) gl.Uniform
 	GetSamplers() []string
+	GetCompilationError() string
 	SetTexture(channel string, texture *gl.GLTextureBuffer)
 	SetMatrix(uniformName string, val *math32.Matrix4)
+	SetMatrices(uniformName string, val []float32)
+	SetFloatArray(uniformName string, val []float32)
+	SetArray3(uniformName string, val []float32)
+	SetArray4(uniformName string, val []float32)
+	SetFloat(uniformName string, val float32)
 	SetBool(uniformName string, val bool)
 	SetVector2(uniformName string, x, y float32)
 	SetVector2i(uniformName string, x, y int)
@@ -10,6 +10,7 @@ type IShadowGenerator interface {
 	Dispose()
 }
 type ILight interface {
+	GetId() string
 	IsEnabled() bool
 	IsSupportShadow() bool
 
@@ -22,4 +23,11 @@ type ILight interface {
 
 	GetPosition() *math32.Vector3
 	GetDirection() *math32.Vector3
+
+	GetRange() float32
+	// CanAffectMesh tells whether the light lights the mesh at all
+	CanAffectMesh(IMesh) bool
+	// GetInfluence ranks the lights of a mesh, the most influential ones are
+	// used when there are too many
+	GetInfluence(IMesh) float32
 }
@@ -7,7 +7,9 @@ type IMultiMaterial interface {
 	GetSubMaterial(index int) IMaterial
 }
 type IMaterial interface {
-	IsReady(IMesh) bool
+	// IsReady reports if the material can render the mesh, with hardware
+	// instancing when useInstances is true
+	IsReady(mesh IMesh, useInstances bool) bool
 	GetId() string
 	GetEffect() IEffect
 	PreBind()
@@ -6,6 +6,7 @@ const (
 	// Enums
 	IMesh_VB_PositionKind        = "position"
 	IMesh_VB_NormalKind          = "normal"
+	IMesh_VB_TangentKind         = "tangent"
 	IMesh_VB_UVKind              = "uv"
 	IMesh_VB_UV2Kind             = "uv2"
 	IMesh_VB_ColorKind           = "color"
@@ -49,6 +50,22 @@ type IMesh interface {
 	IsInFrustrum([]*math32.Plane) bool
 
 	GetSubMeshes() []ISubMesh
+	// GetLOD returns the mesh drawn for this one at the distance of the
+	// camera, nil when the mesh is culled
+	GetLOD(camera ICamera) IMesh
+	// GetSkeleton returns the skeleton deforming the mesh, nil if it has none
+	GetSkeleton() ISkeleton
+	// GetMorphTargetInfluences returns the influences of the morph targets
+	// blended by the vertex shader, nil when the mesh blends them on the CPU
+	GetMorphTargetInfluences() []float32
+	IsMorphTargetNormalsPresent() bool
 
 	Dispose()
 }
+
+// IInstancedMesh is a mesh drawing the geometry and material of its source
+// mesh with its own transformation
+type IInstancedMesh interface {
+	IMesh
+	GetSourceMesh() IMesh
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+// ISerializable is implemented by the objects a scene file can store
+type ISerializable interface {
+	// Serialize returns a value encoded with encoding/json, its "type" field selects the parser
+	Serialize() interface{}
+}
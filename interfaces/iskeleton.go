@@ -0,0 +1,12 @@
+package interfaces
+
+type ISkeleton interface {
+	GetId() string
+	GetName() string
+	GetBonesCount() int
+	// GetTransformMatrices returns the skinning matrix of every bone for the
+	// current pose, 16 floats per bone
+	GetTransformMatrices() []float32
+	// Prepare computes the transform matrices of the current pose
+	Prepare()
+}
@@ -0,0 +1,104 @@
+package math32
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Vectors, quaternions and colors are encoded as JSON arrays
+
+func unmarshalArray(data []byte, size int) ([]float32, error) {
+	var values []float32
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if len(values) != size {
+		return nil, fmt.Errorf("math32: expected %d values, got %d", size, len(values))
+	}
+	return values, nil
+}
+
+func (this *Vector2) AsArray() []float32 {
+	return []float32{this.X, this.Y}
+}
+
+func (this *Vector2) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.AsArray())
+}
+
+func (this *Vector2) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalArray(data, 2)
+	if err != nil {
+		return err
+	}
+	this.X, this.Y = values[0], values[1]
+	return nil
+}
+
+func (this *Vector3) AsArray() []float32 {
+	return []float32{this.X, this.Y, this.Z}
+}
+
+func (this *Vector3) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.AsArray())
+}
+
+func (this *Vector3) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalArray(data, 3)
+	if err != nil {
+		return err
+	}
+	this.X, this.Y, this.Z = values[0], values[1], values[2]
+	return nil
+}
+
+func (this *Quaternion) AsArray() []float32 {
+	return []float32{this.X, this.Y, this.Z, this.W}
+}
+
+func (this *Quaternion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.AsArray())
+}
+
+func (this *Quaternion) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalArray(data, 4)
+	if err != nil {
+		return err
+	}
+	this.X, this.Y, this.Z, this.W = values[0], values[1], values[2], values[3]
+	return nil
+}
+
+func (this *Color3) AsArray() []float32 {
+	return []float32{this.R, this.G, this.B}
+}
+
+func (this *Color3) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.AsArray())
+}
+
+func (this *Color3) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalArray(data, 3)
+	if err != nil {
+		return err
+	}
+	this.R, this.G, this.B = values[0], values[1], values[2]
+	return nil
+}
+
+func (this *Color4) AsArray() []float32 {
+	return []float32{this.R, this.G, this.B, this.A}
+}
+
+func (this *Color4) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.AsArray())
+}
+
+func (this *Color4) UnmarshalJSON(data []byte) error {
+	values, err := unmarshalArray(data, 4)
+	if err != nil {
+		return err
+	}
+	this.R, this.G, this.B, this.A = values[0], values[1], values[2], values[3]
+	return nil
+}
@@ -604,3 +604,44 @@ func (this *Matrix4) Transpose(matrix *Matrix4) *Matrix4 {
 
 	return result
 }
+
+// Compose returns the matrix scaling, then rotating and then translating
+func (this *Matrix4) Compose(scaling *Vector3, rotation *Quaternion, translation *Vector3) *Matrix4 {
+	rotationMatrix := NewMatrix4()
+	rotation.ToRotationMatrix(rotationMatrix)
+
+	result := NewMatrix4().Scaling(scaling.X, scaling.Y, scaling.Z).Multiply(rotationMatrix)
+	result[12] = translation.X
+	result[13] = translation.Y
+	result[14] = translation.Z
+
+	return result
+}
+
+// Decompose splits an affine matrix into the scaling, rotation and translation
+// that Compose takes
+func (this *Matrix4) Decompose() (scaling *Vector3, rotation *Quaternion, translation *Vector3) {
+	translation = NewVector3(this[12], this[13], this[14])
+
+	scaling = NewVector3(
+		Sqrt(this[0]*this[0]+this[1]*this[1]+this[2]*this[2]),
+		Sqrt(this[4]*this[4]+this[5]*this[5]+this[6]*this[6]),
+		Sqrt(this[8]*this[8]+this[9]*this[9]+this[10]*this[10]))
+	if this.Determinant() < 0 {
+		scaling.X = -scaling.X
+	}
+
+	if scaling.X == 0 || scaling.Y == 0 || scaling.Z == 0 {
+		return scaling, NewQuaternion(0, 0, 0, 1), translation
+	}
+
+	rotationMatrix := NewMatrix4().Identity()
+	for column := 0; column < 3; column++ {
+		rotationMatrix[column] = this[column] / scaling.X
+		rotationMatrix[4+column] = this[4+column] / scaling.Y
+		rotationMatrix[8+column] = this[8+column] / scaling.Z
+	}
+	rotation = NewQuaternionZero().FromRotationMatrix(rotationMatrix)
+
+	return scaling, rotation, translation
+}
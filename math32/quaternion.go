@@ -27,6 +27,9 @@ func NewQuaternionZero() *Quaternion {
 func (this *Quaternion) Clone() *Quaternion {
 	return NewQuaternion(this.X, this.Y, this.Z, this.W)
 }
+func (this *Quaternion) Equals(other *Quaternion) bool {
+	return this.X == other.X && this.Y == other.Y && this.Z == other.Z && this.W == other.W
+}
 func (this *Quaternion) Sub(other *Quaternion) *Quaternion {
 	return NewQuaternion(this.X-other.X, this.Y-other.Y, this.Z-other.Z, this.W-other.W)
 }
@@ -84,6 +87,54 @@ func (this *Quaternion) ToRotationMatrix(result *Matrix4) {
 func (this *Quaternion) FromArray(array []float32, offset int) *Quaternion {
 	return NewQuaternion(array[offset], array[offset+1], array[offset+2], array[offset+3])
 }
+
+// FromRotationMatrix extracts the rotation of an orthonormal matrix
+func (this *Quaternion) FromRotationMatrix(matrix *Matrix4) *Quaternion {
+	m11 := matrix[0]
+	m12 := matrix[4]
+	m13 := matrix[8]
+	m21 := matrix[1]
+	m22 := matrix[5]
+	m23 := matrix[9]
+	m31 := matrix[2]
+	m32 := matrix[6]
+	m33 := matrix[10]
+	trace := m11 + m22 + m33
+
+	result := NewQuaternionZero()
+	if trace > 0 {
+		s := 0.5 / Sqrt(trace+1.0)
+
+		result.W = 0.25 / s
+		result.X = (m32 - m23) * s
+		result.Y = (m13 - m31) * s
+		result.Z = (m21 - m12) * s
+	} else if m11 > m22 && m11 > m33 {
+		s := 2.0 * Sqrt(1.0+m11-m22-m33)
+
+		result.W = (m32 - m23) / s
+		result.X = 0.25 * s
+		result.Y = (m12 + m21) / s
+		result.Z = (m13 + m31) / s
+	} else if m22 > m33 {
+		s := 2.0 * Sqrt(1.0+m22-m11-m33)
+
+		result.W = (m13 - m31) / s
+		result.X = (m12 + m21) / s
+		result.Y = 0.25 * s
+		result.Z = (m23 + m32) / s
+	} else {
+		s := 2.0 * Sqrt(1.0+m33-m11-m22)
+
+		result.W = (m21 - m12) / s
+		result.X = (m13 + m31) / s
+		result.Y = (m23 + m32) / s
+		result.Z = 0.25 * s
+	}
+
+	return result
+}
+
 func (this *Quaternion) RotationYawPitchRoll(yaw, pitch, roll float32) *Quaternion {
 
 	result := NewQuaternionZero()
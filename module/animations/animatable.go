@@ -1,6 +1,7 @@
 package animations
 
 import (
+	"github.com/suiqirui1987/fly3d/engines"
 	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/tools"
 )
@@ -15,12 +16,23 @@ type Animatable struct {
 	AnimationStartedDate int
 
 	SpeedRatio float32
+
+	// Clock drives the animation, nil follows the wall clock. NewAnimatable
+	// uses the clock of the scene
+	Clock IClock
+}
+
+// NewAnimatable creates an animatable that follows the clock of scene
+func NewAnimatable(scene *engines.Scene, target IAnimationTarget, from float32, to float32, loop bool, speedRatio float32) *Animatable {
+	return NewAnimatableWithClock(scene.GetClock(), target, from, to, loop, speedRatio)
 }
 
-func NewAnimatable(target IAnimationTarget, from float32, to float32, loop bool, speedRatio float32) *Animatable {
+// NewAnimatableWithClock creates an animatable that reads its time from clock
+func NewAnimatableWithClock(clock IClock, target IAnimationTarget, from float32, to float32, loop bool, speedRatio float32) *Animatable {
 
 	this := &Animatable{
 		SpeedRatio: 1.0,
+		Clock:      clock,
 	}
 	this.Init(target, from, to, loop, speedRatio)
 	return this
@@ -34,10 +46,17 @@ func (this *Animatable) Init(target IAnimationTarget, from float32, to float32,
 	this.LoopAnimation = loop
 	this.SpeedRatio = speedRatio
 
-	this.AnimationStartedDate = tools.GetCurrentTimeMs()
+	this.AnimationStartedDate = this._now()
 
 }
 
+func (this *Animatable) _now() int {
+	if this.Clock != nil {
+		return int(this.Clock.Now())
+	}
+	return tools.GetCurrentTimeMs()
+}
+
 //interface
 /*
 type IAnimatable interface {
@@ -53,7 +72,7 @@ func (this *Animatable) Animate() bool {
 
 	//Getting time
 	var delay float32
-	delay = (float32)(tools.GetCurrentTimeMs() - this.AnimationStartedDate)
+	delay = (float32)(this._now() - this.AnimationStartedDate)
 
 	// Animating
 	running := false
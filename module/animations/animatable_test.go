@@ -0,0 +1,38 @@
+//go:build glnull || glsoft
+// +build glnull glsoft
+
+package animations
+
+import (
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/tools"
+)
+
+type testTarget struct{}
+
+func (testTarget) GetAnimations() []IAnimation        { return nil }
+func (testTarget) GetAnimatables() []IAnimationTarget { return nil }
+
+func TestNewAnimatableFollowsSceneClock(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	clock := tools.NewManualClock()
+	clock.SetTime(5000)
+	scene.SetClock(clock)
+
+	animatable := NewAnimatable(scene, testTarget{}, 0, 100, false, 1)
+	if animatable.Clock != clock {
+		t.Fatalf("animatable clock is %v, want the scene clock", animatable.Clock)
+	}
+	if animatable.AnimationStartedDate != 5000 {
+		t.Errorf("animation started at %v, want 5000", animatable.AnimationStartedDate)
+	}
+
+	clock.Advance(250)
+	clock.Tick()
+	if now := animatable._now(); now != 5250 {
+		t.Errorf("animatable time %v after advancing the clock, want 5250", now)
+	}
+}
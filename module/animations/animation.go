@@ -93,7 +93,13 @@ func (this *Animation) Init(name string, targetProperty string, framePerSecond f
 	this.DataType = dataType
 
 	this._targetProperty = targetProperty
+	// Properties such as morphTargets.0.influence name the exported fields
 	this._targetPropertyPath = strings.Split(targetProperty, ".")
+	for index, name := range this._targetPropertyPath {
+		if name != "" {
+			this._targetPropertyPath[index] = strings.ToUpper(name[:1]) + name[1:]
+		}
+	}
 
 	if loopMode == -1 {
 		this.LoopMode = ANIMATIONLOOPMODE_CYCLE
@@ -228,6 +234,26 @@ func (this *Animation) _interpolate(currentFrame float32, repeatCount int, loopM
 				case ANIMATIONLOOPMODE_RELATIVE:
 					return startValue.Lerp(endValue, gradient).Add(offsetValue.Scale(float32(repeatCount)))
 				}
+			// Matrix, interpolated on its decomposed scaling, rotation and translation
+			case ANIMATIONTYPE_MATRIX:
+				startValue, ok := startValue_obj.(*math32.Matrix4)
+				if !ok {
+					log.Printf("_interpolate The interface type is incorrect, request Matrix ")
+					return math32.NewMatrix4().Identity()
+				}
+				endValue, ok := endValue_obj.(*math32.Matrix4)
+				if !ok {
+					log.Printf("_interpolate The interface type is incorrect, request Matrix ")
+					return math32.NewMatrix4().Identity()
+				}
+
+				startScaling, startRotation, startTranslation := startValue.Decompose()
+				endScaling, endRotation, endTranslation := endValue.Decompose()
+
+				return math32.NewMatrix4().Compose(
+					startScaling.Lerp(endScaling, gradient),
+					startRotation.Slerp(endRotation, gradient),
+					startTranslation.Lerp(endTranslation, gradient))
 			default:
 				break
 			}
@@ -333,7 +359,7 @@ func (this *Animation) Animate(target IAnimationTarget, delay float32, from floa
 		valname := this._targetPropertyPath[len(this._targetPropertyPath)-1]
 		reflections.SetField(property, valname, currentValue)
 	} else {
-		reflections.SetField(target, this._targetProperty, currentValue)
+		reflections.SetField(target, this._targetPropertyPath[0], currentValue)
 	}
 
 	return true
@@ -15,7 +15,7 @@ func BeginAnimation(scene *engines.Scene, target IAnimationTarget, from float32,
 
 		StopAnimation(scene, target)
 
-		animatable := NewAnimatable(target, from, to, loop, speedRatio)
+		animatable := NewAnimatable(scene, target, from, to, loop, speedRatio)
 		scene.ActiveAnimatables = append(scene.ActiveAnimatables, animatable)
 	}
 
@@ -23,7 +23,7 @@ func BeginAnimation(scene *engines.Scene, target IAnimationTarget, from float32,
 	if target.GetAnimatables() != nil {
 		animatables := target.GetAnimatables()
 		for index := 0; index < len(animatables); index++ {
-			BeginAnimation(scene, animatables[index].GetTarget(), from, to, loop, speedRatio)
+			BeginAnimation(scene, animatables[index], from, to, loop, speedRatio)
 		}
 	}
 }
@@ -31,7 +31,12 @@ func StopAnimation(scene *engines.Scene, target IAnimationTarget) {
 	for index := 0; index < len(scene.ActiveAnimatables); index++ {
 		if reflect.DeepEqual(scene.ActiveAnimatables[index].GetTarget(), target) {
 			scene.ActiveAnimatables = append(scene.ActiveAnimatables[:index], scene.ActiveAnimatables[index+1:]...)
-			return
+			break
 		}
 	}
+
+	// Children animations
+	for _, animatable := range target.GetAnimatables() {
+		StopAnimation(scene, animatable)
+	}
 }
@@ -0,0 +1,148 @@
+package animations
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+func init() {
+	engines.RegisterParser("Animation", parseAnimation)
+	engines.RegisterParser("Animatable", parseAnimatable)
+}
+
+type keyData struct {
+	Frame  float32   `json:"frame"`
+	Values []float32 `json:"values"`
+}
+
+type animationData struct {
+	Type           string     `json:"type"`
+	Name           string     `json:"name"`
+	Property       string     `json:"property"`
+	FramePerSecond float32    `json:"framePerSecond"`
+	DataType       int        `json:"dataType"`
+	LoopMode       int        `json:"loopMode"`
+	Keys           []*keyData `json:"keys"`
+}
+
+type animatableData struct {
+	Type       string  `json:"type"`
+	TargetId   string  `json:"targetId"`
+	From       float32 `json:"from"`
+	To         float32 `json:"to"`
+	Loop       bool    `json:"loop"`
+	SpeedRatio float32 `json:"speedRatio"`
+}
+
+func (this *Animation) Serialize() interface{} {
+	data := &animationData{
+		Type:           "Animation",
+		Name:           this.Name,
+		Property:       this._targetProperty,
+		FramePerSecond: this.FramePerSecond,
+		DataType:       this.DataType,
+		LoopMode:       this.LoopMode,
+	}
+
+	for _, key := range this._keys {
+		var values []float32
+		switch value := key.Value.(type) {
+		case float32:
+			values = []float32{value}
+		case *math32.Vector3:
+			values = value.AsArray()
+		case *math32.Quaternion:
+			values = value.AsArray()
+		case *math32.Matrix4:
+			values = value[:]
+		default:
+			log.Printf("animation %s: cannot serialize %T keys", this.Name, key.Value)
+			return nil
+		}
+		data.Keys = append(data.Keys, &keyData{Frame: key.Frame, Values: values})
+	}
+
+	return data
+}
+
+func (this *Animatable) Serialize() interface{} {
+	target, ok := this._target.(interface {
+		GetId() string
+	})
+	if !ok {
+		return nil
+	}
+
+	return &animatableData{
+		Type:       "Animatable",
+		TargetId:   target.GetId(),
+		From:       this.FromFrame,
+		To:         this.ToFrame,
+		Loop:       this.LoopAnimation,
+		SpeedRatio: this.SpeedRatio,
+	}
+}
+
+func parseAnimation(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &animationData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	sizes := map[int]int{
+		ANIMATIONTYPE_FLOAT:      1,
+		ANIMATIONTYPE_VECTOR3:    3,
+		ANIMATIONTYPE_QUATERNION: 4,
+		ANIMATIONTYPE_MATRIX:     16,
+	}
+	size, ok := sizes[data.DataType]
+	if !ok {
+		return nil, fmt.Errorf("animation %s: unknown data type %d", data.Name, data.DataType)
+	}
+
+	keys := make([]*AnimationKeyFrame, 0, len(data.Keys))
+	for _, key := range data.Keys {
+		if len(key.Values) != size {
+			return nil, fmt.Errorf("animation %s: key at frame %v has %d values", data.Name, key.Frame, len(key.Values))
+		}
+
+		var value interface{}
+		switch data.DataType {
+		case ANIMATIONTYPE_FLOAT:
+			value = key.Values[0]
+		case ANIMATIONTYPE_VECTOR3:
+			value = math32.NewVector3Zero().FromArray(key.Values, 0)
+		case ANIMATIONTYPE_QUATERNION:
+			value = math32.NewQuaternionZero().FromArray(key.Values, 0)
+		case ANIMATIONTYPE_MATRIX:
+			matrix := math32.NewMatrix4()
+			copy(matrix[:], key.Values)
+			value = matrix
+		}
+		keys = append(keys, &AnimationKeyFrame{Frame: key.Frame, Value: value})
+	}
+
+	animation := NewAnimation(data.Name, data.Property, data.FramePerSecond, data.DataType, data.LoopMode)
+	animation.SetKeys(keys)
+	return animation, nil
+}
+
+func parseAnimatable(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &animatableData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	target, ok := scene.GetMeshByID(data.TargetId).(IAnimationTarget)
+	if !ok {
+		return nil, fmt.Errorf("animatable: target %s not found", data.TargetId)
+	}
+
+	BeginAnimation(scene, target, data.From, data.To, data.Loop, data.SpeedRatio)
+	return nil, nil
+}
@@ -0,0 +1,151 @@
+package bones
+
+import (
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+// Bone is a joint of a skeleton. Its local transformation is relative to its
+// parent bone and can be animated either as a matrix with the "Matrix"
+// property or decomposed with the "Position", "RotationQuaternion" and
+// "Scaling" properties
+type Bone struct {
+	Name string
+
+	// Matrix is the local transformation, it is composed from Position,
+	// RotationQuaternion and Scaling when one of them changes
+	Matrix             *math32.Matrix4
+	Position           *math32.Vector3
+	RotationQuaternion *math32.Quaternion
+	Scaling            *math32.Vector3
+
+	_skeleton *Skeleton
+	_parent   *Bone
+	_children []*Bone
+
+	_restPose                  *math32.Matrix4
+	_absoluteTransform         *math32.Matrix4
+	_invertedAbsoluteTransform *math32.Matrix4
+
+	//cache
+	_cachedMatrix   *math32.Matrix4
+	_cachedPosition *math32.Vector3
+	_cachedRotation *math32.Quaternion
+	_cachedScaling  *math32.Vector3
+
+	//Animation Target
+	_animations []IAnimation
+}
+
+// NewBone adds a bone to the skeleton, matrix is the local transformation of
+// the bind pose. The parent must be a bone of the same skeleton or nil
+func NewBone(name string, skeleton *Skeleton, parent *Bone, matrix *math32.Matrix4) *Bone {
+	this := &Bone{}
+	this.Name = name
+	this._skeleton = skeleton
+	this._parent = parent
+
+	this._restPose = matrix.Clone()
+	this._setMatrix(matrix.Clone())
+
+	// Bind pose
+	this._absoluteTransform = this.Matrix.Clone()
+	if parent != nil {
+		this._absoluteTransform = this.Matrix.Multiply(parent._absoluteTransform)
+		parent._children = append(parent._children, this)
+	}
+	this._invertedAbsoluteTransform = this._absoluteTransform.Clone()
+	this._invertedAbsoluteTransform.Invert()
+
+	skeleton.Bones = append(skeleton.Bones, this)
+
+	return this
+}
+
+func (this *Bone) GetSkeleton() *Skeleton {
+	return this._skeleton
+}
+
+func (this *Bone) GetParent() *Bone {
+	return this._parent
+}
+
+func (this *Bone) GetChildren() []*Bone {
+	return this._children
+}
+
+func (this *Bone) GetRestPose() *math32.Matrix4 {
+	return this._restPose
+}
+
+// ReturnToRest sets the local transformation back to the bind pose
+func (this *Bone) ReturnToRest() {
+	this._setMatrix(this._restPose.Clone())
+}
+
+// UpdateMatrix sets the local transformation
+func (this *Bone) UpdateMatrix(matrix *math32.Matrix4) {
+	this._setMatrix(matrix)
+}
+
+// GetAbsoluteTransform returns the transformation of the current pose in the
+// skeleton space, as computed by the last Skeleton.Prepare
+func (this *Bone) GetAbsoluteTransform() *math32.Matrix4 {
+	return this._absoluteTransform
+}
+
+// GetInvertedAbsoluteTransform returns the inverse of the bind pose
+// transformation in the skeleton space
+func (this *Bone) GetInvertedAbsoluteTransform() *math32.Matrix4 {
+	return this._invertedAbsoluteTransform
+}
+
+func (this *Bone) _setMatrix(matrix *math32.Matrix4) {
+	this.Matrix = matrix
+	this.Scaling, this.RotationQuaternion, this.Position = matrix.Decompose()
+	this._cache()
+}
+
+func (this *Bone) _cache() {
+	this._cachedMatrix = this.Matrix.Clone()
+	this._cachedPosition = this.Position.Clone()
+	this._cachedRotation = this.RotationQuaternion.Clone()
+	this._cachedScaling = this.Scaling.Clone()
+}
+
+// _updateLocalMatrix brings Matrix and its decomposition back in sync after
+// the animations changed one of them
+func (this *Bone) _updateLocalMatrix() {
+	if !this.Position.Equals(this._cachedPosition) || !this.RotationQuaternion.Equals(this._cachedRotation) || !this.Scaling.Equals(this._cachedScaling) {
+		this.Matrix = math32.NewMatrix4().Compose(this.Scaling, this.RotationQuaternion, this.Position)
+		this._cache()
+	} else if !this.Matrix.Equals(this._cachedMatrix) {
+		this._setMatrix(this.Matrix)
+	}
+}
+
+// _computeAbsoluteTransform updates the current pose of the bone, its parent
+// must be up to date
+func (this *Bone) _computeAbsoluteTransform() {
+	this._updateLocalMatrix()
+
+	if this._parent != nil {
+		this._absoluteTransform = this.Matrix.Multiply(this._parent._absoluteTransform)
+	} else {
+		this._absoluteTransform = this.Matrix.Clone()
+	}
+}
+
+// IAnimationTarget
+func (this *Bone) AddAnimation(val IAnimation) {
+	this._animations = append(this._animations, val)
+}
+func (this *Bone) SetAnimations(val []IAnimation) {
+	this._animations = val
+}
+func (this *Bone) GetAnimations() []IAnimation {
+	return this._animations
+}
+func (this *Bone) GetAnimatables() []IAnimationTarget {
+	return nil
+}
@@ -0,0 +1,103 @@
+package bones
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+
+	// Registers the animation parser
+	_ "github.com/suiqirui1987/fly3d/module/animations"
+)
+
+func init() {
+	engines.RegisterParser("Skeleton", parseSkeleton)
+}
+
+type boneData struct {
+	Name        string            `json:"name"`
+	ParentIndex int               `json:"parentIndex"`
+	Matrix      *math32.Matrix4   `json:"matrix"`
+	Animations  []json.RawMessage `json:"animations,omitempty"`
+}
+
+type skeletonData struct {
+	Type  string      `json:"type"`
+	Name  string      `json:"name"`
+	Id    string      `json:"id"`
+	Bones []*boneData `json:"bones"`
+}
+
+func (this *Skeleton) Serialize() interface{} {
+	data := &skeletonData{
+		Type: "Skeleton",
+		Name: this.Name,
+		Id:   this.Id,
+	}
+
+	for _, bone := range this.Bones {
+		boneContent := &boneData{
+			Name:        bone.Name,
+			ParentIndex: -1,
+			Matrix:      bone._restPose,
+		}
+		if bone._parent != nil {
+			for index, parent := range this.Bones {
+				if parent == bone._parent {
+					boneContent.ParentIndex = index
+					break
+				}
+			}
+		}
+
+		for _, animation := range bone._animations {
+			if content := engines.SerializeObject(animation); content != nil {
+				boneContent.Animations = append(boneContent.Animations, content)
+			}
+		}
+
+		data.Bones = append(data.Bones, boneContent)
+	}
+
+	return data
+}
+
+func parseSkeleton(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &skeletonData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	skeleton := NewSkeleton(data.Name, data.Id, scene)
+
+	for index, boneContent := range data.Bones {
+		var parent *Bone
+		if boneContent.ParentIndex > -1 {
+			if boneContent.ParentIndex >= index {
+				return nil, fmt.Errorf("skeleton %s: bone %s has an invalid parent %d", data.Name, boneContent.Name, boneContent.ParentIndex)
+			}
+			parent = skeleton.Bones[boneContent.ParentIndex]
+		}
+
+		matrix := boneContent.Matrix
+		if matrix == nil {
+			matrix = math32.NewMatrix4().Identity()
+		}
+
+		bone := NewBone(boneContent.Name, skeleton, parent, matrix)
+
+		for _, animationContent := range boneContent.Animations {
+			object, err := engines.ParseObject(animationContent, scene)
+			if err != nil {
+				return nil, fmt.Errorf("skeleton %s: %s", data.Name, err)
+			}
+			if animation, ok := object.(IAnimation); ok {
+				bone.AddAnimation(animation)
+			}
+		}
+	}
+
+	return skeleton, nil
+}
@@ -0,0 +1,105 @@
+package bones
+
+import (
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/tools"
+)
+
+// Skeleton is a hierarchy of bones deforming the meshes it is attached to.
+// Its animations are the animations of its bones
+type Skeleton struct {
+	Name  string
+	Id    string
+	Bones []*Bone
+
+	_scene *engines.Scene
+
+	_transformMatrices []float32
+}
+
+func NewSkeleton(name string, id string, scene *engines.Scene) *Skeleton {
+	this := &Skeleton{}
+	this.Name = name
+	this.Id = id
+	this.Bones = make([]*Bone, 0)
+	this._scene = scene
+
+	scene.Skeletons = append(scene.Skeletons, this)
+
+	return this
+}
+
+func (this *Skeleton) GetId() string {
+	return this.Id
+}
+
+func (this *Skeleton) GetName() string {
+	return this.Name
+}
+
+func (this *Skeleton) GetScene() *engines.Scene {
+	return this._scene
+}
+
+func (this *Skeleton) GetBonesCount() int {
+	return len(this.Bones)
+}
+
+func (this *Skeleton) GetBoneByName(name string) *Bone {
+	for _, bone := range this.Bones {
+		if bone.Name == name {
+			return bone
+		}
+	}
+	return nil
+}
+
+// GetTransformMatrices returns the skinning matrices of the bones, 16 floats
+// per bone, taking the vertices from the bind pose to the current pose
+func (this *Skeleton) GetTransformMatrices() []float32 {
+	if len(this._transformMatrices) != 16*len(this.Bones) {
+		this.Prepare()
+	}
+	return this._transformMatrices
+}
+
+// Prepare computes the current pose, the scene calls it once per frame after
+// the animations
+func (this *Skeleton) Prepare() {
+	if len(this._transformMatrices) != 16*len(this.Bones) {
+		this._transformMatrices = make([]float32, 16*len(this.Bones))
+	}
+
+	// Parents are always before their children
+	for index, bone := range this.Bones {
+		bone._computeAbsoluteTransform()
+		bone._invertedAbsoluteTransform.Multiply(bone._absoluteTransform).ToArray(this._transformMatrices, index*16)
+	}
+}
+
+// ReturnToRest sets all the bones back to the bind pose
+func (this *Skeleton) ReturnToRest() {
+	for _, bone := range this.Bones {
+		bone.ReturnToRest()
+	}
+}
+
+func (this *Skeleton) Dispose() {
+	index := tools.IndexOf(this, this._scene.Skeletons)
+	if index > -1 {
+		this._scene.Skeletons = append(this._scene.Skeletons[:index], this._scene.Skeletons[index+1:]...)
+	}
+}
+
+//IAnimationTarget
+func (this *Skeleton) GetAnimations() []IAnimation {
+	return nil
+}
+func (this *Skeleton) GetAnimatables() []IAnimationTarget {
+	animatables := make([]IAnimationTarget, len(this.Bones))
+	for index, bone := range this.Bones {
+		animatables[index] = bone
+	}
+	return animatables
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/suiqirui1987/fly3d/engines"
 	"github.com/suiqirui1987/fly3d/math32"
 	"github.com/suiqirui1987/fly3d/tools"
-	"github.com/suiqirui1987/fly3d/windows"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 )
 
 type IArcRotateCameraTarget interface {
@@ -67,12 +67,12 @@ func (this *ArcRotateCamera) Init(name string, alpha, beta, radius float32, targ
 
 }
 
-func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
+func (this *ArcRotateCamera) AttachControl(win canvas.IWindow) {
 
 	var previousPosition *math32.Vector2
 	that := this
 
-	_onPointerDown := func(evt *windows.MouseEvent) error {
+	_onPointerDown := func(evt *canvas.MouseEvent) error {
 
 		previousPosition = math32.NewVector2(evt.ClientX, evt.ClientY)
 
@@ -80,13 +80,13 @@ func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onPointerUp := func(evt *windows.MouseEvent) error {
+	_onPointerUp := func(evt *canvas.MouseEvent) error {
 		previousPosition = nil
 		evt.StopPropagation()
 		return nil
 	}
 
-	_onPointerMove := func(evt *windows.MouseEvent) error {
+	_onPointerMove := func(evt *canvas.MouseEvent) error {
 		if previousPosition == nil {
 			return nil
 		}
@@ -104,7 +104,7 @@ func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_wheel := func(evt *windows.WheelEvent) error {
+	_wheel := func(evt *canvas.WheelEvent) error {
 		var delta float32
 		delta = 0.0
 		if evt.DeltaX != 0.0 {
@@ -120,7 +120,7 @@ func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onKeyDown := func(evt *windows.KeyboardEvent) error {
+	_onKeyDown := func(evt *canvas.KeyboardEvent) error {
 		if tools.IndexOf(evt.CharCode, this.KeysUp) != -1 ||
 			tools.IndexOf(evt.CharCode, this.KeysDown) != -1 ||
 			tools.IndexOf(evt.CharCode, this.KeysLeft) != -1 ||
@@ -136,7 +136,7 @@ func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onKeyUp := func(evt *windows.KeyboardEvent) error {
+	_onKeyUp := func(evt *canvas.KeyboardEvent) error {
 		if tools.IndexOf(evt.CharCode, this.KeysUp) != -1 ||
 			tools.IndexOf(evt.CharCode, this.KeysDown) != -1 ||
 			tools.IndexOf(evt.CharCode, this.KeysLeft) != -1 ||
@@ -153,7 +153,7 @@ func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onLostFocus := func(evt *windows.FocusEvent) error {
+	_onLostFocus := func(evt *canvas.FocusEvent) error {
 		if evt.Focused == false {
 			that._keys = make([]string, 0)
 		}
@@ -161,29 +161,29 @@ func (this *ArcRotateCamera) AttachControl(win windows.IWindow) {
 	}
 
 	// Subscribe to events
-	win.On(windows.MouseDown, _onPointerDown)
-	win.On(windows.MouseUp, _onPointerUp)
-	win.On(windows.MouseOut, _onPointerUp)
-	win.On(windows.MouseMove, _onPointerMove)
+	win.On(canvas.MouseDown, _onPointerDown)
+	win.On(canvas.MouseUp, _onPointerUp)
+	win.On(canvas.MouseOut, _onPointerUp)
+	win.On(canvas.MouseMove, _onPointerMove)
 
-	win.On(windows.Wheel, _wheel)
+	win.On(canvas.Wheel, _wheel)
 
-	win.On(windows.Keydown, _onKeyDown)
-	win.On(windows.Keyup, _onKeyUp)
-	win.On(windows.Focus, _onLostFocus)
+	win.On(canvas.Keydown, _onKeyDown)
+	win.On(canvas.Keyup, _onKeyUp)
+	win.On(canvas.Focus, _onLostFocus)
 }
 
-func (this *ArcRotateCamera) DetachControl(win windows.IWindow) {
-	win.Remove(windows.MouseDown)
-	win.Remove(windows.MouseUp)
-	win.Remove(windows.MouseOut)
-	win.Remove(windows.MouseMove)
+func (this *ArcRotateCamera) DetachControl(win canvas.IWindow) {
+	win.Remove(canvas.MouseDown)
+	win.Remove(canvas.MouseUp)
+	win.Remove(canvas.MouseOut)
+	win.Remove(canvas.MouseMove)
 
-	win.Remove(windows.Wheel)
+	win.Remove(canvas.Wheel)
 
-	win.Remove(windows.Keydown)
-	win.Remove(windows.Keyup)
-	win.Remove(windows.Focus)
+	win.Remove(canvas.Keydown)
+	win.Remove(canvas.Keyup)
+	win.Remove(canvas.Focus)
 }
 
 func (this *ArcRotateCamera) Update() {
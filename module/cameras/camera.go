@@ -3,7 +3,7 @@ package cameras
 import (
 	"github.com/suiqirui1987/fly3d/engines"
 	"github.com/suiqirui1987/fly3d/math32"
-	"github.com/suiqirui1987/fly3d/windows"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 )
 
 type Camera struct {
@@ -68,11 +68,11 @@ func (this *Camera) GetId() string {
 func (this *Camera) GetPosition() *math32.Vector3 {
 	return this.Position
 }
-func (this *Camera) AttachControl(win windows.IWindow) {
+func (this *Camera) AttachControl(win canvas.IWindow) {
 
 }
 
-func (this *Camera) DetachControl(win windows.IWindow) {
+func (this *Camera) DetachControl(win canvas.IWindow) {
 
 }
 
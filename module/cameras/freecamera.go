@@ -10,7 +10,7 @@ import (
 	"github.com/suiqirui1987/fly3d/math32"
 	"github.com/suiqirui1987/fly3d/module/collisions"
 	"github.com/suiqirui1987/fly3d/tools"
-	"github.com/suiqirui1987/fly3d/windows"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 
 	log "github.com/suiqirui1987/fly3d/tools/logrus"
 )
@@ -89,7 +89,8 @@ func (this *FreeCamera) Init(name string, position *math32.Vector3, scene *engin
 }
 
 func (this *FreeCamera) _computeLocalCameraSpeed() float32 {
-	return this.Speed * (tools.GetDeltaTime() / (tools.GetFps() * 10.0))
+	clock := this._scene.GetClock()
+	return this.Speed * (clock.GetDeltaTime() / (clock.GetFps() * 10.0))
 }
 
 //
@@ -113,11 +114,11 @@ func (this *FreeCamera) SetTarget(target *math32.Vector3) {
 
 }
 
-func (this *FreeCamera) AttachControl(win windows.IWindow) {
+func (this *FreeCamera) AttachControl(win canvas.IWindow) {
 	var previousPosition *math32.Vector2
 	that := this
 
-	_onPointerDown := func(evt *windows.MouseEvent) error {
+	_onPointerDown := func(evt *canvas.MouseEvent) error {
 
 		previousPosition = math32.NewVector2(evt.ClientX, evt.ClientY)
 
@@ -125,20 +126,20 @@ func (this *FreeCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onPointerUp := func(evt *windows.MouseEvent) error {
+	_onPointerUp := func(evt *canvas.MouseEvent) error {
 		previousPosition = nil
 		evt.StopPropagation()
 		return nil
 	}
 
-	_onPointerOut := func(evt *windows.MouseEvent) error {
+	_onPointerOut := func(evt *canvas.MouseEvent) error {
 		previousPosition = nil
 		that._keys = make([]string, 0)
 		evt.StopPropagation()
 		return nil
 	}
 
-	_onPointerMove := func(evt *windows.MouseEvent) error {
+	_onPointerMove := func(evt *canvas.MouseEvent) error {
 		if previousPosition == nil {
 			return nil
 		}
@@ -156,7 +157,7 @@ func (this *FreeCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onKeyDown := func(evt *windows.KeyboardEvent) error {
+	_onKeyDown := func(evt *canvas.KeyboardEvent) error {
 		log.Printf("FreeCamera onKeyDown %d, %s", evt.KeyCode, evt.CharCode)
 		if tools.IndexOf(evt.CharCode, this.KeysUp) != -1 ||
 			tools.IndexOf(evt.CharCode, this.KeysDown) != -1 ||
@@ -173,7 +174,7 @@ func (this *FreeCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onKeyUp := func(evt *windows.KeyboardEvent) error {
+	_onKeyUp := func(evt *canvas.KeyboardEvent) error {
 		log.Printf("FreeCamera onKeyUp %d, %s", evt.KeyCode, evt.CharCode)
 		if tools.IndexOf(evt.CharCode, this.KeysUp) != -1 ||
 			tools.IndexOf(evt.CharCode, this.KeysDown) != -1 ||
@@ -191,7 +192,7 @@ func (this *FreeCamera) AttachControl(win windows.IWindow) {
 		return nil
 	}
 
-	_onLostFocus := func(evt *windows.FocusEvent) error {
+	_onLostFocus := func(evt *canvas.FocusEvent) error {
 		if evt.Focused == false {
 			that._keys = make([]string, 0)
 		}
@@ -199,25 +200,25 @@ func (this *FreeCamera) AttachControl(win windows.IWindow) {
 	}
 
 	// Subscribe to events
-	win.On(windows.MouseDown, _onPointerDown)
-	win.On(windows.MouseUp, _onPointerUp)
-	win.On(windows.MouseOut, _onPointerOut)
-	win.On(windows.MouseMove, _onPointerMove)
-
-	win.On(windows.Keydown, _onKeyDown)
-	win.On(windows.Keyup, _onKeyUp)
-	win.On(windows.Focus, _onLostFocus)
+	win.On(canvas.MouseDown, _onPointerDown)
+	win.On(canvas.MouseUp, _onPointerUp)
+	win.On(canvas.MouseOut, _onPointerOut)
+	win.On(canvas.MouseMove, _onPointerMove)
+
+	win.On(canvas.Keydown, _onKeyDown)
+	win.On(canvas.Keyup, _onKeyUp)
+	win.On(canvas.Focus, _onLostFocus)
 }
 
-func (this *FreeCamera) DetachControl(win windows.IWindow) {
-	win.Remove(windows.MouseDown)
-	win.Remove(windows.MouseUp)
-	win.Remove(windows.MouseOut)
-	win.Remove(windows.MouseMove)
+func (this *FreeCamera) DetachControl(win canvas.IWindow) {
+	win.Remove(canvas.MouseDown)
+	win.Remove(canvas.MouseUp)
+	win.Remove(canvas.MouseOut)
+	win.Remove(canvas.MouseMove)
 
-	win.Remove(windows.Keydown)
-	win.Remove(windows.Keyup)
-	win.Remove(windows.Focus)
+	win.Remove(canvas.Keydown)
+	win.Remove(canvas.Keyup)
+	win.Remove(canvas.Focus)
 }
 
 func (this *FreeCamera) _collideWithWorld(velocity *math32.Vector3) {
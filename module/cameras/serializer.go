@@ -0,0 +1,167 @@
+package cameras
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+func init() {
+	engines.RegisterParser("FreeCamera", parseFreeCamera)
+	engines.RegisterParser("ArcRotateCamera", parseArcRotateCamera)
+}
+
+type cameraData struct {
+	Type     string          `json:"type"`
+	Name     string          `json:"name"`
+	Id       string          `json:"id"`
+	Position *math32.Vector3 `json:"position"`
+
+	Fov         float32 `json:"fov"`
+	OrthoLeft   float32 `json:"orthoLeft"`
+	OrthoRight  float32 `json:"orthoRight"`
+	OrthoBottom float32 `json:"orthoBottom"`
+	OrthoTop    float32 `json:"orthoTop"`
+	MinZ        float32 `json:"minZ"`
+	MaxZ        float32 `json:"maxZ"`
+	Inertia     float32 `json:"inertia"`
+	Mode        int     `json:"mode"`
+}
+
+type freeCameraData struct {
+	cameraData
+
+	Rotation        *math32.Vector3 `json:"rotation"`
+	Ellipsoid       *math32.Vector3 `json:"ellipsoid"`
+	Speed           float32         `json:"speed"`
+	CheckCollisions bool            `json:"checkCollisions"`
+	ApplyGravity    bool            `json:"applyGravity"`
+}
+
+type arcRotateCameraData struct {
+	cameraData
+
+	Alpha  float32         `json:"alpha"`
+	Beta   float32         `json:"beta"`
+	Radius float32         `json:"radius"`
+	Target *math32.Vector3 `json:"target"`
+	// LockedTargetId is the mesh whose position is the target
+	LockedTargetId string `json:"lockedTargetId,omitempty"`
+}
+
+func (this *Camera) _serialize(typeName string) cameraData {
+	return cameraData{
+		Type:     typeName,
+		Name:     this.Name,
+		Id:       this.Id,
+		Position: this.Position,
+
+		Fov:         this.Fov,
+		OrthoLeft:   this.OrthoLeft,
+		OrthoRight:  this.OrthoRight,
+		OrthoBottom: this.OrthoBottom,
+		OrthoTop:    this.OrthoTop,
+		MinZ:        this.MinZ,
+		MaxZ:        this.MaxZ,
+		Inertia:     this.Inertia,
+		Mode:        this.Mode,
+	}
+}
+
+func (this *Camera) _parse(data *cameraData) {
+	this.Id = data.Id
+
+	this.Fov = data.Fov
+	this.OrthoLeft = data.OrthoLeft
+	this.OrthoRight = data.OrthoRight
+	this.OrthoBottom = data.OrthoBottom
+	this.OrthoTop = data.OrthoTop
+	this.MinZ = data.MinZ
+	this.MaxZ = data.MaxZ
+	this.Inertia = data.Inertia
+	this.Mode = data.Mode
+}
+
+func (this *FreeCamera) Serialize() interface{} {
+	return &freeCameraData{
+		cameraData: this._serialize("FreeCamera"),
+
+		Rotation:        this.Rotation,
+		Ellipsoid:       this.Ellipsoid,
+		Speed:           this.Speed,
+		CheckCollisions: this.CheckCollisions,
+		ApplyGravity:    this.ApplyGravity,
+	}
+}
+
+func (this *ArcRotateCamera) Serialize() interface{} {
+	data := &arcRotateCameraData{
+		cameraData: this._serialize("ArcRotateCamera"),
+
+		Alpha:  this.Alpha,
+		Beta:   this.Beta,
+		Radius: this.Radius,
+		Target: this.Target.GetPostion(),
+	}
+
+	for _, mesh := range this._scene.Meshes {
+		if mesh.GetPosition() == data.Target {
+			data.LockedTargetId = mesh.GetId()
+			break
+		}
+	}
+
+	return data
+}
+
+func parseFreeCamera(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &freeCameraData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	position := data.Position
+	if position == nil {
+		position = math32.NewVector3Zero()
+	}
+
+	camera := NewFreeCamera(data.Name, position, scene)
+	camera._parse(&data.cameraData)
+	if data.Rotation != nil {
+		camera.Rotation = data.Rotation
+	}
+	if data.Ellipsoid != nil {
+		camera.Ellipsoid = data.Ellipsoid
+	}
+	camera.Speed = data.Speed
+	camera.CheckCollisions = data.CheckCollisions
+	camera.ApplyGravity = data.ApplyGravity
+
+	return camera, nil
+}
+
+func parseArcRotateCamera(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &arcRotateCameraData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	target := data.Target
+	if data.LockedTargetId != "" {
+		mesh := scene.GetMeshByID(data.LockedTargetId)
+		if mesh == nil {
+			return nil, fmt.Errorf("camera %s: target %s not found", data.Name, data.LockedTargetId)
+		}
+		target = mesh.GetPosition()
+	}
+	if target == nil {
+		target = math32.NewVector3Zero()
+	}
+
+	camera := NewArcRotateCamera(data.Name, data.Alpha, data.Beta, data.Radius, target, scene)
+	camera._parse(&data.cameraData)
+
+	return camera, nil
+}
@@ -369,11 +369,11 @@ func (this *Collider) CanDoCollision(sphereCenter *math32.Vector3, sphereRadius
 
 }
 
-func (this *Collider) Collide(subMesh ISubMesh, pts []*math32.Vector3, indices []uint16, indexStart int, indexEnd int, decal int) {
+func (this *Collider) Collide(subMesh ISubMesh, pts []*math32.Vector3, indices []uint32, indexStart int, indexEnd int, decal int) {
 	for i := indexStart; i < indexEnd; i += 3 {
-		p1 := pts[indices[i]-uint16(decal)]
-		p2 := pts[indices[i+1]-uint16(decal)]
-		p3 := pts[indices[i+2]-uint16(decal)]
+		p1 := pts[indices[i]-uint32(decal)]
+		p2 := pts[indices[i+1]-uint32(decal)]
+		p3 := pts[indices[i+2]-uint32(decal)]
 
 		this.TestTriangle(subMesh, p3, p2, p1)
 	}
@@ -0,0 +1,309 @@
+package csg
+
+import (
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+// epsilon is the thickness of the planes, points closer than it to a plane
+// are on the plane
+const epsilon = 1e-5
+
+const (
+	coplanar = 0
+	front    = 1
+	back     = 2
+	spanning = 3
+)
+
+// Vertex is a point of a polygon with the attributes interpolated when the
+// polygon is split
+type Vertex struct {
+	Position *math32.Vector3
+	Normal   *math32.Vector3
+	UV       *math32.Vector2
+}
+
+func NewVertex(position *math32.Vector3, normal *math32.Vector3, uv *math32.Vector2) *Vertex {
+	return &Vertex{Position: position, Normal: normal, UV: uv}
+}
+
+func (this *Vertex) Clone() *Vertex {
+	return NewVertex(this.Position.Clone(), this.Normal.Clone(), math32.NewVector2(this.UV.X, this.UV.Y))
+}
+
+// Flip makes the normal point the other way
+func (this *Vertex) Flip() {
+	this.Normal = this.Normal.Negate()
+}
+
+// Interpolate returns the vertex at amount between this one and other
+func (this *Vertex) Interpolate(other *Vertex, amount float32) *Vertex {
+	return NewVertex(this.Position.Lerp(other.Position, amount), this.Normal.Lerp(other.Normal, amount), this.UV.Lerp(other.UV, amount))
+}
+
+// Plane is the plane of a polygon, the normal points to the front side
+type Plane struct {
+	Normal *math32.Vector3
+	W      float32
+}
+
+// NewPlaneFromPoints returns nil when the points are aligned
+func NewPlaneFromPoints(a, b, c *math32.Vector3) *Plane {
+	normal := b.Sub(a).Cross(c.Sub(a))
+	if normal.LengthSq() == 0 {
+		return nil
+	}
+	normal.Normalize()
+
+	return &Plane{Normal: normal, W: normal.Dot(a)}
+}
+
+func (this *Plane) Clone() *Plane {
+	return &Plane{Normal: this.Normal.Clone(), W: this.W}
+}
+
+func (this *Plane) Flip() {
+	this.Normal = this.Normal.Negate()
+	this.W = -this.W
+}
+
+// SplitPolygon puts the polygon, or its parts when the plane cuts it, in the
+// lists matching their side of the plane. The polygons on the plane go to
+// coplanarFront or coplanarBack depending on their orientation
+func (this *Plane) SplitPolygon(polygon *Polygon, coplanarFront, coplanarBack, frontPolygons, backPolygons *[]*Polygon) {
+	polygonType := coplanar
+	types := make([]int, len(polygon.Vertices))
+
+	for index, vertex := range polygon.Vertices {
+		distance := this.Normal.Dot(vertex.Position) - this.W
+		vertexType := coplanar
+		if distance < -epsilon {
+			vertexType = back
+		} else if distance > epsilon {
+			vertexType = front
+		}
+		polygonType |= vertexType
+		types[index] = vertexType
+	}
+
+	switch polygonType {
+	case coplanar:
+		if this.Normal.Dot(polygon.Plane.Normal) > 0 {
+			*coplanarFront = append(*coplanarFront, polygon)
+		} else {
+			*coplanarBack = append(*coplanarBack, polygon)
+		}
+	case front:
+		*frontPolygons = append(*frontPolygons, polygon)
+	case back:
+		*backPolygons = append(*backPolygons, polygon)
+	case spanning:
+		frontVertices := make([]*Vertex, 0)
+		backVertices := make([]*Vertex, 0)
+
+		for i := range polygon.Vertices {
+			j := (i + 1) % len(polygon.Vertices)
+			ti, tj := types[i], types[j]
+			vi, vj := polygon.Vertices[i], polygon.Vertices[j]
+
+			if ti != back {
+				frontVertices = append(frontVertices, vi)
+			}
+			if ti != front {
+				if ti != back {
+					vi = vi.Clone()
+				}
+				backVertices = append(backVertices, vi)
+			}
+			if ti|tj == spanning {
+				amount := (this.W - this.Normal.Dot(vi.Position)) / this.Normal.Dot(vj.Position.Sub(vi.Position))
+				vertex := vi.Interpolate(vj, amount)
+				frontVertices = append(frontVertices, vertex)
+				backVertices = append(backVertices, vertex.Clone())
+			}
+		}
+
+		if len(frontVertices) >= 3 {
+			if split := NewPolygon(frontVertices, polygon.Material); split != nil {
+				*frontPolygons = append(*frontPolygons, split)
+			}
+		}
+		if len(backVertices) >= 3 {
+			if split := NewPolygon(backVertices, polygon.Material); split != nil {
+				*backPolygons = append(*backPolygons, split)
+			}
+		}
+	}
+}
+
+// Polygon is a convex polygon whose front face is counter clockwise seen
+// from the side its plane normal points to
+type Polygon struct {
+	Vertices []*Vertex
+	Plane    *Plane
+	Material IMaterial
+}
+
+// NewPolygon returns nil for degenerate polygons
+func NewPolygon(vertices []*Vertex, material IMaterial) *Polygon {
+	plane := NewPlaneFromPoints(vertices[0].Position, vertices[1].Position, vertices[2].Position)
+	if plane == nil {
+		return nil
+	}
+
+	return &Polygon{Vertices: vertices, Plane: plane, Material: material}
+}
+
+func (this *Polygon) Clone() *Polygon {
+	vertices := make([]*Vertex, len(this.Vertices))
+	for index, vertex := range this.Vertices {
+		vertices[index] = vertex.Clone()
+	}
+
+	return &Polygon{Vertices: vertices, Plane: this.Plane.Clone(), Material: this.Material}
+}
+
+// Flip turns the polygon inside out
+func (this *Polygon) Flip() {
+	for i, j := 0, len(this.Vertices)-1; i < j; i, j = i+1, j-1 {
+		this.Vertices[i], this.Vertices[j] = this.Vertices[j], this.Vertices[i]
+	}
+	for _, vertex := range this.Vertices {
+		vertex.Flip()
+	}
+	this.Plane.Flip()
+}
+
+// Node is a node of a BSP tree, the polygons of the front subtree are in
+// front of the plane and those of the back subtree behind it
+type Node struct {
+	Plane    *Plane
+	Front    *Node
+	Back     *Node
+	Polygons []*Polygon
+}
+
+func NewNode(polygons []*Polygon) *Node {
+	this := &Node{}
+	if len(polygons) > 0 {
+		this.Build(polygons)
+	}
+
+	return this
+}
+
+func (this *Node) Clone() *Node {
+	node := &Node{}
+	if this.Plane != nil {
+		node.Plane = this.Plane.Clone()
+	}
+	if this.Front != nil {
+		node.Front = this.Front.Clone()
+	}
+	if this.Back != nil {
+		node.Back = this.Back.Clone()
+	}
+	node.Polygons = make([]*Polygon, len(this.Polygons))
+	for index, polygon := range this.Polygons {
+		node.Polygons[index] = polygon.Clone()
+	}
+
+	return node
+}
+
+// Invert swaps the solid and the empty space
+func (this *Node) Invert() {
+	for _, polygon := range this.Polygons {
+		polygon.Flip()
+	}
+	if this.Plane != nil {
+		this.Plane.Flip()
+	}
+	if this.Front != nil {
+		this.Front.Invert()
+	}
+	if this.Back != nil {
+		this.Back.Invert()
+	}
+	this.Front, this.Back = this.Back, this.Front
+}
+
+// ClipPolygons returns the parts of the polygons outside of the solid of the
+// tree
+func (this *Node) ClipPolygons(polygons []*Polygon) []*Polygon {
+	if this.Plane == nil {
+		return append([]*Polygon(nil), polygons...)
+	}
+
+	frontPolygons := make([]*Polygon, 0)
+	backPolygons := make([]*Polygon, 0)
+	for _, polygon := range polygons {
+		this.Plane.SplitPolygon(polygon, &frontPolygons, &backPolygons, &frontPolygons, &backPolygons)
+	}
+
+	if this.Front != nil {
+		frontPolygons = this.Front.ClipPolygons(frontPolygons)
+	}
+	if this.Back != nil {
+		backPolygons = this.Back.ClipPolygons(backPolygons)
+	} else {
+		backPolygons = nil
+	}
+
+	return append(frontPolygons, backPolygons...)
+}
+
+// ClipTo removes the parts of the polygons of this tree inside the solid of
+// the other one
+func (this *Node) ClipTo(node *Node) {
+	this.Polygons = node.ClipPolygons(this.Polygons)
+	if this.Front != nil {
+		this.Front.ClipTo(node)
+	}
+	if this.Back != nil {
+		this.Back.ClipTo(node)
+	}
+}
+
+func (this *Node) AllPolygons() []*Polygon {
+	polygons := append([]*Polygon(nil), this.Polygons...)
+	if this.Front != nil {
+		polygons = append(polygons, this.Front.AllPolygons()...)
+	}
+	if this.Back != nil {
+		polygons = append(polygons, this.Back.AllPolygons()...)
+	}
+
+	return polygons
+}
+
+// Build adds the polygons to the tree, the first polygon of a node gives its
+// plane
+func (this *Node) Build(polygons []*Polygon) {
+	if len(polygons) == 0 {
+		return
+	}
+	if this.Plane == nil {
+		this.Plane = polygons[0].Plane.Clone()
+	}
+
+	frontPolygons := make([]*Polygon, 0)
+	backPolygons := make([]*Polygon, 0)
+	for _, polygon := range polygons {
+		this.Plane.SplitPolygon(polygon, &this.Polygons, &this.Polygons, &frontPolygons, &backPolygons)
+	}
+
+	if len(frontPolygons) > 0 {
+		if this.Front == nil {
+			this.Front = &Node{}
+		}
+		this.Front.Build(frontPolygons)
+	}
+	if len(backPolygons) > 0 {
+		if this.Back == nil {
+			this.Back = &Node{}
+		}
+		this.Back.Build(backPolygons)
+	}
+}
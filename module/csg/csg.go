@@ -0,0 +1,296 @@
+package csg
+
+import (
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// CSG is a solid made of the world space polygons of meshes. The boolean
+// operations return new solids and leave their operands untouched
+type CSG struct {
+	Polygons []*Polygon
+
+	// Transform of the mesh the solid comes from, ToMesh gives it back to
+	// the new mesh
+	_matrix             *math32.Matrix4
+	_position           *math32.Vector3
+	_rotation           *math32.Vector3
+	_rotationQuaternion *math32.Quaternion
+	_scaling            *math32.Vector3
+	_parent             *meshs.Mesh
+}
+
+func newCSG(polygons []*Polygon, source *CSG) *CSG {
+	this := &CSG{}
+	this.Polygons = polygons
+
+	this._matrix = source._matrix
+	this._position = source._position
+	this._rotation = source._rotation
+	this._rotationQuaternion = source._rotationQuaternion
+	this._scaling = source._scaling
+	this._parent = source._parent
+
+	return this
+}
+
+// FromMesh builds the solid of the mesh. Its faces must enclose a volume
+func FromMesh(mesh *meshs.Mesh) *CSG {
+	this := &CSG{}
+	this.Polygons = make([]*Polygon, 0)
+
+	mesh.ComputeWorldMatrix()
+	world := mesh.GetWorldMatrix()
+
+	this._matrix = world.Clone()
+	this._position = mesh.Position.Clone()
+	this._rotation = mesh.Rotation.Clone()
+	if mesh.RotationQuaternion != nil {
+		this._rotationQuaternion = mesh.RotationQuaternion.Clone()
+	}
+	this._scaling = mesh.Scaling.Clone()
+	this._parent = mesh.Parent
+
+	positions := mesh.GetVerticesData(IMesh_VB_PositionKind)
+	if positions == nil {
+		return this
+	}
+	indices := mesh.GetIndices()
+	normals := mesh.GetVerticesData(IMesh_VB_NormalKind)
+	if normals == nil {
+		normals = meshs.ComputeNormals(positions, indices)
+	}
+	uvs := mesh.GetVerticesData(IMesh_VB_UVKind)
+
+	for _, subMesh := range mesh.SubMeshes {
+		var material IMaterial
+		if mesh.MutilMaterial != nil {
+			material = mesh.MutilMaterial.GetSubMaterial(subMesh.GetMaterialIndex())
+		} else {
+			material = mesh.Material
+		}
+
+		start := subMesh.GetIndexStart()
+		for index := start; index+2 < start+subMesh.GetIndexCount(); index += 3 {
+			// The front faces of the meshes are clockwise, the polygons are
+			// counter clockwise
+			vertices := make([]*Vertex, 0, 3)
+			for corner := 2; corner >= 0; corner-- {
+				vertex := int(indices[index+corner])
+
+				position := math32.NewVector3Zero().TransformCoordinatesFromFloats(positions[vertex*3], positions[vertex*3+1], positions[vertex*3+2], world)
+				normal := math32.NewVector3Zero().TransformNormalFromFloats(normals[vertex*3], normals[vertex*3+1], normals[vertex*3+2], world)
+				normal.Normalize()
+				uv := math32.NewVector2Zero()
+				if uvs != nil {
+					uv = math32.NewVector2(uvs[vertex*2], uvs[vertex*2+1])
+				}
+
+				vertices = append(vertices, NewVertex(position, normal, uv))
+			}
+
+			if polygon := NewPolygon(vertices, material); polygon != nil {
+				this.Polygons = append(this.Polygons, polygon)
+			}
+		}
+	}
+
+	return this
+}
+
+func (this *CSG) Clone() *CSG {
+	polygons := make([]*Polygon, len(this.Polygons))
+	for index, polygon := range this.Polygons {
+		polygons[index] = polygon.Clone()
+	}
+
+	return newCSG(polygons, this)
+}
+
+// Union returns the space filled by either solid
+func (this *CSG) Union(csg *CSG) *CSG {
+	a := NewNode(this.Clone().Polygons)
+	b := NewNode(csg.Clone().Polygons)
+
+	a.ClipTo(b)
+	b.ClipTo(a)
+	b.Invert()
+	b.ClipTo(a)
+	b.Invert()
+	a.Build(b.AllPolygons())
+
+	return newCSG(a.AllPolygons(), this)
+}
+
+// Subtract returns the space of this solid outside of the other one
+func (this *CSG) Subtract(csg *CSG) *CSG {
+	a := NewNode(this.Clone().Polygons)
+	b := NewNode(csg.Clone().Polygons)
+
+	a.Invert()
+	a.ClipTo(b)
+	b.ClipTo(a)
+	b.Invert()
+	b.ClipTo(a)
+	b.Invert()
+	a.Build(b.AllPolygons())
+	a.Invert()
+
+	return newCSG(a.AllPolygons(), this)
+}
+
+// Intersect returns the space filled by both solids
+func (this *CSG) Intersect(csg *CSG) *CSG {
+	a := NewNode(this.Clone().Polygons)
+	b := NewNode(csg.Clone().Polygons)
+
+	a.Invert()
+	b.ClipTo(a)
+	b.Invert()
+	a.ClipTo(b)
+	b.ClipTo(a)
+	a.Build(b.AllPolygons())
+	a.Invert()
+
+	return newCSG(a.AllPolygons(), this)
+}
+
+// Inverse returns the solid with the inside and the outside swapped
+func (this *CSG) Inverse() *CSG {
+	csg := this.Clone()
+	for _, polygon := range csg.Polygons {
+		polygon.Flip()
+	}
+
+	return csg
+}
+
+type vertexKey struct {
+	x, y, z    float32
+	nx, ny, nz float32
+	u, v       float32
+}
+
+// ToMesh creates a mesh of the solid with the transform of the mesh the
+// solid was made from. The polygons of each source material make a submesh,
+// the mesh gets a MultiMaterial when there are several materials
+func (this *CSG) ToMesh(name string, scene *engines.Scene) *meshs.Mesh {
+	mesh := meshs.NewMesh(name, scene)
+
+	mesh.Position = this._position.Clone()
+	mesh.Rotation = this._rotation.Clone()
+	if this._rotationQuaternion != nil {
+		mesh.RotationQuaternion = this._rotationQuaternion.Clone()
+	}
+	mesh.Scaling = this._scaling.Clone()
+	mesh.Parent = this._parent
+
+	// The polygons grouped by material, in order of appearance
+	materialList := make([]IMaterial, 0)
+	groups := make([][]*Polygon, 0)
+	for _, polygon := range this.Polygons {
+		group := -1
+		for index, material := range materialList {
+			if material == polygon.Material {
+				group = index
+				break
+			}
+		}
+		if group < 0 {
+			group = len(materialList)
+			materialList = append(materialList, polygon.Material)
+			groups = append(groups, make([]*Polygon, 0))
+		}
+		groups[group] = append(groups[group], polygon)
+	}
+
+	inverse := this._matrix.Clone()
+	inverse.Invert()
+
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+	indices := make([]uint32, 0)
+
+	type subMeshRange struct {
+		verticesStart, verticesCount, indexStart, indexCount int
+	}
+	ranges := make([]subMeshRange, 0, len(groups))
+
+	for _, group := range groups {
+		verticesStart := len(positions) / 3
+		indexStart := len(indices)
+		vertexIndices := map[vertexKey]uint32{}
+
+		addVertex := func(vertex *Vertex) uint32 {
+			position := vertex.Position.TransformCoordinates(inverse)
+			normal := vertex.Normal.TransformNormal(inverse)
+			normal.Normalize()
+
+			key := vertexKey{position.X, position.Y, position.Z, normal.X, normal.Y, normal.Z, vertex.UV.X, vertex.UV.Y}
+			if index, ok := vertexIndices[key]; ok {
+				return index
+			}
+
+			index := uint32(len(positions) / 3)
+			positions = append(positions, position.X, position.Y, position.Z)
+			normals = append(normals, normal.X, normal.Y, normal.Z)
+			uvs = append(uvs, vertex.UV.X, vertex.UV.Y)
+			vertexIndices[key] = index
+
+			return index
+		}
+
+		for _, polygon := range group {
+			// Back to the clockwise front faces of the meshes
+			for corner := 2; corner < len(polygon.Vertices); corner++ {
+				indices = append(indices,
+					addVertex(polygon.Vertices[0]),
+					addVertex(polygon.Vertices[corner]),
+					addVertex(polygon.Vertices[corner-1]))
+			}
+		}
+
+		ranges = append(ranges, subMeshRange{verticesStart, len(positions)/3 - verticesStart, indexStart, len(indices) - indexStart})
+	}
+
+	if len(indices) == 0 {
+		return mesh
+	}
+
+	mesh.SetVerticesData(positions, IMesh_VB_PositionKind, false)
+	mesh.SetVerticesData(normals, IMesh_VB_NormalKind, false)
+	mesh.SetVerticesData(uvs, IMesh_VB_UVKind, false)
+	if err := mesh.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
+
+	mesh.SubMeshes = make([]*meshs.SubMesh, 0, len(ranges))
+	for index, subMesh := range ranges {
+		meshs.NewSubMesh(index, subMesh.verticesStart, subMesh.verticesCount, subMesh.indexStart, subMesh.indexCount, mesh)
+	}
+
+	if len(materialList) == 1 {
+		mesh.Material = materialList[0]
+	} else {
+		multiMaterial := materials.NewMultiMaterial(name, scene)
+		var defaultMaterial IMaterial
+		for _, material := range materialList {
+			// Meshes without material are drawn with a default one
+			if material == nil {
+				if defaultMaterial == nil {
+					defaultMaterial = materials.NewStandardMaterial(name+".default", scene)
+				}
+				material = defaultMaterial
+			}
+			multiMaterial.SubMaterials = append(multiMaterial.SubMaterials, material)
+		}
+		mesh.MutilMaterial = multiMaterial
+	}
+
+	return mesh
+}
@@ -0,0 +1,186 @@
+// +build glnull glsoft
+
+package csg
+
+import (
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+)
+
+// volume sums the signed volumes of the tetrahedrons made by the origin and
+// the triangles of the solid, negative when the solid is inside out
+func volume(solid *CSG) float32 {
+	var result float32
+	for _, polygon := range solid.Polygons {
+		a := polygon.Vertices[0].Position
+		for corner := 2; corner < len(polygon.Vertices); corner++ {
+			b := polygon.Vertices[corner-1].Position
+			c := polygon.Vertices[corner].Position
+			result += a.Dot(b.Cross(c)) / 6
+		}
+	}
+	return result
+}
+
+func box(name string, position *math32.Vector3, scene *engines.Scene) *meshs.Mesh {
+	mesh := meshs.CreateBox(name, 2, scene, false)
+	mesh.Position = position
+	return mesh
+}
+
+func TestOperations(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	a := FromMesh(box("a", math32.NewVector3(0, 0, 0), scene))
+	b := FromMesh(box("b", math32.NewVector3(1, 0, 0), scene))
+	far := FromMesh(box("far", math32.NewVector3(5, 0, 0), scene))
+
+	tests := []struct {
+		name   string
+		solid  func() *CSG
+		volume float32
+	}{
+		{"box", func() *CSG { return a }, 8},
+		{"union", func() *CSG { return a.Union(b) }, 12},
+		{"subtract", func() *CSG { return a.Subtract(b) }, 4},
+		{"subtract reversed", func() *CSG { return b.Subtract(a) }, 4},
+		{"intersect", func() *CSG { return a.Intersect(b) }, 4},
+		{"disjoint union", func() *CSG { return a.Union(far) }, 16},
+		{"disjoint subtract", func() *CSG { return a.Subtract(far) }, 8},
+		{"disjoint intersect", func() *CSG { return a.Intersect(far) }, 0},
+		{"inverse", func() *CSG { return a.Inverse() }, -8},
+		{"subtract itself", func() *CSG { return a.Subtract(a) }, 0},
+		{"chained", func() *CSG { return a.Union(b).Subtract(far).Intersect(b) }, 8},
+	}
+
+	polygons := len(a.Polygons)
+	for _, test := range tests {
+		if got := volume(test.solid()); math32.Abs(got-test.volume) > 1e-3 {
+			t.Errorf("%s: volume %v, want %v", test.name, got, test.volume)
+		}
+	}
+
+	// The operands are left untouched
+	if len(a.Polygons) != polygons || math32.Abs(volume(a)-8) > 1e-3 || math32.Abs(volume(b)-8) > 1e-3 {
+		t.Errorf("the operations changed their operands")
+	}
+}
+
+func TestToMesh(t *testing.T) {
+	scene := enginetest.NewScene(t)
+
+	red := materials.NewStandardMaterial("red", scene)
+	blue := materials.NewStandardMaterial("blue", scene)
+
+	a := box("a", math32.NewVector3(0, 1, 0), scene)
+	a.Material = red
+	b := box("b", math32.NewVector3(1, 1, 0), scene)
+	b.Material = blue
+	c := box("c", math32.NewVector3(-1, 1, 0), scene)
+	c.Material = red
+
+	tests := []struct {
+		name      string
+		solid     *CSG
+		volume    float32
+		materials []string
+	}{
+		{"one material", FromMesh(a).Subtract(FromMesh(c)), 4, []string{"red"}},
+		{"two materials", FromMesh(a).Subtract(FromMesh(b)), 4, []string{"red", "blue"}},
+		{"empty", FromMesh(a).Intersect(FromMesh(box("far", math32.NewVector3(5, 0, 0), scene))), 0, nil},
+	}
+
+	for _, test := range tests {
+		mesh := test.solid.ToMesh(test.name, scene)
+
+		if !mesh.Position.Equals(math32.NewVector3(0, 1, 0)) {
+			t.Errorf("%s: position %v, want the position of a", test.name, mesh.Position)
+		}
+
+		// The solid of the new mesh is the one it was made of
+		if got := volume(FromMesh(mesh)); math32.Abs(got-test.volume) > 1e-3 {
+			t.Errorf("%s: mesh volume %v, want %v", test.name, got, test.volume)
+		}
+
+		names := make([]string, 0)
+		if multiMaterial, ok := mesh.MutilMaterial.(*materials.MultiMaterial); ok {
+			for _, material := range multiMaterial.SubMaterials {
+				names = append(names, material.(*materials.StandardMaterial).Name)
+			}
+		} else if material, ok := mesh.Material.(*materials.StandardMaterial); ok {
+			names = append(names, material.Name)
+		}
+		if len(names) != len(test.materials) {
+			t.Errorf("%s: materials %v, want %v", test.name, names, test.materials)
+			continue
+		}
+		for index := range names {
+			if names[index] != test.materials[index] {
+				t.Errorf("%s: materials %v, want %v", test.name, names, test.materials)
+			}
+		}
+		if len(test.materials) > 0 && len(mesh.SubMeshes) != len(test.materials) {
+			t.Errorf("%s: %d submeshes, want %d", test.name, len(mesh.SubMeshes), len(test.materials))
+		}
+	}
+}
+
+func TestSplitPolygon(t *testing.T) {
+	plane := &Plane{Normal: math32.NewVector3(0, 1, 0), W: 0}
+	triangle := func(y0, y1, y2 float32) *Polygon {
+		return NewPolygon([]*Vertex{
+			NewVertex(math32.NewVector3(0, y0, 0), math32.NewVector3(0, 0, 1), math32.NewVector2Zero()),
+			NewVertex(math32.NewVector3(1, y1, 0), math32.NewVector3(0, 0, 1), math32.NewVector2Zero()),
+			NewVertex(math32.NewVector3(0, y2, 1), math32.NewVector3(0, 0, 1), math32.NewVector2Zero()),
+		}, nil)
+	}
+
+	tests := []struct {
+		name    string
+		polygon *Polygon
+		counts  [4]int
+	}{
+		{"front", triangle(1, 1, 1), [4]int{0, 0, 1, 0}},
+		{"back", triangle(-1, -1, -1), [4]int{0, 0, 0, 1}},
+		{"spanning", triangle(1, -1, 1), [4]int{0, 0, 1, 1}},
+		{"touching", triangle(0, 1, 1), [4]int{0, 0, 1, 0}},
+		{"coplanar front", NewPolygon([]*Vertex{
+			NewVertex(math32.NewVector3(0, 0, 0), math32.NewVector3(0, 1, 0), math32.NewVector2Zero()),
+			NewVertex(math32.NewVector3(0, 0, 1), math32.NewVector3(0, 1, 0), math32.NewVector2Zero()),
+			NewVertex(math32.NewVector3(1, 0, 0), math32.NewVector3(0, 1, 0), math32.NewVector2Zero()),
+		}, nil), [4]int{1, 0, 0, 0}},
+		{"coplanar back", NewPolygon([]*Vertex{
+			NewVertex(math32.NewVector3(0, 0, 0), math32.NewVector3(0, -1, 0), math32.NewVector2Zero()),
+			NewVertex(math32.NewVector3(1, 0, 0), math32.NewVector3(0, -1, 0), math32.NewVector2Zero()),
+			NewVertex(math32.NewVector3(0, 0, 1), math32.NewVector3(0, -1, 0), math32.NewVector2Zero()),
+		}, nil), [4]int{0, 1, 0, 0}},
+	}
+
+	for _, test := range tests {
+		var coplanarFront, coplanarBack, frontPolygons, backPolygons []*Polygon
+		plane.SplitPolygon(test.polygon, &coplanarFront, &coplanarBack, &frontPolygons, &backPolygons)
+
+		counts := [4]int{len(coplanarFront), len(coplanarBack), len(frontPolygons), len(backPolygons)}
+		if counts != test.counts {
+			t.Errorf("%s: coplanar front, coplanar back, front and back %v, want %v", test.name, counts, test.counts)
+		}
+		for _, polygon := range frontPolygons {
+			for _, vertex := range polygon.Vertices {
+				if vertex.Position.Y < -epsilon {
+					t.Errorf("%s: front vertex %v behind the plane", test.name, vertex.Position)
+				}
+			}
+		}
+		for _, polygon := range backPolygons {
+			for _, vertex := range polygon.Vertices {
+				if vertex.Position.Y > epsilon {
+					t.Errorf("%s: back vertex %v in front of the plane", test.name, vertex.Position)
+				}
+			}
+		}
+	}
+}
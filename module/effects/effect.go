@@ -2,6 +2,8 @@ package effects
 
 import (
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/suiqirui1987/fly3d/core"
 	"github.com/suiqirui1987/fly3d/engines"
@@ -22,12 +24,18 @@ type Effect struct {
 	_valueCache      map[string]interface{}
 	_isReady         bool
 
+	// OnError is called with the annotated sources when the program does not
+	// compile, before the fallbacks are tried
+	OnError           func(effect *Effect, errors string)
+	_fallbacks        *EffectFallbacks
+	_compilationError string
+
 	_program    gl.Program
 	_attributes []gl.Attrib
 	_uniforms   []gl.Uniform
 }
 
-func CreateEffect(engine *engines.Engine, baseName string, attributesNames []string, uniformsNames []string, samplers []string, defines string) *Effect {
+func CreateEffect(engine *engines.Engine, baseName string, attributesNames []string, uniformsNames []string, samplers []string, defines string, fallbacks *EffectFallbacks, onError func(effect *Effect, errors string)) *Effect {
 	name := baseName + "@" + defines
 	var e *Effect
 
@@ -36,13 +44,13 @@ func CreateEffect(engine *engines.Engine, baseName string, attributesNames []str
 		return e
 	}
 
-	e = NewEffect(baseName, attributesNames, uniformsNames, samplers, engine, defines)
+	e = NewEffect(baseName, attributesNames, uniformsNames, samplers, engine, defines, fallbacks, onError)
 	engine.CompiledEffects[name] = e
 
 	return e
 }
 
-func NewEffect(baseName string, attributesNames []string, uniformsNames []string, samplers []string, engine *engines.Engine, defines string) *Effect {
+func NewEffect(baseName string, attributesNames []string, uniformsNames []string, samplers []string, engine *engines.Engine, defines string, fallbacks *EffectFallbacks, onError func(effect *Effect, errors string)) *Effect {
 
 	this := &Effect{}
 
@@ -54,6 +62,8 @@ func NewEffect(baseName string, attributesNames []string, uniformsNames []string
 	this._samplers = samplers
 	this._isReady = false
 	this._valueCache = map[string]interface{}{}
+	this._fallbacks = fallbacks
+	this.OnError = onError
 
 	// Is in local store
 	if _, ok := ShadersStore[baseName+"_vertex"]; ok {
@@ -66,6 +76,9 @@ func NewEffect(baseName string, attributesNames []string, uniformsNames []string
 		shaderUrl := core.GlobalFly3D.ResRepository + core.GlobalFly3D.ShadersRepository + baseName
 
 		that := this
+		onfail := func(err error) {
+			that._onError(err.Error(), "")
+		}
 		// Vertex shader
 		tools.LoadFile(shaderUrl+".vertex.fx",
 			func(vertexSourceCode string) {
@@ -74,9 +87,9 @@ func NewEffect(baseName string, attributesNames []string, uniformsNames []string
 					func(fragmentSourceCode string) {
 
 						that._prepareEffect(vertexSourceCode, fragmentSourceCode, attributesNames, defines)
-					}, nil)
+					}, nil, onfail)
 
-			}, nil)
+			}, nil, onfail)
 	}
 	return this
 }
@@ -125,10 +138,38 @@ func (this *Effect) GetSamplers() []string {
 	return this._samplers
 }
 
+// GetCompilationError returns the log of the last failed compilation or load,
+// kept when a fallback links
+func (this *Effect) GetCompilationError() string {
+	return this._compilationError
+}
+
 func (this *Effect) _prepareEffect(vertexSourceCode string, fragmentSourceCode string, attributesNames []string, defines string) {
 	log.Printf("start _prepareEffect \r")
 	engine := this._engine
-	this._program = engine.CreateShaderProgram(vertexSourceCode, fragmentSourceCode, defines)
+	vertexCode, err := processShader(vertexSourceCode, defines)
+	if err != nil {
+		this._onError(err.Error(), "")
+		return
+	}
+	fragmentCode, err := processShader(fragmentSourceCode, defines)
+	if err != nil {
+		this._onError(err.Error(), "")
+		return
+	}
+	program, err := engine.CreateShaderProgram(vertexCode, fragmentCode, defines)
+	if err != nil {
+		this._onError(err.Error(), annotateSources(vertexCode, fragmentCode, defines))
+
+		// Try again without the optional defines of the next rank
+		if this._fallbacks != nil && this._fallbacks.IsMoreFallbacks() {
+			this.Defines = this._fallbacks.Reduce(defines)
+			log.Printf("Trying effect %s with fallback defines", this.Name)
+			this._prepareEffect(vertexSourceCode, fragmentSourceCode, attributesNames, this.Defines)
+		}
+		return
+	}
+	this._program = program
 
 	this._uniforms = engine.GetUniforms(this._program, this._uniformsNames)
 	this._attributes = engine.GetAttributes(this._program, attributesNames)
@@ -147,6 +188,52 @@ func (this *Effect) _prepareEffect(vertexSourceCode string, fragmentSourceCode s
 	this._isReady = true
 }
 
+// _onError keeps the error of a failed compilation or load and reports it
+// with the sources
+func (this *Effect) _onError(errors string, sources string) {
+	this._compilationError = errors
+
+	if sources != "" {
+		errors = errors + "\n" + sources
+	}
+	log.Printf("Effect %s failed: %s", this.Name, errors)
+
+	if this.OnError != nil {
+		this.OnError(this, errors)
+	}
+}
+
+// annotateSources lists the defines and the sources as the compiler got them,
+// lines numbered from 1 like the compile logs
+func annotateSources(vertexCode string, fragmentCode string, defines string) string {
+	if defines != "" {
+		defines = defines + "\n"
+	}
+
+	var result strings.Builder
+	result.WriteString("Defines:\n")
+	result.WriteString(defines)
+	result.WriteString("Vertex code:\n")
+	result.WriteString(numberLines(defines + vertexCode))
+	result.WriteString("Fragment code:\n")
+	result.WriteString(numberLines(defines + fragmentCode))
+
+	return result.String()
+}
+
+func numberLines(code string) string {
+	lines := strings.Split(code, "\n")
+	width := len(strconv.Itoa(len(lines)))
+
+	var result strings.Builder
+	for index, line := range lines {
+		number := strconv.Itoa(index + 1)
+		result.WriteString(strings.Repeat(" ", width-len(number)) + number + ": " + strings.TrimRight(line, "\r") + "\n")
+	}
+
+	return result.String()
+}
+
 func (this *Effect) SetTexture(channel string, texture *gl.GLTextureBuffer) {
 	index := tools.IndexOf(channel, this._samplers)
 	if index > -1 {
@@ -168,6 +255,74 @@ func (this *Effect) SetMatrix(uniformName string, val *math32.Matrix4) {
 	log.Debugf("Effect SetMatrix %s index %s", uniformName, val.String())
 }
 
+// SetMatrices sets a mat4 array uniform, val holds 16 floats per matrix
+func (this *Effect) SetMatrices(uniformName string, val []float32) {
+	uniform_val, ok := this._valueCache[uniformName]
+	if ok && reflect.DeepEqual(uniform_val, val) {
+		return
+	}
+
+	// The caller keeps updating its slice
+	this._valueCache[uniformName] = append([]float32(nil), val...)
+	this._engine.SetMatrices(this.GetUniform(uniformName), val)
+
+	log.Debugf("Effect SetMatrices %s count %d", uniformName, len(val)/16)
+}
+
+// SetFloatArray sets a float array uniform
+func (this *Effect) SetFloatArray(uniformName string, val []float32) {
+	uniform_val, ok := this._valueCache[uniformName]
+	if ok && reflect.DeepEqual(uniform_val, val) {
+		return
+	}
+
+	// The caller keeps updating its slice
+	this._valueCache[uniformName] = append([]float32(nil), val...)
+	this._engine.SetFloatArray(this.GetUniform(uniformName), val)
+
+	log.Debugf("Effect SetFloatArray %s count %d", uniformName, len(val))
+}
+
+// SetArray3 sets a vec3 array uniform, val holds 3 floats per element
+func (this *Effect) SetArray3(uniformName string, val []float32) {
+	uniform_val, ok := this._valueCache[uniformName]
+	if ok && reflect.DeepEqual(uniform_val, val) {
+		return
+	}
+
+	// The caller keeps updating its slice
+	this._valueCache[uniformName] = append([]float32(nil), val...)
+	this._engine.SetArray3(this.GetUniform(uniformName), val)
+
+	log.Debugf("Effect SetArray3 %s count %d", uniformName, len(val)/3)
+}
+
+// SetArray4 sets a vec4 array uniform, val holds 4 floats per element
+func (this *Effect) SetArray4(uniformName string, val []float32) {
+	uniform_val, ok := this._valueCache[uniformName]
+	if ok && reflect.DeepEqual(uniform_val, val) {
+		return
+	}
+
+	// The caller keeps updating its slice
+	this._valueCache[uniformName] = append([]float32(nil), val...)
+	this._engine.SetArray4(this.GetUniform(uniformName), val)
+
+	log.Debugf("Effect SetArray4 %s count %d", uniformName, len(val)/4)
+}
+
+func (this *Effect) SetFloat(uniformName string, val float32) {
+	uniform_val, ok := this._valueCache[uniformName]
+	if ok && reflect.DeepEqual(uniform_val, val) {
+		return
+	}
+
+	this._valueCache[uniformName] = val
+	this._engine.SetFloat(this.GetUniform(uniformName), val)
+
+	log.Debugf("Effect SetFloat %s value %f ", uniformName, val)
+}
+
 func (this *Effect) SetBool(uniformName string, val bool) {
 	uniform_val, ok := this._valueCache[uniformName]
 	if ok && reflect.DeepEqual(uniform_val, val) {
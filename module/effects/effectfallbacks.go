@@ -0,0 +1,65 @@
+package effects
+
+import (
+	"strings"
+)
+
+// EffectFallbacks lists the optional defines of an effect by rank. When the
+// program does not compile or link, the defines of the lowest rank left are
+// dropped and the effect tries again
+type EffectFallbacks struct {
+	_defines     map[int][]string
+	_currentRank int
+	_maxRank     int
+}
+
+func NewEffectFallbacks() *EffectFallbacks {
+	this := &EffectFallbacks{}
+
+	this._defines = map[int][]string{}
+	this._currentRank = 0
+	this._maxRank = -1
+
+	return this
+}
+
+// AddFallback drops define, the name without #define, at rank
+func (this *EffectFallbacks) AddFallback(rank int, define string) {
+	this._defines[rank] = append(this._defines[rank], define)
+
+	if rank > this._maxRank {
+		this._maxRank = rank
+	}
+}
+
+func (this *EffectFallbacks) IsMoreFallbacks() bool {
+	return this._currentRank <= this._maxRank
+}
+
+// Reduce removes from defines the defines of the next ranks, until one of
+// them was there
+func (this *EffectFallbacks) Reduce(defines string) string {
+	for this.IsMoreFallbacks() {
+		dropped := map[string]bool{}
+		for _, define := range this._defines[this._currentRank] {
+			dropped[define] = true
+		}
+		this._currentRank++
+
+		lines := strings.Split(defines, "\n")
+		kept := make([]string, 0, len(lines))
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "#define" && dropped[fields[1]] {
+				continue
+			}
+			kept = append(kept, line)
+		}
+
+		if len(kept) < len(lines) {
+			return strings.Join(kept, "\n")
+		}
+	}
+
+	return defines
+}
@@ -0,0 +1,63 @@
+// +build glnull glsoft
+
+package effects
+
+import (
+	"testing"
+)
+
+func TestEffectFallbacksReduce(t *testing.T) {
+	defines := "#define NORMAL\n#define SHADOWS\n#define SHADOWVSM0\n#define FOG"
+
+	tests := []struct {
+		name      string
+		fallbacks map[int][]string
+		reduced   []string
+		more      bool
+	}{
+		{
+			"no fallback",
+			nil,
+			[]string{defines},
+			false,
+		},
+		{
+			"one rank per reduce",
+			map[int][]string{0: {"FOG"}, 1: {"SHADOWVSM0", "SHADOWS"}},
+			[]string{"#define NORMAL\n#define SHADOWS\n#define SHADOWVSM0", "#define NORMAL"},
+			false,
+		},
+		{
+			"absent defines are skipped",
+			map[int][]string{0: {"BUMP"}, 2: {"FOG"}, 3: {"NORMAL"}},
+			[]string{"#define NORMAL\n#define SHADOWS\n#define SHADOWVSM0"},
+			true,
+		},
+		{
+			"nothing left to drop",
+			map[int][]string{0: {"BUMP"}},
+			[]string{defines},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		fallbacks := NewEffectFallbacks()
+		for rank, names := range test.fallbacks {
+			for _, name := range names {
+				fallbacks.AddFallback(rank, name)
+			}
+		}
+
+		current := defines
+		for index, want := range test.reduced {
+			current = fallbacks.Reduce(current)
+			if current != want {
+				t.Errorf("%s: reduce %d got %q, want %q", test.name, index, current, want)
+			}
+		}
+		if fallbacks.IsMoreFallbacks() != test.more {
+			t.Errorf("%s: more fallbacks %v, want %v", test.name, fallbacks.IsMoreFallbacks(), test.more)
+		}
+	}
+}
@@ -5,6 +5,30 @@ var ShadersStore = map[string]string{}
 
 func init() { 
 
+ShadersStore["color_fragment"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+uniform vec4 color;
+
+void main(void) {
+	gl_FragColor = color;
+}` 
+
+ShadersStore["color_vertex"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+// Attributes
+attribute vec3 position;
+
+// Uniforms
+uniform mat4 worldViewProjection;
+
+void main(void) {
+	gl_Position = worldViewProjection * vec4(position, 1.0);
+}` 
+
 ShadersStore["default_fragment"] = `#ifdef GL_ES
 precision mediump float;
 #endif
@@ -27,69 +51,7 @@ varying vec3 vColor;
 #endif
 
 // Lights
-#ifdef LIGHT0
-uniform vec4 vLightData0;
-uniform vec3 vLightDiffuse0;
-uniform vec3 vLightSpecular0;
-#ifdef SHADOW0
-varying vec4 vPositionFromLight0;
-uniform sampler2D shadowSampler0;
-#endif
-#ifdef SPOTLIGHT0
-uniform vec4 vLightDirection0;
-#endif
-#ifdef HEMILIGHT0
-uniform vec3 vLightGround0;
-#endif
-#endif
-
-#ifdef LIGHT1
-uniform vec4 vLightData1;
-uniform vec3 vLightDiffuse1;
-uniform vec3 vLightSpecular1;
-#ifdef SHADOW1
-varying vec4 vPositionFromLight1;
-uniform sampler2D shadowSampler1;
-#endif
-#ifdef SPOTLIGHT1
-uniform vec4 vLightDirection1;
-#endif
-#ifdef HEMILIGHT1
-uniform vec3 vLightGround1;
-#endif
-#endif
-
-#ifdef LIGHT2
-uniform vec4 vLightData2;
-uniform vec3 vLightDiffuse2;
-uniform vec3 vLightSpecular2;
-#ifdef SHADOW2
-varying vec4 vPositionFromLight2;
-uniform sampler2D shadowSampler2;
-#endif
-#ifdef SPOTLIGHT2
-uniform vec4 vLightDirection2;
-#endif
-#ifdef HEMILIGHT2
-uniform vec3 vLightGround2;
-#endif
-#endif
-
-#ifdef LIGHT3
-uniform vec4 vLightData3;
-uniform vec3 vLightDiffuse3;
-uniform vec3 vLightSpecular3;
-#ifdef SHADOW3
-varying vec4 vPositionFromLight3;
-uniform sampler2D shadowSampler3;
-#endif
-#ifdef SPOTLIGHT3
-uniform vec4 vLightDirection3;
-#endif
-#ifdef HEMILIGHT3
-uniform vec3 vLightGround3;
-#endif
-#endif
+#include<lightFragmentDeclaration>[0..maxSimultaneousLights]
 
 // Samplers
 #ifdef DIFFUSE
@@ -129,425 +91,112 @@ uniform vec2 vSpecularInfos;
 uniform sampler2D specularSampler;
 #endif
 
-// Shadows
-#ifdef SHADOWS
-
-float unpack(vec4 color)
-{
-	const vec4 bitShift = vec4(1. / (255. * 255. * 255.), 1. / (255. * 255.), 1. / 255., 1.);
-	return dot(color, bitShift);
-}
-
-float unpackHalf(vec2 color) 
-{ 
-	return color.x + (color.y / 255.0);
-}
-
-float computeShadow(vec4 vPositionFromLight, sampler2D shadowSampler)
-{
-	vec3 depth = vPositionFromLight.xyz / vPositionFromLight.w;
-	vec2 uv = 0.5 * depth.xy + vec2(0.5, 0.5);
-
-	if (uv.x < 0. || uv.x > 1.0 || uv.y < 0. || uv.y > 1.0)
-	{
-		return 1.0;
-	}
-
-	float shadow = unpack(texture2D(shadowSampler, uv));
+#include<shadowsFragmentFunctions>
 
-	if (depth.z > shadow)
-	{
-		return 0.;
-	}
-	return 1.;
-}
+#include<bumpFragmentFunctions>
 
-// Thanks to http://devmaster.net/
-float ChebychevInequality(vec2 moments, float t) 
-{
-	if (t <= moments.x)
-	{
-		return 1.0;
-	}
-	
-	float variance = moments.y - (moments.x * moments.x); 
-	variance = max(variance, 0.);
+#include<clipPlaneFragmentDeclaration>
+#include<fogFragmentDeclaration>
 
-	float d = t - moments.x; 	
-	return variance / (variance + d * d); 
-}
+#include<lightsFragmentFunctions>
 
-float computeShadowWithVSM(vec4 vPositionFromLight, sampler2D shadowSampler)
-{
-	vec3 depth = vPositionFromLight.xyz / vPositionFromLight.w;
-	vec2 uv = 0.5 * depth.xy + vec2(0.5, 0.5);
+void main(void) {
+	// Clip plane
+#include<clipPlaneFragment>
 
-	if (uv.x < 0. || uv.x > 1.0 || uv.y < 0. || uv.y > 1.0)
-	{
-		return 1.0;
-	}
+	vec3 viewDirectionW = normalize(vEyePosition - vPositionW);
 
-	vec4 texel = texture2D(shadowSampler, uv);
+	// Base color
+	vec4 baseColor = vec4(1., 1., 1., 1.);
+	vec3 diffuseColor = vDiffuseColor.rgb;
 
-	vec2 moments = vec2(unpackHalf(texel.xy), unpackHalf(texel.zw));
-	return clamp(1.3 - ChebychevInequality(moments, depth.z), 0., 1.0);
-}
+#ifdef VERTEXCOLOR
+	diffuseColor *= vColor;
 #endif
 
-// Bump
-#ifdef BUMP
-#extension GL_OES_standard_derivatives : enable
-varying vec2 vBumpUV;
-uniform vec2 vBumpInfos;
-uniform sampler2D bumpSampler;
+#ifdef DIFFUSE
+	baseColor = texture2D(diffuseSampler, vDiffuseUV);
 
-// Thanks to http://www.thetenthplanet.de/archives/1180
-mat3 cotangent_frame(vec3 normal, vec3 p, vec2 uv)
-{
-	// get edge vectors of the pixel triangle
-	vec3 dp1 = dFdx(p);
-	vec3 dp2 = dFdy(p);
-	vec2 duv1 = dFdx(uv);
-	vec2 duv2 = dFdy(uv);
+#ifdef ALPHATEST
+	if (baseColor.a < 0.4)
+		discard;
+#endif
 
-	// solve the linear system
-	vec3 dp2perp = cross(dp2, normal);
-	vec3 dp1perp = cross(normal, dp1);
-	vec3 tangent = dp2perp * duv1.x + dp1perp * duv2.x;
-	vec3 binormal = dp2perp * duv1.y + dp1perp * duv2.y;
+	baseColor.rgb *= vDiffuseInfos.y;
+#endif
 
-	// construct a scale-invariant frame 
-	float invmax = inversesqrt(max(dot(tangent, tangent), dot(binormal, binormal)));
-	return mat3(tangent * invmax, binormal * invmax, normal);
-}
+	// Bump
+	vec3 normalW = vNormalW;
 
-vec3 perturbNormal(vec3 viewDir)
-{
-	vec3 map = texture2D(bumpSampler, vBumpUV).xyz * vBumpInfos.y;
-	map = map * 255. / 127. - 128. / 127.;
-	mat3 TBN = cotangent_frame(vNormalW, -viewDir, vBumpUV);
-	return normalize(TBN * map);
-}
+#ifdef BUMP
+	normalW = perturbNormal(viewDirectionW);
 #endif
 
-#ifdef CLIPPLANE
-varying float fClipDistance;
-#endif
+	// Ambient color
+	vec3 baseAmbientColor = vec3(1., 1., 1.);
 
-// Fog
-#ifdef FOG
+#ifdef AMBIENT
+	baseAmbientColor = texture2D(ambientSampler, vAmbientUV).rgb * vAmbientInfos.y;
+#endif
 
-#define FOGMODE_NONE    0.
-#define FOGMODE_EXP     1.
-#define FOGMODE_EXP2    2.
-#define FOGMODE_LINEAR  3.
-#define E 2.71828
+	// Lighting
+	vec3 diffuseBase = vec3(0., 0., 0.);
+	vec3 specularBase = vec3(0., 0., 0.);
+	float shadow = 1.;
+	lightingInfo info;
 
-uniform vec4 vFogInfos;
-uniform vec3 vFogColor;
-varying float fFogDistance;
+#include<lightFragment>[0..maxSimultaneousLights]
 
-float CalcFogFactor()
-{
-	float fogCoeff = 1.0;
-	float fogStart = vFogInfos.y;
-	float fogEnd = vFogInfos.z;
-	float fogDensity = vFogInfos.w;
+	// Reflection
+	vec3 reflectionColor = vec3(0., 0., 0.);
 
-	if (FOGMODE_LINEAR == vFogInfos.x)
-	{
-		fogCoeff = (fogEnd - fFogDistance) / (fogEnd - fogStart);
-	}
-	else if (FOGMODE_EXP == vFogInfos.x)
+#ifdef REFLECTION
+	if (vReflectionInfos.z != 0.0)
 	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fogDensity);
+		reflectionColor = textureCube(reflectionCubeSampler, vReflectionUVW).rgb * vReflectionInfos.y;
 	}
-	else if (FOGMODE_EXP2 == vFogInfos.x)
+	else
 	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fFogDistance * fogDensity * fogDensity);
-	}
-
-	return min(1., max(0., fogCoeff));
-}
-#endif
+		vec2 coords = vReflectionUVW.xy;
 
-// Light Computing
-struct lightingInfo
-{
-	vec3 diffuse;
-	vec3 specular;
-};
+		if (vReflectionInfos.x == MAP_PROJECTION)
+		{
+			coords /= vReflectionUVW.z;
+		}
 
-lightingInfo computeLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor) {
-	lightingInfo result;
+		coords.y = 1.0 - coords.y;
 
-	vec3 lightVectorW;
-	if (lightData.w == 0.)
-	{
-		lightVectorW = normalize(lightData.xyz - vPositionW);
-	}
-	else
-	{
-		lightVectorW = normalize(-lightData.xyz);
+		reflectionColor = texture2D(reflection2DSampler, coords).rgb * vReflectionInfos.y;
 	}
+#endif
 
-	// diffuse
-	float ndl = max(0., dot(vNormal, lightVectorW));
-
-	// Specular
-	vec3 angleW = normalize(viewDirectionW + lightVectorW);
-	float specComp = max(0., dot(vNormal, angleW));
-	specComp = pow(specComp, vSpecularColor.a);
+	// Alpha
+	float alpha = vDiffuseColor.a;
 
-	result.diffuse = ndl * diffuseColor;
-	result.specular = specComp * specularColor;
+#ifdef OPACITY
+	vec3 opacityMap = texture2D(opacitySampler, vOpacityUV).rgb * vec3(0.3, 0.59, 0.11);
+	alpha *= (opacityMap.x + opacityMap.y + opacityMap.z)* vOpacityInfos.y;
+#endif
 
-	return result;
-}
+	// Emissive
+	vec3 emissiveColor = vEmissiveColor;
+#ifdef EMISSIVE
+	emissiveColor += texture2D(emissiveSampler, vEmissiveUV).rgb * vEmissiveInfos.y;
+#endif
 
-lightingInfo computeSpotLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec4 lightDirection, vec3 diffuseColor, vec3 specularColor) {
-	lightingInfo result;
+	// Specular map
+	vec3 specularColor = vSpecularColor.rgb;
+#ifdef SPECULAR
+	specularColor = texture2D(specularSampler, vSpecularUV).rgb * vSpecularInfos.y;
+#endif
 
-	vec3 lightVectorW = normalize(lightData.xyz - vPositionW);
+	// Composition
+	vec3 finalDiffuse = clamp(diffuseBase * diffuseColor + emissiveColor + vAmbientColor, 0.0, 1.0) * baseColor.rgb;
+	vec3 finalSpecular = specularBase * specularColor;
 
-	// diffuse
-	float cosAngle = max(0., dot(-lightDirection.xyz, lightVectorW));
-	float spotAtten = 0.0;
+	vec4 color = vec4(finalDiffuse * baseAmbientColor + finalSpecular + reflectionColor, alpha);
 
-	if (cosAngle >= lightDirection.w)
-	{
-		cosAngle = max(0., pow(cosAngle, lightData.w));
-		spotAtten = max(0., (cosAngle - lightDirection.w) / (1. - cosAngle));
-
-		// Diffuse
-		float ndl = max(0., dot(vNormal, -lightDirection.xyz));
-
-		// Specular
-		vec3 angleW = normalize(viewDirectionW - lightDirection.xyz);
-		float specComp = max(0., dot(vNormal, angleW));
-		specComp = pow(specComp, vSpecularColor.a);
-
-		result.diffuse = ndl * spotAtten * diffuseColor;
-		result.specular = specComp * specularColor * spotAtten;
-
-		return result;
-	}
-
-	result.diffuse = vec3(0.);
-	result.specular = vec3(0.);
-
-	return result;
-}
-
-lightingInfo computeHemisphericLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, vec3 groundColor) {
-	lightingInfo result;
-
-	// Diffuse
-	float ndl = dot(vNormal, lightData.xyz) * 0.5 + 0.5;
-
-	// Specular
-	vec3 angleW = normalize(viewDirectionW + lightData.xyz);
-	float specComp = max(0., dot(vNormal, angleW));
-	specComp = pow(specComp, vSpecularColor.a);
-
-	result.diffuse = mix(groundColor, diffuseColor, ndl);
-	result.specular = specComp * specularColor;
-
-	return result;
-}
-
-void main(void) {
-	// Clip plane
-#ifdef CLIPPLANE
-	if (fClipDistance > 0.0)
-		discard;
-#endif
-
-	vec3 viewDirectionW = normalize(vEyePosition - vPositionW);
-
-	// Base color
-	vec4 baseColor = vec4(1., 1., 1., 1.);
-	vec3 diffuseColor = vDiffuseColor.rgb;
-
-#ifdef VERTEXCOLOR
-	diffuseColor *= vColor;
-#endif
-
-#ifdef DIFFUSE
-	baseColor = texture2D(diffuseSampler, vDiffuseUV);
-
-#ifdef ALPHATEST
-	if (baseColor.a < 0.4)
-		discard;
-#endif
-
-	baseColor.rgb *= vDiffuseInfos.y;
-#endif
-
-	// Bump
-	vec3 normalW = vNormalW;
-
-#ifdef BUMP
-	normalW = perturbNormal(viewDirectionW);
-#endif
-
-	// Ambient color
-	vec3 baseAmbientColor = vec3(1., 1., 1.);
-
-#ifdef AMBIENT
-	baseAmbientColor = texture2D(ambientSampler, vAmbientUV).rgb * vAmbientInfos.y;
-#endif
-
-	// Lighting
-	vec3 diffuseBase = vec3(0., 0., 0.);
-	vec3 specularBase = vec3(0., 0., 0.);
-	float shadow = 1.;
-
-#ifdef LIGHT0
-#ifdef SPOTLIGHT0
-	lightingInfo info = computeSpotLighting(viewDirectionW, normalW, vLightData0, vLightDirection0, vLightDiffuse0, vLightSpecular0);
-#endif
-#ifdef HEMILIGHT0
-	lightingInfo info = computeHemisphericLighting(viewDirectionW, normalW, vLightData0, vLightDiffuse0, vLightSpecular0, vLightGround0);
-#endif
-#ifdef POINTDIRLIGHT0
-	lightingInfo info = computeLighting(viewDirectionW, normalW, vLightData0, vLightDiffuse0, vLightSpecular0);
-#endif
-#ifdef SHADOW0
-	#ifdef SHADOWVSM0
-		shadow = computeShadowWithVSM(vPositionFromLight0, shadowSampler0);
-	#else
-		shadow = computeShadow(vPositionFromLight0, shadowSampler0);
-	#endif
-#else
-	shadow = 1.;
-#endif
-	diffuseBase += info.diffuse * shadow;
-	specularBase += info.specular * shadow;
-#endif
-
-#ifdef LIGHT1
-#ifdef SPOTLIGHT1
-	info = computeSpotLighting(viewDirectionW, normalW, vLightData1, vLightDirection1, vLightDiffuse1, vLightSpecular1);
-#endif
-#ifdef HEMILIGHT1
-	info = computeHemisphericLighting(viewDirectionW, normalW, vLightData1, vLightDiffuse1, vLightSpecular1, vLightGround1);
-#endif
-#ifdef POINTDIRLIGHT1
-	info = computeLighting(viewDirectionW, normalW, vLightData1, vLightDiffuse1, vLightSpecular1);
-#endif
-#ifdef SHADOW1
-	#ifdef SHADOWVSM1
-		shadow = computeShadowWithVSM(vPositionFromLight1, shadowSampler1);
-	#else
-		shadow = computeShadow(vPositionFromLight1, shadowSampler1);
-	#endif
-#else
-	shadow = 1.;
-#endif
-	diffuseBase += info.diffuse * shadow;
-	specularBase += info.specular * shadow;
-#endif
-
-#ifdef LIGHT2
-#ifdef SPOTLIGHT2
-	info = computeSpotLighting(viewDirectionW, normalW, vLightData2, vLightDirection2, vLightDiffuse2, vLightSpecular2);
-#endif
-#ifdef HEMILIGHT2
-	info = computeHemisphericLighting(viewDirectionW, normalW, vLightData2, vLightDiffuse2, vLightSpecular2, vLightGround2);
-#endif
-#ifdef POINTDIRLIGHT2
-	info = computeLighting(viewDirectionW, normalW, vLightData2, vLightDiffuse2, vLightSpecular2);
-#endif
-#ifdef SHADOW2
-	#ifdef SHADOWVSM2
-		shadow = computeShadowWithVSM(vPositionFromLight2, shadowSampler2);
-	#else
-		shadow = computeShadow(vPositionFromLight2, shadowSampler2);
-	#endif	
-#else
-	shadow = 1.;
-#endif
-	diffuseBase += info.diffuse * shadow;
-	specularBase += info.specular * shadow;
-#endif
-
-#ifdef LIGHT3
-#ifdef SPOTLIGHT3
-	info = computeSpotLighting(viewDirectionW, normalW, vLightData3, vLightDirection3, vLightDiffuse3, vLightSpecular3);
-#endif
-#ifdef HEMILIGHT3
-	info = computeHemisphericLighting(viewDirectionW, normalW, vLightData3, vLightDiffuse3, vLightSpecular3, vLightGround3);
-#endif
-#ifdef POINTDIRLIGHT3
-	info = computeLighting(viewDirectionW, normalW, vLightData3, vLightDiffuse3, vLightSpecular3);
-#endif
-#ifdef SHADOW3
-	#ifdef SHADOWVSM3
-		shadow = computeShadowWithVSM(vPositionFromLight3, shadowSampler3);
-	#else
-		shadow = computeShadow(vPositionFromLight3, shadowSampler3);
-	#endif	
-#else
-	shadow = 1.;
-#endif
-	diffuseBase += info.diffuse * shadow;
-	specularBase += info.specular * shadow;
-#endif
-
-	// Reflection
-	vec3 reflectionColor = vec3(0., 0., 0.);
-
-#ifdef REFLECTION
-	if (vReflectionInfos.z != 0.0)
-	{
-		reflectionColor = textureCube(reflectionCubeSampler, vReflectionUVW).rgb * vReflectionInfos.y;
-	}
-	else
-	{
-		vec2 coords = vReflectionUVW.xy;
-
-		if (vReflectionInfos.x == MAP_PROJECTION)
-		{
-			coords /= vReflectionUVW.z;
-		}
-
-		coords.y = 1.0 - coords.y;
-
-		reflectionColor = texture2D(reflection2DSampler, coords).rgb * vReflectionInfos.y;
-	}
-#endif
-
-	// Alpha
-	float alpha = vDiffuseColor.a;
-
-#ifdef OPACITY
-	vec3 opacityMap = texture2D(opacitySampler, vOpacityUV).rgb * vec3(0.3, 0.59, 0.11);
-	alpha *= (opacityMap.x + opacityMap.y + opacityMap.z)* vOpacityInfos.y;
-#endif
-
-	// Emissive
-	vec3 emissiveColor = vEmissiveColor;
-#ifdef EMISSIVE
-	emissiveColor += texture2D(emissiveSampler, vEmissiveUV).rgb * vEmissiveInfos.y;
-#endif
-
-	// Specular map
-	vec3 specularColor = vSpecularColor.rgb;
-#ifdef SPECULAR
-	specularColor = texture2D(specularSampler, vSpecularUV).rgb * vSpecularInfos.y;
-#endif
-
-	// Composition
-	vec3 finalDiffuse = clamp(diffuseBase * diffuseColor + emissiveColor + vAmbientColor, 0.0, 1.0) * baseColor.rgb;
-	vec3 finalSpecular = specularBase * specularColor;
-
-	vec4 color = vec4(finalDiffuse * baseAmbientColor + finalSpecular + reflectionColor, alpha);
-
-#ifdef FOG
-	float fog = CalcFogFactor();
-	color.rgb = fog * color.rgb + (1.0 - fog) * vFogColor;
-#endif
+#include<fogFragment>
 
 	gl_FragColor = color;
 }` 
@@ -566,6 +215,9 @@ precision mediump float;
 // Attributes
 attribute vec3 position;
 attribute vec3 normal;
+#ifdef TANGENT
+attribute vec4 tangent;
+#endif
 #ifdef UV1
 attribute vec2 uv;
 #endif
@@ -575,11 +227,18 @@ attribute vec2 uv2;
 #ifdef VERTEXCOLOR
 attribute vec3 color;
 #endif
+#include<instancesDeclaration>
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
 
 // Uniforms
 uniform mat4 world;
 uniform mat4 view;
 uniform mat4 worldViewProjection;
+#if defined(INSTANCES) || defined(BONES)
+uniform mat4 viewProjection;
+#endif
+#include<morphTargetsVertexGlobalDeclaration>
 
 #ifdef DIFFUSE
 varying vec2 vDiffuseUV;
@@ -618,11 +277,7 @@ uniform vec2 vSpecularInfos;
 uniform mat4 specularMatrix;
 #endif
 
-#ifdef BUMP
-varying vec2 vBumpUV;
-uniform vec2 vBumpInfos;
-uniform mat4 bumpMatrix;
-#endif
+#include<bumpVertexDeclaration>
 
 // Output
 varying vec3 vPositionW;
@@ -632,33 +287,10 @@ varying vec3 vNormalW;
 varying vec3 vColor;
 #endif
 
-#ifdef CLIPPLANE
-uniform vec4 vClipPlane;
-varying float fClipDistance;
-#endif
-
-#ifdef FOG
-varying float fFogDistance;
-#endif
+#include<clipPlaneVertexDeclaration>
+#include<fogVertexDeclaration>
 
-#ifdef SHADOWS
-#ifdef LIGHT0
-uniform mat4 lightMatrix0;
-varying vec4 vPositionFromLight0;
-#endif
-#ifdef LIGHT1
-uniform mat4 lightMatrix1;
-varying vec4 vPositionFromLight1;
-#endif
-#ifdef LIGHT2
-uniform mat4 lightMatrix2;
-varying vec4 vPositionFromLight2;
-#endif
-#ifdef LIGHT3
-uniform mat4 lightMatrix3;
-varying vec4 vPositionFromLight3;
-#endif
-#endif
+#include<shadowsVertexDeclaration>[0..maxSimultaneousLights]
 
 #ifdef REFLECTION
 vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
@@ -697,11 +329,22 @@ vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
 #endif
 
 void main(void) {
-	gl_Position = worldViewProjection * vec4(position, 1.0);
+	vec3 positionUpdated = position;
+	vec3 normalUpdated = normal;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+#include<instancesVertex>
+#include<bonesVertex>
+
+#if defined(INSTANCES) || defined(BONES)
+	gl_Position = viewProjection * finalWorld * vec4(positionUpdated, 1.0);
+#else
+	gl_Position = worldViewProjection * vec4(positionUpdated, 1.0);
+#endif
 
-	vec4 worldPos = world * vec4(position, 1.0);
+	vec4 worldPos = finalWorld * vec4(positionUpdated, 1.0);
 	vPositionW = vec3(worldPos);
-	vNormalW = normalize(vec3(world * vec4(normal, 0.0)));
+	vNormalW = normalize(vec3(finalWorld * vec4(normalUpdated, 0.0)));
 
 	// Texture coordinates
 #ifndef UV1
@@ -770,42 +413,16 @@ void main(void) {
 	}
 #endif
 
-#ifdef BUMP
-	if (vBumpInfos.x == 0.)
-	{
-		vBumpUV = vec2(bumpMatrix * vec4(uv, 1.0, 0.0));
-	}
-	else
-	{
-		vBumpUV = vec2(bumpMatrix * vec4(uv2, 1.0, 0.0));
-	}
-#endif
+#include<bumpVertex>
 
 	// Clip plane
-#ifdef CLIPPLANE
-	fClipDistance = dot(worldPos, vClipPlane);
-#endif
+#include<clipPlaneVertex>
 
 	// Fog
-#ifdef FOG
-	fFogDistance = (view * worldPos).z;
-#endif
+#include<fogVertex>
 
 	// Shadows
-#ifdef SHADOWS
-#ifdef LIGHT0
-	vPositionFromLight0 = lightMatrix0 * vec4(position, 1.0);
-#endif
-#ifdef LIGHT1
-	vPositionFromLight1 = lightMatrix1 * vec4(position, 1.0);
-#endif
-#ifdef LIGHT2
-	vPositionFromLight2 = lightMatrix2 * vec4(position, 1.0);
-#endif
-#ifdef LIGHT3
-	vPositionFromLight3 = lightMatrix3 * vec4(position, 1.0);
-#endif
-#endif
+#include<shadowsVertex>[0..maxSimultaneousLights]
 
 	// Vertex color
 #ifdef VERTEXCOLOR
@@ -827,15 +444,7 @@ uniform vec4 vSpecularColor;
 uniform vec3 vEmissiveColor;
 
 // Lights
-#ifdef LIGHT0
-uniform vec4 vLightData0;
-uniform vec3 vLightDiffuse0;
-uniform vec3 vLightSpecular0;
-#ifdef SHADOW0
-varying vec4 vPositionFromLight0;
-uniform sampler2D shadowSampler0;
-#endif
-#endif
+#include<lightFragmentDeclaration>[0..1]
 
 //#ifdef LIGHT1
 //uniform vec4 vLightData1;
@@ -891,9 +500,7 @@ uniform sampler2D specularSampler;
 varying vec3 vPositionW;
 varying vec3 vNormalW;
 
-#ifdef CLIPPLANE
-varying float fClipDistance;
-#endif
+#include<clipPlaneFragmentDeclaration>
 
 // Shadows
 #ifdef SHADOWS
@@ -926,54 +533,18 @@ float ChebychevInequality(vec2 moments, float t)
 
 #endif
 
-// Fog
-#ifdef FOG
+#include<fogFragmentDeclaration>
 
-#define FOGMODE_NONE    0.
-#define FOGMODE_EXP     1.
-#define FOGMODE_EXP2    2.
-#define FOGMODE_LINEAR  3.
-#define E 2.71828
-
-uniform vec4 vFogInfos;
-uniform vec3 vFogColor;
-varying float fFogDistance;
-
-float CalcFogFactor()
-{
-	float fogCoeff = 1.0;
-	float fogStart = vFogInfos.y;
-	float fogEnd = vFogInfos.z;
-	float fogDensity = vFogInfos.w;
-
-	if (FOGMODE_LINEAR == vFogInfos.x)
-	{
-		fogCoeff = (fogEnd - fFogDistance) / (fogEnd - fogStart);
-	}
-	else if (FOGMODE_EXP == vFogInfos.x)
-	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fogDensity);
-	}
-	else if (FOGMODE_EXP2 == vFogInfos.x)
-	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fFogDistance * fogDensity * fogDensity);
-	}
-
-	return min(1., max(0., fogCoeff));
-}
-
-#endif
-
-vec3 computeDiffuseLighting(vec3 vNormal, vec4 lightData, vec3 diffuseColor) {
-	vec3 lightVectorW;
-	if (lightData.w == 0.)
-	{
-		lightVectorW = normalize(lightData.xyz - vPositionW);
-	}
-	else
-	{
-		lightVectorW = normalize(-lightData.xyz);
-	}
+vec3 computeDiffuseLighting(vec3 vNormal, vec4 lightData, vec3 diffuseColor) {
+	vec3 lightVectorW;
+	if (lightData.w == 0.)
+	{
+		lightVectorW = normalize(lightData.xyz - vPositionW);
+	}
+	else
+	{
+		lightVectorW = normalize(-lightData.xyz);
+	}
 
 	// diffuse
 	float ndl = max(0., dot(vNormal, lightVectorW));
@@ -1002,10 +573,7 @@ vec3 computeSpecularLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData,
 
 void main(void) {
 	// Clip plane
-#ifdef CLIPPLANE
-	if (fClipDistance > 0.0)
-		discard;
-#endif
+#include<clipPlaneFragment>
 
 	vec3 viewDirectionW = normalize(vEyePosition - vPositionW);
 
@@ -1061,7 +629,7 @@ void main(void) {
 		#endif
 		}
 	#endif
-	diffuseBase += computeDiffuseLighting(normalW, vLightData0, vLightDiffuse0) * shadow;
+	diffuseBase += computeDiffuseLighting(normalW, vLightData0, vLightDiffuse0.rgb) * shadow;
 	specularBase += computeSpecularLighting(viewDirectionW, normalW, vLightData0, vLightSpecular0) * shadow;
 #endif
 //#ifdef LIGHT1
@@ -1123,10 +691,7 @@ void main(void) {
 
 	vec4 color = vec4(finalDiffuse * baseAmbientColor + finalSpecular + reflectionColor, alpha);
 
-#ifdef FOG
-	float fog = CalcFogFactor();
-	color.rgb = fog * color.rgb + (1.0 - fog) * vFogColor;
-#endif
+#include<fogFragment>
 
 	gl_FragColor = color;
 }` 
@@ -1151,11 +716,18 @@ attribute vec2 uv;
 #ifdef UV2
 attribute vec2 uv2;
 #endif
+#include<instancesDeclaration>
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
 
 // Uniforms
 uniform mat4 world;
 uniform mat4 view;
 uniform mat4 worldViewProjection;
+#if defined(INSTANCES) || defined(BONES)
+uniform mat4 viewProjection;
+#endif
+#include<morphTargetsVertexGlobalDeclaration>
 
 #ifdef DIFFUSE
 varying vec2 vDiffuseUV;
@@ -1199,21 +771,10 @@ uniform mat4 specularMatrix;
 varying vec3 vPositionW;
 varying vec3 vNormalW;
 
-#ifdef CLIPPLANE
-uniform vec4 vClipPlane;
-varying float fClipDistance;
-#endif
-
-#ifdef FOG
-varying float fFogDistance;
-#endif
+#include<clipPlaneVertexDeclaration>
+#include<fogVertexDeclaration>
 
-#ifdef SHADOWS
-#ifdef LIGHT0
-uniform mat4 lightMatrix0;
-varying vec4 vPositionFromLight0;
-#endif
-#endif
+#include<shadowsVertexDeclaration>[0..1]
 
 #ifdef REFLECTION
 vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
@@ -1252,11 +813,22 @@ vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
 #endif
 
 void main(void) {
-	gl_Position = worldViewProjection * vec4(position, 1.0);
+	vec3 positionUpdated = position;
+	vec3 normalUpdated = normal;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+#include<instancesVertex>
+#include<bonesVertex>
+
+#if defined(INSTANCES) || defined(BONES)
+	gl_Position = viewProjection * finalWorld * vec4(positionUpdated, 1.0);
+#else
+	gl_Position = worldViewProjection * vec4(positionUpdated, 1.0);
+#endif
 
-	vec4 worldPos = world * vec4(position, 1.0);
+	vec4 worldPos = finalWorld * vec4(positionUpdated, 1.0);
 	vPositionW = vec3(worldPos);
-	vNormalW = normalize(vec3(world * vec4(normal, 0.0)));
+	vNormalW = normalize(vec3(finalWorld * vec4(normalUpdated, 0.0)));
 
 	// Texture coordinates
 #ifndef UV1
@@ -1326,21 +898,13 @@ void main(void) {
 #endif
 
 	// Clip plane
-#ifdef CLIPPLANE
-	fClipDistance = dot(worldPos, vClipPlane);
-#endif
+#include<clipPlaneVertex>
 
 	// Fog
-#ifdef FOG
-	fFogDistance = (view * worldPos).z;
-#endif
+#include<fogVertex>
 
 	// Shadows
-#ifdef SHADOWS
-#ifdef LIGHT0
-	vPositionFromLight0 = lightMatrix0 * vec4(position, 1.0);
-#endif
-#endif
+#include<shadowsVertex>[0..1]
 }` 
 
 ShadersStore["layer_fragment"] = `#ifdef GL_ES
@@ -1457,183 +1021,1069 @@ void main(void) {
 #endif
 }` 
 
-ShadersStore["shadowMap_fragment"] = `#ifdef GL_ES
-precision mediump float;
-#endif
-
-vec4 pack(float depth)
-{
-	const vec4 bitOffset = vec4(255. * 255. * 255., 255. * 255., 255., 1.);
-	const vec4 bitMask = vec4(0., 1. / 255., 1. / 255., 1. / 255.);
-	
-	vec4 comp = fract(depth * bitOffset);
-	comp -= comp.xxyz * bitMask;
-	
-	return comp;
-}
-
-// Thanks to http://devmaster.net/
-vec2 packHalf(float depth) 
-{ 
-	const vec2 bitOffset = vec2(1.0 / 255., 0.);
-	vec2 color = vec2(depth, fract(depth * 255.));
-
-	return color - (color.yy * bitOffset);
-}
-
-
-void main(void)
-{
-#ifdef VSM
-	float moment1 = gl_FragCoord.z / gl_FragCoord.w; 
-	float moment2 = moment1 * moment1;
-	gl_FragColor = vec4(packHalf(moment1), packHalf(moment2));
-#else
-	gl_FragColor = pack(gl_FragCoord.z / gl_FragCoord.w);
-#endif
-}` 
-
-ShadersStore["shadowMap_vertex"] = `#ifdef GL_ES
-precision mediump float;
-#endif
-
-// Attribute
-attribute vec3 position;
-
-// Uniform
-uniform mat4 worldViewProjection;
-
-void main(void)
-{
-	gl_Position = worldViewProjection * vec4(position, 1.0);
-}` 
-
-ShadersStore["sprites_fragment"] = `#ifdef GL_ES
+ShadersStore["pbr_fragment"] = `#ifdef TEXTURELOD
+#ifdef GL_ES
+#extension GL_EXT_shader_texture_lod : enable
+#else
+#extension GL_ARB_shader_texture_lod : enable
+#define textureCubeLodEXT textureCubeLod
+#endif
+#endif
+
+#ifdef GL_ES
 precision mediump float;
 #endif
 
-uniform bool alphaTest;
+#define PI 3.14159265
 
-varying vec4 vColor;
+// Constants
+uniform vec3 vEyePosition;
+uniform vec3 vAmbientColor;
+uniform vec4 vAlbedoColor;
+uniform vec2 vMetallicRoughness;
+uniform vec3 vEmissiveColor;
+
+// Input
+varying vec3 vPositionW;
+varying vec3 vNormalW;
+
+#ifdef VERTEXCOLOR
+varying vec3 vColor;
+#endif
+
+// Lights
+#include<lightFragmentDeclaration>[0..maxSimultaneousLights]
 
 // Samplers
-varying vec2 vUV;
-uniform sampler2D diffuseSampler;
+#ifdef ALBEDO
+varying vec2 vAlbedoUV;
+uniform sampler2D albedoSampler;
+uniform vec2 vAlbedoInfos;
+#endif
 
-// Fog
-#ifdef FOG
+// Roughness in green and metallic in blue, as in glTF
+#ifdef METALLICROUGHNESS
+varying vec2 vMetallicRoughnessUV;
+uniform sampler2D metallicRoughnessSampler;
+uniform vec2 vMetallicRoughnessInfos;
+#endif
 
-#define FOGMODE_NONE    0.
-#define FOGMODE_EXP     1.
-#define FOGMODE_EXP2    2.
-#define FOGMODE_LINEAR  3.
-#define E 2.71828
+// Occlusion in red, the level is the strength
+#ifdef AO
+varying vec2 vAOUV;
+uniform sampler2D aoSampler;
+uniform vec2 vAOInfos;
+#endif
 
-uniform vec4 vFogInfos;
-uniform vec3 vFogColor;
-varying float fFogDistance;
+#ifdef EMISSIVE
+varying vec2 vEmissiveUV;
+uniform sampler2D emissiveSampler;
+uniform vec2 vEmissiveInfos;
+#endif
 
-float CalcFogFactor()
+// Image based lighting, the mip levels of the cube hold the environment
+// prefiltered for increasing roughness. x is the level, y the last mip
+#ifdef REFLECTION
+uniform samplerCube reflectionCubeSampler;
+uniform mat4 reflectionMatrix;
+uniform vec2 vReflectionInfos;
+#endif
+
+// Scale and bias of the specular reflectance by NdotV and roughness
+#ifdef BRDF
+uniform sampler2D brdfSampler;
+#endif
+
+#include<shadowsFragmentFunctions>
+
+#include<bumpFragmentFunctions>
+
+#include<clipPlaneFragmentDeclaration>
+#include<fogFragmentDeclaration>
+
+// Cook-Torrance
+float distributionGGX(float NdotH, float alpha)
 {
-	float fogCoeff = 1.0;
-	float fogStart = vFogInfos.y;
-	float fogEnd = vFogInfos.z;
-	float fogDensity = vFogInfos.w;
+	float alpha2 = alpha * alpha;
+	float d = NdotH * NdotH * (alpha2 - 1.) + 1.;
+	return alpha2 / (PI * d * d);
+}
 
-	if (FOGMODE_LINEAR == vFogInfos.x)
-	{
-		fogCoeff = (fogEnd - fFogDistance) / (fogEnd - fogStart);
-	}
-	else if (FOGMODE_EXP == vFogInfos.x)
+float visibilitySmithGGX(float NdotL, float NdotV, float alpha)
+{
+	float k = alpha * 0.5;
+	float gl = NdotL / (NdotL * (1. - k) + k);
+	float gv = NdotV / (NdotV * (1. - k) + k);
+	return gl * gv / max(4. * NdotL * NdotV, 0.0001);
+}
+
+vec3 fresnelSchlick(float VdotH, vec3 F0)
+{
+	return F0 + (1. - F0) * pow(1. - VdotH, 5.);
+}
+
+vec3 fresnelSchlickRoughness(float NdotV, vec3 F0, float roughness)
+{
+	return F0 + (max(vec3(1. - roughness), F0) - F0) * pow(1. - NdotV, 5.);
+}
+
+// Thanks to https://www.unrealengine.com/blog/physically-based-shading-on-mobile
+vec2 environmentBRDFApprox(float NdotV, float roughness)
+{
+	const vec4 c0 = vec4(-1., -0.0275, -0.572, 0.022);
+	const vec4 c1 = vec4(1., 0.0425, 1.04, -0.04);
+	vec4 r = roughness * c0 + c1;
+	float a004 = min(r.x * r.x, exp2(-9.28 * NdotV)) * r.x + r.y;
+	return vec2(-1.04, 1.04) * a004 + r.zw;
+}
+
+// Light Computing
+struct lightingInfo
+{
+	vec3 diffuse;
+	vec3 specular;
+};
+
+// The diffuse part is multiplied by the diffuse color of the surface, the
+// specular part already holds its reflectance
+lightingInfo computeBRDF(vec3 viewDirectionW, vec3 vNormal, vec3 lightVectorW, vec3 diffuseColor, vec3 specularColor, vec3 F0, float alpha)
+{
+	lightingInfo result;
+
+	float NdotL = max(0., dot(vNormal, lightVectorW));
+	float NdotV = max(0.0001, dot(vNormal, viewDirectionW));
+	vec3 H = normalize(viewDirectionW + lightVectorW);
+	float NdotH = max(0., dot(vNormal, H));
+	float VdotH = max(0., dot(viewDirectionW, H));
+
+	vec3 F = fresnelSchlick(VdotH, F0);
+	float specular = distributionGGX(NdotH, alpha) * visibilitySmithGGX(NdotL, NdotV, alpha);
+
+	// The lights are not divided by PI, neither is the lambertian diffuse
+	result.diffuse = (1. - F) * NdotL * diffuseColor;
+	result.specular = F * (specular * PI * NdotL) * specularColor;
+
+	return result;
+}
+
+lightingInfo computeLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, float range, vec3 F0, float alpha) {
+	vec3 lightVectorW;
+	float attenuation = 1.0;
+	if (lightData.w == 0.)
 	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fogDensity);
+		vec3 direction = lightData.xyz - vPositionW;
+
+		attenuation = max(0., 1.0 - length(direction) / range);
+		lightVectorW = normalize(direction);
 	}
-	else if (FOGMODE_EXP2 == vFogInfos.x)
+	else
 	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fFogDistance * fogDensity * fogDensity);
+		lightVectorW = normalize(-lightData.xyz);
 	}
 
-	return min(1., max(0., fogCoeff));
+	return computeBRDF(viewDirectionW, vNormal, lightVectorW, diffuseColor * attenuation, specularColor * attenuation, F0, alpha);
 }
-#endif
 
+lightingInfo computeSpotLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec4 lightDirection, vec3 diffuseColor, vec3 specularColor, float range, vec3 F0, float alpha) {
+	lightingInfo result;
 
-void main(void) {
-	vec4 baseColor = texture2D(diffuseSampler, vUV);
+	vec3 direction = lightData.xyz - vPositionW;
+	vec3 lightVectorW = normalize(direction);
 
-	if (alphaTest) 
+	float cosAngle = max(0., dot(-lightDirection.xyz, lightVectorW));
+
+	if (cosAngle >= lightDirection.w)
 	{
-		if (baseColor.a < 0.95)
-			discard;
+		cosAngle = max(0., pow(cosAngle, lightData.w));
+		float spotAtten = max(0., (cosAngle - lightDirection.w) / (1. - cosAngle));
+		spotAtten *= max(0., 1.0 - length(direction) / range);
+
+		return computeBRDF(viewDirectionW, vNormal, lightVectorW, diffuseColor * spotAtten, specularColor * spotAtten, F0, alpha);
 	}
 
-	baseColor *= vColor;
+	result.diffuse = vec3(0.);
+	result.specular = vec3(0.);
 
-#ifdef FOG
-	float fog = CalcFogFactor();
-	baseColor.rgb = fog * baseColor.rgb + (1.0 - fog) * vFogColor;
-#endif
+	return result;
+}
 
-	gl_FragColor = baseColor;
-}` 
-
-ShadersStore["sprites_vertex"] = `#ifdef GL_ES
-precision mediump float;
-#endif
+lightingInfo computeHemisphericLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, vec3 groundColor, vec3 F0, float alpha) {
+	lightingInfo result = computeBRDF(viewDirectionW, vNormal, lightData.xyz, diffuseColor, specularColor, F0, alpha);
 
-// Attributes
-attribute vec3 position;
-attribute vec4 options;
-attribute vec4 cellInfo;
-attribute vec4 color;
+	// Diffuse
+	float ndl = dot(vNormal, lightData.xyz) * 0.5 + 0.5;
+	result.diffuse = mix(groundColor, diffuseColor, ndl);
+
+	return result;
+}
+
+void main(void) {
+	// Clip plane
+#include<clipPlaneFragment>
+
+	vec3 viewDirectionW = normalize(vEyePosition - vPositionW);
+
+	// Albedo
+	vec4 albedo = vAlbedoColor;
+
+#ifdef VERTEXCOLOR
+	albedo.rgb *= vColor;
+#endif
+
+#ifdef ALBEDO
+	vec4 albedoMap = texture2D(albedoSampler, vAlbedoUV);
+
+#ifdef ALPHATEST
+	if (albedoMap.a < 0.4)
+		discard;
+#endif
+
+	albedo.rgb *= albedoMap.rgb * vAlbedoInfos.y;
+#endif
+
+	// Metallic and roughness
+	float metallic = vMetallicRoughness.x;
+	float roughness = vMetallicRoughness.y;
+
+#ifdef METALLICROUGHNESS
+	vec4 metallicRoughnessMap = texture2D(metallicRoughnessSampler, vMetallicRoughnessUV);
+	metallic *= metallicRoughnessMap.b;
+	roughness *= metallicRoughnessMap.g;
+#endif
+
+	metallic = clamp(metallic, 0., 1.);
+	roughness = clamp(roughness, 0.04, 1.);
+	float alpha = roughness * roughness;
+
+	vec3 diffuseColor = albedo.rgb * (1. - metallic);
+	vec3 F0 = mix(vec3(0.04), albedo.rgb, metallic);
+
+	// Bump
+	vec3 normalW = normalize(vNormalW);
+
+#ifdef BUMP
+	normalW = perturbNormal(viewDirectionW);
+#endif
+
+	// Lighting
+	vec3 diffuseBase = vec3(0., 0., 0.);
+	vec3 specularBase = vec3(0., 0., 0.);
+	float shadow = 1.;
+	lightingInfo info;
+
+#include<pbrLightFragment>[0..maxSimultaneousLights]
+
+	// Ambient and image based lighting
+	float NdotV = max(0.0001, dot(normalW, viewDirectionW));
+	vec3 F = fresnelSchlickRoughness(NdotV, F0, roughness);
+
+#ifdef BRDF
+	vec2 environmentBRDF = texture2D(brdfSampler, vec2(NdotV, roughness)).rg;
+#else
+	vec2 environmentBRDF = environmentBRDFApprox(NdotV, roughness);
+#endif
+
+	vec3 irradiance = vAmbientColor;
+	vec3 environmentSpecular = vec3(0., 0., 0.);
+
+#ifdef REFLECTION
+	vec3 irradianceVector = vec3(reflectionMatrix * vec4(normalW, 0));
+	vec3 reflectionVector = vec3(reflectionMatrix * vec4(reflect(-viewDirectionW, normalW), 0));
+
+#ifdef TEXTURELOD
+	irradiance += textureCubeLodEXT(reflectionCubeSampler, irradianceVector, vReflectionInfos.y).rgb * vReflectionInfos.x;
+	environmentSpecular = textureCubeLodEXT(reflectionCubeSampler, reflectionVector, roughness * vReflectionInfos.y).rgb * vReflectionInfos.x;
+#else
+	// The bias is added to the level the derivatives pick
+	irradiance += textureCube(reflectionCubeSampler, irradianceVector, vReflectionInfos.y).rgb * vReflectionInfos.x;
+	environmentSpecular = textureCube(reflectionCubeSampler, reflectionVector, roughness * vReflectionInfos.y).rgb * vReflectionInfos.x;
+#endif
+#endif
+
+	vec3 ambientOcclusion = vec3(1., 1., 1.);
+
+#ifdef AO
+	ambientOcclusion = mix(vec3(1.), texture2D(aoSampler, vAOUV).rrr, vAOInfos.y);
+#endif
+
+	vec3 ambientDiffuse = irradiance * (1. - F) * diffuseColor;
+	vec3 ambientSpecular = environmentSpecular * (F0 * environmentBRDF.x + environmentBRDF.y);
+
+	// Emissive
+	vec3 emissiveColor = vEmissiveColor;
+
+#ifdef EMISSIVE
+	emissiveColor += texture2D(emissiveSampler, vEmissiveUV).rgb * vEmissiveInfos.y;
+#endif
+
+	// Composition
+	vec3 finalDiffuse = diffuseBase * diffuseColor;
+	vec4 color = vec4(finalDiffuse + specularBase + (ambientDiffuse + ambientSpecular) * ambientOcclusion + emissiveColor, albedo.a);
+
+#include<fogFragment>
+
+	gl_FragColor = color;
+}` 
+
+ShadersStore["pbr_vertex"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+// Attributes
+attribute vec3 position;
+attribute vec3 normal;
+#ifdef TANGENT
+attribute vec4 tangent;
+#endif
+#ifdef UV1
+attribute vec2 uv;
+#endif
+#ifdef UV2
+attribute vec2 uv2;
+#endif
+#ifdef VERTEXCOLOR
+attribute vec3 color;
+#endif
+#include<instancesDeclaration>
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
 
 // Uniforms
-uniform vec2 textureInfos;
+uniform mat4 world;
 uniform mat4 view;
-uniform mat4 projection;
+uniform mat4 worldViewProjection;
+#if defined(INSTANCES) || defined(BONES)
+uniform mat4 viewProjection;
+#endif
+#include<morphTargetsVertexGlobalDeclaration>
+
+#ifdef ALBEDO
+varying vec2 vAlbedoUV;
+uniform mat4 albedoMatrix;
+uniform vec2 vAlbedoInfos;
+#endif
+
+#ifdef METALLICROUGHNESS
+varying vec2 vMetallicRoughnessUV;
+uniform mat4 metallicRoughnessMatrix;
+uniform vec2 vMetallicRoughnessInfos;
+#endif
+
+#ifdef AO
+varying vec2 vAOUV;
+uniform mat4 aoMatrix;
+uniform vec2 vAOInfos;
+#endif
+
+#ifdef EMISSIVE
+varying vec2 vEmissiveUV;
+uniform mat4 emissiveMatrix;
+uniform vec2 vEmissiveInfos;
+#endif
+
+#include<bumpVertexDeclaration>
 
 // Output
-varying vec2 vUV;
-varying vec4 vColor;
+varying vec3 vPositionW;
+varying vec3 vNormalW;
 
-#ifdef FOG
-varying float fFogDistance;
+#ifdef VERTEXCOLOR
+varying vec3 vColor;
 #endif
 
-void main(void) {	
-	vec3 viewPos = (view * vec4(position, 1.0)).xyz; 
-	vec3 cornerPos;
-	
-	float angle = options.x;
-	float size = options.y;
-	vec2 offset = options.zw;
-	vec2 uvScale = textureInfos.xy;
+#include<clipPlaneVertexDeclaration>
+#include<fogVertexDeclaration>
 
-	cornerPos = vec3(offset.x - 0.5, offset.y  - 0.5, 0.) * size;
+#include<shadowsVertexDeclaration>[0..maxSimultaneousLights]
 
-	// Rotate
-	vec3 rotatedCorner;
-	rotatedCorner.x = cornerPos.x * cos(angle) - cornerPos.y * sin(angle);
-	rotatedCorner.y = cornerPos.x * sin(angle) + cornerPos.y * cos(angle);
-	rotatedCorner.z = 0.;
+void main(void) {
+	vec3 positionUpdated = position;
+	vec3 normalUpdated = normal;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
 
-	// Position
-	viewPos += rotatedCorner;
-	gl_Position = projection * vec4(viewPos, 1.0);   
+#include<instancesVertex>
+#include<bonesVertex>
 
-	// Color
-	vColor = color;
-	
-	// Texture
-	vec2 uvOffset = vec2(abs(offset.x - cellInfo.x), 1.0 - abs(offset.y - cellInfo.y));
+#if defined(INSTANCES) || defined(BONES)
+	gl_Position = viewProjection * finalWorld * vec4(positionUpdated, 1.0);
+#else
+	gl_Position = worldViewProjection * vec4(positionUpdated, 1.0);
+#endif
 
-	vUV = (uvOffset + cellInfo.zw) * uvScale;
+	vec4 worldPos = finalWorld * vec4(positionUpdated, 1.0);
+	vPositionW = vec3(worldPos);
+	vNormalW = normalize(vec3(finalWorld * vec4(normalUpdated, 0.0)));
+
+	// Texture coordinates
+#ifndef UV1
+	vec2 uv = vec2(0., 0.);
+#endif
+#ifndef UV2
+	vec2 uv2 = vec2(0., 0.);
+#endif
+
+#ifdef ALBEDO
+	if (vAlbedoInfos.x == 0.)
+	{
+		vAlbedoUV = vec2(albedoMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vAlbedoUV = vec2(albedoMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#ifdef METALLICROUGHNESS
+	if (vMetallicRoughnessInfos.x == 0.)
+	{
+		vMetallicRoughnessUV = vec2(metallicRoughnessMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vMetallicRoughnessUV = vec2(metallicRoughnessMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#ifdef AO
+	if (vAOInfos.x == 0.)
+	{
+		vAOUV = vec2(aoMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vAOUV = vec2(aoMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#ifdef EMISSIVE
+	if (vEmissiveInfos.x == 0.)
+	{
+		vEmissiveUV = vec2(emissiveMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vEmissiveUV = vec2(emissiveMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#include<bumpVertex>
+
+	// Clip plane
+#include<clipPlaneVertex>
 
 	// Fog
-#ifdef FOG
-	fFogDistance = viewPos.z;
+#include<fogVertex>
+
+	// Shadows
+#include<shadowsVertex>[0..maxSimultaneousLights]
+
+	// Vertex color
+#ifdef VERTEXCOLOR
+	vColor = color;
 #endif
 }` 
 
+ShadersStore["shadowMap_fragment"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+vec4 pack(float depth)
+{
+	const vec4 bitOffset = vec4(255. * 255. * 255., 255. * 255., 255., 1.);
+	const vec4 bitMask = vec4(0., 1. / 255., 1. / 255., 1. / 255.);
+	
+	vec4 comp = fract(depth * bitOffset);
+	comp -= comp.xxyz * bitMask;
+	
+	return comp;
+}
+
+// Thanks to http://devmaster.net/
+vec2 packHalf(float depth) 
+{ 
+	const vec2 bitOffset = vec2(1.0 / 255., 0.);
+	vec2 color = vec2(depth, fract(depth * 255.));
+
+	return color - (color.yy * bitOffset);
+}
+
+
+void main(void)
+{
+#ifdef VSM
+	float moment1 = gl_FragCoord.z / gl_FragCoord.w; 
+	float moment2 = moment1 * moment1;
+	gl_FragColor = vec4(packHalf(moment1), packHalf(moment2));
+#else
+	gl_FragColor = pack(gl_FragCoord.z / gl_FragCoord.w);
+#endif
+}` 
+
+ShadersStore["shadowMap_vertex"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+// Attribute
+attribute vec3 position;
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
+
+// Uniform
+uniform mat4 worldViewProjection;
+#include<morphTargetsVertexGlobalDeclaration>
+
+void main(void)
+{
+	vec3 positionUpdated = position;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+	// The world is in worldViewProjection, the bones are applied on top of it
+	mat4 finalWorld = mat4(1.0);
+#include<bonesVertex>
+
+	gl_Position = worldViewProjection * finalWorld * vec4(positionUpdated, 1.0);
+}` 
+
+ShadersStore["sprites_fragment"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+uniform bool alphaTest;
+
+varying vec4 vColor;
+
+// Samplers
+varying vec2 vUV;
+uniform sampler2D diffuseSampler;
+
+// Fog
+#ifdef FOG
+
+#define FOGMODE_NONE    0.
+#define FOGMODE_EXP     1.
+#define FOGMODE_EXP2    2.
+#define FOGMODE_LINEAR  3.
+#define E 2.71828
+
+uniform vec4 vFogInfos;
+uniform vec3 vFogColor;
+varying float fFogDistance;
+
+float CalcFogFactor()
+{
+	float fogCoeff = 1.0;
+	float fogStart = vFogInfos.y;
+	float fogEnd = vFogInfos.z;
+	float fogDensity = vFogInfos.w;
+
+	if (FOGMODE_LINEAR == vFogInfos.x)
+	{
+		fogCoeff = (fogEnd - fFogDistance) / (fogEnd - fogStart);
+	}
+	else if (FOGMODE_EXP == vFogInfos.x)
+	{
+		fogCoeff = 1.0 / pow(E, fFogDistance * fogDensity);
+	}
+	else if (FOGMODE_EXP2 == vFogInfos.x)
+	{
+		fogCoeff = 1.0 / pow(E, fFogDistance * fFogDistance * fogDensity * fogDensity);
+	}
+
+	return min(1., max(0., fogCoeff));
+}
+#endif
+
+
+void main(void) {
+	vec4 baseColor = texture2D(diffuseSampler, vUV);
+
+	if (alphaTest) 
+	{
+		if (baseColor.a < 0.95)
+			discard;
+	}
+
+	baseColor *= vColor;
+
+#ifdef FOG
+	float fog = CalcFogFactor();
+	baseColor.rgb = fog * baseColor.rgb + (1.0 - fog) * vFogColor;
+#endif
+
+	gl_FragColor = baseColor;
+}` 
+
+ShadersStore["sprites_vertex"] = `#ifdef GL_ES
+precision mediump float;
+#endif
+
+// Attributes
+attribute vec3 position;
+attribute vec4 options;
+attribute vec4 cellInfo;
+attribute vec4 color;
+
+// Uniforms
+uniform vec2 textureInfos;
+uniform mat4 view;
+uniform mat4 projection;
+
+// Output
+varying vec2 vUV;
+varying vec4 vColor;
+
+#ifdef FOG
+varying float fFogDistance;
+#endif
+
+void main(void) {	
+	vec3 viewPos = (view * vec4(position, 1.0)).xyz; 
+	vec3 cornerPos;
+	
+	float angle = options.x;
+	float size = options.y;
+	vec2 offset = options.zw;
+	vec2 uvScale = textureInfos.xy;
+
+	cornerPos = vec3(offset.x - 0.5, offset.y  - 0.5, 0.) * size;
+
+	// Rotate
+	vec3 rotatedCorner;
+	rotatedCorner.x = cornerPos.x * cos(angle) - cornerPos.y * sin(angle);
+	rotatedCorner.y = cornerPos.x * sin(angle) + cornerPos.y * cos(angle);
+	rotatedCorner.z = 0.;
+
+	// Position
+	viewPos += rotatedCorner;
+	gl_Position = projection * vec4(viewPos, 1.0);   
+
+	// Color
+	vColor = color;
+	
+	// Texture
+	vec2 uvOffset = vec2(abs(offset.x - cellInfo.x), 1.0 - abs(offset.y - cellInfo.y));
+
+	vUV = (uvOffset + cellInfo.zw) * uvScale;
+
+	// Fog
+#ifdef FOG
+	fFogDistance = viewPos.z;
+#endif
+}` 
+
+ShadersStore["bonesDeclaration"] = `#ifdef BONES
+attribute vec4 matricesIndices;
+attribute vec4 matricesWeights;
+uniform mat4 mBones[BonesPerMesh];
+#endif` 
+
+ShadersStore["bonesVertex"] = `#ifdef BONES
+	mat4 m0 = mBones[int(matricesIndices.x)] * matricesWeights.x;
+	mat4 m1 = mBones[int(matricesIndices.y)] * matricesWeights.y;
+	mat4 m2 = mBones[int(matricesIndices.z)] * matricesWeights.z;
+	mat4 m3 = mBones[int(matricesIndices.w)] * matricesWeights.w;
+	finalWorld = finalWorld * (m0 + m1 + m2 + m3);
+#endif` 
+
+ShadersStore["bumpFragmentFunctions"] = `#ifdef BUMP
+varying vec2 vBumpUV;
+uniform vec2 vBumpInfos;
+uniform sampler2D bumpSampler;
+
+#ifdef TANGENT
+varying vec3 vTangentW;
+varying vec3 vBitangentW;
+
+// The basis of the tangent space comes from the tangents of the mesh
+mat3 tangent_frame(vec3 normal)
+{
+	return mat3(normalize(vTangentW), normalize(vBitangentW), normal);
+}
+#else
+#extension GL_OES_standard_derivatives : enable
+
+// Thanks to http://www.thetenthplanet.de/archives/1180
+mat3 cotangent_frame(vec3 normal, vec3 p, vec2 uv)
+{
+	// get edge vectors of the pixel triangle
+	vec3 dp1 = dFdx(p);
+	vec3 dp2 = dFdy(p);
+	vec2 duv1 = dFdx(uv);
+	vec2 duv2 = dFdy(uv);
+
+	// solve the linear system
+	vec3 dp2perp = cross(dp2, normal);
+	vec3 dp1perp = cross(normal, dp1);
+	vec3 tangent = dp2perp * duv1.x + dp1perp * duv2.x;
+	vec3 binormal = dp2perp * duv1.y + dp1perp * duv2.y;
+
+	// construct a scale-invariant frame 
+	float invmax = inversesqrt(max(dot(tangent, tangent), dot(binormal, binormal)));
+	return mat3(tangent * invmax, binormal * invmax, normal);
+}
+#endif
+
+vec3 perturbNormal(vec3 viewDir)
+{
+	vec3 map = texture2D(bumpSampler, vBumpUV).xyz * vBumpInfos.y;
+	map = map * 255. / 127. - 128. / 127.;
+#ifdef TANGENT
+	mat3 TBN = tangent_frame(normalize(vNormalW));
+#else
+	mat3 TBN = cotangent_frame(vNormalW, -viewDir, vBumpUV);
+#endif
+	return normalize(TBN * map);
+}
+#endif` 
+
+ShadersStore["bumpVertex"] = `#ifdef BUMP
+	if (vBumpInfos.x == 0.)
+	{
+		vBumpUV = vec2(bumpMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vBumpUV = vec2(bumpMatrix * vec4(uv2, 1.0, 0.0));
+	}
+
+#ifdef TANGENT
+	// w is the handedness of the tangent space, negative for mirrored uvs
+	vTangentW = normalize(vec3(finalWorld * vec4(tangent.xyz, 0.0)));
+	vBitangentW = cross(vNormalW, vTangentW) * tangent.w;
+#endif
+#endif` 
+
+ShadersStore["bumpVertexDeclaration"] = `#ifdef BUMP
+varying vec2 vBumpUV;
+uniform mat4 bumpMatrix;
+uniform vec2 vBumpInfos;
+#ifdef TANGENT
+varying vec3 vTangentW;
+varying vec3 vBitangentW;
+#endif
+#endif` 
+
+ShadersStore["clipPlaneFragment"] = `#ifdef CLIPPLANE
+	if (fClipDistance > 0.0)
+		discard;
+#endif` 
+
+ShadersStore["clipPlaneFragmentDeclaration"] = `#ifdef CLIPPLANE
+varying float fClipDistance;
+#endif` 
+
+ShadersStore["clipPlaneVertex"] = `#ifdef CLIPPLANE
+	fClipDistance = dot(worldPos, vClipPlane);
+#endif` 
+
+ShadersStore["clipPlaneVertexDeclaration"] = `#ifdef CLIPPLANE
+uniform vec4 vClipPlane;
+varying float fClipDistance;
+#endif` 
+
+ShadersStore["fogFragment"] = `#ifdef FOG
+	float fog = CalcFogFactor();
+	color.rgb = fog * color.rgb + (1.0 - fog) * vFogColor;
+#endif` 
+
+ShadersStore["fogFragmentDeclaration"] = `#ifdef FOG
+
+#define FOGMODE_NONE    0.
+#define FOGMODE_EXP     1.
+#define FOGMODE_EXP2    2.
+#define FOGMODE_LINEAR  3.
+#define E 2.71828
+
+uniform vec4 vFogInfos;
+uniform vec3 vFogColor;
+varying float fFogDistance;
+
+float CalcFogFactor()
+{
+	float fogCoeff = 1.0;
+	float fogStart = vFogInfos.y;
+	float fogEnd = vFogInfos.z;
+	float fogDensity = vFogInfos.w;
+
+	if (FOGMODE_LINEAR == vFogInfos.x)
+	{
+		fogCoeff = (fogEnd - fFogDistance) / (fogEnd - fogStart);
+	}
+	else if (FOGMODE_EXP == vFogInfos.x)
+	{
+		fogCoeff = 1.0 / pow(E, fFogDistance * fogDensity);
+	}
+	else if (FOGMODE_EXP2 == vFogInfos.x)
+	{
+		fogCoeff = 1.0 / pow(E, fFogDistance * fFogDistance * fogDensity * fogDensity);
+	}
+
+	return min(1., max(0., fogCoeff));
+}
+#endif` 
+
+ShadersStore["fogVertex"] = `#ifdef FOG
+	fFogDistance = (view * worldPos).z;
+#endif` 
+
+ShadersStore["fogVertexDeclaration"] = `#ifdef FOG
+varying float fFogDistance;
+#endif` 
+
+ShadersStore["instancesDeclaration"] = `#ifdef INSTANCES
+attribute vec4 world0;
+attribute vec4 world1;
+attribute vec4 world2;
+attribute vec4 world3;
+#endif` 
+
+ShadersStore["instancesVertex"] = `#ifdef INSTANCES
+	mat4 finalWorld = mat4(world0, world1, world2, world3);
+#else
+	mat4 finalWorld = world;
+#endif` 
+
+ShadersStore["lightFragment"] = `#ifdef LIGHT{X}
+#ifdef SPOTLIGHT{X}
+	info = computeSpotLighting(viewDirectionW, normalW, vLightData{X}, vLightDirection{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightDiffuse{X}.a);
+#endif
+#ifdef HEMILIGHT{X}
+	info = computeHemisphericLighting(viewDirectionW, normalW, vLightData{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightGround{X});
+#endif
+#ifdef POINTDIRLIGHT{X}
+	info = computeLighting(viewDirectionW, normalW, vLightData{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightDiffuse{X}.a);
+#endif
+#include<shadowFragment>
+	diffuseBase += info.diffuse * shadow;
+	specularBase += info.specular * shadow;
+#endif` 
+
+ShadersStore["lightFragmentDeclaration"] = `#ifdef LIGHT{X}
+uniform vec4 vLightData{X};
+uniform vec4 vLightDiffuse{X};
+uniform vec3 vLightSpecular{X};
+#ifdef SHADOW{X}
+varying vec4 vPositionFromLight{X};
+uniform sampler2D shadowSampler{X};
+#endif
+#ifdef SPOTLIGHT{X}
+uniform vec4 vLightDirection{X};
+#endif
+#ifdef HEMILIGHT{X}
+uniform vec3 vLightGround{X};
+#endif
+#endif` 
+
+ShadersStore["lightsFragmentFunctions"] = `// Light Computing
+struct lightingInfo
+{
+	vec3 diffuse;
+	vec3 specular;
+};
+
+lightingInfo computeLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, float range) {
+	lightingInfo result;
+
+	vec3 lightVectorW;
+	float attenuation = 1.0;
+	if (lightData.w == 0.)
+	{
+		vec3 direction = lightData.xyz - vPositionW;
+
+		attenuation = max(0., 1.0 - length(direction) / range);
+		lightVectorW = normalize(direction);
+	}
+	else
+	{
+		lightVectorW = normalize(-lightData.xyz);
+	}
+
+	// diffuse
+	float ndl = max(0., dot(vNormal, lightVectorW));
+
+	// Specular
+	vec3 angleW = normalize(viewDirectionW + lightVectorW);
+	float specComp = max(0., dot(vNormal, angleW));
+	specComp = pow(specComp, vSpecularColor.a);
+
+	result.diffuse = ndl * diffuseColor * attenuation;
+	result.specular = specComp * specularColor * attenuation;
+
+	return result;
+}
+
+lightingInfo computeSpotLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec4 lightDirection, vec3 diffuseColor, vec3 specularColor, float range) {
+	lightingInfo result;
+
+	vec3 direction = lightData.xyz - vPositionW;
+	vec3 lightVectorW = normalize(direction);
+	float attenuation = max(0., 1.0 - length(direction) / range);
+
+	// diffuse
+	float cosAngle = max(0., dot(-lightDirection.xyz, lightVectorW));
+	float spotAtten = 0.0;
+
+	if (cosAngle >= lightDirection.w)
+	{
+		cosAngle = max(0., pow(cosAngle, lightData.w));
+		spotAtten = max(0., (cosAngle - lightDirection.w) / (1. - cosAngle));
+
+		// Diffuse
+		float ndl = max(0., dot(vNormal, -lightDirection.xyz));
+
+		// Specular
+		vec3 angleW = normalize(viewDirectionW - lightDirection.xyz);
+		float specComp = max(0., dot(vNormal, angleW));
+		specComp = pow(specComp, vSpecularColor.a);
+
+		result.diffuse = ndl * spotAtten * diffuseColor * attenuation;
+		result.specular = specComp * specularColor * spotAtten * attenuation;
+
+		return result;
+	}
+
+	result.diffuse = vec3(0.);
+	result.specular = vec3(0.);
+
+	return result;
+}
+
+lightingInfo computeHemisphericLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, vec3 groundColor) {
+	lightingInfo result;
+
+	// Diffuse
+	float ndl = dot(vNormal, lightData.xyz) * 0.5 + 0.5;
+
+	// Specular
+	vec3 angleW = normalize(viewDirectionW + lightData.xyz);
+	float specComp = max(0., dot(vNormal, angleW));
+	specComp = pow(specComp, vSpecularColor.a);
+
+	result.diffuse = mix(groundColor, diffuseColor, ndl);
+	result.specular = specComp * specularColor;
+
+	return result;
+}` 
+
+ShadersStore["morphTargetsVertex"] = `#ifdef MORPHTARGET{X}
+	positionUpdated += (position{X} - position) * morphTargetInfluences[{X}];
+#ifdef MORPHTARGETS_NORMAL
+	normalUpdated += (normal{X} - normal) * morphTargetInfluences[{X}];
+#endif
+#endif` 
+
+ShadersStore["morphTargetsVertexDeclaration"] = `#ifdef MORPHTARGET{X}
+attribute vec3 position{X};
+#ifdef MORPHTARGETS_NORMAL
+attribute vec3 normal{X};
+#endif
+#endif` 
+
+ShadersStore["morphTargetsVertexGlobalDeclaration"] = `#ifdef MORPHTARGETS
+uniform float morphTargetInfluences[NUM_MORPH_INFLUENCERS];
+#endif` 
+
+ShadersStore["pbrLightFragment"] = `#ifdef LIGHT{X}
+#ifdef SPOTLIGHT{X}
+	info = computeSpotLighting(viewDirectionW, normalW, vLightData{X}, vLightDirection{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightDiffuse{X}.a, F0, alpha);
+#endif
+#ifdef HEMILIGHT{X}
+	info = computeHemisphericLighting(viewDirectionW, normalW, vLightData{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightGround{X}, F0, alpha);
+#endif
+#ifdef POINTDIRLIGHT{X}
+	info = computeLighting(viewDirectionW, normalW, vLightData{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightDiffuse{X}.a, F0, alpha);
+#endif
+#include<shadowFragment>
+	diffuseBase += info.diffuse * shadow;
+	specularBase += info.specular * shadow;
+#endif` 
+
+ShadersStore["shadowFragment"] = `#ifdef SHADOW{X}
+	#ifdef SHADOWVSM{X}
+		shadow = computeShadowWithVSM(vPositionFromLight{X}, shadowSampler{X});
+	#else
+		shadow = computeShadow(vPositionFromLight{X}, shadowSampler{X});
+	#endif
+#else
+	shadow = 1.;
+#endif` 
+
+ShadersStore["shadowsFragmentFunctions"] = `#ifdef SHADOWS
+
+float unpack(vec4 color)
+{
+	const vec4 bitShift = vec4(1. / (255. * 255. * 255.), 1. / (255. * 255.), 1. / 255., 1.);
+	return dot(color, bitShift);
+}
+
+float unpackHalf(vec2 color) 
+{ 
+	return color.x + (color.y / 255.0);
+}
+
+float computeShadow(vec4 vPositionFromLight, sampler2D shadowSampler)
+{
+	vec3 depth = vPositionFromLight.xyz / vPositionFromLight.w;
+	vec2 uv = 0.5 * depth.xy + vec2(0.5, 0.5);
+
+	if (uv.x < 0. || uv.x > 1.0 || uv.y < 0. || uv.y > 1.0)
+	{
+		return 1.0;
+	}
+
+	float shadow = unpack(texture2D(shadowSampler, uv));
+
+	if (depth.z > shadow)
+	{
+		return 0.;
+	}
+	return 1.;
+}
+
+// Thanks to http://devmaster.net/
+float ChebychevInequality(vec2 moments, float t) 
+{
+	if (t <= moments.x)
+	{
+		return 1.0;
+	}
+	
+	float variance = moments.y - (moments.x * moments.x); 
+	variance = max(variance, 0.);
+
+	float d = t - moments.x; 	
+	return variance / (variance + d * d); 
+}
+
+float computeShadowWithVSM(vec4 vPositionFromLight, sampler2D shadowSampler)
+{
+	vec3 depth = vPositionFromLight.xyz / vPositionFromLight.w;
+	vec2 uv = 0.5 * depth.xy + vec2(0.5, 0.5);
+
+	if (uv.x < 0. || uv.x > 1.0 || uv.y < 0. || uv.y > 1.0)
+	{
+		return 1.0;
+	}
+
+	vec4 texel = texture2D(shadowSampler, uv);
+
+	vec2 moments = vec2(unpackHalf(texel.xy), unpackHalf(texel.zw));
+	return clamp(1.3 - ChebychevInequality(moments, depth.z), 0., 1.0);
+}
+#endif` 
+
+ShadersStore["shadowsVertex"] = `#ifdef SHADOWS
+#ifdef LIGHT{X}
+	vPositionFromLight{X} = lightMatrix{X} * worldPos;
+#endif
+#endif` 
+
+ShadersStore["shadowsVertexDeclaration"] = `#ifdef SHADOWS
+#ifdef LIGHT{X}
+uniform mat4 lightMatrix{X};
+varying vec4 vPositionFromLight{X};
+#endif
+#endif` 
+
 } 
 
@@ -0,0 +1,106 @@
+package effects
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/core"
+	"github.com/suiqirui1987/fly3d/tools"
+)
+
+// maxIncludeDepth stops chunks including each other forever
+const maxIncludeDepth = 16
+
+var includeRegexp = regexp.MustCompile(`^\s*#include\s*<(\w+)>\s*(?:\[\s*(\w+)\s*\.\.\s*(\w+)\s*\])?\s*$`)
+
+// processShader replaces the #include directives of the code of a shader by
+// the chunks they name
+//
+//	#include<fogFragmentDeclaration>
+//	#include<lightFragmentDeclaration>[0..maxSimultaneousLights]
+//
+// The second form writes the chunk once per index of the range, end
+// excluded, {X} replaced by the index. The bounds are numbers or the names
+// of a #define of defines. Chunks may include other chunks
+func processShader(code string, defines string) (string, error) {
+	return processIncludes(code, defines, 0)
+}
+
+func processIncludes(code string, defines string, depth int) (string, error) {
+	lines := strings.Split(code, "\n")
+	result := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		match := includeRegexp.FindStringSubmatch(line)
+		if match == nil {
+			result = append(result, line)
+			continue
+		}
+
+		if depth >= maxIncludeDepth {
+			return "", fmt.Errorf("shader include %s is nested too deep", match[1])
+		}
+
+		chunk, err := includeShader(match[1])
+		if err != nil {
+			return "", err
+		}
+		chunk, err = processIncludes(chunk, defines, depth+1)
+		if err != nil {
+			return "", err
+		}
+
+		if match[2] == "" {
+			result = append(result, chunk)
+			continue
+		}
+
+		start := defineValue(match[2], defines)
+		end := defineValue(match[3], defines)
+		for index := start; index < end; index++ {
+			result = append(result, strings.Replace(chunk, "{X}", strconv.Itoa(index), -1))
+		}
+	}
+
+	return strings.Join(result, "\n"), nil
+}
+
+// includeShader returns the chunk name of ShadersStore, else loads it from
+// ShadersInclude in the shaders repository and keeps it in ShadersStore
+func includeShader(name string) (string, error) {
+	if chunk, ok := ShadersStore[name]; ok {
+		return chunk, nil
+	}
+
+	var loadErr error
+	url := core.GlobalFly3D.ResRepository + core.GlobalFly3D.ShadersRepository + "ShadersInclude/" + name + ".fx"
+	tools.LoadFile(url, func(chunk string) {
+		ShadersStore[name] = strings.Replace(chunk, "\r\n", "\n", -1)
+	}, nil, func(err error) {
+		loadErr = err
+	})
+	if loadErr != nil {
+		return "", fmt.Errorf("shader include %s not found: %s", name, loadErr)
+	}
+
+	return ShadersStore[name], nil
+}
+
+// defineValue reads a bound of an #include range, a number or a define
+func defineValue(value string, defines string) int {
+	if number, err := strconv.Atoi(value); err == nil {
+		return number
+	}
+
+	for _, line := range strings.Split(defines, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "#define" && fields[1] == value {
+			number, _ := strconv.Atoi(fields[2])
+			return number
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,61 @@
+// +build glnull glsoft
+
+package effects
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProcessShader(t *testing.T) {
+	chunks := map[string]string{
+		"testPlain":  "float plain;",
+		"testIndex":  "uniform vec4 color{X};",
+		"testNested": "#include<testPlain>\nfloat nested;",
+		"testLoop":   "#include<testLoop>",
+	}
+	for name, chunk := range chunks {
+		ShadersStore[name] = chunk
+	}
+	defer func() {
+		for name := range chunks {
+			delete(ShadersStore, name)
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		code    string
+		defines string
+		want    string
+		err     string
+	}{
+		{"no include", "void main() {}", "", "void main() {}", ""},
+		{"include", "#include<testPlain>\nvoid main() {}", "", "float plain;\nvoid main() {}", ""},
+		{"spaces", "  #include <testPlain>  ", "", "float plain;", ""},
+		{"not a directive", "// #include<testPlain>", "", "// #include<testPlain>", ""},
+		{"number range", "#include<testIndex>[0..3]", "", "uniform vec4 color0;\nuniform vec4 color1;\nuniform vec4 color2;", ""},
+		{"define range", "#include<testIndex>[1..maxLights]", "#define A\n#define maxLights 3", "uniform vec4 color1;\nuniform vec4 color2;", ""},
+		{"undefined range", "a\n#include<testIndex>[0..maxLights]\nb", "", "a\nb", ""},
+		{"nested", "#include<testNested>", "", "float plain;\nfloat nested;", ""},
+		{"recursive", "#include<testLoop>", "", "", "nested too deep"},
+		{"missing", "#include<testMissingChunk>", "", "", "testMissingChunk not found"},
+	}
+
+	for _, test := range tests {
+		got, err := processShader(test.code, test.defines)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
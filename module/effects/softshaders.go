@@ -0,0 +1,1399 @@
+// +build glsoft
+
+package effects
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/gl"
+)
+
+// Go versions of the built-in shaders for the software gl backend. They
+// follow the GLSL in ShadersStore line by line, except that BUMP needs
+// TANGENT because the rasterizer has no derivatives.
+
+func init() {
+	gl.RegisterSoftShader(softMatch("color"), newSoftColor)
+	gl.RegisterSoftShader(softMatch("default"), newSoftDefault)
+	gl.RegisterSoftShader(softMatch("iedefault"), newSoftDefault)
+	gl.RegisterSoftShader(softMatch("layer"), newSoftLayer)
+	gl.RegisterSoftShader(softMatch("particles"), newSoftParticles)
+	gl.RegisterSoftShader(softMatch("pbr"), newSoftPBR)
+	gl.RegisterSoftShader(softMatch("shadowMap"), newSoftShadowMap)
+	gl.RegisterSoftShader(softMatch("sprites"), newSoftSprites)
+}
+
+// softMatch accepts programs built from a ShadersStore entry, defines are prepended to the processed code
+func softMatch(baseName string) func(vertexSource, fragmentSource string) bool {
+	return func(vertexSource, fragmentSource string) bool {
+		vertex, ok := ShadersStore[baseName+"_vertex"]
+		if !ok {
+			return false
+		}
+		fragment, ok := ShadersStore[baseName+"_fragment"]
+		if !ok {
+			return false
+		}
+		// The defines lead the sources, which is all processShader reads
+		vertex, err := processShader(vertex, vertexSource)
+		if err != nil {
+			return false
+		}
+		fragment, err = processShader(fragment, fragmentSource)
+		if err != nil {
+			return false
+		}
+		return strings.HasSuffix(vertexSource, vertex) && strings.HasSuffix(fragmentSource, fragment)
+	}
+}
+
+type vec3 [3]float32
+
+func (this vec3) add(other vec3) vec3 {
+	return vec3{this[0] + other[0], this[1] + other[1], this[2] + other[2]}
+}
+func (this vec3) sub(other vec3) vec3 {
+	return vec3{this[0] - other[0], this[1] - other[1], this[2] - other[2]}
+}
+func (this vec3) mul(other vec3) vec3 {
+	return vec3{this[0] * other[0], this[1] * other[1], this[2] * other[2]}
+}
+func (this vec3) scale(s float32) vec3 {
+	return vec3{this[0] * s, this[1] * s, this[2] * s}
+}
+func (this vec3) dot(other vec3) float32 {
+	return this[0]*other[0] + this[1]*other[1] + this[2]*other[2]
+}
+func (this vec3) normalize() vec3 {
+	length := float32(math.Sqrt(float64(this.dot(this))))
+	if length == 0 {
+		return this
+	}
+	return this.scale(1 / length)
+}
+func (this vec3) cross(other vec3) vec3 {
+	return vec3{this[1]*other[2] - this[2]*other[1], this[2]*other[0] - this[0]*other[2], this[0]*other[1] - this[1]*other[0]}
+}
+func (this vec3) reflect(normal vec3) vec3 {
+	return this.sub(normal.scale(2 * normal.dot(this)))
+}
+func (this vec3) clamp() vec3 {
+	return vec3{clamp(this[0], 0, 1), clamp(this[1], 0, 1), clamp(this[2], 0, 1)}
+}
+
+func vec3Of(v [4]float32) vec3 {
+	return vec3{v[0], v[1], v[2]}
+}
+
+func clamp(value, min, max float32) float32 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func pow32(x, y float32) float32 {
+	return float32(math.Pow(float64(x), float64(y)))
+}
+
+func fract(x float32) float32 {
+	return x - float32(math.Floor(float64(x)))
+}
+
+// transform returns m * v with m uploaded by Engine.SetMatrix
+func transform(m [16]float32, x, y, z, w float32) [4]float32 {
+	return [4]float32{
+		x*m[0] + y*m[4] + z*m[8] + w*m[12],
+		x*m[1] + y*m[5] + z*m[9] + w*m[13],
+		x*m[2] + y*m[6] + z*m[10] + w*m[14],
+		x*m[3] + y*m[7] + z*m[11] + w*m[15],
+	}
+}
+
+// multiply returns a * b in the order of math32.Matrix4.Multiply
+func multiply(a, b [16]float32) [16]float32 {
+	var result [16]float32
+	for row := 0; row < 4; row++ {
+		for column := 0; column < 4; column++ {
+			for k := 0; k < 4; k++ {
+				result[row*4+column] += a[row*4+k] * b[k*4+column]
+			}
+		}
+	}
+	return result
+}
+
+// bonesMatrices returns the mBones uniform, BonesPerMesh matrices
+func bonesMatrices(program *gl.SoftProgram) []float32 {
+	count, _ := strconv.Atoi(program.Define("BonesPerMesh"))
+	return program.Uniform("mBones", 16*count)
+}
+
+// skin computes m0 + m1 + m2 + m3 of the BONES vertex shaders
+func skin(bones []float32, indices, weights [4]float32) [16]float32 {
+	var result [16]float32
+	for influence := 0; influence < 4; influence++ {
+		offset := int(indices[influence]) * 16
+		if offset < 0 || offset+16 > len(bones) {
+			continue
+		}
+		for index := 0; index < 16; index++ {
+			result[index] += bones[offset+index] * weights[influence]
+		}
+	}
+	return result
+}
+
+// morphInfluences returns morphTargetInfluences, one per MORPHTARGETn define
+func morphInfluences(program *gl.SoftProgram) []float32 {
+	if !program.Defined("MORPHTARGETS") {
+		return nil
+	}
+	count := 0
+	for program.Defined("MORPHTARGET" + strconv.Itoa(count)) {
+		count++
+	}
+	return program.Uniform("morphTargetInfluences", count)
+}
+
+// morph computes the MORPHTARGETn blocks of the vertex shaders, the target
+// attributes are stride apart
+func morph(value [4]float32, targets [][4]float32, stride int, influences []float32) [4]float32 {
+	result := value
+	for index, influence := range influences {
+		target := targets[index*stride]
+		for component := 0; component < 3; component++ {
+			result[component] += (target[component] - value[component]) * influence
+		}
+	}
+	return result
+}
+
+// fog computes CalcFogFactor, infos is vFogInfos
+func fog(infos [4]float32, distance float32) float32 {
+	fogCoeff := float32(1.0)
+	fogStart := infos[1]
+	fogEnd := infos[2]
+	fogDensity := infos[3]
+
+	switch infos[0] {
+	case 3:
+		fogCoeff = (fogEnd - distance) / (fogEnd - fogStart)
+	case 1:
+		fogCoeff = 1.0 / pow32(2.71828, distance*fogDensity)
+	case 2:
+		fogCoeff = 1.0 / pow32(2.71828, distance*distance*fogDensity*fogDensity)
+	}
+
+	return clamp(fogCoeff, 0, 1)
+}
+
+// default
+
+const (
+	softPositionW     = 0
+	softNormalW       = 3
+	softDiffuseUV     = 6
+	softAmbientUV     = 8
+	softOpacityUV     = 10
+	softReflectionUVW = 12
+	softEmissiveUV    = 15
+	softSpecularUV    = 17
+	softColor         = 19
+	softClipDistance  = 22
+	softFogDistance   = 23
+	softBumpUV        = 24
+	softTangentW      = 26
+	softBitangentW    = 29
+	softFromLight     = 32
+)
+
+const (
+	softPointDirLight = iota
+	softSpotLight
+	softHemiLight
+)
+
+type softLight struct {
+	kind       int
+	data       [4]float32
+	direction  [4]float32
+	diffuse    vec3
+	specular   vec3
+	ground     vec3
+	lightRange float32
+
+	shadow  bool
+	vsm     bool
+	matrix  [16]float32
+	sampler *gl.SoftSampler
+}
+
+// softLights reads the LIGHTn defines and the uniforms of the lights
+func softLights(program *gl.SoftProgram) []*softLight {
+	var lights []*softLight
+	for index := 0; program.Defined("LIGHT" + strconv.Itoa(index)); index++ {
+		suffix := strconv.Itoa(index)
+		light := &softLight{}
+		switch {
+		case program.Defined("SPOTLIGHT" + suffix):
+			light.kind = softSpotLight
+		case program.Defined("HEMILIGHT" + suffix):
+			light.kind = softHemiLight
+		case program.Defined("POINTDIRLIGHT" + suffix):
+			light.kind = softPointDirLight
+		default:
+			// Materials define the type of every light, the GLSL computes
+			// nothing for a light without one
+			continue
+		}
+		light.data = program.Vec4("vLightData" + suffix)
+		light.direction = program.Vec4("vLightDirection" + suffix)
+		light.diffuse = vec3Of(program.Vec4("vLightDiffuse" + suffix))
+		light.lightRange = program.Vec4("vLightDiffuse" + suffix)[3]
+		light.specular = vec3Of(program.Vec4("vLightSpecular" + suffix))
+		light.ground = vec3Of(program.Vec4("vLightGround" + suffix))
+		light.matrix = program.Matrix("lightMatrix" + suffix)
+		light.shadow = program.Defined("SHADOW" + suffix)
+		light.vsm = program.Defined("SHADOWVSM" + suffix)
+		light.sampler = program.Sampler("shadowSampler"+suffix, gl.TEXTURE_2D)
+		lights = append(lights, light)
+	}
+
+	return lights
+}
+
+type softDefault struct {
+	diffuse, ambient, opacity, reflection, emissive, specular bool
+	clipPlane, fog, vertexColor, alphaTest, shadows           bool
+	uv1, uv2, instances, bones, morphNormals, bump            bool
+
+	world, view, worldViewProjection, viewProjection [16]float32
+	mBones, morphTargetInfluences                    []float32
+
+	diffuseMatrix, ambientMatrix, opacityMatrix      [16]float32
+	emissiveMatrix, specularMatrix, reflectionMatrix [16]float32
+	diffuseInfos, ambientInfos, opacityInfos         [4]float32
+	emissiveInfos, specularInfos, reflectionInfos    [4]float32
+	bumpMatrix                                       [16]float32
+	bumpInfos                                        [4]float32
+
+	eyePosition   vec3
+	ambientColor  vec3
+	diffuseColor  [4]float32
+	specularColor [4]float32
+	emissiveColor vec3
+	clipPlaneEq   [4]float32
+	fogInfos      [4]float32
+	fogColor      vec3
+
+	diffuseSampler, ambientSampler, opacitySampler *gl.SoftSampler
+	emissiveSampler, specularSampler               *gl.SoftSampler
+	reflectionCubeSampler, reflection2DSampler     *gl.SoftSampler
+	bumpSampler                                    *gl.SoftSampler
+
+	lights []*softLight
+}
+
+func newSoftDefault(program *gl.SoftProgram) gl.SoftShader {
+	this := &softDefault{}
+
+	this.diffuse = program.Defined("DIFFUSE")
+	this.ambient = program.Defined("AMBIENT")
+	this.opacity = program.Defined("OPACITY")
+	this.reflection = program.Defined("REFLECTION")
+	this.emissive = program.Defined("EMISSIVE")
+	this.specular = program.Defined("SPECULAR")
+	this.clipPlane = program.Defined("CLIPPLANE")
+	this.fog = program.Defined("FOG")
+	this.vertexColor = program.Defined("VERTEXCOLOR")
+	this.alphaTest = program.Defined("ALPHATEST")
+	this.shadows = program.Defined("SHADOWS")
+	this.uv1 = program.Defined("UV1")
+	this.uv2 = program.Defined("UV2")
+	this.instances = program.Defined("INSTANCES")
+	this.bones = program.Defined("BONES")
+	this.morphNormals = program.Defined("MORPHTARGETS_NORMAL")
+	this.bump = program.Defined("BUMP") && program.Defined("TANGENT")
+
+	this.world = program.Matrix("world")
+	this.view = program.Matrix("view")
+	this.worldViewProjection = program.Matrix("worldViewProjection")
+	this.viewProjection = program.Matrix("viewProjection")
+	if this.bones {
+		this.mBones = bonesMatrices(program)
+	}
+	this.morphTargetInfluences = morphInfluences(program)
+
+	this.diffuseMatrix = program.Matrix("diffuseMatrix")
+	this.ambientMatrix = program.Matrix("ambientMatrix")
+	this.opacityMatrix = program.Matrix("opacityMatrix")
+	this.emissiveMatrix = program.Matrix("emissiveMatrix")
+	this.specularMatrix = program.Matrix("specularMatrix")
+	this.reflectionMatrix = program.Matrix("reflectionMatrix")
+	this.bumpMatrix = program.Matrix("bumpMatrix")
+	this.diffuseInfos = program.Vec4("vDiffuseInfos")
+	this.ambientInfos = program.Vec4("vAmbientInfos")
+	this.opacityInfos = program.Vec4("vOpacityInfos")
+	this.emissiveInfos = program.Vec4("vEmissiveInfos")
+	this.specularInfos = program.Vec4("vSpecularInfos")
+	this.reflectionInfos = program.Vec4("vReflectionInfos")
+	this.bumpInfos = program.Vec4("vBumpInfos")
+
+	this.eyePosition = vec3Of(program.Vec4("vEyePosition"))
+	this.ambientColor = vec3Of(program.Vec4("vAmbientColor"))
+	this.diffuseColor = program.Vec4("vDiffuseColor")
+	this.specularColor = program.Vec4("vSpecularColor")
+	this.emissiveColor = vec3Of(program.Vec4("vEmissiveColor"))
+	this.clipPlaneEq = program.Vec4("vClipPlane")
+	this.fogInfos = program.Vec4("vFogInfos")
+	this.fogColor = vec3Of(program.Vec4("vFogColor"))
+
+	this.diffuseSampler = program.Sampler("diffuseSampler", gl.TEXTURE_2D)
+	this.ambientSampler = program.Sampler("ambientSampler", gl.TEXTURE_2D)
+	this.opacitySampler = program.Sampler("opacitySampler", gl.TEXTURE_2D)
+	this.emissiveSampler = program.Sampler("emissiveSampler", gl.TEXTURE_2D)
+	this.specularSampler = program.Sampler("specularSampler", gl.TEXTURE_2D)
+	this.reflectionCubeSampler = program.Sampler("reflectionCubeSampler", gl.TEXTURE_CUBE_MAP)
+	this.reflection2DSampler = program.Sampler("reflection2DSampler", gl.TEXTURE_2D)
+	this.bumpSampler = program.Sampler("bumpSampler", gl.TEXTURE_2D)
+
+	this.lights = softLights(program)
+
+	return this
+}
+
+func (this *softDefault) Attributes() []string {
+	return []string{"position", "normal", "uv", "uv2", "color", "world0", "world1", "world2", "world3", "matricesIndices", "matricesWeights",
+		"position0", "normal0", "position1", "normal1", "position2", "normal2", "position3", "normal3", "tangent"}
+}
+
+func (this *softDefault) Varyings() int {
+	return softFromLight + 4*len(this.lights)
+}
+
+func (this *softDefault) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	position := attributes[0]
+	normal := attributes[1]
+	uv := [2]float32{}
+	if this.uv1 {
+		uv = [2]float32{attributes[2][0], attributes[2][1]}
+	}
+	uv2 := [2]float32{}
+	if this.uv2 {
+		uv2 = [2]float32{attributes[3][0], attributes[3][1]}
+	}
+
+	positionUpdated := morph(position, attributes[11:], 2, this.morphTargetInfluences)
+	normalUpdated := normal
+	if this.morphNormals {
+		normalUpdated = morph(normal, attributes[12:], 2, this.morphTargetInfluences)
+	}
+
+	// The instances world matrix is read from the four world attributes
+	world := this.world
+	if this.instances {
+		for row := 0; row < 4; row++ {
+			copy(world[row*4:], attributes[5+row][:])
+		}
+	}
+	if this.bones {
+		world = multiply(skin(this.mBones, attributes[9], attributes[10]), world)
+	}
+
+	worldPos := transform(world, positionUpdated[0], positionUpdated[1], positionUpdated[2], 1)
+	normalW := vec3Of(transform(world, normalUpdated[0], normalUpdated[1], normalUpdated[2], 0)).normalize()
+	copy(varyings[softPositionW:], worldPos[:3])
+	copy(varyings[softNormalW:], normalW[:])
+
+	textureUV := func(infos [4]float32, matrix [16]float32, offset int) {
+		coords := uv
+		if infos[0] != 0 {
+			coords = uv2
+		}
+		result := transform(matrix, coords[0], coords[1], 1, 0)
+		varyings[offset] = result[0]
+		varyings[offset+1] = result[1]
+	}
+	if this.diffuse {
+		textureUV(this.diffuseInfos, this.diffuseMatrix, softDiffuseUV)
+	}
+	if this.ambient {
+		textureUV(this.ambientInfos, this.ambientMatrix, softAmbientUV)
+	}
+	if this.opacity {
+		textureUV(this.opacityInfos, this.opacityMatrix, softOpacityUV)
+	}
+	if this.reflection {
+		coords := this.reflectionCoords(worldPos, normalW, position)
+		copy(varyings[softReflectionUVW:], coords[:])
+	}
+	if this.emissive {
+		textureUV(this.emissiveInfos, this.emissiveMatrix, softEmissiveUV)
+	}
+	if this.specular {
+		textureUV(this.specularInfos, this.specularMatrix, softSpecularUV)
+	}
+	if this.bump {
+		textureUV(this.bumpInfos, this.bumpMatrix, softBumpUV)
+
+		// w is the handedness of the tangent space, negative for mirrored uvs
+		tangent := attributes[19]
+		tangentW := vec3Of(transform(world, tangent[0], tangent[1], tangent[2], 0)).normalize()
+		bitangentW := normalW.cross(tangentW).scale(tangent[3])
+		copy(varyings[softTangentW:], tangentW[:])
+		copy(varyings[softBitangentW:], bitangentW[:])
+	}
+
+	if this.clipPlane {
+		eq := this.clipPlaneEq
+		varyings[softClipDistance] = worldPos[0]*eq[0] + worldPos[1]*eq[1] + worldPos[2]*eq[2] + worldPos[3]*eq[3]
+	}
+
+	if this.fog {
+		varyings[softFogDistance] = transform(this.view, worldPos[0], worldPos[1], worldPos[2], worldPos[3])[2]
+	}
+
+	if this.shadows {
+		for index, light := range this.lights {
+			fromLight := transform(light.matrix, worldPos[0], worldPos[1], worldPos[2], worldPos[3])
+			copy(varyings[softFromLight+4*index:], fromLight[:])
+		}
+	}
+
+	if this.vertexColor {
+		copy(varyings[softColor:softColor+3], attributes[4][:3])
+	}
+
+	if this.instances || this.bones {
+		return transform(this.viewProjection, worldPos[0], worldPos[1], worldPos[2], worldPos[3])
+	}
+	return transform(this.worldViewProjection, positionUpdated[0], positionUpdated[1], positionUpdated[2], 1)
+}
+
+// reflectionCoords computes computeReflectionCoords
+func (this *softDefault) reflectionCoords(worldPos [4]float32, worldNormal vec3, position [4]float32) vec3 {
+	switch this.reflectionInfos[0] {
+	case 1: // MAP_SPHERICAL
+		coords := transform(this.view, worldNormal[0], worldNormal[1], worldNormal[2], 0)
+		return vec3Of(transform(this.reflectionMatrix, coords[0], coords[1], coords[2], 1))
+	case 2: // MAP_PLANAR
+		viewDir := vec3Of(worldPos).sub(this.eyePosition)
+		coords := viewDir.reflect(worldNormal).normalize()
+		return vec3Of(transform(this.reflectionMatrix, coords[0], coords[1], coords[2], 1))
+	case 3: // MAP_CUBIC
+		viewDir := vec3Of(worldPos).sub(this.eyePosition)
+		coords := viewDir.reflect(worldNormal)
+		return vec3Of(transform(this.reflectionMatrix, coords[0], coords[1], coords[2], 0))
+	case 4: // MAP_PROJECTION
+		viewPos := transform(this.view, worldPos[0], worldPos[1], worldPos[2], worldPos[3])
+		return vec3Of(transform(this.reflectionMatrix, viewPos[0], viewPos[1], viewPos[2], viewPos[3]))
+	case 5: // MAP_SKYBOX
+		return vec3Of(position)
+	}
+	return vec3{}
+}
+
+func unpack(color [4]float32) float32 {
+	return color[0]/(255.*255.*255.) + color[1]/(255.*255.) + color[2]/255. + color[3]
+}
+
+func unpackHalf(x, y float32) float32 {
+	return x + y/255.0
+}
+
+// attenuation fades the light out to nothing at its range
+func (this *softLight) attenuation(lightOffset vec3) float32 {
+	return float32(math.Max(0, float64(1-float32(math.Sqrt(float64(lightOffset.dot(lightOffset))))/this.lightRange)))
+}
+
+func (this *softLight) computeShadow(fromLight []float32) float32 {
+	depth := vec3{fromLight[0] / fromLight[3], fromLight[1] / fromLight[3], fromLight[2] / fromLight[3]}
+	u := 0.5*depth[0] + 0.5
+	v := 0.5*depth[1] + 0.5
+
+	if u < 0 || u > 1 || v < 0 || v > 1 {
+		return 1
+	}
+
+	texel := this.sampler.Sample(u, v)
+	if this.vsm {
+		// ChebychevInequality
+		moments := [2]float32{unpackHalf(texel[0], texel[1]), unpackHalf(texel[2], texel[3])}
+		result := float32(1)
+		if depth[2] > moments[0] {
+			variance := moments[1] - moments[0]*moments[0]
+			if variance < 0 {
+				variance = 0
+			}
+			d := depth[2] - moments[0]
+			result = variance / (variance + d*d)
+		}
+		return clamp(1.3-result, 0, 1)
+	}
+
+	if depth[2] > unpack(texel) {
+		return 0
+	}
+	return 1
+}
+
+func (this *softDefault) lighting(light *softLight, viewDirectionW, normal, positionW vec3) (vec3, vec3) {
+	data := light.data
+	switch light.kind {
+	case softSpotLight:
+		direction := vec3Of(light.direction)
+		lightOffset := vec3Of(data).sub(positionW)
+		lightVectorW := lightOffset.normalize()
+		attenuation := light.attenuation(lightOffset)
+
+		cosAngle := float32(math.Max(0, float64(direction.scale(-1).dot(lightVectorW))))
+		if cosAngle >= light.direction[3] {
+			cosAngle = float32(math.Max(0, float64(pow32(cosAngle, data[3]))))
+			spotAtten := float32(math.Max(0, float64((cosAngle-light.direction[3])/(1-cosAngle))))
+
+			ndl := float32(math.Max(0, float64(normal.dot(direction.scale(-1)))))
+			angleW := viewDirectionW.sub(direction).normalize()
+			specComp := pow32(float32(math.Max(0, float64(normal.dot(angleW)))), this.specularColor[3])
+
+			return light.diffuse.scale(ndl * spotAtten * attenuation), light.specular.scale(specComp * spotAtten * attenuation)
+		}
+		return vec3{}, vec3{}
+	case softHemiLight:
+		ndl := normal.dot(vec3Of(data))*0.5 + 0.5
+		angleW := viewDirectionW.add(vec3Of(data)).normalize()
+		specComp := pow32(float32(math.Max(0, float64(normal.dot(angleW)))), this.specularColor[3])
+
+		diffuse := light.ground.scale(1 - ndl).add(light.diffuse.scale(ndl))
+		return diffuse, light.specular.scale(specComp)
+	}
+
+	var lightVectorW vec3
+	attenuation := float32(1)
+	if data[3] == 0 {
+		lightOffset := vec3Of(data).sub(positionW)
+		attenuation = light.attenuation(lightOffset)
+		lightVectorW = lightOffset.normalize()
+	} else {
+		lightVectorW = vec3Of(data).scale(-1).normalize()
+	}
+
+	ndl := float32(math.Max(0, float64(normal.dot(lightVectorW))))
+	angleW := viewDirectionW.add(lightVectorW).normalize()
+	specComp := pow32(float32(math.Max(0, float64(normal.dot(angleW)))), this.specularColor[3])
+
+	return light.diffuse.scale(ndl * attenuation), light.specular.scale(specComp * attenuation)
+}
+
+// perturbNormal computes perturbNormal with the tangent frame
+func (this *softDefault) perturbNormal(normalW vec3, varyings []float32) vec3 {
+	bumpMap := vec3Of(this.bumpSampler.Sample(varyings[softBumpUV], varyings[softBumpUV+1])).scale(this.bumpInfos[1])
+	bumpMap = bumpMap.scale(255.0 / 127.0).sub(vec3{128.0 / 127.0, 128.0 / 127.0, 128.0 / 127.0})
+
+	tangentW := vec3{varyings[softTangentW], varyings[softTangentW+1], varyings[softTangentW+2]}.normalize()
+	bitangentW := vec3{varyings[softBitangentW], varyings[softBitangentW+1], varyings[softBitangentW+2]}.normalize()
+
+	return tangentW.scale(bumpMap[0]).add(bitangentW.scale(bumpMap[1])).add(normalW.normalize().scale(bumpMap[2])).normalize()
+}
+
+func (this *softDefault) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	if this.clipPlane && varyings[softClipDistance] > 0 {
+		return [4]float32{}, true
+	}
+
+	positionW := vec3{varyings[softPositionW], varyings[softPositionW+1], varyings[softPositionW+2]}
+	normalW := vec3{varyings[softNormalW], varyings[softNormalW+1], varyings[softNormalW+2]}
+	viewDirectionW := this.eyePosition.sub(positionW).normalize()
+
+	// Bump
+	if this.bump {
+		normalW = this.perturbNormal(normalW, varyings)
+	}
+
+	// Base color
+	baseColor := [4]float32{1, 1, 1, 1}
+	diffuseColor := vec3Of(this.diffuseColor)
+
+	if this.vertexColor {
+		diffuseColor = diffuseColor.mul(vec3{varyings[softColor], varyings[softColor+1], varyings[softColor+2]})
+	}
+
+	if this.diffuse {
+		baseColor = this.diffuseSampler.Sample(varyings[softDiffuseUV], varyings[softDiffuseUV+1])
+		if this.alphaTest && baseColor[3] < 0.4 {
+			return [4]float32{}, true
+		}
+		for index := 0; index < 3; index++ {
+			baseColor[index] *= this.diffuseInfos[1]
+		}
+	}
+
+	// Ambient color
+	baseAmbientColor := vec3{1, 1, 1}
+	if this.ambient {
+		baseAmbientColor = vec3Of(this.ambientSampler.Sample(varyings[softAmbientUV], varyings[softAmbientUV+1])).scale(this.ambientInfos[1])
+	}
+
+	// Lighting
+	diffuseBase := vec3{}
+	specularBase := vec3{}
+	for index, light := range this.lights {
+		diffuse, specular := this.lighting(light, viewDirectionW, normalW, positionW)
+		shadow := float32(1)
+		if light.shadow {
+			shadow = light.computeShadow(varyings[softFromLight+4*index : softFromLight+4*index+4])
+		}
+		diffuseBase = diffuseBase.add(diffuse.scale(shadow))
+		specularBase = specularBase.add(specular.scale(shadow))
+	}
+
+	// Reflection
+	reflectionColor := vec3{}
+	if this.reflection {
+		uvw := vec3{varyings[softReflectionUVW], varyings[softReflectionUVW+1], varyings[softReflectionUVW+2]}
+		if this.reflectionInfos[2] != 0 {
+			reflectionColor = vec3Of(this.reflectionCubeSampler.SampleCube(uvw[0], uvw[1], uvw[2])).scale(this.reflectionInfos[1])
+		} else {
+			u, v := uvw[0], uvw[1]
+			if this.reflectionInfos[0] == 4 {
+				u /= uvw[2]
+				v /= uvw[2]
+			}
+			v = 1.0 - v
+			reflectionColor = vec3Of(this.reflection2DSampler.Sample(u, v)).scale(this.reflectionInfos[1])
+		}
+	}
+
+	// Alpha
+	alpha := this.diffuseColor[3]
+	if this.opacity {
+		opacityMap := vec3Of(this.opacitySampler.Sample(varyings[softOpacityUV], varyings[softOpacityUV+1])).mul(vec3{0.3, 0.59, 0.11})
+		alpha *= (opacityMap[0] + opacityMap[1] + opacityMap[2]) * this.opacityInfos[1]
+	}
+
+	// Emissive
+	emissiveColor := this.emissiveColor
+	if this.emissive {
+		emissiveColor = emissiveColor.add(vec3Of(this.emissiveSampler.Sample(varyings[softEmissiveUV], varyings[softEmissiveUV+1])).scale(this.emissiveInfos[1]))
+	}
+
+	// Specular map
+	specularColor := vec3Of(this.specularColor)
+	if this.specular {
+		specularColor = vec3Of(this.specularSampler.Sample(varyings[softSpecularUV], varyings[softSpecularUV+1])).scale(this.specularInfos[1])
+	}
+
+	// Composition
+	finalDiffuse := diffuseBase.mul(diffuseColor).add(emissiveColor).add(this.ambientColor).clamp().mul(vec3Of(baseColor))
+	finalSpecular := specularBase.mul(specularColor)
+
+	color := finalDiffuse.mul(baseAmbientColor).add(finalSpecular).add(reflectionColor)
+
+	if this.fog {
+		factor := fog(this.fogInfos, varyings[softFogDistance])
+		color = color.scale(factor).add(this.fogColor.scale(1 - factor))
+	}
+
+	return [4]float32{color[0], color[1], color[2], alpha}, false
+}
+
+// pbr
+
+const (
+	softPBRPositionW           = 0
+	softPBRNormalW             = 3
+	softPBRAlbedoUV            = 6
+	softPBRMetallicRoughnessUV = 8
+	softPBRAOUV                = 10
+	softPBREmissiveUV          = 12
+	softPBRBumpUV              = 14
+	softPBRColor               = 16
+	softPBRClipDistance        = 19
+	softPBRFogDistance         = 20
+	softPBRTangentW            = 21
+	softPBRBitangentW          = 24
+	softPBRFromLight           = 27
+)
+
+type softPBR struct {
+	albedo, metallicRoughness, ao, emissive, reflection, brdf bool
+	clipPlane, fog, vertexColor, alphaTest, shadows           bool
+	uv1, uv2, instances, bones, morphNormals, bump            bool
+
+	world, view, worldViewProjection, viewProjection [16]float32
+	mBones, morphTargetInfluences                    []float32
+
+	albedoMatrix, metallicRoughnessMatrix, aoMatrix [16]float32
+	emissiveMatrix, bumpMatrix, reflectionMatrix    [16]float32
+	albedoInfos, metallicRoughnessInfos, aoInfos    [4]float32
+	emissiveInfos, bumpInfos, reflectionInfos       [4]float32
+
+	eyePosition             vec3
+	ambientColor            vec3
+	albedoColor             [4]float32
+	metallicRoughnessValues [4]float32
+	emissiveColor           vec3
+	clipPlaneEq             [4]float32
+	fogInfos                [4]float32
+	fogColor                vec3
+
+	albedoSampler, metallicRoughnessSampler, aoSampler *gl.SoftSampler
+	emissiveSampler, bumpSampler, brdfSampler          *gl.SoftSampler
+	reflectionCubeSampler                              *gl.SoftSampler
+
+	lights []*softLight
+}
+
+func newSoftPBR(program *gl.SoftProgram) gl.SoftShader {
+	this := &softPBR{}
+
+	this.albedo = program.Defined("ALBEDO")
+	this.metallicRoughness = program.Defined("METALLICROUGHNESS")
+	this.ao = program.Defined("AO")
+	this.emissive = program.Defined("EMISSIVE")
+	this.reflection = program.Defined("REFLECTION")
+	this.brdf = program.Defined("BRDF")
+	this.clipPlane = program.Defined("CLIPPLANE")
+	this.fog = program.Defined("FOG")
+	this.vertexColor = program.Defined("VERTEXCOLOR")
+	this.alphaTest = program.Defined("ALPHATEST")
+	this.shadows = program.Defined("SHADOWS")
+	this.uv1 = program.Defined("UV1")
+	this.uv2 = program.Defined("UV2")
+	this.instances = program.Defined("INSTANCES")
+	this.bones = program.Defined("BONES")
+	this.morphNormals = program.Defined("MORPHTARGETS_NORMAL")
+	this.bump = program.Defined("BUMP") && program.Defined("TANGENT")
+
+	this.world = program.Matrix("world")
+	this.view = program.Matrix("view")
+	this.worldViewProjection = program.Matrix("worldViewProjection")
+	this.viewProjection = program.Matrix("viewProjection")
+	if this.bones {
+		this.mBones = bonesMatrices(program)
+	}
+	this.morphTargetInfluences = morphInfluences(program)
+
+	this.albedoMatrix = program.Matrix("albedoMatrix")
+	this.metallicRoughnessMatrix = program.Matrix("metallicRoughnessMatrix")
+	this.aoMatrix = program.Matrix("aoMatrix")
+	this.emissiveMatrix = program.Matrix("emissiveMatrix")
+	this.bumpMatrix = program.Matrix("bumpMatrix")
+	this.reflectionMatrix = program.Matrix("reflectionMatrix")
+	this.albedoInfos = program.Vec4("vAlbedoInfos")
+	this.metallicRoughnessInfos = program.Vec4("vMetallicRoughnessInfos")
+	this.aoInfos = program.Vec4("vAOInfos")
+	this.emissiveInfos = program.Vec4("vEmissiveInfos")
+	this.bumpInfos = program.Vec4("vBumpInfos")
+	this.reflectionInfos = program.Vec4("vReflectionInfos")
+
+	this.eyePosition = vec3Of(program.Vec4("vEyePosition"))
+	this.ambientColor = vec3Of(program.Vec4("vAmbientColor"))
+	this.albedoColor = program.Vec4("vAlbedoColor")
+	this.metallicRoughnessValues = program.Vec4("vMetallicRoughness")
+	this.emissiveColor = vec3Of(program.Vec4("vEmissiveColor"))
+	this.clipPlaneEq = program.Vec4("vClipPlane")
+	this.fogInfos = program.Vec4("vFogInfos")
+	this.fogColor = vec3Of(program.Vec4("vFogColor"))
+
+	this.albedoSampler = program.Sampler("albedoSampler", gl.TEXTURE_2D)
+	this.metallicRoughnessSampler = program.Sampler("metallicRoughnessSampler", gl.TEXTURE_2D)
+	this.aoSampler = program.Sampler("aoSampler", gl.TEXTURE_2D)
+	this.emissiveSampler = program.Sampler("emissiveSampler", gl.TEXTURE_2D)
+	this.bumpSampler = program.Sampler("bumpSampler", gl.TEXTURE_2D)
+	this.brdfSampler = program.Sampler("brdfSampler", gl.TEXTURE_2D)
+	this.reflectionCubeSampler = program.Sampler("reflectionCubeSampler", gl.TEXTURE_CUBE_MAP)
+
+	this.lights = softLights(program)
+
+	return this
+}
+
+func (this *softPBR) Attributes() []string {
+	return []string{"position", "normal", "uv", "uv2", "color", "world0", "world1", "world2", "world3", "matricesIndices", "matricesWeights",
+		"position0", "normal0", "position1", "normal1", "position2", "normal2", "position3", "normal3", "tangent"}
+}
+
+func (this *softPBR) Varyings() int {
+	return softPBRFromLight + 4*len(this.lights)
+}
+
+func (this *softPBR) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	position := attributes[0]
+	normal := attributes[1]
+	uv := [2]float32{}
+	if this.uv1 {
+		uv = [2]float32{attributes[2][0], attributes[2][1]}
+	}
+	uv2 := [2]float32{}
+	if this.uv2 {
+		uv2 = [2]float32{attributes[3][0], attributes[3][1]}
+	}
+
+	positionUpdated := morph(position, attributes[11:], 2, this.morphTargetInfluences)
+	normalUpdated := normal
+	if this.morphNormals {
+		normalUpdated = morph(normal, attributes[12:], 2, this.morphTargetInfluences)
+	}
+
+	// The instances world matrix is read from the four world attributes
+	world := this.world
+	if this.instances {
+		for row := 0; row < 4; row++ {
+			copy(world[row*4:], attributes[5+row][:])
+		}
+	}
+	if this.bones {
+		world = multiply(skin(this.mBones, attributes[9], attributes[10]), world)
+	}
+
+	worldPos := transform(world, positionUpdated[0], positionUpdated[1], positionUpdated[2], 1)
+	normalW := vec3Of(transform(world, normalUpdated[0], normalUpdated[1], normalUpdated[2], 0)).normalize()
+	copy(varyings[softPBRPositionW:], worldPos[:3])
+	copy(varyings[softPBRNormalW:], normalW[:])
+
+	textureUV := func(infos [4]float32, matrix [16]float32, offset int) {
+		coords := uv
+		if infos[0] != 0 {
+			coords = uv2
+		}
+		result := transform(matrix, coords[0], coords[1], 1, 0)
+		varyings[offset] = result[0]
+		varyings[offset+1] = result[1]
+	}
+	if this.albedo {
+		textureUV(this.albedoInfos, this.albedoMatrix, softPBRAlbedoUV)
+	}
+	if this.metallicRoughness {
+		textureUV(this.metallicRoughnessInfos, this.metallicRoughnessMatrix, softPBRMetallicRoughnessUV)
+	}
+	if this.ao {
+		textureUV(this.aoInfos, this.aoMatrix, softPBRAOUV)
+	}
+	if this.emissive {
+		textureUV(this.emissiveInfos, this.emissiveMatrix, softPBREmissiveUV)
+	}
+	if this.bump {
+		textureUV(this.bumpInfos, this.bumpMatrix, softPBRBumpUV)
+
+		// w is the handedness of the tangent space, negative for mirrored uvs
+		tangent := attributes[19]
+		tangentW := vec3Of(transform(world, tangent[0], tangent[1], tangent[2], 0)).normalize()
+		bitangentW := normalW.cross(tangentW).scale(tangent[3])
+		copy(varyings[softPBRTangentW:], tangentW[:])
+		copy(varyings[softPBRBitangentW:], bitangentW[:])
+	}
+
+	if this.clipPlane {
+		eq := this.clipPlaneEq
+		varyings[softPBRClipDistance] = worldPos[0]*eq[0] + worldPos[1]*eq[1] + worldPos[2]*eq[2] + worldPos[3]*eq[3]
+	}
+
+	if this.fog {
+		varyings[softPBRFogDistance] = transform(this.view, worldPos[0], worldPos[1], worldPos[2], worldPos[3])[2]
+	}
+
+	if this.shadows {
+		for index, light := range this.lights {
+			fromLight := transform(light.matrix, worldPos[0], worldPos[1], worldPos[2], worldPos[3])
+			copy(varyings[softPBRFromLight+4*index:], fromLight[:])
+		}
+	}
+
+	if this.vertexColor {
+		copy(varyings[softPBRColor:softPBRColor+3], attributes[4][:3])
+	}
+
+	if this.instances || this.bones {
+		return transform(this.viewProjection, worldPos[0], worldPos[1], worldPos[2], worldPos[3])
+	}
+	return transform(this.worldViewProjection, positionUpdated[0], positionUpdated[1], positionUpdated[2], 1)
+}
+
+// fresnelSchlick computes fresnelSchlick
+func fresnelSchlick(cosTheta float32, F0 vec3) vec3 {
+	factor := pow32(1-cosTheta, 5)
+	return F0.add(vec3{1, 1, 1}.sub(F0).scale(factor))
+}
+
+// computeBRDF computes computeBRDF, the diffuse part and the specular part
+func computeBRDF(viewDirectionW, normal, lightVectorW, diffuseColor, specularColor, F0 vec3, alpha float32) (vec3, vec3) {
+	NdotL := float32(math.Max(0, float64(normal.dot(lightVectorW))))
+	NdotV := float32(math.Max(0.0001, float64(normal.dot(viewDirectionW))))
+	H := viewDirectionW.add(lightVectorW).normalize()
+	NdotH := float32(math.Max(0, float64(normal.dot(H))))
+	VdotH := float32(math.Max(0, float64(viewDirectionW.dot(H))))
+
+	F := fresnelSchlick(VdotH, F0)
+
+	// distributionGGX
+	alpha2 := alpha * alpha
+	d := NdotH*NdotH*(alpha2-1) + 1
+	distribution := alpha2 / (math.Pi * d * d)
+
+	// visibilitySmithGGX
+	k := alpha * 0.5
+	gL := NdotL / (NdotL*(1-k) + k)
+	gV := NdotV / (NdotV*(1-k) + k)
+	visibility := gL * gV / float32(math.Max(float64(4*NdotL*NdotV), 0.0001))
+
+	specular := distribution * visibility
+
+	diffuse := vec3{1, 1, 1}.sub(F).scale(NdotL).mul(diffuseColor)
+	return diffuse, F.scale(specular * math.Pi * NdotL).mul(specularColor)
+}
+
+func (this *softPBR) lighting(light *softLight, viewDirectionW, normal, positionW, F0 vec3, alpha float32) (vec3, vec3) {
+	data := light.data
+	switch light.kind {
+	case softSpotLight:
+		lightOffset := vec3Of(data).sub(positionW)
+		lightVectorW := lightOffset.normalize()
+
+		cosAngle := float32(math.Max(0, float64(vec3Of(light.direction).scale(-1).dot(lightVectorW))))
+		if cosAngle >= light.direction[3] {
+			cosAngle = float32(math.Max(0, float64(pow32(cosAngle, data[3]))))
+			spotAtten := float32(math.Max(0, float64((cosAngle-light.direction[3])/(1-cosAngle))))
+			spotAtten *= light.attenuation(lightOffset)
+
+			return computeBRDF(viewDirectionW, normal, lightVectorW, light.diffuse.scale(spotAtten), light.specular.scale(spotAtten), F0, alpha)
+		}
+		return vec3{}, vec3{}
+	case softHemiLight:
+		_, specular := computeBRDF(viewDirectionW, normal, vec3Of(data), light.diffuse, light.specular, F0, alpha)
+
+		ndl := normal.dot(vec3Of(data))*0.5 + 0.5
+		return light.ground.scale(1 - ndl).add(light.diffuse.scale(ndl)), specular
+	}
+
+	var lightVectorW vec3
+	attenuation := float32(1)
+	if data[3] == 0 {
+		lightOffset := vec3Of(data).sub(positionW)
+		attenuation = light.attenuation(lightOffset)
+		lightVectorW = lightOffset.normalize()
+	} else {
+		lightVectorW = vec3Of(data).scale(-1).normalize()
+	}
+
+	return computeBRDF(viewDirectionW, normal, lightVectorW, light.diffuse.scale(attenuation), light.specular.scale(attenuation), F0, alpha)
+}
+
+// environmentBRDFApprox computes environmentBRDFApprox
+func environmentBRDFApprox(NdotV, roughness float32) (float32, float32) {
+	c0 := [4]float32{-1, -0.0275, -0.572, 0.022}
+	c1 := [4]float32{1, 0.0425, 1.04, -0.04}
+	var r [4]float32
+	for index := range r {
+		r[index] = roughness*c0[index] + c1[index]
+	}
+	a004 := float32(math.Min(float64(r[0]*r[0]), math.Exp2(float64(-9.28*NdotV))))*r[0] + r[1]
+	return -1.04*a004 + r[2], 1.04*a004 + r[3]
+}
+
+func (this *softPBR) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	if this.clipPlane && varyings[softPBRClipDistance] > 0 {
+		return [4]float32{}, true
+	}
+
+	positionW := vec3{varyings[softPBRPositionW], varyings[softPBRPositionW+1], varyings[softPBRPositionW+2]}
+	normalW := vec3{varyings[softPBRNormalW], varyings[softPBRNormalW+1], varyings[softPBRNormalW+2]}.normalize()
+	viewDirectionW := this.eyePosition.sub(positionW).normalize()
+
+	// Albedo
+	albedo := vec3Of(this.albedoColor)
+	alpha := this.albedoColor[3]
+
+	if this.vertexColor {
+		albedo = albedo.mul(vec3{varyings[softPBRColor], varyings[softPBRColor+1], varyings[softPBRColor+2]})
+	}
+
+	if this.albedo {
+		albedoMap := this.albedoSampler.Sample(varyings[softPBRAlbedoUV], varyings[softPBRAlbedoUV+1])
+		if this.alphaTest && albedoMap[3] < 0.4 {
+			return [4]float32{}, true
+		}
+		albedo = albedo.mul(vec3Of(albedoMap).scale(this.albedoInfos[1]))
+	}
+
+	// Metallic and roughness
+	metallic := this.metallicRoughnessValues[0]
+	roughness := this.metallicRoughnessValues[1]
+
+	if this.metallicRoughness {
+		metallicRoughnessMap := this.metallicRoughnessSampler.Sample(varyings[softPBRMetallicRoughnessUV], varyings[softPBRMetallicRoughnessUV+1])
+		metallic *= metallicRoughnessMap[2]
+		roughness *= metallicRoughnessMap[1]
+	}
+
+	metallic = clamp(metallic, 0, 1)
+	roughness = clamp(roughness, 0.04, 1)
+	alphaG := roughness * roughness
+
+	diffuseColor := albedo.scale(1 - metallic)
+	F0 := vec3{0.04, 0.04, 0.04}.scale(1 - metallic).add(albedo.scale(metallic))
+
+	// Bump
+	if this.bump {
+		bumpMap := vec3Of(this.bumpSampler.Sample(varyings[softPBRBumpUV], varyings[softPBRBumpUV+1])).scale(this.bumpInfos[1])
+		bumpMap = bumpMap.scale(255.0 / 127.0).sub(vec3{128.0 / 127.0, 128.0 / 127.0, 128.0 / 127.0})
+
+		tangentW := vec3{varyings[softPBRTangentW], varyings[softPBRTangentW+1], varyings[softPBRTangentW+2]}.normalize()
+		bitangentW := vec3{varyings[softPBRBitangentW], varyings[softPBRBitangentW+1], varyings[softPBRBitangentW+2]}.normalize()
+		normalW = tangentW.scale(bumpMap[0]).add(bitangentW.scale(bumpMap[1])).add(normalW.scale(bumpMap[2])).normalize()
+	}
+
+	// Lighting
+	diffuseBase := vec3{}
+	specularBase := vec3{}
+	for index, light := range this.lights {
+		diffuse, specular := this.lighting(light, viewDirectionW, normalW, positionW, F0, alphaG)
+		shadow := float32(1)
+		if light.shadow {
+			shadow = light.computeShadow(varyings[softPBRFromLight+4*index : softPBRFromLight+4*index+4])
+		}
+		diffuseBase = diffuseBase.add(diffuse.scale(shadow))
+		specularBase = specularBase.add(specular.scale(shadow))
+	}
+
+	// Ambient and image based lighting
+	NdotV := float32(math.Max(0.0001, float64(normalW.dot(viewDirectionW))))
+
+	// fresnelSchlickRoughness
+	grazing := float32(1) - roughness
+	fresnelMax := vec3{float32(math.Max(float64(grazing), float64(F0[0]))), float32(math.Max(float64(grazing), float64(F0[1]))), float32(math.Max(float64(grazing), float64(F0[2])))}
+	F := F0.add(fresnelMax.sub(F0).scale(pow32(1-NdotV, 5)))
+
+	var scale, bias float32
+	if this.brdf {
+		texel := this.brdfSampler.Sample(NdotV, roughness)
+		scale, bias = texel[0], texel[1]
+	} else {
+		scale, bias = environmentBRDFApprox(NdotV, roughness)
+	}
+
+	// The soft sampler only reads the first mip level
+	irradiance := this.ambientColor
+	environmentSpecular := vec3{}
+	if this.reflection {
+		irradianceVector := transform(this.reflectionMatrix, normalW[0], normalW[1], normalW[2], 0)
+		reflected := viewDirectionW.scale(-1).reflect(normalW)
+		reflectionVector := transform(this.reflectionMatrix, reflected[0], reflected[1], reflected[2], 0)
+
+		irradiance = irradiance.add(vec3Of(this.reflectionCubeSampler.SampleCube(irradianceVector[0], irradianceVector[1], irradianceVector[2])).scale(this.reflectionInfos[0]))
+		environmentSpecular = vec3Of(this.reflectionCubeSampler.SampleCube(reflectionVector[0], reflectionVector[1], reflectionVector[2])).scale(this.reflectionInfos[0])
+	}
+
+	ambientOcclusion := vec3{1, 1, 1}
+	if this.ao {
+		occlusion := this.aoSampler.Sample(varyings[softPBRAOUV], varyings[softPBRAOUV+1])[0]
+		ambientOcclusion = ambientOcclusion.scale(1 - this.aoInfos[1]).add(vec3{occlusion, occlusion, occlusion}.scale(this.aoInfos[1]))
+	}
+
+	ambientDiffuse := irradiance.mul(vec3{1, 1, 1}.sub(F)).mul(diffuseColor)
+	ambientSpecular := environmentSpecular.mul(F0.scale(scale).add(vec3{bias, bias, bias}))
+
+	// Emissive
+	emissiveColor := this.emissiveColor
+	if this.emissive {
+		emissiveColor = emissiveColor.add(vec3Of(this.emissiveSampler.Sample(varyings[softPBREmissiveUV], varyings[softPBREmissiveUV+1])).scale(this.emissiveInfos[1]))
+	}
+
+	// Composition
+	color := diffuseBase.mul(diffuseColor).add(specularBase).add(ambientDiffuse.add(ambientSpecular).mul(ambientOcclusion)).add(emissiveColor)
+
+	if this.fog {
+		factor := fog(this.fogInfos, varyings[softPBRFogDistance])
+		color = color.scale(factor).add(this.fogColor.scale(1 - factor))
+	}
+
+	return [4]float32{color[0], color[1], color[2], alpha}, false
+}
+
+// layer
+
+type softLayer struct {
+	textureMatrix [16]float32
+	color         [4]float32
+	sampler       *gl.SoftSampler
+}
+
+func newSoftLayer(program *gl.SoftProgram) gl.SoftShader {
+	this := &softLayer{}
+	this.textureMatrix = program.Matrix("textureMatrix")
+	this.color = program.Vec4("color")
+	this.sampler = program.Sampler("textureSampler", gl.TEXTURE_2D)
+	return this
+}
+
+func (this *softLayer) Attributes() []string {
+	return []string{"position"}
+}
+
+func (this *softLayer) Varyings() int {
+	return 2
+}
+
+func (this *softLayer) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	position := attributes[0]
+	uv := transform(this.textureMatrix, position[0]*0.5+0.5, position[1]*0.5+0.5, 1, 0)
+	varyings[0] = uv[0]
+	varyings[1] = uv[1]
+	return [4]float32{position[0], position[1], 0, 1}
+}
+
+func (this *softLayer) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	baseColor := this.sampler.Sample(varyings[0], varyings[1])
+	for index := range baseColor {
+		baseColor[index] *= this.color[index]
+	}
+	return baseColor, false
+}
+
+type softColorShader struct {
+	worldViewProjection [16]float32
+	color               [4]float32
+}
+
+func newSoftColor(program *gl.SoftProgram) gl.SoftShader {
+	this := &softColorShader{}
+	this.worldViewProjection = program.Matrix("worldViewProjection")
+	this.color = program.Vec4("color")
+	return this
+}
+
+func (this *softColorShader) Attributes() []string {
+	return []string{"position"}
+}
+
+func (this *softColorShader) Varyings() int {
+	return 0
+}
+
+func (this *softColorShader) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	position := attributes[0]
+	return transform(this.worldViewProjection, position[0], position[1], position[2], 1)
+}
+
+func (this *softColorShader) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	return this.color, false
+}
+
+// particles and sprites share the billboard corner computation
+
+func billboard(view, projection [16]float32, position, options [4]float32, angle, size float32) ([4]float32, vec3) {
+	viewPos := vec3Of(transform(view, position[0], position[1], position[2], 1))
+	offsetX, offsetY := options[2], options[3]
+
+	cornerX := (offsetX - 0.5) * size
+	cornerY := (offsetY - 0.5) * size
+
+	cos := float32(math.Cos(float64(angle)))
+	sin := float32(math.Sin(float64(angle)))
+	viewPos[0] += cornerX*cos - cornerY*sin
+	viewPos[1] += cornerX*sin + cornerY*cos
+
+	return transform(projection, viewPos[0], viewPos[1], viewPos[2], 1), viewPos
+}
+
+type softParticles struct {
+	clipPlane   bool
+	view        [16]float32
+	projection  [16]float32
+	invView     [16]float32
+	clipPlaneEq [4]float32
+	textureMask [4]float32
+	sampler     *gl.SoftSampler
+}
+
+func newSoftParticles(program *gl.SoftProgram) gl.SoftShader {
+	this := &softParticles{}
+	this.clipPlane = program.Defined("CLIPPLANE")
+	this.view = program.Matrix("view")
+	this.projection = program.Matrix("projection")
+	this.invView = program.Matrix("invView")
+	this.clipPlaneEq = program.Vec4("vClipPlane")
+	this.textureMask = program.Vec4("textureMask")
+	this.sampler = program.Sampler("diffuseSampler", gl.TEXTURE_2D)
+	return this
+}
+
+func (this *softParticles) Attributes() []string {
+	return []string{"position", "color", "options"}
+}
+
+func (this *softParticles) Varyings() int {
+	return 7
+}
+
+func (this *softParticles) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	options := attributes[2]
+	position, viewPos := billboard(this.view, this.projection, attributes[0], options, options[0], options[1])
+
+	copy(varyings[0:4], attributes[1][:])
+	varyings[4] = options[2]
+	varyings[5] = options[3]
+
+	if this.clipPlane {
+		worldPos := transform(this.invView, viewPos[0], viewPos[1], viewPos[2], 1)
+		eq := this.clipPlaneEq
+		varyings[6] = worldPos[0]*eq[0] + worldPos[1]*eq[1] + worldPos[2]*eq[2] + worldPos[3]*eq[3]
+	}
+
+	return position
+}
+
+func (this *softParticles) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	if this.clipPlane && varyings[6] > 0 {
+		return [4]float32{}, true
+	}
+	baseColor := this.sampler.Sample(varyings[4], varyings[5])
+
+	var color [4]float32
+	for index := range color {
+		color[index] = (baseColor[index]*this.textureMask[index] + (1 - this.textureMask[index])) * varyings[index]
+	}
+	return color, false
+}
+
+// shadowMap
+
+type softShadowMap struct {
+	vsm, bones                    bool
+	worldViewProjection           [16]float32
+	mBones, morphTargetInfluences []float32
+}
+
+func newSoftShadowMap(program *gl.SoftProgram) gl.SoftShader {
+	this := &softShadowMap{}
+	this.vsm = program.Defined("VSM")
+	this.bones = program.Defined("BONES")
+	this.worldViewProjection = program.Matrix("worldViewProjection")
+	if this.bones {
+		this.mBones = bonesMatrices(program)
+	}
+	this.morphTargetInfluences = morphInfluences(program)
+	return this
+}
+
+func (this *softShadowMap) Attributes() []string {
+	return []string{"position", "matricesIndices", "matricesWeights", "position0", "position1", "position2", "position3"}
+}
+
+func (this *softShadowMap) Varyings() int {
+	return 0
+}
+
+func (this *softShadowMap) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	position := morph(attributes[0], attributes[3:], 1, this.morphTargetInfluences)
+	if this.bones {
+		position = transform(skin(this.mBones, attributes[1], attributes[2]), position[0], position[1], position[2], 1)
+		return transform(this.worldViewProjection, position[0], position[1], position[2], position[3])
+	}
+	return transform(this.worldViewProjection, position[0], position[1], position[2], 1)
+}
+
+func pack(depth float32) [4]float32 {
+	comp := [4]float32{
+		fract(depth * 255. * 255. * 255.),
+		fract(depth * 255. * 255.),
+		fract(depth * 255.),
+		fract(depth),
+	}
+	return [4]float32{comp[0], comp[1] - comp[0]/255., comp[2] - comp[1]/255., comp[3] - comp[2]/255.}
+}
+
+func packHalf(depth float32) (float32, float32) {
+	y := fract(depth * 255.)
+	return depth - y/255., y
+}
+
+func (this *softShadowMap) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	moment1 := fragment.Z / fragment.W
+	if this.vsm {
+		moment2 := moment1 * moment1
+		x, y := packHalf(moment1)
+		z, w := packHalf(moment2)
+		return [4]float32{x, y, z, w}, false
+	}
+	return pack(moment1), false
+}
+
+// sprites
+
+type softSprites struct {
+	fog          bool
+	alphaTest    bool
+	textureInfos [4]float32
+	view         [16]float32
+	projection   [16]float32
+	fogInfos     [4]float32
+	fogColor     vec3
+	sampler      *gl.SoftSampler
+}
+
+func newSoftSprites(program *gl.SoftProgram) gl.SoftShader {
+	this := &softSprites{}
+	this.fog = program.Defined("FOG")
+	this.alphaTest = program.Float("alphaTest") != 0
+	this.textureInfos = program.Vec4("textureInfos")
+	this.view = program.Matrix("view")
+	this.projection = program.Matrix("projection")
+	this.fogInfos = program.Vec4("vFogInfos")
+	this.fogColor = vec3Of(program.Vec4("vFogColor"))
+	this.sampler = program.Sampler("diffuseSampler", gl.TEXTURE_2D)
+	return this
+}
+
+func (this *softSprites) Attributes() []string {
+	return []string{"position", "options", "cellInfo", "color"}
+}
+
+func (this *softSprites) Varyings() int {
+	return 7
+}
+
+func (this *softSprites) Vertex(attributes [][4]float32, varyings []float32) [4]float32 {
+	options := attributes[1]
+	cellInfo := attributes[2]
+	position, viewPos := billboard(this.view, this.projection, attributes[0], options, options[0], options[1])
+
+	copy(varyings[0:4], attributes[3][:])
+
+	uvOffsetX := float32(math.Abs(float64(options[2] - cellInfo[0])))
+	uvOffsetY := 1.0 - float32(math.Abs(float64(options[3]-cellInfo[1])))
+	varyings[4] = (uvOffsetX + cellInfo[2]) * this.textureInfos[0]
+	varyings[5] = (uvOffsetY + cellInfo[3]) * this.textureInfos[1]
+
+	if this.fog {
+		varyings[6] = viewPos[2]
+	}
+
+	return position
+}
+
+func (this *softSprites) Fragment(fragment *gl.SoftFragment, varyings []float32) ([4]float32, bool) {
+	baseColor := this.sampler.Sample(varyings[4], varyings[5])
+
+	if this.alphaTest && baseColor[3] < 0.95 {
+		return [4]float32{}, true
+	}
+
+	for index := range baseColor {
+		baseColor[index] *= varyings[index]
+	}
+
+	if this.fog {
+		factor := fog(this.fogInfos, varyings[6])
+		for index := 0; index < 3; index++ {
+			baseColor[index] = factor*baseColor[index] + (1-factor)*this.fogColor[index]
+		}
+	}
+
+	return baseColor, false
+}
@@ -0,0 +1,121 @@
+// +build glsoft
+
+package effects
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/gl"
+)
+
+var softConditionRegexp = regexp.MustCompile(`(?:#ifn?def\s+|defined\s*\(\s*)(\w+)`)
+
+// testLightDefines are the defines of a point, a spot and a hemispheric light, the
+// first two casting shadows
+const testLightDefines = `#define maxSimultaneousLights 4
+#define LIGHT0
+#define POINTDIRLIGHT0
+#define SHADOW0
+#define SHADOWS
+#define LIGHT1
+#define SPOTLIGHT1
+#define SHADOW1
+#define SHADOWVSM1
+#define LIGHT2
+#define HEMILIGHT2`
+
+const testMorphDefines = `#define MORPHTARGETS
+#define NUM_MORPH_INFLUENCERS 2
+#define MORPHTARGETS_NORMAL
+#define MORPHTARGET0
+#define MORPHTARGET1`
+
+// TestSoftShadersFollowGLSL checks that the Go ports read the uniforms and
+// the defines the GLSL of ShadersStore uses, and feed its attributes
+func TestSoftShadersFollowGLSL(t *testing.T) {
+	tests := []struct {
+		baseName string
+		defines  []string
+	}{
+		{"color", nil},
+		{"layer", nil},
+		{"particles", []string{"#define CLIPPLANE"}},
+		{"sprites", []string{"#define FOG"}},
+		{"shadowMap", nil},
+		{"shadowMap", []string{"#define VSM", "#define BONES", "#define BonesPerMesh 4", "#define MORPHTARGETS", "#define NUM_MORPH_INFLUENCERS 1", "#define MORPHTARGET0"}},
+		{"default", []string{"#define maxSimultaneousLights 4"}},
+		{"default", []string{"#define DIFFUSE", "#define AMBIENT", "#define OPACITY", "#define REFLECTION", "#define EMISSIVE", "#define SPECULAR",
+			"#define BUMP", "#define TANGENT", "#define UV1", "#define UV2", "#define VERTEXCOLOR", "#define ALPHATEST", "#define FOG", "#define CLIPPLANE", testLightDefines}},
+		{"default", []string{"#define UV1", "#define BONES", "#define BonesPerMesh 4", "#define INSTANCES", testMorphDefines, testLightDefines}},
+		{"pbr", []string{"#define maxSimultaneousLights 4"}},
+		{"pbr", []string{"#define ALBEDO", "#define METALLICROUGHNESS", "#define AO", "#define EMISSIVE", "#define REFLECTION", "#define BRDF",
+			"#define BUMP", "#define TANGENT", "#define UV1", "#define UV2", "#define VERTEXCOLOR", "#define ALPHATEST", "#define FOG", "#define CLIPPLANE", testLightDefines}},
+		{"pbr", []string{"#define UV1", "#define BONES", "#define BonesPerMesh 4", "#define INSTANCES", testMorphDefines, testLightDefines}},
+	}
+
+	for _, test := range tests {
+		defines := strings.Join(test.defines, "\n")
+		name := test.baseName + " " + strings.Join(strings.Fields(strings.Replace(defines, "#define ", "", -1)), ",")
+
+		engine := enginetest.NewEngine(t)
+		effect := CreateEffect(engine, test.baseName, nil, nil, nil, defines, nil, nil)
+		if !effect.IsReady() {
+			t.Errorf("%s: %s", name, effect.GetCompilationError())
+			continue
+		}
+		program := effect.GetProgram()
+
+		attributes, reads, ok := gl.SoftShaderReads(program)
+		if !ok {
+			t.Errorf("%s: no SoftShader", name)
+			continue
+		}
+
+		var missing []string
+		for index := 0; index < gl.GetProgrami(program, gl.ACTIVE_ATTRIBUTES); index++ {
+			attribute, _, _ := gl.GetActiveAttrib(program, uint32(index))
+			if !containsString(attributes, attribute) {
+				missing = append(missing, "attribute "+attribute)
+			}
+		}
+		for index := 0; index < gl.GetProgrami(program, gl.ACTIVE_UNIFORMS); index++ {
+			uniform, _, _ := gl.GetActiveUniform(program, uint32(index))
+			uniform = strings.TrimSuffix(uniform, "[0]")
+			if !reads[uniform] {
+				missing = append(missing, "uniform "+uniform)
+			}
+		}
+
+		// The defines of the test the GLSL tests
+		vertex, _ := processShader(ShadersStore[test.baseName+"_vertex"], defines)
+		fragment, _ := processShader(ShadersStore[test.baseName+"_fragment"], defines)
+		conditions := map[string]bool{}
+		for _, match := range softConditionRegexp.FindAllStringSubmatch(vertex+fragment, -1) {
+			conditions[match[1]] = true
+		}
+		for _, line := range strings.Split(defines, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) > 1 && conditions[fields[1]] && !reads[fields[1]] {
+				missing = append(missing, "define "+fields[1])
+			}
+		}
+
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			t.Errorf("%s: the port ignores %s", name, strings.Join(missing, ", "))
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
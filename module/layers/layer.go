@@ -61,18 +61,19 @@ func NewLayer(name string, imgUrl string, scene *engines.Scene, isBackgroud bool
 	this._vertexBuffer = this._scene.GetEngine().CreateVertexBuffer(vertices)
 
 	// Indices
-	indices := []uint16{
+	indices := []uint32{
 		0, 1, 2,
 		0, 2, 3,
 	}
 
-	this._indexBuffer = scene.GetEngine().CreateIndexBuffer(indices, false)
+	// 16 bits indices, this cannot fail
+	this._indexBuffer, _ = scene.GetEngine().CreateIndexBuffer(indices, false)
 
 	// Effects
 	this._effect = effects.CreateEffect(this._scene.GetEngine(), "layer",
 		[]string{"position"},
 		[]string{"textureMatrix", "color"},
-		[]string{"textureSampler"}, "")
+		[]string{"textureSampler"}, "", nil, nil)
 
 	this._scene.Layers = append(this._scene.Layers, this)
 	return this
@@ -0,0 +1,51 @@
+package layers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+func init() {
+	engines.RegisterParser("Layer", parseLayer)
+}
+
+type layerData struct {
+	Type         string          `json:"type"`
+	Name         string          `json:"name"`
+	IsBackground bool            `json:"isBackground"`
+	Color        *math32.Color4  `json:"color"`
+	Texture      json.RawMessage `json:"texture,omitempty"`
+}
+
+func (this *Layer) Serialize() interface{} {
+	return &layerData{
+		Type:         "Layer",
+		Name:         this.Name,
+		IsBackground: this.Isbackground,
+		Color:        this.Color,
+		Texture:      engines.SerializeObject(this._texture),
+	}
+}
+
+func parseLayer(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &layerData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	layer := NewLayer(data.Name, "", scene, data.IsBackground, data.Color)
+
+	object, err := engines.ParseObject(data.Texture, scene)
+	if err != nil {
+		return nil, fmt.Errorf("layer %s: %s", data.Name, err)
+	}
+	if texture, ok := object.(ITexture); ok {
+		layer._texture = texture
+	}
+
+	return layer, nil
+}
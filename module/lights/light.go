@@ -1,9 +1,12 @@
 package lights
 
 import (
+	"math"
+
 	"github.com/suiqirui1987/fly3d/engines"
 	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cullings"
 	"github.com/suiqirui1987/fly3d/tools"
 )
 
@@ -21,6 +24,15 @@ type Light struct {
 	Isenable  bool
 	Diffuse   *math32.Color3
 	Specular  *math32.Color3
+
+	// Range limits the reach of point and spot lights, they fade out to
+	// nothing at this distance
+	Range float32
+
+	// IncludedOnlyMeshes restricts the light to these meshes when not empty,
+	// the light never affects the ExcludedMeshes
+	IncludedOnlyMeshes []IMesh
+	ExcludedMeshes     []IMesh
 }
 
 func NewLight(name string, scene *engines.Scene) *Light {
@@ -38,12 +50,17 @@ func NewLight(name string, scene *engines.Scene) *Light {
 func (this *Light) Init() {
 	this.Intensity = 1.0
 	this.Isenable = true
+	this.Range = math.MaxFloat32
 }
 
 func (this *Light) GetScene() *engines.Scene {
 	return this._scene
 }
 
+func (this *Light) GetId() string {
+	return this.Id
+}
+
 func (this *Light) IsEnabled() bool {
 	return this.Isenable
 }
@@ -92,3 +109,65 @@ func (this *Light) Dispose() {
 	}
 
 }
+
+func (this *Light) GetRange() float32 {
+	return this.Range
+}
+
+// CanAffectMesh tells whether the mesh is in the included and not in the
+// excluded meshes of the light
+func (this *Light) CanAffectMesh(mesh IMesh) bool {
+	if mesh == nil {
+		return true
+	}
+
+	if len(this.IncludedOnlyMeshes) > 0 && !containsMesh(this.IncludedOnlyMeshes, mesh) {
+		return false
+	}
+
+	return !containsMesh(this.ExcludedMeshes, mesh)
+}
+
+// GetInfluence ranks the light among the lights of the mesh, lights without
+// a position reach every mesh at full intensity
+func (this *Light) GetInfluence(mesh IMesh) float32 {
+	return math.MaxFloat32
+}
+
+// _rangeInfluence is the influence of a light at Position, the intensity
+// lowered by the distance to the mesh and null out of range
+func (this *Light) _rangeInfluence(mesh IMesh) float32 {
+	if mesh == nil {
+		return this.Intensity
+	}
+
+	distance := distanceToMesh(this.Position, mesh)
+	if distance >= this.Range {
+		return 0
+	}
+
+	return this.Intensity * (1 - distance/this.Range) / math32.Max(distance*distance, 1)
+}
+
+func containsMesh(meshes []IMesh, mesh IMesh) bool {
+	for _, other := range meshes {
+		if other == mesh {
+			return true
+		}
+	}
+	return false
+}
+
+// distanceToMesh returns the distance from position to the bounding sphere of
+// the mesh, or to its origin when it has no bounds
+func distanceToMesh(position *math32.Vector3, mesh IMesh) float32 {
+	if bounded, ok := mesh.(interface {
+		GetBoundingInfo() *cullings.BoundingInfo
+	}); ok && bounded.GetBoundingInfo() != nil && bounded.GetBoundingInfo().Sphere.CenterWorld != nil {
+		sphere := bounded.GetBoundingInfo().Sphere
+		return math32.Max(position.Distance(sphere.CenterWorld)-sphere.RadiusWorld, 0)
+	}
+
+	world := mesh.GetWorldMatrix()
+	return position.Distance(math32.NewVector3(world[12], world[13], world[14]))
+}
@@ -2,6 +2,7 @@ package lights
 
 import (
 	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/math32"
 )
 
@@ -35,3 +36,12 @@ func (this *PointLight) Init() {
 func (this *PointLight) IsSupportShadow() bool {
 	return false
 }
+
+// CanAffectMesh also leaves out the meshes out of Range
+func (this *PointLight) CanAffectMesh(mesh IMesh) bool {
+	return this.Light.CanAffectMesh(mesh) && this._rangeInfluence(mesh) > 0
+}
+
+func (this *PointLight) GetInfluence(mesh IMesh) float32 {
+	return this._rangeInfluence(mesh)
+}
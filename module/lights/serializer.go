@@ -0,0 +1,186 @@
+package lights
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+func init() {
+	engines.RegisterParser("PointLight", parseLight)
+	engines.RegisterParser("DirectionalLight", parseLight)
+	engines.RegisterParser("SpotLight", parseLight)
+	engines.RegisterParser("HemisphericLight", parseLight)
+	engines.RegisterParser("ShadowGenerator", parseShadowGenerator)
+}
+
+type lightData struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Id        string          `json:"id"`
+	Enabled   bool            `json:"enabled"`
+	Position  *math32.Vector3 `json:"position"`
+	Direction *math32.Vector3 `json:"direction"`
+	Intensity float32         `json:"intensity"`
+	Diffuse   *math32.Color3  `json:"diffuse"`
+	Specular  *math32.Color3  `json:"specular"`
+	Range     float32         `json:"range,omitempty"`
+
+	// Spot lights
+	Angle    float32 `json:"angle,omitempty"`
+	Exponent float32 `json:"exponent,omitempty"`
+
+	// Hemispheric lights
+	GroundColor *math32.Color3 `json:"groundColor,omitempty"`
+}
+
+type shadowGeneratorData struct {
+	Type                 string   `json:"type"`
+	Name                 string   `json:"name"`
+	LightId              string   `json:"lightId"`
+	MapSize              int      `json:"mapSize"`
+	UseVarianceShadowMap bool     `json:"useVarianceShadowMap"`
+	RenderList           []string `json:"renderList"`
+}
+
+func (this *Light) _serialize(typeName string) *lightData {
+	data := &lightData{
+		Type:      typeName,
+		Name:      this.Name,
+		Id:        this.Id,
+		Enabled:   this.Isenable,
+		Position:  this.Position,
+		Direction: this.Direction,
+		Intensity: this.Intensity,
+		Diffuse:   this.Diffuse,
+		Specular:  this.Specular,
+	}
+	if this.Range < math.MaxFloat32 {
+		data.Range = this.Range
+	}
+	return data
+}
+
+func (this *Light) _parse(data *lightData) {
+	this.Id = data.Id
+	this.Isenable = data.Enabled
+	this.Intensity = data.Intensity
+	if data.Diffuse != nil {
+		this.Diffuse = data.Diffuse
+	}
+	if data.Specular != nil {
+		this.Specular = data.Specular
+	}
+	if data.Range > 0 {
+		this.Range = data.Range
+	}
+}
+
+func (this *PointLight) Serialize() interface{} {
+	return this._serialize("PointLight")
+}
+
+func (this *DirectionalLight) Serialize() interface{} {
+	return this._serialize("DirectionalLight")
+}
+
+func (this *SpotLight) Serialize() interface{} {
+	data := this._serialize("SpotLight")
+	data.Angle = this.Angle
+	data.Exponent = this.Exponent
+	return data
+}
+
+func (this *HemisphericLight) Serialize() interface{} {
+	data := this._serialize("HemisphericLight")
+	data.GroundColor = this.GroundColor
+	return data
+}
+
+func (this *ShadowGenerator) Serialize() interface{} {
+	data := &shadowGeneratorData{
+		Type:                 "ShadowGenerator",
+		Name:                 this._shadowMap.Name,
+		LightId:              this._light.GetId(),
+		MapSize:              this._shadowMap.GetGLTexture().Width,
+		UseVarianceShadowMap: this.UseVarianceShadowMap,
+	}
+	for _, mesh := range this._shadowMap.GetRenderList() {
+		data.RenderList = append(data.RenderList, mesh.GetId())
+	}
+	return data
+}
+
+func parseLight(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &lightData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	position := data.Position
+	if position == nil {
+		position = math32.NewVector3Zero()
+	}
+	direction := data.Direction
+	if direction == nil {
+		direction = math32.NewVector3(0, -1, 0)
+	}
+
+	switch data.Type {
+	case "PointLight":
+		light := NewPointLight(data.Name, position, scene)
+		light._parse(data)
+		return light, nil
+	case "DirectionalLight":
+		light := NewDirectionalLight(data.Name, direction, scene)
+		light.Position = position
+		light._parse(data)
+		return light, nil
+	case "SpotLight":
+		light := NewSpotLight(data.Name, position, direction, data.Angle, data.Exponent, scene)
+		light._parse(data)
+		return light, nil
+	case "HemisphericLight":
+		light := NewHemisphericLight(data.Name, direction, scene)
+		if data.GroundColor != nil {
+			light.GroundColor = data.GroundColor
+		}
+		light._parse(data)
+		return light, nil
+	}
+	return nil, fmt.Errorf("unknown light type %q", data.Type)
+}
+
+func parseShadowGenerator(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &shadowGeneratorData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	light := scene.GetLightByID(data.LightId)
+	if light == nil {
+		return nil, fmt.Errorf("shadow generator: light %s not found", data.LightId)
+	}
+
+	// The size is unknown when the render target could not be created
+	mapSize := data.MapSize
+	if mapSize == 0 {
+		mapSize = 1024
+	}
+	generator := NewShadowGenerator(data.Name, mapSize, light, scene)
+	if generator == nil {
+		return nil, fmt.Errorf("shadow generator: light %s does not cast shadows", data.LightId)
+	}
+	generator.UseVarianceShadowMap = data.UseVarianceShadowMap
+
+	for _, id := range data.RenderList {
+		if mesh := scene.GetMeshByID(id); mesh != nil {
+			generator.GetShadowMap().AddRenderList(mesh)
+		}
+	}
+
+	return generator, nil
+}
@@ -2,6 +2,8 @@ package lights
 
 import (
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/suiqirui1987/fly3d/engines"
 	"github.com/suiqirui1987/fly3d/gl"
@@ -53,48 +55,62 @@ func NewShadowGenerator(name string, mapSize int, light ILight, scene *engines.S
 	this._effect = effects.CreateEffect(engine, "shadowMap",
 		[]string{"position"},
 		[]string{"worldViewProjection"},
-		[]string{}, "")
+		[]string{}, "", nil, nil)
 
 	this._effectVSM = effects.CreateEffect(engine, "shadowMap",
 		[]string{"position"},
 		[]string{"worldViewProjection"},
-		[]string{}, "#define VSM")
+		[]string{}, "#define VSM", nil, nil)
 
 	// Custom render function
 	that := this
 
-	renderSubMesh := func(subMesh ISubMesh, effect IEffect) {
+	renderSubMesh := func(subMesh ISubMesh) {
 
 		mesh := subMesh.GetMesh()
 
-		world := mesh.GetWorldMatrix()
-
-		that._worldViewProjection = world.Multiply(that.GetTransformMatrix())
+		// Skinned and morphed meshes deform their vertices
+		effect := that._getMeshEffect(mesh)
+		if !effect.IsReady() {
+			return
+		}
+		that._scene.GetEngine().EnableEffect(effect)
 
-		effect.SetMatrix("worldViewProjection", that._worldViewProjection)
+		if that._isSkinned(mesh) {
+			effect.SetMatrices("mBones", mesh.GetSkeleton().GetTransformMatrices())
+		}
+		if influences := mesh.GetMorphTargetInfluences(); len(influences) > 0 {
+			effect.SetFloatArray("morphTargetInfluences", influences)
+		}
 
-		subMesh.BindAndDraw(effect, false)
+		// The mesh and its active instances
+		worlds := []*math32.Matrix4{mesh.GetWorldMatrix()}
+		if batch := that._scene.GetInstancesBatch(mesh); batch != nil {
+			if !batch.RenderSelf {
+				worlds = worlds[:0]
+			}
+			for _, instance := range batch.Instances {
+				worlds = append(worlds, instance.GetWorldMatrix())
+			}
+		}
 
-	}
+		for _, world := range worlds {
+			that._worldViewProjection = world.Multiply(that.GetTransformMatrix())
 
-	this._shadowMap.CustomRenderFunction = func(opaqueSubMeshes []ISubMesh, alphaTestSubMeshes []ISubMesh, transparentSubMeshes []ISubMesh, activeMeshes []IMesh) {
-		engine := that._scene.GetEngine()
+			effect.SetMatrix("worldViewProjection", that._worldViewProjection)
 
-		var effect IEffect
-		if that.UseVarianceShadowMap == true {
-			effect = that._effectVSM
-		} else {
-			effect = that._effect
+			subMesh.BindAndDraw(effect, false)
 		}
 
-		engine.EnableEffect(effect)
+	}
 
+	this._shadowMap.CustomRenderFunction = func(opaqueSubMeshes []ISubMesh, alphaTestSubMeshes []ISubMesh, transparentSubMeshes []ISubMesh, activeMeshes []IMesh) {
 		for index := 0; index < len(opaqueSubMeshes); index++ {
-			renderSubMesh(opaqueSubMeshes[index], effect)
+			renderSubMesh(opaqueSubMeshes[index])
 		}
 
 		for index := 0; index < len(alphaTestSubMeshes); index++ {
-			renderSubMesh(alphaTestSubMeshes[index], effect)
+			renderSubMesh(alphaTestSubMeshes[index])
 		}
 	}
 
@@ -107,6 +123,46 @@ func NewShadowGenerator(name string, mapSize int, light ILight, scene *engines.S
 	return this
 }
 
+func (this *ShadowGenerator) _isSkinned(mesh IMesh) bool {
+	return mesh.GetSkeleton() != nil && mesh.IsVerticesDataPresent(IMesh_VB_MatricesIndicesKind) && mesh.IsVerticesDataPresent(IMesh_VB_MatricesWeightsKind)
+}
+
+// _getMeshEffect returns the effect rendering the mesh in the shadow map
+func (this *ShadowGenerator) _getMeshEffect(mesh IMesh) IEffect {
+	attribs := []string{"position"}
+	defines := []string{}
+
+	if this._isSkinned(mesh) {
+		attribs = append(attribs, "matricesIndices", "matricesWeights")
+		defines = append(defines, "#define BONES", "#define BonesPerMesh "+strconv.Itoa(mesh.GetSkeleton().GetBonesCount()))
+	}
+
+	if influences := mesh.GetMorphTargetInfluences(); len(influences) > 0 {
+		defines = append(defines, "#define MORPHTARGETS", "#define NUM_MORPH_INFLUENCERS "+strconv.Itoa(len(influences)))
+		for index := range influences {
+			attribs = append(attribs, "position"+strconv.Itoa(index))
+			defines = append(defines, "#define MORPHTARGET"+strconv.Itoa(index))
+		}
+	}
+
+	// The vertices are not deformed
+	if len(defines) == 0 {
+		if this.UseVarianceShadowMap {
+			return this._effectVSM
+		}
+		return this._effect
+	}
+
+	if this.UseVarianceShadowMap {
+		defines = append(defines, "#define VSM")
+	}
+
+	return effects.CreateEffect(this._scene.GetEngine(), "shadowMap",
+		attribs,
+		[]string{"worldViewProjection", "mBones", "morphTargetInfluences"},
+		[]string{}, strings.Join(defines, "\n"), nil, nil)
+}
+
 func (this *ShadowGenerator) Dispose() {
 	this._shadowMap.Dispose()
 }
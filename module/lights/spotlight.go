@@ -2,6 +2,7 @@ package lights
 
 import (
 	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/math32"
 )
 
@@ -37,3 +38,12 @@ func (this *SpotLight) Init() {
 func (this *SpotLight) IsSupportShadow() bool {
 	return true
 }
+
+// CanAffectMesh also leaves out the meshes out of Range
+func (this *SpotLight) CanAffectMesh(mesh IMesh) bool {
+	return this.Light.CanAffectMesh(mesh) && this._rangeInfluence(mesh) > 0
+}
+
+func (this *SpotLight) GetInfluence(mesh IMesh) float32 {
+	return this._rangeInfluence(mesh)
+}
@@ -0,0 +1,246 @@
+// Package babylon loads scenes saved in the .babylon JSON format.
+//
+// Babylon textures are flipped vertically when uploaded while fly3d keeps the
+// first image row at v = 0, texture coordinates are inverted while loading so
+// that exported assets look the same in both engines.
+package babylon
+
+type sceneData struct {
+	AutoClear    *bool     `json:"autoClear"`
+	ClearColor   []float32 `json:"clearColor"`
+	AmbientColor []float32 `json:"ambientColor"`
+	Gravity      []float32 `json:"gravity"`
+
+	FogMode    int       `json:"fogMode"`
+	FogColor   []float32 `json:"fogColor"`
+	FogStart   float32   `json:"fogStart"`
+	FogEnd     float32   `json:"fogEnd"`
+	FogDensity float32   `json:"fogDensity"`
+
+	Lights              []*lightData              `json:"lights"`
+	Cameras             []*cameraData             `json:"cameras"`
+	ActiveCameraID      string                    `json:"activeCameraID"`
+	Materials           []*materialData           `json:"materials"`
+	MultiMaterials      []*multiMaterialData      `json:"multiMaterials"`
+	Skeletons           []*skeletonData           `json:"skeletons"`
+	MorphTargetManagers []*morphTargetManagerData `json:"morphTargetManagers"`
+	Meshes              []*meshData               `json:"meshes"`
+	ParticleSystems     []*particleSystemData     `json:"particleSystems"`
+	ShadowGenerators    []*shadowGeneratorData    `json:"shadowGenerators"`
+}
+
+type lightData struct {
+	Name        string    `json:"name"`
+	Id          string    `json:"id"`
+	Type        int       `json:"type"`
+	Position    []float32 `json:"position"`
+	Direction   []float32 `json:"direction"`
+	Angle       float32   `json:"angle"`
+	Exponent    float32   `json:"exponent"`
+	GroundColor []float32 `json:"groundColor"`
+	Intensity   *float32  `json:"intensity"`
+	Diffuse     []float32 `json:"diffuse"`
+	Specular    []float32 `json:"specular"`
+}
+
+type cameraData struct {
+	Name           string    `json:"name"`
+	Id             string    `json:"id"`
+	Type           string    `json:"type"`
+	Position       []float32 `json:"position"`
+	Target         []float32 `json:"target"`
+	Rotation       []float32 `json:"rotation"`
+	LockedTargetId string    `json:"lockedTargetId"`
+
+	Alpha  float32 `json:"alpha"`
+	Beta   float32 `json:"beta"`
+	Radius float32 `json:"radius"`
+
+	Fov             float32   `json:"fov"`
+	MinZ            float32   `json:"minZ"`
+	MaxZ            float32   `json:"maxZ"`
+	Speed           float32   `json:"speed"`
+	Inertia         *float32  `json:"inertia"`
+	CheckCollisions bool      `json:"checkCollisions"`
+	ApplyGravity    bool      `json:"applyGravity"`
+	Ellipsoid       []float32 `json:"ellipsoid"`
+}
+
+type textureData struct {
+	Name             string    `json:"name"`
+	Level            *float32  `json:"level"`
+	HasAlpha         bool      `json:"hasAlpha"`
+	CoordinatesMode  int       `json:"coordinatesMode"`
+	CoordinatesIndex float32   `json:"coordinatesIndex"`
+	UOffset          float32   `json:"uOffset"`
+	VOffset          float32   `json:"vOffset"`
+	UScale           *float32  `json:"uScale"`
+	VScale           *float32  `json:"vScale"`
+	UAng             float32   `json:"uAng"`
+	VAng             float32   `json:"vAng"`
+	WAng             float32   `json:"wAng"`
+	WrapU            *int      `json:"wrapU"`
+	WrapV            *int      `json:"wrapV"`
+	IsCube           bool      `json:"isCube"`
+	IsRenderTarget   bool      `json:"isRenderTarget"`
+	RenderTargetSize int       `json:"renderTargetSize"`
+	MirrorPlane      []float32 `json:"mirrorPlane"`
+	RenderList       []string  `json:"renderList"`
+}
+
+type materialData struct {
+	Name            string    `json:"name"`
+	Id              string    `json:"id"`
+	Ambient         []float32 `json:"ambient"`
+	Diffuse         []float32 `json:"diffuse"`
+	Specular        []float32 `json:"specular"`
+	SpecularPower   *float32  `json:"specularPower"`
+	Emissive        []float32 `json:"emissive"`
+	Alpha           *float32  `json:"alpha"`
+	BackFaceCulling *bool     `json:"backFaceCulling"`
+	Wireframe       bool      `json:"wireframe"`
+
+	DiffuseTexture    *textureData `json:"diffuseTexture"`
+	AmbientTexture    *textureData `json:"ambientTexture"`
+	OpacityTexture    *textureData `json:"opacityTexture"`
+	ReflectionTexture *textureData `json:"reflectionTexture"`
+	EmissiveTexture   *textureData `json:"emissiveTexture"`
+	SpecularTexture   *textureData `json:"specularTexture"`
+	BumpTexture       *textureData `json:"bumpTexture"`
+}
+
+type multiMaterialData struct {
+	Name      string   `json:"name"`
+	Id        string   `json:"id"`
+	Materials []string `json:"materials"`
+}
+
+type subMeshData struct {
+	MaterialIndex int `json:"materialIndex"`
+	VerticesStart int `json:"verticesStart"`
+	VerticesCount int `json:"verticesCount"`
+	IndexStart    int `json:"indexStart"`
+	IndexCount    int `json:"indexCount"`
+}
+
+type keyData struct {
+	Frame  float32   `json:"frame"`
+	Values []float32 `json:"values"`
+}
+
+type animationData struct {
+	Name           string     `json:"name"`
+	Property       string     `json:"property"`
+	DataType       int        `json:"dataType"`
+	FramePerSecond float32    `json:"framePerSecond"`
+	LoopBehavior   int        `json:"loopBehavior"`
+	Keys           []*keyData `json:"keys"`
+}
+
+type boneData struct {
+	Name            string         `json:"name"`
+	ParentBoneIndex int            `json:"parentBoneIndex"`
+	Matrix          []float32      `json:"matrix"`
+	Animation       *animationData `json:"animation"`
+}
+
+type skeletonData struct {
+	Name  string      `json:"name"`
+	Id    int         `json:"id"`
+	Bones []*boneData `json:"bones"`
+
+	AutoAnimate     bool    `json:"autoAnimate"`
+	AutoAnimateFrom float32 `json:"autoAnimateFrom"`
+	AutoAnimateTo   float32 `json:"autoAnimateTo"`
+	AutoAnimateLoop bool    `json:"autoAnimateLoop"`
+}
+
+type morphTargetData struct {
+	Name       string           `json:"name"`
+	Influence  float32          `json:"influence"`
+	Positions  []float32        `json:"positions"`
+	Normals    []float32        `json:"normals"`
+	Animations []*animationData `json:"animations"`
+}
+
+type morphTargetManagerData struct {
+	Id      int                `json:"id"`
+	Targets []*morphTargetData `json:"targets"`
+}
+
+type meshData struct {
+	Name                 string    `json:"name"`
+	Id                   string    `json:"id"`
+	ParentId             string    `json:"parentId"`
+	MaterialId           string    `json:"materialId"`
+	SkeletonId           *int      `json:"skeletonId"`
+	MorphTargetManagerId *int      `json:"morphTargetManagerId"`
+	Position             []float32 `json:"position"`
+	Rotation             []float32 `json:"rotation"`
+	RotationQuaternion   []float32 `json:"rotationQuaternion"`
+	Scaling              []float32 `json:"scaling"`
+
+	IsEnabled       *bool    `json:"isEnabled"`
+	IsVisible       *bool    `json:"isVisible"`
+	Pickable        *bool    `json:"pickable"`
+	Visibility      *float32 `json:"visibility"`
+	BillboardMode   int      `json:"billboardMode"`
+	CheckCollisions bool     `json:"checkCollisions"`
+	ReceiveShadows  bool     `json:"receiveShadows"`
+
+	Positions       []float32 `json:"positions"`
+	Normals         []float32 `json:"normals"`
+	Uvs             []float32 `json:"uvs"`
+	Uvs2            []float32 `json:"uvs2"`
+	Colors          []float32 `json:"colors"`
+	MatricesIndices []float32 `json:"matricesIndices"`
+	MatricesWeights []float32 `json:"matricesWeights"`
+	Indices         []int     `json:"indices"`
+
+	// Format of the first exporters, positions, normals and uvCount uv sets interleaved
+	Vertices []float32 `json:"vertices"`
+	UvCount  int       `json:"uvCount"`
+
+	SubMeshes []*subMeshData `json:"subMeshes"`
+
+	Animations      []*animationData `json:"animations"`
+	AutoAnimate     bool             `json:"autoAnimate"`
+	AutoAnimateFrom float32          `json:"autoAnimateFrom"`
+	AutoAnimateTo   float32          `json:"autoAnimateTo"`
+	AutoAnimateLoop bool             `json:"autoAnimateLoop"`
+}
+
+type particleSystemData struct {
+	EmitterId       string    `json:"emitterId"`
+	Capacity        int       `json:"capacity"`
+	TextureName     string    `json:"textureName"`
+	MinAngularSpeed float32   `json:"minAngularSpeed"`
+	MaxAngularSpeed float32   `json:"maxAngularSpeed"`
+	MinSize         float32   `json:"minSize"`
+	MaxSize         float32   `json:"maxSize"`
+	MinLifeTime     float32   `json:"minLifeTime"`
+	MaxLifeTime     float32   `json:"maxLifeTime"`
+	MinEmitPower    float32   `json:"minEmitPower"`
+	MaxEmitPower    float32   `json:"maxEmitPower"`
+	EmitRate        float32   `json:"emitRate"`
+	UpdateSpeed     float32   `json:"updateSpeed"`
+	TargetStopFrame float32   `json:"targetStopFrame"`
+	BlendMode       int       `json:"blendMode"`
+	DeadAlpha       float32   `json:"deadAlpha"`
+	Gravity         []float32 `json:"gravity"`
+	Direction1      []float32 `json:"direction1"`
+	Direction2      []float32 `json:"direction2"`
+	MinEmitBox      []float32 `json:"minEmitBox"`
+	MaxEmitBox      []float32 `json:"maxEmitBox"`
+	Color1          []float32 `json:"color1"`
+	Color2          []float32 `json:"color2"`
+	ColorDead       []float32 `json:"colorDead"`
+	TextureMask     []float32 `json:"textureMask"`
+}
+
+type shadowGeneratorData struct {
+	LightId              string   `json:"lightId"`
+	MapSize              int      `json:"mapSize"`
+	UseVarianceShadowMap bool     `json:"useVarianceShadowMap"`
+	RenderList           []string `json:"renderList"`
+}
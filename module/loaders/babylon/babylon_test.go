@@ -0,0 +1,227 @@
+// +build glnull glsoft
+
+package babylon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/lights"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+)
+
+func TestLoadMeshes(t *testing.T) {
+	tests := []struct {
+		name      string
+		meshes    string
+		positions []float32
+		uvs       []float32
+		indices   []uint32
+		subMeshes int
+		material  string
+	}{
+		{
+			name:      "positions",
+			meshes:    `[{"name": "mesh", "id": "mesh", "positions": [0, 0, 0, 1, 0, 0, 0, 1, 0], "normals": [0, 0, 1, 0, 0, 1, 0, 0, 1], "indices": [0, 1, 2]}]`,
+			positions: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+			material:  "default material",
+		},
+		{
+			name:      "interleaved vertices",
+			meshes:    `[{"name": "mesh", "id": "mesh", "uvCount": 1, "vertices": [0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 0, 0, 1, 1, 0.25, 0, 1, 0, 0, 0, 1, 0, 1], "indices": [0, 1, 2]}]`,
+			positions: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+			uvs:       []float32{0, 1, 1, 0.75, 0, 0},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+			material:  "default material",
+		},
+		{
+			name:      "material",
+			meshes:    `[{"name": "mesh", "id": "mesh", "materialId": "red", "positions": [0, 0, 0, 1, 0, 0, 0, 1, 0], "indices": [0, 1, 2]}]`,
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+			material:  "red",
+		},
+		{
+			name:      "missing material",
+			meshes:    `[{"name": "mesh", "id": "mesh", "materialId": "missing", "positions": [0, 0, 0, 1, 0, 0, 0, 1, 0], "indices": [0, 1, 2]}]`,
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+			material:  "default material",
+		},
+		{
+			name: "submeshes",
+			meshes: `[{"name": "mesh", "id": "mesh", "materialId": "multi", "positions": [0, 0, 0, 1, 0, 0, 0, 1, 0, 1, 1, 0], "indices": [0, 1, 2, 2, 1, 3],
+				"subMeshes": [{"materialIndex": 0, "verticesStart": 0, "verticesCount": 3, "indexStart": 0, "indexCount": 3}, {"materialIndex": 1, "verticesStart": 1, "verticesCount": 3, "indexStart": 3, "indexCount": 3}]}]`,
+			indices:   []uint32{0, 1, 2, 2, 1, 3},
+			subMeshes: 2,
+			material:  "multi",
+		},
+		{
+			name:     "index out of range",
+			meshes:   `[{"name": "mesh", "id": "mesh", "positions": [0, 0, 0, 1, 0, 0, 0, 1, 0], "indices": [0, 1, 3]}]`,
+			material: "default material",
+		},
+	}
+
+	for _, test := range tests {
+		scene := enginetest.NewScene(t)
+		content := `{
+			"materials": [{"name": "red", "id": "red", "diffuse": [1, 0, 0]}, {"name": "blue", "id": "blue", "diffuse": [0, 0, 1]}],
+			"multiMaterials": [{"name": "multi", "id": "multi", "materials": ["red", "blue"]}],
+			"meshes": ` + test.meshes + `}`
+		if err := NewSceneLoader().LoadFromData("", []byte(content), scene, nil, nil); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		mesh, ok := scene.GetMeshByID("mesh").(*meshs.Mesh)
+		if !ok {
+			t.Errorf("%s: mesh not loaded", test.name)
+			continue
+		}
+		if test.positions != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_PositionKind), test.positions) {
+			t.Errorf("%s: positions %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_PositionKind), test.positions)
+		}
+		if test.uvs != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_UVKind), test.uvs) {
+			t.Errorf("%s: uvs %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_UVKind), test.uvs)
+		}
+		if len(mesh.GetIndices()) != 0 || len(test.indices) != 0 {
+			if !reflect.DeepEqual(mesh.GetIndices(), test.indices) {
+				t.Errorf("%s: indices %v, want %v", test.name, mesh.GetIndices(), test.indices)
+			}
+		}
+		if len(mesh.SubMeshes) != test.subMeshes {
+			t.Errorf("%s: %d submeshes, want %d", test.name, len(mesh.SubMeshes), test.subMeshes)
+		}
+
+		material := ""
+		if multiMaterial, ok := mesh.MutilMaterial.(*materials.MultiMaterial); ok {
+			material = multiMaterial.Name
+		} else if standard, ok := mesh.Material.(*materials.StandardMaterial); ok {
+			material = standard.Name
+		}
+		if material != test.material {
+			t.Errorf("%s: material %q, want %q", test.name, material, test.material)
+		}
+	}
+}
+
+func TestLoadScene(t *testing.T) {
+	content := `{
+		"clearColor": [0.1, 0.2, 0.3],
+		"fogMode": 3, "fogStart": 10, "fogEnd": 20,
+		"lights": [
+			{"name": "point", "id": "point", "type": 0, "position": [1, 2, 3], "intensity": 0.5},
+			{"name": "sun", "id": "sun", "type": 1, "direction": [0, -1, 1]},
+			{"name": "sky", "id": "sky", "type": 3, "groundColor": [0.5, 0.5, 0.5]},
+			{"name": "unknown", "id": "unknown", "type": 9}
+		],
+		"cameras": [
+			{"name": "free", "id": "free", "type": "FreeCamera", "position": [0, 1, -10]},
+			{"name": "orbit", "id": "orbit", "type": "ArcRotateCamera", "alpha": 1, "beta": 1, "radius": 10, "lockedTargetId": "child"}
+		],
+		"activeCameraID": "orbit",
+		"meshes": [
+			{"name": "child", "id": "child", "parentId": "root"},
+			{"name": "root", "id": "root", "position": [1, 0, 0]}
+		]
+	}`
+
+	scene := enginetest.NewScene(t)
+	progress := make([]int, 0)
+	loaded := false
+	err := NewSceneLoader().LoadFromData("", []byte(content), scene, func(*engines.Scene) {
+		loaded = true
+	}, func(percent int) {
+		progress = append(progress, percent)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded || len(progress) != 8 || progress[len(progress)-1] != 100 {
+		t.Errorf("loaded %v with progress %v", loaded, progress)
+	}
+	if !scene.ClearColor.Equals(math32.NewColor3(0.1, 0.2, 0.3)) || scene.FogMode != 3 || scene.FogEnd != 20 {
+		t.Errorf("scene settings not loaded")
+	}
+
+	checks := []func(light ILight) bool{
+		func(light ILight) bool {
+			point, ok := light.(*lights.PointLight)
+			return ok && point.Id == "point" && point.Position.Equals(math32.NewVector3(1, 2, 3)) && point.Intensity == 0.5
+		},
+		func(light ILight) bool {
+			sun, ok := light.(*lights.DirectionalLight)
+			return ok && sun.Id == "sun"
+		},
+		func(light ILight) bool {
+			hemispheric, ok := light.(*lights.HemisphericLight)
+			return ok && hemispheric.Id == "sky" && hemispheric.GroundColor.Equals(math32.NewColor3(0.5, 0.5, 0.5))
+		},
+	}
+	if len(scene.Lights) != len(checks) {
+		t.Fatalf("%d lights, want %d", len(scene.Lights), len(checks))
+	}
+	for index, check := range checks {
+		if !check(scene.Lights[index]) {
+			t.Errorf("light %d: got %T", index, scene.Lights[index])
+		}
+	}
+
+	if scene.ActiveCamera == nil || scene.ActiveCamera.GetId() != "orbit" {
+		t.Errorf("active camera %v, want orbit", scene.ActiveCamera)
+	}
+
+	child := scene.GetMeshByID("child").(*meshs.Mesh)
+	root := scene.GetMeshByID("root").(*meshs.Mesh)
+	if child.Parent != root {
+		t.Errorf("child is not parented to root")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	err := NewSceneLoader().LoadFromData("", []byte(`{"meshes": [`), enginetest.NewScene(t), nil, nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "babylon: ") {
+		t.Errorf("error %v, want a babylon error", err)
+	}
+}
+
+func TestUnpackMatricesIndices(t *testing.T) {
+	tests := []struct {
+		name    string
+		indices []float32
+		count   int
+		want    []float32
+	}{
+		{"packed", []float32{float32(1 | 2<<8 | 3<<16), 4}, 2, []float32{1, 2, 3, 0, 4, 0, 0, 0}},
+		{"unpacked", []float32{1, 2, 3, 0}, 1, []float32{1, 2, 3, 0}},
+	}
+
+	for _, test := range tests {
+		if got := unpackMatricesIndices(test.indices, test.count); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func nearSlice(values []float32, want []float32) bool {
+	if len(values) != len(want) {
+		return false
+	}
+	for index := range values {
+		if math32.Abs(values[index]-want[index]) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,848 @@
+package babylon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/gl"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/animations"
+	"github.com/suiqirui1987/fly3d/module/bones"
+	"github.com/suiqirui1987/fly3d/module/cameras"
+	"github.com/suiqirui1987/fly3d/module/lights"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+	"github.com/suiqirui1987/fly3d/module/particles"
+	"github.com/suiqirui1987/fly3d/module/textures"
+	"github.com/suiqirui1987/fly3d/tools"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+const (
+	lightTypePoint       = 0
+	lightTypeDirectional = 1
+	lightTypeSpot        = 2
+	lightTypeHemispheric = 3
+)
+
+type SceneLoader struct {
+	_scene      *engines.Scene
+	_rootUrl    string
+	_onProgress func(int)
+
+	_lights  map[string]ILight
+	_cameras map[string]ICamera
+	_meshes  map[string]*meshs.Mesh
+
+	_skeletons           map[int]*bones.Skeleton
+	_morphTargetManagers map[int]*morphTargetManagerData
+
+	_defaultMaterial *materials.StandardMaterial
+
+	_total  int
+	_loaded int
+}
+
+func NewSceneLoader() *SceneLoader {
+	this := &SceneLoader{}
+	return this
+}
+
+// Load reads rootUrl+fileName and adds its content to scene, onProgress receives a percentage
+func (this *SceneLoader) Load(rootUrl string, fileName string, scene *engines.Scene, onSuccess func(*engines.Scene), onProgress func(int)) error {
+	content, err := tools.OpenGeneralFile(rootUrl + fileName)
+	if err != nil {
+		return err
+	}
+
+	return this.LoadFromData(rootUrl, content, scene, onSuccess, onProgress)
+}
+
+// LoadFromData adds the content of a .babylon file to scene, textures are relative to rootUrl
+func (this *SceneLoader) LoadFromData(rootUrl string, content []byte, scene *engines.Scene, onSuccess func(*engines.Scene), onProgress func(int)) error {
+	data := &sceneData{}
+	if err := json.Unmarshal(tools.Clean(content), data); err != nil {
+		return fmt.Errorf("babylon: %s", err)
+	}
+
+	this._scene = scene
+	this._rootUrl = rootUrl
+	this._onProgress = onProgress
+
+	this._lights = map[string]ILight{}
+	this._cameras = map[string]ICamera{}
+	this._meshes = map[string]*meshs.Mesh{}
+	this._skeletons = map[int]*bones.Skeleton{}
+	this._morphTargetManagers = map[int]*morphTargetManagerData{}
+	for _, m := range data.MorphTargetManagers {
+		this._morphTargetManagers[m.Id] = m
+	}
+	this._defaultMaterial = nil
+
+	this._total = len(data.Lights) + len(data.Cameras) + len(data.Materials) + len(data.MultiMaterials) +
+		len(data.Skeletons) + len(data.Meshes) + len(data.ShadowGenerators) + len(data.ParticleSystems)
+	this._loaded = 0
+
+	this._loadScene(data)
+
+	for _, l := range data.Lights {
+		this._loadLight(l)
+		this._progress()
+	}
+
+	for _, c := range data.Cameras {
+		this._loadCamera(c)
+		this._progress()
+	}
+
+	for _, m := range data.Materials {
+		this._loadMaterial(m)
+		this._progress()
+	}
+
+	for _, m := range data.MultiMaterials {
+		this._loadMultiMaterial(m)
+		this._progress()
+	}
+
+	for _, s := range data.Skeletons {
+		this._loadSkeleton(s)
+		this._progress()
+	}
+
+	for _, m := range data.Meshes {
+		this._loadMesh(m)
+		this._progress()
+	}
+
+	// Parents may come after their children
+	for _, m := range data.Meshes {
+		if m.ParentId == "" {
+			continue
+		}
+		mesh, ok := this._meshes[m.Id]
+		if !ok {
+			continue
+		}
+		if parent, ok := this._meshes[m.ParentId]; ok {
+			mesh.Parent = parent
+		} else {
+			log.Printf("babylon: parent %s of mesh %s not found", m.ParentId, m.Name)
+		}
+	}
+
+	// Cameras locked on a mesh need the meshes
+	for _, c := range data.Cameras {
+		this._lockCamera(c)
+	}
+	if data.ActiveCameraID != "" {
+		if camera, ok := this._cameras[data.ActiveCameraID]; ok {
+			scene.ActiveCamera = camera
+		}
+	}
+
+	for _, s := range data.ShadowGenerators {
+		this._loadShadowGenerator(s)
+		this._progress()
+	}
+
+	for _, p := range data.ParticleSystems {
+		this._loadParticleSystem(p)
+		this._progress()
+	}
+
+	// Animations start once the whole hierarchy exists
+	for _, m := range data.Meshes {
+		if mesh, ok := this._meshes[m.Id]; ok && m.AutoAnimate {
+			animations.BeginAnimation(scene, mesh, m.AutoAnimateFrom, m.AutoAnimateTo, m.AutoAnimateLoop, 1.0)
+		}
+	}
+	for _, s := range data.Skeletons {
+		if skeleton, ok := this._skeletons[s.Id]; ok && s.AutoAnimate {
+			animations.BeginAnimation(scene, skeleton, s.AutoAnimateFrom, s.AutoAnimateTo, s.AutoAnimateLoop, 1.0)
+		}
+	}
+
+	if this._total == 0 && onProgress != nil {
+		onProgress(100)
+	}
+	if onSuccess != nil {
+		onSuccess(scene)
+	}
+
+	return nil
+}
+
+func (this *SceneLoader) _progress() {
+	this._loaded++
+	if this._onProgress != nil {
+		this._onProgress(this._loaded * 100 / this._total)
+	}
+}
+
+func vector3(values []float32) *math32.Vector3 {
+	if len(values) < 3 {
+		return nil
+	}
+	return math32.NewVector3(values[0], values[1], values[2])
+}
+
+func color3(values []float32) *math32.Color3 {
+	if len(values) < 3 {
+		return nil
+	}
+	return math32.NewColor3(values[0], values[1], values[2])
+}
+
+func color4(values []float32) *math32.Color4 {
+	if len(values) < 4 {
+		return nil
+	}
+	return math32.NewColor4(values[0], values[1], values[2], values[3])
+}
+
+func (this *SceneLoader) _loadScene(data *sceneData) {
+	scene := this._scene
+
+	if data.AutoClear != nil {
+		scene.AutoClear = *data.AutoClear
+	}
+	if color := color3(data.ClearColor); color != nil {
+		scene.ClearColor = color
+	}
+	if color := color3(data.AmbientColor); color != nil {
+		scene.AmbientColor = color
+	}
+	if gravity := vector3(data.Gravity); gravity != nil {
+		scene.Gravity = gravity
+	}
+
+	if data.FogMode != 0 {
+		scene.FogMode = data.FogMode
+		if color := color3(data.FogColor); color != nil {
+			scene.FogColor = color
+		}
+		scene.FogStart = data.FogStart
+		scene.FogEnd = data.FogEnd
+		scene.FogDensity = data.FogDensity
+	}
+}
+
+// Lights
+
+func (this *SceneLoader) _loadLight(data *lightData) {
+	position := vector3(data.Position)
+	if position == nil {
+		position = math32.NewVector3Zero()
+	}
+	direction := vector3(data.Direction)
+	if direction == nil {
+		direction = math32.NewVector3(0, -1, 0)
+	}
+
+	var light *lights.Light
+	var result ILight
+	switch data.Type {
+	case lightTypePoint:
+		point := lights.NewPointLight(data.Name, position, this._scene)
+		light, result = &point.Light, point
+	case lightTypeDirectional:
+		directional := lights.NewDirectionalLight(data.Name, direction, this._scene)
+		light, result = &directional.Light, directional
+	case lightTypeSpot:
+		spot := lights.NewSpotLight(data.Name, position, direction, data.Angle, data.Exponent, this._scene)
+		light, result = &spot.Light, spot
+	case lightTypeHemispheric:
+		hemispheric := lights.NewHemisphericLight(data.Name, direction, this._scene)
+		if color := color3(data.GroundColor); color != nil {
+			hemispheric.GroundColor = color
+		}
+		light, result = &hemispheric.Light, hemispheric
+	default:
+		log.Printf("babylon: light type %d is not supported", data.Type)
+		return
+	}
+
+	if data.Id != "" {
+		light.Id = data.Id
+	}
+	if data.Intensity != nil {
+		light.Intensity = *data.Intensity
+	}
+	if color := color3(data.Diffuse); color != nil {
+		light.Diffuse = color
+	}
+	if color := color3(data.Specular); color != nil {
+		light.Specular = color
+	}
+
+	this._lights[light.Id] = result
+}
+
+// Cameras
+
+func (this *SceneLoader) _loadCamera(data *cameraData) {
+	var camera *cameras.Camera
+	var result ICamera
+
+	if data.Type == "ArcRotateCamera" {
+		// The locked mesh is set once the meshes are loaded
+		target := vector3(data.Target)
+		if target == nil {
+			target = math32.NewVector3Zero()
+		}
+		arcRotate := cameras.NewArcRotateCamera(data.Name, data.Alpha, data.Beta, data.Radius, target, this._scene)
+		camera, result = &arcRotate.Camera, arcRotate
+	} else {
+		position := vector3(data.Position)
+		if position == nil {
+			position = math32.NewVector3Zero()
+		}
+		free := cameras.NewFreeCamera(data.Name, position, this._scene)
+		if target := vector3(data.Target); target != nil {
+			free.SetTarget(target)
+		} else if rotation := vector3(data.Rotation); rotation != nil {
+			free.Rotation = rotation
+		}
+		if data.Speed != 0 {
+			free.Speed = data.Speed
+		}
+		free.CheckCollisions = data.CheckCollisions
+		free.ApplyGravity = data.ApplyGravity
+		if ellipsoid := vector3(data.Ellipsoid); ellipsoid != nil {
+			free.Ellipsoid = ellipsoid
+		}
+		camera, result = &free.Camera, free
+	}
+
+	if data.Id != "" {
+		camera.Id = data.Id
+	}
+	if data.Fov != 0 {
+		camera.Fov = data.Fov
+	}
+	if data.MinZ != 0 {
+		camera.MinZ = data.MinZ
+	}
+	if data.MaxZ != 0 {
+		camera.MaxZ = data.MaxZ
+	}
+	if data.Inertia != nil {
+		camera.Inertia = *data.Inertia
+	}
+
+	this._cameras[camera.Id] = result
+}
+
+func (this *SceneLoader) _lockCamera(data *cameraData) {
+	if data.LockedTargetId == "" {
+		return
+	}
+	id := data.Id
+	if id == "" {
+		id = data.Name
+	}
+
+	mesh, ok := this._meshes[data.LockedTargetId]
+	if !ok {
+		log.Printf("babylon: target %s of camera %s not found", data.LockedTargetId, data.Name)
+		return
+	}
+
+	switch camera := this._cameras[id].(type) {
+	case *cameras.ArcRotateCamera:
+		camera.Target = mesh.Position
+	case *cameras.FreeCamera:
+		camera.SetTarget(mesh.Position)
+	}
+}
+
+// Materials
+
+func (this *SceneLoader) _loadTexture(data *textureData) ITexture {
+	if data == nil || data.Name == "" {
+		return nil
+	}
+	url := this._rootUrl + data.Name
+
+	if data.IsRenderTarget || len(data.MirrorPlane) > 0 {
+		log.Printf("babylon: render target texture %s is not supported", data.Name)
+		return nil
+	}
+
+	if data.IsCube {
+		cube := textures.NewCubeTexture(url, this._scene, nil)
+		if data.Level != nil {
+			cube.Level = *data.Level
+		}
+		cube.EnableHasAlpha(data.HasAlpha)
+		return cube
+	}
+
+	texture := textures.NewTexture(url, this._scene, false, 0)
+	if texture == nil {
+		return nil
+	}
+
+	if data.Level != nil {
+		texture.Level = *data.Level
+	}
+	texture.EnableHasAlpha(data.HasAlpha)
+	texture.CoordinatesMode = data.CoordinatesMode
+	texture.CoordinatesIndex = data.CoordinatesIndex
+	texture.UOffset = data.UOffset
+	texture.VOffset = data.VOffset
+	if data.UScale != nil {
+		texture.UScale = *data.UScale
+	}
+	if data.VScale != nil {
+		texture.VScale = *data.VScale
+	}
+	texture.UAng = data.UAng
+	texture.VAng = data.VAng
+	texture.WAng = data.WAng
+
+	// 0 clamp, 1 wrap, 2 mirror like the engine address modes
+	if data.WrapU != nil {
+		texture.GetGLTexture().WrapU = gl.Enum(*data.WrapU)
+	}
+	if data.WrapV != nil {
+		texture.GetGLTexture().WrapV = gl.Enum(*data.WrapV)
+	}
+
+	return texture
+}
+
+func (this *SceneLoader) _loadMaterial(data *materialData) {
+	mat := materials.NewStandardMaterial(data.Name, this._scene)
+	if data.Id != "" {
+		mat.Id = data.Id
+	}
+
+	if color := color3(data.Ambient); color != nil {
+		mat.AmbientColor = color
+	}
+	if color := color3(data.Diffuse); color != nil {
+		mat.DiffuseColor = color
+	}
+	if color := color3(data.Specular); color != nil {
+		mat.SpecularColor = color
+	}
+	if data.SpecularPower != nil {
+		mat.SpecularPower = *data.SpecularPower
+	}
+	if color := color3(data.Emissive); color != nil {
+		mat.EmissiveColor = color
+	}
+	if data.Alpha != nil {
+		mat.Alpha = *data.Alpha
+	}
+	if data.BackFaceCulling != nil {
+		mat.BackFaceCulling = *data.BackFaceCulling
+	}
+	mat.Wireframe = data.Wireframe
+
+	mat.DiffuseTexture = this._loadTexture(data.DiffuseTexture)
+	mat.AmbientTexture = this._loadTexture(data.AmbientTexture)
+	mat.OpacityTexture = this._loadTexture(data.OpacityTexture)
+	mat.ReflectionTexture = this._loadTexture(data.ReflectionTexture)
+	mat.EmissiveTexture = this._loadTexture(data.EmissiveTexture)
+	mat.SpecularTexture = this._loadTexture(data.SpecularTexture)
+	mat.BumpTexture = this._loadTexture(data.BumpTexture)
+}
+
+func (this *SceneLoader) _loadMultiMaterial(data *multiMaterialData) {
+	multiMaterial := materials.NewMultiMaterial(data.Name, this._scene)
+	if data.Id != "" {
+		multiMaterial.Id = data.Id
+	}
+
+	for _, id := range data.Materials {
+		mat := this._scene.GetMaterialByID(id)
+		if mat == nil {
+			log.Printf("babylon: material %s of %s not found", id, data.Name)
+		}
+		multiMaterial.SubMaterials = append(multiMaterial.SubMaterials, mat)
+	}
+}
+
+func (this *SceneLoader) _material() *materials.StandardMaterial {
+	if this._defaultMaterial == nil {
+		this._defaultMaterial = materials.NewStandardMaterial("default material", this._scene)
+	}
+	return this._defaultMaterial
+}
+
+// Meshes
+
+func (this *SceneLoader) _loadMesh(data *meshData) {
+	mesh := meshs.NewMesh(data.Name, this._scene)
+	if data.Id != "" {
+		mesh.Id = data.Id
+	}
+	this._meshes[mesh.Id] = mesh
+
+	if position := vector3(data.Position); position != nil {
+		mesh.Position = position
+	}
+	if rotation := vector3(data.Rotation); rotation != nil {
+		mesh.Rotation = rotation
+	}
+	if len(data.RotationQuaternion) >= 4 {
+		mesh.RotationQuaternion = math32.NewQuaternion(data.RotationQuaternion[0], data.RotationQuaternion[1], data.RotationQuaternion[2], data.RotationQuaternion[3])
+	}
+	if scaling := vector3(data.Scaling); scaling != nil {
+		mesh.Scaling = scaling
+	}
+
+	if data.IsEnabled != nil {
+		mesh.SetEnabled(*data.IsEnabled)
+	}
+	if data.IsVisible != nil {
+		mesh.Isvisible = *data.IsVisible
+	}
+	if data.Pickable != nil {
+		mesh.Ispickable = *data.Pickable
+	}
+	if data.Visibility != nil {
+		mesh.Visibility = *data.Visibility
+	}
+	mesh.BillboardMode = data.BillboardMode
+	mesh.Checkcollisions = data.CheckCollisions
+	mesh.ReceiveShadows = data.ReceiveShadows
+
+	if err := this._loadGeometry(data, mesh); err != nil {
+		log.Printf("babylon: mesh %s: %s", data.Name, err)
+	}
+
+	if data.SkeletonId != nil && *data.SkeletonId > -1 {
+		if skeleton, ok := this._skeletons[*data.SkeletonId]; ok {
+			mesh.Skeleton = skeleton
+		} else {
+			log.Printf("babylon: skeleton %d of mesh %s not found", *data.SkeletonId, data.Name)
+		}
+	}
+
+	if data.MorphTargetManagerId != nil && *data.MorphTargetManagerId > -1 {
+		if manager, ok := this._morphTargetManagers[*data.MorphTargetManagerId]; ok {
+			this._loadMorphTargets(manager, mesh)
+		} else {
+			log.Printf("babylon: morph target manager %d of mesh %s not found", *data.MorphTargetManagerId, data.Name)
+		}
+	}
+
+	if data.MaterialId != "" {
+		mesh.SetMaterialByID(data.MaterialId)
+	}
+	if mesh.Material == nil && mesh.MutilMaterial == nil {
+		if data.MaterialId != "" {
+			log.Printf("babylon: material %s of mesh %s not found", data.MaterialId, data.Name)
+		}
+		mesh.Material = this._material()
+	}
+
+	for _, a := range data.Animations {
+		if animation := this._loadAnimation(a); animation != nil {
+			mesh.AddAnimation(animation)
+		}
+	}
+}
+
+func (this *SceneLoader) _loadGeometry(data *meshData, mesh *meshs.Mesh) error {
+	positions := data.Positions
+	normals := data.Normals
+	uvs := data.Uvs
+	uvs2 := data.Uvs2
+
+	if len(data.Vertices) > 0 {
+		stride := 6 + data.UvCount*2
+		count := len(data.Vertices) / stride
+		positions = make([]float32, 0, count*3)
+		normals = make([]float32, 0, count*3)
+		uvs, uvs2 = nil, nil
+		for vertex := 0; vertex < count; vertex++ {
+			offset := vertex * stride
+			positions = append(positions, data.Vertices[offset:offset+3]...)
+			normals = append(normals, data.Vertices[offset+3:offset+6]...)
+			if data.UvCount > 0 {
+				uvs = append(uvs, data.Vertices[offset+6:offset+8]...)
+			}
+			if data.UvCount > 1 {
+				uvs2 = append(uvs2, data.Vertices[offset+8:offset+10]...)
+			}
+		}
+	}
+
+	if len(positions) == 0 {
+		return nil
+	}
+	indices := make([]uint32, len(data.Indices))
+	for index, value := range data.Indices {
+		if value < 0 || value >= len(positions)/3 {
+			return fmt.Errorf("index %d out of range", value)
+		}
+		indices[index] = uint32(value)
+	}
+
+	mesh.SetVerticesData(positions, IMesh_VB_PositionKind, false)
+	if len(normals) > 0 {
+		mesh.SetVerticesData(normals, IMesh_VB_NormalKind, false)
+	}
+	if len(uvs) > 0 {
+		mesh.SetVerticesData(flipV(uvs), IMesh_VB_UVKind, false)
+	}
+	if len(uvs2) > 0 {
+		mesh.SetVerticesData(flipV(uvs2), IMesh_VB_UV2Kind, false)
+	}
+	if len(data.Colors) > 0 {
+		mesh.SetVerticesData(data.Colors, IMesh_VB_ColorKind, false)
+	}
+	if len(data.MatricesIndices) > 0 {
+		mesh.SetVerticesData(unpackMatricesIndices(data.MatricesIndices, len(positions)/3), IMesh_VB_MatricesIndicesKind, false)
+	}
+	if len(data.MatricesWeights) > 0 {
+		mesh.SetVerticesData(data.MatricesWeights, IMesh_VB_MatricesWeightsKind, false)
+	}
+	if err := mesh.SetIndices(indices); err != nil {
+		return err
+	}
+
+	if len(data.SubMeshes) > 0 {
+		mesh.SubMeshes = make([]*meshs.SubMesh, 0)
+		for _, sub := range data.SubMeshes {
+			meshs.NewSubMesh(sub.MaterialIndex, sub.VerticesStart, sub.VerticesCount, sub.IndexStart, sub.IndexCount, mesh)
+		}
+	}
+
+	return nil
+}
+
+// flipV inverts the v coordinates, Babylon starts them at the bottom of the image
+func flipV(uvs []float32) []float32 {
+	result := make([]float32, len(uvs))
+	for index := 0; index+1 < len(uvs); index += 2 {
+		result[index] = uvs[index]
+		result[index+1] = 1 - uvs[index+1]
+	}
+	return result
+}
+
+// unpackMatricesIndices expands the bone indices that exporters pack in one
+// value per vertex, one byte per index
+func unpackMatricesIndices(matricesIndices []float32, verticesCount int) []float32 {
+	if len(matricesIndices) != verticesCount {
+		return matricesIndices
+	}
+
+	result := make([]float32, 0, verticesCount*4)
+	for _, value := range matricesIndices {
+		packed := uint32(value)
+		result = append(result, float32(packed&0xFF), float32((packed>>8)&0xFF), float32((packed>>16)&0xFF), float32(packed>>24))
+	}
+	return result
+}
+
+// Skeletons
+
+func (this *SceneLoader) _loadSkeleton(data *skeletonData) {
+	skeleton := bones.NewSkeleton(data.Name, strconv.Itoa(data.Id), this._scene)
+	this._skeletons[data.Id] = skeleton
+
+	for _, b := range data.Bones {
+		var parent *bones.Bone
+		if b.ParentBoneIndex > -1 {
+			if b.ParentBoneIndex >= len(skeleton.Bones) {
+				log.Printf("babylon: skeleton %s: parent %d of bone %s not found", data.Name, b.ParentBoneIndex, b.Name)
+			} else {
+				parent = skeleton.Bones[b.ParentBoneIndex]
+			}
+		}
+
+		matrix := math32.NewMatrix4().Identity()
+		if len(b.Matrix) >= 16 {
+			copy(matrix[:], b.Matrix)
+		}
+
+		bone := bones.NewBone(b.Name, skeleton, parent, matrix)
+
+		if b.Animation != nil {
+			// The exporters animate the private matrix of the bones
+			if b.Animation.Property == "_matrix" {
+				b.Animation.Property = "matrix"
+			}
+			if animation := this._loadAnimation(b.Animation); animation != nil {
+				bone.AddAnimation(animation)
+			}
+		}
+	}
+}
+
+// Morph targets
+
+func (this *SceneLoader) _loadMorphTargets(data *morphTargetManagerData, mesh *meshs.Mesh) {
+	for _, t := range data.Targets {
+		target := mesh.AddMorphTarget(t.Name, t.Positions, t.Normals)
+		if target == nil {
+			continue
+		}
+		target.SetInfluence(t.Influence)
+
+		// The influence of a target is animated through its mesh
+		index := len(mesh.MorphTargets) - 1
+		for _, a := range t.Animations {
+			if a.Property == "influence" {
+				a.Property = "morphTargets." + strconv.Itoa(index) + ".influence"
+			}
+			if animation := this._loadAnimation(a); animation != nil {
+				mesh.AddAnimation(animation)
+			}
+		}
+	}
+}
+
+// Animations
+
+func (this *SceneLoader) _loadAnimation(data *animationData) *animations.Animation {
+	if len(data.Keys) == 0 {
+		return nil
+	}
+
+	keys := make([]*animations.AnimationKeyFrame, 0, len(data.Keys))
+	for _, key := range data.Keys {
+		var value interface{}
+		switch data.DataType {
+		case animations.ANIMATIONTYPE_FLOAT:
+			if len(key.Values) >= 1 {
+				value = key.Values[0]
+			}
+		case animations.ANIMATIONTYPE_VECTOR3:
+			if v := vector3(key.Values); v != nil {
+				value = v
+			}
+		case animations.ANIMATIONTYPE_QUATERNION:
+			if len(key.Values) >= 4 {
+				value = math32.NewQuaternion(key.Values[0], key.Values[1], key.Values[2], key.Values[3])
+			}
+		case animations.ANIMATIONTYPE_MATRIX:
+			if len(key.Values) >= 16 {
+				matrix := math32.NewMatrix4()
+				copy(matrix[:], key.Values)
+				value = matrix
+			}
+		default:
+			log.Printf("babylon: animation %s: data type %d is not supported", data.Name, data.DataType)
+			return nil
+		}
+		if value == nil {
+			log.Printf("babylon: animation %s: invalid key at frame %v", data.Name, key.Frame)
+			return nil
+		}
+		keys = append(keys, &animations.AnimationKeyFrame{Frame: key.Frame, Value: value})
+	}
+
+	// Animations need at least one frame between the first and the last key
+	if keys[len(keys)-1].Frame-keys[0].Frame < 1 {
+		keys = append(keys, &animations.AnimationKeyFrame{Frame: keys[0].Frame + 1, Value: keys[len(keys)-1].Value})
+	}
+
+	framePerSecond := data.FramePerSecond
+	if framePerSecond == 0 {
+		framePerSecond = 30
+	}
+
+	animation := animations.NewAnimation(data.Name, data.Property, framePerSecond, data.DataType, data.LoopBehavior)
+	animation.SetKeys(keys)
+	return animation
+}
+
+// Shadows and particles
+
+func (this *SceneLoader) _loadShadowGenerator(data *shadowGeneratorData) {
+	light, ok := this._lights[data.LightId]
+	if !ok {
+		log.Printf("babylon: light %s of shadow generator not found", data.LightId)
+		return
+	}
+
+	mapSize := data.MapSize
+	if mapSize == 0 {
+		mapSize = 1024
+	}
+	generator := lights.NewShadowGenerator(data.LightId+"_shadowMap", mapSize, light, this._scene)
+	if generator == nil {
+		return
+	}
+	generator.UseVarianceShadowMap = data.UseVarianceShadowMap
+
+	for _, id := range data.RenderList {
+		if mesh, ok := this._meshes[id]; ok {
+			generator.GetShadowMap().AddRenderList(mesh)
+		} else {
+			log.Printf("babylon: mesh %s of shadow generator not found", id)
+		}
+	}
+}
+
+func (this *SceneLoader) _loadParticleSystem(data *particleSystemData) {
+	emitter, ok := this._meshes[data.EmitterId]
+	if !ok {
+		log.Printf("babylon: emitter %s of particle system not found", data.EmitterId)
+		return
+	}
+
+	system := particles.NewParticleSystem("particles#"+emitter.Name, data.Capacity, this._scene)
+	system.Emitter = emitter
+
+	if data.TextureName != "" {
+		if texture := textures.NewTexture(this._rootUrl+data.TextureName, this._scene, false, 0); texture != nil {
+			system.ParticleTexture = texture
+		}
+	}
+
+	system.MinAngularSpeed = data.MinAngularSpeed
+	system.MaxAngularSpeed = data.MaxAngularSpeed
+	system.MinSize = data.MinSize
+	system.MaxSize = data.MaxSize
+	system.MinLifeTime = data.MinLifeTime
+	system.MaxLifeTime = data.MaxLifeTime
+	system.MinEmitPower = data.MinEmitPower
+	system.MaxEmitPower = data.MaxEmitPower
+	system.EmitRate = data.EmitRate
+	system.UpdateSpeed = data.UpdateSpeed
+	system.TargetStopDuration = data.TargetStopFrame
+	system.BlendMode = particles.PARTICLEENUM(data.BlendMode)
+	system.DeadAlpha = data.DeadAlpha
+
+	if v := vector3(data.Gravity); v != nil {
+		system.Gravity = v
+	}
+	if v := vector3(data.Direction1); v != nil {
+		system.Direction1 = v
+	}
+	if v := vector3(data.Direction2); v != nil {
+		system.Direction2 = v
+	}
+	if v := vector3(data.MinEmitBox); v != nil {
+		system.MinEmitBox = v
+	}
+	if v := vector3(data.MaxEmitBox); v != nil {
+		system.MaxEmitBox = v
+	}
+	if c := color4(data.Color1); c != nil {
+		system.Color1 = c
+	}
+	if c := color4(data.Color2); c != nil {
+		system.Color2 = c
+	}
+	if c := color4(data.ColorDead); c != nil {
+		system.ColorDead = c
+	}
+	if c := color4(data.TextureMask); c != nil {
+		system.TextureMask = c
+	}
+
+	system.Start()
+}
@@ -0,0 +1,340 @@
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/tools"
+)
+
+// parse reads a .gltf JSON document or a .glb container, the binary chunk of a .glb is returned with the document
+func parse(content []byte) (*document, []byte, error) {
+	var binaryChunk []byte
+
+	if len(content) >= 12 && binary.LittleEndian.Uint32(content[0:4]) == glbMagic {
+		version := binary.LittleEndian.Uint32(content[4:8])
+		if version != 2 {
+			return nil, nil, fmt.Errorf("gltf: unsupported glb version %d", version)
+		}
+
+		length := int(binary.LittleEndian.Uint32(content[8:12]))
+		if length > len(content) {
+			return nil, nil, errors.New("gltf: truncated glb file")
+		}
+
+		var jsonChunk []byte
+		offset := 12
+		for offset+8 <= length {
+			chunkLength := int(binary.LittleEndian.Uint32(content[offset : offset+4]))
+			chunkType := binary.LittleEndian.Uint32(content[offset+4 : offset+8])
+			offset += 8
+			if offset+chunkLength > length {
+				return nil, nil, errors.New("gltf: truncated glb chunk")
+			}
+
+			switch chunkType {
+			case glbChunkJSON:
+				jsonChunk = content[offset : offset+chunkLength]
+			case glbChunkBIN:
+				if binaryChunk == nil {
+					binaryChunk = content[offset : offset+chunkLength]
+				}
+			}
+			offset += chunkLength
+		}
+
+		if jsonChunk == nil {
+			return nil, nil, errors.New("gltf: glb file without JSON chunk")
+		}
+		content = jsonChunk
+	}
+
+	doc := &document{}
+	if err := json.Unmarshal(content, doc); err != nil {
+		return nil, nil, fmt.Errorf("gltf: %s", err)
+	}
+	if !strings.HasPrefix(doc.Asset.Version, "2") {
+		return nil, nil, fmt.Errorf("gltf: unsupported version %q", doc.Asset.Version)
+	}
+
+	return doc, binaryChunk, nil
+}
+
+// isDataURI reports whether uri embeds its content
+func isDataURI(uri string) bool {
+	return strings.HasPrefix(uri, "data:")
+}
+
+func decodeDataURI(uri string) ([]byte, error) {
+	comma := strings.Index(uri, ",")
+	if comma == -1 {
+		return nil, errors.New("gltf: malformed data uri")
+	}
+
+	header := uri[:comma]
+	data := uri[comma+1:]
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	unescaped, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(unescaped), nil
+}
+
+// _loadURI returns the content of an embedded or external resource, external resources are relative to the asset
+func (this *Loader) _loadURI(uri string) ([]byte, error) {
+	if isDataURI(uri) {
+		return decodeDataURI(uri)
+	}
+
+	return tools.OpenGeneralFile(this._resolve(uri))
+}
+
+func (this *Loader) _resolve(uri string) string {
+	unescaped, err := url.PathUnescape(uri)
+	if err == nil {
+		uri = unescaped
+	}
+
+	return this._rootUrl + uri
+}
+
+func (this *Loader) _loadBuffers() error {
+	this._buffers = make([][]byte, len(this._doc.Buffers))
+
+	for index, buf := range this._doc.Buffers {
+		var data []byte
+		if buf.URI == "" {
+			if index != 0 || this._binary == nil {
+				return fmt.Errorf("gltf: buffer %d has no data", index)
+			}
+			data = this._binary
+		} else {
+			var err error
+			data, err = this._loadURI(buf.URI)
+			if err != nil {
+				return fmt.Errorf("gltf: buffer %d: %s", index, err)
+			}
+		}
+
+		if len(data) < buf.ByteLength {
+			return fmt.Errorf("gltf: buffer %d is %d bytes, expected %d", index, len(data), buf.ByteLength)
+		}
+		this._buffers[index] = data
+	}
+
+	return nil
+}
+
+func (this *Loader) _bufferView(index int) (*bufferView, []byte, error) {
+	if index < 0 || index >= len(this._doc.BufferViews) {
+		return nil, nil, fmt.Errorf("gltf: invalid buffer view %d", index)
+	}
+
+	view := this._doc.BufferViews[index]
+	if view.Buffer < 0 || view.Buffer >= len(this._buffers) {
+		return nil, nil, fmt.Errorf("gltf: buffer view %d references invalid buffer %d", index, view.Buffer)
+	}
+
+	data := this._buffers[view.Buffer]
+	end := view.ByteOffset + view.ByteLength
+	if view.ByteOffset < 0 || end > len(data) {
+		return nil, nil, fmt.Errorf("gltf: buffer view %d is out of range", index)
+	}
+
+	return view, data[view.ByteOffset:end], nil
+}
+
+// readComponent returns the component at offset as float, normalized integers are mapped to [0, 1] or [-1, 1]
+func readComponent(data []byte, offset int, componentType int, normalized bool) float32 {
+	switch componentType {
+	case componentByte:
+		value := float32(int8(data[offset]))
+		if normalized {
+			return float32(math.Max(float64(value)/127.0, -1.0))
+		}
+		return value
+	case componentUnsignedByte:
+		value := float32(data[offset])
+		if normalized {
+			return value / 255.0
+		}
+		return value
+	case componentShort:
+		value := float32(int16(binary.LittleEndian.Uint16(data[offset:])))
+		if normalized {
+			return float32(math.Max(float64(value)/32767.0, -1.0))
+		}
+		return value
+	case componentUnsignedShort:
+		value := float32(binary.LittleEndian.Uint16(data[offset:]))
+		if normalized {
+			return value / 65535.0
+		}
+		return value
+	case componentUnsignedInt:
+		return float32(binary.LittleEndian.Uint32(data[offset:]))
+	case componentFloat:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data[offset:]))
+	}
+
+	return 0
+}
+
+func readIndex(data []byte, offset int, componentType int) int {
+	switch componentType {
+	case componentUnsignedByte:
+		return int(data[offset])
+	case componentUnsignedShort:
+		return int(binary.LittleEndian.Uint16(data[offset:]))
+	case componentUnsignedInt:
+		return int(binary.LittleEndian.Uint32(data[offset:]))
+	}
+
+	return 0
+}
+
+// _readAccessor returns the elements of an accessor as a flat float array and the number of components per element
+func (this *Loader) _readAccessor(index int) ([]float32, int, error) {
+	if index < 0 || index >= len(this._doc.Accessors) {
+		return nil, 0, fmt.Errorf("gltf: invalid accessor %d", index)
+	}
+
+	acc := this._doc.Accessors[index]
+	componentSize, ok := componentSizes[acc.ComponentType]
+	if !ok {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has invalid component type %d", index, acc.ComponentType)
+	}
+	components, ok := typeSizes[acc.Type]
+	if !ok {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has invalid type %q", index, acc.Type)
+	}
+	if acc.Count < 0 || acc.ByteOffset < 0 {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has negative count or offset", index)
+	}
+
+	result := make([]float32, acc.Count*components)
+
+	// Without buffer view the accessor is initialized with zeros
+	if acc.BufferView != nil {
+		view, data, err := this._bufferView(*acc.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		elementSize := componentSize * components
+		stride := elementSize
+		if view.ByteStride > 0 {
+			stride = view.ByteStride
+		}
+
+		if acc.Count > 0 && acc.ByteOffset+stride*(acc.Count-1)+elementSize > len(data) {
+			return nil, 0, fmt.Errorf("gltf: accessor %d is out of range", index)
+		}
+
+		for element := 0; element < acc.Count; element++ {
+			offset := acc.ByteOffset + element*stride
+			for component := 0; component < components; component++ {
+				result[element*components+component] = readComponent(data, offset+component*componentSize, acc.ComponentType, acc.Normalized)
+			}
+		}
+	}
+
+	// Sparse values replace the referenced elements
+	if acc.Sparse != nil && acc.Sparse.Count > 0 {
+		sparse := acc.Sparse
+		_, indices, err := this._bufferView(sparse.Indices.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+		_, values, err := this._bufferView(sparse.Values.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		indexSize := componentSizes[sparse.Indices.ComponentType]
+		elementSize := componentSize * components
+		if sparse.Indices.ByteOffset+indexSize*sparse.Count > len(indices) || sparse.Values.ByteOffset+elementSize*sparse.Count > len(values) {
+			return nil, 0, fmt.Errorf("gltf: sparse accessor %d is out of range", index)
+		}
+
+		for i := 0; i < sparse.Count; i++ {
+			element := readIndex(indices, sparse.Indices.ByteOffset+i*indexSize, sparse.Indices.ComponentType)
+			if element >= acc.Count {
+				return nil, 0, fmt.Errorf("gltf: sparse accessor %d references element %d", index, element)
+			}
+
+			offset := sparse.Values.ByteOffset + i*elementSize
+			for component := 0; component < components; component++ {
+				result[element*components+component] = readComponent(values, offset+component*componentSize, acc.ComponentType, acc.Normalized)
+			}
+		}
+	}
+
+	return result, components, nil
+}
+
+// _readIndices returns the vertex indices of a primitive
+func (this *Loader) _readIndices(index int) ([]int, error) {
+	if index < 0 || index >= len(this._doc.Accessors) {
+		return nil, fmt.Errorf("gltf: invalid accessor %d", index)
+	}
+
+	acc := this._doc.Accessors[index]
+	if acc.Type != "SCALAR" {
+		return nil, fmt.Errorf("gltf: index accessor %d is not scalar", index)
+	}
+
+	switch acc.ComponentType {
+	case componentUnsignedByte, componentUnsignedShort, componentUnsignedInt:
+	default:
+		return nil, fmt.Errorf("gltf: index accessor %d has invalid component type %d", index, acc.ComponentType)
+	}
+	if acc.Count < 0 || acc.ByteOffset < 0 {
+		return nil, fmt.Errorf("gltf: accessor %d has negative count or offset", index)
+	}
+
+	// Sparse or empty index accessors go through the generic path
+	if acc.BufferView == nil || acc.Sparse != nil {
+		values, _, err := this._readAccessor(index)
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]int, len(values))
+		for i, value := range values {
+			result[i] = int(value)
+		}
+		return result, nil
+	}
+
+	view, data, err := this._bufferView(*acc.BufferView)
+	if err != nil {
+		return nil, err
+	}
+
+	componentSize := componentSizes[acc.ComponentType]
+	stride := componentSize
+	if view.ByteStride > 0 {
+		stride = view.ByteStride
+	}
+	if acc.Count > 0 && acc.ByteOffset+stride*(acc.Count-1)+componentSize > len(data) {
+		return nil, fmt.Errorf("gltf: accessor %d is out of range", index)
+	}
+
+	result := make([]int, acc.Count)
+	for i := 0; i < acc.Count; i++ {
+		result[i] = readIndex(data, acc.ByteOffset+i*stride, acc.ComponentType)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package gltf
+
+import (
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/animations"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// stepOffset separates the two keys emulating a step between samples
+const stepOffset = 0.001
+
+// AnimationGroup is a glTF animation, the tracks of a target are installed on the mesh when the group starts.
+// The tracks of the first group are installed while loading so BeginAnimation plays it directly.
+type AnimationGroup struct {
+	Name string
+	From float32
+	To   float32
+
+	_scene      *engines.Scene
+	_targets    []*meshs.Mesh
+	_animations [][]IAnimation
+}
+
+func (this *AnimationGroup) GetTargets() []*meshs.Mesh {
+	return this._targets
+}
+
+// Start plays the group on its targets, speedRatio 1.0 plays at the authored speed
+func (this *AnimationGroup) Start(loop bool, speedRatio float32) {
+	for index, target := range this._targets {
+		target.SetAnimations(this._animations[index])
+		animations.BeginAnimation(this._scene, target, this.From, this.To, loop, speedRatio)
+	}
+}
+
+func (this *AnimationGroup) Stop() {
+	for _, target := range this._targets {
+		animations.StopAnimation(this._scene, target)
+	}
+}
+
+func (this *AnimationGroup) _addAnimation(target *meshs.Mesh, animation *animations.Animation) {
+	for index := range this._targets {
+		if this._targets[index] == target {
+			this._animations[index] = append(this._animations[index], animation)
+			return
+		}
+	}
+
+	this._targets = append(this._targets, target)
+	this._animations = append(this._animations, []IAnimation{animation})
+}
+
+func (this *Loader) _loadAnimation(index int) {
+	a := this._doc.Animations[index]
+	name := a.Name
+	if name == "" {
+		name = fmt.Sprintf("animation%d", index)
+	}
+
+	group := &AnimationGroup{}
+	group.Name = name
+	group._scene = this._scene
+
+	first := true
+	for channelIndex, channel := range a.Channels {
+		if channel.Target.Node == nil || *channel.Target.Node < 0 || *channel.Target.Node >= len(this._nodes) {
+			continue
+		}
+		target := this._nodes[*channel.Target.Node]
+		if target == nil {
+			continue
+		}
+		if channel.Sampler < 0 || channel.Sampler >= len(a.Samplers) {
+			log.Printf("gltf: animation %s channel %d has invalid sampler", name, channelIndex)
+			continue
+		}
+
+		var property string
+		var dataType int
+		switch channel.Target.Path {
+		case "translation":
+			property = "Position"
+			dataType = animations.ANIMATIONTYPE_VECTOR3
+		case "rotation":
+			property = "RotationQuaternion"
+			dataType = animations.ANIMATIONTYPE_QUATERNION
+		case "scale":
+			property = "Scaling"
+			dataType = animations.ANIMATIONTYPE_VECTOR3
+		default:
+			log.Printf("gltf: animation %s channel %d: path %q is not supported", name, channelIndex, channel.Target.Path)
+			continue
+		}
+
+		keys, err := this._loadKeys(a.Samplers[channel.Sampler].Input, a.Samplers[channel.Sampler].Output, a.Samplers[channel.Sampler].Interpolation, channel.Target.Path)
+		if err != nil {
+			log.Printf("gltf: animation %s channel %d: %s", name, channelIndex, err)
+			continue
+		}
+
+		animation := animations.NewAnimation(fmt.Sprintf("%s.%s", name, property), property, framePerSecond, dataType, animations.ANIMATIONLOOPMODE_CYCLE)
+		animation.SetKeys(keys)
+		group._addAnimation(target, animation)
+
+		if first || keys[0].Frame < group.From {
+			group.From = keys[0].Frame
+		}
+		if first || keys[len(keys)-1].Frame > group.To {
+			group.To = keys[len(keys)-1].Frame
+		}
+		first = false
+	}
+
+	if len(group._targets) == 0 {
+		return
+	}
+
+	if len(this._model.AnimationGroups) == 0 {
+		for index, target := range group._targets {
+			target.SetAnimations(group._animations[index])
+		}
+	}
+	this._model.AnimationGroups = append(this._model.AnimationGroups, group)
+}
+
+// _loadKeys converts a sampler to key frames, cubic splines keep their values and are interpolated linearly
+func (this *Loader) _loadKeys(input int, output int, interpolation string, path string) ([]*animations.AnimationKeyFrame, error) {
+	times, _, err := this._readAccessor(input)
+	if err != nil {
+		return nil, err
+	}
+	values, components, err := this._readAccessor(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, fmt.Errorf("sampler without keys")
+	}
+
+	stride := components
+	offset := 0
+	if interpolation == "CUBICSPLINE" {
+		// in tangent, value, out tangent
+		stride = components * 3
+		offset = components
+	}
+	if len(values) < len(times)*stride {
+		return nil, fmt.Errorf("sampler has %d values for %d keys", len(values)/stride, len(times))
+	}
+
+	value := func(index int) interface{} {
+		start := index*stride + offset
+		switch path {
+		case "rotation":
+			return convertQuaternion(math32.NewQuaternion(values[start], values[start+1], values[start+2], values[start+3]))
+		case "translation":
+			return convertVector3(math32.NewVector3(values[start], values[start+1], values[start+2]))
+		}
+		return math32.NewVector3(values[start], values[start+1], values[start+2])
+	}
+
+	keys := make([]*animations.AnimationKeyFrame, 0, len(times))
+	for index, time := range times {
+		frame := time * framePerSecond
+		if interpolation == "STEP" && index > 0 {
+			keys = append(keys, &animations.AnimationKeyFrame{Frame: frame - stepOffset, Value: value(index - 1)})
+		}
+		keys = append(keys, &animations.AnimationKeyFrame{Frame: frame, Value: value(index)})
+	}
+
+	// Animations need at least one frame between the first and the last key
+	last := keys[len(keys)-1]
+	if last.Frame-keys[0].Frame < 1 {
+		keys = append(keys, &animations.AnimationKeyFrame{Frame: keys[0].Frame + 1, Value: last.Value})
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,221 @@
+// Package gltf loads glTF 2.0 assets (.gltf and .glb) into a scene.
+//
+// glTF is right handed while the engine is left handed, positions, normals,
+// tangents, translations and rotations are mirrored on the Z axis while
+// loading.
+package gltf
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+
+	modeTriangles = 4
+
+	wrapClampToEdge = 33071
+
+	// glTF stores times in seconds, animations are sampled at this rate
+	framePerSecond = 60
+)
+
+var componentSizes = map[int]int{
+	componentByte:          1,
+	componentUnsignedByte:  1,
+	componentShort:         2,
+	componentUnsignedShort: 2,
+	componentUnsignedInt:   4,
+	componentFloat:         4,
+}
+
+var typeSizes = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+	"MAT2":   4,
+	"MAT3":   9,
+	"MAT4":   16,
+}
+
+type document struct {
+	Asset struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Name  string `json:"name"`
+		Nodes []int  `json:"nodes"`
+	} `json:"scenes"`
+
+	Nodes       []*node       `json:"nodes"`
+	Meshes      []*mesh       `json:"meshes"`
+	Accessors   []*accessor   `json:"accessors"`
+	BufferViews []*bufferView `json:"bufferViews"`
+	Buffers     []*buffer     `json:"buffers"`
+	Materials   []*material   `json:"materials"`
+	Textures    []*texture    `json:"textures"`
+	Images      []*imageInfo  `json:"images"`
+	Samplers    []*sampler    `json:"samplers"`
+	Cameras     []*camera     `json:"cameras"`
+	Animations  []*animation  `json:"animations"`
+
+	ExtensionsRequired []string `json:"extensionsRequired"`
+	Extensions         struct {
+		LightsPunctual *struct {
+			Lights []*light `json:"lights"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type node struct {
+	Name        string    `json:"name"`
+	Mesh        *int      `json:"mesh"`
+	Camera      *int      `json:"camera"`
+	Children    []int     `json:"children"`
+	Translation []float32 `json:"translation"`
+	Rotation    []float32 `json:"rotation"`
+	Scale       []float32 `json:"scale"`
+	Matrix      []float32 `json:"matrix"`
+
+	Extensions struct {
+		LightsPunctual *struct {
+			Light int `json:"light"`
+		} `json:"KHR_lights_punctual"`
+	} `json:"extensions"`
+}
+
+type primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type mesh struct {
+	Name       string       `json:"name"`
+	Primitives []*primitive `json:"primitives"`
+}
+
+type accessor struct {
+	BufferView    *int   `json:"bufferView"`
+	ByteOffset    int    `json:"byteOffset"`
+	ComponentType int    `json:"componentType"`
+	Normalized    bool   `json:"normalized"`
+	Count         int    `json:"count"`
+	Type          string `json:"type"`
+
+	Sparse *struct {
+		Count   int `json:"count"`
+		Indices struct {
+			BufferView    int `json:"bufferView"`
+			ByteOffset    int `json:"byteOffset"`
+			ComponentType int `json:"componentType"`
+		} `json:"indices"`
+		Values struct {
+			BufferView int `json:"bufferView"`
+			ByteOffset int `json:"byteOffset"`
+		} `json:"values"`
+	} `json:"sparse"`
+}
+
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride"`
+}
+
+type buffer struct {
+	URI        string `json:"uri"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type textureInfo struct {
+	Index    int     `json:"index"`
+	TexCoord int     `json:"texCoord"`
+	Strength float32 `json:"strength"`
+}
+
+type material struct {
+	Name                 string `json:"name"`
+	PbrMetallicRoughness *struct {
+		BaseColorFactor  []float32    `json:"baseColorFactor"`
+		BaseColorTexture *textureInfo `json:"baseColorTexture"`
+		MetallicFactor   *float32     `json:"metallicFactor"`
+		RoughnessFactor  *float32     `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+	OcclusionTexture *textureInfo `json:"occlusionTexture"`
+	EmissiveTexture  *textureInfo `json:"emissiveTexture"`
+	EmissiveFactor   []float32    `json:"emissiveFactor"`
+	AlphaMode        string       `json:"alphaMode"`
+	DoubleSided      bool         `json:"doubleSided"`
+}
+
+type texture struct {
+	Sampler *int `json:"sampler"`
+	Source  *int `json:"source"`
+}
+
+type imageInfo struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+}
+
+type sampler struct {
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type camera struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Perspective *struct {
+		YFov  float32 `json:"yfov"`
+		ZNear float32 `json:"znear"`
+		ZFar  float32 `json:"zfar"`
+	} `json:"perspective"`
+	Orthographic *struct {
+		XMag  float32 `json:"xmag"`
+		YMag  float32 `json:"ymag"`
+		ZNear float32 `json:"znear"`
+		ZFar  float32 `json:"zfar"`
+	} `json:"orthographic"`
+}
+
+type light struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Color     []float32 `json:"color"`
+	Intensity *float32  `json:"intensity"`
+	Spot      *struct {
+		InnerConeAngle float32  `json:"innerConeAngle"`
+		OuterConeAngle *float32 `json:"outerConeAngle"`
+	} `json:"spot"`
+}
+
+type animation struct {
+	Name     string `json:"name"`
+	Channels []struct {
+		Sampler int `json:"sampler"`
+		Target  struct {
+			Node *int   `json:"node"`
+			Path string `json:"path"`
+		} `json:"target"`
+	} `json:"channels"`
+	Samplers []struct {
+		Input         int    `json:"input"`
+		Output        int    `json:"output"`
+		Interpolation string `json:"interpolation"`
+	} `json:"samplers"`
+}
@@ -0,0 +1,270 @@
+// +build glnull glsoft
+
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+// testBuffer holds the positions, the indices and the tangents of a triangle
+func testBuffer() []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []float32{0, 0, 1, 1, 0, 1, 0, 1, 1})
+	binary.Write(&data, binary.LittleEndian, []uint16{0, 1, 2, 0})
+	binary.Write(&data, binary.LittleEndian, []float32{1, 0, 0, 1, 1, 0, 0, 1, 0, 0, 1, -1})
+	return data.Bytes()
+}
+
+// testDocument returns an asset reading testBuffer, with the given meshes and nodes
+func testDocument(meshes string, nodes string) string {
+	buffer := testBuffer()
+	return fmt.Sprintf(`{
+		"asset": {"version": "2.0"},
+		"buffers": [{"uri": "data:application/octet-stream;base64,%s", "byteLength": %d}],
+		"bufferViews": [
+			{"buffer": 0, "byteOffset": 0, "byteLength": 36},
+			{"buffer": 0, "byteOffset": 36, "byteLength": 6},
+			{"buffer": 0, "byteOffset": 44, "byteLength": 48}
+		],
+		"accessors": [
+			{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+			{"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"},
+			{"bufferView": 2, "componentType": 5126, "count": 3, "type": "VEC4"},
+			{"bufferView": 0, "componentType": 5126, "count": -1, "type": "VEC3"}
+		],
+		"materials": [{"name": "a"}, {"name": "b"}],
+		"meshes": %s,
+		"nodes": %s
+	}`, base64.StdEncoding.EncodeToString(buffer), len(buffer), meshes, nodes)
+}
+
+func TestLoadFromData(t *testing.T) {
+	triangle := `[{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1}]}]`
+
+	tests := []struct {
+		name      string
+		meshes    string
+		nodes     string
+		names     []string
+		positions []float32
+		tangents  []float32
+		indices   []uint32
+		subMeshes int
+	}{
+		{
+			name:      "triangle",
+			meshes:    triangle,
+			nodes:     `[{"name": "triangle", "mesh": 0}]`,
+			names:     []string{"triangle"},
+			positions: []float32{0, 0, -1, 1, 0, -1, 0, 1, -1},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:      "without indices",
+			meshes:    `[{"primitives": [{"attributes": {"POSITION": 0}}]}]`,
+			nodes:     `[{"mesh": 0}]`,
+			names:     []string{"node0"},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:      "tangents",
+			meshes:    `[{"primitives": [{"attributes": {"POSITION": 0, "TANGENT": 2}, "indices": 1}]}]`,
+			nodes:     `[{"mesh": 0}]`,
+			names:     []string{"node0"},
+			tangents:  []float32{1, 0, 0, -1, 1, 0, 0, -1, 0, 0, -1, 1},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:      "tangents of some primitives",
+			meshes:    `[{"primitives": [{"attributes": {"POSITION": 0, "TANGENT": 2}}, {"attributes": {"POSITION": 0}}]}]`,
+			nodes:     `[{"mesh": 0}]`,
+			names:     []string{"node0"},
+			indices:   []uint32{0, 1, 2, 3, 4, 5},
+			subMeshes: 2,
+		},
+		{
+			name:      "primitives",
+			meshes:    `[{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 0}, {"attributes": {"POSITION": 0}, "indices": 1, "material": 1}]}]`,
+			nodes:     `[{"mesh": 0}]`,
+			names:     []string{"node0"},
+			positions: []float32{0, 0, -1, 1, 0, -1, 0, 1, -1, 0, 0, -1, 1, 0, -1, 0, 1, -1},
+			indices:   []uint32{0, 1, 2, 3, 4, 5},
+			subMeshes: 2,
+		},
+		{
+			name:      "hierarchy",
+			meshes:    triangle,
+			nodes:     `[{"name": "root", "children": [1], "translation": [1, 2, 3]}, {"name": "child", "mesh": 0}]`,
+			names:     []string{"root", "child"},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:   "negative count",
+			meshes: `[{"primitives": [{"attributes": {"POSITION": 3}}]}]`,
+			nodes:  `[{"name": "invalid", "mesh": 0}]`,
+			names:  []string{"invalid"},
+		},
+	}
+
+	for _, test := range tests {
+		scene := enginetest.NewScene(t)
+		model, err := LoadFromData(test.name+".gltf", []byte(testDocument(test.meshes, test.nodes)), scene)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		names := make([]string, len(model.Meshes))
+		for index, mesh := range model.Meshes {
+			names[index] = mesh.GetName()
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s: meshes %v, want %v", test.name, names, test.names)
+			continue
+		}
+
+		mesh := model.Meshes[len(model.Meshes)-1]
+		if test.positions != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_PositionKind), test.positions) {
+			t.Errorf("%s: positions %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_PositionKind), test.positions)
+		}
+		if mesh.IsVerticesDataPresent(IMesh_VB_TangentKind) != (test.tangents != nil) {
+			t.Errorf("%s: tangents present %v", test.name, mesh.IsVerticesDataPresent(IMesh_VB_TangentKind))
+		} else if test.tangents != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_TangentKind), test.tangents) {
+			t.Errorf("%s: tangents %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_TangentKind), test.tangents)
+		}
+		if len(mesh.GetIndices()) != 0 || len(test.indices) != 0 {
+			if !reflect.DeepEqual(mesh.GetIndices(), test.indices) {
+				t.Errorf("%s: indices %v, want %v", test.name, mesh.GetIndices(), test.indices)
+			}
+		}
+		if len(mesh.SubMeshes) != test.subMeshes {
+			t.Errorf("%s: %d submeshes, want %d", test.name, len(mesh.SubMeshes), test.subMeshes)
+		}
+		if test.subMeshes > 1 && mesh.MutilMaterial == nil {
+			t.Errorf("%s: primitives without MultiMaterial", test.name)
+		}
+	}
+}
+
+func TestLoadHierarchy(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	nodes := `[{"name": "root", "children": [1], "translation": [1, 2, 3], "scale": [2, 2, 2]}, {"name": "child", "mesh": 0}]`
+	model, err := LoadFromData("hierarchy.gltf", []byte(testDocument(`[{"primitives": [{"attributes": {"POSITION": 0}}]}]`, nodes)), scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, child := model.Meshes[0], model.Meshes[1]
+	if len(model.Roots) != 1 || model.Roots[0] != root || child.Parent != root {
+		t.Fatalf("child is not parented to root")
+	}
+	if !root.Position.Equals(math32.NewVector3(1, 2, -3)) {
+		t.Errorf("root position %v, want (1, 2, -3)", root.Position)
+	}
+	if !root.Scaling.Equals(math32.NewVector3(2, 2, 2)) {
+		t.Errorf("root scaling %v, want (2, 2, 2)", root.Scaling)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"invalid json", `{"asset":`, "gltf: unexpected end of JSON input"},
+		{"version", `{"asset": {"version": "1.0"}}`, `unsupported version "1.0"`},
+		{"extension", `{"asset": {"version": "2.0"}, "extensionsRequired": ["KHR_draco_mesh_compression"]}`, "KHR_draco_mesh_compression is not supported"},
+		{"buffer without data", `{"asset": {"version": "2.0"}, "buffers": [{"byteLength": 4}]}`, "buffer 0 has no data"},
+		{"short buffer", `{"asset": {"version": "2.0"}, "buffers": [{"uri": "data:,abc", "byteLength": 4}]}`, "buffer 0 is 3 bytes, expected 4"},
+	}
+
+	for _, test := range tests {
+		_, err := LoadFromData(test.name+".gltf", []byte(test.content), enginetest.NewScene(t))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+// glb packs chunks into a binary container, the chunk types are the little endian magic numbers
+func glb(version uint32, length int, chunks ...[]byte) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []uint32{glbMagic, version, 0})
+	for index, chunk := range chunks {
+		chunkType := uint32(glbChunkJSON)
+		if index > 0 {
+			chunkType = glbChunkBIN
+		}
+		binary.Write(&data, binary.LittleEndian, []uint32{uint32(len(chunk)), chunkType})
+		data.Write(chunk)
+	}
+
+	content := data.Bytes()
+	if length < 0 {
+		length = len(content)
+	}
+	binary.LittleEndian.PutUint32(content[8:], uint32(length))
+	return content
+}
+
+func TestParse(t *testing.T) {
+	document := []byte(`{"asset": {"version": "2.0"}}`)
+
+	tests := []struct {
+		name    string
+		content []byte
+		binary  int
+		err     string
+	}{
+		{"gltf", document, 0, ""},
+		{"glb", glb(2, -1, document, testBuffer()), len(testBuffer()), ""},
+		{"glb without binary", glb(2, -1, document), 0, ""},
+		{"glb version", glb(1, -1, document), 0, "unsupported glb version 1"},
+		{"glb truncated", glb(2, 1000, document), 0, "truncated glb file"},
+		{"glb without json", glb(2, 12), 0, "glb file without JSON chunk"},
+	}
+
+	for _, test := range tests {
+		doc, binary, err := parse(test.content)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if doc.Asset.Version != "2.0" || len(binary) != test.binary {
+			t.Errorf("%s: version %q and %d binary bytes, want 2.0 and %d", test.name, doc.Asset.Version, len(binary), test.binary)
+		}
+	}
+}
+
+func nearSlice(values []float32, want []float32) bool {
+	if len(values) != len(want) {
+		return false
+	}
+	for index := range values {
+		if math32.Abs(values[index]-want[index]) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,769 @@
+package gltf
+
+import (
+	"errors"
+	"fmt"
+	_ "image/jpeg"
+	"math"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/gl"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cameras"
+	"github.com/suiqirui1987/fly3d/module/lights"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+	"github.com/suiqirui1987/fly3d/module/textures"
+	"github.com/suiqirui1987/fly3d/tools"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// Model holds the objects created for an asset
+type Model struct {
+	// Meshes has one mesh per node, parents come before their children
+	Meshes          []*meshs.Mesh
+	Roots           []*meshs.Mesh
+	Materials       []*materials.StandardMaterial
+	Cameras         []*cameras.FreeCamera
+	Lights          []ILight
+	AnimationGroups []*AnimationGroup
+}
+
+type Loader struct {
+	_scene   *engines.Scene
+	_url     string
+	_rootUrl string
+
+	_doc     *document
+	_binary  []byte
+	_buffers [][]byte
+
+	_geometries      map[int]*geometry
+	_materials       map[int]*materials.StandardMaterial
+	_defaultMaterial *materials.StandardMaterial
+	_textures        map[string]*textures.Texture
+	_nodes           []*meshs.Mesh
+
+	_model *Model
+}
+
+// Load reads a .gltf or .glb file and adds its content to scene
+func Load(url string, scene *engines.Scene) (*Model, error) {
+	content, err := tools.OpenGeneralFile(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFromData(url, content, scene)
+}
+
+// LoadFromData adds the content of a .gltf or .glb file to scene, external resources are resolved relative to url
+func LoadFromData(url string, content []byte, scene *engines.Scene) (*Model, error) {
+	doc, binary, err := parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, extension := range doc.ExtensionsRequired {
+		if extension != "KHR_lights_punctual" {
+			return nil, fmt.Errorf("gltf: required extension %s is not supported", extension)
+		}
+	}
+
+	this := &Loader{}
+	this._scene = scene
+	this._url = url
+	this._rootUrl = url[:strings.LastIndex(url, "/")+1]
+	this._doc = doc
+	this._binary = binary
+
+	this._geometries = map[int]*geometry{}
+	this._materials = map[int]*materials.StandardMaterial{}
+	this._textures = map[string]*textures.Texture{}
+	this._nodes = make([]*meshs.Mesh, len(doc.Nodes))
+
+	this._model = &Model{}
+
+	if err := this._loadBuffers(); err != nil {
+		return nil, err
+	}
+
+	for _, index := range this._rootNodes() {
+		this._loadNode(index, nil, math32.NewMatrix4().Identity())
+	}
+
+	for index := range doc.Animations {
+		this._loadAnimation(index)
+	}
+
+	return this._model, nil
+}
+
+// _rootNodes returns the nodes of the default scene, or every node without parent when the asset has no scene
+func (this *Loader) _rootNodes() []int {
+	doc := this._doc
+	if len(doc.Scenes) > 0 {
+		index := 0
+		if doc.Scene != nil && *doc.Scene >= 0 && *doc.Scene < len(doc.Scenes) {
+			index = *doc.Scene
+		}
+		return doc.Scenes[index].Nodes
+	}
+
+	isChild := make([]bool, len(doc.Nodes))
+	for _, n := range doc.Nodes {
+		for _, child := range n.Children {
+			if child >= 0 && child < len(isChild) {
+				isChild[child] = true
+			}
+		}
+	}
+
+	roots := make([]int, 0)
+	for index := range doc.Nodes {
+		if !isChild[index] {
+			roots = append(roots, index)
+		}
+	}
+	return roots
+}
+
+// Nodes
+
+// transform returns the local transformation of a node converted to the left handed system
+func (this *node) transform() (*math32.Vector3, *math32.Quaternion, *math32.Vector3) {
+	translation := math32.NewVector3(0, 0, 0)
+	rotation := math32.NewQuaternion(0, 0, 0, 1)
+	scaling := math32.NewVector3(1, 1, 1)
+
+	if len(this.Matrix) == 16 {
+		// Column major matrices match the row vector layout of Matrix4
+		m := math32.NewMatrix4()
+		copy(m[:], this.Matrix)
+
+		translation = math32.NewVector3(m[12], m[13], m[14])
+		scaling = math32.NewVector3(
+			math32.NewVector3(m[0], m[1], m[2]).Length(),
+			math32.NewVector3(m[4], m[5], m[6]).Length(),
+			math32.NewVector3(m[8], m[9], m[10]).Length())
+		if m.Determinant() < 0 {
+			scaling.X = -scaling.X
+		}
+
+		if scaling.X != 0 && scaling.Y != 0 && scaling.Z != 0 {
+			rotationMatrix := math32.NewMatrix4().Identity()
+			for column := 0; column < 3; column++ {
+				rotationMatrix[column] = m[column] / scaling.X
+				rotationMatrix[4+column] = m[4+column] / scaling.Y
+				rotationMatrix[8+column] = m[8+column] / scaling.Z
+			}
+			rotation = rotation.FromRotationMatrix(rotationMatrix)
+		}
+	} else {
+		if len(this.Translation) == 3 {
+			translation = math32.NewVector3(this.Translation[0], this.Translation[1], this.Translation[2])
+		}
+		if len(this.Rotation) == 4 {
+			rotation = math32.NewQuaternion(this.Rotation[0], this.Rotation[1], this.Rotation[2], this.Rotation[3])
+		}
+		if len(this.Scale) == 3 {
+			scaling = math32.NewVector3(this.Scale[0], this.Scale[1], this.Scale[2])
+		}
+	}
+
+	return convertVector3(translation), convertQuaternion(rotation), scaling
+}
+
+// convertVector3 mirrors a position or a direction on the Z axis
+func convertVector3(v *math32.Vector3) *math32.Vector3 {
+	return math32.NewVector3(v.X, v.Y, -v.Z)
+}
+
+// convertQuaternion mirrors a rotation on the Z axis
+func convertQuaternion(q *math32.Quaternion) *math32.Quaternion {
+	return math32.NewQuaternion(-q.X, -q.Y, q.Z, q.W)
+}
+
+func (this *Loader) _loadNode(index int, parent *meshs.Mesh, parentWorld *math32.Matrix4) {
+	if index < 0 || index >= len(this._doc.Nodes) || this._nodes[index] != nil {
+		log.Printf("gltf: node %d is invalid or referenced twice", index)
+		return
+	}
+
+	n := this._doc.Nodes[index]
+	name := n.Name
+	if name == "" {
+		name = fmt.Sprintf("node%d", index)
+	}
+
+	mesh := meshs.NewMesh(name, this._scene)
+	mesh.Parent = parent
+	this._nodes[index] = mesh
+
+	this._model.Meshes = append(this._model.Meshes, mesh)
+	if parent == nil {
+		this._model.Roots = append(this._model.Roots, mesh)
+	}
+
+	translation, rotation, scaling := n.transform()
+	mesh.Position = translation
+	mesh.RotationQuaternion = rotation
+	mesh.Scaling = scaling
+
+	rotationMatrix := math32.NewMatrix4()
+	rotation.ToRotationMatrix(rotationMatrix)
+	world := math32.NewMatrix4().Scaling(scaling.X, scaling.Y, scaling.Z).
+		Multiply(rotationMatrix).
+		Multiply(math32.NewMatrix4().Translation(translation.X, translation.Y, translation.Z)).
+		Multiply(parentWorld)
+
+	if n.Mesh != nil {
+		if err := this._loadMesh(*n.Mesh, mesh); err != nil {
+			log.Printf("gltf: node %s: %s", name, err)
+		}
+	}
+
+	if n.Camera != nil {
+		this._loadCamera(*n.Camera, name, world)
+	}
+
+	if n.Extensions.LightsPunctual != nil {
+		this._loadLight(n.Extensions.LightsPunctual.Light, name, world)
+	}
+
+	for _, child := range n.Children {
+		this._loadNode(child, mesh, world)
+	}
+}
+
+// Geometry
+
+type subMeshInfo struct {
+	material      int
+	verticesStart int
+	verticesCount int
+	indexStart    int
+	indexCount    int
+}
+
+type geometry struct {
+	positions []float32
+	normals   []float32
+	tangents  []float32
+	uvs       []float32
+	uvs2      []float32
+	colors    []float32
+	indices   []uint32
+
+	subMeshes []*subMeshInfo
+}
+
+type primitiveData struct {
+	positions []float32
+	normals   []float32
+	tangents  []float32
+	uvs       []float32
+	uvs2      []float32
+	colors    []float32
+	indices   []int
+	material  int
+}
+
+func (this *Loader) _readAttribute(p *primitive, name string, components int, count int) ([]float32, error) {
+	index, ok := p.Attributes[name]
+	if !ok {
+		return nil, nil
+	}
+
+	data, size, err := this._readAccessor(index)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)/size != count {
+		return nil, fmt.Errorf("attribute %s has %d elements, expected %d", name, len(data)/size, count)
+	}
+	if size == components {
+		return data, nil
+	}
+	if size < components {
+		return nil, fmt.Errorf("attribute %s has %d components, expected %d", name, size, components)
+	}
+
+	// Drop the extra components, vertex colors are stored without alpha
+	result := make([]float32, count*components)
+	for element := 0; element < count; element++ {
+		copy(result[element*components:(element+1)*components], data[element*size:])
+	}
+	return result, nil
+}
+
+func (this *Loader) _readPrimitive(p *primitive) (*primitiveData, error) {
+	positionIndex, ok := p.Attributes["POSITION"]
+	if !ok {
+		return nil, errors.New("primitive without positions")
+	}
+
+	positions, size, err := this._readAccessor(positionIndex)
+	if err != nil {
+		return nil, err
+	}
+	if size != 3 {
+		return nil, errors.New("positions are not VEC3")
+	}
+	count := len(positions) / 3
+
+	result := &primitiveData{}
+	result.positions = positions
+	result.material = -1
+	if p.Material != nil {
+		result.material = *p.Material
+	}
+
+	if p.Indices != nil {
+		result.indices, err = this._readIndices(*p.Indices)
+		if err != nil {
+			return nil, err
+		}
+		for _, vertex := range result.indices {
+			if vertex >= count {
+				return nil, fmt.Errorf("index %d out of %d vertices", vertex, count)
+			}
+		}
+	} else {
+		result.indices = make([]int, count)
+		for i := range result.indices {
+			result.indices[i] = i
+		}
+	}
+	result.indices = result.indices[:len(result.indices)-len(result.indices)%3]
+
+	if result.normals, err = this._readAttribute(p, "NORMAL", 3, count); err != nil {
+		return nil, err
+	}
+	if result.normals == nil {
+		result.normals = computeNormals(positions, result.indices)
+	}
+	if result.tangents, err = this._readAttribute(p, "TANGENT", 4, count); err != nil {
+		return nil, err
+	}
+	if result.uvs, err = this._readAttribute(p, "TEXCOORD_0", 2, count); err != nil {
+		return nil, err
+	}
+	if result.uvs2, err = this._readAttribute(p, "TEXCOORD_1", 2, count); err != nil {
+		return nil, err
+	}
+	if result.colors, err = this._readAttribute(p, "COLOR_0", 3, count); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// computeNormals averages the normals of the faces sharing each vertex
+func computeNormals(positions []float32, indices []int) []float32 {
+	normals := make([]float32, len(positions))
+
+	for face := 0; face+2 < len(indices); face += 3 {
+		a := math32.NewVector3Zero().FromArray(positions, indices[face]*3)
+		b := math32.NewVector3Zero().FromArray(positions, indices[face+1]*3)
+		c := math32.NewVector3Zero().FromArray(positions, indices[face+2]*3)
+
+		normal := b.Sub(a).Cross(c.Sub(a))
+		for i := 0; i < 3; i++ {
+			vertex := indices[face+i] * 3
+			normals[vertex] += normal.X
+			normals[vertex+1] += normal.Y
+			normals[vertex+2] += normal.Z
+		}
+	}
+
+	for vertex := 0; vertex < len(normals); vertex += 3 {
+		normal := math32.NewVector3Zero().FromArray(normals, vertex)
+		length := normal.Length()
+		if length > 0 {
+			normals[vertex] /= length
+			normals[vertex+1] /= length
+			normals[vertex+2] /= length
+		}
+	}
+
+	return normals
+}
+
+// appendOrZero appends data, or count zero elements when the primitive lacks the attribute
+func appendOrZero(target []float32, data []float32, count int) []float32 {
+	if data != nil {
+		return append(target, data...)
+	}
+	return append(target, make([]float32, count)...)
+}
+
+// _loadGeometry merges the primitives of a mesh into one vertex and index buffer with a submesh per primitive
+func (this *Loader) _loadGeometry(index int) (*geometry, error) {
+	if geo, ok := this._geometries[index]; ok {
+		return geo, nil
+	}
+	if index < 0 || index >= len(this._doc.Meshes) {
+		return nil, fmt.Errorf("invalid mesh %d", index)
+	}
+
+	m := this._doc.Meshes[index]
+	primitives := make([]*primitiveData, 0)
+	hasUV := false
+	hasUV2 := false
+	hasColor := false
+	// Tangents are only kept when every primitive has them, the materials
+	// derive the others
+	hasTangent := true
+
+	for primitiveIndex, p := range m.Primitives {
+		mode := modeTriangles
+		if p.Mode != nil {
+			mode = *p.Mode
+		}
+		if mode != modeTriangles {
+			log.Printf("gltf: mesh %d primitive %d: mode %d is not supported", index, primitiveIndex, mode)
+			continue
+		}
+
+		data, err := this._readPrimitive(p)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %d primitive %d: %s", index, primitiveIndex, err)
+		}
+
+		hasUV = hasUV || data.uvs != nil
+		hasUV2 = hasUV2 || data.uvs2 != nil
+		hasColor = hasColor || data.colors != nil
+		hasTangent = hasTangent && data.tangents != nil
+
+		primitives = append(primitives, data)
+	}
+
+	geo := &geometry{}
+	for _, data := range primitives {
+		count := len(data.positions) / 3
+		verticesStart := len(geo.positions) / 3
+		indexStart := len(geo.indices)
+
+		// Mirror on Z
+		for vertex := 0; vertex < count; vertex++ {
+			geo.positions = append(geo.positions, data.positions[vertex*3], data.positions[vertex*3+1], -data.positions[vertex*3+2])
+			geo.normals = append(geo.normals, data.normals[vertex*3], data.normals[vertex*3+1], -data.normals[vertex*3+2])
+		}
+		// The mirror also inverts the handedness of the tangent space
+		if hasTangent {
+			for vertex := 0; vertex < count; vertex++ {
+				geo.tangents = append(geo.tangents, data.tangents[vertex*4], data.tangents[vertex*4+1], -data.tangents[vertex*4+2], -data.tangents[vertex*4+3])
+			}
+		}
+		if hasUV {
+			geo.uvs = appendOrZero(geo.uvs, data.uvs, count*2)
+		}
+		if hasUV2 {
+			geo.uvs2 = appendOrZero(geo.uvs2, data.uvs2, count*2)
+		}
+		if hasColor {
+			colors := data.colors
+			if colors == nil {
+				colors = make([]float32, count*3)
+				for i := range colors {
+					colors[i] = 1
+				}
+			}
+			geo.colors = append(geo.colors, colors...)
+		}
+
+		// Mirroring turns the counter clockwise faces of glTF clockwise, the front faces of the engine
+		for _, vertex := range data.indices {
+			geo.indices = append(geo.indices, uint32(verticesStart+vertex))
+		}
+
+		geo.subMeshes = append(geo.subMeshes, &subMeshInfo{
+			material:      data.material,
+			verticesStart: verticesStart,
+			verticesCount: count,
+			indexStart:    indexStart,
+			indexCount:    len(geo.indices) - indexStart,
+		})
+	}
+
+	this._geometries[index] = geo
+	return geo, nil
+}
+
+func (this *Loader) _loadMesh(index int, mesh *meshs.Mesh) error {
+	geo, err := this._loadGeometry(index)
+	if err != nil {
+		return err
+	}
+	if len(geo.subMeshes) == 0 {
+		return nil
+	}
+
+	mesh.SetVerticesData(geo.positions, IMesh_VB_PositionKind, false)
+	mesh.SetVerticesData(geo.normals, IMesh_VB_NormalKind, false)
+	if geo.tangents != nil {
+		mesh.SetVerticesData(geo.tangents, IMesh_VB_TangentKind, false)
+	}
+	if geo.uvs != nil {
+		mesh.SetVerticesData(geo.uvs, IMesh_VB_UVKind, false)
+	}
+	if geo.uvs2 != nil {
+		mesh.SetVerticesData(geo.uvs2, IMesh_VB_UV2Kind, false)
+	}
+	if geo.colors != nil {
+		mesh.SetVerticesData(geo.colors, IMesh_VB_ColorKind, false)
+	}
+	if err := mesh.SetIndices(geo.indices); err != nil {
+		return err
+	}
+
+	if len(geo.subMeshes) == 1 {
+		mesh.Material = this._loadMaterial(geo.subMeshes[0].material)
+		return nil
+	}
+
+	multiMaterial := materials.NewMultiMaterial(mesh.Name, this._scene)
+	mesh.MutilMaterial = multiMaterial
+	mesh.SubMeshes = make([]*meshs.SubMesh, 0)
+	for subIndex, info := range geo.subMeshes {
+		multiMaterial.SubMaterials = append(multiMaterial.SubMaterials, this._loadMaterial(info.material))
+		meshs.NewSubMesh(subIndex, info.verticesStart, info.verticesCount, info.indexStart, info.indexCount, mesh)
+	}
+
+	return nil
+}
+
+// Materials
+
+// _loadMaterial converts a metallic roughness material, primitives without material share a default one
+func (this *Loader) _loadMaterial(index int) *materials.StandardMaterial {
+	if index < 0 || index >= len(this._doc.Materials) {
+		if this._defaultMaterial == nil {
+			this._defaultMaterial = materials.NewStandardMaterial(this._url+"#default", this._scene)
+			this._defaultMaterial.SpecularColor = math32.NewColor3(0, 0, 0)
+			this._model.Materials = append(this._model.Materials, this._defaultMaterial)
+		}
+		return this._defaultMaterial
+	}
+	if mat, ok := this._materials[index]; ok {
+		return mat
+	}
+
+	m := this._doc.Materials[index]
+	name := m.Name
+	if name == "" {
+		name = fmt.Sprintf("material%d", index)
+	}
+
+	mat := materials.NewStandardMaterial(name, this._scene)
+	this._materials[index] = mat
+	this._model.Materials = append(this._model.Materials, mat)
+
+	alpha := float32(1.0)
+	metallic := float32(1.0)
+	roughness := float32(1.0)
+	if pbr := m.PbrMetallicRoughness; pbr != nil {
+		if len(pbr.BaseColorFactor) == 4 {
+			mat.DiffuseColor = math32.NewColor3(pbr.BaseColorFactor[0], pbr.BaseColorFactor[1], pbr.BaseColorFactor[2])
+			alpha = pbr.BaseColorFactor[3]
+		}
+		if pbr.BaseColorTexture != nil {
+			mat.DiffuseTexture = this._loadTexture(pbr.BaseColorTexture)
+		}
+		if pbr.MetallicFactor != nil {
+			metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			roughness = *pbr.RoughnessFactor
+		}
+	}
+
+	// The highlights get sharper and take the base color as the surface gets smoother and more metallic
+	smoothness := 1 - roughness
+	specular := math32.NewColor3(1, 1, 1)
+	specular.R += (mat.DiffuseColor.R - 1) * metallic
+	specular.G += (mat.DiffuseColor.G - 1) * metallic
+	specular.B += (mat.DiffuseColor.B - 1) * metallic
+	mat.SpecularColor = specular.Scale(smoothness)
+	mat.SpecularPower = 2 + 254*smoothness*smoothness
+
+	if len(m.EmissiveFactor) == 3 {
+		mat.EmissiveColor = math32.NewColor3(m.EmissiveFactor[0], m.EmissiveFactor[1], m.EmissiveFactor[2])
+	}
+	if m.EmissiveTexture != nil {
+		mat.EmissiveTexture = this._loadTexture(m.EmissiveTexture)
+	}
+	if m.OcclusionTexture != nil {
+		mat.AmbientTexture = this._loadTexture(m.OcclusionTexture)
+	}
+
+	switch m.AlphaMode {
+	case "MASK":
+		if texture, ok := mat.DiffuseTexture.(*textures.Texture); ok && texture != nil {
+			texture.EnableHasAlpha(true)
+		}
+	case "BLEND":
+		mat.Alpha = alpha
+		if texture, ok := mat.DiffuseTexture.(*textures.Texture); ok && texture != nil {
+			texture.EnableHasAlpha(true)
+		}
+	}
+
+	mat.BackFaceCulling = !m.DoubleSided
+
+	return mat
+}
+
+func (this *Loader) _loadTexture(info *textureInfo) ITexture {
+	key := fmt.Sprintf("%d/%d", info.Index, info.TexCoord)
+	if texture, ok := this._textures[key]; ok {
+		return texture
+	}
+
+	if info.Index < 0 || info.Index >= len(this._doc.Textures) {
+		log.Printf("gltf: invalid texture %d", info.Index)
+		return nil
+	}
+	t := this._doc.Textures[info.Index]
+	if t.Source == nil || *t.Source < 0 || *t.Source >= len(this._doc.Images) {
+		log.Printf("gltf: texture %d has no supported image", info.Index)
+		return nil
+	}
+
+	imageIndex := *t.Source
+	img := this._doc.Images[imageIndex]
+
+	var texture *textures.Texture
+	if img.BufferView != nil || isDataURI(img.URI) {
+		var content []byte
+		var err error
+		if img.BufferView != nil {
+			_, content, err = this._bufferView(*img.BufferView)
+		} else {
+			content, err = decodeDataURI(img.URI)
+		}
+		if err != nil {
+			log.Printf("gltf: image %d: %s", imageIndex, err)
+			return nil
+		}
+
+		texture = textures.NewTextureFromData(fmt.Sprintf("%s#image%d", this._url, imageIndex), content, this._scene, false, 0)
+	} else {
+		texture = textures.NewTexture(this._resolve(img.URI), this._scene, false, 0)
+	}
+	if texture == nil {
+		return nil
+	}
+
+	texture.CoordinatesIndex = float32(info.TexCoord)
+
+	if t.Sampler != nil && *t.Sampler >= 0 && *t.Sampler < len(this._doc.Samplers) {
+		s := this._doc.Samplers[*t.Sampler]
+		if s.WrapS == wrapClampToEdge {
+			texture.GetGLTexture().WrapU = gl.CLAMP_ADDRESSMODE
+		}
+		if s.WrapT == wrapClampToEdge {
+			texture.GetGLTexture().WrapV = gl.CLAMP_ADDRESSMODE
+		}
+	}
+
+	this._textures[key] = texture
+	return texture
+}
+
+// Cameras and lights
+
+func (this *Loader) _loadCamera(index int, name string, world *math32.Matrix4) {
+	if index < 0 || index >= len(this._doc.Cameras) {
+		log.Printf("gltf: invalid camera %d", index)
+		return
+	}
+	c := this._doc.Cameras[index]
+	if c.Name != "" {
+		name = c.Name
+	}
+
+	// Cameras look down -Z, +Z once mirrored
+	position := math32.NewVector3(0, 0, 0).TransformCoordinates(world)
+	direction := math32.NewVector3(0, 0, 1).TransformNormal(world)
+
+	camera := cameras.NewFreeCamera(name, position, this._scene)
+	camera.SetTarget(position.Add(direction))
+
+	if c.Type == "orthographic" && c.Orthographic != nil {
+		camera.Mode = cameras.ORTHOGRAPHIC_CAMERA
+		camera.OrthoLeft = -c.Orthographic.XMag
+		camera.OrthoRight = c.Orthographic.XMag
+		camera.OrthoBottom = -c.Orthographic.YMag
+		camera.OrthoTop = c.Orthographic.YMag
+		camera.MinZ = c.Orthographic.ZNear
+		camera.MaxZ = c.Orthographic.ZFar
+	} else if c.Perspective != nil {
+		camera.Fov = c.Perspective.YFov
+		camera.MinZ = c.Perspective.ZNear
+		if c.Perspective.ZFar > 0 {
+			camera.MaxZ = c.Perspective.ZFar
+		}
+	}
+
+	this._model.Cameras = append(this._model.Cameras, camera)
+}
+
+func (this *Loader) _loadLight(index int, name string, world *math32.Matrix4) {
+	extension := this._doc.Extensions.LightsPunctual
+	if extension == nil || index < 0 || index >= len(extension.Lights) {
+		log.Printf("gltf: invalid light %d", index)
+		return
+	}
+	l := extension.Lights[index]
+	if l.Name != "" {
+		name = l.Name
+	}
+
+	color := math32.NewColor3(1, 1, 1)
+	if len(l.Color) == 3 {
+		color = math32.NewColor3(l.Color[0], l.Color[1], l.Color[2])
+	}
+	intensity := float32(1.0)
+	if l.Intensity != nil {
+		intensity = *l.Intensity
+	}
+
+	// Lights point down -Z, +Z once mirrored
+	position := math32.NewVector3(0, 0, 0).TransformCoordinates(world)
+	direction := math32.NewVector3(0, 0, 1).TransformNormal(world).NormalizeTo()
+
+	var light ILight
+	switch l.Type {
+	case "directional":
+		directional := lights.NewDirectionalLight(name, direction, this._scene)
+		directional.Diffuse = color
+		directional.Specular = color
+		directional.Intensity = intensity
+		light = directional
+	case "point":
+		point := lights.NewPointLight(name, position, this._scene)
+		point.Diffuse = color
+		point.Specular = color
+		point.Intensity = intensity
+		light = point
+	case "spot":
+		angle := float32(math.Pi / 4)
+		if l.Spot != nil && l.Spot.OuterConeAngle != nil {
+			angle = *l.Spot.OuterConeAngle
+		}
+		spot := lights.NewSpotLight(name, position, direction, angle*2, 2, this._scene)
+		spot.Diffuse = color
+		spot.Specular = color
+		spot.Intensity = intensity
+		light = spot
+	default:
+		log.Printf("gltf: light type %q is not supported", l.Type)
+		return
+	}
+
+	this._model.Lights = append(this._model.Lights, light)
+}
@@ -0,0 +1,158 @@
+package obj
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/textures"
+	"github.com/suiqirui1987/fly3d/tools"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+func parseFloats(fields []string, count int) ([]float32, bool) {
+	if len(fields) < count {
+		return nil, false
+	}
+
+	result := make([]float32, count)
+	for index := 0; index < count; index++ {
+		value, err := strconv.ParseFloat(fields[index], 32)
+		if err != nil {
+			return nil, false
+		}
+		result[index] = float32(value)
+	}
+	return result, true
+}
+
+func parseColor(fields []string) (*math32.Color3, bool) {
+	values, ok := parseFloats(fields, 3)
+	if !ok {
+		// A single value is a gray
+		values, ok = parseFloats(fields, 1)
+		if !ok {
+			return nil, false
+		}
+		return math32.NewColor3(values[0], values[0], values[0]), true
+	}
+	return math32.NewColor3(values[0], values[1], values[2]), true
+}
+
+// mapFile returns the file of a map statement, options such as -bm 1 come before it
+func mapFile(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// _loadMaterialLibrary creates the materials of a .mtl file, they are looked up by name on usemtl
+func (this *Loader) _loadMaterialLibrary(name string) {
+	url := this._resolve(name)
+	content, err := tools.OpenGeneralFile(url)
+	if err != nil {
+		log.Printf("obj: material library %s: %s", url, err)
+		return
+	}
+
+	var mat *materials.StandardMaterial
+	scanner := bufio.NewScanner(bytes.NewReader(tools.Clean(content)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		keyword := fields[0]
+		args := fields[1:]
+		if keyword == "newmtl" {
+			materialName := strings.Join(args, " ")
+			mat = materials.NewStandardMaterial(materialName, this._scene)
+			this._materials[materialName] = mat
+			this._model.Materials = append(this._model.Materials, mat)
+			continue
+		}
+		if mat == nil {
+			continue
+		}
+
+		switch keyword {
+		case "Kd":
+			if color, ok := parseColor(args); ok {
+				mat.DiffuseColor = color
+			}
+		case "Ks":
+			if color, ok := parseColor(args); ok {
+				mat.SpecularColor = color
+			}
+		case "Ka":
+			if color, ok := parseColor(args); ok {
+				mat.AmbientColor = color
+			}
+		case "Ke":
+			if color, ok := parseColor(args); ok {
+				mat.EmissiveColor = color
+			}
+		case "Ns":
+			if values, ok := parseFloats(args, 1); ok {
+				mat.SpecularPower = values[0]
+			}
+		case "d":
+			if values, ok := parseFloats(args, 1); ok {
+				mat.Alpha = values[0]
+			}
+		case "Tr":
+			if values, ok := parseFloats(args, 1); ok {
+				mat.Alpha = 1 - values[0]
+			}
+		case "map_Kd":
+			if texture := this._loadTexture(mapFile(args)); texture != nil {
+				mat.DiffuseTexture = texture
+			}
+		case "map_Ks":
+			if texture := this._loadTexture(mapFile(args)); texture != nil {
+				mat.SpecularTexture = texture
+			}
+		case "map_Ka":
+			if texture := this._loadTexture(mapFile(args)); texture != nil {
+				mat.AmbientTexture = texture
+			}
+		case "map_Ke":
+			if texture := this._loadTexture(mapFile(args)); texture != nil {
+				mat.EmissiveTexture = texture
+			}
+		case "map_d":
+			if texture := this._loadTexture(mapFile(args)); texture != nil {
+				mat.OpacityTexture = texture
+			}
+		case "map_bump", "map_Bump", "bump":
+			if texture := this._loadTexture(mapFile(args)); texture != nil {
+				mat.BumpTexture = texture
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Printf("obj: material library %s: %s", url, err)
+	}
+}
+
+func (this *Loader) _loadTexture(name string) *textures.Texture {
+	if name == "" {
+		return nil
+	}
+
+	url := this._resolve(name)
+	if texture, ok := this._textures[url]; ok {
+		return texture
+	}
+
+	texture := textures.NewTexture(url, this._scene, false, 0)
+	this._textures[url] = texture
+	return texture
+}
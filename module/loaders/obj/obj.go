@@ -0,0 +1,443 @@
+// Package obj loads Wavefront .obj files and their .mtl material libraries.
+//
+// Groups and usemtl switches become submeshes of a mesh backed by a
+// MultiMaterial. Meshes that need more than 65536 vertices use 32 bit
+// indices, or are split into several meshes when the engine only has 16 bit
+// index buffers. OBJ files are right handed, positions and normals are
+// mirrored on the Z axis like the glTF loader does.
+package obj
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+	"github.com/suiqirui1987/fly3d/module/textures"
+	"github.com/suiqirui1987/fly3d/tools"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// maxVertices16 is the number of vertices 16 bit indices can address
+const maxVertices16 = math.MaxUint16 + 1
+
+// Model holds the objects created for a file
+type Model struct {
+	Meshes    []*meshs.Mesh
+	Materials []*materials.StandardMaterial
+}
+
+type Loader struct {
+	_scene   *engines.Scene
+	_name    string
+	_rootUrl string
+
+	_materials       map[string]*materials.StandardMaterial
+	_defaultMaterial *materials.StandardMaterial
+	_textures        map[string]*textures.Texture
+
+	_model *Model
+}
+
+// corner references a position, a texture coordinate and a normal, -1 when missing
+type corner struct {
+	v  int
+	vt int
+	vn int
+}
+
+// run is a sequence of triangles sharing a group and a material
+type run struct {
+	material string
+	corners  []corner
+}
+
+type objData struct {
+	positions []float32
+	uvs       []float32
+	normals   []float32
+	runs      []*run
+}
+
+// Load reads a .obj file and adds its meshes to scene, material libraries and textures are relative to url
+func Load(url string, scene *engines.Scene) (*Model, error) {
+	content, err := tools.OpenGeneralFile(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadFromData(url, content, scene)
+}
+
+// LoadFromData adds the meshes of the .obj content to scene
+func LoadFromData(url string, content []byte, scene *engines.Scene) (*Model, error) {
+	this := &Loader{}
+	this._scene = scene
+	this._rootUrl = url[:strings.LastIndex(url, "/")+1]
+	this._name = strings.TrimSuffix(path.Base(url), path.Ext(url))
+
+	this._materials = map[string]*materials.StandardMaterial{}
+	this._textures = map[string]*textures.Texture{}
+
+	this._model = &Model{}
+
+	data, err := this._parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := this._build(data); err != nil {
+		return nil, err
+	}
+
+	return this._model, nil
+}
+
+func (this *Loader) _resolve(name string) string {
+	return this._rootUrl + strings.Replace(name, "\\", "/", -1)
+}
+
+// resolveIndex converts a one based or negative relative index
+func resolveIndex(field string, count int) (int, error) {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+		return -1, err
+	}
+	if index < 0 {
+		index = count + index
+	} else {
+		index--
+	}
+	if index < 0 || index >= count {
+		return -1, fmt.Errorf("index %s out of range", field)
+	}
+	return index, nil
+}
+
+func (this *Loader) _parse(content []byte) (*objData, error) {
+	data := &objData{}
+
+	material := ""
+	current := &run{}
+
+	// A group or a material switch starts a new run
+	next := func() {
+		if len(current.corners) > 0 {
+			data.runs = append(data.runs, current)
+		}
+		current = &run{material: material}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(tools.Clean(content)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		args := fields[1:]
+		switch fields[0] {
+		case "v":
+			values, ok := parseFloats(args, 3)
+			if !ok {
+				return nil, fmt.Errorf("obj: line %d: invalid vertex", line)
+			}
+			data.positions = append(data.positions, values...)
+		case "vt":
+			values, ok := parseFloats(args, 2)
+			if !ok {
+				values, ok = parseFloats(args, 1)
+				if !ok {
+					return nil, fmt.Errorf("obj: line %d: invalid texture coordinate", line)
+				}
+				values = append(values, 0)
+			}
+			data.uvs = append(data.uvs, values...)
+		case "vn":
+			values, ok := parseFloats(args, 3)
+			if !ok {
+				return nil, fmt.Errorf("obj: line %d: invalid normal", line)
+			}
+			data.normals = append(data.normals, values...)
+		case "f":
+			if len(args) < 3 {
+				return nil, fmt.Errorf("obj: line %d: face with less than 3 vertices", line)
+			}
+
+			corners := make([]corner, len(args))
+			for index, arg := range args {
+				c, err := data.parseCorner(arg)
+				if err != nil {
+					return nil, fmt.Errorf("obj: line %d: %s", line, err)
+				}
+				corners[index] = c
+			}
+
+			// Polygons are triangulated as fans
+			for index := 1; index+1 < len(corners); index++ {
+				current.corners = append(current.corners, corners[0], corners[index], corners[index+1])
+			}
+		case "g", "o":
+			next()
+		case "usemtl":
+			material = strings.Join(args, " ")
+			next()
+		case "mtllib":
+			for _, library := range args {
+				this._loadMaterialLibrary(library)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("obj: %s", err)
+	}
+	next()
+
+	return data, nil
+}
+
+func (this *objData) parseCorner(field string) (corner, error) {
+	c := corner{v: -1, vt: -1, vn: -1}
+	parts := strings.Split(field, "/")
+
+	var err error
+	c.v, err = resolveIndex(parts[0], len(this.positions)/3)
+	if err != nil {
+		return c, err
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		c.vt, err = resolveIndex(parts[1], len(this.uvs)/2)
+		if err != nil {
+			return c, err
+		}
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		c.vn, err = resolveIndex(parts[2], len(this.normals)/3)
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// computeNormals averages the normals of the faces sharing each position
+func (this *objData) computeNormals() []float32 {
+	normals := make([]float32, len(this.positions))
+
+	for _, r := range this.runs {
+		for face := 0; face+2 < len(r.corners); face += 3 {
+			a := math32.NewVector3Zero().FromArray(this.positions, r.corners[face].v*3)
+			b := math32.NewVector3Zero().FromArray(this.positions, r.corners[face+1].v*3)
+			c := math32.NewVector3Zero().FromArray(this.positions, r.corners[face+2].v*3)
+
+			normal := b.Sub(a).Cross(c.Sub(a))
+			for i := 0; i < 3; i++ {
+				vertex := r.corners[face+i].v * 3
+				normals[vertex] += normal.X
+				normals[vertex+1] += normal.Y
+				normals[vertex+2] += normal.Z
+			}
+		}
+	}
+
+	for vertex := 0; vertex < len(normals); vertex += 3 {
+		length := math32.NewVector3Zero().FromArray(normals, vertex).Length()
+		if length > 0 {
+			normals[vertex] /= length
+			normals[vertex+1] /= length
+			normals[vertex+2] /= length
+		}
+	}
+
+	return normals
+}
+
+// Meshes
+
+type subMeshInfo struct {
+	materialIndex int
+	minVertex     int
+	maxVertex     int
+	indexStart    int
+	indexCount    int
+}
+
+type chunk struct {
+	positions []float32
+	normals   []float32
+	uvs       []float32
+	indices   []uint32
+
+	vertices  map[corner]int
+	subMeshes []*subMeshInfo
+	materials []*materials.StandardMaterial
+}
+
+func newChunk() *chunk {
+	this := &chunk{}
+	this.vertices = map[corner]int{}
+	return this
+}
+
+func (this *chunk) materialIndex(mat *materials.StandardMaterial) int {
+	for index, m := range this.materials {
+		if m == mat {
+			return index
+		}
+	}
+	this.materials = append(this.materials, mat)
+	return len(this.materials) - 1
+}
+
+func (this *Loader) _material(name string) *materials.StandardMaterial {
+	if mat, ok := this._materials[name]; ok {
+		return mat
+	}
+	if name != "" {
+		log.Printf("obj: material %s not found", name)
+	}
+
+	if this._defaultMaterial == nil {
+		this._defaultMaterial = materials.NewStandardMaterial(this._name+"#default", this._scene)
+		this._model.Materials = append(this._model.Materials, this._defaultMaterial)
+	}
+	return this._defaultMaterial
+}
+
+func (this *Loader) _build(data *objData) error {
+	hasUV := len(data.uvs) > 0
+
+	var generated []float32
+	for _, r := range data.runs {
+		for _, c := range r.corners {
+			if c.vn == -1 && generated == nil {
+				generated = data.computeNormals()
+			}
+		}
+	}
+
+	maxVertices := math.MaxInt32
+	if !this._scene.GetEngine().GetCaps().Uint32Indices {
+		maxVertices = maxVertices16
+	}
+
+	current := newChunk()
+	for _, r := range data.runs {
+		mat := this._material(r.material)
+		var sub *subMeshInfo
+
+		for face := 0; face+2 < len(r.corners); face += 3 {
+			corners := r.corners[face : face+3]
+
+			// Start a new mesh when the face does not fit
+			added := 0
+			for _, c := range corners {
+				if _, ok := current.vertices[c]; !ok {
+					added++
+				}
+			}
+			if len(current.positions)/3+added > maxVertices {
+				if err := this._flush(current, hasUV); err != nil {
+					return err
+				}
+				current = newChunk()
+				sub = nil
+			}
+
+			if sub == nil {
+				sub = &subMeshInfo{
+					materialIndex: current.materialIndex(mat),
+					minVertex:     math.MaxInt32,
+					maxVertex:     -1,
+					indexStart:    len(current.indices),
+				}
+				current.subMeshes = append(current.subMeshes, sub)
+			}
+
+			for _, c := range corners {
+				vertex, ok := current.vertices[c]
+				if !ok {
+					vertex = len(current.positions) / 3
+					current.vertices[c] = vertex
+
+					// Mirror on Z, texture coordinates start at the bottom of the image
+					current.positions = append(current.positions, data.positions[c.v*3], data.positions[c.v*3+1], -data.positions[c.v*3+2])
+					if c.vn != -1 {
+						current.normals = append(current.normals, data.normals[c.vn*3], data.normals[c.vn*3+1], -data.normals[c.vn*3+2])
+					} else {
+						current.normals = append(current.normals, generated[c.v*3], generated[c.v*3+1], -generated[c.v*3+2])
+					}
+					if hasUV {
+						if c.vt != -1 {
+							current.uvs = append(current.uvs, data.uvs[c.vt*2], 1-data.uvs[c.vt*2+1])
+						} else {
+							current.uvs = append(current.uvs, 0, 0)
+						}
+					}
+				}
+
+				current.indices = append(current.indices, uint32(vertex))
+				sub.indexCount++
+				if vertex < sub.minVertex {
+					sub.minVertex = vertex
+				}
+				if vertex > sub.maxVertex {
+					sub.maxVertex = vertex
+				}
+			}
+		}
+	}
+
+	return this._flush(current, hasUV)
+}
+
+func (this *Loader) _flush(current *chunk, hasUV bool) error {
+	if len(current.indices) == 0 {
+		return nil
+	}
+
+	name := this._name
+	if len(this._model.Meshes) > 0 {
+		name = fmt.Sprintf("%s_%d", this._name, len(this._model.Meshes))
+	}
+
+	mesh := meshs.NewMesh(name, this._scene)
+	mesh.SetVerticesData(current.positions, IMesh_VB_PositionKind, false)
+	mesh.SetVerticesData(current.normals, IMesh_VB_NormalKind, false)
+	if hasUV {
+		mesh.SetVerticesData(current.uvs, IMesh_VB_UVKind, false)
+	}
+	if err := mesh.SetIndices(current.indices); err != nil {
+		mesh.Dispose()
+		return err
+	}
+
+	mesh.SubMeshes = make([]*meshs.SubMesh, 0)
+	for _, sub := range current.subMeshes {
+		meshs.NewSubMesh(sub.materialIndex, sub.minVertex, sub.maxVertex-sub.minVertex+1, sub.indexStart, sub.indexCount, mesh)
+	}
+
+	if len(current.materials) == 1 {
+		mesh.Material = current.materials[0]
+	} else {
+		multiMaterial := materials.NewMultiMaterial(name, this._scene)
+		for _, mat := range current.materials {
+			multiMaterial.SubMaterials = append(multiMaterial.SubMaterials, mat)
+		}
+		mesh.MutilMaterial = multiMaterial
+	}
+
+	this._model.Meshes = append(this._model.Meshes, mesh)
+	return nil
+}
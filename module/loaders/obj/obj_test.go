@@ -0,0 +1,240 @@
+// +build glnull glsoft
+
+package obj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/gl"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/materials"
+)
+
+func TestLoadFromData(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		positions []float32
+		normals   []float32
+		uvs       []float32
+		indices   []uint32
+		subMeshes int
+		err       string
+	}{
+		{
+			name:      "triangle",
+			content:   "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n",
+			positions: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+			normals:   []float32{0, 0, -1, 0, 0, -1, 0, 0, -1},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:      "quad fan",
+			content:   "v 0 0 1\nv 1 0 1\nv 1 1 1\nv 0 1 1\nf 1 2 3 4\n",
+			positions: []float32{0, 0, -1, 1, 0, -1, 1, 1, -1, 0, 1, -1},
+			indices:   []uint32{0, 1, 2, 0, 2, 3},
+			subMeshes: 1,
+		},
+		{
+			name:      "relative indices",
+			content:   "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\n",
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:      "texture coordinates and normals",
+			content:   "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nvt 1 0.25\nvn 0 0 1\nf 1/1/1 2/2/1 3//1\n",
+			normals:   []float32{0, 0, -1, 0, 0, -1, 0, 0, -1},
+			uvs:       []float32{0, 1, 1, 0.75, 0, 0},
+			indices:   []uint32{0, 1, 2},
+			subMeshes: 1,
+		},
+		{
+			name:      "shared corners",
+			content:   "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nf 1 2 3\nf 3 2 4\n",
+			indices:   []uint32{0, 1, 2, 2, 1, 3},
+			subMeshes: 1,
+		},
+		{
+			name:      "groups",
+			content:   "v 0 0 0\nv 1 0 0\nv 0 1 0\ng a\nf 1 2 3\ng b\nf 3 2 1\n",
+			indices:   []uint32{0, 1, 2, 2, 1, 0},
+			subMeshes: 2,
+		},
+		{name: "index out of range", content: "v 0 0 0\nf 1 2 3\n", err: "line 2: index 2 out of range"},
+		{name: "invalid vertex", content: "v 0 x 0\n", err: "line 1: invalid vertex"},
+		{name: "degenerate face", content: "v 0 0 0\nv 1 0 0\nf 1 2\n", err: "face with less than 3 vertices"},
+	}
+
+	for _, test := range tests {
+		scene := enginetest.NewScene(t)
+		model, err := LoadFromData("testdata/"+test.name+".obj", []byte(test.content), scene)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if len(model.Meshes) != 1 {
+			t.Errorf("%s: %d meshes, want 1", test.name, len(model.Meshes))
+			continue
+		}
+
+		mesh := model.Meshes[0]
+		if mesh.GetName() != test.name {
+			t.Errorf("%s: mesh named %s", test.name, mesh.GetName())
+		}
+		if test.positions != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_PositionKind), test.positions) {
+			t.Errorf("%s: positions %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_PositionKind), test.positions)
+		}
+		if test.normals != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_NormalKind), test.normals) {
+			t.Errorf("%s: normals %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_NormalKind), test.normals)
+		}
+		if test.uvs != nil && !nearSlice(mesh.GetVerticesData(IMesh_VB_UVKind), test.uvs) {
+			t.Errorf("%s: uvs %v, want %v", test.name, mesh.GetVerticesData(IMesh_VB_UVKind), test.uvs)
+		}
+		if test.uvs == nil && mesh.IsVerticesDataPresent(IMesh_VB_UVKind) {
+			t.Errorf("%s: unexpected uvs", test.name)
+		}
+		if !reflect.DeepEqual(mesh.GetIndices(), test.indices) {
+			t.Errorf("%s: indices %v, want %v", test.name, mesh.GetIndices(), test.indices)
+		}
+		if len(mesh.SubMeshes) != test.subMeshes {
+			t.Errorf("%s: %d submeshes, want %d", test.name, len(mesh.SubMeshes), test.subMeshes)
+		}
+	}
+}
+
+func TestLoadMaterials(t *testing.T) {
+	content := "mtllib materials.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\nusemtl red\nf 1 2 3\nusemtl glass\nf 3 2 1\nusemtl missing\nf 1 3 2\n"
+
+	scene := enginetest.NewScene(t)
+	model, err := LoadFromData("testdata/materials.obj", []byte(content), scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// red, glass and the default material for the missing one
+	if len(model.Materials) != 3 {
+		t.Fatalf("%d materials, want 3", len(model.Materials))
+	}
+
+	mesh := model.Meshes[0]
+	if _, ok := mesh.MutilMaterial.(*materials.MultiMaterial); !ok {
+		t.Fatalf("mesh material %T, want a MultiMaterial", mesh.MutilMaterial)
+	}
+
+	tests := []struct {
+		name    string
+		diffuse *math32.Color3
+		alpha   float32
+	}{
+		{"red", math32.NewColor3(1, 0, 0), 1},
+		{"glass", math32.NewColor3(0, 0, 1), 0.5},
+		{"materials#default", nil, 1},
+	}
+
+	for index, test := range tests {
+		mat, ok := mesh.SubMeshes[index].GetMaterial().(*materials.StandardMaterial)
+		if !ok || mat.Name != test.name {
+			t.Errorf("submesh %d: material %v, want %s", index, mat, test.name)
+			continue
+		}
+		if test.diffuse != nil && !mat.DiffuseColor.Equals(test.diffuse) {
+			t.Errorf("%s: diffuse %v, want %v", test.name, mat.DiffuseColor, test.diffuse)
+		}
+		if mat.Alpha != test.alpha {
+			t.Errorf("%s: alpha %v, want %v", test.name, mat.Alpha, test.alpha)
+		}
+	}
+}
+
+func TestSplit16BitsIndices(t *testing.T) {
+	// One triangle of its own vertices per face, past what 16 bits indices address
+	faces := maxVertices16/3 + 10
+
+	var content strings.Builder
+	for face := 0; face < faces; face++ {
+		fmt.Fprintf(&content, "v %d 0 0\nv %d 1 0\nv %d 0 1\nf -3 -2 -1\n", face, face, face)
+	}
+
+	tests := []struct {
+		name     string
+		uint32   bool
+		meshes   []string
+		vertices []int
+	}{
+		{"big", true, []string{"big"}, []int{faces * 3}},
+		{"split", false, []string{"split", "split_1"}, []int{maxVertices16 - maxVertices16%3, faces*3 - (maxVertices16 - maxVertices16%3)}},
+	}
+
+	for _, test := range tests {
+		gl.SetElementIndexUintSupported(test.uint32)
+		scene := enginetest.NewScene(t)
+		model, err := LoadFromData(test.name+".obj", []byte(content.String()), scene)
+		gl.SetElementIndexUintSupported(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(model.Meshes) != len(test.meshes) {
+			t.Errorf("%s: %d meshes, want %d", test.name, len(model.Meshes), len(test.meshes))
+			continue
+		}
+		for index, mesh := range model.Meshes {
+			if mesh.GetName() != test.meshes[index] || mesh.GetTotalVertices() != test.vertices[index] {
+				t.Errorf("%s: mesh %s of %d vertices, want %s of %d", test.name, mesh.GetName(), mesh.GetTotalVertices(), test.meshes[index], test.vertices[index])
+			}
+			if mesh.Is32BitsIndices() != (test.uint32 && mesh.GetTotalVertices() > maxVertices16) {
+				t.Errorf("%s: mesh %s uses 32 bits indices %v", test.name, mesh.GetName(), mesh.Is32BitsIndices())
+			}
+		}
+	}
+}
+
+func TestResolveIndex(t *testing.T) {
+	tests := []struct {
+		field string
+		count int
+		index int
+		err   bool
+	}{
+		{"1", 3, 0, false},
+		{"3", 3, 2, false},
+		{"-1", 3, 2, false},
+		{"-3", 3, 0, false},
+		{"0", 3, -1, true},
+		{"4", 3, -1, true},
+		{"-4", 3, -1, true},
+		{"x", 3, -1, true},
+	}
+
+	for _, test := range tests {
+		index, err := resolveIndex(test.field, test.count)
+		if index != test.index || (err != nil) != test.err {
+			t.Errorf("resolveIndex(%q, %d) = %d, %v, want %d", test.field, test.count, index, err, test.index)
+		}
+	}
+}
+
+func nearSlice(values []float32, want []float32) bool {
+	if len(values) != len(want) {
+		return false
+	}
+	for index := range values {
+		if math32.Abs(values[index]-want[index]) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
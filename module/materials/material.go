@@ -4,6 +4,7 @@ import (
 	"github.com/suiqirui1987/fly3d/engines"
 	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/effects"
 	"github.com/suiqirui1987/fly3d/tools"
 )
 
@@ -16,6 +17,8 @@ type Material struct {
 	BackFaceCulling bool
 	_effect         IEffect
 	OnDispose       func()
+	// OnError gets the annotated sources of the effects which do not compile
+	OnError func(effect IEffect, errors string)
 }
 
 func NewMaterial(name string, scene *engines.Scene) *Material {
@@ -48,8 +51,15 @@ func (this *Material) BaseDispose() {
 	}
 }
 
+// _onEffectError hands the compile errors of an effect to OnError
+func (this *Material) _onEffectError(effect *effects.Effect, errors string) {
+	if this.OnError != nil {
+		this.OnError(effect, errors)
+	}
+}
+
 /** interface IMaterial*/
-func (this *Material) IsReady(IMesh) bool {
+func (this *Material) IsReady(IMesh, bool) bool {
 	return true
 }
 func (this *Material) GetId() string {
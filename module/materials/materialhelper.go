@@ -0,0 +1,181 @@
+package materials
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/effects"
+	"github.com/suiqirui1987/fly3d/module/lights"
+)
+
+// defaultMaxSimultaneousLights is the number of lights a mesh gets from a
+// lighting material unless told otherwise
+const defaultMaxSimultaneousLights = 4
+
+// lightsUniforms returns the uniforms and the samplers of maxLights lights in
+// the lighting shaders
+func lightsUniforms(maxLights int) ([]string, []string) {
+	uniforms := make([]string, 0, 6*maxLights)
+	samplers := make([]string, 0, maxLights)
+	for index := 0; index < maxLights; index++ {
+		suffix := strconv.Itoa(index)
+		uniforms = append(uniforms, "vLightData"+suffix, "vLightDiffuse"+suffix, "vLightSpecular"+suffix, "vLightDirection"+suffix, "vLightGround"+suffix, "lightMatrix"+suffix)
+		samplers = append(samplers, "shadowSampler"+suffix)
+	}
+	return uniforms, samplers
+}
+
+// selectLights returns the lights of the mesh: the maxLights most influential
+// of the enabled lights of the scene which can affect it, in scene order for
+// equal influences. Lights without influence, out of range, are left out
+func selectLights(scene *engines.Scene, mesh IMesh, maxLights int) []ILight {
+	selected := make([]ILight, 0, len(scene.Lights))
+	influences := map[ILight]float32{}
+	for _, light := range scene.Lights {
+		if !light.IsEnabled() || !light.CanAffectMesh(mesh) {
+			continue
+		}
+		influence := light.GetInfluence(mesh)
+		if influence <= 0 {
+			continue
+		}
+		selected = append(selected, light)
+		influences[light] = influence
+	}
+
+	if len(selected) > maxLights {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return influences[selected[i]] > influences[selected[j]]
+		})
+		selected = selected[:maxLights]
+	}
+
+	return selected
+}
+
+// lightsCache keeps the lights selected for the meshes of a material during
+// a frame, IsReady and Bind then agree and only select them once. A mesh
+// moved during the frame, by CaptureScreenshot for instance, selects again
+type lightsCache struct {
+	_renderId  int
+	_maxLights int
+	_lights    map[IMesh]*lightsCacheEntry
+}
+
+type lightsCacheEntry struct {
+	world  math32.Matrix4
+	lights []ILight
+}
+
+func (this *lightsCache) selectLights(scene *engines.Scene, mesh IMesh, maxLights int) []ILight {
+	if this._lights == nil || this._renderId != scene.GetRenderId() || this._maxLights != maxLights {
+		this._renderId = scene.GetRenderId()
+		this._maxLights = maxLights
+		this._lights = map[IMesh]*lightsCacheEntry{}
+	}
+
+	var world math32.Matrix4
+	if matrix := mesh.GetWorldMatrix(); matrix != nil {
+		world = *matrix
+	}
+
+	entry, ok := this._lights[mesh]
+	if !ok || entry.world != world {
+		entry = &lightsCacheEntry{world: world, lights: selectLights(scene, mesh, maxLights)}
+		this._lights[mesh] = entry
+	}
+
+	return entry.lights
+}
+
+// prepareLightsDefines appends the defines of the selected lights of the
+// mesh and of the shadows they cast on it
+func prepareLightsDefines(selected []ILight, mesh IMesh, maxLights int, defines []string) []string {
+	defines = append(defines, "#define maxSimultaneousLights "+strconv.Itoa(maxLights))
+
+	shadowsActivated := false
+	for lightIndex, light := range selected {
+		lightIndex_str := strconv.Itoa(lightIndex)
+
+		defines = append(defines, "#define LIGHT"+lightIndex_str)
+
+		if _, ok := light.(*lights.SpotLight); ok {
+			defines = append(defines, "#define SPOTLIGHT"+lightIndex_str)
+		} else if _, ok := light.(*lights.HemisphericLight); ok {
+			defines = append(defines, "#define HEMILIGHT"+lightIndex_str)
+		} else {
+			defines = append(defines, "#define POINTDIRLIGHT"+lightIndex_str)
+		}
+
+		// Shadows
+		shadowGenerator := light.GetShadowGenerator()
+		if mesh != nil && mesh.IsReceiveShadows() == true && shadowGenerator != nil && shadowGenerator.IsReady() {
+			defines = append(defines, "#define SHADOW"+lightIndex_str)
+
+			if !shadowsActivated {
+				defines = append(defines, "#define SHADOWS")
+				shadowsActivated = true
+			}
+
+			if shadowGenerator.IsUseVarianceShadowMap() {
+				defines = append(defines, "#define SHADOWVSM"+lightIndex_str)
+			}
+		}
+	}
+
+	return defines
+}
+
+// addLightsFallbacks lets an effect drop the variance shadow maps at rank 0,
+// the shadows at rank 1, then the lights but the first, least influential
+// first
+func addLightsFallbacks(fallbacks *effects.EffectFallbacks, maxLights int) {
+	for index := 0; index < maxLights; index++ {
+		suffix := strconv.Itoa(index)
+		fallbacks.AddFallback(0, "SHADOWVSM"+suffix)
+		fallbacks.AddFallback(1, "SHADOW"+suffix)
+		if index > 0 {
+			fallbacks.AddFallback(1+maxLights-index, "LIGHT"+suffix)
+		}
+	}
+}
+
+// bindLights sets the uniforms and the shadow maps of the lights defined by
+// prepareLightsDefines
+func bindLights(selected []ILight, mesh IMesh, effect IEffect) {
+	for lightIndex, light := range selected {
+		lightIndex_str := strconv.Itoa(lightIndex)
+
+		if polight, ok := light.(*lights.PointLight); ok {
+			// Point Light
+			effect.SetFloat4("vLightData"+lightIndex_str, polight.Position.X, polight.Position.Y, polight.Position.Z, 0)
+		} else if dlight, ok := light.(*lights.DirectionalLight); ok {
+			// Directional Light
+			effect.SetFloat4("vLightData"+lightIndex_str, dlight.Direction.X, dlight.Direction.Y, dlight.Direction.Z, 1)
+		} else if slight, ok := light.(*lights.SpotLight); ok {
+			// Spot Light
+			effect.SetFloat4("vLightData"+lightIndex_str, slight.Position.X, slight.Position.Y, slight.Position.Z, slight.Exponent)
+			normalizeDirection := slight.Direction.NormalizeTo()
+			effect.SetFloat4("vLightDirection"+lightIndex_str, normalizeDirection.X, normalizeDirection.Y, normalizeDirection.Z, math32.Cos(slight.Angle*0.5))
+		} else if hlight, ok := light.(*lights.HemisphericLight); ok {
+			// Hemispheric Light
+			normalizeDirection := hlight.Direction.NormalizeTo()
+			effect.SetFloat4("vLightData"+lightIndex_str, normalizeDirection.X, normalizeDirection.Y, normalizeDirection.Z, 0)
+			effect.SetColor3("vLightGround"+lightIndex_str, hlight.GroundColor.Scale(hlight.Intensity))
+		}
+
+		// The range rides along with the diffuse color
+		effect.SetColor4("vLightDiffuse"+lightIndex_str, light.GetDiffuse().Scale(light.GetIntensity()), light.GetRange())
+		effect.SetColor3("vLightSpecular"+lightIndex_str, light.GetSpecular().Scale(light.GetIntensity()))
+
+		// Shadows
+		shadowGenerator := light.GetShadowGenerator()
+		if mesh.IsReceiveShadows() && shadowGenerator != nil && shadowGenerator.IsReady() {
+			effect.SetMatrix("lightMatrix"+lightIndex_str, shadowGenerator.GetTransformMatrix())
+			effect.SetTexture("shadowSampler"+lightIndex_str, shadowGenerator.GetShadowMap().GetGLTexture())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// +build glnull glsoft
+
+package materials
+
+import (
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/lights"
+)
+
+// testMesh is a mesh reduced to its world matrix, the meshs package imports
+// this one
+type testMesh struct {
+	IMesh
+	world *math32.Matrix4
+}
+
+func (this *testMesh) GetWorldMatrix() *math32.Matrix4 {
+	return this.world
+}
+
+func TestLightsCacheFollowsMovedMeshes(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	lamp := lights.NewPointLight("lamp", math32.NewVector3(0, 0, 0), scene)
+	lamp.Range = 5
+	mesh := &testMesh{world: math32.NewMatrix4().Translation(1, 0, 0)}
+
+	cache := lightsCache{}
+	if selected := cache.selectLights(scene, mesh, 4); len(selected) != 1 {
+		t.Fatalf("mesh in range of the lamp has %d lights", len(selected))
+	}
+
+	// Moved within the same frame, as CaptureScreenshot does
+	mesh.world.TranslationToRef(20, 0, 0, mesh.world)
+	if selected := cache.selectLights(scene, mesh, 4); len(selected) != 0 {
+		t.Errorf("mesh out of range of the lamp kept %d lights", len(selected))
+	}
+
+	// The selection is kept while the mesh stays
+	scene.Lights = scene.Lights[:0]
+	mesh.world.TranslationToRef(1, 0, 0, mesh.world)
+	first := cache.selectLights(scene, mesh, 4)
+	scene.Lights = append(scene.Lights, lamp)
+	if selected := cache.selectLights(scene, mesh, 4); len(selected) != len(first) {
+		t.Errorf("selection changed from %d to %d lights within the frame", len(first), len(selected))
+	}
+}
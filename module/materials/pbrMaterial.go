@@ -0,0 +1,372 @@
+package materials
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/core"
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/effects"
+)
+
+// PBRMaterial shades meshes with the metallic-roughness model: a GGX
+// specular lobe with Smith visibility and Schlick fresnel over a lambertian
+// diffuse.
+// MetallicRoughnessTexture holds the roughness in its green channel and the
+// metallic in its blue channel, both multiply Metallic and Roughness.
+// AmbientOcclusionTexture holds the occlusion in its red channel.
+// ReflectionTexture is a cube texture whose mip levels hold the radiance of
+// the environment prefiltered for increasing roughness, the last level is
+// used as irradiance. BRDFTexture is the split sum lookup of the environment
+// BRDF, scale in red and bias in green, indexed by NdotV and roughness. An
+// analytical fit is used without it
+type PBRMaterial struct {
+	Material
+
+	AlbedoTexture            ITexture
+	MetallicRoughnessTexture ITexture
+	AmbientOcclusionTexture  ITexture
+	EmissiveTexture          ITexture
+	BumpTexture              ITexture
+	ReflectionTexture        ITexture
+	BRDFTexture              ITexture
+
+	AlbedoColor   *math32.Color3
+	Metallic      float32
+	Roughness     float32
+	EmissiveColor *math32.Color3
+	AmbientColor  *math32.Color3
+
+	// MaxSimultaneousLights is the number of lights of a mesh, its most
+	// influential ones
+	MaxSimultaneousLights int
+
+	_lightsCache   lightsCache
+	_cachedDefines string
+	_useInstances  bool
+	_useBones      bool
+	_useBump       bool
+	_useReflection bool
+
+	//Internals
+	_worldViewProjectionMatrix *math32.Matrix4
+	_globalAmbientColor        *math32.Color3
+}
+
+func NewPBRMaterial(name string, scene *engines.Scene) *PBRMaterial {
+	this := &PBRMaterial{}
+	this.Name = name
+	this.Id = name
+	this._scene = scene
+	this._scene.Materials = append(this._scene.Materials, this)
+
+	this.Init()
+	return this
+}
+
+func (this *PBRMaterial) Init() {
+	this.Material.Init()
+
+	this.AlbedoColor = math32.NewColor3(1, 1, 1)
+	this.Metallic = 1
+	this.Roughness = 1
+	this.EmissiveColor = math32.NewColor3(0, 0, 0)
+	this.AmbientColor = math32.NewColor3(0, 0, 0)
+
+	this.MaxSimultaneousLights = defaultMaxSimultaneousLights
+
+	this._cachedDefines = ""
+
+	this._worldViewProjectionMatrix = math32.NewMatrix4().Zero()
+	this._globalAmbientColor = math32.NewColor3(0, 0, 0)
+}
+
+func (this *PBRMaterial) NeedAlphaBlending() bool {
+	return this.Alpha < 1.0
+}
+
+func (this *PBRMaterial) NeedAlphaTesting() bool {
+	return this.AlbedoTexture != nil && this.AlbedoTexture.HasAlpha()
+}
+
+func (this *PBRMaterial) IsReady(mesh IMesh, useInstances bool) bool {
+	engine := this._scene.GetEngine()
+
+	// Effect
+	defines := make([]string, 0)
+
+	// Textures
+	textures := []struct {
+		texture ITexture
+		define  string
+	}{
+		{this.AlbedoTexture, "ALBEDO"},
+		{this.MetallicRoughnessTexture, "METALLICROUGHNESS"},
+		{this.AmbientOcclusionTexture, "AO"},
+		{this.EmissiveTexture, "EMISSIVE"},
+		{this.BRDFTexture, "BRDF"},
+	}
+	for _, slot := range textures {
+		if slot.texture == nil {
+			continue
+		}
+		if !slot.texture.IsReady() {
+			return false
+		}
+		defines = append(defines, "#define "+slot.define)
+	}
+
+	// Only cube textures hold an environment
+	this._useReflection = false
+	if this.ReflectionTexture != nil && this.ReflectionTexture.GetGLTexture() != nil && this.ReflectionTexture.GetGLTexture().IsCube {
+		if !this.ReflectionTexture.IsReady() {
+			return false
+		}
+		this._useReflection = true
+		defines = append(defines, "#define REFLECTION")
+
+		// The roughness picks the mip level, the bias of textureCube is a
+		// coarser fallback
+		if engine.GetCaps().TextureLOD {
+			defines = append(defines, "#define TEXTURELOD")
+		}
+	}
+
+	// Bump, the tangent space comes from the tangents of the mesh or else
+	// from the screen space derivatives
+	this._useBump = false
+	if this.BumpTexture != nil && (engine.GetCaps().StandardDerivatives || (mesh != nil && mesh.IsVerticesDataPresent(IMesh_VB_TangentKind))) {
+		if !this.BumpTexture.IsReady() {
+			return false
+		}
+		this._useBump = true
+		defines = append(defines, "#define BUMP")
+	}
+
+	if core.GlobalFly3D.ClipPlane != nil {
+		defines = append(defines, "#define CLIPPLANE")
+	}
+
+	if engine.GetAlphaTesting() {
+		defines = append(defines, "#define ALPHATEST")
+	}
+
+	// Fog
+	if this._scene.FogMode != core.FOGMODE_NONE {
+		defines = append(defines, "#define FOG")
+	}
+
+	defines = prepareLightsDefines(this._lightsCache.selectLights(this._scene, mesh, this.MaxSimultaneousLights), mesh, this.MaxSimultaneousLights, defines)
+
+	attribs := []string{"position", "normal"}
+	if mesh != nil {
+		if mesh.IsVerticesDataPresent(IMesh_VB_UVKind) {
+			attribs = append(attribs, "uv")
+			defines = append(defines, "#define UV1")
+		}
+		if mesh.IsVerticesDataPresent(IMesh_VB_UV2Kind) {
+			attribs = append(attribs, "uv2")
+			defines = append(defines, "#define UV2")
+		}
+		if mesh.IsVerticesDataPresent(IMesh_VB_ColorKind) {
+			attribs = append(attribs, "color")
+			defines = append(defines, "#define VERTEXCOLOR")
+		}
+		if this._useBump && mesh.IsVerticesDataPresent(IMesh_VB_TangentKind) {
+			attribs = append(attribs, "tangent")
+			defines = append(defines, "#define TANGENT")
+		}
+	}
+
+	// Bones
+	this._useBones = false
+	if mesh != nil && mesh.GetSkeleton() != nil && mesh.IsVerticesDataPresent(IMesh_VB_MatricesIndicesKind) && mesh.IsVerticesDataPresent(IMesh_VB_MatricesWeightsKind) {
+		this._useBones = true
+		attribs = append(attribs, "matricesIndices", "matricesWeights")
+		defines = append(defines, "#define BONES")
+		defines = append(defines, "#define BonesPerMesh "+strconv.Itoa(mesh.GetSkeleton().GetBonesCount()))
+	}
+
+	// Morph targets
+	if mesh != nil {
+		if influences := mesh.GetMorphTargetInfluences(); len(influences) > 0 {
+			defines = append(defines, "#define MORPHTARGETS")
+			defines = append(defines, "#define NUM_MORPH_INFLUENCERS "+strconv.Itoa(len(influences)))
+			normals := mesh.IsMorphTargetNormalsPresent()
+			if normals {
+				defines = append(defines, "#define MORPHTARGETS_NORMAL")
+			}
+			for index := range influences {
+				defines = append(defines, "#define MORPHTARGET"+strconv.Itoa(index))
+				attribs = append(attribs, "position"+strconv.Itoa(index))
+				if normals {
+					attribs = append(attribs, "normal"+strconv.Itoa(index))
+				}
+			}
+		}
+	}
+
+	// Instances
+	this._useInstances = useInstances
+	if useInstances {
+		defines = append(defines, "#define INSTANCES")
+		attribs = append(attribs, "world0", "world1", "world2", "world3")
+	}
+
+	// Get correct effect
+	join := strings.Join(defines, "\n")
+	if this._effect == nil || this._cachedDefines != join {
+		this._cachedDefines = join
+
+		// Optional features the effect drops if it does not compile
+		fallbacks := effects.NewEffectFallbacks()
+		fallbacks.AddFallback(0, "BRDF")
+		fallbacks.AddFallback(0, "BUMP")
+		fallbacks.AddFallback(0, "TANGENT")
+		fallbacks.AddFallback(1, "FOG")
+		fallbacks.AddFallback(1, "REFLECTION")
+		addLightsFallbacks(fallbacks, this.MaxSimultaneousLights)
+
+		lightUniforms, lightSamplers := lightsUniforms(this.MaxSimultaneousLights)
+		this._effect = effects.CreateEffect(
+			engine,
+			"pbr",
+			attribs,
+			append([]string{"world", "view", "viewProjection", "worldViewProjection", "mBones", "morphTargetInfluences", "vEyePosition", "vAmbientColor", "vAlbedoColor", "vMetallicRoughness", "vEmissiveColor",
+				"vFogInfos", "vFogColor",
+				"vAlbedoInfos", "vMetallicRoughnessInfos", "vAOInfos", "vEmissiveInfos", "vBumpInfos", "vReflectionInfos",
+				"vClipPlane", "albedoMatrix", "metallicRoughnessMatrix", "aoMatrix", "emissiveMatrix", "bumpMatrix", "reflectionMatrix",
+			}, lightUniforms...),
+			append([]string{"albedoSampler", "metallicRoughnessSampler", "aoSampler", "emissiveSampler", "bumpSampler", "reflectionCubeSampler", "brdfSampler"}, lightSamplers...),
+			join, fallbacks, this._onEffectError)
+	}
+	if !this._effect.IsReady() {
+		return false
+	}
+
+	return true
+}
+
+func (this *PBRMaterial) GetRenderTargetTextures() []ITexture {
+	results := make([]ITexture, 0)
+
+	if this.ReflectionTexture != nil && this.ReflectionTexture.IsRenderTarget() {
+		results = append(results, this.ReflectionTexture)
+	}
+
+	return results
+}
+
+func (this *PBRMaterial) Bind(world *math32.Matrix4, mesh IMesh) {
+	// Values
+	if this.AlbedoTexture != nil {
+		this._effect.SetTexture("albedoSampler", this.AlbedoTexture.GetGLTexture())
+
+		this._effect.SetVector2("vAlbedoInfos", this.AlbedoTexture.GetCoordinatesIndex(), this.AlbedoTexture.GetLevel())
+		this._effect.SetMatrix("albedoMatrix", this.AlbedoTexture.ComputeTextureMatrix())
+	}
+
+	if this.MetallicRoughnessTexture != nil {
+		this._effect.SetTexture("metallicRoughnessSampler", this.MetallicRoughnessTexture.GetGLTexture())
+
+		this._effect.SetVector2("vMetallicRoughnessInfos", this.MetallicRoughnessTexture.GetCoordinatesIndex(), this.MetallicRoughnessTexture.GetLevel())
+		this._effect.SetMatrix("metallicRoughnessMatrix", this.MetallicRoughnessTexture.ComputeTextureMatrix())
+	}
+
+	if this.AmbientOcclusionTexture != nil {
+		this._effect.SetTexture("aoSampler", this.AmbientOcclusionTexture.GetGLTexture())
+
+		this._effect.SetVector2("vAOInfos", this.AmbientOcclusionTexture.GetCoordinatesIndex(), this.AmbientOcclusionTexture.GetLevel())
+		this._effect.SetMatrix("aoMatrix", this.AmbientOcclusionTexture.ComputeTextureMatrix())
+	}
+
+	if this.EmissiveTexture != nil {
+		this._effect.SetTexture("emissiveSampler", this.EmissiveTexture.GetGLTexture())
+
+		this._effect.SetVector2("vEmissiveInfos", this.EmissiveTexture.GetCoordinatesIndex(), this.EmissiveTexture.GetLevel())
+		this._effect.SetMatrix("emissiveMatrix", this.EmissiveTexture.ComputeTextureMatrix())
+	}
+
+	if this._useBump {
+		this._effect.SetTexture("bumpSampler", this.BumpTexture.GetGLTexture())
+
+		this._effect.SetVector2("vBumpInfos", this.BumpTexture.GetCoordinatesIndex(), this.BumpTexture.GetLevel())
+		this._effect.SetMatrix("bumpMatrix", this.BumpTexture.ComputeTextureMatrix())
+	}
+
+	if this._useReflection {
+		texture := this.ReflectionTexture.GetGLTexture()
+		this._effect.SetTexture("reflectionCubeSampler", texture)
+
+		// The roughness picks a level between the first and the last mip
+		lastLevel := float32(0)
+		if texture.Width > 1 {
+			lastLevel = float32(math.Log2(float64(texture.Width)))
+		}
+		this._effect.SetMatrix("reflectionMatrix", this.ReflectionTexture.ComputeReflectionTextureMatrix())
+		this._effect.SetFloat2("vReflectionInfos", this.ReflectionTexture.GetLevel(), lastLevel)
+	}
+
+	if this.BRDFTexture != nil {
+		this._effect.SetTexture("brdfSampler", this.BRDFTexture.GetGLTexture())
+	}
+
+	this._worldViewProjectionMatrix = world.Multiply(this._scene.GetTransformMatrix())
+	this._globalAmbientColor = this._scene.AmbientColor.Multiply(this.AmbientColor)
+
+	this._effect.SetMatrix("world", world)
+	this._effect.SetMatrix("worldViewProjection", this._worldViewProjectionMatrix)
+	if this._useInstances || this._useBones {
+		this._effect.SetMatrix("viewProjection", this._scene.GetTransformMatrix())
+	}
+	if this._useBones {
+		this._effect.SetMatrices("mBones", mesh.GetSkeleton().GetTransformMatrices())
+	}
+	if influences := mesh.GetMorphTargetInfluences(); len(influences) > 0 {
+		this._effect.SetFloatArray("morphTargetInfluences", influences)
+	}
+	this._effect.SetVector3("vEyePosition", this._scene.ActiveCamera.GetPosition())
+	this._effect.SetColor3("vAmbientColor", this._globalAmbientColor)
+	this._effect.SetColor4("vAlbedoColor", this.AlbedoColor, this.Alpha*mesh.GetVisibility())
+	this._effect.SetFloat2("vMetallicRoughness", this.Metallic, this.Roughness)
+	this._effect.SetColor3("vEmissiveColor", this.EmissiveColor)
+
+	bindLights(this._lightsCache.selectLights(this._scene, mesh, this.MaxSimultaneousLights), mesh, this._effect)
+
+	if core.GlobalFly3D.ClipPlane != nil {
+		this._effect.SetFloat4("vClipPlane", core.GlobalFly3D.ClipPlane.Normal.X, core.GlobalFly3D.ClipPlane.Normal.Y, core.GlobalFly3D.ClipPlane.Normal.Z, core.GlobalFly3D.ClipPlane.D)
+	}
+
+	// View
+	if this._scene.FogMode != core.FOGMODE_NONE {
+		this._effect.SetMatrix("view", this._scene.GetViewMatrix())
+	}
+
+	// Fog
+	if this._scene.FogMode != core.FOGMODE_NONE {
+		this._effect.SetFloat4("vFogInfos", float32(this._scene.FogMode), this._scene.FogStart, this._scene.FogEnd, this._scene.FogDensity)
+		this._effect.SetColor3("vFogColor", this._scene.FogColor)
+	}
+}
+
+func (this *PBRMaterial) Dispose() {
+	textures := []ITexture{this.AlbedoTexture, this.MetallicRoughnessTexture, this.AmbientOcclusionTexture, this.EmissiveTexture, this.BumpTexture, this.ReflectionTexture, this.BRDFTexture}
+	for _, texture := range textures {
+		if texture != nil {
+			texture.Dispose()
+		}
+	}
+
+	this.BaseDispose()
+}
+
+// IAnimationTarget
+func (this *PBRMaterial) GetAnimatables() []IAnimationTarget {
+	return nil
+}
+func (this *PBRMaterial) GetAnimations() []IAnimation {
+	return nil
+}
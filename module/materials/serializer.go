@@ -0,0 +1,429 @@
+package materials
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+
+	// Registers the texture parsers
+	_ "github.com/suiqirui1987/fly3d/module/textures"
+)
+
+func init() {
+	engines.RegisterParser("StandardMaterial", parseStandardMaterial)
+	engines.RegisterParser("PBRMaterial", parsePBRMaterial)
+	engines.RegisterParser("ShaderMaterial", parseShaderMaterial)
+	engines.RegisterParser("MultiMaterial", parseMultiMaterial)
+}
+
+type standardMaterialData struct {
+	Type            string  `json:"type"`
+	Name            string  `json:"name"`
+	Id              string  `json:"id"`
+	Alpha           float32 `json:"alpha"`
+	Wireframe       bool    `json:"wireframe"`
+	BackFaceCulling bool    `json:"backFaceCulling"`
+
+	AmbientColor  *math32.Color3 `json:"ambientColor"`
+	DiffuseColor  *math32.Color3 `json:"diffuseColor"`
+	SpecularColor *math32.Color3 `json:"specularColor"`
+	SpecularPower float32        `json:"specularPower"`
+	EmissiveColor *math32.Color3 `json:"emissiveColor"`
+
+	MaxSimultaneousLights int `json:"maxSimultaneousLights,omitempty"`
+
+	DiffuseTexture    json.RawMessage `json:"diffuseTexture,omitempty"`
+	AmbientTexture    json.RawMessage `json:"ambientTexture,omitempty"`
+	OpacityTexture    json.RawMessage `json:"opacityTexture,omitempty"`
+	ReflectionTexture json.RawMessage `json:"reflectionTexture,omitempty"`
+	EmissiveTexture   json.RawMessage `json:"emissiveTexture,omitempty"`
+	SpecularTexture   json.RawMessage `json:"specularTexture,omitempty"`
+	BumpTexture       json.RawMessage `json:"bumpTexture,omitempty"`
+}
+
+type pbrMaterialData struct {
+	Type            string  `json:"type"`
+	Name            string  `json:"name"`
+	Id              string  `json:"id"`
+	Alpha           float32 `json:"alpha"`
+	Wireframe       bool    `json:"wireframe"`
+	BackFaceCulling bool    `json:"backFaceCulling"`
+
+	AlbedoColor   *math32.Color3 `json:"albedoColor"`
+	Metallic      float32        `json:"metallic"`
+	Roughness     float32        `json:"roughness"`
+	EmissiveColor *math32.Color3 `json:"emissiveColor"`
+	AmbientColor  *math32.Color3 `json:"ambientColor"`
+
+	MaxSimultaneousLights int `json:"maxSimultaneousLights,omitempty"`
+
+	AlbedoTexture            json.RawMessage `json:"albedoTexture,omitempty"`
+	MetallicRoughnessTexture json.RawMessage `json:"metallicRoughnessTexture,omitempty"`
+	AmbientOcclusionTexture  json.RawMessage `json:"ambientOcclusionTexture,omitempty"`
+	EmissiveTexture          json.RawMessage `json:"emissiveTexture,omitempty"`
+	BumpTexture              json.RawMessage `json:"bumpTexture,omitempty"`
+	ReflectionTexture        json.RawMessage `json:"reflectionTexture,omitempty"`
+	BRDFTexture              json.RawMessage `json:"brdfTexture,omitempty"`
+}
+
+type shaderMaterialOptionsData struct {
+	Attributes []string `json:"attributes"`
+	Uniforms   []string `json:"uniforms"`
+	Samplers   []string `json:"samplers"`
+	Defines    []string `json:"defines,omitempty"`
+
+	NeedAlphaBlending bool `json:"needAlphaBlending"`
+	NeedAlphaTesting  bool `json:"needAlphaTesting"`
+}
+
+type shaderMaterialData struct {
+	Type            string  `json:"type"`
+	Name            string  `json:"name"`
+	Id              string  `json:"id"`
+	Alpha           float32 `json:"alpha"`
+	Wireframe       bool    `json:"wireframe"`
+	BackFaceCulling bool    `json:"backFaceCulling"`
+
+	// The code is only stored for the materials created from source
+	ShaderPath     string                     `json:"shaderPath"`
+	VertexSource   string                     `json:"vertexSource,omitempty"`
+	FragmentSource string                     `json:"fragmentSource,omitempty"`
+	Options        *shaderMaterialOptionsData `json:"options"`
+
+	Textures     map[string]json.RawMessage `json:"textures,omitempty"`
+	Floats       map[string]float32         `json:"floats,omitempty"`
+	FloatsArrays map[string][]float32       `json:"floatsArrays,omitempty"`
+	Vectors2     map[string]*math32.Vector2 `json:"vectors2,omitempty"`
+	Vectors3     map[string]*math32.Vector3 `json:"vectors3,omitempty"`
+	Arrays3      map[string][]float32       `json:"arrays3,omitempty"`
+	Colors3      map[string]*math32.Color3  `json:"colors3,omitempty"`
+	Colors4      map[string]*math32.Color4  `json:"colors4,omitempty"`
+	Arrays4      map[string][]float32       `json:"arrays4,omitempty"`
+	Matrices     map[string]*math32.Matrix4 `json:"matrices,omitempty"`
+	MatrixArrays map[string][]float32       `json:"matrixArrays,omitempty"`
+}
+
+type multiMaterialData struct {
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	Id           string   `json:"id"`
+	SubMaterials []string `json:"subMaterials"`
+}
+
+func (this *StandardMaterial) Serialize() interface{} {
+	return &standardMaterialData{
+		Type:            "StandardMaterial",
+		Name:            this.Name,
+		Id:              this.Id,
+		Alpha:           this.Alpha,
+		Wireframe:       this.Wireframe,
+		BackFaceCulling: this.BackFaceCulling,
+
+		AmbientColor:  this.AmbientColor,
+		DiffuseColor:  this.DiffuseColor,
+		SpecularColor: this.SpecularColor,
+		SpecularPower: this.SpecularPower,
+		EmissiveColor: this.EmissiveColor,
+
+		MaxSimultaneousLights: this.MaxSimultaneousLights,
+
+		DiffuseTexture:    engines.SerializeObject(this.DiffuseTexture),
+		AmbientTexture:    engines.SerializeObject(this.AmbientTexture),
+		OpacityTexture:    engines.SerializeObject(this.OpacityTexture),
+		ReflectionTexture: engines.SerializeObject(this.ReflectionTexture),
+		EmissiveTexture:   engines.SerializeObject(this.EmissiveTexture),
+		SpecularTexture:   engines.SerializeObject(this.SpecularTexture),
+		BumpTexture:       engines.SerializeObject(this.BumpTexture),
+	}
+}
+
+func (this *PBRMaterial) Serialize() interface{} {
+	return &pbrMaterialData{
+		Type:            "PBRMaterial",
+		Name:            this.Name,
+		Id:              this.Id,
+		Alpha:           this.Alpha,
+		Wireframe:       this.Wireframe,
+		BackFaceCulling: this.BackFaceCulling,
+
+		AlbedoColor:   this.AlbedoColor,
+		Metallic:      this.Metallic,
+		Roughness:     this.Roughness,
+		EmissiveColor: this.EmissiveColor,
+		AmbientColor:  this.AmbientColor,
+
+		MaxSimultaneousLights: this.MaxSimultaneousLights,
+
+		AlbedoTexture:            engines.SerializeObject(this.AlbedoTexture),
+		MetallicRoughnessTexture: engines.SerializeObject(this.MetallicRoughnessTexture),
+		AmbientOcclusionTexture:  engines.SerializeObject(this.AmbientOcclusionTexture),
+		EmissiveTexture:          engines.SerializeObject(this.EmissiveTexture),
+		BumpTexture:              engines.SerializeObject(this.BumpTexture),
+		ReflectionTexture:        engines.SerializeObject(this.ReflectionTexture),
+		BRDFTexture:              engines.SerializeObject(this.BRDFTexture),
+	}
+}
+
+func (this *ShaderMaterial) Serialize() interface{} {
+	data := &shaderMaterialData{
+		Type:            "ShaderMaterial",
+		Name:            this.Name,
+		Id:              this.Id,
+		Alpha:           this.Alpha,
+		Wireframe:       this.Wireframe,
+		BackFaceCulling: this.BackFaceCulling,
+
+		ShaderPath:     this._shaderPath,
+		VertexSource:   this._vertexSource,
+		FragmentSource: this._fragmentSource,
+		Options: &shaderMaterialOptionsData{
+			Attributes:        this._options.Attributes,
+			Uniforms:          this._options.Uniforms,
+			Samplers:          this._options.Samplers,
+			Defines:           this._options.Defines,
+			NeedAlphaBlending: this._options.NeedAlphaBlending,
+			NeedAlphaTesting:  this._options.NeedAlphaTesting,
+		},
+
+		Textures:     map[string]json.RawMessage{},
+		Floats:       this._floats,
+		FloatsArrays: this._floatsArrays,
+		Vectors2:     this._vectors2,
+		Vectors3:     this._vectors3,
+		Arrays3:      this._arrays3,
+		Colors3:      this._colors3,
+		Colors4:      this._colors4,
+		Arrays4:      this._arrays4,
+		Matrices:     this._matrices,
+		MatrixArrays: this._matrixArrays,
+	}
+
+	for name, texture := range this._textures {
+		if content := engines.SerializeObject(texture); content != nil {
+			data.Textures[name] = content
+		}
+	}
+
+	return data
+}
+
+func (this *MultiMaterial) Serialize() interface{} {
+	data := &multiMaterialData{
+		Type: "MultiMaterial",
+		Name: this.Name,
+		Id:   this.Id,
+	}
+	for _, material := range this.SubMaterials {
+		id := ""
+		if material != nil {
+			id = material.GetId()
+		}
+		data.SubMaterials = append(data.SubMaterials, id)
+	}
+	return data
+}
+
+func parseTexture(content json.RawMessage, scene *engines.Scene) (ITexture, error) {
+	object, err := engines.ParseObject(content, scene)
+	if err != nil || object == nil {
+		return nil, err
+	}
+
+	texture, ok := object.(ITexture)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a texture", object)
+	}
+	return texture, nil
+}
+
+func parseStandardMaterial(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &standardMaterialData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	material := NewStandardMaterial(data.Name, scene)
+	material.Id = data.Id
+	material.Alpha = data.Alpha
+	material.Wireframe = data.Wireframe
+	material.BackFaceCulling = data.BackFaceCulling
+
+	if data.AmbientColor != nil {
+		material.AmbientColor = data.AmbientColor
+	}
+	if data.DiffuseColor != nil {
+		material.DiffuseColor = data.DiffuseColor
+	}
+	if data.SpecularColor != nil {
+		material.SpecularColor = data.SpecularColor
+	}
+	material.SpecularPower = data.SpecularPower
+	if data.EmissiveColor != nil {
+		material.EmissiveColor = data.EmissiveColor
+	}
+
+	if data.MaxSimultaneousLights > 0 {
+		material.MaxSimultaneousLights = data.MaxSimultaneousLights
+	}
+
+	slots := []struct {
+		content json.RawMessage
+		texture *ITexture
+	}{
+		{data.DiffuseTexture, &material.DiffuseTexture},
+		{data.AmbientTexture, &material.AmbientTexture},
+		{data.OpacityTexture, &material.OpacityTexture},
+		{data.ReflectionTexture, &material.ReflectionTexture},
+		{data.EmissiveTexture, &material.EmissiveTexture},
+		{data.SpecularTexture, &material.SpecularTexture},
+		{data.BumpTexture, &material.BumpTexture},
+	}
+	for _, slot := range slots {
+		texture, err := parseTexture(slot.content, scene)
+		if err != nil {
+			return nil, fmt.Errorf("material %s: %s", data.Name, err)
+		}
+		*slot.texture = texture
+	}
+
+	return material, nil
+}
+
+func parsePBRMaterial(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &pbrMaterialData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	material := NewPBRMaterial(data.Name, scene)
+	material.Id = data.Id
+	material.Alpha = data.Alpha
+	material.Wireframe = data.Wireframe
+	material.BackFaceCulling = data.BackFaceCulling
+
+	if data.AlbedoColor != nil {
+		material.AlbedoColor = data.AlbedoColor
+	}
+	material.Metallic = data.Metallic
+	material.Roughness = data.Roughness
+	if data.EmissiveColor != nil {
+		material.EmissiveColor = data.EmissiveColor
+	}
+	if data.AmbientColor != nil {
+		material.AmbientColor = data.AmbientColor
+	}
+
+	if data.MaxSimultaneousLights > 0 {
+		material.MaxSimultaneousLights = data.MaxSimultaneousLights
+	}
+
+	slots := []struct {
+		content json.RawMessage
+		texture *ITexture
+	}{
+		{data.AlbedoTexture, &material.AlbedoTexture},
+		{data.MetallicRoughnessTexture, &material.MetallicRoughnessTexture},
+		{data.AmbientOcclusionTexture, &material.AmbientOcclusionTexture},
+		{data.EmissiveTexture, &material.EmissiveTexture},
+		{data.BumpTexture, &material.BumpTexture},
+		{data.ReflectionTexture, &material.ReflectionTexture},
+		{data.BRDFTexture, &material.BRDFTexture},
+	}
+	for _, slot := range slots {
+		texture, err := parseTexture(slot.content, scene)
+		if err != nil {
+			return nil, fmt.Errorf("material %s: %s", data.Name, err)
+		}
+		*slot.texture = texture
+	}
+
+	return material, nil
+}
+
+func parseShaderMaterial(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &shaderMaterialData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	options := &ShaderMaterialOptions{}
+	if data.Options != nil {
+		options.Attributes = data.Options.Attributes
+		options.Uniforms = data.Options.Uniforms
+		options.Samplers = data.Options.Samplers
+		options.Defines = data.Options.Defines
+		options.NeedAlphaBlending = data.Options.NeedAlphaBlending
+		options.NeedAlphaTesting = data.Options.NeedAlphaTesting
+	}
+
+	var material *ShaderMaterial
+	if data.VertexSource != "" || data.FragmentSource != "" {
+		material = NewShaderMaterialFromSource(data.Name, scene, data.VertexSource, data.FragmentSource, options)
+	} else {
+		material = NewShaderMaterial(data.Name, scene, data.ShaderPath, options)
+	}
+	material.Id = data.Id
+	material.Alpha = data.Alpha
+	material.Wireframe = data.Wireframe
+	material.BackFaceCulling = data.BackFaceCulling
+
+	for name, textureContent := range data.Textures {
+		texture, err := parseTexture(textureContent, scene)
+		if err != nil {
+			return nil, fmt.Errorf("material %s: %s", data.Name, err)
+		}
+		if texture != nil {
+			material._textures[name] = texture
+		}
+	}
+	for name, value := range data.Floats {
+		material._floats[name] = value
+	}
+	for name, values := range data.FloatsArrays {
+		material._floatsArrays[name] = values
+	}
+	for name, value := range data.Vectors2 {
+		material._vectors2[name] = value
+	}
+	for name, value := range data.Vectors3 {
+		material._vectors3[name] = value
+	}
+	for name, values := range data.Arrays3 {
+		material._arrays3[name] = values
+	}
+	for name, value := range data.Colors3 {
+		material._colors3[name] = value
+	}
+	for name, value := range data.Colors4 {
+		material._colors4[name] = value
+	}
+	for name, values := range data.Arrays4 {
+		material._arrays4[name] = values
+	}
+	for name, value := range data.Matrices {
+		material._matrices[name] = value
+	}
+	for name, values := range data.MatrixArrays {
+		material._matrixArrays[name] = values
+	}
+
+	return material, nil
+}
+
+func parseMultiMaterial(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &multiMaterialData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	multiMaterial := NewMultiMaterial(data.Name, scene)
+	multiMaterial.Id = data.Id
+	for _, id := range data.SubMaterials {
+		multiMaterial.SubMaterials = append(multiMaterial.SubMaterials, scene.GetMaterialByID(id))
+	}
+
+	return multiMaterial, nil
+}
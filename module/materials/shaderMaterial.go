@@ -0,0 +1,350 @@
+package materials
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/effects"
+)
+
+// ShaderMaterialOptions describes the inputs of the shaders of a
+// ShaderMaterial
+type ShaderMaterialOptions struct {
+	Attributes []string
+	Uniforms   []string
+	Samplers   []string
+	// Defines are full lines such as "#define WAVES" prepended to the code
+	Defines []string
+
+	NeedAlphaBlending bool
+	NeedAlphaTesting  bool
+}
+
+// ShaderMaterial draws meshes with application shaders. The values set on
+// the material are bound to the uniforms and samplers of the same name, which
+// must be listed in the options.
+// world, view, projection, worldView, worldViewProjection and viewProjection
+// are bound from the scene when they are listed in the uniforms. Instanced
+// meshes get INSTANCES and the world0 to world3 attributes, skinned meshes
+// get BONES, BonesPerMesh, the bones attributes and the mBones uniform
+type ShaderMaterial struct {
+	Material
+
+	_shaderPath string
+	_options    *ShaderMaterialOptions
+
+	// Code given to NewShaderMaterialFromSource, kept for Serialize
+	_vertexSource   string
+	_fragmentSource string
+
+	_textures     map[string]ITexture
+	_floats       map[string]float32
+	_floatsArrays map[string][]float32
+	_vectors2     map[string]*math32.Vector2
+	_vectors3     map[string]*math32.Vector3
+	_arrays3      map[string][]float32
+	_colors3      map[string]*math32.Color3
+	_colors4      map[string]*math32.Color4
+	_arrays4      map[string][]float32
+	_matrices     map[string]*math32.Matrix4
+	_matrixArrays map[string][]float32
+
+	_cachedDefines string
+	_useInstances  bool
+	_useBones      bool
+}
+
+// NewShaderMaterial creates a material drawing with the shaders shaderPath,
+// found in effects.ShadersStore as shaderPath_vertex and shaderPath_fragment
+// or loaded from shaderPath.vertex.fx and shaderPath.fragment.fx in the
+// shaders repository
+func NewShaderMaterial(name string, scene *engines.Scene, shaderPath string, options *ShaderMaterialOptions) *ShaderMaterial {
+	this := &ShaderMaterial{}
+	this.Name = name
+	this.Id = name
+	this._scene = scene
+	this._scene.Materials = append(this._scene.Materials, this)
+
+	this._shaderPath = shaderPath
+	if options == nil {
+		options = &ShaderMaterialOptions{}
+	}
+	this._options = options
+
+	this.Init()
+	return this
+}
+
+// NewShaderMaterialFromSource creates a material drawing with the given
+// code, stored in effects.ShadersStore under the name of the material. The
+// compiled effects are shared by name, materials with different code need
+// different names
+func NewShaderMaterialFromSource(name string, scene *engines.Scene, vertexSource string, fragmentSource string, options *ShaderMaterialOptions) *ShaderMaterial {
+	effects.ShadersStore[name+"_vertex"] = vertexSource
+	effects.ShadersStore[name+"_fragment"] = fragmentSource
+
+	this := NewShaderMaterial(name, scene, name, options)
+	this._vertexSource = vertexSource
+	this._fragmentSource = fragmentSource
+	return this
+}
+
+func (this *ShaderMaterial) Init() {
+	this.Material.Init()
+
+	this._textures = map[string]ITexture{}
+	this._floats = map[string]float32{}
+	this._floatsArrays = map[string][]float32{}
+	this._vectors2 = map[string]*math32.Vector2{}
+	this._vectors3 = map[string]*math32.Vector3{}
+	this._arrays3 = map[string][]float32{}
+	this._colors3 = map[string]*math32.Color3{}
+	this._colors4 = map[string]*math32.Color4{}
+	this._arrays4 = map[string][]float32{}
+	this._matrices = map[string]*math32.Matrix4{}
+	this._matrixArrays = map[string][]float32{}
+
+	this._cachedDefines = ""
+}
+
+// Values
+
+func (this *ShaderMaterial) SetTexture(name string, texture ITexture) *ShaderMaterial {
+	this._textures[name] = texture
+
+	return this
+}
+
+func (this *ShaderMaterial) SetFloat(name string, value float32) *ShaderMaterial {
+	this._floats[name] = value
+
+	return this
+}
+
+// SetFloats sets a float array uniform
+func (this *ShaderMaterial) SetFloats(name string, values []float32) *ShaderMaterial {
+	this._floatsArrays[name] = values
+
+	return this
+}
+
+func (this *ShaderMaterial) SetVector2(name string, value *math32.Vector2) *ShaderMaterial {
+	this._vectors2[name] = value
+
+	return this
+}
+
+func (this *ShaderMaterial) SetVector3(name string, value *math32.Vector3) *ShaderMaterial {
+	this._vectors3[name] = value
+
+	return this
+}
+
+// SetVector3Array sets a vec3 array uniform
+func (this *ShaderMaterial) SetVector3Array(name string, values []*math32.Vector3) *ShaderMaterial {
+	array := make([]float32, 0, len(values)*3)
+	for _, value := range values {
+		array = append(array, value.X, value.Y, value.Z)
+	}
+	this._arrays3[name] = array
+
+	return this
+}
+
+func (this *ShaderMaterial) SetColor3(name string, value *math32.Color3) *ShaderMaterial {
+	this._colors3[name] = value
+
+	return this
+}
+
+// SetColor3Array sets a vec3 array uniform
+func (this *ShaderMaterial) SetColor3Array(name string, values []*math32.Color3) *ShaderMaterial {
+	array := make([]float32, 0, len(values)*3)
+	for _, value := range values {
+		array = append(array, value.R, value.G, value.B)
+	}
+	this._arrays3[name] = array
+
+	return this
+}
+
+func (this *ShaderMaterial) SetColor4(name string, value *math32.Color4) *ShaderMaterial {
+	this._colors4[name] = value
+
+	return this
+}
+
+// SetColor4Array sets a vec4 array uniform
+func (this *ShaderMaterial) SetColor4Array(name string, values []*math32.Color4) *ShaderMaterial {
+	array := make([]float32, 0, len(values)*4)
+	for _, value := range values {
+		array = append(array, value.R, value.G, value.B, value.A)
+	}
+	this._arrays4[name] = array
+
+	return this
+}
+
+func (this *ShaderMaterial) SetMatrix(name string, value *math32.Matrix4) *ShaderMaterial {
+	this._matrices[name] = value
+
+	return this
+}
+
+// SetMatrices sets a mat4 array uniform
+func (this *ShaderMaterial) SetMatrices(name string, values []*math32.Matrix4) *ShaderMaterial {
+	array := make([]float32, 0, len(values)*16)
+	for _, value := range values {
+		array = append(array, value[:]...)
+	}
+	this._matrixArrays[name] = array
+
+	return this
+}
+
+/** interface IMaterial*/
+func (this *ShaderMaterial) NeedAlphaBlending() bool {
+	return this._options.NeedAlphaBlending
+}
+
+func (this *ShaderMaterial) NeedAlphaTesting() bool {
+	return this._options.NeedAlphaTesting
+}
+
+func (this *ShaderMaterial) IsReady(mesh IMesh, useInstances bool) bool {
+	for _, texture := range this._textures {
+		if !texture.IsReady() {
+			return false
+		}
+	}
+
+	defines := append([]string{}, this._options.Defines...)
+	attribs := append([]string{}, this._options.Attributes...)
+
+	// Instances
+	this._useInstances = useInstances
+	if useInstances {
+		defines = append(defines, "#define INSTANCES")
+		attribs = append(attribs, "world0", "world1", "world2", "world3")
+	}
+
+	// Bones
+	this._useBones = false
+	if mesh != nil && mesh.GetSkeleton() != nil && mesh.IsVerticesDataPresent(IMesh_VB_MatricesIndicesKind) && mesh.IsVerticesDataPresent(IMesh_VB_MatricesWeightsKind) {
+		this._useBones = true
+		attribs = append(attribs, "matricesIndices", "matricesWeights")
+		defines = append(defines, "#define BONES")
+		defines = append(defines, "#define BonesPerMesh "+strconv.Itoa(mesh.GetSkeleton().GetBonesCount()))
+	}
+
+	join := strings.Join(defines, "\n")
+	if this._effect == nil || this._cachedDefines != join {
+		this._cachedDefines = join
+
+		// The effect keeps and edits its lists
+		uniforms := append([]string{}, this._options.Uniforms...)
+		if this._useBones {
+			uniforms = append(uniforms, "mBones")
+		}
+		samplers := append([]string{}, this._options.Samplers...)
+
+		this._effect = effects.CreateEffect(this._scene.GetEngine(), this._shaderPath, attribs, uniforms, samplers, join, nil, this._onEffectError)
+	}
+
+	if !this._effect.IsReady() {
+		return false
+	}
+
+	return true
+}
+
+func (this *ShaderMaterial) GetRenderTargetTextures() []ITexture {
+	results := make([]ITexture, 0)
+
+	for _, texture := range this._textures {
+		if texture.IsRenderTarget() {
+			results = append(results, texture)
+		}
+	}
+
+	return results
+}
+
+func (this *ShaderMaterial) _hasUniform(uniformName string) bool {
+	return this._effect.GetUniformIndex(uniformName) > -1
+}
+
+func (this *ShaderMaterial) Bind(world *math32.Matrix4, mesh IMesh) {
+	// Scene matrices
+	if this._hasUniform("world") {
+		this._effect.SetMatrix("world", world)
+	}
+	if this._hasUniform("view") {
+		this._effect.SetMatrix("view", this._scene.GetViewMatrix())
+	}
+	if this._hasUniform("worldView") {
+		this._effect.SetMatrix("worldView", world.Multiply(this._scene.GetViewMatrix()))
+	}
+	if this._hasUniform("projection") {
+		this._effect.SetMatrix("projection", this._scene.GetProjectionMatrix())
+	}
+	if this._hasUniform("viewProjection") {
+		this._effect.SetMatrix("viewProjection", this._scene.GetTransformMatrix())
+	}
+	if this._hasUniform("worldViewProjection") {
+		this._effect.SetMatrix("worldViewProjection", world.Multiply(this._scene.GetTransformMatrix()))
+	}
+
+	// Bones
+	if this._useBones {
+		this._effect.SetMatrices("mBones", mesh.GetSkeleton().GetTransformMatrices())
+	}
+
+	// Values
+	for name, texture := range this._textures {
+		this._effect.SetTexture(name, texture.GetGLTexture())
+	}
+	for name, value := range this._floats {
+		this._effect.SetFloat(name, value)
+	}
+	for name, values := range this._floatsArrays {
+		this._effect.SetFloatArray(name, values)
+	}
+	for name, value := range this._vectors2 {
+		this._effect.SetVector2(name, value.X, value.Y)
+	}
+	for name, value := range this._vectors3 {
+		this._effect.SetVector3(name, value)
+	}
+	for name, values := range this._arrays3 {
+		this._effect.SetArray3(name, values)
+	}
+	for name, value := range this._colors3 {
+		this._effect.SetColor3(name, value)
+	}
+	for name, value := range this._colors4 {
+		this._effect.SetColor42(name, value)
+	}
+	for name, values := range this._arrays4 {
+		this._effect.SetArray4(name, values)
+	}
+	for name, value := range this._matrices {
+		this._effect.SetMatrix(name, value)
+	}
+	for name, values := range this._matrixArrays {
+		this._effect.SetMatrices(name, values)
+	}
+}
+
+func (this *ShaderMaterial) Dispose() {
+	for _, texture := range this._textures {
+		texture.Dispose()
+	}
+
+	this.BaseDispose()
+}
+
+/***/
@@ -9,7 +9,6 @@ import (
 	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/math32"
 	"github.com/suiqirui1987/fly3d/module/effects"
-	"github.com/suiqirui1987/fly3d/module/lights"
 )
 
 type StandardMaterial struct {
@@ -29,16 +28,21 @@ type StandardMaterial struct {
 	SpecularPower float32
 	EmissiveColor *math32.Color3
 
+	// MaxSimultaneousLights is the number of lights of a mesh, its most
+	// influential ones
+	MaxSimultaneousLights int
+
+	_lightsCache   lightsCache
 	_cachedDefines string
+	_useInstances  bool
+	_useBones      bool
+	_useBump       bool
 	_renderTargets []interface{}
 
 	//Internals
 	_worldViewProjectionMatrix *math32.Matrix4
-	_lightMatrix               *math32.Matrix4
 	_globalAmbientColor        *math32.Color3
 	_baseColor                 *math32.Color3
-	_scaledDiffuse             *math32.Color3
-	_scaledSpecular            *math32.Color3
 }
 
 func NewStandardMaterial(name string, scene *engines.Scene) *StandardMaterial {
@@ -62,15 +66,14 @@ func (this *StandardMaterial) Init() {
 	this.SpecularPower = 64
 	this.EmissiveColor = math32.NewColor3(0, 0, 0)
 
+	this.MaxSimultaneousLights = defaultMaxSimultaneousLights
+
 	this._cachedDefines = ""
 	this._renderTargets = make([]interface{}, 0)
 
 	this._worldViewProjectionMatrix = math32.NewMatrix4().Zero()
-	this._lightMatrix = math32.NewMatrix4().Zero()
 	this._globalAmbientColor = math32.NewColor3(0, 0, 0)
 	this._baseColor = math32.NewColor3(0, 0, 0)
-	this._scaledDiffuse = math32.NewColor3(0, 0, 0)
-	this._scaledSpecular = math32.NewColor3(0, 0, 0)
 }
 
 func (this *StandardMaterial) NeedAlphaBlending() bool {
@@ -80,7 +83,7 @@ func (this *StandardMaterial) NeedAlphaBlending() bool {
 func (this *StandardMaterial) NeedAlphaTesting() bool {
 	return this.DiffuseTexture != nil && this.DiffuseTexture.HasAlpha()
 }
-func (this *StandardMaterial) IsReady(mesh IMesh) bool {
+func (this *StandardMaterial) IsReady(mesh IMesh, useInstances bool) bool {
 	engine := this._scene.GetEngine()
 
 	// Effect
@@ -138,10 +141,14 @@ func (this *StandardMaterial) IsReady(mesh IMesh) bool {
 		}
 	}
 
-	if engine.GetCaps().StandardDerivatives && this.BumpTexture != nil {
+	// Bump, the tangent space comes from the tangents of the mesh or else
+	// from the screen space derivatives
+	this._useBump = false
+	if this.BumpTexture != nil && (engine.GetCaps().StandardDerivatives || (mesh != nil && mesh.IsVerticesDataPresent(IMesh_VB_TangentKind))) {
 		if !this.BumpTexture.IsReady() {
 			return false
 		} else {
+			this._useBump = true
 			defines = append(defines, "#define BUMP")
 		}
 	}
@@ -159,49 +166,7 @@ func (this *StandardMaterial) IsReady(mesh IMesh) bool {
 		defines = append(defines, "#define FOG")
 	}
 
-	shadowsActivated := false
-	var lightIndex int
-	lightIndex = 0
-	for index := 0; index < len(this._scene.Lights); index++ {
-		light := this._scene.Lights[index]
-
-		if !light.IsEnabled() {
-			continue
-		}
-
-		lightIndex_str := strconv.Itoa(lightIndex)
-
-		defines = append(defines, "#define LIGHT"+lightIndex_str)
-
-		if _, ok := light.(*lights.SpotLight); ok {
-			defines = append(defines, "#define SPOTLIGHT"+lightIndex_str)
-		} else if _, ok := light.(*lights.HemisphericLight); ok {
-			defines = append(defines, "#define HEMILIGHT"+lightIndex_str)
-		} else {
-			defines = append(defines, "#define POINTDIRLIGHT"+lightIndex_str)
-		}
-
-		// Shadows
-		shadowGenerator := light.GetShadowGenerator()
-		if mesh != nil && mesh.IsReceiveShadows() == true && shadowGenerator != nil && shadowGenerator.IsReady() {
-			defines = append(defines, "#define SHADOW"+lightIndex_str)
-
-			if !shadowsActivated {
-				defines = append(defines, "#define SHADOWS")
-				shadowsActivated = true
-			}
-
-			if shadowGenerator.IsUseVarianceShadowMap() {
-				defines = append(defines, "#define SHADOWVSM"+lightIndex_str)
-			}
-		}
-
-		lightIndex++
-		if lightIndex == 4 {
-			break
-		}
-
-	}
+	defines = prepareLightsDefines(this._lightsCache.selectLights(this._scene, mesh, this.MaxSimultaneousLights), mesh, this.MaxSimultaneousLights, defines)
 
 	attribs := []string{"position", "normal"}
 	if mesh != nil {
@@ -217,6 +182,45 @@ func (this *StandardMaterial) IsReady(mesh IMesh) bool {
 			attribs = append(attribs, "color")
 			defines = append(defines, "#define VERTEXCOLOR")
 		}
+		if this._useBump && mesh.IsVerticesDataPresent(IMesh_VB_TangentKind) {
+			attribs = append(attribs, "tangent")
+			defines = append(defines, "#define TANGENT")
+		}
+	}
+
+	// Bones
+	this._useBones = false
+	if mesh != nil && mesh.GetSkeleton() != nil && mesh.IsVerticesDataPresent(IMesh_VB_MatricesIndicesKind) && mesh.IsVerticesDataPresent(IMesh_VB_MatricesWeightsKind) {
+		this._useBones = true
+		attribs = append(attribs, "matricesIndices", "matricesWeights")
+		defines = append(defines, "#define BONES")
+		defines = append(defines, "#define BonesPerMesh "+strconv.Itoa(mesh.GetSkeleton().GetBonesCount()))
+	}
+
+	// Morph targets
+	if mesh != nil {
+		if influences := mesh.GetMorphTargetInfluences(); len(influences) > 0 {
+			defines = append(defines, "#define MORPHTARGETS")
+			defines = append(defines, "#define NUM_MORPH_INFLUENCERS "+strconv.Itoa(len(influences)))
+			normals := mesh.IsMorphTargetNormalsPresent()
+			if normals {
+				defines = append(defines, "#define MORPHTARGETS_NORMAL")
+			}
+			for index := range influences {
+				defines = append(defines, "#define MORPHTARGET"+strconv.Itoa(index))
+				attribs = append(attribs, "position"+strconv.Itoa(index))
+				if normals {
+					attribs = append(attribs, "normal"+strconv.Itoa(index))
+				}
+			}
+		}
+	}
+
+	// Instances
+	this._useInstances = useInstances
+	if useInstances {
+		defines = append(defines, "#define INSTANCES")
+		attribs = append(attribs, "world0", "world1", "world2", "world3")
 	}
 
 	// Get correct effect
@@ -230,24 +234,28 @@ func (this *StandardMaterial) IsReady(mesh IMesh) bool {
 			shaderName = "iedefault"
 		}
 
+		// Optional features the effect drops if it does not compile
+		fallbacks := effects.NewEffectFallbacks()
+		fallbacks.AddFallback(0, "REFLECTION")
+		fallbacks.AddFallback(0, "SPECULAR")
+		fallbacks.AddFallback(0, "BUMP")
+		fallbacks.AddFallback(0, "TANGENT")
+		fallbacks.AddFallback(1, "FOG")
+		addLightsFallbacks(fallbacks, this.MaxSimultaneousLights)
+
+		lightUniforms, lightSamplers := lightsUniforms(this.MaxSimultaneousLights)
 		this._effect = effects.CreateEffect(
 			engine,
 			shaderName,
 			attribs,
-			[]string{"world", "view", "worldViewProjection", "vEyePosition", "vLightsType", "vAmbientColor", "vDiffuseColor", "vSpecularColor", "vEmissiveColor",
-				"vLightData0", "vLightDiffuse0", "vLightSpecular0", "vLightDirection0", "vLightGround0", "lightMatrix0",
-				"vLightData1", "vLightDiffuse1", "vLightSpecular1", "vLightDirection1", "vLightGround1", "lightMatrix1",
-				"vLightData2", "vLightDiffuse2", "vLightSpecular2", "vLightDirection2", "vLightGround2", "lightMatrix2",
-				"vLightData3", "vLightDiffuse3", "vLightSpecular3", "vLightDirection3", "vLightGround3", "lightMatrix3",
+			append([]string{"world", "view", "viewProjection", "worldViewProjection", "mBones", "morphTargetInfluences", "vEyePosition", "vLightsType", "vAmbientColor", "vDiffuseColor", "vSpecularColor", "vEmissiveColor",
 				"vFogInfos", "vFogColor",
 				"vDiffuseInfos", "vAmbientInfos", "vOpacityInfos", "vReflectionInfos", "vEmissiveInfos", "vSpecularInfos", "vBumpInfos",
 				"vClipPlane", "diffuseMatrix", "ambientMatrix", "opacityMatrix", "reflectionMatrix", "emissiveMatrix", "specularMatrix", "bumpMatrix",
-			},
+			}, lightUniforms...),
 
-			[]string{"diffuseSampler", "ambientSampler", "opacitySampler", "reflectionCubeSampler", "reflection2DSampler", "emissiveSampler", "specularSampler", "bumpSampler",
-				"shadowSampler0", "shadowSampler1", "shadowSampler2", "shadowSampler3",
-			},
-			join)
+			append([]string{"diffuseSampler", "ambientSampler", "opacitySampler", "reflectionCubeSampler", "reflection2DSampler", "emissiveSampler", "specularSampler", "bumpSampler"}, lightSamplers...),
+			join, fallbacks, this._onEffectError)
 	}
 	if !this._effect.IsReady() {
 		return false
@@ -328,7 +336,7 @@ func (this *StandardMaterial) Bind(world *math32.Matrix4, mesh IMesh) {
 		this._effect.SetMatrix("specularMatrix", this.SpecularTexture.ComputeTextureMatrix())
 	}
 
-	if this.BumpTexture != nil && this._scene.GetEngine().GetCaps().StandardDerivatives {
+	if this._useBump {
 		this._effect.SetTexture("bumpSampler", this.BumpTexture.GetGLTexture())
 
 		this._effect.SetVector2("vBumpInfos", this.BumpTexture.GetCoordinatesIndex(), this.BumpTexture.GetLevel())
@@ -340,60 +348,22 @@ func (this *StandardMaterial) Bind(world *math32.Matrix4, mesh IMesh) {
 
 	this._effect.SetMatrix("world", world)
 	this._effect.SetMatrix("worldViewProjection", this._worldViewProjectionMatrix)
+	if this._useInstances || this._useBones {
+		this._effect.SetMatrix("viewProjection", this._scene.GetTransformMatrix())
+	}
+	if this._useBones {
+		this._effect.SetMatrices("mBones", mesh.GetSkeleton().GetTransformMatrices())
+	}
+	if influences := mesh.GetMorphTargetInfluences(); len(influences) > 0 {
+		this._effect.SetFloatArray("morphTargetInfluences", influences)
+	}
 	this._effect.SetVector3("vEyePosition", this._scene.ActiveCamera.GetPosition())
 	this._effect.SetColor3("vAmbientColor", this._globalAmbientColor)
 	this._effect.SetColor4("vDiffuseColor", baseColor, this.Alpha*mesh.GetVisibility())
 	this._effect.SetColor4("vSpecularColor", this.SpecularColor, this.SpecularPower)
 	this._effect.SetColor3("vEmissiveColor", this.EmissiveColor)
 
-	lightIndex := 0
-	for index := 0; index < len(this._scene.Lights); index++ {
-		light := this._scene.Lights[index]
-
-		if !light.IsEnabled() {
-			continue
-		}
-
-		lightIndex_str := strconv.Itoa(lightIndex)
-
-		if polight, ok := light.(*lights.PointLight); ok {
-			// Point Light
-			this._effect.SetFloat4("vLightData"+lightIndex_str, polight.Position.X, polight.Position.Y, polight.Position.Z, 0)
-		} else if dlight, ok := light.(*lights.DirectionalLight); ok {
-			// Directional Light
-			this._effect.SetFloat4("vLightData"+lightIndex_str, dlight.Direction.X, dlight.Direction.Y, dlight.Direction.Z, 1)
-		} else if slight, ok := light.(*lights.SpotLight); ok {
-			// Spot Light
-			this._effect.SetFloat4("vLightData"+lightIndex_str, slight.Position.X, slight.Position.Y, slight.Position.Z, slight.Exponent)
-			normalizeDirection := slight.Direction.NormalizeTo()
-			this._effect.SetFloat4("vLightDirection"+lightIndex_str, normalizeDirection.X, normalizeDirection.Y, normalizeDirection.Z, math32.Cos(slight.Angle*0.5))
-		} else if hlight, ok := light.(*lights.HemisphericLight); ok {
-			// Hemispheric Light
-			normalizeDirection := hlight.Direction.NormalizeTo()
-			this._effect.SetFloat4("vLightData"+lightIndex_str, normalizeDirection.X, normalizeDirection.Y, normalizeDirection.Z, 0)
-			this._effect.SetColor3("vLightGround"+lightIndex_str, hlight.GroundColor.Scale(hlight.Intensity))
-		}
-		this._scaledDiffuse = light.GetDiffuse().Scale(light.GetIntensity())
-		this._scaledSpecular = light.GetSpecular().Scale(light.GetIntensity())
-
-		this._effect.SetColor3("vLightDiffuse"+lightIndex_str, this._scaledDiffuse)
-		this._effect.SetColor3("vLightSpecular"+lightIndex_str, this._scaledSpecular)
-
-		// Shadows
-		shadowGenerator := light.GetShadowGenerator()
-		if mesh.IsReceiveShadows() && shadowGenerator != nil && shadowGenerator.IsReady() {
-			this._lightMatrix = world.Multiply(shadowGenerator.GetTransformMatrix())
-			this._effect.SetMatrix("lightMatrix"+lightIndex_str, this._lightMatrix)
-			this._effect.SetTexture("shadowSampler"+lightIndex_str, shadowGenerator.GetShadowMap().GetGLTexture())
-		}
-
-		lightIndex++
-
-		if lightIndex == 4 {
-			break
-		}
-
-	}
+	bindLights(this._lightsCache.selectLights(this._scene, mesh, this.MaxSimultaneousLights), mesh, this._effect)
 
 	if core.GlobalFly3D.ClipPlane != nil {
 		this._effect.SetFloat4("vClipPlane", core.GlobalFly3D.ClipPlane.Normal.X, core.GlobalFly3D.ClipPlane.Normal.Y, core.GlobalFly3D.ClipPlane.Normal.Z, core.GlobalFly3D.ClipPlane.D)
@@ -445,7 +415,7 @@ func (this *StandardMaterial) Dispose() {
 }
 
 //IAnimationTarget
-func (this *StandardMaterial) GetAnimatables() []IAnimatable {
+func (this *StandardMaterial) GetAnimatables() []IAnimationTarget {
 	return nil
 }
 func (this *StandardMaterial) GetAnimations() []IAnimation {
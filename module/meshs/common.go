@@ -8,3 +8,19 @@ const (
 	BILLBOARDMODE_Z    = 4
 	BILLBOARDMODE_ALL  = 7
 )
+
+// Caps closing the ends of the tubes and of the extruded shapes
+const (
+	CAP_NONE  = 0
+	CAP_START = 1
+	CAP_END   = 2
+	CAP_ALL   = 3
+)
+
+// Polyhedra built by CreatePolyhedron
+const (
+	POLYHEDRON_TETRAHEDRON  = 0
+	POLYHEDRON_OCTAHEDRON   = 1
+	POLYHEDRON_DODECAHEDRON = 2
+	POLYHEDRON_ICOSAHEDRON  = 3
+)
@@ -0,0 +1,148 @@
+package meshs
+
+import (
+	"image"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/tools"
+)
+
+// GroundMesh is a grid in the XZ plane whose vertices may be moved up and
+// down, it knows the height of its surface at any point
+type GroundMesh struct {
+	*Mesh
+
+	_width        float32
+	_height       float32
+	_subdivisions int
+}
+
+func NewGroundMesh(name string, width float32, height float32, subdivisions int, scene *engines.Scene) *GroundMesh {
+	this := &GroundMesh{}
+	this.Mesh = NewMesh(name, scene)
+	this._width = width
+	this._height = height
+	this._subdivisions = subdivisions
+
+	return this
+}
+
+func (this *GroundMesh) GetSubdivisions() int {
+	return this._subdivisions
+}
+
+// CreateGroundFromHeightMap builds a ground whose heights come from the
+// brightness of the image at url, from minHeight for black to maxHeight for
+// white. The image is loaded asynchronously, the ground stays flat and is
+// not ready until then
+func CreateGroundFromHeightMap(name string, url string, width float32, height float32, subdivisions int, minHeight float32, maxHeight float32, scene *engines.Scene) *GroundMesh {
+	ground := NewGroundMesh(name, width, height, subdivisions, scene)
+	ground._isReady = false
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	for row := 0; row <= subdivisions; row++ {
+		for col := 0; col <= subdivisions; col++ {
+			x := float32(col)*width/float32(subdivisions) - width/2.0
+			z := float32(subdivisions-row)*height/float32(subdivisions) - height/2.0
+
+			positions = append(positions, x, 0, z)
+			normals = append(normals, 0, 1.0, 0)
+			uvs = append(uvs, float32(col)/float32(subdivisions), 1.0-float32(row)/float32(subdivisions))
+		}
+	}
+
+	for row := 0; row < subdivisions; row++ {
+		for col := 0; col < subdivisions; col++ {
+			indices = append(indices, uint32(col+1+(row+1)*(subdivisions+1)))
+			indices = append(indices, uint32(col+1+row*(subdivisions+1)))
+			indices = append(indices, uint32(col+row*(subdivisions+1)))
+
+			indices = append(indices, uint32(col+(row+1)*(subdivisions+1)))
+			indices = append(indices, uint32(col+1+(row+1)*(subdivisions+1)))
+			indices = append(indices, uint32(col+row*(subdivisions+1)))
+		}
+	}
+
+	setVertexData(ground.Mesh, positions, normals, uvs, indices, false)
+
+	onload := func(img *image.RGBA) {
+		bounds := img.Bounds()
+		bufferWidth := bounds.Dx()
+		bufferHeight := bounds.Dy()
+
+		// The pixels are read where the texture coordinates of the vertices
+		// would sample a texture of the same image
+		for index := 0; index < len(positions)/3; index++ {
+			x := int(uvs[index*2] * float32(bufferWidth-1))
+			y := int(uvs[index*2+1] * float32(bufferHeight-1))
+			pixel := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+
+			gradient := (float32(pixel.R)*0.3 + float32(pixel.G)*0.59 + float32(pixel.B)*0.11) / 255.0
+			positions[index*3+1] = minHeight + (maxHeight-minHeight)*gradient
+		}
+
+		setVertexData(ground.Mesh, positions, ComputeNormals(positions, indices), uvs, indices, false)
+
+		ground._isReady = true
+		scene.RemovePendingData(url)
+	}
+	onfailed := func(err error) {
+		// The ground stays flat
+		ground._isReady = true
+		scene.RemovePendingData(url)
+	}
+	scene.AddPendingData(url)
+	tools.LoadImage(url, onload, onfailed)
+
+	return ground
+}
+
+// GetHeightAtCoordinates returns the world height of the ground surface above
+// or below the world point x, z. Outside of the ground it returns the height
+// of the ground position. The ground may be moved and turned around the Y
+// axis
+func (this *GroundMesh) GetHeightAtCoordinates(x float32, z float32) float32 {
+	world := this.GetWorldMatrix()
+	invert := world.Clone()
+	invert.Invert()
+
+	local := math32.NewVector3(x, 0, z).TransformCoordinates(invert)
+
+	// Position of the point in the grid
+	column := (local.X + this._width/2.0) / this._width * float32(this._subdivisions)
+	row := (this._height/2.0 - local.Z) / this._height * float32(this._subdivisions)
+	if column < 0 || row < 0 || column > float32(this._subdivisions) || row > float32(this._subdivisions) {
+		return this.Position.Y
+	}
+
+	col := math32.ClampInt(int(column), 0, this._subdivisions-1)
+	line := math32.ClampInt(int(row), 0, this._subdivisions-1)
+
+	positions := this.GetVerticesData(IMesh_VB_PositionKind)
+	vertex := func(line, col int) *math32.Vector3 {
+		return math32.NewVector3Zero().FromArray(positions, (col+line*(this._subdivisions+1))*3)
+	}
+
+	// Each cell is split along the diagonal from its first vertex to its
+	// last one
+	a := vertex(line, col)
+	c := vertex(line+1, col+1)
+	b := vertex(line+1, col)
+	if column-float32(col) >= row-float32(line) {
+		b = vertex(line, col+1)
+	}
+
+	normal := b.Sub(a).Cross(c.Sub(a))
+	if normal.Y == 0 {
+		return this.Position.Y
+	}
+	y := a.Y - (normal.X*(local.X-a.X)+normal.Z*(local.Z-a.Z))/normal.Y
+
+	return math32.NewVector3(local.X, y, local.Z).TransformCoordinates(world).Y
+}
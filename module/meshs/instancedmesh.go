@@ -0,0 +1,304 @@
+package meshs
+
+import (
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cullings"
+	"github.com/suiqirui1987/fly3d/tools"
+)
+
+// InstancedMesh draws the geometry and the material of its source mesh with
+// its own transformation. The source mesh draws all its active instances in
+// one instanced draw call per submesh when the engine supports it
+type InstancedMesh struct {
+	Name        string
+	Id          string
+	_scene      *engines.Scene
+	_sourceMesh *Mesh
+
+	_worldMatrix *math32.Matrix4
+
+	Position *math32.Vector3
+	Rotation *math32.Vector3
+	Scaling  *math32.Vector3
+	// RotationQuaternion replaces Rotation when it is set
+	RotationQuaternion *math32.Quaternion
+	_scaleFactor       float32
+
+	Parent      *Mesh
+	_isEnabled  bool
+	Isvisible   bool
+	Ispickable  bool
+	OnDispose   func()
+	_isDisposed bool
+
+	_boundingInfo *cullings.BoundingInfo
+
+	//cache
+	_cache *MeshCache
+}
+
+func NewInstancedMesh(name string, source *Mesh) *InstancedMesh {
+	this := &InstancedMesh{}
+	this.Name = name
+	this.Id = name
+	this._scene = source._scene
+	this._sourceMesh = source
+
+	this._worldMatrix = math32.NewMatrix4().Identity()
+
+	this.Position = math32.NewVector3(0, 0, 0)
+	this.Rotation = math32.NewVector3(0, 0, 0)
+	this.Scaling = math32.NewVector3(1, 1, 1)
+	this._scaleFactor = 1
+
+	this._isEnabled = true
+	this.Isvisible = true
+	this.Ispickable = true
+
+	this._cache = &MeshCache{}
+
+	this.RefreshBoundingInfo()
+
+	source._instances = append(source._instances, this)
+	this._scene.Meshes = append(this._scene.Meshes, this)
+
+	return this
+}
+
+func (this *InstancedMesh) GetSourceMesh() IMesh {
+	return this._sourceMesh
+}
+
+func (this *InstancedMesh) GetScene() *engines.Scene {
+	return this._scene
+}
+
+func (this *InstancedMesh) GetParent() IMesh {
+	if this.Parent == nil {
+		return nil
+	}
+	return this.Parent
+}
+
+func (this *InstancedMesh) GetBoundingInfo() *cullings.BoundingInfo {
+	return this._boundingInfo
+}
+
+// RefreshBoundingInfo copies the bounds of the source geometry, it must be
+// called when the source vertices change
+func (this *InstancedMesh) RefreshBoundingInfo() {
+	source := this._sourceMesh
+	if !source.IsVerticesDataPresent(IMesh_VB_PositionKind) {
+		this._boundingInfo = nil
+		return
+	}
+
+	this._boundingInfo = cullings.NewBoundingInfo(source.GetVerticesData(IMesh_VB_PositionKind), 0, source._totalVertices)
+	this._cache = &MeshCache{}
+}
+
+func (this *InstancedMesh) SetEnabled(val bool) {
+	this._isEnabled = val
+}
+
+func (this *InstancedMesh) IsDisposed() bool {
+	return this._isDisposed
+}
+
+func (this *InstancedMesh) IsSynchronized() bool {
+	if this._cache.position == nil || this._cache.rotation == nil || this._cache.scaling == nil {
+		return false
+	}
+
+	if !this._cache.position.Equals(this.Position) || !this._cache.rotation.Equals(this.Rotation) || !this._cache.scaling.Equals(this.Scaling) {
+		return false
+	}
+	if this.RotationQuaternion != nil {
+		if this._cache.rotationQuaternion == nil || !this._cache.rotationQuaternion.Equals(this.RotationQuaternion) {
+			return false
+		}
+	} else if this._cache.rotationQuaternion != nil {
+		return false
+	}
+	if this.Parent != nil {
+		return !this.Parent._needToSynchonizeChildren()
+	}
+	return true
+}
+
+/***  IMesh interface start ***/
+
+func (this *InstancedMesh) GetId() string {
+	return this.Id
+}
+func (this *InstancedMesh) GetName() string {
+	return this.Name
+}
+func (this *InstancedMesh) GetPosition() *math32.Vector3 {
+	return this.Position
+}
+func (this *InstancedMesh) GetTotalVertices() int {
+	return this._sourceMesh.GetTotalVertices()
+}
+func (this *InstancedMesh) GetWorldMatrix() *math32.Matrix4 {
+	return this._worldMatrix
+}
+
+func (this *InstancedMesh) IsReady() bool {
+	return this._sourceMesh.IsReady()
+}
+
+func (this *InstancedMesh) IsReceiveShadows() bool {
+	return this._sourceMesh.IsReceiveShadows()
+}
+func (this *InstancedMesh) IsVerticesDataPresent(kind string) bool {
+	return this._sourceMesh.IsVerticesDataPresent(kind)
+}
+
+func (this *InstancedMesh) ComputeWorldMatrix() {
+	if this.IsSynchronized() {
+		return
+	}
+
+	this._cache.position = this.Position.Clone()
+	this._cache.rotation = this.Rotation.Clone()
+	this._cache.scaling = this.Scaling.Clone()
+	this._cache.rotationQuaternion = nil
+
+	var localRotation *math32.Matrix4
+	if this.RotationQuaternion != nil {
+		this._cache.rotationQuaternion = this.RotationQuaternion.Clone()
+
+		localRotation = math32.NewMatrix4()
+		this.RotationQuaternion.ToRotationMatrix(localRotation)
+	} else {
+		localRotation = math32.NewMatrix4().RotationYawPitchRoll(this.Rotation.Y, this.Rotation.X, this.Rotation.Z)
+	}
+	localScaling := math32.NewMatrix4().Scaling(this.Scaling.X, this.Scaling.Y, this.Scaling.Z)
+	localTranslation := math32.NewMatrix4().Translation(this.Position.X, this.Position.Y, this.Position.Z)
+
+	this._worldMatrix = localScaling.Multiply(localRotation).Multiply(localTranslation)
+	if this.Parent != nil {
+		this._worldMatrix = this._worldMatrix.Multiply(this.Parent.GetWorldMatrix())
+	}
+
+	// Bounding info
+	if this._boundingInfo != nil {
+		this._scaleFactor = math32.Max(this.Scaling.X, this.Scaling.Y)
+		this._scaleFactor = math32.Max(this._scaleFactor, this.Scaling.Z)
+
+		if this.Parent != nil {
+			this._scaleFactor = this._scaleFactor * this.Parent._scaleFactor
+		}
+
+		this._boundingInfo.Update(this._worldMatrix, this._scaleFactor)
+	}
+}
+
+func (this *InstancedMesh) IsEnabled() bool {
+	if !this.IsReady() || !this._isEnabled {
+		return false
+	}
+
+	if this.Parent != nil {
+		return this.Parent.IsEnabled()
+	}
+
+	return true
+}
+
+func (this *InstancedMesh) IsVisible() bool {
+	return this.Isvisible
+}
+func (this *InstancedMesh) IsPickable() bool {
+	return this.Ispickable
+}
+
+// Intersects tests the source geometry, the ray is in the instance space
+func (this *InstancedMesh) Intersects(ray *math32.Ray) *math32.RayIntersectsResult {
+	// The bounding info is computed with the base shape
+	if this._boundingInfo == nil || (!this._sourceMesh._isDeformed() && !ray.IntersectsSphere(this._boundingInfo.Sphere.GetSphere())) {
+		return &math32.RayIntersectsResult{Hit: false, Distance: 0}
+	}
+
+	return this._sourceMesh._intersectsSubMeshes(ray)
+}
+
+func (this *InstancedMesh) CheckCollision(collider ICollider) {
+	// Bounding box test
+	if this._boundingInfo == nil || !this._boundingInfo.CheckCollision(collider) {
+		return
+	}
+
+	// Transformation matrix
+	radius := collider.GetRadius()
+	transformMatrix := this._worldMatrix.Multiply(math32.NewMatrix4().Scaling(1.0/radius.X, 1.0/radius.Y, 1.0/radius.Z))
+
+	for _, subMesh := range this._sourceMesh.SubMeshes {
+		this._sourceMesh._collideForSubMesh(subMesh, transformMatrix, collider)
+	}
+
+	if collider.HasCollisionFound() {
+		collider.SetMesh(this)
+	}
+}
+
+func (this *InstancedMesh) GetVisibility() float32 {
+	return this._sourceMesh.GetVisibility()
+}
+func (this *InstancedMesh) IsInFrustrum(frustumPlanes []*math32.Plane) bool {
+	if this._boundingInfo == nil {
+		return false
+	}
+	return this._boundingInfo.IsInFrustrum(frustumPlanes)
+}
+
+// GetSubMeshes returns the submeshes of the source mesh
+func (this *InstancedMesh) GetSubMeshes() []ISubMesh {
+	return this._sourceMesh.GetSubMeshes()
+}
+
+// GetLOD returns the level of detail of the source mesh drawn for the
+// instance at the distance of the camera, nil when it is culled
+func (this *InstancedMesh) GetLOD(camera ICamera) IMesh {
+	return this._sourceMesh._getLOD(camera, this._boundingInfo)
+}
+
+func (this *InstancedMesh) GetSkeleton() ISkeleton {
+	return this._sourceMesh.GetSkeleton()
+}
+
+func (this *InstancedMesh) GetMorphTargetInfluences() []float32 {
+	return this._sourceMesh.GetMorphTargetInfluences()
+}
+
+func (this *InstancedMesh) IsMorphTargetNormalsPresent() bool {
+	return this._sourceMesh.IsMorphTargetNormalsPresent()
+}
+
+func (this *InstancedMesh) Dispose() {
+	source := this._sourceMesh
+	for index, instance := range source._instances {
+		if instance == this {
+			source._instances = append(source._instances[:index], source._instances[index+1:]...)
+			break
+		}
+	}
+
+	// Remove from scene
+	index := tools.IndexOf(this, this._scene.Meshes)
+	if index > -1 {
+		this._scene.Meshes = append(this._scene.Meshes[:index], this._scene.Meshes[index+1:]...)
+	}
+
+	this._isDisposed = true
+
+	// Callback
+	if this.OnDispose != nil {
+		this.OnDispose()
+	}
+}
+
+/***  IMesh interface end ***/
@@ -0,0 +1,117 @@
+package meshs
+
+import (
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/gl"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/effects"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// LinesMesh is a mesh whose indices are pairs of points joined by a line. The
+// lines are drawn with Color, they ignore the material and the lights and the
+// visibility of the mesh is their alpha
+type LinesMesh struct {
+	*Mesh
+
+	Color *math32.Color3
+
+	_effect IEffect
+}
+
+func NewLinesMesh(name string, scene *engines.Scene) *LinesMesh {
+	this := &LinesMesh{}
+	this.Mesh = NewMesh(name, scene)
+	this.Mesh._linesMesh = this
+	this.Color = math32.NewColor3(1, 1, 1)
+
+	// Lines have no faces to pick
+	this.Ispickable = false
+
+	this._effect = effects.CreateEffect(scene.GetEngine(), "color",
+		[]string{"position"},
+		[]string{"worldViewProjection", "color"},
+		[]string{}, "", nil, nil)
+
+	return this
+}
+
+func (this *LinesMesh) _render(subMesh *SubMesh) {
+	if !this._effect.IsReady() {
+		return
+	}
+	engine := this._scene.GetEngine()
+
+	engine.EnableEffect(this._effect)
+	this._effect.SetMatrix("worldViewProjection", this.GetWorldMatrix().Multiply(this._scene.GetTransformMatrix()))
+	this._effect.SetFloat4("color", this.Color.R, this.Color.G, this.Color.B, this.Visibility)
+
+	engine.BindMultiBuffers(map[string]*gl.GLVertexBuffer{
+		IMesh_VB_PositionKind: this._vertexBuffers[IMesh_VB_PositionKind]._buffer,
+	}, this._indexBuffer, this._effect)
+	engine.Draw(false, subMesh._indexStart, subMesh._indexCount)
+}
+
+// CreateLines joins the points with a line
+func CreateLines(name string, points []*math32.Vector3, scene *engines.Scene, updatable bool) *LinesMesh {
+	lines := NewLinesMesh(name, scene)
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+
+	for index, point := range points {
+		positions = append(positions, point.X, point.Y, point.Z)
+		if index > 0 {
+			indices = append(indices, uint32(index-1), uint32(index))
+		}
+	}
+
+	lines.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
+	if err := lines.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
+
+	return lines
+}
+
+// CreateDashedLines joins the points with about dashNb dashes, the dashes and
+// the gaps between them keep the ratio of dashSize to gapSize
+func CreateDashedLines(name string, points []*math32.Vector3, dashSize float32, gapSize float32, dashNb int, scene *engines.Scene, updatable bool) *LinesMesh {
+	lines := NewLinesMesh(name, scene)
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+
+	var length float32
+	for index := 1; index < len(points); index++ {
+		length += points[index].Sub(points[index-1]).Length()
+	}
+
+	if dashNb > 0 && dashSize+gapSize > 0 && length > 0 {
+		shift := length / float32(dashNb)
+		dashShift := dashSize * shift / (dashSize + gapSize)
+
+		for index := 1; index < len(points); index++ {
+			direction := points[index].Sub(points[index-1])
+			count := int(direction.Length() / shift)
+			direction.Normalize()
+
+			for dash := 0; dash < count; dash++ {
+				start := points[index-1].Add(direction.Scale(shift * float32(dash)))
+				end := start.Add(direction.Scale(dashShift))
+
+				vertex := uint32(len(positions) / 3)
+				positions = append(positions, start.X, start.Y, start.Z, end.X, end.Y, end.Z)
+				indices = append(indices, vertex, vertex+1)
+			}
+		}
+	}
+
+	lines.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
+	if err := lines.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
+
+	return lines
+}
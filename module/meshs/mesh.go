@@ -1,8 +1,10 @@
 package meshs
 
 import (
+	"fmt"
 	"math"
 	"reflect"
+	"strconv"
 
 	"github.com/suiqirui1987/fly3d/core"
 	"github.com/suiqirui1987/fly3d/engines"
@@ -18,6 +20,8 @@ type MeshCache struct {
 	position *math32.Vector3
 	scaling  *math32.Vector3
 	rotation *math32.Vector3
+
+	rotationQuaternion *math32.Quaternion
 }
 
 type Mesh struct {
@@ -31,17 +35,30 @@ type Mesh struct {
 	Position     *math32.Vector3
 	Rotation     *math32.Vector3
 	Scaling      *math32.Vector3
+	// RotationQuaternion replaces Rotation when it is set
+	RotationQuaternion *math32.Quaternion
 	_scaleFactor float32
 
 	_vertexStrideSize int
 
-	_indices      []uint16
+	_indices      []uint32
 	SubMeshes     []*SubMesh
 	_childrenFlag bool
 
 	Material      IMaterial
 	MutilMaterial IMultiMaterial
 
+	// Skeleton deforms the vertices with their matricesIndices and
+	// matricesWeights data
+	Skeleton ISkeleton
+
+	// MorphTargets are the alternative shapes blended with the vertices
+	MorphTargets []*MorphTarget
+	// _morphTargetsDirty is set when the blended vertices need an update,
+	// _morphTargetsBlended when the vertex buffers hold blended vertices
+	_morphTargetsDirty   bool
+	_morphTargetsBlended bool
+
 	Parent          *Mesh
 	_isReady        bool
 	_isEnabled      bool
@@ -69,6 +86,19 @@ type Mesh struct {
 
 	//Animation Target
 	_animations []IAnimation
+
+	//Instances
+	_instances           []*InstancedMesh
+	_instancesBuffer     *gl.GLVertexBuffer
+	_instancesBufferSize int
+
+	// Set when the mesh is the geometry of a LinesMesh
+	_linesMesh *LinesMesh
+
+	// Levels of detail, by increasing distance
+	_LODLevels []*MeshLODLevel
+	// Set when the mesh is a level of detail of another one
+	_masterMesh *Mesh
 }
 
 func NewMesh(name string, scene *engines.Scene) *Mesh {
@@ -94,7 +124,7 @@ func (this *Mesh) Init() {
 	this.Rotation = math32.NewVector3(0, 0, 0)
 	this.Scaling = math32.NewVector3(1, 1, 1)
 
-	this._indices = make([]uint16, 0)
+	this._indices = make([]uint32, 0)
 	this.SubMeshes = make([]*SubMesh, 0)
 	this._childrenFlag = false
 
@@ -127,16 +157,31 @@ func (this *Mesh) GetScene() *engines.Scene {
 	return this._scene
 }
 
+func (this *Mesh) GetParent() IMesh {
+	if this.Parent == nil {
+		return nil
+	}
+	return this.Parent
+}
+
+// GetVerticesData returns nil when the mesh has no data of kind
 func (this *Mesh) GetVerticesData(kind string) []float32 {
-	return this._vertexBuffers[kind].GetData()
+	buffer, ok := this._vertexBuffers[kind]
+	if !ok {
+		return nil
+	}
+	return buffer.GetData()
 }
 
 func (this *Mesh) GetTotalIndices() int {
 	return len(this._indices)
 }
-func (this *Mesh) GetIndices() []uint16 {
+func (this *Mesh) GetIndices() []uint32 {
 	return this._indices
 }
+func (this *Mesh) Is32BitsIndices() bool {
+	return this._indexBuffer != nil && this._indexBuffer.Is32Bits
+}
 func (this *Mesh) GetVertexStrideSize() int {
 	return this._vertexStrideSize
 }
@@ -163,6 +208,13 @@ func (this *Mesh) IsSynchronized() bool {
 	if !this._cache.scaling.Equals(this.Scaling) {
 		return false
 	}
+	if this.RotationQuaternion != nil {
+		if this._cache.rotationQuaternion == nil || !this._cache.rotationQuaternion.Equals(this.RotationQuaternion) {
+			return false
+		}
+	} else if this._cache.rotationQuaternion != nil {
+		return false
+	}
 	if this.Parent != nil {
 		return !this.Parent._needToSynchonizeChildren()
 	}
@@ -182,6 +234,10 @@ func (this *Mesh) IsDisposed() bool {
 }
 
 func (this *Mesh) _computeWorldMatrix() *math32.Matrix4 {
+	if this._masterMesh != nil {
+		return this._computeLODWorldMatrix()
+	}
+
 	if this.IsSynchronized() {
 		this._childrenFlag = false
 		return this._worldMatrix
@@ -194,9 +250,18 @@ func (this *Mesh) _computeWorldMatrix() *math32.Matrix4 {
 	this._cache.position = this.Position.Clone()
 	this._cache.rotation = this.Rotation.Clone()
 	this._cache.scaling = this.Scaling.Clone()
+	this._cache.rotationQuaternion = nil
 
-	localScaling := math32.NewMatrix4().RotationYawPitchRoll(this.Rotation.Y, this.Rotation.X, this.Rotation.Z)
-	localRotation := math32.NewMatrix4().Scaling(this.Scaling.X, this.Scaling.Y, this.Scaling.Z)
+	var localRotation *math32.Matrix4
+	if this.RotationQuaternion != nil {
+		this._cache.rotationQuaternion = this.RotationQuaternion.Clone()
+
+		localRotation = math32.NewMatrix4()
+		this.RotationQuaternion.ToRotationMatrix(localRotation)
+	} else {
+		localRotation = math32.NewMatrix4().RotationYawPitchRoll(this.Rotation.Y, this.Rotation.X, this.Rotation.Z)
+	}
+	localScaling := math32.NewMatrix4().Scaling(this.Scaling.X, this.Scaling.Y, this.Scaling.Z)
 
 	localScalingRotation := localScaling.Multiply(localRotation)
 
@@ -305,6 +370,8 @@ func (this *Mesh) SetVerticesData(data []float32, kind string, updatable bool) {
 	}
 
 	this._vertexBuffers[kind] = NewVertexBuffer(this, data, kind, updatable)
+	this._morphTargetsBlended = false
+	this._morphTargetsDirty = true
 
 	if kind == IMesh_VB_PositionKind {
 		stride := this._vertexBuffers[kind].GetStrideSize()
@@ -319,23 +386,43 @@ func (this *Mesh) SetVerticesData(data []float32, kind string, updatable bool) {
 func (this *Mesh) UpdateVertices(kind string, data []float32) {
 	if _, ok := this._vertexBuffers[kind]; ok {
 		this._vertexBuffers[kind].Update(data)
+		this._morphTargetsBlended = false
+		this._morphTargetsDirty = true
 	}
 }
 
-func (this *Mesh) SetIndices(indices []uint16) {
+// SetIndices fails when the indices need 32 bits and the engine only has 16
+// bits index buffers, the mesh is then left without indices and not drawn
+func (this *Mesh) SetIndices(indices []uint32) error {
 	engine := this._scene.GetEngine()
 
 	if this._indexBuffer != nil {
 		engine.ReleaseIndexBuffer(this._indexBuffer)
+		this._indexBuffer = nil
 	}
 
-	this._indexBuffer = engine.CreateIndexBuffer(indices, false)
+	indexBuffer, err := engine.CreateIndexBuffer(indices, false)
+	if err != nil {
+		this._indices = nil
+		this.SubMeshes = make([]*SubMesh, 0)
+		return fmt.Errorf("mesh %s: %s", this.Name, err)
+	}
+
+	this._indexBuffer = indexBuffer
 	this._indices = indices
 
 	this.CreateGlobalSubMesh()
+
+	return nil
 }
 
 func (this *Mesh) BindAndDraw(subMesh *SubMesh, effect IEffect, wireframe bool) {
+	this._bindAndDraw(subMesh, effect, wireframe, nil, 0)
+}
+
+// _bindAndDraw draws the submesh, instancesCount times with the instances
+// world matrices bound to the locations when instancesCount is not 0
+func (this *Mesh) _bindAndDraw(subMesh *SubMesh, effect IEffect, wireframe bool, locations []gl.Attrib, instancesCount int) {
 	engine := this._scene.GetEngine()
 
 	// Wireframe
@@ -346,21 +433,60 @@ func (this *Mesh) BindAndDraw(subMesh *SubMesh, effect IEffect, wireframe bool)
 		indexToBind = subMesh.GetLinesIndexBuffer(this._indices, engine)
 		useTriangles = false
 	}
+	if indexToBind == nil {
+		return
+	}
+
+	// Morph targets the vertex shaders cannot blend, shadow maps included
+	this._updateMorphTargets()
 
 	glvertexBuffers := map[string]*gl.GLVertexBuffer{}
 	for key, val := range this._vertexBuffers {
 		glvertexBuffers[key] = val._buffer
 	}
+	if this._morphTargetsOnGPU() {
+		for index, target := range this.MorphTargets {
+			glvertexBuffers[IMesh_VB_PositionKind+strconv.Itoa(index)] = target._positions._buffer
+			if target._normals != nil {
+				glvertexBuffers[IMesh_VB_NormalKind+strconv.Itoa(index)] = target._normals._buffer
+			}
+		}
+	}
 
 	// VBOs
 	engine.BindMultiBuffers(glvertexBuffers, indexToBind, effect)
 
+	indexStart, indexCount := subMesh._indexStart, subMesh._indexCount
+	if !useTriangles {
+		indexStart, indexCount = 0, subMesh._linesIndexCount
+	}
+
 	// Draw order
-	if useTriangles {
-		engine.Draw(true, subMesh._indexStart, subMesh._indexCount)
-	} else {
-		engine.Draw(false, 0, subMesh._linesIndexCount)
+	if instancesCount == 0 {
+		engine.Draw(useTriangles, indexStart, indexCount)
+		return
+	}
+
+	engine.BindInstancesBuffer(this._instancesBuffer, locations)
+	engine.DrawInstanced(useTriangles, indexStart, indexCount, instancesCount)
+	engine.UnBindInstancesBuffer(locations)
+}
+
+// _updateInstancesBuffer uploads the world matrices, growing the buffer when
+// they do not fit
+func (this *Mesh) _updateInstancesBuffer(matrices []float32) {
+	engine := this._scene.GetEngine()
+
+	size := len(matrices) * 4
+	if this._instancesBuffer == nil || this._instancesBufferSize < size {
+		if this._instancesBuffer != nil {
+			engine.ReleaseVertexBuffer(this._instancesBuffer)
+		}
+		this._instancesBufferSize = size * 2
+		this._instancesBuffer = engine.CreateDynamicVertexBuffer(this._instancesBufferSize)
 	}
+
+	engine.UpdateDynamicVertexBuffer(this._instancesBuffer, matrices)
 }
 
 func (this *Mesh) Render(submesh ISubMesh) {
@@ -371,15 +497,29 @@ func (this *Mesh) Render(submesh ISubMesh) {
 		return
 	}
 
+	if this._linesMesh != nil {
+		this._linesMesh._render(subMesh)
+		return
+	}
+
 	engine := this._scene.GetEngine()
 
 	// World
 	world := this.GetWorldMatrix()
 
+	// Instances, a mesh that was not evaluated by the scene draws itself
+	renderSelf := true
+	var instances []IInstancedMesh
+	if batch := this._scene.GetInstancesBatch(this); batch != nil {
+		renderSelf = batch.RenderSelf
+		instances = batch.Instances
+	}
+	hardwareInstancedRendering := engine.GetCaps().InstancedArrays && len(instances) > 0
+
 	// Material
 	effectiveMaterial := subMesh.GetMaterial()
 
-	if effectiveMaterial == nil || !effectiveMaterial.IsReady(this) {
+	if effectiveMaterial == nil || !effectiveMaterial.IsReady(this, hardwareInstancedRendering) {
 		return
 	}
 
@@ -390,8 +530,40 @@ func (this *Mesh) Render(submesh ISubMesh) {
 	if engine.ForceWireframe || effectiveMaterial.HasWireframe() {
 		haswireframe = true
 	}
-	// Bind and draw
-	this.BindAndDraw(subMesh, effectiveMaterial.GetEffect(), haswireframe)
+	effect := effectiveMaterial.GetEffect()
+
+	if hardwareInstancedRendering {
+		// One world matrix per drawn instance
+		matrices := make([]float32, 0, 16*(len(instances)+1))
+		if renderSelf {
+			matrices = append(matrices, world[:]...)
+		}
+		for _, instance := range instances {
+			matrices = append(matrices, instance.GetWorldMatrix()[:]...)
+		}
+		this._updateInstancesBuffer(matrices)
+
+		locations := make([]gl.Attrib, 4)
+		for index, name := range effect.GetAttributesNames() {
+			for row := 0; row < 4; row++ {
+				if name == "world"+strconv.Itoa(row) {
+					locations[row] = effect.GetAttribute(index)
+				}
+			}
+		}
+
+		// Bind and draw
+		this._bindAndDraw(subMesh, effect, haswireframe, locations, len(matrices)/16)
+	} else {
+		// Bind and draw
+		if renderSelf {
+			this.BindAndDraw(subMesh, effect, haswireframe)
+		}
+		for _, instance := range instances {
+			effectiveMaterial.Bind(instance.GetWorldMatrix(), this)
+			this.BindAndDraw(subMesh, effect, haswireframe)
+		}
+	}
 
 	// UnBind
 	effectiveMaterial.UnBind()
@@ -483,6 +655,135 @@ func (this *Mesh) SetMaterialByID(id string) {
 	}
 }
 
+// AddMorphTarget adds a shape with the same vertices count as the mesh,
+// normals are optional
+func (this *Mesh) AddMorphTarget(name string, positions []float32, normals []float32) *MorphTarget {
+	if len(positions) != this._totalVertices*3 || (len(normals) > 0 && len(normals) != this._totalVertices*3) {
+		log.Printf("Mesh %s: morph target %s does not match the %d vertices", this.Name, name, this._totalVertices)
+		return nil
+	}
+	return NewMorphTarget(name, this, positions, normals)
+}
+
+func (this *Mesh) GetMorphTargetByName(name string) *MorphTarget {
+	for _, target := range this.MorphTargets {
+		if target.Name == name {
+			return target
+		}
+	}
+	return nil
+}
+
+// _morphTargetsNormals tells whether all the morph targets have normals
+func (this *Mesh) _morphTargetsNormals() bool {
+	if !this.IsVerticesDataPresent(IMesh_VB_NormalKind) {
+		return false
+	}
+	for _, target := range this.MorphTargets {
+		if target._normals == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// _morphTargetsOnGPU tells whether the vertex shaders can blend all the
+// morph targets with the vertex attributes left by the mesh data
+func (this *Mesh) _morphTargetsOnGPU() bool {
+	if len(this.MorphTargets) == 0 || len(this.MorphTargets) > MorphTargetsMaxGPU {
+		return false
+	}
+
+	attributes := len(this._vertexBuffers)
+	if len(this._instances) > 0 {
+		attributes += 4
+	}
+	if this._morphTargetsNormals() {
+		attributes += 2 * len(this.MorphTargets)
+	} else {
+		attributes += len(this.MorphTargets)
+	}
+
+	return attributes <= this._scene.GetEngine().GetCaps().MaxVertexAttribs
+}
+
+// _isDeformed tells whether the vertices may leave the bounding info, which
+// is computed with the base shape
+func (this *Mesh) _isDeformed() bool {
+	return this._isSkinned() || this._isMorphed()
+}
+
+func (this *Mesh) _isMorphed() bool {
+	for _, target := range this.MorphTargets {
+		if target._influence != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// _blendMorphTargets moves the positions, or the normals, toward the morph
+// targets by their influences
+func (this *Mesh) _blendMorphTargets(data []float32, normals bool) []float32 {
+	result := make([]float32, len(data))
+	copy(result, data)
+
+	for _, target := range this.MorphTargets {
+		if target._influence == 0 {
+			continue
+		}
+		targetData := target.GetPositions()
+		if normals {
+			targetData = target.GetNormals()
+		}
+		if len(targetData) != len(data) {
+			continue
+		}
+		for index := range result {
+			result[index] += (targetData[index] - data[index]) * target._influence
+		}
+	}
+
+	return result
+}
+
+// _updateMorphTargets uploads the blended vertices when the vertex shaders
+// cannot blend the morph targets, the vertex buffers keep the base shape data
+func (this *Mesh) _updateMorphTargets() {
+	if !this._morphTargetsDirty {
+		return
+	}
+	this._morphTargetsDirty = false
+
+	blend := len(this.MorphTargets) > 0 && !this._morphTargetsOnGPU()
+	if !blend && !this._morphTargetsBlended {
+		return
+	}
+	this._morphTargetsBlended = blend
+
+	engine := this._scene.GetEngine()
+	for _, kind := range []string{IMesh_VB_PositionKind, IMesh_VB_NormalKind} {
+		buffer, ok := this._vertexBuffers[kind]
+		if !ok {
+			continue
+		}
+		data := buffer.GetData()
+		if blend {
+			data = this._blendMorphTargets(data, kind == IMesh_VB_NormalKind)
+		}
+		engine.UpdateDynamicVertexBuffer(buffer._buffer, data)
+	}
+}
+
+// CreateInstance creates an instance sharing the geometry and the material of
+// the mesh
+func (this *Mesh) CreateInstance(name string) *InstancedMesh {
+	return NewInstancedMesh(name, this)
+}
+func (this *Mesh) GetInstances() []*InstancedMesh {
+	return this._instances
+}
+
 // Cache
 func (this *Mesh) _resetPointsArrayCache() {
 	this._cache_positions = nil
@@ -501,18 +802,63 @@ func (this *Mesh) _generatePointsArray() {
 	}
 }
 
+func (this *Mesh) _isSkinned() bool {
+	return this.Skeleton != nil && this.IsVerticesDataPresent(IMesh_VB_MatricesIndicesKind) && this.IsVerticesDataPresent(IMesh_VB_MatricesWeightsKind)
+}
+
+// _getPositions returns the vertices blended with the morph targets and in
+// the current pose of the skeleton, the cached positions when they are not
+// deformed
+func (this *Mesh) _getPositions() []*math32.Vector3 {
+	this._generatePointsArray()
+	positions := this._cache_positions
+
+	if this._isMorphed() {
+		data := this._blendMorphTargets(this._vertexBuffers[IMesh_VB_PositionKind].GetData(), false)
+		positions = make([]*math32.Vector3, 0, len(data)/3)
+		for index := 0; index < len(data); index += 3 {
+			positions = append(positions, math32.NewVector3Zero().FromArray(data, index))
+		}
+	}
+
+	if !this._isSkinned() {
+		return positions
+	}
+
+	matrices := this.Skeleton.GetTransformMatrices()
+	matricesIndices := this._vertexBuffers[IMesh_VB_MatricesIndicesKind].GetData()
+	matricesWeights := this._vertexBuffers[IMesh_VB_MatricesWeightsKind].GetData()
+
+	matrix := math32.NewMatrix4()
+	skinned := make([]*math32.Vector3, len(positions))
+	for index, position := range positions {
+		skinned[index] = math32.NewVector3Zero()
+		for influence := index * 4; influence < index*4+4 && influence < len(matricesWeights); influence++ {
+			weight := matricesWeights[influence]
+			offset := int(matricesIndices[influence]) * 16
+			if weight == 0 || offset+16 > len(matrices) {
+				continue
+			}
+			copy(matrix[:], matrices[offset:offset+16])
+			skinned[index] = skinned[index].Add(position.TransformCoordinates(matrix).Scale(weight))
+		}
+	}
+
+	return skinned
+}
+
 //Collisions
 func (this *Mesh) _collideForSubMesh(subMesh *SubMesh, transformMatrix *math32.Matrix4, collider ICollider) {
-	this._generatePointsArray()
 	// Transformation
-	if subMesh._lastColliderWorldVertices == nil || !reflect.DeepEqual(subMesh._lastColliderTransformMatrix, transformMatrix) {
+	if this._isDeformed() || subMesh._lastColliderWorldVertices == nil || !reflect.DeepEqual(subMesh._lastColliderTransformMatrix, transformMatrix) {
+		positions := this._getPositions()
 		subMesh._lastColliderTransformMatrix = transformMatrix
 		subMesh._lastColliderWorldVertices = make([]*math32.Vector3, 0)
 
 		start := subMesh._verticesStart
 		end := (subMesh._verticesStart + subMesh._verticesCount)
 		for i := start; i < end; i++ {
-			pos := this._cache_positions[i].TransformCoordinates(transformMatrix)
+			pos := positions[i].TransformCoordinates(transformMatrix)
 			subMesh._lastColliderWorldVertices = append(subMesh._lastColliderWorldVertices, pos)
 		}
 	}
@@ -576,10 +922,13 @@ func (this *Mesh) AddAnimation(val IAnimation) {
 
 	this._animations = append(this._animations, val)
 }
+func (this *Mesh) SetAnimations(val []IAnimation) {
+	this._animations = val
+}
 func (this *Mesh) GetAnimations() []IAnimation {
 	return this._animations
 }
-func (this *Mesh) GetAnimatables() []IAnimatable {
+func (this *Mesh) GetAnimatables() []IAnimationTarget {
 	return nil
 }
 
@@ -637,22 +986,28 @@ func (this *Mesh) IsPickable() bool {
 }
 
 func (this *Mesh) Intersects(ray *math32.Ray) *math32.RayIntersectsResult {
-	if this._boundingInfo == nil || !ray.IntersectsSphere(this._boundingInfo.Sphere.GetSphere()) {
+	// The bounding info is computed with the base shape
+	if this._boundingInfo == nil || (!this._isDeformed() && !ray.IntersectsSphere(this._boundingInfo.Sphere.GetSphere())) {
 		return &math32.RayIntersectsResult{Hit: false, Distance: 0}
 	}
 
-	this._generatePointsArray()
+	return this._intersectsSubMeshes(ray)
+}
+
+// _intersectsSubMeshes intersects the geometry with a ray in the mesh space
+func (this *Mesh) _intersectsSubMeshes(ray *math32.Ray) *math32.RayIntersectsResult {
+	positions := this._getPositions()
 
 	var distance float32
 	distance = math.MaxFloat32
 
 	for _, subMesh := range this.SubMeshes {
 		// Bounding test
-		if len(this.SubMeshes) > 1 && !subMesh.CanIntersects(ray) {
+		if len(this.SubMeshes) > 1 && !this._isDeformed() && !subMesh.CanIntersects(ray) {
 			continue
 		}
 
-		result := subMesh.Intersects(ray, this._cache_positions, this._indices)
+		result := subMesh.Intersects(ray, positions, this._indices)
 
 		if result.Hit {
 			if result.Distance < distance && result.Distance >= 0 {
@@ -676,6 +1031,9 @@ func (this *Mesh) GetVisibility() float32 {
 	return this.Visibility
 }
 func (this *Mesh) IsInFrustrum(frustumPlanes []*math32.Plane) bool {
+	if this._boundingInfo == nil {
+		return false
+	}
 	return this._boundingInfo.IsInFrustrum(frustumPlanes)
 }
 
@@ -687,7 +1045,45 @@ func (this *Mesh) GetSubMeshes() []ISubMesh {
 	return submeshs
 }
 
+func (this *Mesh) GetSkeleton() ISkeleton {
+	return this.Skeleton
+}
+
+func (this *Mesh) GetMorphTargetInfluences() []float32 {
+	if !this._morphTargetsOnGPU() {
+		return nil
+	}
+
+	influences := make([]float32, len(this.MorphTargets))
+	for index, target := range this.MorphTargets {
+		influences[index] = target._influence
+	}
+	return influences
+}
+
+func (this *Mesh) IsMorphTargetNormalsPresent() bool {
+	return this._morphTargetsOnGPU() && this._morphTargetsNormals()
+}
+
 func (this *Mesh) Dispose() {
+	// Instances
+	for len(this._instances) > 0 {
+		this._instances[0].Dispose()
+	}
+
+	if this._instancesBuffer != nil {
+		this._scene.GetEngine().ReleaseVertexBuffer(this._instancesBuffer)
+		this._instancesBuffer = nil
+	}
+
+	// Morph targets
+	for len(this.MorphTargets) > 0 {
+		this.MorphTargets[0].Dispose()
+	}
+
+	// Levels of detail
+	this._disposeLODLevels()
+
 	if this._vertexBuffers != nil {
 		for _, vb := range this._vertexBuffers {
 			this._scene.GetEngine().ReleaseVertexBuffer(vb._buffer)
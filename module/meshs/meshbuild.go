@@ -2,10 +2,12 @@ package meshs
 
 import (
 	"math"
+	"sort"
 
 	"github.com/suiqirui1987/fly3d/engines"
 	. "github.com/suiqirui1987/fly3d/interfaces"
 	"github.com/suiqirui1987/fly3d/math32"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
 )
 
 func CreateBox(name string, size float32, scene *engines.Scene, updatable bool) *Mesh {
@@ -20,7 +22,7 @@ func CreateBox(name string, size float32, scene *engines.Scene, updatable bool)
 		math32.NewVector3(0, -1, 0),
 	}
 
-	indices := make([]uint16, 0)
+	indices := make([]uint32, 0)
 	positions := make([]float32, 0)
 	normals := make([]float32, 0)
 	uvs := make([]float32, 0)
@@ -34,7 +36,7 @@ func CreateBox(name string, size float32, scene *engines.Scene, updatable bool)
 		side2 := normal.Cross(side1)
 
 		// Six indices (two triangles) per face.
-		verticesLength := uint16(len(positions) / 3)
+		verticesLength := uint32(len(positions) / 3)
 		indices = append(indices, verticesLength)
 		indices = append(indices, verticesLength+1)
 		indices = append(indices, verticesLength+2)
@@ -68,7 +70,9 @@ func CreateBox(name string, size float32, scene *engines.Scene, updatable bool)
 	box.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
 	box.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
 	box.SetVerticesData(uvs, IMesh_VB_UVKind, updatable)
-	box.SetIndices(indices)
+	if err := box.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
 
 	return box
 }
@@ -76,14 +80,14 @@ func CreateBox(name string, size float32, scene *engines.Scene, updatable bool)
 func CreateSphere(name string, segments int, diameter float32, scene *engines.Scene, updatable bool) *Mesh {
 	sphere := NewMesh(name, scene)
 
-	var totalZRotationSteps, totalYRotationSteps, zRotationStep, yRotationStep, verticesCount uint16
+	var totalZRotationSteps, totalYRotationSteps, zRotationStep, yRotationStep, verticesCount uint32
 
 	radius := float32(diameter) / 2.0
 
-	totalZRotationSteps = uint16(2 + segments)
-	totalYRotationSteps = uint16(2 * totalZRotationSteps)
+	totalZRotationSteps = uint32(2 + segments)
+	totalYRotationSteps = uint32(2 * totalZRotationSteps)
 
-	indices := make([]uint16, 0)
+	indices := make([]uint32, 0)
 	positions := make([]float32, 0)
 	normals := make([]float32, 0)
 	uvs := make([]float32, 0)
@@ -112,8 +116,8 @@ func CreateSphere(name string, segments int, diameter float32, scene *engines.Sc
 
 		if zRotationStep > 0 {
 
-			verticesCount = uint16(len(positions) / 3)
-			var firstIndex uint16
+			verticesCount = uint32(len(positions) / 3)
+			var firstIndex uint32
 			for firstIndex = verticesCount - 2*(totalYRotationSteps+1); (firstIndex + totalYRotationSteps + 2) < verticesCount; firstIndex++ {
 				indices = append(indices, (firstIndex))
 				indices = append(indices, (firstIndex + 1))
@@ -129,7 +133,9 @@ func CreateSphere(name string, segments int, diameter float32, scene *engines.Sc
 	sphere.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
 	sphere.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
 	sphere.SetVerticesData(uvs, IMesh_VB_UVKind, updatable)
-	sphere.SetIndices(indices)
+	if err := sphere.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
 
 	return sphere
 }
@@ -137,7 +143,7 @@ func CreateSphere(name string, segments int, diameter float32, scene *engines.Sc
 func CreatePlane(name string, size int, scene *engines.Scene, updatable bool) *Mesh {
 	plane := NewMesh(name, scene)
 
-	indices := make([]uint16, 0)
+	indices := make([]uint32, 0)
 	positions := make([]float32, 0)
 	normals := make([]float32, 0)
 	uvs := make([]float32, 0)
@@ -160,18 +166,20 @@ func CreatePlane(name string, size int, scene *engines.Scene, updatable bool) *M
 	uvs = append(uvs, 0.0, 1.0)
 
 	// Indices
-	indices = append(indices, uint16(0))
-	indices = append(indices, uint16(1))
-	indices = append(indices, uint16(2))
+	indices = append(indices, uint32(0))
+	indices = append(indices, uint32(1))
+	indices = append(indices, uint32(2))
 
-	indices = append(indices, uint16(0))
-	indices = append(indices, uint16(2))
-	indices = append(indices, uint16(3))
+	indices = append(indices, uint32(0))
+	indices = append(indices, uint32(2))
+	indices = append(indices, uint32(3))
 
 	plane.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
 	plane.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
 	plane.SetVerticesData(uvs, IMesh_VB_UVKind, updatable)
-	plane.SetIndices(indices)
+	if err := plane.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
 
 	return plane
 }
@@ -179,7 +187,7 @@ func CreatePlane(name string, size int, scene *engines.Scene, updatable bool) *M
 func CreateGround(name string, width int, height int, subdivisions int, scene *engines.Scene, updatable bool) *Mesh {
 	ground := NewMesh(name, scene)
 
-	indices := make([]uint16, 0)
+	indices := make([]uint32, 0)
 	positions := make([]float32, 0)
 	normals := make([]float32, 0)
 	uvs := make([]float32, 0)
@@ -203,20 +211,22 @@ func CreateGround(name string, width int, height int, subdivisions int, scene *e
 
 	for row = 0; row < subdivisions; row++ {
 		for col = 0; col < subdivisions; col++ {
-			indices = append(indices, uint16(col+1+(row+1)*(subdivisions+1)))
-			indices = append(indices, uint16(col+1+row*(subdivisions+1)))
-			indices = append(indices, uint16(col+row*(subdivisions+1)))
+			indices = append(indices, uint32(col+1+(row+1)*(subdivisions+1)))
+			indices = append(indices, uint32(col+1+row*(subdivisions+1)))
+			indices = append(indices, uint32(col+row*(subdivisions+1)))
 
-			indices = append(indices, uint16(col+(row+1)*(subdivisions+1)))
-			indices = append(indices, uint16(col+1+(row+1)*(subdivisions+1)))
-			indices = append(indices, uint16(col+row*(subdivisions+1)))
+			indices = append(indices, uint32(col+(row+1)*(subdivisions+1)))
+			indices = append(indices, uint32(col+1+(row+1)*(subdivisions+1)))
+			indices = append(indices, uint32(col+row*(subdivisions+1)))
 		}
 	}
 
 	ground.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
 	ground.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
 	ground.SetVerticesData(uvs, IMesh_VB_UVKind, updatable)
-	ground.SetIndices(indices)
+	if err := ground.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
 
 	return ground
 }
@@ -226,7 +236,7 @@ func CreateTorus(name string, diameter float32, thickness float32, tessellation
 
 	torus := NewMesh(name, scene)
 
-	indices := make([]uint16, 0)
+	indices := make([]uint32, 0)
 	positions := make([]float32, 0)
 	normals := make([]float32, 0)
 	uvs := make([]float32, 0)
@@ -266,21 +276,778 @@ func CreateTorus(name string, diameter float32, thickness float32, tessellation
 			nextI := (i + 1) % stride
 			nextJ := (j + 1) % stride
 
-			indices = append(indices, uint16(i*stride+j))
-			indices = append(indices, uint16(i*stride+nextJ))
-			indices = append(indices, uint16(nextI*stride+j))
+			indices = append(indices, uint32(i*stride+j))
+			indices = append(indices, uint32(i*stride+nextJ))
+			indices = append(indices, uint32(nextI*stride+j))
 
-			indices = append(indices, uint16(i*stride+nextJ))
-			indices = append(indices, uint16(nextI*stride+nextJ))
-			indices = append(indices, uint16(nextI*stride+j))
+			indices = append(indices, uint32(i*stride+nextJ))
+			indices = append(indices, uint32(nextI*stride+nextJ))
+			indices = append(indices, uint32(nextI*stride+j))
 		}
 	}
 
 	torus.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
 	torus.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
 	torus.SetVerticesData(uvs, IMesh_VB_UVKind, updatable)
-	torus.SetIndices(indices)
+	if err := torus.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
 
 	return torus
 
 }
+
+// CreateCylinder builds a cylinder along the Y axis, a diameter of 0 at the top
+// makes a cone. The side is split into subdivisions rings
+func CreateCylinder(name string, height float32, diameterTop float32, diameterBottom float32, tessellation int, subdivisions int, scene *engines.Scene, updatable bool) *Mesh {
+	cylinder := NewMesh(name, scene)
+
+	if subdivisions < 1 {
+		subdivisions = 1
+	}
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	radiusTop := diameterTop / 2.0
+	radiusBottom := diameterBottom / 2.0
+	stride := uint32(tessellation + 1)
+
+	// Side, from the top ring to the bottom one
+	for row := 0; row <= subdivisions; row++ {
+		ratio := float32(row) / float32(subdivisions)
+		y := height/2.0 - ratio*height
+		radius := radiusTop + (radiusBottom-radiusTop)*ratio
+
+		for column := 0; column <= tessellation; column++ {
+			angle := float32(column) * math.Pi * 2.0 / float32(tessellation)
+			cos := math32.Cos(angle)
+			sin := math32.Sin(angle)
+
+			normal := math32.NewVector3(cos*height, radiusBottom-radiusTop, sin*height)
+			normal.Normalize()
+
+			positions = append(positions, cos*radius, y, sin*radius)
+			normals = append(normals, normal.X, normal.Y, normal.Z)
+			uvs = append(uvs, float32(column)/float32(tessellation), ratio)
+		}
+
+		if row > 0 {
+			first := uint32((row - 1) * (tessellation + 1))
+			for column := uint32(0); column < uint32(tessellation); column++ {
+				indices = append(indices, first+column, first+stride+column, first+column+1)
+				indices = append(indices, first+column+1, first+stride+column, first+stride+column+1)
+			}
+		}
+	}
+
+	// Caps
+	createCap := func(y float32, radius float32, top bool) {
+		if radius <= 0 {
+			return
+		}
+
+		var ny float32 = -1.0
+		if top {
+			ny = 1.0
+		}
+
+		center := uint32(len(positions) / 3)
+		positions = append(positions, 0, y, 0)
+		normals = append(normals, 0, ny, 0)
+		uvs = append(uvs, 0.5, 0.5)
+
+		for column := 0; column < tessellation; column++ {
+			angle := float32(column) * math.Pi * 2.0 / float32(tessellation)
+			cos := math32.Cos(angle)
+			sin := math32.Sin(angle)
+
+			positions = append(positions, cos*radius, y, sin*radius)
+			normals = append(normals, 0, ny, 0)
+			uvs = append(uvs, cos*0.5+0.5, sin*0.5+0.5)
+
+			current := center + 1 + uint32(column)
+			next := center + 1 + uint32((column+1)%tessellation)
+			if top {
+				indices = append(indices, center, current, next)
+			} else {
+				indices = append(indices, center, next, current)
+			}
+		}
+	}
+	createCap(height/2.0, radiusTop, true)
+	createCap(-height/2.0, radiusBottom, false)
+
+	setVertexData(cylinder, positions, normals, uvs, indices, updatable)
+
+	return cylinder
+}
+
+// CreateDisc builds a disc in the XY plane facing -Z like CreatePlane
+func CreateDisc(name string, radius float32, tessellation int, scene *engines.Scene, updatable bool) *Mesh {
+	disc := NewMesh(name, scene)
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	positions = append(positions, 0, 0, 0)
+	normals = append(normals, 0, 0, -1.0)
+	uvs = append(uvs, 0.5, 0.5)
+
+	for index := 0; index < tessellation; index++ {
+		angle := float32(index) * math.Pi * 2.0 / float32(tessellation)
+		cos := math32.Cos(angle)
+		sin := math32.Sin(angle)
+
+		positions = append(positions, cos*radius, sin*radius, 0)
+		normals = append(normals, 0, 0, -1.0)
+		uvs = append(uvs, cos*0.5+0.5, sin*0.5+0.5)
+
+		indices = append(indices, 0, uint32(index+1), uint32((index+1)%tessellation+1))
+	}
+
+	setVertexData(disc, positions, normals, uvs, indices, updatable)
+
+	return disc
+}
+
+// CreateCapsule builds a cylinder along the Y axis ended by two half spheres,
+// height includes the half spheres. Each half sphere is split into rings
+func CreateCapsule(name string, height float32, radius float32, tessellation int, rings int, scene *engines.Scene, updatable bool) *Mesh {
+	capsule := NewMesh(name, scene)
+
+	if rings < 1 {
+		rings = 1
+	}
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	// Half the length of the cylinder between the centers of the half spheres
+	center := math32.Max(height/2.0-radius, 0)
+	length := math.Pi*radius + 2.0*center
+	stride := uint32(tessellation + 1)
+
+	// The rings of the top half sphere then the ones of the bottom half sphere
+	for row := 0; row <= 2*rings+1; row++ {
+		var angle, y, distance float32
+		if row <= rings {
+			angle = float32(row) / float32(rings) * math.Pi / 2.0
+			y = center
+			distance = angle * radius
+		} else {
+			angle = float32(row-1) / float32(rings) * math.Pi / 2.0
+			y = -center
+			distance = angle*radius + 2.0*center
+		}
+		ringRadius := math32.Sin(angle)
+		ringY := math32.Cos(angle)
+
+		for column := 0; column <= tessellation; column++ {
+			theta := float32(column) * math.Pi * 2.0 / float32(tessellation)
+			normal := math32.NewVector3(ringRadius*math32.Cos(theta), ringY, ringRadius*math32.Sin(theta))
+
+			positions = append(positions, normal.X*radius, y+normal.Y*radius, normal.Z*radius)
+			normals = append(normals, normal.X, normal.Y, normal.Z)
+			uvs = append(uvs, float32(column)/float32(tessellation), distance/length)
+		}
+
+		if row > 0 {
+			first := uint32(row-1) * stride
+			for column := uint32(0); column < uint32(tessellation); column++ {
+				indices = append(indices, first+column, first+stride+column, first+column+1)
+				indices = append(indices, first+column+1, first+stride+column, first+stride+column+1)
+			}
+		}
+	}
+
+	setVertexData(capsule, positions, normals, uvs, indices, updatable)
+
+	return capsule
+}
+
+// icosahedron returns the unit vertices and the faces of an icosahedron
+func icosahedron() ([]*math32.Vector3, [][]int) {
+	t := float32((1.0 + math.Sqrt(5.0)) / 2.0)
+
+	vertices := []*math32.Vector3{
+		math32.NewVector3(-1, t, 0), math32.NewVector3(1, t, 0), math32.NewVector3(-1, -t, 0), math32.NewVector3(1, -t, 0),
+		math32.NewVector3(0, -1, t), math32.NewVector3(0, 1, t), math32.NewVector3(0, -1, -t), math32.NewVector3(0, 1, -t),
+		math32.NewVector3(t, 0, -1), math32.NewVector3(t, 0, 1), math32.NewVector3(-t, 0, -1), math32.NewVector3(-t, 0, 1),
+	}
+	for _, vertex := range vertices {
+		vertex.Normalize()
+	}
+
+	faces := [][]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+
+	return vertices, faces
+}
+
+// orientFace orders the vertices of a face of a solid centered on the origin
+// so that it faces outward
+func orientFace(vertices []*math32.Vector3, face []int) []int {
+	a, b, c := vertices[face[0]], vertices[face[1]], vertices[face[2]]
+	center := a.Add(b).Add(c)
+
+	// Front faces are clockwise
+	if b.Sub(a).Cross(c.Sub(a)).Dot(center) > 0 {
+		reversed := make([]int, len(face))
+		for index, vertex := range face {
+			reversed[len(face)-1-index] = vertex
+		}
+		return reversed
+	}
+	return face
+}
+
+// sphereUV maps a point of a unit sphere like CreateSphere, u goes from the
+// top to the bottom and v around the Y axis
+func sphereUV(point *math32.Vector3) (float32, float32) {
+	u := math32.Acos(math32.Clamp(point.Y, -1, 1)) / math.Pi
+	v := math32.Atan2(-point.Z, point.X) / (2.0 * math.Pi)
+	if v < 0 {
+		v += 1.0
+	}
+	return u, v
+}
+
+// CreateIcoSphere builds a sphere from an icosahedron whose faces are split
+// subdivisions times, its triangles are more regular than the CreateSphere
+// ones
+func CreateIcoSphere(name string, radius float32, subdivisions int, scene *engines.Scene, updatable bool) *Mesh {
+	icoSphere := NewMesh(name, scene)
+
+	if subdivisions < 1 {
+		subdivisions = 1
+	}
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	vertices, faces := icosahedron()
+
+	// Each triangle has its own vertices to wrap its texture coordinates
+	addTriangle := func(points ...*math32.Vector3) {
+		var us, vs [3]float32
+		for index, point := range points {
+			us[index], vs[index] = sphereUV(point)
+		}
+
+		// Across the seam
+		if math32.Max(vs[0], math32.Max(vs[1], vs[2]))-math32.Min(vs[0], math32.Min(vs[1], vs[2])) > 0.5 {
+			for index := range vs {
+				if vs[index] < 0.5 {
+					vs[index] += 1.0
+				}
+			}
+		}
+
+		// The poles take the v of their triangle
+		for index, point := range points {
+			if math32.Abs(point.Y) > 0.9999 {
+				vs[index] = (vs[(index+1)%3] + vs[(index+2)%3]) / 2.0
+			}
+		}
+
+		for index, point := range points {
+			indices = append(indices, uint32(len(positions)/3))
+			positions = append(positions, point.X*radius, point.Y*radius, point.Z*radius)
+			normals = append(normals, point.X, point.Y, point.Z)
+			uvs = append(uvs, us[index], vs[index])
+		}
+	}
+
+	for _, face := range faces {
+		face = orientFace(vertices, face)
+		a, b, c := vertices[face[0]], vertices[face[1]], vertices[face[2]]
+
+		point := func(i, j int) *math32.Vector3 {
+			result := a.Add(b.Sub(a).Scale(float32(i) / float32(subdivisions))).Add(c.Sub(a).Scale(float32(j) / float32(subdivisions)))
+			result.Normalize()
+			return result
+		}
+
+		for i := 0; i < subdivisions; i++ {
+			for j := 0; i+j < subdivisions; j++ {
+				addTriangle(point(i, j), point(i+1, j), point(i, j+1))
+				if i+j+1 < subdivisions {
+					addTriangle(point(i+1, j), point(i+1, j+1), point(i, j+1))
+				}
+			}
+		}
+	}
+
+	setVertexData(icoSphere, positions, normals, uvs, indices, updatable)
+
+	return icoSphere
+}
+
+// polyhedron returns the unit vertices and the faces of a polyhedron type
+func polyhedron(polyhedronType int) ([]*math32.Vector3, [][]int) {
+	switch polyhedronType {
+	case POLYHEDRON_TETRAHEDRON:
+		vertices := []*math32.Vector3{
+			math32.NewVector3(1, 1, 1), math32.NewVector3(1, -1, -1), math32.NewVector3(-1, 1, -1), math32.NewVector3(-1, -1, 1),
+		}
+		for _, vertex := range vertices {
+			vertex.Normalize()
+		}
+		return vertices, [][]int{{0, 1, 2}, {0, 1, 3}, {0, 2, 3}, {1, 2, 3}}
+
+	case POLYHEDRON_OCTAHEDRON:
+		vertices := []*math32.Vector3{
+			math32.NewVector3(1, 0, 0), math32.NewVector3(-1, 0, 0),
+			math32.NewVector3(0, 1, 0), math32.NewVector3(0, -1, 0),
+			math32.NewVector3(0, 0, 1), math32.NewVector3(0, 0, -1),
+		}
+		faces := make([][]int, 0, 8)
+		for _, x := range []int{0, 1} {
+			for _, y := range []int{2, 3} {
+				for _, z := range []int{4, 5} {
+					faces = append(faces, []int{x, y, z})
+				}
+			}
+		}
+		return vertices, faces
+
+	case POLYHEDRON_DODECAHEDRON:
+		// The dual of the icosahedron, a vertex at the center of each face
+		icoVertices, icoFaces := icosahedron()
+
+		vertices := make([]*math32.Vector3, len(icoFaces))
+		for index, face := range icoFaces {
+			vertices[index] = icoVertices[face[0]].Add(icoVertices[face[1]]).Add(icoVertices[face[2]])
+			vertices[index].Normalize()
+		}
+
+		// A face around each vertex of the icosahedron
+		faces := make([][]int, 0, len(icoVertices))
+		for vertexIndex, axis := range icoVertices {
+			face := make([]int, 0, 5)
+			for faceIndex, icoFace := range icoFaces {
+				for _, index := range icoFace {
+					if index == vertexIndex {
+						face = append(face, faceIndex)
+					}
+				}
+			}
+
+			side := vertices[face[0]].Sub(axis)
+			up := axis.Cross(side)
+			angle := func(index int) float32 {
+				offset := vertices[index].Sub(axis)
+				return math32.Atan2(offset.Dot(up), offset.Dot(side))
+			}
+			sort.Slice(face, func(i, j int) bool {
+				return angle(face[i]) < angle(face[j])
+			})
+
+			faces = append(faces, face)
+		}
+		return vertices, faces
+
+	default:
+		return icosahedron()
+	}
+}
+
+// CreatePolyhedron builds one of the POLYHEDRON_ types with flat faces, it
+// fits in a sphere of the given diameter
+func CreatePolyhedron(name string, polyhedronType int, diameter float32, scene *engines.Scene, updatable bool) *Mesh {
+	solid := NewMesh(name, scene)
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	normals := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	vertices, faces := polyhedron(polyhedronType)
+
+	for _, face := range faces {
+		face = orientFace(vertices, face)
+
+		center := math32.NewVector3Zero()
+		for _, index := range face {
+			center = center.Add(vertices[index])
+		}
+		center = center.Scale(1.0 / float32(len(face)))
+
+		normal := center.Clone()
+		normal.Normalize()
+
+		// The texture covers the circle around the face
+		side := vertices[face[0]].Sub(center)
+		size := side.Length()
+		side.Normalize()
+		up := normal.Cross(side)
+
+		first := uint32(len(positions) / 3)
+		for _, index := range face {
+			vertex := vertices[index]
+			offset := vertex.Sub(center)
+
+			positions = append(positions, vertex.X*diameter/2.0, vertex.Y*diameter/2.0, vertex.Z*diameter/2.0)
+			normals = append(normals, normal.X, normal.Y, normal.Z)
+			uvs = append(uvs, offset.Dot(side)/size*0.5+0.5, offset.Dot(up)/size*0.5+0.5)
+		}
+
+		for index := uint32(1); index+1 < uint32(len(face)); index++ {
+			indices = append(indices, first, first+index, first+index+1)
+		}
+	}
+
+	setVertexData(solid, positions, normals, uvs, indices, updatable)
+
+	return solid
+}
+
+// CreateTorusKnot builds a tube of the given radius winding p times around the
+// axis of a torus and q times through its hole. The knot has radialSegments
+// points and the tube tubularSegments sides
+func CreateTorusKnot(name string, radius float32, tube float32, radialSegments int, tubularSegments int, p float32, q float32, scene *engines.Scene, updatable bool) *Mesh {
+	position := func(angle float32) *math32.Vector3 {
+		quOverP := q / p * angle
+		cs := math32.Cos(quOverP)
+
+		return math32.NewVector3(radius*(2.0+cs)*0.5*math32.Cos(angle), radius*(2.0+cs)*0.5*math32.Sin(angle), radius*math32.Sin(quOverP)*0.5)
+	}
+
+	pathArray := make([][]*math32.Vector3, 0, radialSegments)
+	for i := 0; i < radialSegments; i++ {
+		angle := float32(i) / float32(radialSegments) * 2.0 * p * math.Pi
+		p1 := position(angle)
+		p2 := position(angle + 0.01)
+
+		// The frames only depend on the angle so that the knot closes
+		tangent := p2.Sub(p1)
+		normal := p2.Add(p1)
+		binormal := tangent.Cross(normal)
+		normal = binormal.Cross(tangent)
+		binormal.Normalize()
+		normal.Normalize()
+
+		pathArray = append(pathArray, circle(p1, normal, binormal, tube, tubularSegments))
+	}
+
+	return CreateRibbon(name, pathArray, true, true, scene, updatable)
+}
+
+// circle returns the points around center in the plane of normal and
+// binormal, turning clockwise around their cross product
+func circle(center *math32.Vector3, normal *math32.Vector3, binormal *math32.Vector3, radius float32, tessellation int) []*math32.Vector3 {
+	points := make([]*math32.Vector3, 0, tessellation)
+	for index := 0; index < tessellation; index++ {
+		angle := float32(index) * math.Pi * 2.0 / float32(tessellation)
+		points = append(points, center.Add(normal.Scale(math32.Cos(angle)*radius)).Sub(binormal.Scale(math32.Sin(angle)*radius)))
+	}
+	return points
+}
+
+// pathFrames returns the tangents, normals and binormals along the path, the
+// normals follow the path with as little twist as possible
+func pathFrames(path []*math32.Vector3) ([]*math32.Vector3, []*math32.Vector3, []*math32.Vector3) {
+	tangents := make([]*math32.Vector3, len(path))
+	normals := make([]*math32.Vector3, len(path))
+	binormals := make([]*math32.Vector3, len(path))
+
+	tangent := math32.NewVector3(0, 0, 1)
+	for index := range path {
+		previous := path[math32.ClampInt(index-1, 0, len(path)-1)]
+		next := path[math32.ClampInt(index+1, 0, len(path)-1)]
+
+		// Repeated points keep the previous tangent
+		if direction := next.Sub(previous); direction.Length() > 0 {
+			tangent = direction
+			tangent.Normalize()
+		}
+		tangents[index] = tangent
+
+		var normal *math32.Vector3
+		if index == 0 {
+			// Any vector perpendicular to the first tangent
+			axis := math32.NewVector3(1, 0, 0)
+			if math32.Abs(tangent.Y) < math32.Abs(tangent.X) && math32.Abs(tangent.Y) <= math32.Abs(tangent.Z) {
+				axis = math32.NewVector3(0, 1, 0)
+			} else if math32.Abs(tangent.Z) < math32.Abs(tangent.X) {
+				axis = math32.NewVector3(0, 0, 1)
+			}
+			normal = tangent.Cross(axis)
+		} else {
+			normal = binormals[index-1].Cross(tangent)
+		}
+		normal.Normalize()
+
+		normals[index] = normal
+		binormals[index] = tangent.Cross(normal)
+	}
+
+	return tangents, normals, binormals
+}
+
+// capPaths adds the caps to the shapes swept along a path, each cap is a fan
+// around the center of the end shape. The end shapes are repeated so that
+// the caps and the sides do not share normals
+func capPaths(pathArray [][]*math32.Vector3, capType int) [][]*math32.Vector3 {
+	if len(pathArray) == 0 {
+		return pathArray
+	}
+
+	center := func(path []*math32.Vector3) []*math32.Vector3 {
+		barycenter := math32.NewVector3Zero()
+		for _, point := range path {
+			barycenter = barycenter.Add(point)
+		}
+		barycenter = barycenter.Scale(1.0 / float32(len(path)))
+
+		points := make([]*math32.Vector3, len(path))
+		for index := range points {
+			points[index] = barycenter
+		}
+		return points
+	}
+
+	if capType&CAP_START != 0 {
+		first := pathArray[0]
+		pathArray = append([][]*math32.Vector3{center(first), first}, pathArray...)
+	}
+	if capType&CAP_END != 0 {
+		last := pathArray[len(pathArray)-1]
+		pathArray = append(pathArray, last, center(last))
+	}
+
+	return pathArray
+}
+
+// CreateTube builds a tube along the path, capType is one of the CAP_ values
+func CreateTube(name string, path []*math32.Vector3, radius float32, tessellation int, capType int, scene *engines.Scene, updatable bool) *Mesh {
+	_, normals, binormals := pathFrames(path)
+
+	pathArray := make([][]*math32.Vector3, 0, len(path))
+	for index, point := range path {
+		pathArray = append(pathArray, circle(point, normals[index], binormals[index], radius, tessellation))
+	}
+
+	return CreateRibbon(name, capPaths(pathArray, capType), false, true, scene, updatable)
+}
+
+// CreateLathe rotates the shape around the Y axis, the x of the shape points
+// is their distance to the axis scaled by radius. The shape goes up from its
+// first point for the normals to point outward
+func CreateLathe(name string, shape []*math32.Vector3, radius float32, tessellation int, scene *engines.Scene, updatable bool) *Mesh {
+	pathArray := make([][]*math32.Vector3, 0, tessellation)
+	for index := 0; index < tessellation; index++ {
+		angle := -float32(index) * math.Pi * 2.0 / float32(tessellation)
+		cos := math32.Cos(angle)
+		sin := math32.Sin(angle)
+
+		path := make([]*math32.Vector3, len(shape))
+		for pointIndex, point := range shape {
+			path[pointIndex] = math32.NewVector3(point.X*radius*cos, point.Y, point.X*radius*sin)
+		}
+		pathArray = append(pathArray, path)
+	}
+
+	return CreateRibbon(name, pathArray, true, false, scene, updatable)
+}
+
+// ExtrudeShape sweeps the closed shape, drawn in the XY plane, along the path.
+// At each point of the path the shape is scaled by scale and turned by
+// rotation more than at the previous one, capType is one of the CAP_ values
+func ExtrudeShape(name string, shape []*math32.Vector3, path []*math32.Vector3, scale float32, rotation float32, capType int, scene *engines.Scene, updatable bool) *Mesh {
+	// The shape turns clockwise for the normals to point outward
+	var area float32
+	for index, point := range shape {
+		next := shape[(index+1)%len(shape)]
+		area += point.X*next.Y - next.X*point.Y
+	}
+	if area > 0 {
+		reversed := make([]*math32.Vector3, len(shape))
+		for index, point := range shape {
+			reversed[len(shape)-1-index] = point
+		}
+		shape = reversed
+	}
+
+	_, normals, binormals := pathFrames(path)
+
+	pathArray := make([][]*math32.Vector3, 0, len(path))
+	for index, point := range path {
+		angle := rotation * float32(index)
+		cos := math32.Cos(angle)
+		sin := math32.Sin(angle)
+
+		points := make([]*math32.Vector3, len(shape))
+		for shapeIndex, shapePoint := range shape {
+			x := (shapePoint.X*cos - shapePoint.Y*sin) * scale
+			y := (shapePoint.X*sin + shapePoint.Y*cos) * scale
+			points[shapeIndex] = point.Add(normals[index].Scale(x)).Add(binormals[index].Scale(y))
+		}
+		pathArray = append(pathArray, points)
+	}
+
+	return CreateRibbon(name, capPaths(pathArray, capType), false, true, scene, updatable)
+}
+
+// CreateRibbon builds a surface joining the paths, each path to the next one.
+// All the paths have the length of the shortest one. closePath joins the last
+// point of the paths to their first one and closeArray the last path to the
+// first one. The normals point along the cross product of the direction from
+// a path to the next one and the direction along the paths
+func CreateRibbon(name string, pathArray [][]*math32.Vector3, closeArray bool, closePath bool, scene *engines.Scene, updatable bool) *Mesh {
+	ribbon := NewMesh(name, scene)
+
+	length := -1
+	for _, path := range pathArray {
+		if length < 0 || len(path) < length {
+			length = len(path)
+		}
+	}
+
+	paths := make([][]*math32.Vector3, 0, len(pathArray)+1)
+	for _, path := range pathArray {
+		path = path[:length:length]
+		// The seams repeat the first points with other texture coordinates
+		if closePath && length > 0 {
+			path = append(path, path[0])
+		}
+		paths = append(paths, path)
+	}
+	if closeArray && len(paths) > 0 {
+		paths = append(paths, paths[0])
+	}
+
+	indices := make([]uint32, 0)
+	positions := make([]float32, 0)
+	uvs := make([]float32, 0)
+
+	rows := len(paths)
+	columns := 0
+	if rows > 0 {
+		columns = len(paths[0])
+	}
+
+	// u follows the paths and v goes across them, both by distance
+	distances := func(count int, point func(index int) *math32.Vector3) []float32 {
+		result := make([]float32, count)
+		for index := 1; index < count; index++ {
+			result[index] = result[index-1] + point(index).Sub(point(index-1)).Length()
+		}
+		for index := range result {
+			if total := result[count-1]; total > 0 {
+				result[index] /= total
+			} else if count > 1 {
+				result[index] = float32(index) / float32(count-1)
+			}
+		}
+		return result
+	}
+
+	us := make([][]float32, rows)
+	for row, path := range paths {
+		us[row] = distances(columns, func(index int) *math32.Vector3 { return path[index] })
+	}
+	vs := make([][]float32, columns)
+	for column := range vs {
+		vs[column] = distances(rows, func(index int) *math32.Vector3 { return paths[index][column] })
+	}
+
+	for row, path := range paths {
+		for column, point := range path {
+			positions = append(positions, point.X, point.Y, point.Z)
+			uvs = append(uvs, us[row][column], vs[column][row])
+		}
+	}
+
+	for row := 0; row+1 < rows; row++ {
+		for column := 0; column+1 < columns; column++ {
+			a := uint32(row*columns + column)
+			b := a + 1
+			c := a + uint32(columns)
+			d := c + 1
+
+			indices = append(indices, a, b, c)
+			indices = append(indices, b, d, c)
+		}
+	}
+
+	normals := ComputeNormals(positions, indices)
+
+	// The repeated vertices of the seams share their normals
+	if closePath {
+		for row := 0; row < rows; row++ {
+			averageNormals(normals, row*columns, row*columns+columns-1)
+		}
+	}
+	if closeArray {
+		for column := 0; column < columns; column++ {
+			averageNormals(normals, column, (rows-1)*columns+column)
+		}
+	}
+
+	setVertexData(ribbon, positions, normals, uvs, indices, updatable)
+
+	return ribbon
+}
+
+// ComputeNormals returns the normals of the vertices, the sum of the normals
+// of their faces weighted by the faces areas
+func ComputeNormals(positions []float32, indices []uint32) []float32 {
+	normals := make([]float32, len(positions))
+
+	for index := 0; index+2 < len(indices); index += 3 {
+		a := math32.NewVector3Zero().FromArray(positions, int(indices[index])*3)
+		b := math32.NewVector3Zero().FromArray(positions, int(indices[index+1])*3)
+		c := math32.NewVector3Zero().FromArray(positions, int(indices[index+2])*3)
+
+		// Front faces are clockwise
+		normal := c.Sub(a).Cross(b.Sub(a))
+
+		for _, vertex := range indices[index : index+3] {
+			normals[vertex*3] += normal.X
+			normals[vertex*3+1] += normal.Y
+			normals[vertex*3+2] += normal.Z
+		}
+	}
+
+	for index := 0; index < len(normals); index += 3 {
+		normal := math32.NewVector3(normals[index], normals[index+1], normals[index+2])
+		normal.Normalize()
+		normals[index], normals[index+1], normals[index+2] = normal.X, normal.Y, normal.Z
+	}
+
+	return normals
+}
+
+// averageNormals gives the normal between them to two vertices
+func averageNormals(normals []float32, a int, b int) {
+	normal := math32.NewVector3(normals[a*3]+normals[b*3], normals[a*3+1]+normals[b*3+1], normals[a*3+2]+normals[b*3+2])
+	normal.Normalize()
+
+	for _, vertex := range []int{a, b} {
+		normals[vertex*3], normals[vertex*3+1], normals[vertex*3+2] = normal.X, normal.Y, normal.Z
+	}
+}
+
+// setVertexData gives their geometry to the meshes built above
+func setVertexData(mesh *Mesh, positions []float32, normals []float32, uvs []float32, indices []uint32, updatable bool) {
+	mesh.SetVerticesData(positions, IMesh_VB_PositionKind, updatable)
+	mesh.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
+	mesh.SetVerticesData(uvs, IMesh_VB_UVKind, updatable)
+	if err := mesh.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
+}
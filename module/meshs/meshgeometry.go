@@ -0,0 +1,345 @@
+package meshs
+
+import (
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cullings"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// Clone creates a mesh with a copy of the geometry, the submeshes and the
+// morph targets of this one. It shares the materials, the skeleton and the
+// animations. The children are cloned too, under the new mesh. The clone of
+// the geometry of a LinesMesh is the geometry of a new LinesMesh
+func (this *Mesh) Clone(name string, newParent *Mesh) *Mesh {
+	var clone *Mesh
+	if this._linesMesh != nil {
+		lines := NewLinesMesh(name, this._scene)
+		lines.Color = math32.NewColor3(this._linesMesh.Color.R, this._linesMesh.Color.G, this._linesMesh.Color.B)
+		clone = lines.Mesh
+	} else {
+		clone = NewMesh(name, this._scene)
+	}
+
+	clone.Position = this.Position.Clone()
+	clone.Rotation = this.Rotation.Clone()
+	if this.RotationQuaternion != nil {
+		clone.RotationQuaternion = this.RotationQuaternion.Clone()
+	}
+	clone.Scaling = this.Scaling.Clone()
+
+	clone._isEnabled = this._isEnabled
+	clone.Isvisible = this.Isvisible
+	clone.Ispickable = this.Ispickable
+	clone.Visibility = this.Visibility
+	clone.BillboardMode = this.BillboardMode
+	clone.Checkcollisions = this.Checkcollisions
+	clone.ReceiveShadows = this.ReceiveShadows
+
+	clone.Material = this.Material
+	clone.MutilMaterial = this.MutilMaterial
+	clone.Skeleton = this.Skeleton
+	clone.Parent = newParent
+
+	// Geometry
+	if buffer, ok := this._vertexBuffers[IMesh_VB_PositionKind]; ok {
+		clone.SetVerticesData(copyFloats(buffer.GetData()), IMesh_VB_PositionKind, buffer.IsUpdatable())
+	}
+	for kind, buffer := range this._vertexBuffers {
+		if kind != IMesh_VB_PositionKind {
+			clone.SetVerticesData(copyFloats(buffer.GetData()), kind, buffer.IsUpdatable())
+		}
+	}
+	if len(this._indices) > 0 {
+		if err := clone.SetIndices(append([]uint32(nil), this._indices...)); err != nil {
+			log.Println(err)
+		}
+	}
+
+	clone.SubMeshes = make([]*SubMesh, 0, len(this.SubMeshes))
+	for _, subMesh := range this.SubMeshes {
+		subMesh.Clone(clone)
+	}
+
+	for _, target := range this.MorphTargets {
+		NewMorphTarget(target.Name, clone, copyFloats(target.GetPositions()), copyFloats(target.GetNormals())).SetInfluence(target.GetInfluence())
+	}
+
+	clone._animations = append([]IAnimation(nil), this._animations...)
+
+	for _, child := range this.GetChildren() {
+		child.Clone(name+"."+child.Name, clone)
+	}
+
+	return clone
+}
+
+func copyFloats(data []float32) []float32 {
+	if data == nil {
+		return nil
+	}
+	return append([]float32(nil), data...)
+}
+
+// RefreshBoundingInfo computes the bounding infos of the mesh and of its
+// submeshes again from the positions
+func (this *Mesh) RefreshBoundingInfo() {
+	positions := this.GetVerticesData(IMesh_VB_PositionKind)
+	if positions == nil {
+		return
+	}
+
+	this._boundingInfo = cullings.NewBoundingInfo(positions, 0, this._totalVertices)
+	for _, subMesh := range this.SubMeshes {
+		subMesh.RefreshBoundingInfo()
+	}
+	this._resetPointsArrayCache()
+}
+
+// _setGeometry replaces the vertex data of the given kinds and the indices
+// when they are not nil, keeping the submeshes
+func (this *Mesh) _setGeometry(data map[string][]float32, indices []uint32) {
+	subMeshes := this.SubMeshes
+
+	for kind, values := range data {
+		updatable := false
+		if buffer, ok := this._vertexBuffers[kind]; ok {
+			updatable = buffer.IsUpdatable()
+		}
+		this.SetVerticesData(values, kind, updatable)
+	}
+	if indices != nil {
+		if err := this.SetIndices(indices); err != nil {
+			log.Println(err)
+		}
+	}
+
+	this.SubMeshes = subMeshes
+	for _, subMesh := range this.SubMeshes {
+		subMesh._resetLinesIndexBuffer()
+	}
+	this.RefreshBoundingInfo()
+}
+
+// BakeTransformIntoVertices applies the transformation to the positions, the
+// normals and the tangents. Transformations mirroring the mesh also flip its faces
+func (this *Mesh) BakeTransformIntoVertices(transform *math32.Matrix4) {
+	positions := this.GetVerticesData(IMesh_VB_PositionKind)
+	if positions == nil {
+		return
+	}
+
+	data := map[string][]float32{}
+
+	transformed := make([]float32, len(positions))
+	for index := 0; index+2 < len(positions); index += 3 {
+		position := math32.NewVector3Zero().TransformCoordinatesFromFloats(positions[index], positions[index+1], positions[index+2], transform)
+		transformed[index], transformed[index+1], transformed[index+2] = position.X, position.Y, position.Z
+	}
+	data[IMesh_VB_PositionKind] = transformed
+
+	if normals := this.GetVerticesData(IMesh_VB_NormalKind); normals != nil {
+		transformed := make([]float32, len(normals))
+		for index := 0; index+2 < len(normals); index += 3 {
+			normal := math32.NewVector3Zero().TransformNormalFromFloats(normals[index], normals[index+1], normals[index+2], transform)
+			normal.Normalize()
+			transformed[index], transformed[index+1], transformed[index+2] = normal.X, normal.Y, normal.Z
+		}
+		data[IMesh_VB_NormalKind] = transformed
+	}
+
+	if tangents := this.GetVerticesData(IMesh_VB_TangentKind); tangents != nil {
+		data[IMesh_VB_TangentKind] = transformTangents(tangents, transform)
+	}
+
+	var indices []uint32
+	if transform.Determinant() < 0 {
+		indices = flipIndices(this._indices)
+	}
+
+	this._setGeometry(data, indices)
+}
+
+// FlipFaces turns the faces inside out, flipNormals makes the normals point
+// the other way too
+func (this *Mesh) FlipFaces(flipNormals bool) {
+	data := map[string][]float32{}
+
+	if normals := this.GetVerticesData(IMesh_VB_NormalKind); flipNormals && normals != nil {
+		flipped := make([]float32, len(normals))
+		for index, value := range normals {
+			flipped[index] = -value
+		}
+		data[IMesh_VB_NormalKind] = flipped
+
+		// The bitangents keep their direction
+		if tangents := this.GetVerticesData(IMesh_VB_TangentKind); tangents != nil {
+			flipped := copyFloats(tangents)
+			for index := 3; index < len(flipped); index += 4 {
+				flipped[index] = -flipped[index]
+			}
+			data[IMesh_VB_TangentKind] = flipped
+		}
+	}
+
+	this._setGeometry(data, flipIndices(this._indices))
+}
+
+func flipIndices(indices []uint32) []uint32 {
+	flipped := make([]uint32, len(indices))
+	for index := 0; index+2 < len(indices); index += 3 {
+		flipped[index], flipped[index+1], flipped[index+2] = indices[index+2], indices[index+1], indices[index]
+	}
+	return flipped
+}
+
+// ConvertToFlatShadedMesh gives each face its own vertices with the normal of
+// the face so that the mesh looks faceted. The morph targets are converted too
+func (this *Mesh) ConvertToFlatShadedMesh() {
+	if this.GetVerticesData(IMesh_VB_PositionKind) == nil || len(this._indices) == 0 {
+		return
+	}
+
+	// The faces of the submeshes follow each other
+	indices := make([]uint32, 0, len(this._indices))
+	for _, subMesh := range this.SubMeshes {
+		indices = append(indices, this._indices[subMesh._indexStart:subMesh._indexStart+subMesh._indexCount]...)
+	}
+
+	flatten := func(values []float32, stride int) []float32 {
+		flat := make([]float32, 0, len(indices)*stride)
+		for _, index := range indices {
+			flat = append(flat, values[int(index)*stride:int(index+1)*stride]...)
+		}
+		return flat
+	}
+
+	newIndices := make([]uint32, len(indices))
+	for index := range newIndices {
+		newIndices[index] = uint32(index)
+	}
+
+	data := map[string][]float32{}
+	for kind, buffer := range this._vertexBuffers {
+		data[kind] = flatten(buffer.GetData(), buffer.GetStrideSize())
+	}
+	// Each vertex takes the normal of its face
+	data[IMesh_VB_NormalKind] = ComputeNormals(data[IMesh_VB_PositionKind], newIndices)
+	if uvs, ok := data[IMesh_VB_UVKind]; ok && data[IMesh_VB_TangentKind] != nil {
+		data[IMesh_VB_TangentKind] = ComputeTangents(data[IMesh_VB_PositionKind], data[IMesh_VB_NormalKind], uvs, newIndices)
+	}
+
+	for _, target := range this.MorphTargets {
+		positions := flatten(target.GetPositions(), 3)
+		target._positions.Dispose()
+		target._positions = NewVertexBuffer(this, positions, IMesh_VB_PositionKind, false)
+		if target._normals != nil {
+			target._normals.Dispose()
+			target._normals = NewVertexBuffer(this, ComputeNormals(positions, newIndices), IMesh_VB_NormalKind, false)
+		}
+	}
+
+	subMeshes := this.SubMeshes
+	this.SubMeshes = nil
+	this._setGeometry(data, newIndices)
+
+	offset := 0
+	for _, subMesh := range subMeshes {
+		subMesh._resetLinesIndexBuffer()
+		NewSubMesh(subMesh._materialIndex, offset, subMesh._indexCount, offset, subMesh._indexCount, this)
+		offset += subMesh._indexCount
+	}
+}
+
+// MergeMeshes creates a mesh with the geometry of the meshes in world space,
+// drawn in a single call with the material of the first mesh. Only the vertex
+// data present in all the meshes is kept. disposeSource disposes the meshes.
+// It returns nil when the merged indices do not fit the index buffers
+func MergeMeshes(meshes []*Mesh, disposeSource bool) *Mesh {
+	if len(meshes) == 0 {
+		return nil
+	}
+	source := meshes[0]
+
+	// The kinds shared by all the meshes
+	kinds := []string{}
+	for kind := range source._vertexBuffers {
+		shared := true
+		for _, mesh := range meshes[1:] {
+			if !mesh.IsVerticesDataPresent(kind) {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			kinds = append(kinds, kind)
+		} else {
+			log.Printf("MergeMeshes: %s is missing from some meshes and is not merged", kind)
+		}
+	}
+
+	data := map[string][]float32{}
+	indices := make([]uint32, 0)
+
+	for _, mesh := range meshes {
+		mesh.ComputeWorldMatrix()
+		world := mesh.GetWorldMatrix()
+		start := uint32(len(data[IMesh_VB_PositionKind]) / 3)
+
+		for _, kind := range kinds {
+			values := mesh.GetVerticesData(kind)
+
+			switch kind {
+			case IMesh_VB_PositionKind:
+				for index := 0; index+2 < len(values); index += 3 {
+					position := math32.NewVector3Zero().TransformCoordinatesFromFloats(values[index], values[index+1], values[index+2], world)
+					data[kind] = append(data[kind], position.X, position.Y, position.Z)
+				}
+			case IMesh_VB_NormalKind:
+				for index := 0; index+2 < len(values); index += 3 {
+					normal := math32.NewVector3Zero().TransformNormalFromFloats(values[index], values[index+1], values[index+2], world)
+					normal.Normalize()
+					data[kind] = append(data[kind], normal.X, normal.Y, normal.Z)
+				}
+			case IMesh_VB_TangentKind:
+				data[kind] = append(data[kind], transformTangents(values, world)...)
+			default:
+				data[kind] = append(data[kind], values...)
+			}
+		}
+
+		// Mirrored meshes keep their faces outward
+		meshIndices := mesh.GetIndices()
+		if world.Determinant() < 0 {
+			meshIndices = flipIndices(meshIndices)
+		}
+		for _, index := range meshIndices {
+			indices = append(indices, start+index)
+		}
+	}
+
+	merged := NewMesh(source.Name+"_merged", source._scene)
+	merged.Material = source.Material
+
+	if positions, ok := data[IMesh_VB_PositionKind]; ok {
+		merged.SetVerticesData(positions, IMesh_VB_PositionKind, false)
+	}
+	for kind, values := range data {
+		if kind != IMesh_VB_PositionKind {
+			merged.SetVerticesData(values, kind, false)
+		}
+	}
+	if err := merged.SetIndices(indices); err != nil {
+		log.Printf("MergeMeshes: %s, the meshes are kept", err)
+		merged.Dispose()
+		return nil
+	}
+
+	if disposeSource {
+		for _, mesh := range meshes {
+			mesh.Dispose()
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,41 @@
+// +build glnull glsoft
+
+package meshs
+
+import (
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/gl"
+)
+
+func TestMergeMeshesWithout32BitsIndices(t *testing.T) {
+	gl.SetElementIndexUintSupported(false)
+	defer gl.SetElementIndexUintSupported(true)
+	scene := enginetest.NewScene(t)
+
+	// Each ground fits in 16 bits indices, both together do not
+	first := CreateGround("first", 10, 10, 200, scene, false)
+	second := CreateGround("second", 10, 10, 200, scene, false)
+	if first.GetTotalIndices() == 0 || first.Is32BitsIndices() {
+		t.Fatalf("ground of %d vertices has %d indices, 32 bits %v", first.GetTotalVertices(), first.GetTotalIndices(), first.Is32BitsIndices())
+	}
+
+	if merged := MergeMeshes([]*Mesh{first, second}, true); merged != nil {
+		t.Errorf("merged %d vertices into %s with 16 bits indices", merged.GetTotalVertices(), merged.Name)
+	}
+	if first.IsDisposed() || second.IsDisposed() {
+		t.Errorf("the meshes were disposed by a failed merge")
+	}
+	if scene.GetMeshByName("first_merged") != nil {
+		t.Errorf("the failed merge left a mesh in the scene")
+	}
+
+	// The error leaves the mesh without indices and submeshes
+	if err := first.SetIndices([]uint32{0, 1, 70000}); err == nil {
+		t.Errorf("SetIndices accepted 32 bits indices")
+	}
+	if first.GetTotalIndices() != 0 || len(first.SubMeshes) != 0 {
+		t.Errorf("failed SetIndices kept %d indices and %d submeshes", first.GetTotalIndices(), len(first.SubMeshes))
+	}
+}
@@ -0,0 +1,131 @@
+package meshs
+
+import (
+	"sort"
+
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cullings"
+)
+
+// MeshLODLevel is the mesh drawn in place of its master mesh from Distance to
+// the camera on, a nil Mesh culls the master mesh
+type MeshLODLevel struct {
+	Distance float32
+	Mesh     *Mesh
+}
+
+// AddLODLevel draws mesh instead of this one when the camera is at least at
+// distance. The level is drawn with the transformation of this mesh and is
+// not drawn on its own anymore. A nil mesh culls this one beyond distance
+func (this *Mesh) AddLODLevel(distance float32, mesh *Mesh) *Mesh {
+	if mesh != nil {
+		if mesh._masterMesh != nil {
+			mesh._masterMesh.RemoveLODLevel(mesh)
+		}
+		mesh._masterMesh = this
+	}
+
+	this._LODLevels = append(this._LODLevels, &MeshLODLevel{Distance: distance, Mesh: mesh})
+	sort.SliceStable(this._LODLevels, func(i, j int) bool {
+		return this._LODLevels[i].Distance < this._LODLevels[j].Distance
+	})
+
+	return this
+}
+
+// RemoveLODLevel removes the level drawing mesh, nil removes the culling
+// levels
+func (this *Mesh) RemoveLODLevel(mesh *Mesh) *Mesh {
+	for index, level := range this._LODLevels {
+		if level.Mesh == mesh {
+			this._LODLevels = append(this._LODLevels[:index], this._LODLevels[index+1:]...)
+			if mesh != nil {
+				mesh._masterMesh = nil
+			}
+			break
+		}
+	}
+
+	return this
+}
+
+// GetLODLevels returns the levels of detail by increasing distance
+func (this *Mesh) GetLODLevels() []*MeshLODLevel {
+	return this._LODLevels
+}
+
+// GetLODLevelAtDistance returns the mesh drawn at distance, this mesh when
+// no level applies and nil when the mesh is culled
+func (this *Mesh) GetLODLevelAtDistance(distance float32) *Mesh {
+	mesh := this
+	for _, level := range this._LODLevels {
+		if distance < level.Distance {
+			break
+		}
+		mesh = level.Mesh
+	}
+
+	return mesh
+}
+
+// GetLOD returns the mesh drawn for this one at the distance of the camera,
+// nil when it is culled. The levels of detail of other meshes return nil, they
+// are only drawn by their master
+func (this *Mesh) GetLOD(camera ICamera) IMesh {
+	if this._masterMesh != nil {
+		return nil
+	}
+
+	return this._getLOD(camera, this._boundingInfo)
+}
+
+// _getLOD picks the level for the distance of the camera to the bounding
+// sphere of the mesh or of one of its instances
+func (this *Mesh) _getLOD(camera ICamera, boundingInfo *cullings.BoundingInfo) IMesh {
+	if len(this._LODLevels) == 0 || camera == nil || boundingInfo == nil || boundingInfo.Sphere.CenterWorld == nil {
+		return this
+	}
+
+	mesh := this.GetLODLevelAtDistance(boundingInfo.Sphere.CenterWorld.Distance(camera.GetPosition()))
+	if mesh == nil {
+		// A nil *Mesh is not a nil IMesh
+		return nil
+	}
+
+	return mesh
+}
+
+// _computeLODWorldMatrix gives the level of detail the world matrix of its
+// master mesh
+func (this *Mesh) _computeLODWorldMatrix() *math32.Matrix4 {
+	master := this._masterMesh
+	master.ComputeWorldMatrix()
+
+	this._worldMatrix = master._worldMatrix
+	this._scaleFactor = master._scaleFactor
+
+	if this._boundingInfo != nil {
+		this._boundingInfo.Update(this._worldMatrix, this._scaleFactor)
+		for _, subMesh := range this.SubMeshes {
+			subMesh.UpdateBoundingInfo(this._worldMatrix, this._scaleFactor)
+		}
+	}
+
+	return this._worldMatrix
+}
+
+func (this *Mesh) _disposeLODLevels() {
+	if this._masterMesh != nil {
+		this._masterMesh.RemoveLODLevel(this)
+	}
+
+	// The levels of detail belong to their master
+	for len(this._LODLevels) > 0 {
+		mesh := this._LODLevels[0].Mesh
+		this.RemoveLODLevel(mesh)
+		if mesh != nil {
+			mesh.Dispose()
+		}
+	}
+}
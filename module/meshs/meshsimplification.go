@@ -0,0 +1,494 @@
+package meshs
+
+import (
+	"fmt"
+	"math"
+
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// SimplificationSettings describes a level of detail generated by Simplify,
+// Quality is the fraction of the triangles kept
+type SimplificationSettings struct {
+	Quality  float32
+	Distance float32
+}
+
+// Simplify generates a simplified level of detail for each of the settings
+func (this *Mesh) Simplify(settings []*SimplificationSettings) *Mesh {
+	for index, setting := range settings {
+		lod := this.CreateSimplifiedMesh(fmt.Sprintf("%s.lod%d", this.Name, index), setting.Quality)
+		this.AddLODLevel(setting.Distance, lod)
+	}
+
+	return this
+}
+
+// CreateSimplifiedMesh creates a mesh with about quality times the triangles
+// of this one, made by collapsing the edges whose removal changes the surface
+// the least. The borders of the surface, texture seams included, are kept.
+// The vertices keep their attributes, the new mesh shares the materials and
+// has the submeshes of this one
+func (this *Mesh) CreateSimplifiedMesh(name string, quality float32) *Mesh {
+	simplifier := newQuadricSimplifier(this)
+	simplifier.simplify(int(float32(len(simplifier.triangles)) * math32.Clamp(quality, 0, 1)))
+
+	return simplifier.toMesh(name)
+}
+
+// quadric is the symmetric matrix of the squared distance to a set of planes
+type quadric [10]float64
+
+func newPlaneQuadric(a, b, c, d float64) quadric {
+	return quadric{a * a, a * b, a * c, a * d, b * b, b * c, b * d, c * c, c * d, d * d}
+}
+
+func (this quadric) add(other quadric) quadric {
+	for index := range this {
+		this[index] += other[index]
+	}
+	return this
+}
+
+func (this quadric) det(a11, a12, a13, a21, a22, a23, a31, a32, a33 int) float64 {
+	return this[a11]*this[a22]*this[a33] + this[a13]*this[a21]*this[a32] + this[a12]*this[a23]*this[a31] -
+		this[a13]*this[a22]*this[a31] - this[a11]*this[a23]*this[a32] - this[a12]*this[a21]*this[a33]
+}
+
+// vertexError is the squared distance of the point to the planes
+func (this quadric) vertexError(x, y, z float64) float64 {
+	return this[0]*x*x + 2*this[1]*x*y + 2*this[2]*x*z + 2*this[3]*x + this[4]*y*y +
+		2*this[5]*y*z + 2*this[6]*y + this[7]*z*z + 2*this[8]*z + this[9]
+}
+
+type simplifierVertex struct {
+	position [3]float64
+	q        quadric
+	start    int
+	count    int
+	border   bool
+}
+
+type simplifierTriangle struct {
+	v       [3]int
+	err     [4]float64
+	deleted bool
+	dirty   bool
+	normal  [3]float64
+	subMesh int
+}
+
+type simplifierReference struct {
+	triangle int
+	corner   int
+}
+
+type quadricSimplifier struct {
+	mesh       *Mesh
+	vertices   []*simplifierVertex
+	triangles  []*simplifierTriangle
+	references []simplifierReference
+}
+
+func newQuadricSimplifier(mesh *Mesh) *quadricSimplifier {
+	this := &quadricSimplifier{}
+	this.mesh = mesh
+
+	positions := mesh.GetVerticesData(IMesh_VB_PositionKind)
+	for index := 0; index+2 < len(positions); index += 3 {
+		this.vertices = append(this.vertices, &simplifierVertex{
+			position: [3]float64{float64(positions[index]), float64(positions[index+1]), float64(positions[index+2])},
+		})
+	}
+
+	indices := mesh.GetIndices()
+	for subMeshIndex, subMesh := range mesh.SubMeshes {
+		for index := subMesh._indexStart; index+2 < subMesh._indexStart+subMesh._indexCount; index += 3 {
+			this.triangles = append(this.triangles, &simplifierTriangle{
+				v:       [3]int{int(indices[index]), int(indices[index+1]), int(indices[index+2])},
+				subMesh: subMeshIndex,
+			})
+		}
+	}
+
+	return this
+}
+
+func sub3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func dot3(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func normalize3(a [3]float64) [3]float64 {
+	length := math.Sqrt(dot3(a, a))
+	if length == 0 {
+		return a
+	}
+	return [3]float64{a[0] / length, a[1] / length, a[2] / length}
+}
+
+// simplify collapses edges until target triangles are left or no edge can
+// be collapsed anymore
+func (this *quadricSimplifier) simplify(target int) {
+	deletedTriangles := 0
+	deleted0 := make([]bool, 0)
+	deleted1 := make([]bool, 0)
+	triangleCount := len(this.triangles)
+
+	for iteration := 0; iteration < 100; iteration++ {
+		if triangleCount-deletedTriangles <= target {
+			break
+		}
+
+		if iteration%5 == 0 {
+			this.updateMesh(iteration)
+		}
+		for _, triangle := range this.triangles {
+			triangle.dirty = false
+		}
+
+		// The error allowed grows with the iterations
+		threshold := 0.000000001 * math.Pow(float64(iteration+3), 7)
+
+		for _, triangle := range this.triangles {
+			if triangle.err[3] > threshold || triangle.deleted || triangle.dirty {
+				continue
+			}
+
+			for corner := 0; corner < 3; corner++ {
+				if triangle.err[corner] >= threshold {
+					continue
+				}
+
+				i0 := triangle.v[corner]
+				i1 := triangle.v[(corner+1)%3]
+				v0 := this.vertices[i0]
+				v1 := this.vertices[i1]
+
+				// The borders keep their shape
+				if v0.border || v1.border {
+					continue
+				}
+
+				_, position := this.calculateError(i0, i1)
+
+				deleted0 = resizeBools(deleted0, v0.count)
+				deleted1 = resizeBools(deleted1, v1.count)
+
+				if this.flipped(position, i1, v0, deleted0) || this.flipped(position, i0, v1, deleted1) {
+					continue
+				}
+
+				v0.position = position
+				v0.q = v0.q.add(v1.q)
+
+				start := len(this.references)
+				deletedTriangles += this.updateTriangles(i0, v0, deleted0)
+				deletedTriangles += this.updateTriangles(i0, v1, deleted1)
+				count := len(this.references) - start
+
+				if count <= v0.count {
+					copy(this.references[v0.start:], this.references[start:start+count])
+					this.references = this.references[:start]
+				} else {
+					v0.start = start
+				}
+				v0.count = count
+				break
+			}
+
+			if triangleCount-deletedTriangles <= target {
+				break
+			}
+		}
+	}
+
+	this.compactTriangles()
+}
+
+func resizeBools(values []bool, size int) []bool {
+	if cap(values) < size {
+		return make([]bool, size)
+	}
+	values = values[:size]
+	for index := range values {
+		values[index] = false
+	}
+	return values
+}
+
+// flipped tells whether moving the vertex to position turns one of its
+// triangles over, the triangles shared with the other vertex of the edge are
+// marked as deleted
+func (this *quadricSimplifier) flipped(position [3]float64, other int, vertex *simplifierVertex, deleted []bool) bool {
+	for index := 0; index < vertex.count; index++ {
+		reference := this.references[vertex.start+index]
+		triangle := this.triangles[reference.triangle]
+		if triangle.deleted {
+			continue
+		}
+
+		corner := reference.corner
+		id1 := triangle.v[(corner+1)%3]
+		id2 := triangle.v[(corner+2)%3]
+
+		if id1 == other || id2 == other {
+			deleted[index] = true
+			continue
+		}
+
+		d1 := normalize3(sub3(this.vertices[id1].position, position))
+		d2 := normalize3(sub3(this.vertices[id2].position, position))
+		if math.Abs(dot3(d1, d2)) > 0.999 {
+			return true
+		}
+
+		normal := normalize3(cross3(d1, d2))
+		deleted[index] = false
+		if dot3(normal, triangle.normal) < 0.2 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateTriangles moves the triangles of vertex to the vertex i0, it returns
+// the number of triangles deleted
+func (this *quadricSimplifier) updateTriangles(i0 int, vertex *simplifierVertex, deleted []bool) int {
+	deletedTriangles := 0
+
+	for index := 0; index < vertex.count; index++ {
+		reference := this.references[vertex.start+index]
+		triangle := this.triangles[reference.triangle]
+		if triangle.deleted {
+			continue
+		}
+
+		if deleted[index] {
+			triangle.deleted = true
+			deletedTriangles++
+			continue
+		}
+
+		triangle.v[reference.corner] = i0
+		triangle.dirty = true
+		triangle.err[0], _ = this.calculateError(triangle.v[0], triangle.v[1])
+		triangle.err[1], _ = this.calculateError(triangle.v[1], triangle.v[2])
+		triangle.err[2], _ = this.calculateError(triangle.v[2], triangle.v[0])
+		triangle.err[3] = math.Min(triangle.err[0], math.Min(triangle.err[1], triangle.err[2]))
+
+		this.references = append(this.references, reference)
+	}
+
+	return deletedTriangles
+}
+
+// updateMesh drops the deleted triangles and builds the references from the
+// vertices to their triangles again. The first time it also computes the
+// quadrics, the errors and the borders
+func (this *quadricSimplifier) updateMesh(iteration int) {
+	if iteration > 0 {
+		this.compactTriangles()
+	}
+
+	if iteration == 0 {
+		for _, triangle := range this.triangles {
+			p0 := this.vertices[triangle.v[0]].position
+			p1 := this.vertices[triangle.v[1]].position
+			p2 := this.vertices[triangle.v[2]].position
+
+			normal := normalize3(cross3(sub3(p1, p0), sub3(p2, p0)))
+			triangle.normal = normal
+
+			q := newPlaneQuadric(normal[0], normal[1], normal[2], -dot3(normal, p0))
+			for corner := 0; corner < 3; corner++ {
+				vertex := this.vertices[triangle.v[corner]]
+				vertex.q = vertex.q.add(q)
+			}
+		}
+
+		for _, triangle := range this.triangles {
+			for corner := 0; corner < 3; corner++ {
+				triangle.err[corner], _ = this.calculateError(triangle.v[corner], triangle.v[(corner+1)%3])
+			}
+			triangle.err[3] = math.Min(triangle.err[0], math.Min(triangle.err[1], triangle.err[2]))
+		}
+	}
+
+	// References
+	for _, vertex := range this.vertices {
+		vertex.start = 0
+		vertex.count = 0
+	}
+	for _, triangle := range this.triangles {
+		for corner := 0; corner < 3; corner++ {
+			this.vertices[triangle.v[corner]].count++
+		}
+	}
+	start := 0
+	for _, vertex := range this.vertices {
+		vertex.start = start
+		start += vertex.count
+		vertex.count = 0
+	}
+	this.references = make([]simplifierReference, len(this.triangles)*3)
+	for triangleIndex, triangle := range this.triangles {
+		for corner := 0; corner < 3; corner++ {
+			vertex := this.vertices[triangle.v[corner]]
+			this.references[vertex.start+vertex.count] = simplifierReference{triangleIndex, corner}
+			vertex.count++
+		}
+	}
+
+	// Borders are the edges of a single triangle
+	if iteration == 0 {
+		for vertexIndex, vertex := range this.vertices {
+			counts := map[int]int{}
+			for index := 0; index < vertex.count; index++ {
+				triangle := this.triangles[this.references[vertex.start+index].triangle]
+				for corner := 0; corner < 3; corner++ {
+					if id := triangle.v[corner]; id != vertexIndex {
+						counts[id]++
+					}
+				}
+			}
+			for id, count := range counts {
+				if count == 1 {
+					vertex.border = true
+					this.vertices[id].border = true
+				}
+			}
+		}
+	}
+}
+
+func (this *quadricSimplifier) compactTriangles() {
+	triangles := this.triangles[:0]
+	for _, triangle := range this.triangles {
+		if !triangle.deleted {
+			triangles = append(triangles, triangle)
+		}
+	}
+	this.triangles = triangles
+}
+
+// calculateError returns the error of collapsing the edge and the position
+// of the vertex left
+func (this *quadricSimplifier) calculateError(id0, id1 int) (float64, [3]float64) {
+	q := this.vertices[id0].q.add(this.vertices[id1].q)
+
+	det := q.det(0, 1, 2, 1, 4, 5, 2, 5, 7)
+	if det != 0 {
+		position := [3]float64{
+			-1 / det * q.det(1, 2, 3, 4, 5, 6, 5, 7, 8),
+			1 / det * q.det(0, 2, 3, 1, 5, 6, 2, 7, 8),
+			-1 / det * q.det(0, 1, 3, 1, 4, 6, 2, 5, 8),
+		}
+		return q.vertexError(position[0], position[1], position[2]), position
+	}
+
+	// The best position is along the edge
+	p0 := this.vertices[id0].position
+	p1 := this.vertices[id1].position
+	p2 := [3]float64{(p0[0] + p1[0]) / 2, (p0[1] + p1[1]) / 2, (p0[2] + p1[2]) / 2}
+
+	error0 := q.vertexError(p0[0], p0[1], p0[2])
+	error1 := q.vertexError(p1[0], p1[1], p1[2])
+	error2 := q.vertexError(p2[0], p2[1], p2[2])
+
+	minimum := math.Min(error0, math.Min(error1, error2))
+	switch minimum {
+	case error0:
+		return minimum, p0
+	case error1:
+		return minimum, p1
+	}
+	return minimum, p2
+}
+
+// toMesh creates the mesh of the remaining triangles with the attributes of
+// their vertices
+func (this *quadricSimplifier) toMesh(name string) *Mesh {
+	source := this.mesh
+	mesh := NewMesh(name, source._scene)
+	mesh.Material = source.Material
+	mesh.MutilMaterial = source.MutilMaterial
+	mesh.Skeleton = source.Skeleton
+
+	// The vertices still used, in the order of the triangles
+	remap := make([]int, len(this.vertices))
+	for index := range remap {
+		remap[index] = -1
+	}
+	used := make([]int, 0)
+
+	indices := make([]uint32, 0, len(this.triangles)*3)
+	ranges := make([][2]int, len(source.SubMeshes))
+	for subMeshIndex := range source.SubMeshes {
+		start := len(indices)
+		for _, triangle := range this.triangles {
+			if triangle.subMesh != subMeshIndex {
+				continue
+			}
+			for corner := 0; corner < 3; corner++ {
+				id := triangle.v[corner]
+				if remap[id] < 0 {
+					remap[id] = len(used)
+					used = append(used, id)
+				}
+				indices = append(indices, uint32(remap[id]))
+			}
+		}
+		ranges[subMeshIndex] = [2]int{start, len(indices) - start}
+	}
+
+	if len(indices) == 0 {
+		return mesh
+	}
+
+	data := map[string][]float32{}
+	for kind, buffer := range source._vertexBuffers {
+		stride := buffer.GetStrideSize()
+		values := buffer.GetData()
+
+		compacted := make([]float32, 0, len(used)*stride)
+		for _, id := range used {
+			if kind == IMesh_VB_PositionKind {
+				position := this.vertices[id].position
+				compacted = append(compacted, float32(position[0]), float32(position[1]), float32(position[2]))
+				continue
+			}
+			compacted = append(compacted, values[id*stride:(id+1)*stride]...)
+		}
+		data[kind] = compacted
+	}
+
+	mesh.SetVerticesData(data[IMesh_VB_PositionKind], IMesh_VB_PositionKind, false)
+	for kind, values := range data {
+		if kind != IMesh_VB_PositionKind {
+			mesh.SetVerticesData(values, kind, false)
+		}
+	}
+	if err := mesh.SetIndices(indices); err != nil {
+		log.Println(err)
+	}
+
+	mesh.SubMeshes = make([]*SubMesh, 0, len(ranges))
+	for subMeshIndex, subMesh := range source.SubMeshes {
+		if ranges[subMeshIndex][1] > 0 {
+			CreateFromIndices(subMesh._materialIndex, ranges[subMeshIndex][0], ranges[subMeshIndex][1], mesh)
+		}
+	}
+
+	return mesh
+}
@@ -0,0 +1,160 @@
+// +build glnull glsoft
+
+package meshs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+// bounds returns the minimum and the maximum of the positions
+func bounds(positions []float32) (*math32.Vector3, *math32.Vector3) {
+	minimum := math32.NewVector3(math.MaxFloat32, math.MaxFloat32, math.MaxFloat32)
+	maximum := math32.NewVector3(-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32)
+	for index := 0; index+2 < len(positions); index += 3 {
+		minimum.X = math32.Min(minimum.X, positions[index])
+		minimum.Y = math32.Min(minimum.Y, positions[index+1])
+		minimum.Z = math32.Min(minimum.Z, positions[index+2])
+		maximum.X = math32.Max(maximum.X, positions[index])
+		maximum.Y = math32.Max(maximum.Y, positions[index+1])
+		maximum.Z = math32.Max(maximum.Z, positions[index+2])
+	}
+	return minimum, maximum
+}
+
+func TestCreateSimplifiedMesh(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	sphere := CreateSphere("sphere", 16, 2, scene, false)
+	box := CreateBox("box", 1, scene, false)
+	ground := CreateGround("ground", 10, 10, 10, scene, false)
+
+	tests := []struct {
+		name    string
+		mesh    *Mesh
+		quality float32
+		// The triangles left, the collapses remove one or two triangles
+		minTriangles int
+		maxTriangles int
+	}{
+		{"sphere kept", sphere, 1, 1296, 1296},
+		{"sphere half", sphere, 0.5, 647, 649},
+		{"sphere quarter", sphere, 0.25, 323, 325},
+		{"sphere clamped", sphere, 2, 1296, 1296},
+		// The seams of the texture coordinates are borders, so the faces of a box cannot be collapsed
+		{"box", box, 0.5, 12, 12},
+		{"ground half", ground, 0.5, 99, 101},
+		// Only the borders are left, 10 edges on each side
+		{"ground empty", ground, 0, 40, 40},
+	}
+
+	for _, test := range tests {
+		simplified := test.mesh.CreateSimplifiedMesh(test.name, test.quality)
+
+		triangles := len(simplified.GetIndices()) / 3
+		if triangles < test.minTriangles || triangles > test.maxTriangles {
+			t.Errorf("%s: %d triangles, want %d to %d", test.name, triangles, test.minTriangles, test.maxTriangles)
+		}
+
+		for _, kind := range []string{IMesh_VB_NormalKind, IMesh_VB_UVKind} {
+			if len(simplified.GetVerticesData(kind)) != simplified.GetTotalVertices()*len(test.mesh.GetVerticesData(kind))/test.mesh.GetTotalVertices() {
+				t.Errorf("%s: %s not kept for every vertex", test.name, kind)
+			}
+		}
+		for _, index := range simplified.GetIndices() {
+			if int(index) >= simplified.GetTotalVertices() {
+				t.Errorf("%s: index %d out of %d vertices", test.name, index, simplified.GetTotalVertices())
+				break
+			}
+		}
+
+		// The borders are kept, so is the extent of the mesh
+		minimum, maximum := bounds(simplified.GetVerticesData(IMesh_VB_PositionKind))
+		wantMinimum, wantMaximum := bounds(test.mesh.GetVerticesData(IMesh_VB_PositionKind))
+		if test.mesh == ground && (!minimum.Equals(wantMinimum) || !maximum.Equals(wantMaximum)) {
+			t.Errorf("%s: extent %v %v, want %v %v", test.name, minimum, maximum, wantMinimum, wantMaximum)
+		}
+		if simplified.Material != test.mesh.Material || len(simplified.SubMeshes) != len(test.mesh.SubMeshes) {
+			t.Errorf("%s: material or submeshes not kept", test.name)
+		}
+	}
+}
+
+func TestSimplifiedSurface(t *testing.T) {
+	scene := enginetest.NewScene(t)
+
+	// A flat surface stays flat
+	ground := CreateGround("ground", 10, 10, 10, scene, false).CreateSimplifiedMesh("flat", 0.2)
+	positions := ground.GetVerticesData(IMesh_VB_PositionKind)
+	for index := 1; index < len(positions); index += 3 {
+		if math32.Abs(positions[index]) > 1e-5 {
+			t.Fatalf("vertex %d of the ground left the plane: %v", index/3, positions[index])
+		}
+	}
+
+	// The vertices of a sphere stay close to the sphere
+	sphere := CreateSphere("sphere", 16, 2, scene, false).CreateSimplifiedMesh("round", 0.25)
+	positions = sphere.GetVerticesData(IMesh_VB_PositionKind)
+	for index := 0; index+2 < len(positions); index += 3 {
+		radius := math32.NewVector3(positions[index], positions[index+1], positions[index+2]).Length()
+		if radius < 0.85 || radius > 1.1 {
+			t.Fatalf("vertex %d of the sphere at %v from the center", index/3, radius)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	sphere := CreateSphere("sphere", 16, 2, scene, false)
+
+	sphere.Simplify([]*SimplificationSettings{
+		{Quality: 0.2, Distance: 60},
+		{Quality: 0.6, Distance: 20},
+	})
+
+	tests := []struct {
+		name     string
+		distance float32
+	}{
+		{"sphere.lod1", 20},
+		{"sphere.lod0", 60},
+	}
+
+	levels := sphere.GetLODLevels()
+	if len(levels) != len(tests) {
+		t.Fatalf("%d levels of detail, want %d", len(levels), len(tests))
+	}
+	for index, test := range tests {
+		if levels[index].Mesh.Name != test.name || levels[index].Distance != test.distance {
+			t.Errorf("level %d: %s at %v, want %s at %v", index, levels[index].Mesh.Name, levels[index].Distance, test.name, test.distance)
+		}
+	}
+	if len(levels[0].Mesh.GetIndices()) <= len(levels[1].Mesh.GetIndices()) {
+		t.Errorf("the nearest level has less triangles than the farthest")
+	}
+}
+
+func TestQuadricVertexError(t *testing.T) {
+	// The planes y = 0 and x = 1
+	planes := newPlaneQuadric(0, 1, 0, 0).add(newPlaneQuadric(1, 0, 0, -1))
+
+	tests := []struct {
+		x, y, z float64
+		err     float64
+	}{
+		{1, 0, 0, 0},
+		{1, 0, 5, 0},
+		{1, 2, 0, 4},
+		{0, 0, 0, 1},
+		{3, -1, 7, 5},
+	}
+
+	for _, test := range tests {
+		if err := planes.vertexError(test.x, test.y, test.z); math.Abs(err-test.err) > 1e-9 {
+			t.Errorf("vertexError(%v, %v, %v) = %v, want %v", test.x, test.y, test.z, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package meshs
+
+import (
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+)
+
+// ComputeTangents returns the tangents of the vertices for normal mapping,
+// four floats per vertex. The tangent follows the u direction of the texture
+// and w is the handedness of the basis: the bitangent, along v, is
+// cross(normal, tangent) * w. As in MikkTSpace the face tangents are weighted
+// by the angle of the face at the vertex, so that splitting a face does not
+// change the result, and mirrored uvs get their own handedness
+func ComputeTangents(positions []float32, normals []float32, uvs []float32, indices []uint32) []float32 {
+	count := len(positions) / 3
+	tangents := make([]float32, count*3)
+	bitangents := make([]float32, count*3)
+
+	for index := 0; index+2 < len(indices); index += 3 {
+		corners := indices[index : index+3]
+
+		p0 := math32.NewVector3Zero().FromArray(positions, int(corners[0])*3)
+		p1 := math32.NewVector3Zero().FromArray(positions, int(corners[1])*3)
+		p2 := math32.NewVector3Zero().FromArray(positions, int(corners[2])*3)
+
+		edge1 := p1.Sub(p0)
+		edge2 := p2.Sub(p0)
+		du1 := uvs[corners[1]*2] - uvs[corners[0]*2]
+		dv1 := uvs[corners[1]*2+1] - uvs[corners[0]*2+1]
+		du2 := uvs[corners[2]*2] - uvs[corners[0]*2]
+		dv2 := uvs[corners[2]*2+1] - uvs[corners[0]*2+1]
+
+		// Faces without uv area give no direction
+		determinant := du1*dv2 - du2*dv1
+		if math32.Abs(determinant) < 1e-12 {
+			continue
+		}
+		r := 1 / determinant
+
+		tangent := edge1.Scale(dv2).Sub(edge2.Scale(dv1)).Scale(r)
+		bitangent := edge2.Scale(du1).Sub(edge1.Scale(du2)).Scale(r)
+		tangent.Normalize()
+		bitangent.Normalize()
+
+		points := []*math32.Vector3{p0, p1, p2}
+		for corner, vertex := range corners {
+			a := points[(corner+1)%3].Sub(points[corner])
+			b := points[(corner+2)%3].Sub(points[corner])
+			a.Normalize()
+			b.Normalize()
+			angle := math32.Acos(math32.Clamp(a.Dot(b), -1, 1))
+
+			tangents[vertex*3] += tangent.X * angle
+			tangents[vertex*3+1] += tangent.Y * angle
+			tangents[vertex*3+2] += tangent.Z * angle
+			bitangents[vertex*3] += bitangent.X * angle
+			bitangents[vertex*3+1] += bitangent.Y * angle
+			bitangents[vertex*3+2] += bitangent.Z * angle
+		}
+	}
+
+	result := make([]float32, count*4)
+	for vertex := 0; vertex < count; vertex++ {
+		normal := math32.NewVector3Zero().FromArray(normals, vertex*3)
+		tangent := math32.NewVector3Zero().FromArray(tangents, vertex*3)
+		bitangent := math32.NewVector3Zero().FromArray(bitangents, vertex*3)
+
+		// Gram-Schmidt, the tangent is made perpendicular to the normal
+		tangent = tangent.Sub(normal.Scale(normal.Dot(tangent)))
+		if tangent.LengthSq() < 1e-12 {
+			// Any direction in the plane of the normal will do
+			tangent = normal.Cross(math32.NewVector3(0, 0, 1))
+			if tangent.LengthSq() < 1e-12 {
+				tangent = normal.Cross(math32.NewVector3(0, 1, 0))
+			}
+		}
+		tangent.Normalize()
+
+		handedness := float32(1)
+		if normal.Cross(tangent).Dot(bitangent) < 0 {
+			handedness = -1
+		}
+
+		result[vertex*4], result[vertex*4+1], result[vertex*4+2], result[vertex*4+3] = tangent.X, tangent.Y, tangent.Z, handedness
+	}
+
+	return result
+}
+
+// CreateTangents computes the tangents of the mesh from its positions,
+// normals and uvs and sets them as its tangent vertex data. The normals are
+// computed when the mesh has none. It returns false when the mesh has no uvs
+func (this *Mesh) CreateTangents(updatable bool) bool {
+	positions := this.GetVerticesData(IMesh_VB_PositionKind)
+	uvs := this.GetVerticesData(IMesh_VB_UVKind)
+	if positions == nil || uvs == nil || len(this._indices) == 0 {
+		return false
+	}
+
+	normals := this.GetVerticesData(IMesh_VB_NormalKind)
+	if normals == nil {
+		normals = ComputeNormals(positions, this._indices)
+		this.SetVerticesData(normals, IMesh_VB_NormalKind, updatable)
+	}
+
+	this.SetVerticesData(ComputeTangents(positions, normals, uvs, this._indices), IMesh_VB_TangentKind, updatable)
+
+	return true
+}
+
+// transformTangents moves the tangents along with the transformation, the
+// handedness changes when it mirrors the mesh
+func transformTangents(tangents []float32, transform *math32.Matrix4) []float32 {
+	mirrored := transform.Determinant() < 0
+
+	transformed := make([]float32, len(tangents))
+	for index := 0; index+3 < len(tangents); index += 4 {
+		tangent := math32.NewVector3Zero().TransformNormalFromFloats(tangents[index], tangents[index+1], tangents[index+2], transform)
+		tangent.Normalize()
+		transformed[index], transformed[index+1], transformed[index+2] = tangent.X, tangent.Y, tangent.Z
+
+		transformed[index+3] = tangents[index+3]
+		if mirrored {
+			transformed[index+3] = -transformed[index+3]
+		}
+	}
+
+	return transformed
+}
@@ -0,0 +1,83 @@
+package meshs
+
+import (
+	. "github.com/suiqirui1987/fly3d/interfaces"
+)
+
+// MorphTargetsMaxGPU is the number of morph targets the vertex shaders blend,
+// meshes with more targets morph their vertices on the CPU
+const MorphTargetsMaxGPU = 4
+
+// MorphTarget is an alternative shape of a mesh, the vertices move toward its
+// positions and normals by its influence. Animations drive the influence with
+// the "morphTargets.<index>.influence" property of the mesh
+type MorphTarget struct {
+	Name string
+
+	_influence float32
+	_mesh      *Mesh
+	_positions *VertexBuffer
+	_normals   *VertexBuffer
+}
+
+// NewMorphTarget adds a target to the mesh, normals are optional
+func NewMorphTarget(name string, mesh *Mesh, positions []float32, normals []float32) *MorphTarget {
+	this := &MorphTarget{}
+	this.Name = name
+	this._mesh = mesh
+
+	this._positions = NewVertexBuffer(mesh, positions, IMesh_VB_PositionKind, false)
+	if len(normals) > 0 {
+		this._normals = NewVertexBuffer(mesh, normals, IMesh_VB_NormalKind, false)
+	}
+
+	mesh.MorphTargets = append(mesh.MorphTargets, this)
+	mesh._morphTargetsDirty = true
+
+	return this
+}
+
+func (this *MorphTarget) GetInfluence() float32 {
+	return this._influence
+}
+
+// SetInfluence is also called by the animations of the influence
+func (this *MorphTarget) SetInfluence(influence float32) {
+	if influence == this._influence {
+		return
+	}
+	this._influence = influence
+	this._mesh._morphTargetsDirty = true
+}
+
+func (this *MorphTarget) GetPositions() []float32 {
+	return this._positions.GetData()
+}
+
+// GetNormals returns nil when the target has no normals
+func (this *MorphTarget) GetNormals() []float32 {
+	if this._normals == nil {
+		return nil
+	}
+	return this._normals.GetData()
+}
+
+func (this *MorphTarget) HasNormals() bool {
+	return this._normals != nil
+}
+
+func (this *MorphTarget) Dispose() {
+	this._positions.Dispose()
+	if this._normals != nil {
+		this._normals.Dispose()
+	}
+
+	mesh := this._mesh
+	for index, target := range mesh.MorphTargets {
+		if target == this {
+			mesh.MorphTargets = append(mesh.MorphTargets[:index], mesh.MorphTargets[index+1:]...)
+			mesh._morphTargetsDirty = true
+			break
+		}
+	}
+}
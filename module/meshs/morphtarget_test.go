@@ -0,0 +1,63 @@
+// +build glnull glsoft
+
+package meshs
+
+import (
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/gl"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/module/animations"
+)
+
+func TestMorphTargetAnimation(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	box := CreateBox("box", 1, scene, false)
+	target := box.AddMorphTarget("open", box.GetVerticesData(IMesh_VB_PositionKind), nil)
+
+	for _, property := range []string{"morphTargets.0.influence", "MorphTargets.0.Influence"} {
+		target.SetInfluence(0)
+		animation := animations.NewAnimation("open", property, 30, animations.ANIMATIONTYPE_FLOAT, animations.ANIMATIONLOOPMODE_CONSTANT)
+		animation.SetKeys([]*animations.AnimationKeyFrame{{Frame: 0, Value: float32(0.75)}, {Frame: 30, Value: float32(0.75)}})
+
+		animation.Animate(box, 500, 0, 30, false, 1)
+		if influence := target.GetInfluence(); influence != 0.75 {
+			t.Errorf("%s: influence %v, want 0.75", property, influence)
+		}
+	}
+}
+
+func TestMorphTargetsBlendWhenDirty(t *testing.T) {
+	scene := enginetest.NewScene(t)
+	box := CreateBox("box", 1, scene, false)
+	positions := box.GetVerticesData(IMesh_VB_PositionKind)
+	normals := box.GetVerticesData(IMesh_VB_NormalKind)
+
+	// More targets than the vertex shaders blend
+	for index := 0; index <= MorphTargetsMaxGPU; index++ {
+		box.AddMorphTarget("target", positions, normals)
+	}
+
+	steps := []struct {
+		name    string
+		change  func()
+		uploads int
+	}{
+		{"new targets", func() {}, 2},
+		{"unchanged", func() {}, 0},
+		{"same influence", func() { box.MorphTargets[0].SetInfluence(0) }, 0},
+		{"new influence", func() { box.MorphTargets[0].SetInfluence(0.5) }, 2},
+		{"blended on the gpu", func() { box.MorphTargets[MorphTargetsMaxGPU].Dispose() }, 2},
+		{"gpu influence", func() { box.MorphTargets[0].SetInfluence(1) }, 0},
+	}
+
+	for _, step := range steps {
+		step.change()
+		gl.ResetCalls()
+		box._updateMorphTargets()
+		if uploads := gl.CountCalls("BufferSubData"); uploads != step.uploads {
+			t.Errorf("%s: %d vertex buffers uploaded, want %d", step.name, uploads, step.uploads)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package meshs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+
+	// Registers the animation and skeleton parsers
+	_ "github.com/suiqirui1987/fly3d/module/animations"
+	_ "github.com/suiqirui1987/fly3d/module/bones"
+)
+
+func init() {
+	engines.RegisterParser("Mesh", parseMesh)
+	engines.RegisterParser("LinesMesh", parseLinesMesh)
+	engines.RegisterParser("InstancedMesh", parseInstancedMesh)
+}
+
+type subMeshData struct {
+	MaterialIndex int `json:"materialIndex"`
+	VerticesStart int `json:"verticesStart"`
+	VerticesCount int `json:"verticesCount"`
+	IndexStart    int `json:"indexStart"`
+	IndexCount    int `json:"indexCount"`
+}
+
+type vertexData struct {
+	Data      []float32 `json:"data"`
+	Updatable bool      `json:"updatable"`
+}
+
+type morphTargetData struct {
+	Name      string    `json:"name"`
+	Influence float32   `json:"influence"`
+	Positions []float32 `json:"positions"`
+	Normals   []float32 `json:"normals,omitempty"`
+}
+
+// lodLevelData stores a level of detail, a nil MeshId culls the mesh
+type lodLevelData struct {
+	Distance float32 `json:"distance"`
+	MeshId   *string `json:"meshId"`
+}
+
+type meshData struct {
+	Type            string `json:"type"`
+	Name            string `json:"name"`
+	Id              string `json:"id"`
+	ParentId        string `json:"parentId,omitempty"`
+	MaterialId      string `json:"materialId,omitempty"`
+	MultiMaterialId string `json:"multiMaterialId,omitempty"`
+	SkeletonId      string `json:"skeletonId,omitempty"`
+
+	Position           *math32.Vector3    `json:"position"`
+	Rotation           *math32.Vector3    `json:"rotation"`
+	RotationQuaternion *math32.Quaternion `json:"rotationQuaternion,omitempty"`
+	Scaling            *math32.Vector3    `json:"scaling"`
+
+	Enabled         bool    `json:"enabled"`
+	Visible         bool    `json:"visible"`
+	Pickable        bool    `json:"pickable"`
+	Visibility      float32 `json:"visibility"`
+	BillboardMode   int     `json:"billboardMode"`
+	CheckCollisions bool    `json:"checkCollisions"`
+	ReceiveShadows  bool    `json:"receiveShadows"`
+
+	VertexData map[string]*vertexData `json:"vertexData"`
+	Indices    []uint32               `json:"indices"`
+	SubMeshes  []*subMeshData         `json:"subMeshes"`
+
+	// Color of the lines of a LinesMesh
+	Color *math32.Color3 `json:"color,omitempty"`
+
+	MorphTargets []*morphTargetData `json:"morphTargets,omitempty"`
+	Animations   []json.RawMessage  `json:"animations,omitempty"`
+
+	LODLevels []*lodLevelData `json:"lodLevels,omitempty"`
+}
+
+type instancedMeshData struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Id       string `json:"id"`
+	SourceId string `json:"sourceId"`
+	ParentId string `json:"parentId,omitempty"`
+
+	Position           *math32.Vector3    `json:"position"`
+	Rotation           *math32.Vector3    `json:"rotation"`
+	RotationQuaternion *math32.Quaternion `json:"rotationQuaternion,omitempty"`
+	Scaling            *math32.Vector3    `json:"scaling"`
+
+	Enabled  bool `json:"enabled"`
+	Visible  bool `json:"visible"`
+	Pickable bool `json:"pickable"`
+}
+
+func (this *Mesh) Serialize() interface{} {
+	data := &meshData{
+		Type: "Mesh",
+		Name: this.Name,
+		Id:   this.Id,
+
+		Position:           this.Position,
+		Rotation:           this.Rotation,
+		RotationQuaternion: this.RotationQuaternion,
+		Scaling:            this.Scaling,
+
+		Enabled:         this._isEnabled,
+		Visible:         this.Isvisible,
+		Pickable:        this.Ispickable,
+		Visibility:      this.Visibility,
+		BillboardMode:   this.BillboardMode,
+		CheckCollisions: this.Checkcollisions,
+		ReceiveShadows:  this.ReceiveShadows,
+
+		VertexData: map[string]*vertexData{},
+		Indices:    this._indices,
+	}
+
+	if this._linesMesh != nil {
+		data.Type = "LinesMesh"
+		data.Color = this._linesMesh.Color
+	}
+	if this.Parent != nil {
+		data.ParentId = this.Parent.Id
+	}
+	if this.Material != nil {
+		data.MaterialId = this.Material.GetId()
+	}
+	if this.MutilMaterial != nil {
+		data.MultiMaterialId = this.MutilMaterial.GetId()
+	}
+	if this.Skeleton != nil {
+		data.SkeletonId = this.Skeleton.GetId()
+	}
+
+	for kind, buffer := range this._vertexBuffers {
+		data.VertexData[kind] = &vertexData{Data: buffer.GetData(), Updatable: buffer.IsUpdatable()}
+	}
+
+	for _, subMesh := range this.SubMeshes {
+		data.SubMeshes = append(data.SubMeshes, &subMeshData{
+			MaterialIndex: subMesh._materialIndex,
+			VerticesStart: subMesh._verticesStart,
+			VerticesCount: subMesh._verticesCount,
+			IndexStart:    subMesh._indexStart,
+			IndexCount:    subMesh._indexCount,
+		})
+	}
+
+	for _, target := range this.MorphTargets {
+		data.MorphTargets = append(data.MorphTargets, &morphTargetData{
+			Name:      target.Name,
+			Influence: target.GetInfluence(),
+			Positions: target.GetPositions(),
+			Normals:   target.GetNormals(),
+		})
+	}
+
+	for _, animation := range this._animations {
+		if content := engines.SerializeObject(animation); content != nil {
+			data.Animations = append(data.Animations, content)
+		}
+	}
+
+	for _, level := range this._LODLevels {
+		levelData := &lodLevelData{Distance: level.Distance}
+		if level.Mesh != nil {
+			levelData.MeshId = &level.Mesh.Id
+		}
+		data.LODLevels = append(data.LODLevels, levelData)
+	}
+
+	return data
+}
+
+func parseMesh(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &meshData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	return loadMesh(data, NewMesh(data.Name, scene), scene)
+}
+
+func parseLinesMesh(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &meshData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	lines := NewLinesMesh(data.Name, scene)
+	if data.Color != nil {
+		lines.Color = data.Color
+	}
+
+	if _, err := loadMesh(data, lines.Mesh, scene); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// loadMesh sets up a mesh created by a parser
+func loadMesh(data *meshData, mesh *Mesh, scene *engines.Scene) (*Mesh, error) {
+	mesh.Id = data.Id
+
+	if data.ParentId != "" {
+		parent, ok := scene.GetMeshByID(data.ParentId).(*Mesh)
+		if !ok {
+			return nil, fmt.Errorf("mesh %s: parent %s not found", data.Name, data.ParentId)
+		}
+		mesh.Parent = parent
+	}
+
+	if data.Position != nil {
+		mesh.Position = data.Position
+	}
+	if data.Rotation != nil {
+		mesh.Rotation = data.Rotation
+	}
+	mesh.RotationQuaternion = data.RotationQuaternion
+	if data.Scaling != nil {
+		mesh.Scaling = data.Scaling
+	}
+
+	mesh._isEnabled = data.Enabled
+	mesh.Isvisible = data.Visible
+	mesh.Ispickable = data.Pickable
+	mesh.Visibility = data.Visibility
+	mesh.BillboardMode = data.BillboardMode
+	mesh.Checkcollisions = data.CheckCollisions
+	mesh.ReceiveShadows = data.ReceiveShadows
+
+	// Positions first, they size the mesh
+	if positions, ok := data.VertexData[IMesh_VB_PositionKind]; ok {
+		mesh.SetVerticesData(positions.Data, IMesh_VB_PositionKind, positions.Updatable)
+	}
+	for kind, vertices := range data.VertexData {
+		if kind != IMesh_VB_PositionKind {
+			mesh.SetVerticesData(vertices.Data, kind, vertices.Updatable)
+		}
+	}
+	if len(data.Indices) > 0 {
+		if err := mesh.SetIndices(data.Indices); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data.SubMeshes) > 0 {
+		mesh.SubMeshes = make([]*SubMesh, 0)
+		for _, subMesh := range data.SubMeshes {
+			NewSubMesh(subMesh.MaterialIndex, subMesh.VerticesStart, subMesh.VerticesCount, subMesh.IndexStart, subMesh.IndexCount, mesh)
+		}
+	}
+
+	for _, targetContent := range data.MorphTargets {
+		target := mesh.AddMorphTarget(targetContent.Name, targetContent.Positions, targetContent.Normals)
+		if target == nil {
+			return nil, fmt.Errorf("mesh %s: morph target %s does not match the vertices", data.Name, targetContent.Name)
+		}
+		target.SetInfluence(targetContent.Influence)
+	}
+
+	if data.MaterialId != "" {
+		mesh.Material = scene.GetMaterialByID(data.MaterialId)
+		if mesh.Material == nil {
+			return nil, fmt.Errorf("mesh %s: material %s not found", data.Name, data.MaterialId)
+		}
+	}
+	if data.MultiMaterialId != "" {
+		for _, multiMaterial := range scene.MultiMaterials {
+			if multiMaterial.GetId() == data.MultiMaterialId {
+				mesh.MutilMaterial = multiMaterial
+				break
+			}
+		}
+	}
+	if data.SkeletonId != "" {
+		mesh.Skeleton = scene.GetSkeletonByID(data.SkeletonId)
+		if mesh.Skeleton == nil {
+			return nil, fmt.Errorf("mesh %s: skeleton %s not found", data.Name, data.SkeletonId)
+		}
+	}
+
+	for _, animationContent := range data.Animations {
+		object, err := engines.ParseObject(animationContent, scene)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %s: %s", data.Name, err)
+		}
+		if animation, ok := object.(IAnimation); ok {
+			mesh.AddAnimation(animation)
+		}
+	}
+
+	// The levels of detail can be stored after this mesh
+	if len(data.LODLevels) > 0 {
+		scene.AfterParse(func() error {
+			for _, level := range data.LODLevels {
+				if level.MeshId == nil {
+					mesh.AddLODLevel(level.Distance, nil)
+					continue
+				}
+
+				lod, ok := scene.GetMeshByID(*level.MeshId).(*Mesh)
+				if !ok {
+					return fmt.Errorf("mesh %s: level of detail %s not found", data.Name, *level.MeshId)
+				}
+				mesh.AddLODLevel(level.Distance, lod)
+			}
+			return nil
+		})
+	}
+
+	return mesh, nil
+}
+
+func (this *InstancedMesh) Serialize() interface{} {
+	data := &instancedMeshData{
+		Type:     "InstancedMesh",
+		Name:     this.Name,
+		Id:       this.Id,
+		SourceId: this._sourceMesh.Id,
+
+		Position:           this.Position,
+		Rotation:           this.Rotation,
+		RotationQuaternion: this.RotationQuaternion,
+		Scaling:            this.Scaling,
+
+		Enabled:  this._isEnabled,
+		Visible:  this.Isvisible,
+		Pickable: this.Ispickable,
+	}
+
+	if this.Parent != nil {
+		data.ParentId = this.Parent.Id
+	}
+
+	return data
+}
+
+func parseInstancedMesh(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &instancedMeshData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	source, ok := scene.GetMeshByID(data.SourceId).(*Mesh)
+	if !ok {
+		return nil, fmt.Errorf("instance %s: source mesh %s not found", data.Name, data.SourceId)
+	}
+
+	instance := source.CreateInstance(data.Name)
+	instance.Id = data.Id
+
+	if data.ParentId != "" {
+		parent, ok := scene.GetMeshByID(data.ParentId).(*Mesh)
+		if !ok {
+			return nil, fmt.Errorf("instance %s: parent %s not found", data.Name, data.ParentId)
+		}
+		instance.Parent = parent
+	}
+
+	if data.Position != nil {
+		instance.Position = data.Position
+	}
+	if data.Rotation != nil {
+		instance.Rotation = data.Rotation
+	}
+	instance.RotationQuaternion = data.RotationQuaternion
+	if data.Scaling != nil {
+		instance.Scaling = data.Scaling
+	}
+
+	instance._isEnabled = data.Enabled
+	instance.Isvisible = data.Visible
+	instance.Ispickable = data.Pickable
+
+	return instance, nil
+}
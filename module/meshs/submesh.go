@@ -7,6 +7,7 @@ import (
 	"github.com/suiqirui1987/fly3d/math32"
 	"github.com/suiqirui1987/fly3d/module/cullings"
 	"github.com/suiqirui1987/fly3d/module/materials"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
 	"math"
 )
 
@@ -28,9 +29,9 @@ type SubMesh struct {
 
 // Statics
 func CreateFromIndices(materialIndex int, startIndex int, indexCount int, mesh *Mesh) *SubMesh {
-	var minVertexIndex, maxVertexIndex uint16
-	minVertexIndex = 0
-	maxVertexIndex = math.MaxUint16
+	var minVertexIndex, maxVertexIndex uint32
+	minVertexIndex = math.MaxUint32
+	maxVertexIndex = 0
 
 	indices := mesh.GetIndices()
 
@@ -39,12 +40,17 @@ func CreateFromIndices(materialIndex int, startIndex int, indexCount int, mesh *
 
 		if vertexIndex < minVertexIndex {
 			minVertexIndex = vertexIndex
-		} else if vertexIndex > maxVertexIndex {
+		}
+		if vertexIndex > maxVertexIndex {
 			maxVertexIndex = vertexIndex
 		}
 	}
 
-	return NewSubMesh(materialIndex, int(minVertexIndex), int(maxVertexIndex-minVertexIndex), startIndex, indexCount, mesh)
+	if indexCount == 0 {
+		minVertexIndex = 0
+	}
+
+	return NewSubMesh(materialIndex, int(minVertexIndex), int(maxVertexIndex-minVertexIndex)+1, startIndex, indexCount, mesh)
 }
 
 func NewSubMesh(materialIndex, verticesStart, verticesCount, indexStart, indexCount int, mesh *Mesh) *SubMesh {
@@ -90,9 +96,9 @@ func (this *SubMesh) UpdateBoundingInfo(world *math32.Matrix4, scale float32) {
 	this._boundingInfo.Update(world, scale)
 }
 
-func (this *SubMesh) GetLinesIndexBuffer(indices []uint16, engine *engines.Engine) *gl.GLIndexBuffer {
+func (this *SubMesh) GetLinesIndexBuffer(indices []uint32, engine *engines.Engine) *gl.GLIndexBuffer {
 	if this._linesIndexBuffer == nil {
-		linesIndices := make([]uint16, 0)
+		linesIndices := make([]uint32, 0)
 
 		for index := this._indexStart; index < this._indexStart+this._indexCount; index += 3 {
 			linesIndices = append(linesIndices, indices[index], indices[index+1],
@@ -101,17 +107,33 @@ func (this *SubMesh) GetLinesIndexBuffer(indices []uint16, engine *engines.Engin
 
 		}
 
-		this._linesIndexBuffer = engine.CreateIndexBuffer(linesIndices, false)
+		linesIndexBuffer, err := engine.CreateIndexBuffer(linesIndices, this._mesh.Is32BitsIndices())
+		if err != nil {
+			log.Printf("Mesh %s: no wireframe, %s", this._mesh.Name, err)
+			return nil
+		}
+		this._linesIndexBuffer = linesIndexBuffer
 		this._linesIndexCount = len(linesIndices)
 	}
 	return this._linesIndexBuffer
 }
 
+// _resetLinesIndexBuffer releases the wireframe indices, they are built again
+// from the new indices of the mesh on the next wireframe draw
+func (this *SubMesh) _resetLinesIndexBuffer() {
+	if this._linesIndexBuffer == nil {
+		return
+	}
+	this._mesh._scene.GetEngine().ReleaseIndexBuffer(this._linesIndexBuffer)
+	this._linesIndexBuffer = nil
+	this._linesIndexCount = 0
+}
+
 func (this *SubMesh) CanIntersects(ray *math32.Ray) bool {
 	return ray.IntersectsBox(this._boundingInfo.Box.GetBox())
 }
 
-func (this *SubMesh) Intersects(ray *math32.Ray, positions []*math32.Vector3, indices []uint16) *math32.RayIntersectsResult {
+func (this *SubMesh) Intersects(ray *math32.Ray, positions []*math32.Vector3, indices []uint32) *math32.RayIntersectsResult {
 	var distance float32
 	distance = math.MaxFloat32
 
@@ -137,6 +159,18 @@ func (this *SubMesh) Intersects(ray *math32.Ray, positions []*math32.Vector3, in
 	return &math32.RayIntersectsResult{Hit: true, Distance: distance}
 }
 
+func (this *SubMesh) GetMaterialIndex() int {
+	return this._materialIndex
+}
+
+func (this *SubMesh) GetIndexStart() int {
+	return this._indexStart
+}
+
+func (this *SubMesh) GetIndexCount() int {
+	return this._indexCount
+}
+
 func (this *SubMesh) Clone(newMesh *Mesh) *SubMesh {
 	return NewSubMesh(this._materialIndex, this._verticesStart, this._verticesCount, this._indexStart, this._indexCount, newMesh)
 }
@@ -39,6 +39,9 @@ func NewVertexBuffer(mesh *Mesh, data []float32, kind string, updatable bool) *V
 	case IMesh_VB_NormalKind:
 		this._buffer.StrideSize = 3
 		break
+	case IMesh_VB_TangentKind:
+		this._buffer.StrideSize = 4
+		break
 	case IMesh_VB_UVKind:
 		this._buffer.StrideSize = 2
 		break
@@ -1,6 +1,7 @@
 package particles
 
 import (
+	"math"
 	"math/rand"
 	"strings"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/suiqirui1987/fly3d/math32"
 	"github.com/suiqirui1987/fly3d/module/effects"
 	"github.com/suiqirui1987/fly3d/tools"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
 )
 
 func randomNumber(min float32, max float32) float32 {
@@ -130,13 +132,19 @@ func (this *ParticleSystem) Init() {
 	this._stockParticles = make([]*Particle, 0)
 	this._newPartsExcess = 0
 
+	// Without 32 bits indices the 4 vertices of each particle must fit in 16 bits
+	if maxCapacity := (math.MaxUint16 + 1) / 4; this.Capacity > maxCapacity && !this._scene.GetEngine().GetCaps().Uint32Indices {
+		log.Printf("Particle system %s: capacity %d lowered to %d, OES_element_index_uint is not supported", this.Name, this.Capacity, maxCapacity)
+		this.Capacity = maxCapacity
+	}
+
 	// VBO
 	this._vertexDeclaration = []int{3, 4, 4}
 	this._vertexStrideSize = 11 * 4 // 10 floats per particle (x, y, z, r, g, b, a, angle, size, offsetX, offsetY)
 	this._vertexBuffer = this._scene.GetEngine().CreateDynamicVertexBuffer(this.Capacity * this._vertexStrideSize * 4)
 
-	indices := make([]uint16, 0)
-	var index uint16
+	indices := make([]uint32, 0)
+	var index uint32
 	index = 0
 	for count := 0; count < this.Capacity; count++ {
 		indices = append(indices, index)
@@ -148,7 +156,8 @@ func (this *ParticleSystem) Init() {
 		index += 4
 	}
 
-	this._indexBuffer = this._scene.GetEngine().CreateIndexBuffer(indices, false)
+	// The capacity fits the index buffers of the engine, this cannot fail
+	this._indexBuffer, _ = this._scene.GetEngine().CreateIndexBuffer(indices, false)
 	this._vertices = make([]float32, this.Capacity*this._vertexStrideSize)
 
 	this.Emitter = nil
@@ -294,7 +303,7 @@ func (this *ParticleSystem) _getEffect() IEffect {
 			"particles",
 			[]string{"position", "color", "options"},
 			[]string{"invView", "view", "projection", "vClipPlane", "textureMask"},
-			[]string{"diffuseSampler"}, join)
+			[]string{"diffuseSampler"}, join, nil, nil)
 	}
 
 	return this._effect
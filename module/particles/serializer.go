@@ -0,0 +1,174 @@
+package particles
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	. "github.com/suiqirui1987/fly3d/interfaces"
+	"github.com/suiqirui1987/fly3d/math32"
+
+	// Registers the texture parsers
+	_ "github.com/suiqirui1987/fly3d/module/textures"
+)
+
+func init() {
+	engines.RegisterParser("ParticleSystem", parseParticleSystem)
+}
+
+type particleSystemData struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Id        string          `json:"id"`
+	Capacity  int             `json:"capacity"`
+	EmitterId string          `json:"emitterId,omitempty"`
+	Texture   json.RawMessage `json:"texture,omitempty"`
+	Started   bool            `json:"started"`
+
+	Gravity     *math32.Vector3 `json:"gravity"`
+	Direction1  *math32.Vector3 `json:"direction1"`
+	Direction2  *math32.Vector3 `json:"direction2"`
+	MinEmitBox  *math32.Vector3 `json:"minEmitBox"`
+	MaxEmitBox  *math32.Vector3 `json:"maxEmitBox"`
+	Color1      *math32.Color4  `json:"color1"`
+	Color2      *math32.Color4  `json:"color2"`
+	ColorDead   *math32.Color4  `json:"colorDead"`
+	DeadAlpha   float32         `json:"deadAlpha"`
+	TextureMask *math32.Color4  `json:"textureMask"`
+
+	EmitRate           float32 `json:"emitRate"`
+	ManualEmitCount    float32 `json:"manualEmitCount"`
+	UpdateSpeed        float32 `json:"updateSpeed"`
+	TargetStopDuration float32 `json:"targetStopDuration"`
+	DisposeOnStop      bool    `json:"disposeOnStop"`
+	MinEmitPower       float32 `json:"minEmitPower"`
+	MaxEmitPower       float32 `json:"maxEmitPower"`
+	MinLifeTime        float32 `json:"minLifeTime"`
+	MaxLifeTime        float32 `json:"maxLifeTime"`
+	MinSize            float32 `json:"minSize"`
+	MaxSize            float32 `json:"maxSize"`
+	MinAngularSpeed    float32 `json:"minAngularSpeed"`
+	MaxAngularSpeed    float32 `json:"maxAngularSpeed"`
+	BlendMode          int     `json:"blendMode"`
+}
+
+func (this *ParticleSystem) Serialize() interface{} {
+	data := &particleSystemData{
+		Type:     "ParticleSystem",
+		Name:     this.Name,
+		Id:       this.Id,
+		Capacity: this.Capacity,
+		Texture:  engines.SerializeObject(this.ParticleTexture),
+		Started:  this._started && !this._stopped,
+
+		Gravity:     this.Gravity,
+		Direction1:  this.Direction1,
+		Direction2:  this.Direction2,
+		MinEmitBox:  this.MinEmitBox,
+		MaxEmitBox:  this.MaxEmitBox,
+		Color1:      this.Color1,
+		Color2:      this.Color2,
+		ColorDead:   this.ColorDead,
+		DeadAlpha:   this.DeadAlpha,
+		TextureMask: this.TextureMask,
+
+		EmitRate:           this.EmitRate,
+		ManualEmitCount:    this.ManualEmitCount,
+		UpdateSpeed:        this.UpdateSpeed,
+		TargetStopDuration: this.TargetStopDuration,
+		DisposeOnStop:      this.DisposeOnStop,
+		MinEmitPower:       this.MinEmitPower,
+		MaxEmitPower:       this.MaxEmitPower,
+		MinLifeTime:        this.MinLifeTime,
+		MaxLifeTime:        this.MaxLifeTime,
+		MinSize:            this.MinSize,
+		MaxSize:            this.MaxSize,
+		MinAngularSpeed:    this.MinAngularSpeed,
+		MaxAngularSpeed:    this.MaxAngularSpeed,
+		BlendMode:          int(this.BlendMode),
+	}
+
+	if this.Emitter != nil {
+		data.EmitterId = this.Emitter.GetId()
+	}
+
+	return data
+}
+
+func parseParticleSystem(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &particleSystemData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	system := NewParticleSystem(data.Name, data.Capacity, scene)
+	system.Id = data.Id
+
+	if data.EmitterId != "" {
+		system.Emitter = scene.GetMeshByID(data.EmitterId)
+		if system.Emitter == nil {
+			return nil, fmt.Errorf("particle system %s: emitter %s not found", data.Name, data.EmitterId)
+		}
+	}
+
+	object, err := engines.ParseObject(data.Texture, scene)
+	if err != nil {
+		return nil, fmt.Errorf("particle system %s: %s", data.Name, err)
+	}
+	if texture, ok := object.(ITexture); ok {
+		system.ParticleTexture = texture
+	}
+
+	vectors := []struct {
+		value  *math32.Vector3
+		target **math32.Vector3
+	}{
+		{data.Gravity, &system.Gravity},
+		{data.Direction1, &system.Direction1},
+		{data.Direction2, &system.Direction2},
+		{data.MinEmitBox, &system.MinEmitBox},
+		{data.MaxEmitBox, &system.MaxEmitBox},
+	}
+	for _, vector := range vectors {
+		if vector.value != nil {
+			*vector.target = vector.value
+		}
+	}
+
+	colors := []struct {
+		value  *math32.Color4
+		target **math32.Color4
+	}{
+		{data.Color1, &system.Color1},
+		{data.Color2, &system.Color2},
+		{data.ColorDead, &system.ColorDead},
+		{data.TextureMask, &system.TextureMask},
+	}
+	for _, color := range colors {
+		if color.value != nil {
+			*color.target = color.value
+		}
+	}
+	system.DeadAlpha = data.DeadAlpha
+
+	system.EmitRate = data.EmitRate
+	system.ManualEmitCount = data.ManualEmitCount
+	system.UpdateSpeed = data.UpdateSpeed
+	system.TargetStopDuration = data.TargetStopDuration
+	system.DisposeOnStop = data.DisposeOnStop
+	system.MinEmitPower = data.MinEmitPower
+	system.MaxEmitPower = data.MaxEmitPower
+	system.MinLifeTime = data.MinLifeTime
+	system.MaxLifeTime = data.MaxLifeTime
+	system.MinSize = data.MinSize
+	system.MaxSize = data.MaxSize
+	system.MinAngularSpeed = data.MinAngularSpeed
+	system.MaxAngularSpeed = data.MaxAngularSpeed
+	system.BlendMode = PARTICLEENUM(data.BlendMode)
+
+	if data.Started {
+		system.Start()
+	}
+
+	return system, nil
+}
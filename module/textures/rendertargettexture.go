@@ -33,6 +33,16 @@ func NewRenderTargetTexture(name string, size int, scene *engines.Scene, generat
 	return this
 }
 
+func NewRenderTargetTextureWithSize(name string, width int, height int, scene *engines.Scene, generateMipMaps bool) *RenderTargetTexture {
+	this := &RenderTargetTexture{}
+	this.Name = name
+	this._scene = scene
+	this._scene.Textures = append(this._scene.Textures, this)
+
+	this._texture = scene.GetEngine().CreateRenderTargetTextureWithSize(width, height, generateMipMaps)
+	return this
+}
+
 func (this *RenderTargetTexture) Init() {
 	this.Texture.Init()
 
@@ -53,6 +63,10 @@ func (this *RenderTargetTexture) AddRenderList(val IMesh) {
 	this._renderList = append(this._renderList, val)
 }
 
+func (this *RenderTargetTexture) GetRenderList() []IMesh {
+	return this._renderList
+}
+
 func (this *RenderTargetTexture) Resize(size int, generateMipMaps bool) {
 	this.ReleaseGLTexture()
 	this._texture = this._scene.GetEngine().CreateRenderTargetTexture(size, generateMipMaps)
@@ -90,12 +104,26 @@ func (this *RenderTargetTexture) Render() {
 	this._transparentSubMeshes = make([]ISubMesh, 0)
 	this._alphaTestSubMeshes = make([]ISubMesh, 0)
 
+	// Instances share the submeshes of their source mesh
+	dispatched := map[ISubMesh]bool{}
+
 	for meshIndex := 0; meshIndex < len(this._renderList); meshIndex++ {
 		mesh := this._renderList[meshIndex]
 
+		// The level of detail drawn from the active camera
+		meshLOD := mesh.GetLOD(scene.ActiveCamera)
+		if meshLOD == nil {
+			continue
+		}
+
 		if mesh.IsEnabled() && mesh.IsVisible() {
 
-			for _, subMesh := range mesh.GetSubMeshes() {
+			for _, subMesh := range meshLOD.GetSubMeshes() {
+				if dispatched[subMesh] {
+					continue
+				}
+				dispatched[subMesh] = true
+
 				material := subMesh.GetMaterial()
 
 				if material.NeedAlphaTesting() { // Alpha test
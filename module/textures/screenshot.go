@@ -0,0 +1,87 @@
+package textures
+
+import (
+	"errors"
+	"image"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/tools"
+	log "github.com/suiqirui1987/fly3d/tools/logrus"
+)
+
+// CaptureScreenshot renders the active camera of scene into an offscreen
+// target of width x height pixels and returns the image. A size <= 0 uses the
+// size of the canvas.
+func CaptureScreenshot(scene *engines.Scene, width int, height int) *image.RGBA {
+	camera := scene.ActiveCamera
+	if camera == nil {
+		log.Println("CaptureScreenshot active camera not set")
+		return nil
+	}
+
+	engine := scene.GetEngine()
+	if width <= 0 {
+		width = engine.GetRenderWidth()
+	}
+	if height <= 0 {
+		height = engine.GetRenderHeight()
+	}
+
+	target := NewRenderTargetTextureWithSize("screenshot", width, height, scene, false)
+	if !target.IsReady() {
+		log.Printf("CaptureScreenshot can not create a %dx%d target", width, height)
+		target.dispose()
+		return nil
+	}
+
+	for _, mesh := range scene.Meshes {
+		if mesh.IsReady() {
+			mesh.ComputeWorldMatrix()
+			target.AddRenderList(mesh)
+		}
+	}
+
+	// The projection follows the aspect ratio of the screenshot
+	aspectRatio := engine.GetAspectRatio()
+	viewMatrix := scene.GetViewMatrix()
+	projectionMatrix := scene.GetProjectionMatrix()
+
+	engine.SetAspectRatio(float32(width) / float32(height))
+	scene.SetTransformMatrix(camera.GetViewMatrix(), camera.GetProjectionMatrix())
+
+	engine.BindFramebuffer(target.GetGLTexture())
+	engine.Clear(scene.ClearColor, true, true)
+	target.Render()
+
+	engine.BindFramebuffer(target.GetGLTexture())
+	img := engine.ReadPixels(0, 0, width, height)
+
+	// Restore
+	engine.RestoreDefaultFramebuffer()
+	engine.SetAspectRatio(aspectRatio)
+	if viewMatrix != nil && projectionMatrix != nil {
+		scene.SetTransformMatrix(viewMatrix, projectionMatrix)
+	}
+	target.dispose()
+
+	return img
+}
+
+// SaveScreenshot writes CaptureScreenshot as a PNG file
+func SaveScreenshot(scene *engines.Scene, width int, height int, path string) error {
+	img := CaptureScreenshot(scene, width, height)
+	if img == nil {
+		return errors.New("can't capture screenshot")
+	}
+	return tools.SavePNG(path, img)
+}
+
+// dispose releases the target and removes it from the scene
+func (this *RenderTargetTexture) dispose() {
+	this.ReleaseGLTexture()
+
+	index := tools.IndexOf(this, this._scene.Textures)
+	if index > -1 {
+		this._scene.Textures = append(this._scene.Textures[:index], this._scene.Textures[index+1:]...)
+	}
+}
@@ -0,0 +1,147 @@
+package textures
+
+import (
+	"encoding/json"
+
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/gl"
+)
+
+func init() {
+	engines.RegisterParser("Texture", parseTexture)
+	engines.RegisterParser("CubeTexture", parseCubeTexture)
+}
+
+type textureData struct {
+	Type     string `json:"type"`
+	Url      string `json:"url"`
+	Data     []byte `json:"data,omitempty"`
+	NoMipmap bool   `json:"noMipmap"`
+
+	Level            float32 `json:"level"`
+	HasAlpha         bool    `json:"hasAlpha"`
+	CoordinatesIndex float32 `json:"coordinatesIndex"`
+	CoordinatesMode  int     `json:"coordinatesMode"`
+
+	UOffset float32 `json:"uOffset"`
+	VOffset float32 `json:"vOffset"`
+	UScale  float32 `json:"uScale"`
+	VScale  float32 `json:"vScale"`
+	UAng    float32 `json:"uAng"`
+	VAng    float32 `json:"vAng"`
+	WAng    float32 `json:"wAng"`
+	WrapU   int     `json:"wrapU"`
+	WrapV   int     `json:"wrapV"`
+}
+
+type cubeTextureData struct {
+	Type       string   `json:"type"`
+	RootUrl    string   `json:"rootUrl"`
+	Extensions []string `json:"extensions"`
+
+	Level           float32 `json:"level"`
+	HasAlpha        bool    `json:"hasAlpha"`
+	CoordinatesMode int     `json:"coordinatesMode"`
+}
+
+func (this *Texture) Serialize() interface{} {
+	if this._texture == nil || this._texture.Url == "" {
+		return nil
+	}
+
+	return &textureData{
+		Type:     "Texture",
+		Url:      this._texture.Url,
+		Data:     this._content,
+		NoMipmap: this._texture.NoMipmap,
+
+		Level:            this.Level,
+		HasAlpha:         this._hasAlpha,
+		CoordinatesIndex: this.CoordinatesIndex,
+		CoordinatesMode:  this.CoordinatesMode,
+
+		UOffset: this.UOffset,
+		VOffset: this.VOffset,
+		UScale:  this.UScale,
+		VScale:  this.VScale,
+		UAng:    this.UAng,
+		VAng:    this.VAng,
+		WAng:    this.WAng,
+		WrapU:   int(this._texture.WrapU),
+		WrapV:   int(this._texture.WrapV),
+	}
+}
+
+// Render targets and dynamic textures are filled at run time, their owners create them again
+
+func (this *DynamicTexture) Serialize() interface{} {
+	return nil
+}
+
+func (this *RenderTargetTexture) Serialize() interface{} {
+	return nil
+}
+
+func (this *CubeTexture) Serialize() interface{} {
+	if this._texture == nil {
+		return nil
+	}
+
+	return &cubeTextureData{
+		Type:       "CubeTexture",
+		RootUrl:    this._texture.Url,
+		Extensions: this.Extensions,
+
+		Level:           this.Level,
+		HasAlpha:        this._hasAlpha,
+		CoordinatesMode: this.CoordinatesMode,
+	}
+}
+
+func parseTexture(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &textureData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	var texture *Texture
+	if len(data.Data) > 0 {
+		texture = NewTextureFromData(data.Url, data.Data, scene, data.NoMipmap, 0)
+	} else {
+		texture = NewTexture(data.Url, scene, data.NoMipmap, 0)
+	}
+	if texture == nil {
+		return nil, nil
+	}
+
+	texture.Level = data.Level
+	texture._hasAlpha = data.HasAlpha
+	texture.CoordinatesIndex = data.CoordinatesIndex
+	texture.CoordinatesMode = data.CoordinatesMode
+
+	texture.UOffset = data.UOffset
+	texture.VOffset = data.VOffset
+	texture.UScale = data.UScale
+	texture.VScale = data.VScale
+	texture.UAng = data.UAng
+	texture.VAng = data.VAng
+	texture.WAng = data.WAng
+	texture._texture.WrapU = gl.Enum(data.WrapU)
+	texture._texture.WrapV = gl.Enum(data.WrapV)
+
+	return texture, nil
+}
+
+func parseCubeTexture(content []byte, scene *engines.Scene) (interface{}, error) {
+	data := &cubeTextureData{}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, err
+	}
+
+	texture := NewCubeTexture(data.RootUrl, scene, data.Extensions)
+	texture.Level = data.Level
+	texture._hasAlpha = data.HasAlpha
+	texture.CoordinatesMode = data.CoordinatesMode
+
+	return texture, nil
+}
@@ -33,6 +33,9 @@ type Texture struct {
 	_t0                    *math32.Vector3
 	_t1                    *math32.Vector3
 	_t2                    *math32.Vector3
+
+	// Encoded image of the textures created from data
+	_content []byte
 }
 
 func NewTexture(url string, scene *engines.Scene, noMipmap bool, invertY int) *Texture {
@@ -54,6 +57,27 @@ func NewTexture(url string, scene *engines.Scene, noMipmap bool, invertY int) *T
 	return this
 }
 
+// NewTextureFromData creates a texture from encoded image content, name identifies it in the texture cache
+func NewTextureFromData(name string, content []byte, scene *engines.Scene, noMipmap bool, invertY int) *Texture {
+	if name == "" || len(content) == 0 {
+		return nil
+	}
+	this := &Texture{}
+
+	this._scene = scene
+	this._texture = this._getFromCache(name, noMipmap)
+
+	if this._texture == nil {
+		this._texture = this._scene.GetEngine().CreateTextureFromData(name, content, noMipmap, invertY, scene)
+	}
+	this._content = content
+
+	this._scene.Textures = append(this._scene.Textures, this)
+
+	this.Init()
+	return this
+}
+
 func (this *Texture) Init() {
 	this.BaseTexture.Init()
 
@@ -0,0 +1,5 @@
+#ifdef BONES
+attribute vec4 matricesIndices;
+attribute vec4 matricesWeights;
+uniform mat4 mBones[BonesPerMesh];
+#endif
\ No newline at end of file
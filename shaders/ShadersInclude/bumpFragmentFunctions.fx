@@ -0,0 +1,50 @@
+#ifdef BUMP
+varying vec2 vBumpUV;
+uniform vec2 vBumpInfos;
+uniform sampler2D bumpSampler;
+
+#ifdef TANGENT
+varying vec3 vTangentW;
+varying vec3 vBitangentW;
+
+// The basis of the tangent space comes from the tangents of the mesh
+mat3 tangent_frame(vec3 normal)
+{
+	return mat3(normalize(vTangentW), normalize(vBitangentW), normal);
+}
+#else
+#extension GL_OES_standard_derivatives : enable
+
+// Thanks to http://www.thetenthplanet.de/archives/1180
+mat3 cotangent_frame(vec3 normal, vec3 p, vec2 uv)
+{
+	// get edge vectors of the pixel triangle
+	vec3 dp1 = dFdx(p);
+	vec3 dp2 = dFdy(p);
+	vec2 duv1 = dFdx(uv);
+	vec2 duv2 = dFdy(uv);
+
+	// solve the linear system
+	vec3 dp2perp = cross(dp2, normal);
+	vec3 dp1perp = cross(normal, dp1);
+	vec3 tangent = dp2perp * duv1.x + dp1perp * duv2.x;
+	vec3 binormal = dp2perp * duv1.y + dp1perp * duv2.y;
+
+	// construct a scale-invariant frame 
+	float invmax = inversesqrt(max(dot(tangent, tangent), dot(binormal, binormal)));
+	return mat3(tangent * invmax, binormal * invmax, normal);
+}
+#endif
+
+vec3 perturbNormal(vec3 viewDir)
+{
+	vec3 map = texture2D(bumpSampler, vBumpUV).xyz * vBumpInfos.y;
+	map = map * 255. / 127. - 128. / 127.;
+#ifdef TANGENT
+	mat3 TBN = tangent_frame(normalize(vNormalW));
+#else
+	mat3 TBN = cotangent_frame(vNormalW, -viewDir, vBumpUV);
+#endif
+	return normalize(TBN * map);
+}
+#endif
\ No newline at end of file
@@ -0,0 +1,16 @@
+#ifdef BUMP
+	if (vBumpInfos.x == 0.)
+	{
+		vBumpUV = vec2(bumpMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vBumpUV = vec2(bumpMatrix * vec4(uv2, 1.0, 0.0));
+	}
+
+#ifdef TANGENT
+	// w is the handedness of the tangent space, negative for mirrored uvs
+	vTangentW = normalize(vec3(finalWorld * vec4(tangent.xyz, 0.0)));
+	vBitangentW = cross(vNormalW, vTangentW) * tangent.w;
+#endif
+#endif
\ No newline at end of file
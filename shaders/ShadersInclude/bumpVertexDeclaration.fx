@@ -0,0 +1,9 @@
+#ifdef BUMP
+varying vec2 vBumpUV;
+uniform mat4 bumpMatrix;
+uniform vec2 vBumpInfos;
+#ifdef TANGENT
+varying vec3 vTangentW;
+varying vec3 vBitangentW;
+#endif
+#endif
\ No newline at end of file
@@ -0,0 +1,4 @@
+#ifdef CLIPPLANE
+	if (fClipDistance > 0.0)
+		discard;
+#endif
\ No newline at end of file
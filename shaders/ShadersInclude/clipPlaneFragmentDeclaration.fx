@@ -0,0 +1,3 @@
+#ifdef CLIPPLANE
+varying float fClipDistance;
+#endif
\ No newline at end of file
@@ -0,0 +1,3 @@
+#ifdef CLIPPLANE
+	fClipDistance = dot(worldPos, vClipPlane);
+#endif
\ No newline at end of file
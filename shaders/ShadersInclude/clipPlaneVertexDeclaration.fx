@@ -0,0 +1,4 @@
+#ifdef CLIPPLANE
+uniform vec4 vClipPlane;
+varying float fClipDistance;
+#endif
\ No newline at end of file
@@ -0,0 +1,3 @@
+#ifdef FOG
+	fFogDistance = (view * worldPos).z;
+#endif
\ No newline at end of file
@@ -0,0 +1,3 @@
+#ifdef FOG
+varying float fFogDistance;
+#endif
\ No newline at end of file
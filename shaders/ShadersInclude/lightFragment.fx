@@ -0,0 +1,14 @@
+#ifdef LIGHT{X}
+#ifdef SPOTLIGHT{X}
+	info = computeSpotLighting(viewDirectionW, normalW, vLightData{X}, vLightDirection{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightDiffuse{X}.a);
+#endif
+#ifdef HEMILIGHT{X}
+	info = computeHemisphericLighting(viewDirectionW, normalW, vLightData{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightGround{X});
+#endif
+#ifdef POINTDIRLIGHT{X}
+	info = computeLighting(viewDirectionW, normalW, vLightData{X}, vLightDiffuse{X}.rgb, vLightSpecular{X}, vLightDiffuse{X}.a);
+#endif
+#include<shadowFragment>
+	diffuseBase += info.diffuse * shadow;
+	specularBase += info.specular * shadow;
+#endif
\ No newline at end of file
@@ -0,0 +1,15 @@
+#ifdef LIGHT{X}
+uniform vec4 vLightData{X};
+uniform vec4 vLightDiffuse{X};
+uniform vec3 vLightSpecular{X};
+#ifdef SHADOW{X}
+varying vec4 vPositionFromLight{X};
+uniform sampler2D shadowSampler{X};
+#endif
+#ifdef SPOTLIGHT{X}
+uniform vec4 vLightDirection{X};
+#endif
+#ifdef HEMILIGHT{X}
+uniform vec3 vLightGround{X};
+#endif
+#endif
\ No newline at end of file
@@ -0,0 +1,6 @@
+#ifdef MORPHTARGET{X}
+	positionUpdated += (position{X} - position) * morphTargetInfluences[{X}];
+#ifdef MORPHTARGETS_NORMAL
+	normalUpdated += (normal{X} - normal) * morphTargetInfluences[{X}];
+#endif
+#endif
\ No newline at end of file
@@ -0,0 +1,6 @@
+#ifdef MORPHTARGET{X}
+attribute vec3 position{X};
+#ifdef MORPHTARGETS_NORMAL
+attribute vec3 normal{X};
+#endif
+#endif
\ No newline at end of file
@@ -0,0 +1,3 @@
+#ifdef MORPHTARGETS
+uniform float morphTargetInfluences[NUM_MORPH_INFLUENCERS];
+#endif
\ No newline at end of file
@@ -0,0 +1,9 @@
+#ifdef SHADOW{X}
+	#ifdef SHADOWVSM{X}
+		shadow = computeShadowWithVSM(vPositionFromLight{X}, shadowSampler{X});
+	#else
+		shadow = computeShadow(vPositionFromLight{X}, shadowSampler{X});
+	#endif
+#else
+	shadow = 1.;
+#endif
\ No newline at end of file
@@ -0,0 +1,5 @@
+#ifdef SHADOWS
+#ifdef LIGHT{X}
+	vPositionFromLight{X} = lightMatrix{X} * worldPos;
+#endif
+#endif
\ No newline at end of file
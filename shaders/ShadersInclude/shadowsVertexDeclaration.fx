@@ -0,0 +1,6 @@
+#ifdef SHADOWS
+#ifdef LIGHT{X}
+uniform mat4 lightMatrix{X};
+varying vec4 vPositionFromLight{X};
+#endif
+#endif
\ No newline at end of file
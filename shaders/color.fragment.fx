@@ -0,0 +1,9 @@
+#ifdef GL_ES
+precision mediump float;
+#endif
+
+uniform vec4 color;
+
+void main(void) {
+	gl_FragColor = color;
+}
\ No newline at end of file
@@ -12,6 +12,9 @@ precision mediump float;
 // Attributes
 attribute vec3 position;
 attribute vec3 normal;
+#ifdef TANGENT
+attribute vec4 tangent;
+#endif
 #ifdef UV1
 attribute vec2 uv;
 #endif
@@ -21,11 +24,18 @@ attribute vec2 uv2;
 #ifdef VERTEXCOLOR
 attribute vec3 color;
 #endif
+#include<instancesDeclaration>
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
 
 // Uniforms
 uniform mat4 world;
 uniform mat4 view;
 uniform mat4 worldViewProjection;
+#if defined(INSTANCES) || defined(BONES)
+uniform mat4 viewProjection;
+#endif
+#include<morphTargetsVertexGlobalDeclaration>
 
 #ifdef DIFFUSE
 varying vec2 vDiffuseUV;
@@ -64,11 +74,7 @@ uniform vec2 vSpecularInfos;
 uniform mat4 specularMatrix;
 #endif
 
-#ifdef BUMP
-varying vec2 vBumpUV;
-uniform vec2 vBumpInfos;
-uniform mat4 bumpMatrix;
-#endif
+#include<bumpVertexDeclaration>
 
 // Output
 varying vec3 vPositionW;
@@ -78,33 +84,10 @@ varying vec3 vNormalW;
 varying vec3 vColor;
 #endif
 
-#ifdef CLIPPLANE
-uniform vec4 vClipPlane;
-varying float fClipDistance;
-#endif
+#include<clipPlaneVertexDeclaration>
+#include<fogVertexDeclaration>
 
-#ifdef FOG
-varying float fFogDistance;
-#endif
-
-#ifdef SHADOWS
-#ifdef LIGHT0
-uniform mat4 lightMatrix0;
-varying vec4 vPositionFromLight0;
-#endif
-#ifdef LIGHT1
-uniform mat4 lightMatrix1;
-varying vec4 vPositionFromLight1;
-#endif
-#ifdef LIGHT2
-uniform mat4 lightMatrix2;
-varying vec4 vPositionFromLight2;
-#endif
-#ifdef LIGHT3
-uniform mat4 lightMatrix3;
-varying vec4 vPositionFromLight3;
-#endif
-#endif
+#include<shadowsVertexDeclaration>[0..maxSimultaneousLights]
 
 #ifdef REFLECTION
 vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
@@ -143,11 +126,22 @@ vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
 #endif
 
 void main(void) {
-	gl_Position = worldViewProjection * vec4(position, 1.0);
+	vec3 positionUpdated = position;
+	vec3 normalUpdated = normal;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+#include<instancesVertex>
+#include<bonesVertex>
+
+#if defined(INSTANCES) || defined(BONES)
+	gl_Position = viewProjection * finalWorld * vec4(positionUpdated, 1.0);
+#else
+	gl_Position = worldViewProjection * vec4(positionUpdated, 1.0);
+#endif
 
-	vec4 worldPos = world * vec4(position, 1.0);
+	vec4 worldPos = finalWorld * vec4(positionUpdated, 1.0);
 	vPositionW = vec3(worldPos);
-	vNormalW = normalize(vec3(world * vec4(normal, 0.0)));
+	vNormalW = normalize(vec3(finalWorld * vec4(normalUpdated, 0.0)));
 
 	// Texture coordinates
 #ifndef UV1
@@ -216,42 +210,16 @@ void main(void) {
 	}
 #endif
 
-#ifdef BUMP
-	if (vBumpInfos.x == 0.)
-	{
-		vBumpUV = vec2(bumpMatrix * vec4(uv, 1.0, 0.0));
-	}
-	else
-	{
-		vBumpUV = vec2(bumpMatrix * vec4(uv2, 1.0, 0.0));
-	}
-#endif
+#include<bumpVertex>
 
 	// Clip plane
-#ifdef CLIPPLANE
-	fClipDistance = dot(worldPos, vClipPlane);
-#endif
+#include<clipPlaneVertex>
 
 	// Fog
-#ifdef FOG
-	fFogDistance = (view * worldPos).z;
-#endif
+#include<fogVertex>
 
 	// Shadows
-#ifdef SHADOWS
-#ifdef LIGHT0
-	vPositionFromLight0 = lightMatrix0 * vec4(position, 1.0);
-#endif
-#ifdef LIGHT1
-	vPositionFromLight1 = lightMatrix1 * vec4(position, 1.0);
-#endif
-#ifdef LIGHT2
-	vPositionFromLight2 = lightMatrix2 * vec4(position, 1.0);
-#endif
-#ifdef LIGHT3
-	vPositionFromLight3 = lightMatrix3 * vec4(position, 1.0);
-#endif
-#endif
+#include<shadowsVertex>[0..maxSimultaneousLights]
 
 	// Vertex color
 #ifdef VERTEXCOLOR
@@ -12,15 +12,7 @@ uniform vec4 vSpecularColor;
 uniform vec3 vEmissiveColor;
 
 // Lights
-#ifdef LIGHT0
-uniform vec4 vLightData0;
-uniform vec3 vLightDiffuse0;
-uniform vec3 vLightSpecular0;
-#ifdef SHADOW0
-varying vec4 vPositionFromLight0;
-uniform sampler2D shadowSampler0;
-#endif
-#endif
+#include<lightFragmentDeclaration>[0..1]
 
 //#ifdef LIGHT1
 //uniform vec4 vLightData1;
@@ -76,9 +68,7 @@ uniform sampler2D specularSampler;
 varying vec3 vPositionW;
 varying vec3 vNormalW;
 
-#ifdef CLIPPLANE
-varying float fClipDistance;
-#endif
+#include<clipPlaneFragmentDeclaration>
 
 // Shadows
 #ifdef SHADOWS
@@ -111,43 +101,7 @@ float ChebychevInequality(vec2 moments, float t)
 
 #endif
 
-// Fog
-#ifdef FOG
-
-#define FOGMODE_NONE    0.
-#define FOGMODE_EXP     1.
-#define FOGMODE_EXP2    2.
-#define FOGMODE_LINEAR  3.
-#define E 2.71828
-
-uniform vec4 vFogInfos;
-uniform vec3 vFogColor;
-varying float fFogDistance;
-
-float CalcFogFactor()
-{
-	float fogCoeff = 1.0;
-	float fogStart = vFogInfos.y;
-	float fogEnd = vFogInfos.z;
-	float fogDensity = vFogInfos.w;
-
-	if (FOGMODE_LINEAR == vFogInfos.x)
-	{
-		fogCoeff = (fogEnd - fFogDistance) / (fogEnd - fogStart);
-	}
-	else if (FOGMODE_EXP == vFogInfos.x)
-	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fogDensity);
-	}
-	else if (FOGMODE_EXP2 == vFogInfos.x)
-	{
-		fogCoeff = 1.0 / pow(E, fFogDistance * fFogDistance * fogDensity * fogDensity);
-	}
-
-	return min(1., max(0., fogCoeff));
-}
-
-#endif
+#include<fogFragmentDeclaration>
 
 vec3 computeDiffuseLighting(vec3 vNormal, vec4 lightData, vec3 diffuseColor) {
 	vec3 lightVectorW;
@@ -187,10 +141,7 @@ vec3 computeSpecularLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData,
 
 void main(void) {
 	// Clip plane
-#ifdef CLIPPLANE
-	if (fClipDistance > 0.0)
-		discard;
-#endif
+#include<clipPlaneFragment>
 
 	vec3 viewDirectionW = normalize(vEyePosition - vPositionW);
 
@@ -246,7 +197,7 @@ void main(void) {
 		#endif
 		}
 	#endif
-	diffuseBase += computeDiffuseLighting(normalW, vLightData0, vLightDiffuse0) * shadow;
+	diffuseBase += computeDiffuseLighting(normalW, vLightData0, vLightDiffuse0.rgb) * shadow;
 	specularBase += computeSpecularLighting(viewDirectionW, normalW, vLightData0, vLightSpecular0) * shadow;
 #endif
 //#ifdef LIGHT1
@@ -308,10 +259,7 @@ void main(void) {
 
 	vec4 color = vec4(finalDiffuse * baseAmbientColor + finalSpecular + reflectionColor, alpha);
 
-#ifdef FOG
-	float fog = CalcFogFactor();
-	color.rgb = fog * color.rgb + (1.0 - fog) * vFogColor;
-#endif
+#include<fogFragment>
 
 	gl_FragColor = color;
 }
\ No newline at end of file
@@ -18,11 +18,18 @@ attribute vec2 uv;
 #ifdef UV2
 attribute vec2 uv2;
 #endif
+#include<instancesDeclaration>
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
 
 // Uniforms
 uniform mat4 world;
 uniform mat4 view;
 uniform mat4 worldViewProjection;
+#if defined(INSTANCES) || defined(BONES)
+uniform mat4 viewProjection;
+#endif
+#include<morphTargetsVertexGlobalDeclaration>
 
 #ifdef DIFFUSE
 varying vec2 vDiffuseUV;
@@ -66,21 +73,10 @@ uniform mat4 specularMatrix;
 varying vec3 vPositionW;
 varying vec3 vNormalW;
 
-#ifdef CLIPPLANE
-uniform vec4 vClipPlane;
-varying float fClipDistance;
-#endif
+#include<clipPlaneVertexDeclaration>
+#include<fogVertexDeclaration>
 
-#ifdef FOG
-varying float fFogDistance;
-#endif
-
-#ifdef SHADOWS
-#ifdef LIGHT0
-uniform mat4 lightMatrix0;
-varying vec4 vPositionFromLight0;
-#endif
-#endif
+#include<shadowsVertexDeclaration>[0..1]
 
 #ifdef REFLECTION
 vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
@@ -119,11 +115,22 @@ vec3 computeReflectionCoords(float mode, vec4 worldPos, vec3 worldNormal)
 #endif
 
 void main(void) {
-	gl_Position = worldViewProjection * vec4(position, 1.0);
+	vec3 positionUpdated = position;
+	vec3 normalUpdated = normal;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+#include<instancesVertex>
+#include<bonesVertex>
 
-	vec4 worldPos = world * vec4(position, 1.0);
+#if defined(INSTANCES) || defined(BONES)
+	gl_Position = viewProjection * finalWorld * vec4(positionUpdated, 1.0);
+#else
+	gl_Position = worldViewProjection * vec4(positionUpdated, 1.0);
+#endif
+
+	vec4 worldPos = finalWorld * vec4(positionUpdated, 1.0);
 	vPositionW = vec3(worldPos);
-	vNormalW = normalize(vec3(world * vec4(normal, 0.0)));
+	vNormalW = normalize(vec3(finalWorld * vec4(normalUpdated, 0.0)));
 
 	// Texture coordinates
 #ifndef UV1
@@ -193,19 +200,11 @@ void main(void) {
 #endif
 
 	// Clip plane
-#ifdef CLIPPLANE
-	fClipDistance = dot(worldPos, vClipPlane);
-#endif
+#include<clipPlaneVertex>
 
 	// Fog
-#ifdef FOG
-	fFogDistance = (view * worldPos).z;
-#endif
+#include<fogVertex>
 
 	// Shadows
-#ifdef SHADOWS
-#ifdef LIGHT0
-	vPositionFromLight0 = lightMatrix0 * vec4(position, 1.0);
-#endif
-#endif
+#include<shadowsVertex>[0..1]
 }
\ No newline at end of file
@@ -0,0 +1,303 @@
+#ifdef TEXTURELOD
+#ifdef GL_ES
+#extension GL_EXT_shader_texture_lod : enable
+#else
+#extension GL_ARB_shader_texture_lod : enable
+#define textureCubeLodEXT textureCubeLod
+#endif
+#endif
+
+#ifdef GL_ES
+precision mediump float;
+#endif
+
+#define PI 3.14159265
+
+// Constants
+uniform vec3 vEyePosition;
+uniform vec3 vAmbientColor;
+uniform vec4 vAlbedoColor;
+uniform vec2 vMetallicRoughness;
+uniform vec3 vEmissiveColor;
+
+// Input
+varying vec3 vPositionW;
+varying vec3 vNormalW;
+
+#ifdef VERTEXCOLOR
+varying vec3 vColor;
+#endif
+
+// Lights
+#include<lightFragmentDeclaration>[0..maxSimultaneousLights]
+
+// Samplers
+#ifdef ALBEDO
+varying vec2 vAlbedoUV;
+uniform sampler2D albedoSampler;
+uniform vec2 vAlbedoInfos;
+#endif
+
+// Roughness in green and metallic in blue, as in glTF
+#ifdef METALLICROUGHNESS
+varying vec2 vMetallicRoughnessUV;
+uniform sampler2D metallicRoughnessSampler;
+uniform vec2 vMetallicRoughnessInfos;
+#endif
+
+// Occlusion in red, the level is the strength
+#ifdef AO
+varying vec2 vAOUV;
+uniform sampler2D aoSampler;
+uniform vec2 vAOInfos;
+#endif
+
+#ifdef EMISSIVE
+varying vec2 vEmissiveUV;
+uniform sampler2D emissiveSampler;
+uniform vec2 vEmissiveInfos;
+#endif
+
+// Image based lighting, the mip levels of the cube hold the environment
+// prefiltered for increasing roughness. x is the level, y the last mip
+#ifdef REFLECTION
+uniform samplerCube reflectionCubeSampler;
+uniform mat4 reflectionMatrix;
+uniform vec2 vReflectionInfos;
+#endif
+
+// Scale and bias of the specular reflectance by NdotV and roughness
+#ifdef BRDF
+uniform sampler2D brdfSampler;
+#endif
+
+#include<shadowsFragmentFunctions>
+
+#include<bumpFragmentFunctions>
+
+#include<clipPlaneFragmentDeclaration>
+#include<fogFragmentDeclaration>
+
+// Cook-Torrance
+float distributionGGX(float NdotH, float alpha)
+{
+	float alpha2 = alpha * alpha;
+	float d = NdotH * NdotH * (alpha2 - 1.) + 1.;
+	return alpha2 / (PI * d * d);
+}
+
+float visibilitySmithGGX(float NdotL, float NdotV, float alpha)
+{
+	float k = alpha * 0.5;
+	float gl = NdotL / (NdotL * (1. - k) + k);
+	float gv = NdotV / (NdotV * (1. - k) + k);
+	return gl * gv / max(4. * NdotL * NdotV, 0.0001);
+}
+
+vec3 fresnelSchlick(float VdotH, vec3 F0)
+{
+	return F0 + (1. - F0) * pow(1. - VdotH, 5.);
+}
+
+vec3 fresnelSchlickRoughness(float NdotV, vec3 F0, float roughness)
+{
+	return F0 + (max(vec3(1. - roughness), F0) - F0) * pow(1. - NdotV, 5.);
+}
+
+// Thanks to https://www.unrealengine.com/blog/physically-based-shading-on-mobile
+vec2 environmentBRDFApprox(float NdotV, float roughness)
+{
+	const vec4 c0 = vec4(-1., -0.0275, -0.572, 0.022);
+	const vec4 c1 = vec4(1., 0.0425, 1.04, -0.04);
+	vec4 r = roughness * c0 + c1;
+	float a004 = min(r.x * r.x, exp2(-9.28 * NdotV)) * r.x + r.y;
+	return vec2(-1.04, 1.04) * a004 + r.zw;
+}
+
+// Light Computing
+struct lightingInfo
+{
+	vec3 diffuse;
+	vec3 specular;
+};
+
+// The diffuse part is multiplied by the diffuse color of the surface, the
+// specular part already holds its reflectance
+lightingInfo computeBRDF(vec3 viewDirectionW, vec3 vNormal, vec3 lightVectorW, vec3 diffuseColor, vec3 specularColor, vec3 F0, float alpha)
+{
+	lightingInfo result;
+
+	float NdotL = max(0., dot(vNormal, lightVectorW));
+	float NdotV = max(0.0001, dot(vNormal, viewDirectionW));
+	vec3 H = normalize(viewDirectionW + lightVectorW);
+	float NdotH = max(0., dot(vNormal, H));
+	float VdotH = max(0., dot(viewDirectionW, H));
+
+	vec3 F = fresnelSchlick(VdotH, F0);
+	float specular = distributionGGX(NdotH, alpha) * visibilitySmithGGX(NdotL, NdotV, alpha);
+
+	// The lights are not divided by PI, neither is the lambertian diffuse
+	result.diffuse = (1. - F) * NdotL * diffuseColor;
+	result.specular = F * (specular * PI * NdotL) * specularColor;
+
+	return result;
+}
+
+lightingInfo computeLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, float range, vec3 F0, float alpha) {
+	vec3 lightVectorW;
+	float attenuation = 1.0;
+	if (lightData.w == 0.)
+	{
+		vec3 direction = lightData.xyz - vPositionW;
+
+		attenuation = max(0., 1.0 - length(direction) / range);
+		lightVectorW = normalize(direction);
+	}
+	else
+	{
+		lightVectorW = normalize(-lightData.xyz);
+	}
+
+	return computeBRDF(viewDirectionW, vNormal, lightVectorW, diffuseColor * attenuation, specularColor * attenuation, F0, alpha);
+}
+
+lightingInfo computeSpotLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec4 lightDirection, vec3 diffuseColor, vec3 specularColor, float range, vec3 F0, float alpha) {
+	lightingInfo result;
+
+	vec3 direction = lightData.xyz - vPositionW;
+	vec3 lightVectorW = normalize(direction);
+
+	float cosAngle = max(0., dot(-lightDirection.xyz, lightVectorW));
+
+	if (cosAngle >= lightDirection.w)
+	{
+		cosAngle = max(0., pow(cosAngle, lightData.w));
+		float spotAtten = max(0., (cosAngle - lightDirection.w) / (1. - cosAngle));
+		spotAtten *= max(0., 1.0 - length(direction) / range);
+
+		return computeBRDF(viewDirectionW, vNormal, lightVectorW, diffuseColor * spotAtten, specularColor * spotAtten, F0, alpha);
+	}
+
+	result.diffuse = vec3(0.);
+	result.specular = vec3(0.);
+
+	return result;
+}
+
+lightingInfo computeHemisphericLighting(vec3 viewDirectionW, vec3 vNormal, vec4 lightData, vec3 diffuseColor, vec3 specularColor, vec3 groundColor, vec3 F0, float alpha) {
+	lightingInfo result = computeBRDF(viewDirectionW, vNormal, lightData.xyz, diffuseColor, specularColor, F0, alpha);
+
+	// Diffuse
+	float ndl = dot(vNormal, lightData.xyz) * 0.5 + 0.5;
+	result.diffuse = mix(groundColor, diffuseColor, ndl);
+
+	return result;
+}
+
+void main(void) {
+	// Clip plane
+#include<clipPlaneFragment>
+
+	vec3 viewDirectionW = normalize(vEyePosition - vPositionW);
+
+	// Albedo
+	vec4 albedo = vAlbedoColor;
+
+#ifdef VERTEXCOLOR
+	albedo.rgb *= vColor;
+#endif
+
+#ifdef ALBEDO
+	vec4 albedoMap = texture2D(albedoSampler, vAlbedoUV);
+
+#ifdef ALPHATEST
+	if (albedoMap.a < 0.4)
+		discard;
+#endif
+
+	albedo.rgb *= albedoMap.rgb * vAlbedoInfos.y;
+#endif
+
+	// Metallic and roughness
+	float metallic = vMetallicRoughness.x;
+	float roughness = vMetallicRoughness.y;
+
+#ifdef METALLICROUGHNESS
+	vec4 metallicRoughnessMap = texture2D(metallicRoughnessSampler, vMetallicRoughnessUV);
+	metallic *= metallicRoughnessMap.b;
+	roughness *= metallicRoughnessMap.g;
+#endif
+
+	metallic = clamp(metallic, 0., 1.);
+	roughness = clamp(roughness, 0.04, 1.);
+	float alpha = roughness * roughness;
+
+	vec3 diffuseColor = albedo.rgb * (1. - metallic);
+	vec3 F0 = mix(vec3(0.04), albedo.rgb, metallic);
+
+	// Bump
+	vec3 normalW = normalize(vNormalW);
+
+#ifdef BUMP
+	normalW = perturbNormal(viewDirectionW);
+#endif
+
+	// Lighting
+	vec3 diffuseBase = vec3(0., 0., 0.);
+	vec3 specularBase = vec3(0., 0., 0.);
+	float shadow = 1.;
+	lightingInfo info;
+
+#include<pbrLightFragment>[0..maxSimultaneousLights]
+
+	// Ambient and image based lighting
+	float NdotV = max(0.0001, dot(normalW, viewDirectionW));
+	vec3 F = fresnelSchlickRoughness(NdotV, F0, roughness);
+
+#ifdef BRDF
+	vec2 environmentBRDF = texture2D(brdfSampler, vec2(NdotV, roughness)).rg;
+#else
+	vec2 environmentBRDF = environmentBRDFApprox(NdotV, roughness);
+#endif
+
+	vec3 irradiance = vAmbientColor;
+	vec3 environmentSpecular = vec3(0., 0., 0.);
+
+#ifdef REFLECTION
+	vec3 irradianceVector = vec3(reflectionMatrix * vec4(normalW, 0));
+	vec3 reflectionVector = vec3(reflectionMatrix * vec4(reflect(-viewDirectionW, normalW), 0));
+
+#ifdef TEXTURELOD
+	irradiance += textureCubeLodEXT(reflectionCubeSampler, irradianceVector, vReflectionInfos.y).rgb * vReflectionInfos.x;
+	environmentSpecular = textureCubeLodEXT(reflectionCubeSampler, reflectionVector, roughness * vReflectionInfos.y).rgb * vReflectionInfos.x;
+#else
+	// The bias is added to the level the derivatives pick
+	irradiance += textureCube(reflectionCubeSampler, irradianceVector, vReflectionInfos.y).rgb * vReflectionInfos.x;
+	environmentSpecular = textureCube(reflectionCubeSampler, reflectionVector, roughness * vReflectionInfos.y).rgb * vReflectionInfos.x;
+#endif
+#endif
+
+	vec3 ambientOcclusion = vec3(1., 1., 1.);
+
+#ifdef AO
+	ambientOcclusion = mix(vec3(1.), texture2D(aoSampler, vAOUV).rrr, vAOInfos.y);
+#endif
+
+	vec3 ambientDiffuse = irradiance * (1. - F) * diffuseColor;
+	vec3 ambientSpecular = environmentSpecular * (F0 * environmentBRDF.x + environmentBRDF.y);
+
+	// Emissive
+	vec3 emissiveColor = vEmissiveColor;
+
+#ifdef EMISSIVE
+	emissiveColor += texture2D(emissiveSampler, vEmissiveUV).rgb * vEmissiveInfos.y;
+#endif
+
+	// Composition
+	vec3 finalDiffuse = diffuseBase * diffuseColor;
+	vec4 color = vec4(finalDiffuse + specularBase + (ambientDiffuse + ambientSpecular) * ambientOcclusion + emissiveColor, albedo.a);
+
+#include<fogFragment>
+
+	gl_FragColor = color;
+}
\ No newline at end of file
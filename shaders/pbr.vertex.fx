@@ -0,0 +1,157 @@
+#ifdef GL_ES
+precision mediump float;
+#endif
+
+// Attributes
+attribute vec3 position;
+attribute vec3 normal;
+#ifdef TANGENT
+attribute vec4 tangent;
+#endif
+#ifdef UV1
+attribute vec2 uv;
+#endif
+#ifdef UV2
+attribute vec2 uv2;
+#endif
+#ifdef VERTEXCOLOR
+attribute vec3 color;
+#endif
+#include<instancesDeclaration>
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
+
+// Uniforms
+uniform mat4 world;
+uniform mat4 view;
+uniform mat4 worldViewProjection;
+#if defined(INSTANCES) || defined(BONES)
+uniform mat4 viewProjection;
+#endif
+#include<morphTargetsVertexGlobalDeclaration>
+
+#ifdef ALBEDO
+varying vec2 vAlbedoUV;
+uniform mat4 albedoMatrix;
+uniform vec2 vAlbedoInfos;
+#endif
+
+#ifdef METALLICROUGHNESS
+varying vec2 vMetallicRoughnessUV;
+uniform mat4 metallicRoughnessMatrix;
+uniform vec2 vMetallicRoughnessInfos;
+#endif
+
+#ifdef AO
+varying vec2 vAOUV;
+uniform mat4 aoMatrix;
+uniform vec2 vAOInfos;
+#endif
+
+#ifdef EMISSIVE
+varying vec2 vEmissiveUV;
+uniform mat4 emissiveMatrix;
+uniform vec2 vEmissiveInfos;
+#endif
+
+#include<bumpVertexDeclaration>
+
+// Output
+varying vec3 vPositionW;
+varying vec3 vNormalW;
+
+#ifdef VERTEXCOLOR
+varying vec3 vColor;
+#endif
+
+#include<clipPlaneVertexDeclaration>
+#include<fogVertexDeclaration>
+
+#include<shadowsVertexDeclaration>[0..maxSimultaneousLights]
+
+void main(void) {
+	vec3 positionUpdated = position;
+	vec3 normalUpdated = normal;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+#include<instancesVertex>
+#include<bonesVertex>
+
+#if defined(INSTANCES) || defined(BONES)
+	gl_Position = viewProjection * finalWorld * vec4(positionUpdated, 1.0);
+#else
+	gl_Position = worldViewProjection * vec4(positionUpdated, 1.0);
+#endif
+
+	vec4 worldPos = finalWorld * vec4(positionUpdated, 1.0);
+	vPositionW = vec3(worldPos);
+	vNormalW = normalize(vec3(finalWorld * vec4(normalUpdated, 0.0)));
+
+	// Texture coordinates
+#ifndef UV1
+	vec2 uv = vec2(0., 0.);
+#endif
+#ifndef UV2
+	vec2 uv2 = vec2(0., 0.);
+#endif
+
+#ifdef ALBEDO
+	if (vAlbedoInfos.x == 0.)
+	{
+		vAlbedoUV = vec2(albedoMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vAlbedoUV = vec2(albedoMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#ifdef METALLICROUGHNESS
+	if (vMetallicRoughnessInfos.x == 0.)
+	{
+		vMetallicRoughnessUV = vec2(metallicRoughnessMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vMetallicRoughnessUV = vec2(metallicRoughnessMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#ifdef AO
+	if (vAOInfos.x == 0.)
+	{
+		vAOUV = vec2(aoMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vAOUV = vec2(aoMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#ifdef EMISSIVE
+	if (vEmissiveInfos.x == 0.)
+	{
+		vEmissiveUV = vec2(emissiveMatrix * vec4(uv, 1.0, 0.0));
+	}
+	else
+	{
+		vEmissiveUV = vec2(emissiveMatrix * vec4(uv2, 1.0, 0.0));
+	}
+#endif
+
+#include<bumpVertex>
+
+	// Clip plane
+#include<clipPlaneVertex>
+
+	// Fog
+#include<fogVertex>
+
+	// Shadows
+#include<shadowsVertex>[0..maxSimultaneousLights]
+
+	// Vertex color
+#ifdef VERTEXCOLOR
+	vColor = color;
+#endif
+}
\ No newline at end of file
@@ -4,11 +4,21 @@ precision mediump float;
 
 // Attribute
 attribute vec3 position;
+#include<bonesDeclaration>
+#include<morphTargetsVertexDeclaration>[0..NUM_MORPH_INFLUENCERS]
 
 // Uniform
 uniform mat4 worldViewProjection;
+#include<morphTargetsVertexGlobalDeclaration>
 
 void main(void)
 {
-	gl_Position = worldViewProjection * vec4(position, 1.0);
+	vec3 positionUpdated = position;
+#include<morphTargetsVertex>[0..NUM_MORPH_INFLUENCERS]
+
+	// The world is in worldViewProjection, the bones are applied on top of it
+	mat4 finalWorld = mat4(1.0);
+#include<bonesVertex>
+
+	gl_Position = worldViewProjection * finalWorld * vec4(positionUpdated, 1.0);
 }
\ No newline at end of file
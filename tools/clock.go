@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"time"
+)
+
+// Clocks keep the time of a scene in milliseconds. Tick is called once per
+// frame, the scale and the pause state apply to the time seen by the scene.
+
+type baseClock struct {
+	fpsMeter
+
+	_time      float64
+	_deltaTime float32
+	_scale     float32
+	_paused    bool
+}
+
+func (this *baseClock) init() {
+	this._time = 0
+	this._deltaTime = 0
+	this._scale = 1.0
+	this._paused = false
+	this.initFps()
+}
+
+func (this *baseClock) advance(elapsed float32) float32 {
+	this.measureFps(elapsed)
+
+	if this._paused {
+		this._deltaTime = 0
+	} else {
+		this._deltaTime = elapsed * this._scale
+	}
+	this._time += float64(this._deltaTime)
+
+	return this._deltaTime
+}
+
+func (this *baseClock) Now() float64 {
+	return this._time
+}
+
+// SetTime moves the clock to ms, e.g. to start a replay at a given time
+func (this *baseClock) SetTime(ms float64) {
+	this._time = ms
+}
+
+func (this *baseClock) GetDeltaTime() float32 {
+	return this._deltaTime
+}
+
+func (this *baseClock) SetScale(scale float32) {
+	this._scale = scale
+}
+
+func (this *baseClock) GetScale() float32 {
+	return this._scale
+}
+
+func (this *baseClock) Pause() {
+	this._paused = true
+}
+
+func (this *baseClock) Resume() {
+	this._paused = false
+}
+
+func (this *baseClock) IsPaused() bool {
+	return this._paused
+}
+
+// RealTimeClock follows the wall clock
+type RealTimeClock struct {
+	baseClock
+
+	_last    time.Time
+	_started bool
+}
+
+func NewRealTimeClock() *RealTimeClock {
+	this := &RealTimeClock{}
+	this.init()
+	this._started = false
+	return this
+}
+
+func (this *RealTimeClock) Tick() float32 {
+	now := time.Now()
+
+	var elapsed float32
+	if this._started {
+		elapsed = float32(now.Sub(this._last).Nanoseconds()) / 1e6
+	}
+	this._last = now
+	this._started = true
+
+	return this.advance(elapsed)
+}
+
+// FixedStepClock advances by Step milliseconds every frame, whatever the real frame time
+type FixedStepClock struct {
+	baseClock
+
+	Step float32
+}
+
+func NewFixedStepClock(step float32) *FixedStepClock {
+	this := &FixedStepClock{}
+	this.init()
+	this.Step = step
+	return this
+}
+
+func (this *FixedStepClock) Tick() float32 {
+	return this.advance(this.Step)
+}
+
+// ManualClock only advances by the time passed to Advance, the next Tick reports it as one frame
+type ManualClock struct {
+	baseClock
+
+	_pending float32
+}
+
+func NewManualClock() *ManualClock {
+	this := &ManualClock{}
+	this.init()
+	this._pending = 0
+	return this
+}
+
+func (this *ManualClock) Advance(ms float32) {
+	this._pending += ms
+}
+
+func (this *ManualClock) Tick() float32 {
+	elapsed := this._pending
+	this._pending = 0
+
+	return this.advance(elapsed)
+}
@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedStepClock(t *testing.T) {
+	tests := []struct {
+		name   string
+		step   float32
+		scale  float32
+		paused bool
+		frames int
+		delta  float32
+		now    float64
+	}{
+		{"60 fps", 1000.0 / 60.0, 1, false, 60, 1000.0 / 60.0, 1000},
+		{"30 fps", 1000.0 / 30.0, 1, false, 30, 1000.0 / 30.0, 1000},
+		{"half speed", 20, 0.5, false, 10, 10, 100},
+		{"double speed", 20, 2, false, 10, 40, 400},
+		{"paused", 20, 1, true, 10, 0, 0},
+	}
+
+	for _, test := range tests {
+		clock := NewFixedStepClock(test.step)
+		clock.SetScale(test.scale)
+		if test.paused {
+			clock.Pause()
+		}
+
+		var delta float32
+		for frame := 0; frame < test.frames; frame++ {
+			delta = clock.Tick()
+		}
+
+		if !near(float64(delta), float64(test.delta)) || !near(float64(clock.GetDeltaTime()), float64(test.delta)) {
+			t.Errorf("%s: delta time %v, want %v", test.name, delta, test.delta)
+		}
+		if !near(clock.Now(), test.now) {
+			t.Errorf("%s: time %v, want %v", test.name, clock.Now(), test.now)
+		}
+		// The frame rate is measured on the unscaled time, even when paused
+		if fps := 1000.0 / test.step; !near(float64(clock.GetFps()), float64(fps)) {
+			t.Errorf("%s: %v fps, want %v", test.name, clock.GetFps(), fps)
+		}
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	tests := []struct {
+		name     string
+		advances []float32
+		delta    float32
+		now      float64
+		fps      float32
+	}{
+		{"no advance", nil, 0, 0, 60},
+		{"one advance", []float32{10}, 10, 10, 100},
+		{"summed advances", []float32{10, 15, 25}, 50, 50, 20},
+	}
+
+	for _, test := range tests {
+		clock := NewManualClock()
+		for _, ms := range test.advances {
+			clock.Advance(ms)
+		}
+
+		if delta := clock.Tick(); delta != test.delta {
+			t.Errorf("%s: delta time %v, want %v", test.name, delta, test.delta)
+		}
+		if clock.Now() != test.now {
+			t.Errorf("%s: time %v, want %v", test.name, clock.Now(), test.now)
+		}
+		if !near(float64(clock.GetFps()), float64(test.fps)) {
+			t.Errorf("%s: %v fps, want %v", test.name, clock.GetFps(), test.fps)
+		}
+
+		// The pending time is reported once
+		if delta := clock.Tick(); delta != 0 {
+			t.Errorf("%s: second delta time %v, want 0", test.name, delta)
+		}
+	}
+}
+
+func TestClockSetTime(t *testing.T) {
+	clock := NewFixedStepClock(10)
+	clock.Tick()
+	clock.SetTime(5000)
+	clock.Tick()
+
+	if clock.Now() != 5010 {
+		t.Errorf("time %v, want 5010", clock.Now())
+	}
+
+	clock.Pause()
+	clock.Tick()
+	clock.Resume()
+	clock.Tick()
+
+	if clock.Now() != 5020 {
+		t.Errorf("time after pause %v, want 5020", clock.Now())
+	}
+}
+
+func TestRealTimeClock(t *testing.T) {
+	clock := NewRealTimeClock()
+
+	// The first frame has no duration
+	if delta := clock.Tick(); delta != 0 {
+		t.Errorf("first delta time %v, want 0", delta)
+	}
+	if clock.GetFps() != 60 {
+		t.Errorf("%v fps before the second frame, want 60", clock.GetFps())
+	}
+	if delta := clock.Tick(); delta < 0 {
+		t.Errorf("negative delta time %v", delta)
+	}
+}
+
+func TestFpsMeter(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []float32
+		fps    float32
+	}{
+		{"no frame", nil, 60},
+		{"empty frames", []float32{0, 0, -1}, 60},
+		{"steady", []float32{20, 20, 20}, 50},
+		{"average", []float32{10, 30}, 50},
+		{"window", append(repeat(100, 10), repeat(10, fpsRange)...), 100},
+	}
+
+	for _, test := range tests {
+		var meter fpsMeter
+		meter.initFps()
+		for _, elapsed := range test.frames {
+			meter.measureFps(elapsed)
+		}
+
+		if !near(float64(meter.GetFps()), float64(test.fps)) {
+			t.Errorf("%s: %v fps, want %v", test.name, meter.GetFps(), test.fps)
+		}
+	}
+}
+
+func TestMeasureFps(t *testing.T) {
+	MeasureFps()
+	time.Sleep(5 * time.Millisecond)
+	MeasureFps()
+
+	if delta := GetDeltaTime(); delta < 5 {
+		t.Errorf("delta time %v after 5ms, want at least 5", delta)
+	}
+	if fps := GetFps(); fps <= 0 || fps > 200 {
+		t.Errorf("%v fps after a 5ms frame, want at most 200", fps)
+	}
+}
+
+func repeat(elapsed float32, count int) []float32 {
+	frames := make([]float32, count)
+	for index := range frames {
+		frames[index] = elapsed
+	}
+	return frames
+}
+
+func near(value float64, want float64) bool {
+	diff := value - want
+	return diff > -1e-3*(1+want) && diff < 1e-3*(1+want)
+}
@@ -1,45 +1,64 @@
 package tools
 
-import (
-	"time"
-)
+// fpsRange is the number of frames the frame rate is averaged over
+const fpsRange = 60
 
-var (
-	fpsRange               float32 = 60.0
-	previousFramesDuration []int64 = make([]int64, 0)
-	fps                    float32 = 60.0
-	deltaTime              float32 = 0.0
-)
-
-func GetFps() float32 {
-	return fps
+// fpsMeter measures the frame rate of a clock from the time of its frames,
+// so a fixed-step or manual clock reports the rate it simulates
+type fpsMeter struct {
+	_frameTimes []float32
+	_fps        float32
 }
 
-func GetDeltaTime() float32 {
-	return deltaTime
+func (this *fpsMeter) initFps() {
+	this._frameTimes = make([]float32, 0, fpsRange+1)
+	this._fps = 60.0
 }
 
-func MeasureFps() {
+func (this *fpsMeter) measureFps(elapsed float32) {
+	// The first frame of a real time clock and empty manual frames have no duration
+	if elapsed <= 0 {
+		return
+	}
 
-	millis := time.Now().UnixNano() / 1000000
-	previousFramesDuration = append(previousFramesDuration, millis)
-	length := len(previousFramesDuration)
-	if length >= 2 {
-		deltaTime = float32(previousFramesDuration[length-1] - previousFramesDuration[length-2])
+	this._frameTimes = append(this._frameTimes, elapsed)
+	if len(this._frameTimes) > fpsRange {
+		this._frameTimes = this._frameTimes[1:]
 	}
-	if float32(length) >= fpsRange {
-		if float32(length) > fpsRange {
-			previousFramesDuration = previousFramesDuration[1:]
-			length = len(previousFramesDuration)
-		}
-
-		var sum int64
-		sum = 0
-		for id := 0; id < length-1; id++ {
-			sum += previousFramesDuration[id+1] - previousFramesDuration[id]
-		}
-
-		fps = 1000.0 / float32(sum/int64(length-1))
+
+	var sum float32
+	for _, frameTime := range this._frameTimes {
+		sum += frameTime
 	}
 
+	this._fps = 1000.0 * float32(len(this._frameTimes)) / sum
+}
+
+func (this *fpsMeter) GetFps() float32 {
+	return this._fps
+}
+
+// defaultClock serves the package level frame rate functions below, the
+// engine ticks it once per frame through MeasureFps
+var defaultClock = NewRealTimeClock()
+
+// GetFps returns the frame rate measured by MeasureFps.
+//
+// Deprecated: use the clock of the scene, Scene.GetClock().GetFps().
+func GetFps() float32 {
+	return defaultClock.GetFps()
+}
+
+// GetDeltaTime returns the duration of the last frame in milliseconds.
+//
+// Deprecated: use the clock of the scene, Scene.GetClock().GetDeltaTime().
+func GetDeltaTime() float32 {
+	return defaultClock.GetDeltaTime()
+}
+
+// MeasureFps ticks the default wall clock, call it once per frame.
+//
+// Deprecated: the scene clock measures the frame rate on each Tick.
+func MeasureFps() {
+	defaultClock.Tick()
 }
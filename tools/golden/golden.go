@@ -0,0 +1,202 @@
+// Package golden compares rendered images against reference PNGs stored on
+// disk. Colors are compared in YIQ space so that differences the eye hardly
+// notices (slight shading or antialiasing changes) are tolerated, while real
+// regressions are reported together with a diff image.
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// maxDelta is the YIQ distance between black and white
+const maxDelta = 35215.0
+
+type Options struct {
+	// Dir holds the golden images, failed comparisons are written to Dir/failed
+	Dir string
+	// Threshold is the perceptual distance, 0..1, above which a pixel differs
+	Threshold float64
+	// MaxDiffRatio is the fraction of differing pixels that is still accepted
+	MaxDiffRatio float64
+	// Update rewrites the golden images instead of comparing
+	Update bool
+}
+
+func NewOptions(dir string) *Options {
+	return &Options{
+		Dir:          dir,
+		Threshold:    0.1,
+		MaxDiffRatio: 0.001,
+		Update:       false,
+	}
+}
+
+// Result of a comparison, Diff highlights the differing pixels in red over a faded copy of the expected image
+type Result struct {
+	DiffPixels  int
+	TotalPixels int
+	Diff        *image.RGBA
+}
+
+func (this *Result) Ratio() float64 {
+	if this.TotalPixels == 0 {
+		return 0
+	}
+	return float64(this.DiffPixels) / float64(this.TotalPixels)
+}
+
+func rgb(c color.Color) (float64, float64, float64, float64) {
+	r, g, b, a := color.NRGBAModel.Convert(c).RGBA()
+	return float64(r >> 8), float64(g >> 8), float64(b >> 8), float64(a >> 8)
+}
+
+// blend composes a color over a white background, transparent pixels compare like the page behind them
+func blend(c, a float64) float64 {
+	return 255 + (c-255)*a/255
+}
+
+func yiq(r, g, b float64) (float64, float64, float64) {
+	y := r*0.29889531 + g*0.58662247 + b*0.11448223
+	i := r*0.59597799 - g*0.27417610 - b*0.32180189
+	q := r*0.21147017 - g*0.52261711 + b*0.31114694
+	return y, i, q
+}
+
+// Distance returns the perceptual distance of two colors, 0 for equal colors and 1 between black and white
+func Distance(c1, c2 color.Color) float64 {
+	r1, g1, b1, a1 := rgb(c1)
+	r2, g2, b2, a2 := rgb(c2)
+	if r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2 {
+		return 0
+	}
+
+	y1, i1, q1 := yiq(blend(r1, a1), blend(g1, a1), blend(b1, a1))
+	y2, i2, q2 := yiq(blend(r2, a2), blend(g2, a2), blend(b2, a2))
+
+	y := y1 - y2
+	i := i1 - i2
+	q := q1 - q2
+
+	return math.Sqrt((0.5053*y*y + 0.299*i*i + 0.1957*q*q) / maxDelta)
+}
+
+// Compare counts the pixels of actual whose distance to expected is above threshold
+func Compare(expected, actual image.Image, threshold float64) (*Result, error) {
+	bounds := expected.Bounds()
+	if bounds.Dx() != actual.Bounds().Dx() || bounds.Dy() != actual.Bounds().Dy() {
+		return nil, fmt.Errorf("golden: size mismatch, expected %dx%d got %dx%d",
+			bounds.Dx(), bounds.Dy(), actual.Bounds().Dx(), actual.Bounds().Dy())
+	}
+
+	result := &Result{}
+	result.TotalPixels = bounds.Dx() * bounds.Dy()
+	result.Diff = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	offset := actual.Bounds().Min.Sub(bounds.Min)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			want := expected.At(x, y)
+			got := actual.At(x+offset.X, y+offset.Y)
+
+			dx := x - bounds.Min.X
+			dy := y - bounds.Min.Y
+			if Distance(want, got) > threshold {
+				result.DiffPixels++
+				result.Diff.Set(dx, dy, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+
+			r, g, b, a := rgb(want)
+			luma, _, _ := yiq(blend(r, a), blend(g, a), blend(b, a))
+			gray := uint8(255 + (luma-255)*0.1)
+			result.Diff.Set(dx, dy, color.RGBA{gray, gray, gray, 255})
+		}
+	}
+
+	return result, nil
+}
+
+func Load(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return png.Decode(file)
+}
+
+func Save(path string, img image.Image) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(file, img)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Assert compares actual against Dir/name.png. In update mode the golden image
+// is written instead, a missing golden image skips the test. On failure the
+// actual and diff images are written to Dir/failed.
+func Assert(t testing.TB, name string, actual image.Image, options *Options) {
+	t.Helper()
+
+	path := filepath.Join(options.Dir, name+".png")
+
+	if options.Update {
+		err := Save(path, actual)
+		if err != nil {
+			t.Fatalf("golden: write %s: %v", path, err)
+		}
+		t.Logf("golden: updated %s", path)
+		return
+	}
+
+	expected, err := Load(path)
+	if os.IsNotExist(err) {
+		t.Skipf("golden: %s does not exist, run with -update to create it", path)
+		return
+	}
+	if err != nil {
+		t.Fatalf("golden: read %s: %v", path, err)
+	}
+
+	result, err := Compare(expected, actual, options.Threshold)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	if result.Ratio() <= options.MaxDiffRatio {
+		return
+	}
+
+	failedDir := filepath.Join(options.Dir, "failed")
+	actualPath := filepath.Join(failedDir, name+".png")
+	diffPath := filepath.Join(failedDir, name+".diff.png")
+	if err := Save(actualPath, actual); err != nil {
+		t.Logf("golden: write %s: %v", actualPath, err)
+	}
+	if err := Save(diffPath, result.Diff); err != nil {
+		t.Logf("golden: write %s: %v", diffPath, err)
+	}
+
+	t.Errorf("golden: %s differs in %d of %d pixels (%.3f%%), see %s",
+		name, result.DiffPixels, result.TotalPixels, result.Ratio()*100, diffPath)
+}
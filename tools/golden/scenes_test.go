@@ -0,0 +1,172 @@
+// +build glsoft
+
+package golden_test
+
+import (
+	"flag"
+	"image"
+	"testing"
+
+	"github.com/suiqirui1987/fly3d/core"
+	"github.com/suiqirui1987/fly3d/engines"
+	"github.com/suiqirui1987/fly3d/engines/enginetest"
+	"github.com/suiqirui1987/fly3d/math32"
+	"github.com/suiqirui1987/fly3d/module/cameras"
+	"github.com/suiqirui1987/fly3d/module/lights"
+	"github.com/suiqirui1987/fly3d/module/materials"
+	"github.com/suiqirui1987/fly3d/module/meshs"
+	"github.com/suiqirui1987/fly3d/tools/golden"
+)
+
+// go test -tags glsoft ./tools/golden -update
+var update = flag.Bool("update", false, "regenerate the golden images")
+
+const (
+	renderWidth  = 160
+	renderHeight = 120
+)
+
+// render builds a scene with build, draws one frame offscreen and returns the default framebuffer
+func render(t *testing.T, build func(scene *engines.Scene)) image.Image {
+	app := enginetest.NewApp(t, renderWidth, renderHeight)
+	engine := engines.NewEngine(app, false)
+	scene := engines.NewScene(engine)
+
+	camera := cameras.NewFreeCamera("camera", math32.NewVector3(0, 3, -8), scene)
+	camera.SetTarget(math32.NewVector3(0, 0, 0))
+
+	build(scene)
+
+	engine.RunRenderLoop(func() {
+		scene.Render()
+	})
+	app.RunFrames(1)
+
+	return engine.ReadPixels(0, 0, engine.GetRenderWidth(), engine.GetRenderHeight())
+}
+
+func assert(t *testing.T, name string, build func(scene *engines.Scene)) {
+	options := golden.NewOptions("testdata")
+	options.Update = *update
+
+	golden.Assert(t, name, render(t, build), options)
+}
+
+func material(name string, scene *engines.Scene, diffuse *math32.Color3) *materials.StandardMaterial {
+	mat := materials.NewStandardMaterial(name, scene)
+	mat.DiffuseColor = diffuse
+	mat.SpecularColor = math32.NewColor3(1, 1, 1)
+	mat.SpecularPower = 32
+	return mat
+}
+
+// shapes adds a box, a sphere and a torus side by side
+func shapes(scene *engines.Scene) {
+	box := meshs.CreateBox("box", 1.5, scene, false)
+	box.Position = math32.NewVector3(-2.5, 0, 0)
+	box.Rotation = math32.NewVector3(0.4, 0.6, 0)
+	box.Material = material("red", scene, math32.NewColor3(1, 0.2, 0.2))
+
+	sphere := meshs.CreateSphere("sphere", 16, 2, scene, false)
+	sphere.Material = material("green", scene, math32.NewColor3(0.2, 1, 0.2))
+
+	torus := meshs.CreateTorus("torus", 1.8, 0.5, 24, scene, false)
+	torus.Position = math32.NewVector3(2.5, 0, 0)
+	torus.Rotation = math32.NewVector3(-0.8, 0, 0)
+	torus.Material = material("blue", scene, math32.NewColor3(0.2, 0.2, 1))
+}
+
+func TestEmissive(t *testing.T) {
+	assert(t, "emissive", func(scene *engines.Scene) {
+		shapes(scene)
+		for _, mat := range scene.Materials {
+			mat.(*materials.StandardMaterial).EmissiveColor = math32.NewColor3(0.5, 0.5, 0.5)
+		}
+	})
+}
+
+func TestHemisphericLight(t *testing.T) {
+	assert(t, "hemispheric", func(scene *engines.Scene) {
+		light := lights.NewHemisphericLight("hemi", math32.NewVector3(0, 1, 0), scene)
+		light.GroundColor = math32.NewColor3(0.2, 0.1, 0)
+		shapes(scene)
+	})
+}
+
+func TestPointLight(t *testing.T) {
+	assert(t, "point", func(scene *engines.Scene) {
+		lights.NewPointLight("point", math32.NewVector3(0, 4, -4), scene)
+		shapes(scene)
+	})
+}
+
+func TestDirectionalLight(t *testing.T) {
+	assert(t, "directional", func(scene *engines.Scene) {
+		lights.NewDirectionalLight("dir", math32.NewVector3(0.5, -1, 1), scene)
+		shapes(scene)
+	})
+}
+
+func TestSpotLight(t *testing.T) {
+	assert(t, "spot", func(scene *engines.Scene) {
+		lights.NewSpotLight("spot", math32.NewVector3(0, 6, 0), math32.NewVector3(0, -1, 0), 0.8, 2, scene)
+		shapes(scene)
+
+		ground := meshs.CreateGround("ground", 12, 12, 1, scene, false)
+		ground.Position = math32.NewVector3(0, -1.2, 0)
+		ground.Material = material("ground", scene, math32.NewColor3(0.8, 0.8, 0.8))
+	})
+}
+
+func TestMultipleLights(t *testing.T) {
+	assert(t, "multiple", func(scene *engines.Scene) {
+		point := lights.NewPointLight("point", math32.NewVector3(-4, 2, -2), scene)
+		point.Diffuse = math32.NewColor3(1, 0.5, 0)
+		dir := lights.NewDirectionalLight("dir", math32.NewVector3(-1, -1, 1), scene)
+		dir.Diffuse = math32.NewColor3(0, 0.5, 1)
+		dir.Specular = math32.NewColor3(0, 0, 0)
+		shapes(scene)
+	})
+}
+
+// A non-uniform scale applies along the axes of the mesh, before its rotation
+func TestScaledRotation(t *testing.T) {
+	assert(t, "scaled_rotation", func(scene *engines.Scene) {
+		lights.NewHemisphericLight("hemi", math32.NewVector3(0, 1, 0), scene)
+
+		box := meshs.CreateBox("box", 1, scene, false)
+		box.Scaling = math32.NewVector3(4, 1, 0.5)
+		box.Rotation = math32.NewVector3(0, 0, 0.5)
+		box.Material = material("red", scene, math32.NewColor3(1, 0.2, 0.2))
+	})
+}
+
+func fog(t *testing.T, name string, mode int) {
+	assert(t, name, func(scene *engines.Scene) {
+		lights.NewHemisphericLight("hemi", math32.NewVector3(0, 1, 0), scene)
+		scene.FogMode = mode
+		scene.FogColor = math32.NewColor3(0.9, 0.9, 0.85)
+		scene.FogDensity = 0.08
+		scene.FogStart = 6
+		scene.FogEnd = 20
+		scene.ClearColor = scene.FogColor
+
+		for index := 0; index < 5; index++ {
+			sphere := meshs.CreateSphere("sphere", 12, 1.5, scene, false)
+			sphere.Position = math32.NewVector3(float32(index)*1.5-3, 0, float32(index)*4)
+			sphere.Material = material("sphere", scene, math32.NewColor3(0.8, 0.3, 0.1))
+		}
+	})
+}
+
+func TestFogLinear(t *testing.T) {
+	fog(t, "fog_linear", core.FOGMODE_LINEAR)
+}
+
+func TestFogExp(t *testing.T) {
+	fog(t, "fog_exp", core.FOGMODE_EXP)
+}
+
+func TestFogExp2(t *testing.T) {
+	fog(t, "fog_exp2", core.FOGMODE_EXP2)
+}
@@ -4,11 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 )
 
 // GetField returns the value of the provided obj field. obj can whether
-// be a structure or pointer to structure.
+// be a structure or pointer to structure. The elements of a slice or an
+// array are fields named by their index.
 func GetField(obj interface{}, name string) (interface{}, error) {
+	if hasValidType(obj, []reflect.Kind{reflect.Slice, reflect.Array}) {
+		return getElement(obj, name)
+	}
+
 	if !hasValidType(obj, []reflect.Kind{reflect.Struct, reflect.Ptr}) {
 		return nil, errors.New("Cannot use GetField on a non-struct interface")
 	}
@@ -81,13 +87,14 @@ func GetFieldTag(obj interface{}, fieldName, tagKey string) (string, error) {
 // SetField sets the provided obj field with provided value. obj param has
 // to be a pointer to a struct, otherwise it will soundly fail. Provided
 // value type should match with the struct field you're trying to set.
+// Without such a field, the Set<name> method of obj is called.
 func SetField(obj interface{}, name string, value interface{}) error {
 	// Fetch the field reflect.Value
 	structValue := reflect.ValueOf(obj).Elem()
 	structFieldValue := structValue.FieldByName(name)
 
 	if !structFieldValue.IsValid() {
-		return fmt.Errorf("No such field: %s in obj", name)
+		return callSetter(obj, name, value)
 	}
 
 	// If obj field value is not settable an error is thrown
@@ -252,6 +259,35 @@ func tags(obj interface{}, key string, deep bool) (map[string]string, error) {
 	return allTags, nil
 }
 
+func callSetter(obj interface{}, name string, value interface{}) error {
+	method := reflect.ValueOf(obj).MethodByName("Set" + name)
+	if !method.IsValid() || method.Type().NumIn() != 1 {
+		return fmt.Errorf("No such field: %s in obj", name)
+	}
+
+	val := reflect.ValueOf(value)
+	if method.Type().In(0) != val.Type() {
+		return errors.New("Provided value type didn't match obj field type")
+	}
+
+	method.Call([]reflect.Value{val})
+	return nil
+}
+
+func getElement(obj interface{}, name string) (interface{}, error) {
+	index, err := strconv.Atoi(name)
+	if err != nil {
+		return nil, fmt.Errorf("No such element: %s in obj", name)
+	}
+
+	objValue := reflect.ValueOf(obj)
+	if index < 0 || index >= objValue.Len() {
+		return nil, fmt.Errorf("Index out of range: %d in obj", index)
+	}
+
+	return objValue.Index(index).Interface(), nil
+}
+
 func reflectValue(obj interface{}) reflect.Value {
 	var val reflect.Value
 
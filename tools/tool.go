@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
+	"image/png"
 	"io/ioutil"
 	"math"
 	"net/http"
@@ -66,6 +67,15 @@ func GetCurrentTimeMs() int {
 	return (int)(time.Now().UnixNano() / 1e6)
 }
 
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, img)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
 func DecodeImage(content []byte) (*image.RGBA, error) {
 
 	// Decodes image
@@ -125,6 +135,34 @@ func BytesUint16(byteOrder binary.ByteOrder, values ...uint16) []byte {
 	return b
 }
 
+func BytesUint32(byteOrder binary.ByteOrder, values ...uint32) []byte {
+	le := false
+	switch byteOrder {
+	case binary.BigEndian:
+	case binary.LittleEndian:
+		le = true
+	default:
+		panic(fmt.Sprintf("invalid byte order %v", byteOrder))
+	}
+
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		u := v
+		if le {
+			b[4*i+0] = byte(u >> 0)
+			b[4*i+1] = byte(u >> 8)
+			b[4*i+2] = byte(u >> 16)
+			b[4*i+3] = byte(u >> 24)
+		} else {
+			b[4*i+0] = byte(u >> 24)
+			b[4*i+1] = byte(u >> 16)
+			b[4*i+2] = byte(u >> 8)
+			b[4*i+3] = byte(u >> 0)
+		}
+	}
+	return b
+}
+
 func OpenGeneralFile(file string) ([]byte, error) {
 	if strings.Index(file, "http") == 0 {
 		//http
@@ -153,6 +191,10 @@ func DownHttpFile(url string) ([]byte, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -2,6 +2,7 @@
 package tools
 
 import (
+	"errors"
 	"image"
 
 	log "github.com/suiqirui1987/fly3d/tools/logrus"
@@ -25,12 +26,21 @@ func LoadImage(url string, onload func(*image.RGBA), onfail func(error)) {
 	return
 }
 
-func LoadFile(url string, callback func(string), progressCallBack func(int)) {
+// LoadFile calls callback with the content of url, or onfail when it can not be read
+func LoadFile(url string, callback func(string), progressCallBack func(int), onfail func(error)) {
 	content, err := DownHttpFile(url)
 	if err != nil {
 		log.Printf("LoadFile Failed %s", err)
+		if onfail != nil {
+			onfail(err)
+		}
 		return
 	}
 	content_str := string(Clean(content))
 	callback(content_str)
 }
+
+// SavePNG is not available in the browser, use EncodePNG and hand the bytes to the page
+func SavePNG(path string, img image.Image) error {
+	return errors.New("SavePNG is not supported in the browser")
+}
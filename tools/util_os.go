@@ -4,6 +4,9 @@ package tools
 
 import (
 	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	log "github.com/suiqirui1987/fly3d/tools/logrus"
 )
@@ -25,12 +28,32 @@ func LoadImage(url string, onload func(*image.RGBA), onfail func(error)) {
 	onload(rgba)
 	return
 }
-func LoadFile(url string, callback func(string), progressCallBack func(int)) {
+
+// LoadFile calls callback with the content of url, or onfail when it can not be read
+func LoadFile(url string, callback func(string), progressCallBack func(int), onfail func(error)) {
 	content, err := OpenGeneralFile(url)
 	if err != nil {
 		log.Printf("LoadFile Failed %s", err)
+		if onfail != nil {
+			onfail(err)
+		}
 		return
 	}
 	content_str := string(Clean(content))
 	callback(content_str)
 }
+
+// SavePNG encodes img as PNG into path, missing directories are created
+func SavePNG(path string, img image.Image) error {
+	content, err := EncodePNG(img)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0644)
+}
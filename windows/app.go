@@ -4,17 +4,14 @@ import (
 	"github.com/suiqirui1987/fly3d/gui/nanogui"
 
 	"github.com/suiqirui1987/fly3d/tools/goevent"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 
 	"github.com/suiqirui1987/fly3d/glfw"
 )
 
 var mainloopActive bool = false
 
-type AppOption struct {
-	Width  int
-	Height int
-	Title  string
-}
+type AppOption = canvas.AppOption
 
 type App struct {
 	goevent.Dispatcher
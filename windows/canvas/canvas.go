@@ -0,0 +1,78 @@
+// Package canvas holds what the engine needs from a window: the IWindow
+// interface and its events. It does not import glfw, so the engine and
+// headless windows build without a display.
+package canvas
+
+import (
+	"github.com/suiqirui1987/fly3d/tools/goevent"
+)
+
+type AppOption struct {
+	Width  int
+	Height int
+	Title  string
+}
+
+type IWindow interface {
+	GetRenderWidth() int
+	GetRenderHeight() int
+
+	StopNewFrame()
+	QueueNewFrame(func())
+	GetWindowDevicePixelRatio() float32
+
+	GetFullscreen() bool
+	ExitFullscreen()
+	RequestFullscreen()
+
+	On(name string, fn interface{}) error
+	Emit(name string, params ...interface{}) error
+	Has(name string) bool
+	List() []string
+	Remove(names ...string)
+}
+
+const (
+	Resize           = "resize"
+	Keydown          = "keydown"
+	Keyup            = "keyup"
+	MouseDown        = "mousedown"
+	MouseUp          = "mouseup"
+	MouseMove        = "mousemove"
+	MouseOut         = "mouseout"
+	Wheel            = "wheel"
+	Focus            = "focus"
+	FullScreenChange = "fullscreenchange"
+)
+
+type ResizeEvent struct {
+	goevent.Event
+
+	WindowWidth  int
+	WindowHeigth int
+}
+type MouseEvent struct {
+	goevent.Event
+
+	ClientX float32
+	ClientY float32
+}
+
+type WheelEvent struct {
+	goevent.Event
+
+	DeltaX float32
+	DeltaY float32
+}
+
+type KeyboardEvent struct {
+	goevent.Event
+
+	KeyCode  int
+	CharCode string
+}
+type FocusEvent struct {
+	goevent.Event
+
+	Focused bool
+}
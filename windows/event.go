@@ -1,50 +1,25 @@
 package windows
 
 import (
-	"github.com/suiqirui1987/fly3d/tools/goevent"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 )
 
+// The events are defined in canvas, which does not import glfw
 const (
-	Resize           = "resize"
-	Keydown          = "keydown"
-	Keyup            = "keyup"
-	MouseDown        = "mousedown"
-	MouseUp          = "mouseup"
-	MouseMove        = "mousemove"
-	MouseOut         = "mouseout"
-	Wheel            = "wheel"
-	Focus            = "focus"
-	FullScreenChange = "fullscreenchange"
+	Resize           = canvas.Resize
+	Keydown          = canvas.Keydown
+	Keyup            = canvas.Keyup
+	MouseDown        = canvas.MouseDown
+	MouseUp          = canvas.MouseUp
+	MouseMove        = canvas.MouseMove
+	MouseOut         = canvas.MouseOut
+	Wheel            = canvas.Wheel
+	Focus            = canvas.Focus
+	FullScreenChange = canvas.FullScreenChange
 )
 
-type ResizeEvent struct {
-	goevent.Event
-
-	WindowWidth  int
-	WindowHeigth int
-}
-type MouseEvent struct {
-	goevent.Event
-
-	ClientX float32
-	ClientY float32
-}
-
-type WheelEvent struct {
-	goevent.Event
-
-	DeltaX float32
-	DeltaY float32
-}
-
-type KeyboardEvent struct {
-	goevent.Event
-
-	KeyCode  int
-	CharCode string
-}
-type FocusEvent struct {
-	goevent.Event
-
-	Focused bool
-}
+type ResizeEvent = canvas.ResizeEvent
+type MouseEvent = canvas.MouseEvent
+type WheelEvent = canvas.WheelEvent
+type KeyboardEvent = canvas.KeyboardEvent
+type FocusEvent = canvas.FocusEvent
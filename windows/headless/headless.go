@@ -0,0 +1,122 @@
+// Package headless provides a window without a display. It does not import
+// glfw, so scenes can be rendered by batch jobs and tests.
+package headless
+
+import (
+	"github.com/suiqirui1987/fly3d/tools/goevent"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
+)
+
+// App is an IWindow without a native window. Frames are driven by
+// Tick/RunFrames instead of a display loop, so scenes can be rendered in
+// batch jobs and tests. The caller is responsible for providing a gl backend
+// or context that works without a display.
+type App struct {
+	goevent.Dispatcher
+
+	Width      int
+	Height     int
+	PixelRatio float32
+
+	_stopFlag   bool
+	_fullscreen bool
+	_frameCount int
+	_renderFun  func()
+}
+
+func NewApp(opt *canvas.AppOption) (*App, error) {
+	this := &App{}
+	this.Width = opt.Width
+	this.Height = opt.Height
+	this.PixelRatio = 1.0
+	this._stopFlag = false
+	this._fullscreen = false
+	this._frameCount = 0
+
+	this.Dispatcher.Initialize()
+
+	return this, nil
+}
+
+// Tick renders one frame, returns false if no frame is queued or the loop was stopped
+func (this *App) Tick() bool {
+	if this._renderFun == nil || this._stopFlag {
+		return false
+	}
+
+	this._renderFun()
+	this._frameCount++
+
+	return true
+}
+
+// RunFrames renders up to count frames and returns the number actually rendered
+func (this *App) RunFrames(count int) int {
+	rendered := 0
+	for index := 0; index < count; index++ {
+		if !this.Tick() {
+			break
+		}
+		rendered++
+	}
+	return rendered
+}
+
+// Run renders frames until the render loop is stopped
+func (this *App) Run() {
+	for this.Tick() {
+	}
+}
+
+func (this *App) GetFrameCount() int {
+	return this._frameCount
+}
+
+func (this *App) SetSize(width int, height int) {
+	this.Width = width
+	this.Height = height
+
+	evt := &canvas.ResizeEvent{
+		WindowWidth:  width,
+		WindowHeigth: height,
+	}
+
+	this.Emit(canvas.Resize, evt)
+}
+
+//interface
+func (this *App) GetRenderWidth() int {
+	return int(float32(this.Width) * this.PixelRatio)
+}
+func (this *App) GetRenderHeight() int {
+	return int(float32(this.Height) * this.PixelRatio)
+}
+
+func (this *App) StopNewFrame() {
+	this._stopFlag = true
+}
+func (this *App) QueueNewFrame(f func()) {
+	this._renderFun = f
+	this._stopFlag = false
+}
+func (this *App) GetWindowDevicePixelRatio() float32 {
+	return this.PixelRatio
+}
+
+func (this *App) GetFullscreen() bool {
+	return this._fullscreen
+}
+func (this *App) ExitFullscreen() {
+	if !this._fullscreen {
+		return
+	}
+	this._fullscreen = false
+	this.Emit(canvas.FullScreenChange)
+}
+func (this *App) RequestFullscreen() {
+	if this._fullscreen {
+		return
+	}
+	this._fullscreen = true
+	this.Emit(canvas.FullScreenChange)
+}
@@ -2,6 +2,7 @@ package windows
 
 import (
 	"github.com/suiqirui1987/fly3d/glfw"
+	"github.com/suiqirui1987/fly3d/windows/canvas"
 )
 
 func KeyString(key glfw.Key) string {
@@ -253,21 +254,4 @@ func KeyString(key glfw.Key) string {
 	}
 }
 
-type IWindow interface {
-	GetRenderWidth() int
-	GetRenderHeight() int
-
-	StopNewFrame()
-	QueueNewFrame(func())
-	GetWindowDevicePixelRatio() float32
-
-	GetFullscreen() bool
-	ExitFullscreen()
-	RequestFullscreen()
-
-	On(name string, fn interface{}) error
-	Emit(name string, params ...interface{}) error
-	Has(name string) bool
-	List() []string
-	Remove(names ...string)
-}
+type IWindow = canvas.IWindow